@@ -0,0 +1,281 @@
+// gen-client 扫描 internal/tools 中的工具注册，生成 pkg/client 的类型化调用封装
+//
+// 用法（在 pkg/client 目录下由 go generate 触发）：
+//
+//	go run ../../cmd/gen-client -src ../../internal/tools -out tools_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// toolSpec 一个已注册工具的生成信息
+type toolSpec struct {
+	Name     string // 工具名 (如 task_chain)
+	ArgsType string // 参数结构体名 (如 TaskChainArgs)，无参数时为空
+	Title    string // 描述首行
+}
+
+func main() {
+	src := flag.String("src", "internal/tools", "工具源码目录")
+	out := flag.String("out", "pkg/client/tools_gen.go", "生成文件路径")
+	pkg := flag.String("pkg", "client", "生成文件的包名")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, *src, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		fatal(err)
+	}
+
+	structs := make(map[string]*ast.StructType)
+	var tools []toolSpec
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			collectStructs(f, structs)
+			tools = append(tools, collectTools(f)...)
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+
+	code, err := render(*pkg, fset, tools, structs)
+	if err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(*out, code, 0644); err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "[gen-client] 已生成 %d 个工具封装 → %s\n", len(tools), *out)
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "[gen-client][ERROR] %v\n", err)
+	os.Exit(1)
+}
+
+// collectStructs 收集文件中所有顶层结构体定义
+func collectStructs(f *ast.File, structs map[string]*ast.StructType) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				structs[ts.Name.Name] = st
+			}
+		}
+	}
+}
+
+// collectTools 查找 mcp.NewTool("name", ..., mcp.WithInputSchema[Args]()) 调用
+func collectTools(f *ast.File) []toolSpec {
+	var tools []toolSpec
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isSelector(call.Fun, "mcp", "NewTool") || len(call.Args) == 0 {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, _ := strconv.Unquote(lit.Value)
+		spec := toolSpec{Name: name}
+
+		for _, opt := range call.Args[1:] {
+			optCall, ok := opt.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			switch fn := optCall.Fun.(type) {
+			case *ast.IndexExpr: // mcp.WithInputSchema[Args]
+				if isSelector(fn.X, "mcp", "WithInputSchema") {
+					if id, ok := fn.Index.(*ast.Ident); ok {
+						spec.ArgsType = id.Name
+					}
+				}
+			case *ast.SelectorExpr: // mcp.WithDescription(`...`)
+				if isSelector(fn, "mcp", "WithDescription") && len(optCall.Args) > 0 {
+					if d, ok := optCall.Args[0].(*ast.BasicLit); ok {
+						text, _ := strconv.Unquote(d.Value)
+						spec.Title = strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+					}
+				}
+			}
+		}
+		tools = append(tools, spec)
+		return true
+	})
+	return tools
+}
+
+func isSelector(expr ast.Expr, pkg, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == pkg
+}
+
+// methodName task_chain -> TaskChain
+func methodName(tool string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(tool, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func render(pkg string, fset *token.FileSet, tools []toolSpec, structs map[string]*ast.StructType) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by cmd/gen-client; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\nimport \"context\"\n", pkg)
+
+	// 参数结构体引用的本地类型（如 MemoItem）按原名一并输出
+	emitted := make(map[string]bool)
+	var emitNested func(expr ast.Expr) error
+	emitNested = func(expr ast.Expr) error {
+		var err error
+		ast.Inspect(expr, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok || err != nil || emitted[id.Name] {
+				return true
+			}
+			st, ok := structs[id.Name]
+			if !ok {
+				return true
+			}
+			emitted[id.Name] = true
+			if err = emitNestedFields(st, emitNested); err != nil {
+				return false
+			}
+			fmt.Fprintf(&buf, "\n// %s 嵌套参数类型\ntype %s struct {\n", id.Name, id.Name)
+			for _, field := range st.Fields.List {
+				if err = renderField(&buf, fset, field); err != nil {
+					return false
+				}
+			}
+			buf.WriteString("}\n")
+			return true
+		})
+		return err
+	}
+
+	for _, t := range tools {
+		method := methodName(t.Name)
+		reqType := method + "Request"
+
+		if t.ArgsType != "" {
+			st, ok := structs[t.ArgsType]
+			if !ok {
+				return nil, fmt.Errorf("工具 %s 的参数结构体 %s 未找到", t.Name, t.ArgsType)
+			}
+			if err := emitNestedFields(st, emitNested); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "\n// %s %s 的请求参数\ntype %s struct {\n", reqType, t.Name, reqType)
+			for _, field := range st.Fields.List {
+				if err := renderField(&buf, fset, field); err != nil {
+					return nil, fmt.Errorf("%s: %w", t.ArgsType, err)
+				}
+			}
+			buf.WriteString("}\n")
+		}
+
+		fmt.Fprintf(&buf, "\n// %s 调用 %s\n", method, t.Title)
+		if t.ArgsType != "" {
+			fmt.Fprintf(&buf, "func (c *Client) %s(ctx context.Context, req %s) (*ToolResult, error) {\n\treturn c.Call(ctx, %q, req)\n}\n", method, reqType, t.Name)
+		} else {
+			fmt.Fprintf(&buf, "func (c *Client) %s(ctx context.Context) (*ToolResult, error) {\n\treturn c.Call(ctx, %q, nil)\n}\n", method, t.Name)
+		}
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// emitNestedFields 对结构体每个字段类型执行 emit
+func emitNestedFields(st *ast.StructType, emit func(ast.Expr) error) error {
+	for _, field := range st.Fields.List {
+		if err := emit(field.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderField 输出字段：保留类型与 json 名，追加 omitempty 以免零值覆盖服务端默认值；
+// jsonschema 描述转为行尾注释
+func renderField(buf *bytes.Buffer, fset *token.FileSet, field *ast.Field) error {
+	var typ bytes.Buffer
+	if err := printer.Fprint(&typ, fset, field.Type); err != nil {
+		return err
+	}
+
+	var tag reflect.StructTag
+	if field.Tag != nil {
+		raw, _ := strconv.Unquote(field.Tag.Value)
+		tag = reflect.StructTag(raw)
+	}
+	jsonName := strings.Split(tag.Get("json"), ",")[0]
+
+	for _, name := range field.Names {
+		if jsonName == "" {
+			jsonName = name.Name
+		}
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s,omitempty\"`", name.Name, typ.String(), jsonName)
+		if desc := schemaDescription(tag.Get("jsonschema")); desc != "" {
+			fmt.Fprintf(buf, " // %s", desc)
+		}
+		buf.WriteString("\n")
+	}
+	return nil
+}
+
+// schemaDescription 提取 jsonschema 标签中的 description（可能包含逗号，取到结尾或下一个 key= 为止）
+func schemaDescription(schema string) string {
+	idx := strings.Index(schema, "description=")
+	if idx < 0 {
+		return ""
+	}
+	rest := schema[idx+len("description="):]
+	parts := strings.Split(rest, ",")
+	desc := []string{parts[0]}
+	for _, p := range parts[1:] {
+		if k := strings.SplitN(p, "=", 2); len(k) == 2 && isSchemaKey(k[0]) {
+			break
+		}
+		if p == "required" {
+			break
+		}
+		desc = append(desc, p)
+	}
+	return strings.Join(desc, ",")
+}
+
+func isSchemaKey(k string) bool {
+	switch k {
+	case "default", "enum", "minimum", "maximum", "example", "description":
+		return true
+	}
+	return false
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"mcp-server-go/internal/core"
 	"mcp-server-go/internal/services"
@@ -30,8 +35,7 @@ func main() {
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "[MCP-Go][ERROR] 记忆层初始化受阻: %v\n", err)
 		} else {
-			sm.Memory = m
-			sm.ProjectRoot = projectRoot
+			sm.Bind(projectRoot, m)
 			fmt.Fprintf(os.Stderr, "[MCP-Go] 记忆层（SSOT）与项目上下文已就绪。\n")
 
 		}
@@ -41,10 +45,32 @@ func main() {
 
 	// 注：HUD 自动启动已移至 initialize_project 工具，不再在 server 启动时触发
 
+	// SIGINT/SIGTERM：停止后台任务与 stdio 循环，随后走 tools.Shutdown 收尾
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		ai.Shutdown() // 立即终止正在运行的索引命令
+	}()
+
+	// 内置调度器：按 settings.json 的 scheduler 段周期执行维护任务（未启用时空转）
+	sm.Scheduler = tools.StartScheduler(ctx, sm, ai)
+
+	// 文件监听：项目文件变更后按范围增量刷新索引（settings.json index.watch=true 时开启）
+	sm.IndexWatcher = tools.StartIndexWatcher(ctx, sm, ai)
+
+	// 空闲存档：会话空闲超过 settings.json session.idle_minutes 时保存进行中的工作上下文
+	tools.StartIdleCheckpointer(ctx, sm)
+
 	// 启动 MCP Server (StdIO)
 	s := server.NewMCPServer(
 		"MyProjectManager-Go",
 		"1.0.0",
+		server.WithToolHandlerMiddleware(tools.RenderMiddleware(sm)),     // 会话渲染目标 (markdown/plain)
+		server.WithToolHandlerMiddleware(tools.ActivityMiddleware(sm)),   // 工具调用活跃度（空闲存档）
+		server.WithToolHandlerMiddleware(tools.MetricsMiddleware()),      // 工具调用次数与耗时 (server_stats)
+		server.WithToolHandlerMiddleware(tools.CapabilityMiddleware(sm)), // 按客户端能力适配结果
+		server.WithHooks(tools.CapabilityHooks(sm)),                      // initialize 时记录客户端能力画像
 	) // 注册工具
 	tools.RegisterSystemTools(s, sm, ai)       // 系统初始化
 	tools.RegisterMemoryTools(s, sm)           // 备忘与检索
@@ -52,13 +78,17 @@ func main() {
 	tools.RegisterIntelligenceTools(s, sm, ai) // 任务分析与事实存档
 	tools.RegisterAnalysisTools(s, sm, ai)     // 影响分析工具
 	tools.RegisterSkillTools(s, sm)            // 技能库工具
-	tools.RegisterTaskTools(s, sm)             // 任务管理工具
+	tools.RegisterTaskTools(s, sm, ai)         // 任务管理工具
 	tools.RegisterEnhanceTools(s, sm)          // 增强工具 (persona)
 
 	fmt.Fprintf(os.Stderr, "[MCP-Go] MyProjectManager 正在启动...\n")
 
-	if err := server.ServeStdio(s); err != nil {
+	err := server.NewStdioServer(s).Listen(ctx, os.Stdin, os.Stdout)
+	stop()
+	tools.Shutdown(sm, ai, 10*time.Second)
+	if err != nil && !errors.Is(err, context.Canceled) {
 		fmt.Fprintf(os.Stderr, "服务运行错误: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "[MCP-Go] 已退出。\n")
 }
@@ -3,6 +3,7 @@ module mcp-server-go
 go 1.25.6
 
 require (
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/mark3labs/mcp-go v0.43.2
 	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.44.3
@@ -0,0 +1,376 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 命令执行守卫：所有代理发起的命令都必须经过这里
+// ============================================================================
+//
+// - 白名单/黑名单：按参数逐词匹配命令前缀，黑名单优先
+// - 参数净化：拒绝控制字符；默认不经 shell，含 shell 语法的命令直接拒绝
+// - 逃逸参数：白名单前缀放行的命令仍拒绝可执行任意程序/写任意文件的参数（go -toolexec、git --output 等）
+// - 环境变量：拒绝可劫持被执行程序的变量（PATH、GOFLAGS、LD_*、GIT_* 等）
+// - 审计：每次执行（含被拒绝的尝试）写入项目数据库 command_audit 表，数据库不可用时追加到 command_audit.jsonl
+// - 执行：统一由 CommandRunner 负责（工作目录限制、超时、输出截断），见 command_runner.go
+
+const (
+	commandAuditFile     = "command_audit.jsonl"
+	commandMaxOutputSize = 64 << 10
+)
+
+// DefaultCommandAllow settings 未配置 commands.allow 时的内置白名单（构建/测试/静态检查类命令）
+var DefaultCommandAllow = []string{
+	"go build", "go test", "go vet", "gofmt -l", "golangci-lint run",
+	"cargo build", "cargo check", "cargo test", "cargo clippy",
+	"npm test", "npm run", "pnpm test", "pnpm run", "yarn test", "yarn run", "npx tsc", "npx eslint",
+	"pytest", "python -m pytest", "python3 -m pytest", "ruff check", "mypy",
+	"make", "mvn test", "gradle test", "dotnet build", "dotnet test",
+	"git status", "git diff", "git log", "git show",
+	"govulncheck", "npm audit", "pip-audit", "cargo audit",
+}
+
+// DefaultCommandDeny 始终生效的黑名单
+var DefaultCommandDeny = []string{
+	"rm", "rmdir", "del", "sudo", "su", "chmod", "chown", "dd", "mkfs", "shutdown", "reboot",
+	"curl", "wget", "ssh", "scp", "nc",
+	"git push", "git reset", "git clean", "git checkout", "npm publish", "cargo publish",
+}
+
+// commandEscapeFlags 按程序名列出的逃逸参数：可让白名单命令执行任意程序或写到任意路径。
+// 匹配时忽略前导 "-" 的个数，并覆盖 "--flag=value" 形式。
+// 程序自身的全局参数（git -c、npx -c 等）位于子命令之前，无法匹配 "git diff"、"npx tsc" 这类前缀规则，无需列出。
+// 注意 npm run / make 等本身就会执行项目内脚本，放行它们即表示信任项目脚本
+var commandEscapeFlags = map[string][]string{
+	"go":     {"exec", "toolexec", "vettool", "o", "overlay", "modfile"},
+	"git":    {"output", "ext-diff", "textconv"},
+	"cargo":  {"config", "Z"},
+	"pytest": {"p"},
+	"python": {"p"}, "python3": {"p"}, // python -m pytest -p <插件>
+}
+
+// deniedEnvKeys 不允许通过 CommandRequest.Env 设置的变量（大小写不敏感）
+var deniedEnvKeys = []string{
+	"PATH", "GOFLAGS", "GOENV", "GOTOOLCHAIN", "GOROOT", "CC", "CXX", "AR", "PKG_CONFIG",
+	"RUSTC", "RUSTC_WRAPPER", "RUSTC_WORKSPACE_WRAPPER", "RUSTFLAGS", "RUSTDOCFLAGS",
+	"NODE_OPTIONS", "PYTHONPATH", "PYTHONSTARTUP", "PYTHONHOME",
+	"BASH_ENV", "ENV", "SHELL", "SHELLOPTS", "BASHOPTS", "IFS", "PS4", "PROMPT_COMMAND",
+	"MAKEFLAGS", "MAKEFILES", "HOME", "XDG_CONFIG_HOME", "PAGER", "EDITOR",
+}
+
+// deniedEnvPrefixes 按前缀拒绝的变量；allowedEnvKeys 为其中的例外
+var (
+	deniedEnvPrefixes = []string{"LD_", "DYLD_", "GIT_", "CGO_", "CARGO_", "NPM_CONFIG_", "BASH_FUNC_"}
+	allowedEnvKeys    = map[string]bool{"CGO_ENABLED": true}
+)
+
+// shellMetaChars 不经 shell 执行时无意义、出现即说明调用方期望 shell 语义
+const shellMetaChars = ";|&$`<>(){}*?~"
+
+// ErrCommandDenied 命令未通过安全策略
+var ErrCommandDenied = errors.New("命令被安全策略拒绝")
+
+// CommandRequest 一次命令执行请求
+type CommandRequest struct {
+	Line   string // 命令行文本（Args 为空时解析）
+	Args   []string
+	Dir    string            // 工作目录，相对项目根；不得越出项目
+	Env    map[string]string // 附加环境变量（在当前进程环境之上覆盖）
+	Source string            // 发起方，如 "task_chain:TASK-1/verify"，写入审计
+	// Timeout 单次超时，只能比 settings 的 commands.timeout_seconds 更短；0 = 使用配置
+	Timeout time.Duration
+	// MaxOutput 输出保留上限（字节，保留尾部）；0 = 64KB
+	MaxOutput int
+	// Stdin 写入标准输入的内容（为空时不提供输入）
+	Stdin []byte
+	// SeparateStderr 为 true 时 Output 只含 stdout，stderr 尾部放入 CommandResult.Stderr（供解析结构化输出）
+	SeparateStderr bool
+}
+
+// CommandResult 执行结果
+type CommandResult struct {
+	Argv      []string
+	Shell     bool
+	ExitCode  int
+	Output    string // stdout+stderr，超过上限时保留尾部
+	Stderr    string // 仅 SeparateStderr 时填充
+	Truncated bool
+	Duration  time.Duration
+}
+
+// CommandAuditRecord 审计记录
+type CommandAuditRecord struct {
+	Time       string   `json:"time"`
+	Source     string   `json:"source,omitempty"`
+	Argv       []string `json:"argv"`
+	Dir        string   `json:"dir"`
+	Shell      bool     `json:"shell,omitempty"`
+	Allowed    bool     `json:"allowed"`
+	Reason     string   `json:"reason,omitempty"`
+	ExitCode   int      `json:"exit_code"`
+	DurationMs int64    `json:"duration_ms"`
+	OutputTail string   `json:"output_tail,omitempty"`
+}
+
+// SplitCommandLine 按空白切分命令行，支持单/双引号与反斜杠转义（不做变量展开）
+func SplitCommandLine(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'' && runtime.GOOS != "windows":
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("命令行引号未闭合")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// hasShellSyntax 未加引号的 shell 元字符（引号内的视为普通参数）
+func hasShellSyntax(line string) bool {
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case strings.ContainsRune(shellMetaChars, r):
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeArgs 拒绝空命令与控制字符
+func sanitizeArgs(argv []string) error {
+	if len(argv) == 0 || strings.TrimSpace(argv[0]) == "" {
+		return fmt.Errorf("空命令")
+	}
+	for _, a := range argv {
+		for _, r := range a {
+			if r == 0 || (r < 0x20 && r != '\t') || r == 0x7f {
+				return fmt.Errorf("参数包含控制字符: %q", a)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeExe 程序名去掉路径与 Windows 扩展名，便于与规则比较
+func normalizeExe(name string) string {
+	base := strings.ToLower(filepath.Base(filepath.ToSlash(name)))
+	for _, ext := range []string{".exe", ".cmd", ".bat"} {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return base
+}
+
+// matchCommandPrefix argv 是否以规则的各个词开头
+func matchCommandPrefix(argv []string, rule string) bool {
+	words := strings.Fields(rule)
+	if len(words) == 0 || len(words) > len(argv) {
+		return false
+	}
+	if normalizeExe(argv[0]) != normalizeExe(words[0]) {
+		return false
+	}
+	for i := 1; i < len(words); i++ {
+		if argv[i] != words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckCommand 依据策略检查一条命令（argv 已切分）
+func CheckCommand(settings CommandSettings, argv []string) error {
+	if err := sanitizeArgs(argv); err != nil {
+		return fmt.Errorf("%w: %v", ErrCommandDenied, err)
+	}
+	for _, rule := range append(append([]string{}, DefaultCommandDeny...), settings.Deny...) {
+		if matchCommandPrefix(argv, rule) {
+			return fmt.Errorf("%w: 命中黑名单 %q", ErrCommandDenied, rule)
+		}
+	}
+	allow := settings.Allow
+	if len(allow) == 0 {
+		allow = DefaultCommandAllow
+	}
+	for _, rule := range allow {
+		if matchCommandPrefix(argv, rule) {
+			return checkEscapeFlags(argv)
+		}
+	}
+	return fmt.Errorf("%w: %q 不在白名单中 (settings.json commands.allow)", ErrCommandDenied, strings.Join(argv, " "))
+}
+
+// checkEscapeFlags 拒绝白名单命令携带的逃逸参数
+func checkEscapeFlags(argv []string) error {
+	flags := commandEscapeFlags[normalizeExe(argv[0])]
+	for _, arg := range argv[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name = name[:i]
+		}
+		for _, f := range flags {
+			if name == f {
+				return fmt.Errorf("%w: 参数 %q 可执行任意程序或写入任意路径", ErrCommandDenied, arg)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckCommandEnv 检查附加环境变量：键名须为合法标识符，且不得劫持被执行的程序
+func CheckCommandEnv(env map[string]string) error {
+	for k, v := range env {
+		if k == "" || strings.ContainsAny(k, "= \t\x00") || strings.ContainsRune(v, 0) {
+			return fmt.Errorf("%w: 无效的环境变量 %q", ErrCommandDenied, k)
+		}
+		key := strings.ToUpper(k)
+		if allowedEnvKeys[key] {
+			continue
+		}
+		denied := false
+		for _, d := range deniedEnvKeys {
+			denied = denied || key == d
+		}
+		for _, p := range deniedEnvPrefixes {
+			denied = denied || strings.HasPrefix(key, p)
+		}
+		if denied {
+			return fmt.Errorf("%w: 不允许设置环境变量 %s（可改变被执行的程序）", ErrCommandDenied, k)
+		}
+	}
+	return nil
+}
+
+// splitShellSegments 按引号外的 ; | & 与换行切分 shell 命令，逐段检查。
+// 引号外出现重定向 (< >) 时报错：重定向可写入任意文件，逐段检查无法约束
+func splitShellSegments(line string) ([]string, error) {
+	var segs []string
+	var cur strings.Builder
+	var quote rune
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			segs = append(segs, s)
+		}
+		cur.Reset()
+	}
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			cur.WriteRune(r)
+		case r == '<' || r == '>':
+			return nil, fmt.Errorf("%w: 不允许重定向 (%c)", ErrCommandDenied, r)
+		case r == ';' || r == '|' || r == '&' || r == '\n':
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return segs, nil
+}
+
+// resolveCommandDir 工作目录必须位于项目内
+func resolveCommandDir(projectRoot, dir string) (string, error) {
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(dir) == "" {
+		return root, nil
+	}
+	target := dir
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+	target = filepath.Clean(target)
+	if rel, err := filepath.Rel(root, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: 工作目录越出项目根: %s", ErrCommandDenied, dir)
+	}
+	return target, nil
+}
+
+var commandAuditMu sync.Mutex
+
+// AppendCommandAudit 追加一条审计记录
+func AppendCommandAudit(projectRoot string, rec CommandAuditRecord) error {
+	commandAuditMu.Lock()
+	defer commandAuditMu.Unlock()
+
+	dir, err := EnsureDataDir(projectRoot)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, commandAuditFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// RunGuardedCommand 检查策略后执行命令并记录审计（等价于 NewCommandRunner(projectRoot).Run）
+// 策略拒绝时返回包装 ErrCommandDenied 的错误；命令非零退出不视为错误，由 ExitCode 体现
+func RunGuardedCommand(ctx context.Context, projectRoot string, req CommandRequest) (*CommandResult, error) {
+	return NewCommandRunner(projectRoot).Run(ctx, req)
+}
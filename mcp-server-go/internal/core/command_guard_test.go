@@ -0,0 +1,79 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	settings := CommandSettings{Deny: []string{"go test -exec"}}
+	cases := []struct {
+		line  string
+		allow bool
+	}{
+		{"go test ./...", true},
+		{"/usr/local/go/bin/go vet ./internal/...", true},
+		{"npm run lint", true},
+		{"go test -exec sudo ./...", false},
+		{"rm -rf /", false},
+		{"git push origin main", false},
+		{"python setup.py install", false},
+		{"go test -toolexec=/tmp/x ./...", false},
+		{"go build -o /home/u/.bashrc .", false},
+		{"go vet -vettool /tmp/x ./...", false},
+		{"git diff --output=/tmp/x", false},
+		{"cargo build --config build.rustc-wrapper=/tmp/x", false},
+		{"python3 -m pytest -p evil_plugin", false},
+		{"go test -run TestOutput -count=1 ./...", true},
+		{"npx tsc -p tsconfig.json", true},
+	}
+	for _, c := range cases {
+		argv, err := SplitCommandLine(c.line)
+		if err != nil {
+			t.Fatalf("split %q: %v", c.line, err)
+		}
+		err = CheckCommand(settings, argv)
+		if c.allow && err != nil {
+			t.Errorf("%q should be allowed: %v", c.line, err)
+		}
+		if !c.allow && !errors.Is(err, ErrCommandDenied) {
+			t.Errorf("%q should be denied, got %v", c.line, err)
+		}
+	}
+
+	if !hasShellSyntax("go test ./... && rm -rf .") || hasShellSyntax(`go test -run "A|B" ./...`) {
+		t.Errorf("shell syntax detection mismatch")
+	}
+	if argv, _ := SplitCommandLine(`go test -run "A|B" ./...`); len(argv) != 5 || argv[3] != "A|B" {
+		t.Errorf("unexpected split: %q", argv)
+	}
+}
+
+func TestSplitShellSegments(t *testing.T) {
+	segs, err := splitShellSegments(`go vet ./... && go test -run "A|B;C" ./... | tail -5 & rm x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 4 || segs[1] != `go test -run "A|B;C" ./...` || segs[3] != "rm x" {
+		t.Errorf("unexpected segments: %q", segs)
+	}
+	for _, line := range []string{"go test ./... > ~/.bashrc", "go test ./... >> out.log", "go test < in.txt", "go test 2>&1"} {
+		if _, err := splitShellSegments(line); !errors.Is(err, ErrCommandDenied) {
+			t.Errorf("%q: redirection should be denied, got %v", line, err)
+		}
+	}
+	if _, err := splitShellSegments(`go test -run "a>b" ./...`); err != nil {
+		t.Errorf("quoted > is a literal argument: %v", err)
+	}
+}
+
+func TestCheckCommandEnv(t *testing.T) {
+	if err := CheckCommandEnv(map[string]string{"CGO_ENABLED": "0", "GOOS": "linux", "DATABASE_URL": "sqlite://x"}); err != nil {
+		t.Errorf("plain env should be allowed: %v", err)
+	}
+	for _, key := range []string{"GOFLAGS", "LD_PRELOAD", "dyld_insert_libraries", "GIT_SSH_COMMAND", "PATH", "CGO_CFLAGS", "NODE_OPTIONS", "A=B"} {
+		if err := CheckCommandEnv(map[string]string{key: "x"}); !errors.Is(err, ErrCommandDenied) {
+			t.Errorf("env %s should be denied, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// CommandRunner：代理发起的命令（verify、审计器、测试）统一从这里执行
+// ============================================================================
+//
+// 策略检查沿用 CheckCommand；工作目录限制在项目内；超时取 settings 与请求中较短者；
+// 输出超过上限保留尾部；审计写入项目数据库 command_audit 表（输出只保留最后 2KB）。
+
+const commandAuditTailSize = 2 << 10
+
+// CommandRunner 项目级命令执行器
+type CommandRunner struct {
+	projectRoot string
+	db          *DatabaseManager // 为空时审计回退到 command_audit.jsonl
+}
+
+// NewCommandRunner 创建执行器；项目数据库不可用时仍可执行，审计写入 JSONL
+func NewCommandRunner(projectRoot string) *CommandRunner {
+	r := &CommandRunner{projectRoot: projectRoot}
+	if db, err := GetDBForProject(projectRoot); err == nil {
+		r.db = db
+	}
+	return r
+}
+
+// Run 检查策略后执行命令并记录审计
+// 策略拒绝时返回包装 ErrCommandDenied 的错误；命令非零退出不视为错误，由 ExitCode 体现
+func (r *CommandRunner) Run(ctx context.Context, req CommandRequest) (*CommandResult, error) {
+	settings := LoadProjectSettings(r.projectRoot).Commands
+	rec := CommandAuditRecord{Time: time.Now().Format(time.RFC3339), Source: req.Source, Dir: req.Dir, ExitCode: -1}
+	deny := func(err error) (*CommandResult, error) {
+		rec.Reason = err.Error()
+		r.audit(rec)
+		return nil, err
+	}
+
+	argv := req.Args
+	useShell := false
+	if len(argv) == 0 {
+		if hasShellSyntax(req.Line) {
+			rec.Argv = []string{req.Line}
+			if !settings.AllowShell {
+				return deny(fmt.Errorf("%w: 包含 shell 语法，默认不经 shell 执行 (可在 settings.json 设置 commands.allow_shell)", ErrCommandDenied))
+			}
+			if strings.Contains(req.Line, "`") || strings.Contains(req.Line, "$(") {
+				return deny(fmt.Errorf("%w: 不允许命令替换", ErrCommandDenied))
+			}
+			segs, err := splitShellSegments(req.Line)
+			if err != nil {
+				return deny(err)
+			}
+			for _, seg := range segs {
+				segArgv, err := SplitCommandLine(seg)
+				if err != nil {
+					return deny(fmt.Errorf("%w: %v", ErrCommandDenied, err))
+				}
+				if err := CheckCommand(settings, segArgv); err != nil {
+					return deny(err)
+				}
+			}
+			useShell = true
+		} else {
+			parsed, err := SplitCommandLine(req.Line)
+			if err != nil {
+				return deny(fmt.Errorf("%w: %v", ErrCommandDenied, err))
+			}
+			argv = parsed
+		}
+	}
+	if !useShell {
+		rec.Argv = argv
+		if err := CheckCommand(settings, argv); err != nil {
+			return deny(err)
+		}
+	}
+	rec.Shell = useShell
+	if err := CheckCommandEnv(req.Env); err != nil {
+		return deny(err)
+	}
+
+	dir, err := resolveCommandDir(r.projectRoot, req.Dir)
+	if err != nil {
+		return deny(err)
+	}
+
+	timeout := time.Duration(settings.TimeoutSeconds) * time.Second
+	if req.Timeout > 0 && req.Timeout < timeout {
+		timeout = req.Timeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if useShell {
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(runCtx, "cmd", "/c", req.Line)
+		} else {
+			cmd = exec.CommandContext(runCtx, "sh", "-c", req.Line)
+		}
+	} else {
+		cmd = exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	}
+	cmd.Dir = dir
+	if len(req.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range req.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	if req.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(req.Stdin)
+	}
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if req.SeparateStderr {
+		cmd.Stderr = &stderr
+	}
+
+	started := time.Now()
+	runErr := cmd.Run()
+	result := &CommandResult{Argv: rec.Argv, Shell: useShell, Duration: time.Since(started)}
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		runErr = fmt.Errorf("超时 (%s)", timeout)
+		rec.Reason = runErr.Error()
+	case runErr == nil:
+		result.ExitCode = 0
+	case errors.As(runErr, new(*exec.ExitError)):
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	default:
+		result.ExitCode = -1
+		rec.Reason = runErr.Error()
+	}
+	maxOutput := req.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = commandMaxOutputSize
+	}
+	result.Output, result.Truncated = tailOutput(out.String(), maxOutput)
+	result.Stderr, _ = tailOutput(stderr.String(), commandAuditTailSize)
+
+	rec.Allowed = true
+	rec.ExitCode = result.ExitCode
+	rec.DurationMs = result.Duration.Milliseconds()
+	rec.OutputTail, _ = tailOutput(result.Output, commandAuditTailSize)
+	if req.SeparateStderr {
+		rec.OutputTail = result.Stderr // stdout 是供程序解析的数据，审计只保留 stderr
+	}
+	r.audit(rec)
+	if result.ExitCode == -1 {
+		return result, fmt.Errorf("命令执行失败: %v", runErr)
+	}
+	return result, nil
+}
+
+// tailOutput 超过上限时保留尾部（失败信息通常在最后）
+func tailOutput(output string, max int) (string, bool) {
+	if len(output) <= max {
+		return output, false
+	}
+	return "...(输出已截断)\n" + strings.ToValidUTF8(output[len(output)-max:], ""), true
+}
+
+// audit 写入数据库审计表，失败时回退到 JSONL
+func (r *CommandRunner) audit(rec CommandAuditRecord) {
+	if r.db != nil {
+		argv, _ := json.Marshal(rec.Argv)
+		_, err := r.db.Exec(`INSERT INTO command_audit (time, source, argv, dir, shell, allowed, reason, exit_code, duration_ms, output_tail)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			rec.Time, rec.Source, string(argv), rec.Dir, rec.Shell, rec.Allowed, rec.Reason, rec.ExitCode, rec.DurationMs, rec.OutputTail)
+		if err == nil {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "[Command][WARN] 写入审计表失败，改写 JSONL: %v\n", err)
+	}
+	if err := AppendCommandAudit(r.projectRoot, rec); err != nil {
+		fmt.Fprintf(os.Stderr, "[Command][WARN] 写入审计失败: %v\n", err)
+	}
+}
+
+// RecentAudits 最近的审计记录（新→旧）；source 非空时按字面前缀过滤
+func (r *CommandRunner) RecentAudits(source string, limit int) ([]CommandAuditRecord, error) {
+	if r.db == nil {
+		return nil, fmt.Errorf("项目数据库不可用")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := r.db.Query(`SELECT time, COALESCE(source, ''), argv, COALESCE(dir, ''), shell, allowed, COALESCE(reason, ''), exit_code, duration_ms, COALESCE(output_tail, '')
+		FROM command_audit WHERE substr(COALESCE(source, ''), 1, length(?)) = ? ORDER BY id DESC LIMIT ?`, source, source, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CommandAuditRecord
+	for rows.Next() {
+		var rec CommandAuditRecord
+		var argv string
+		if err := rows.Scan(&rec.Time, &rec.Source, &argv, &rec.Dir, &rec.Shell, &rec.Allowed, &rec.Reason, &rec.ExitCode, &rec.DurationMs, &rec.OutputTail); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(argv), &rec.Argv)
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
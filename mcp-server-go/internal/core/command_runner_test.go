@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandRunnerAudit(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".mcp-config"), 0755)
+	if err := os.WriteFile(filepath.Join(root, ".mcp-config", "settings.json"), []byte(`{"commands": {"allow": ["go version"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewCommandRunner(root)
+	res, err := r.Run(ctx, CommandRequest{Line: "go version", Source: "test:ok", MaxOutput: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ExitCode != 0 || !res.Truncated || !strings.HasPrefix(res.Output, "...(输出已截断)") {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if _, err := r.Run(ctx, CommandRequest{Line: "go version", Dir: "../outside", Source: "test:escape"}); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("dir escape should be denied, got %v", err)
+	}
+	if _, err := r.Run(ctx, CommandRequest{Line: "go version", Env: map[string]string{"GOFLAGS": "-toolexec=/tmp/x"}, Source: "test:env"}); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("hijacking env should be denied, got %v", err)
+	}
+	if _, err := r.Run(ctx, CommandRequest{Line: "go test ./...", Source: "test:deny"}); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("command outside allowlist should be denied, got %v", err)
+	}
+
+	recs, err := r.RecentAudits("test:", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 4 || recs[0].Source != "test:deny" || recs[0].Allowed || !recs[3].Allowed || recs[3].Argv[1] != "version" {
+		t.Fatalf("unexpected audits: %+v", recs)
+	}
+	if only, _ := r.RecentAudits("test:ok", 10); len(only) != 1 {
+		t.Errorf("source filter: %+v", only)
+	}
+	// 前缀按字面匹配，_ 与 % 不是通配符
+	for _, prefix := range []string{"test_", "%", "t%:ok"} {
+		if recs, _ := r.RecentAudits(prefix, 10); len(recs) != 0 {
+			t.Errorf("prefix %q should match literally, got %d audits", prefix, len(recs))
+		}
+	}
+	if all, _ := r.RecentAudits("", 10); len(all) != 4 {
+		t.Errorf("empty prefix should list all audits, got %d", len(all))
+	}
+}
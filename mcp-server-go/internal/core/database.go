@@ -41,7 +41,11 @@ func GetDBForProject(projectRoot string) (*DatabaseManager, error) {
 		return nil, fmt.Errorf("invalid project path: %s", absRoot)
 	}
 
-	dbPath := filepath.Join(absRoot, ".mcp-data", "mcp_memory.db")
+	dataDir, err := EnsureDataDir(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	dbPath := filepath.Join(dataDir, "mcp_memory.db")
 	mgr := &DatabaseManager{
 		dbPath: dbPath,
 	}
@@ -176,6 +180,82 @@ func (m *DatabaseManager) healSchema() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (task_id) REFERENCES task_chains(task_id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS file_locks (
+			path TEXT PRIMARY KEY,
+			task_id TEXT NOT NULL,
+			holder TEXT,
+			expires_at TEXT NOT NULL,
+			created_at TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS experiments (
+			task_id TEXT PRIMARY KEY,
+			experiment TEXT NOT NULL,
+			protocol TEXT,
+			status TEXT DEFAULT 'running',
+			started_at TEXT NOT NULL,
+			finished_at TEXT,
+			duration_sec INTEGER DEFAULT 0,
+			retries INTEGER DEFAULT 0,
+			reinits INTEGER DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS glossary (
+			term TEXT PRIMARY KEY COLLATE NOCASE,
+			definition TEXT,
+			aliases TEXT,
+			symbols TEXT,
+			paths TEXT,
+			updated_at TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS timeline_annotations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL DEFAULT 'note',
+			title TEXT NOT NULL,
+			content TEXT,
+			author TEXT,
+			at TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS fact_suggestions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			occurrences INTEGER DEFAULT 0,
+			memo_ids TEXT,
+			status TEXT DEFAULT 'pending',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS embeddings (
+			kind TEXT NOT NULL,
+			ref_id INTEGER NOT NULL,
+			model TEXT NOT NULL,
+			dim INTEGER NOT NULL,
+			vector BLOB NOT NULL,
+			content_hash TEXT NOT NULL,
+			updated_at TEXT,
+			PRIMARY KEY (kind, ref_id, model)
+		)`,
+		`CREATE TABLE IF NOT EXISTS checkpoints (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			task_id TEXT,
+			files_json TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			restored_at TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS command_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			time TEXT NOT NULL,
+			source TEXT,
+			argv TEXT NOT NULL,
+			dir TEXT,
+			shell INTEGER DEFAULT 0,
+			allowed INTEGER DEFAULT 0,
+			reason TEXT,
+			exit_code INTEGER,
+			duration_ms INTEGER,
+			output_tail TEXT
+		)`,
 	}
 
 	for _, s := range schemas {
@@ -190,6 +270,11 @@ func (m *DatabaseManager) healSchema() error {
 		"CREATE INDEX IF NOT EXISTS idx_memos_category ON memos(category)",
 		"CREATE INDEX IF NOT EXISTS idx_memos_timestamp ON memos(timestamp DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_task_chain_events_task ON task_chain_events(task_id, created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_file_locks_task ON file_locks(task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_experiments_name ON experiments(experiment, protocol)",
+		"CREATE INDEX IF NOT EXISTS idx_timeline_annotations_at ON timeline_annotations(at)",
+		"CREATE INDEX IF NOT EXISTS idx_checkpoints_task ON checkpoints(task_id)",
+		"CREATE INDEX IF NOT EXISTS idx_command_audit_source ON command_audit(source)",
 	}
 	for _, idx := range indexes {
 		if _, err := m.db.Exec(idx); err != nil {
@@ -197,15 +282,8 @@ func (m *DatabaseManager) healSchema() error {
 		}
 	}
 
-	// 3. 数据迁移（ADD COLUMN，忽略已存在错误）
-	migrations := []string{
-		"ALTER TABLE task_chains ADD COLUMN reinit_count INTEGER DEFAULT 0",
-	}
-	for _, mig := range migrations {
-		m.db.Exec(mig) // 忽略错误（列已存在时会报错，属正常）
-	}
-
-	return nil
+	// 3. 版本化迁移（见 migrations.go）
+	return m.migrate()
 }
 
 // Exec 执行写操作
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ============================================================================
+// 数据目录：记忆库、符号索引与所有生成产物的存放位置
+// ============================================================================
+//
+// 解析顺序：
+//   1. settings.json storage.data_dir
+//      - "global"      → ~/.mpm/<project-hash>/
+//      - 绝对路径      → 原样使用（支持 ~ 开头）
+//      - 相对路径      → 相对项目根目录
+//   2. 环境变量 MPM_DATA_HOME → $MPM_DATA_HOME/<project-hash>/（对所有项目生效）
+//   3. 默认 <project>/.mcp-data
+//
+// 解析结果按项目缓存，EnsureDataDir（initialize_project / 打开数据库时）重新解析，
+// 会话中途修改 settings.json 不会让进程内不同模块解析出不同目录。
+//
+// dev-log.md 与 dev-log-archive/ 默认留在项目根目录（可提交到 git）；
+// 只有显式配置了 storage.data_dir 或 MPM_DATA_HOME 时才写到数据目录下。
+
+const (
+	// LegacyDataDirName 项目内的默认数据目录
+	LegacyDataDirName = ".mcp-data"
+	// DataHomeEnv 全局数据根目录环境变量
+	DataHomeEnv = "MPM_DATA_HOME"
+	// DataDirEnv 传给 Rust 索引引擎与 Python 时间线脚本的已解析数据目录
+	DataDirEnv = "MPM_DATA_DIR"
+
+	devLogName         = "dev-log.md"
+	memoArchiveDirName = "dev-log-archive"
+	memoArchiveName    = "memo_archive.jsonl"
+)
+
+// ProjectDataKey 项目在全局数据目录下的子目录名：<目录名>-<路径哈希>
+func ProjectDataKey(projectRoot string) string {
+	abs, err := filepath.Abs(projectRoot)
+	if err != nil {
+		abs = projectRoot
+	}
+	sum := sha1.Sum([]byte(strings.ToLower(filepath.ToSlash(filepath.Clean(abs)))))
+	return fmt.Sprintf("%s-%s", filepath.Base(abs), hex.EncodeToString(sum[:])[:10])
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// resolvedDataDir 项目数据目录的解析结果
+type resolvedDataDir struct {
+	dir      string
+	explicit bool // 由 storage.data_dir 或 MPM_DATA_HOME 显式指定
+}
+
+var (
+	dataDirMu    sync.RWMutex
+	dataDirCache = map[string]resolvedDataDir{}
+)
+
+func absRoot(projectRoot string) string {
+	abs, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return projectRoot
+	}
+	return abs
+}
+
+// resolveDataDir 读取 settings.json 与环境变量解析数据目录
+func resolveDataDir(abs string) resolvedDataDir {
+	switch configured := strings.TrimSpace(LoadProjectSettings(abs).Storage.DataDir); {
+	case configured == "global":
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return resolvedDataDir{dir: filepath.Join(home, ".mpm", ProjectDataKey(abs)), explicit: true}
+		}
+	case configured != "":
+		configured = expandHome(configured)
+		if filepath.IsAbs(configured) {
+			return resolvedDataDir{dir: filepath.Clean(configured), explicit: true}
+		}
+		return resolvedDataDir{dir: filepath.Join(abs, configured), explicit: true}
+	}
+
+	if base := strings.TrimSpace(os.Getenv(DataHomeEnv)); base != "" {
+		return resolvedDataDir{dir: filepath.Join(expandHome(base), ProjectDataKey(abs)), explicit: true}
+	}
+	return resolvedDataDir{dir: filepath.Join(abs, LegacyDataDirName)}
+}
+
+// lookupDataDir 返回缓存的解析结果，首次访问时解析一次
+func lookupDataDir(projectRoot string) resolvedDataDir {
+	abs := absRoot(projectRoot)
+	dataDirMu.RLock()
+	r, ok := dataDirCache[abs]
+	dataDirMu.RUnlock()
+	if ok {
+		return r
+	}
+	return refreshDataDir(abs)
+}
+
+// refreshDataDir 重新解析并更新缓存
+func refreshDataDir(abs string) resolvedDataDir {
+	r := resolveDataDir(abs)
+	dataDirMu.Lock()
+	dataDirCache[abs] = r
+	dataDirMu.Unlock()
+	return r
+}
+
+// DataDir 返回项目的数据目录（绝对路径，不保证已存在）
+func DataDir(projectRoot string) string {
+	return lookupDataDir(projectRoot).dir
+}
+
+// DataPath 数据目录下的文件路径
+func DataPath(projectRoot string, elem ...string) string {
+	return filepath.Join(append([]string{DataDir(projectRoot)}, elem...)...)
+}
+
+// logDir dev-log.md 与 dev-log-archive/ 所在目录：默认项目根目录，显式配置数据目录时为数据目录
+func logDir(projectRoot string) string {
+	if r := lookupDataDir(projectRoot); r.explicit {
+		return r.dir
+	}
+	return absRoot(projectRoot)
+}
+
+// DevLogPath 人类可读的开发日志 dev-log.md
+func DevLogPath(projectRoot string) string {
+	return filepath.Join(logDir(projectRoot), devLogName)
+}
+
+// MemoArchiveDir memo 物理备份目录 dev-log-archive/
+func MemoArchiveDir(projectRoot string) string {
+	return filepath.Join(logDir(projectRoot), memoArchiveDirName)
+}
+
+// MemoArchivePath append-only 的 memo 归档 memo_archive.jsonl
+func MemoArchivePath(projectRoot string) string {
+	return filepath.Join(MemoArchiveDir(projectRoot), memoArchiveName)
+}
+
+// EnsureDataDir 重新解析并创建数据目录；若配置了外部目录且项目内仍有旧 .mcp-data，则先迁移，
+// 再把项目根目录的 dev-log.md 与 dev-log-archive/ 复制到数据目录（原文件保留）
+func EnsureDataDir(projectRoot string) (string, error) {
+	resolved := refreshDataDir(absRoot(projectRoot))
+	dir := resolved.dir
+	if moved, err := MigrateLegacyDataDir(projectRoot); err != nil {
+		fmt.Fprintf(os.Stderr, "[DataDir][WARN] 迁移 %s 失败，继续使用新目录: %v\n", LegacyDataDirName, err)
+	} else if moved {
+		fmt.Fprintf(os.Stderr, "[DataDir] 已将 %s 迁移到 %s\n", LegacyDataDirName, dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return dir, err
+	}
+	if !resolved.explicit {
+		return dir, nil
+	}
+	for _, name := range []string{devLogName, memoArchiveDirName} {
+		if copied, err := copyLegacyRootEntry(projectRoot, dir, name); err != nil {
+			fmt.Fprintf(os.Stderr, "[DataDir][WARN] 复制项目根目录的 %s 失败: %v\n", name, err)
+		} else if copied {
+			fmt.Fprintf(os.Stderr, "[DataDir][WARN] 已将项目根目录的 %s 复制到 %s，此后写入数据目录；原文件保留，确认无误后可自行删除\n", name, dir)
+		}
+	}
+	return dir, nil
+}
+
+// copyLegacyRootEntry 将项目根目录下的 dev-log 文件/目录复制到数据目录；数据目录已有同名项时跳过。
+// 原文件可能已提交到 git，因此从不删除
+func copyLegacyRootEntry(projectRoot, dataDir, name string) (bool, error) {
+	abs := absRoot(projectRoot)
+	legacy, target := filepath.Join(abs, name), filepath.Join(dataDir, name)
+	if filepath.Clean(legacy) == filepath.Clean(target) {
+		return false, nil
+	}
+	st, err := os.Stat(legacy)
+	if err != nil {
+		return false, nil
+	}
+	if _, err := os.Stat(target); err == nil {
+		return false, nil
+	}
+	if st.IsDir() {
+		return true, copyDir(legacy, target)
+	}
+	return true, copyFile(legacy, target)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, in); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// MigrateLegacyDataDir 将项目内的 .mcp-data 移动到配置的数据目录
+// 目标目录已有数据时不覆盖，返回错误由调用方提示用户手动处理
+func MigrateLegacyDataDir(projectRoot string) (bool, error) {
+	abs, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return false, err
+	}
+	legacy := filepath.Join(abs, LegacyDataDirName)
+	target := DataDir(abs)
+	if filepath.Clean(legacy) == filepath.Clean(target) {
+		return false, nil
+	}
+	if st, err := os.Stat(legacy); err != nil || !st.IsDir() {
+		return false, nil
+	}
+	if entries, err := os.ReadDir(target); err == nil && len(entries) > 0 {
+		return false, fmt.Errorf("目标目录 %s 已有数据，请手动合并后删除 %s", target, legacy)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return false, err
+	}
+	_ = os.Remove(target) // 空目录会阻止 Rename
+	if err := os.Rename(legacy, target); err == nil {
+		return true, nil
+	}
+	// 跨设备时 Rename 失败，退化为复制后删除
+	if err := copyDir(legacy, target); err != nil {
+		return false, err
+	}
+	return true, os.RemoveAll(legacy)
+}
+
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		out := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(out, 0755)
+		}
+		return copyFile(p, out)
+	})
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureDataDir_MigratesLegacyDir(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(DataHomeEnv, "")
+	if err := os.MkdirAll(filepath.Join(root, LegacyDataDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, LegacyDataDirName, "symbols.db"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 旧版本写在项目根目录的开发日志与 memo 归档
+	os.MkdirAll(filepath.Join(root, "dev-log-archive"), 0755)
+	os.WriteFile(filepath.Join(root, "dev-log-archive", "memo_archive.jsonl"), []byte("{}\n"), 0644)
+	os.WriteFile(filepath.Join(root, "dev-log.md"), []byte("# log"), 0644)
+	if got := DataDir(root); got != filepath.Join(root, LegacyDataDirName) {
+		t.Fatalf("default data dir = %s", got)
+	}
+	if DevLogPath(root) != filepath.Join(root, "dev-log.md") || MemoArchiveDir(root) != filepath.Join(root, "dev-log-archive") {
+		t.Fatalf("dev-log should stay in the project root by default: %s", DevLogPath(root))
+	}
+
+	external := filepath.Join(t.TempDir(), "mpm")
+	t.Setenv(DataHomeEnv, external)
+	if got := DataDir(root); got != filepath.Join(root, LegacyDataDirName) {
+		t.Fatalf("data dir should stay cached until EnsureDataDir: %s", got)
+	}
+	dir, err := EnsureDataDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(external, ProjectDataKey(root)) {
+		t.Fatalf("data dir = %s", dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "symbols.db")); err != nil {
+		t.Fatalf("legacy data not migrated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, LegacyDataDirName)); !os.IsNotExist(err) {
+		t.Fatalf("legacy dir still present: %v", err)
+	}
+	if _, err := os.Stat(MemoArchivePath(root)); err != nil || MemoArchivePath(root) != filepath.Join(dir, "dev-log-archive", "memo_archive.jsonl") {
+		t.Fatalf("memo archive not copied into data dir: %v", err)
+	}
+	if _, err := os.Stat(DevLogPath(root)); err != nil || DevLogPath(root) != filepath.Join(dir, "dev-log.md") {
+		t.Fatalf("dev-log.md not copied into data dir: %v", err)
+	}
+	// 项目根目录的原文件可能已提交到 git，只复制不删除
+	for _, name := range []string{"dev-log.md", "dev-log-archive"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("%s should be kept in project root: %v", name, err)
+		}
+	}
+
+	// 数据目录已有副本时不覆盖
+	os.WriteFile(DevLogPath(root), []byte("# newer"), 0644)
+	if _, err := EnsureDataDir(root); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(DevLogPath(root)); string(raw) != "# newer" {
+		t.Errorf("existing dev-log.md in data dir overwritten: %q", raw)
+	}
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ========== 向量化提供方 (settings.json embedding 段) ==========
+//
+// 语义检索只依赖 Embedder 接口，提供方可插拔：
+//   openai  — OpenAI 兼容的 POST {endpoint}/embeddings（也适用于本地部署的 ONNX / Ollama / TEI 服务）
+//   command — 本地命令，stdin 读入 JSON 字符串数组，stdout 输出向量数组（便于直接调用本地 ONNX 模型脚本）
+//   hash    — 内置离线哈希向量（词 + 中文二字组 + 字符三元组），无需模型，只能覆盖字面相近的改写
+
+// Embedder 把文本转为向量
+type Embedder interface {
+	// Model 向量所属模型标识，不同模型的向量不可混用
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+const (
+	defaultEmbeddingEndpoint = "https://api.openai.com/v1"
+	defaultEmbeddingModel    = "text-embedding-3-small"
+	hashEmbeddingDim         = 256
+	embeddingTimeout         = 60 * time.Second
+	embeddingMaxOutput       = 64 << 20
+)
+
+// NewEmbedder 按配置创建提供方；Provider 为空时返回 nil, nil。
+// command 提供方经项目的 CommandRunner 执行（须在 commands.allow 白名单中）
+func NewEmbedder(projectRoot string, cfg EmbeddingSettings) (Embedder, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "":
+		return nil, nil
+	case "openai":
+		keyEnv := cfg.APIKeyEnv
+		if keyEnv == "" {
+			keyEnv = "OPENAI_API_KEY"
+		}
+		endpoint := strings.TrimRight(cfg.Endpoint, "/")
+		if endpoint == "" {
+			endpoint = defaultEmbeddingEndpoint
+		}
+		model := cfg.Model
+		if model == "" {
+			model = defaultEmbeddingModel
+		}
+		return &openAIEmbedder{endpoint: endpoint, model: model, apiKey: os.Getenv(keyEnv),
+			client: &http.Client{Timeout: embeddingTimeout}}, nil
+	case "command":
+		argv, err := SplitCommandLine(cfg.Command)
+		if err != nil || len(argv) == 0 {
+			return nil, fmt.Errorf("embedding.command 无效: %q", cfg.Command)
+		}
+		model := cfg.Model
+		if model == "" {
+			model = "command:" + argv[0]
+		}
+		return &commandEmbedder{projectRoot: projectRoot, argv: argv, model: model}, nil
+	case "hash":
+		return hashEmbedder{}, nil
+	default:
+		return nil, fmt.Errorf("未知的 embedding.provider: %s（可用: openai, command, hash）", cfg.Provider)
+	}
+}
+
+// --- openai ---
+
+type openAIEmbedder struct {
+	endpoint string
+	model    string
+	apiKey   string
+	client   *http.Client
+}
+
+func (e *openAIEmbedder) Model() string { return "openai:" + e.model }
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, _ := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding 接口返回 %d: %s", resp.StatusCode, truncateText(string(data), 200))
+	}
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("解析 embedding 响应失败: %w", err)
+	}
+	out := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index >= 0 && d.Index < len(out) {
+			out[d.Index] = d.Embedding
+		}
+	}
+	return out, checkEmbeddings(out)
+}
+
+// --- command ---
+
+type commandEmbedder struct {
+	projectRoot string
+	argv        []string
+	model       string
+}
+
+func (e *commandEmbedder) Model() string { return e.model }
+
+func (e *commandEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	input, _ := json.Marshal(texts)
+	res, err := NewCommandRunner(e.projectRoot).Run(ctx, CommandRequest{
+		Args: e.argv, Source: "embedding", Timeout: embeddingTimeout,
+		Stdin: input, SeparateStderr: true, MaxOutput: embeddingMaxOutput,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding 命令执行失败: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return nil, fmt.Errorf("embedding 命令退出码 %d: %s", res.ExitCode, truncateText(res.Stderr, 200))
+	}
+	if res.Truncated {
+		return nil, fmt.Errorf("embedding 命令输出超过 %d MB 上限，请减小 batch_size", embeddingMaxOutput>>20)
+	}
+	var out [][]float32
+	if err := json.Unmarshal([]byte(res.Output), &out); err != nil {
+		return nil, fmt.Errorf("解析 embedding 命令输出失败: %w", err)
+	}
+	if len(out) != len(texts) {
+		return nil, fmt.Errorf("embedding 命令返回 %d 个向量，期望 %d 个", len(out), len(texts))
+	}
+	return out, checkEmbeddings(out)
+}
+
+// --- hash ---
+
+type hashEmbedder struct{}
+
+func (hashEmbedder) Model() string { return fmt.Sprintf("hash-%d", hashEmbeddingDim) }
+
+func (hashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = hashVector(t)
+	}
+	return out, nil
+}
+
+// hashVector 特征哈希：token 与英文单词的字符三元组按符号累加到固定维度
+func hashVector(text string) []float32 {
+	vec := make([]float32, hashEmbeddingDim)
+	add := func(feature string, weight float32) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		if sum&0x80000000 != 0 {
+			weight = -weight
+		}
+		vec[sum%hashEmbeddingDim] += weight
+	}
+	for tok := range factTokens(text) {
+		add("t:"+tok, 1)
+		if r := []rune(tok); len(r) > 4 && r[0] < 128 {
+			for i := 0; i+3 <= len(r); i++ {
+				add("g:"+string(r[i:i+3]), 0.5)
+			}
+		}
+	}
+	normalizeVector(vec)
+	return vec
+}
+
+// --- 向量工具 ---
+
+func checkEmbeddings(vecs [][]float32) error {
+	for i, v := range vecs {
+		if len(v) == 0 {
+			return fmt.Errorf("第 %d 条文本没有返回向量", i+1)
+		}
+	}
+	return nil
+}
+
+func normalizeVector(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// CosineSimilarity 余弦相似度；维度不一致时返回 0
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+func truncateText(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "..."
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ========== Known Facts 冲突检测 ==========
+//
+// 新事实与已有事实主题相近却给出相反或不同的要求时视为候选冲突：
+//   - 极性相反：一条要求（必须/always/use），一条禁止（禁止/never/avoid），其余内容高度重合
+//   - 取值不同：规则句式相同，只有少量词不同（snake_case ↔ camelCase、驼峰 ↔ 下划线）
+// 配置了 embedding 时，语义相近的事实也纳入候选，以覆盖措辞差异较大的情况。
+// 冲突只是提示，由调用方决定并存 (force) 还是取代旧事实 (supersedes)。
+
+// FactConflict 候选冲突
+type FactConflict struct {
+	Fact       KnownFact
+	Similarity float64
+	Reason     string
+}
+
+const (
+	conflictPolarityOverlap = 0.5 // 去掉极性词后的最低重合度
+	conflictValueOverlap    = 0.3 // "取值不同"判定的最低重合度
+	conflictValueMaxDiff    = 3   // "取值不同"时每侧最多的独有词数
+	conflictSemanticScore   = 0.6 // 语义候选的最低余弦相似度
+	conflictSemanticPool    = 20  // 语义候选数量上限
+)
+
+var (
+	negativeMarkers = []string{"禁止", "严禁", "不要", "不得", "不能", "不可", "不应", "避免", "勿", "never", "don't", "dont", "do not", "must not", "should not", "avoid", "no longer"}
+	positiveMarkers = []string{"必须", "务必", "应该", "应当", "总是", "始终", "一律", "统一", "always", "must", "should", "prefer", "use"}
+)
+
+// factPolarity 1 要求 / -1 禁止 / 0 陈述
+func factPolarity(s string) int {
+	lower := strings.ToLower(s)
+	for _, m := range negativeMarkers {
+		if strings.Contains(lower, m) {
+			return -1
+		}
+	}
+	for _, m := range positiveMarkers {
+		if strings.Contains(lower, m) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// stripPolarity 去掉极性词，只保留规则主题
+func stripPolarity(s string) string {
+	s = strings.ToLower(s)
+	for _, m := range negativeMarkers {
+		s = strings.ReplaceAll(s, m, " ")
+	}
+	for _, m := range positiveMarkers {
+		s = strings.ReplaceAll(s, m, " ")
+	}
+	return s
+}
+
+// factContradiction 判断两条事实是否可能冲突；semantic 表示已由向量确认主题相近
+func factContradiction(a, b string, semantic bool) (string, bool) {
+	pa, pb := factPolarity(a), factPolarity(b)
+	if pa*pb == -1 {
+		if semantic || FactSimilarity(stripPolarity(a), stripPolarity(b)) >= conflictPolarityOverlap {
+			return "一条要求、一条禁止", true
+		}
+	}
+	if pa != pb {
+		return "", false
+	}
+	ta, tb := factTokens(a), factTokens(b)
+	shared, onlyA, onlyB := 0, 0, 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		} else {
+			onlyA++
+		}
+	}
+	onlyB = len(tb) - shared
+	if shared < 2 || onlyA == 0 || onlyB == 0 || onlyA > conflictValueMaxDiff || onlyB > conflictValueMaxDiff {
+		return "", false
+	}
+	if semantic || float64(shared)/float64(shared+onlyA+onlyB) >= conflictValueOverlap {
+		return "同一规则给出不同取值", true
+	}
+	return "", false
+}
+
+// FindConflictingFacts 查找可能与 summarize 冲突的有效事实（近似重复不算冲突）；e 为 nil 时只做字面比较
+func (m *MemoryLayer) FindConflictingFacts(ctx context.Context, summarize string, e Embedder, limit int) ([]FactConflict, error) {
+	rows, err := m.dbManager.Query("SELECT "+factColumns+" FROM known_facts WHERE "+activeFactCondition, factExpiryNow())
+	if err != nil {
+		return nil, err
+	}
+	var facts []KnownFact
+	for rows.Next() {
+		if f, err := scanFact(rows); err == nil {
+			facts = append(facts, f)
+		}
+	}
+	rows.Close()
+
+	semantic := make(map[int64]float64)
+	if e != nil {
+		if _, err := m.SyncEmbeddings(ctx, e, 0); err == nil {
+			hits, _ := m.SemanticSearch(ctx, e, summarize, conflictSemanticScore, conflictSemanticPool)
+			for _, h := range hits {
+				if h.Kind == EmbeddingFact {
+					semantic[h.ID] = h.Score
+				}
+			}
+		}
+	}
+
+	var conflicts []FactConflict
+	for _, f := range facts {
+		sim := FactSimilarity(summarize, f.Summarize)
+		if sim >= DefaultFactDuplicateThreshold {
+			continue
+		}
+		score, isSemantic := semantic[f.ID]
+		if reason, ok := factContradiction(summarize, f.Summarize, isSemantic); ok {
+			if isSemantic && score > sim {
+				sim = score
+			}
+			conflicts = append(conflicts, FactConflict{Fact: f, Similarity: sim, Reason: reason})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Similarity > conflicts[j].Similarity })
+	if limit > 0 && len(conflicts) > limit {
+		conflicts = conflicts[:limit]
+	}
+	return conflicts, nil
+}
+
+// SupersedeFact 用新事实取代旧事实：旧事实记下 superseded_by，不再被检索
+func (m *MemoryLayer) SupersedeFact(ctx context.Context, oldID, newID int64) error {
+	if oldID == newID {
+		return fmt.Errorf("事实不能取代自身")
+	}
+	res, err := m.dbManager.Exec("UPDATE known_facts SET superseded_by = ? WHERE id = ? AND superseded_by IS NULL", newID, oldID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("事实 %d 不存在或已被取代", oldID)
+	}
+	return nil
+}
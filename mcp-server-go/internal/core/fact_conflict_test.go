@@ -0,0 +1,44 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindConflictingFacts(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snake, _ := mem.SaveFact(ctx, "规范", "Always use snake_case for JSON fields")
+	mem.SaveFact(ctx, "规范", "Always use gofmt before commit")
+	ban, _ := mem.SaveFact(ctx, "铁律", "core 包禁止依赖 tools 包")
+
+	conflicts, err := mem.FindConflictingFacts(ctx, "Always use camelCase for JSON fields", nil, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Fact.ID != snake {
+		t.Fatalf("conflicts = %+v, want fact %d", conflicts, snake)
+	}
+
+	if conflicts, _ = mem.FindConflictingFacts(ctx, "core 包必须依赖 tools 包", nil, 5); len(conflicts) != 1 || conflicts[0].Fact.ID != ban {
+		t.Fatalf("polarity conflicts = %+v, want fact %d", conflicts, ban)
+	}
+	if conflicts, _ = mem.FindConflictingFacts(ctx, "core 包禁止依赖 tools 包！", nil, 5); len(conflicts) != 0 {
+		t.Fatalf("near-duplicate should not be a conflict: %+v", conflicts)
+	}
+
+	camel, _ := mem.SaveFact(ctx, "规范", "Always use camelCase for JSON fields")
+	if err := mem.SupersedeFact(ctx, snake, camel); err != nil {
+		t.Fatal(err)
+	}
+	facts, _ := mem.QueryFacts(ctx, "JSON", 10)
+	if len(facts) != 1 || facts[0].ID != camel {
+		t.Fatalf("facts after supersede = %+v", facts)
+	}
+	if err := mem.SupersedeFact(ctx, snake, camel); err == nil {
+		t.Fatal("superseding twice should fail")
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ========== Known Facts 作用域、优先级与有效期 ==========
+//
+// 作用域把事实绑定到代码的某一部分，manager_analyze 只加载与当前任务符号/路径相关的作用域事实：
+//   path:internal/core      路径前缀（文件或目录）
+//   module:tools            任一路径段等于该名称
+//   symbol:SaveFact         任务涉及的符号（不区分大小写）
+// 没有作用域的事实为全局事实，仍按关键词检索。有效期到达或被新事实取代后，事实不再被任何检索返回。
+
+// 事实优先级
+const (
+	FactPriorityHigh   = "high"
+	FactPriorityMedium = "medium"
+	FactPriorityLow    = "low"
+)
+
+// FactOptions 保存事实的可选属性
+type FactOptions struct {
+	Scope    string        // 见 NormalizeFactScope
+	Priority string        // high / medium / low，默认 medium
+	TTL      time.Duration // 0 表示永久
+}
+
+// FactScopeContext 当前任务涉及的符号与路径
+type FactScopeContext struct {
+	Symbols []string
+	Paths   []string
+}
+
+const (
+	factColumns         = "id, type, summarize, created_at, scope, priority, expires_at"
+	factExpiryLayout    = "2006-01-02 15:04:05"
+	activeFactCondition = "superseded_by IS NULL AND (expires_at IS NULL OR expires_at = '' OR expires_at > ?)"
+)
+
+// factExpiryNow activeFactCondition 的比较参数（UTC 文本，与写入格式一致可按字典序比较）
+func factExpiryNow() string {
+	return time.Now().UTC().Format(factExpiryLayout)
+}
+
+func scanFact(rows *sql.Rows) (KnownFact, error) {
+	var f KnownFact
+	var scope, priority, expires sql.NullString
+	if err := rows.Scan(&f.ID, &f.Type, &f.Summarize, &f.CreatedAt, &scope, &priority, &expires); err != nil {
+		return f, err
+	}
+	f.Scope = scope.String
+	f.Priority = priority.String
+	if f.Priority == "" {
+		f.Priority = FactPriorityMedium
+	}
+	if t, err := time.ParseInLocation(factExpiryLayout, expires.String, time.UTC); err == nil {
+		f.ExpiresAt = sql.NullTime{Time: t.Local(), Valid: true}
+	}
+	return f, nil
+}
+
+// NormalizeFactScope 校验并规范化作用域；省略前缀时含 "/" 的视为 path，否则视为 symbol
+func NormalizeFactScope(scope string) (string, error) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" {
+		return "", nil
+	}
+	kind, value, ok := strings.Cut(scope, ":")
+	if !ok {
+		kind, value = "symbol", scope
+		if strings.ContainsAny(scope, "/\\") {
+			kind = "path"
+		}
+	}
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("作用域缺少值: %s", scope)
+	}
+	switch kind {
+	case "path":
+		value = strings.Trim(path.Clean(strings.ReplaceAll(value, "\\", "/")), "/")
+	case "module", "symbol":
+	default:
+		return "", fmt.Errorf("未知的作用域类型 %q（可用: path, module, symbol）", kind)
+	}
+	return kind + ":" + value, nil
+}
+
+// NormalizeFactPriority 规范化优先级，空值为 medium
+func NormalizeFactPriority(p string) (string, error) {
+	switch p = strings.ToLower(strings.TrimSpace(p)); p {
+	case "":
+		return FactPriorityMedium, nil
+	case FactPriorityHigh, FactPriorityMedium, FactPriorityLow:
+		return p, nil
+	}
+	return "", fmt.Errorf("未知的优先级 %q（可用: high, medium, low）", p)
+}
+
+func factPriorityRank(p string) int {
+	switch p {
+	case FactPriorityHigh:
+		return 0
+	case FactPriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// SaveFactWithOptions 保存带作用域/优先级/有效期的事实
+func (m *MemoryLayer) SaveFactWithOptions(ctx context.Context, factType, summarize string, opts FactOptions) (int64, error) {
+	scope, err := NormalizeFactScope(opts.Scope)
+	if err != nil {
+		return 0, err
+	}
+	priority, err := NormalizeFactPriority(opts.Priority)
+	if err != nil {
+		return 0, err
+	}
+	var expires interface{}
+	if opts.TTL > 0 {
+		expires = time.Now().Add(opts.TTL).UTC().Format(factExpiryLayout)
+	}
+	res, err := m.dbManager.Exec(
+		"INSERT INTO known_facts (type, summarize, created_at, scope, priority, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		factType, summarize, time.Now(), scope, priority, expires,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Matches 作用域是否与任务相关；空作用域（全局事实）恒为 true
+func (c FactScopeContext) Matches(scope string) bool {
+	kind, value, ok := strings.Cut(scope, ":")
+	if !ok || value == "" {
+		return true
+	}
+	switch kind {
+	case "symbol":
+		for _, s := range c.Symbols {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	case "path":
+		for _, p := range c.Paths {
+			p = strings.Trim(path.Clean(strings.ReplaceAll(p, "\\", "/")), "/")
+			// 任务路径在作用域下，或任务范围（目录）包含作用域
+			if p == value || strings.HasPrefix(p, value+"/") || strings.HasPrefix(value, p+"/") {
+				return true
+			}
+		}
+	case "module":
+		for _, p := range c.Paths {
+			for _, seg := range strings.Split(strings.ReplaceAll(p, "\\", "/"), "/") {
+				if seg == value || strings.TrimSuffix(seg, path.Ext(seg)) == value {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// QueryFactsForTask 返回与任务相关的事实：匹配作用域的作用域事实 + 按关键词命中的全局事实，
+// 按优先级排序（同级时作用域事实在前）
+func (m *MemoryLayer) QueryFactsForTask(ctx context.Context, keywords string, tc FactScopeContext, limit int) ([]KnownFact, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := m.dbManager.Query("SELECT "+factColumns+" FROM known_facts WHERE scope IS NOT NULL AND scope != '' AND "+activeFactCondition+" ORDER BY id DESC",
+		factExpiryNow())
+	if err != nil {
+		return nil, err
+	}
+	var results []KnownFact
+	for rows.Next() {
+		f, err := scanFact(rows)
+		if err == nil && tc.Matches(f.Scope) {
+			results = append(results, f)
+		}
+	}
+	rows.Close()
+
+	global, err := m.QueryFacts(ctx, keywords, limit*2)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range global {
+		if f.Scope == "" {
+			results = append(results, f)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return factPriorityRank(results[i].Priority) < factPriorityRank(results[j].Priority)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
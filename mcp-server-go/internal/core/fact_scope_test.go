@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueryFactsForTask(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	global, _ := mem.SaveFact(ctx, "规范", "错误信息使用中文")
+	coreOnly, _ := mem.SaveFactWithOptions(ctx, "规范", "core 包内禁止依赖 tools", FactOptions{Scope: "path:internal/core", Priority: "high"})
+	symbolOnly, _ := mem.SaveFactWithOptions(ctx, "避坑", "SaveFact 需要先去重", FactOptions{Scope: "symbol:SaveFact"})
+	expired, _ := mem.SaveFactWithOptions(ctx, "规范", "错误信息迁移期间保留英文", FactOptions{TTL: time.Hour})
+	mem.dbManager.Exec("UPDATE known_facts SET expires_at = ? WHERE id = ?",
+		time.Now().Add(-time.Minute).UTC().Format(factExpiryLayout), expired)
+
+	ids := func(facts []KnownFact) map[int64]bool {
+		out := make(map[int64]bool)
+		for _, f := range facts {
+			out[f.ID] = true
+		}
+		return out
+	}
+
+	facts, err := mem.QueryFactsForTask(ctx, "错误信息", FactScopeContext{Paths: []string{"internal/core/memory.go"}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ids(facts)
+	if !got[global] || !got[coreOnly] || got[symbolOnly] || got[expired] {
+		t.Fatalf("facts for core task = %+v", facts)
+	}
+	if facts[0].ID != coreOnly {
+		t.Fatalf("high priority fact should come first, got %+v", facts[0])
+	}
+
+	facts, _ = mem.QueryFactsForTask(ctx, "错误信息", FactScopeContext{Symbols: []string{"savefact"}, Paths: []string{"internal/tools"}}, 10)
+	got = ids(facts)
+	if !got[symbolOnly] || got[coreOnly] {
+		t.Fatalf("facts for SaveFact task = %+v", facts)
+	}
+
+	if _, err := NormalizeFactScope("layer:core"); err == nil {
+		t.Fatal("unknown scope kind should be rejected")
+	}
+}
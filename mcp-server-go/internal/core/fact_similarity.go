@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ========== Known Facts 近似重复检测 ==========
+
+// DefaultFactDuplicateThreshold 判定为近似重复的相似度阈值
+const DefaultFactDuplicateThreshold = 0.8
+
+// FactMatch 相似事实及其相似度
+type FactMatch struct {
+	Fact       KnownFact
+	Similarity float64
+}
+
+// normalizeFactText 归一化：小写、去除标点与空白
+func normalizeFactText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// factTokens 将事实拆为 token 集合：英文/数字按单词，中文按相邻二字组
+func factTokens(s string) map[string]bool {
+	tokens := make(map[string]bool)
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens[string(word)] = true
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens[string(han)] = true
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens[string(han[i:i+2])] = true
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// FactSimilarity 计算两条事实的相似度 (0~1)，归一化后完全一致记为 1
+func FactSimilarity(a, b string) float64 {
+	na, nb := normalizeFactText(a), normalizeFactText(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+
+	ta, tb := factTokens(a), factTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	inter := 0
+	for t := range ta {
+		if tb[t] {
+			inter++
+		}
+	}
+	union := len(ta) + len(tb) - inter
+	return float64(inter) / float64(union)
+}
+
+// FindSimilarFacts 查找与给定描述相似度 >= threshold 的已有事实（按相似度降序）
+func (m *MemoryLayer) FindSimilarFacts(ctx context.Context, summarize string, threshold float64, limit int) ([]FactMatch, error) {
+	rows, err := m.dbManager.Query("SELECT "+factColumns+" FROM known_facts WHERE "+activeFactCondition, factExpiryNow())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []FactMatch
+	for rows.Next() {
+		f, err := scanFact(rows)
+		if err != nil {
+			continue
+		}
+		if sim := FactSimilarity(summarize, f.Summarize); sim >= threshold {
+			matches = append(matches, FactMatch{Fact: f, Similarity: sim})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
@@ -0,0 +1,15 @@
+package core
+
+import "testing"
+
+func TestFactSimilarity(t *testing.T) {
+	if sim := FactSimilarity("Always use snake_case!", "always use  snake_case"); sim != 1 {
+		t.Errorf("expected normalized duplicates to score 1, got %.2f", sim)
+	}
+	if sim := FactSimilarity("修改 context 逻辑前必须先备份 session 数据", "修改context逻辑前，必须先备份session数据"); sim < DefaultFactDuplicateThreshold {
+		t.Errorf("expected near-duplicate Chinese facts above threshold, got %.2f", sim)
+	}
+	if sim := FactSimilarity("数据库迁移必须幂等", "前端组件统一使用 PascalCase"); sim >= DefaultFactDuplicateThreshold {
+		t.Errorf("expected unrelated facts below threshold, got %.2f", sim)
+	}
+}
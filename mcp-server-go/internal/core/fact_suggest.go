@@ -0,0 +1,262 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ========== 从 memo 中挖掘候选事实 ==========
+//
+// memo 里反复出现的"记得先 X 再 Y"、"不要直接改 Z"之类的说法是团队的隐性经验。
+// 按句拆分 memo，保留带规则语气的句子，按相似度聚类；在多条不同 memo 中出现的聚类
+// 作为候选事实，经 suggest_facts 一键采纳后成为 known_facts。
+
+// DefaultSuggestMinOccurrences 候选事实至少出现在多少条不同 memo 中
+const DefaultSuggestMinOccurrences = 2
+
+// suggestClusterThreshold 句子归入同一聚类的相似度阈值（低于去重阈值，允许措辞差异）
+const suggestClusterThreshold = 0.5
+
+// 规则语气提示词：禁止类归为"避坑"，要求类归为"铁律"
+var (
+	avoidCues = []string{"不要", "禁止", "切勿", "别直接", "避免", "不能", "不可", "never", "don't", "do not", "avoid"}
+	mustCues  = []string{"必须", "务必", "记得", "一定要", "确保", "always", "must", "remember to", "make sure"}
+)
+
+// FactSuggestion 候选事实
+type FactSuggestion struct {
+	ID          int64   `json:"id"`
+	Type        string  `json:"type"`
+	Summary     string  `json:"summary"`
+	Occurrences int     `json:"occurrences"`
+	MemoIDs     []int64 `json:"memo_ids"`
+	Status      string  `json:"status"` // pending / accepted / dismissed
+	UpdatedAt   string  `json:"updated_at,omitempty"`
+}
+
+// rulePhrase memo 中带规则语气的一句话
+type rulePhrase struct {
+	Type     string
+	Sentence string
+	MemoID   int64
+}
+
+// extractRulePhrases 把文本按句拆分，返回带规则语气的句子
+func extractRulePhrases(text string) []rulePhrase {
+	split := func(r rune) bool {
+		return strings.ContainsRune("。！？!?;；\n", r)
+	}
+	var out []rulePhrase
+	for _, sentence := range strings.FieldsFunc(text, split) {
+		// 英文句号后跟空格才视为断句，避免切断文件名与版本号
+		for _, s := range strings.Split(sentence, ". ") {
+			s = strings.Trim(strings.TrimSpace(s), "-*•>、，, .")
+			if n := utf8.RuneCountInString(s); n < 6 || n > 200 {
+				continue
+			}
+			if t := ruleType(s); t != "" {
+				out = append(out, rulePhrase{Type: t, Sentence: s})
+			}
+		}
+	}
+	return out
+}
+
+func ruleType(s string) string {
+	lower := strings.ToLower(s)
+	for _, c := range avoidCues {
+		if strings.Contains(lower, c) {
+			return "避坑"
+		}
+	}
+	for _, c := range mustCues {
+		if strings.Contains(lower, c) {
+			return "铁律"
+		}
+	}
+	return ""
+}
+
+// MineFactSuggestions 从 memo 中挖掘候选事实；与已有事实近似的聚类会被跳过
+func MineFactSuggestions(memos []Memo, facts []KnownFact, minOccurrences int) []FactSuggestion {
+	if minOccurrences <= 0 {
+		minOccurrences = DefaultSuggestMinOccurrences
+	}
+
+	type cluster struct {
+		phrases []rulePhrase
+		memos   map[int64]bool
+	}
+	var clusters []*cluster
+	for _, m := range memos {
+		for _, p := range extractRulePhrases(m.Content) {
+			p.MemoID = m.ID
+			var target *cluster
+			for _, c := range clusters {
+				if FactSimilarity(c.phrases[0].Sentence, p.Sentence) >= suggestClusterThreshold {
+					target = c
+					break
+				}
+			}
+			if target == nil {
+				target = &cluster{memos: make(map[int64]bool)}
+				clusters = append(clusters, target)
+			}
+			target.phrases = append(target.phrases, p)
+			target.memos[m.ID] = true
+		}
+	}
+
+	var out []FactSuggestion
+	for _, c := range clusters {
+		if len(c.memos) < minOccurrences {
+			continue
+		}
+		// 代表句取最短的一条，通常是最精炼的说法
+		best := c.phrases[0]
+		for _, p := range c.phrases[1:] {
+			if utf8.RuneCountInString(p.Sentence) < utf8.RuneCountInString(best.Sentence) {
+				best = p
+			}
+		}
+		known := false
+		for _, f := range facts {
+			if FactSimilarity(best.Sentence, f.Summarize) >= suggestClusterThreshold {
+				known = true
+				break
+			}
+		}
+		if known {
+			continue
+		}
+		s := FactSuggestion{Type: best.Type, Summary: best.Sentence, Occurrences: len(c.memos), Status: "pending"}
+		for id := range c.memos {
+			s.MemoIDs = append(s.MemoIDs, id)
+		}
+		sort.Slice(s.MemoIDs, func(i, j int) bool { return s.MemoIDs[i] < s.MemoIDs[j] })
+		out = append(out, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Occurrences > out[j].Occurrences })
+	return out
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitIDs(s string) []int64 {
+	var ids []int64
+	for _, p := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// RefreshFactSuggestions 重新挖掘最近的 memo 并写入候选表，返回新增的候选数；
+// 已采纳或已忽略的近似候选不会再次出现，已有的待定候选只更新出现次数
+func (m *MemoryLayer) RefreshFactSuggestions(ctx context.Context, minOccurrences int) (int, error) {
+	memos, err := m.SearchMemos(ctx, "", "", 1000)
+	if err != nil {
+		return 0, err
+	}
+	facts, err := m.QueryFacts(ctx, "", 1000)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := m.ListFactSuggestions(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	now := time.Now().Format(time.RFC3339)
+	for _, s := range MineFactSuggestions(memos, facts, minOccurrences) {
+		var match *FactSuggestion
+		for i := range existing {
+			if FactSimilarity(existing[i].Summary, s.Summary) >= suggestClusterThreshold {
+				match = &existing[i]
+				break
+			}
+		}
+		if match != nil {
+			if match.Status == "pending" && s.Occurrences != match.Occurrences {
+				_, err = m.dbManager.Exec("UPDATE fact_suggestions SET occurrences = ?, memo_ids = ?, updated_at = ? WHERE id = ?",
+					s.Occurrences, joinIDs(s.MemoIDs), now, match.ID)
+			}
+		} else {
+			_, err = m.dbManager.Exec(`INSERT INTO fact_suggestions (type, summary, occurrences, memo_ids, status, updated_at)
+				VALUES (?, ?, ?, ?, 'pending', ?)`, s.Type, s.Summary, s.Occurrences, joinIDs(s.MemoIDs), now)
+			added++
+		}
+		if err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// ListFactSuggestions 列出候选事实；status 为空时返回全部
+func (m *MemoryLayer) ListFactSuggestions(ctx context.Context, status string) ([]FactSuggestion, error) {
+	query := "SELECT id, type, summary, occurrences, COALESCE(memo_ids, ''), status, COALESCE(updated_at, '') FROM fact_suggestions"
+	var params []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		params = append(params, status)
+	}
+	query += " ORDER BY occurrences DESC, id ASC"
+
+	rows, err := m.dbManager.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FactSuggestion
+	for rows.Next() {
+		s, err := scanFactSuggestion(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// GetFactSuggestion 按 ID 查找候选，不存在时返回 nil
+func (m *MemoryLayer) GetFactSuggestion(ctx context.Context, id int64) (*FactSuggestion, error) {
+	row := m.dbManager.QueryRow(`SELECT id, type, summary, occurrences, COALESCE(memo_ids, ''), status, COALESCE(updated_at, '')
+		FROM fact_suggestions WHERE id = ?`, id)
+	s, err := scanFactSuggestion(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+func scanFactSuggestion(scan func(dest ...interface{}) error) (*FactSuggestion, error) {
+	var s FactSuggestion
+	var ids string
+	if err := scan(&s.ID, &s.Type, &s.Summary, &s.Occurrences, &ids, &s.Status, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.MemoIDs = splitIDs(ids)
+	return &s, nil
+}
+
+// SetFactSuggestionStatus 标记候选为 accepted / dismissed
+func (m *MemoryLayer) SetFactSuggestionStatus(ctx context.Context, id int64, status string) error {
+	_, err := m.dbManager.Exec("UPDATE fact_suggestions SET status = ?, updated_at = ? WHERE id = ?",
+		status, time.Now().Format(time.RFC3339), id)
+	return err
+}
@@ -0,0 +1,38 @@
+package core
+
+import "testing"
+
+func TestMineFactSuggestions_RepeatedRules(t *testing.T) {
+	memos := []Memo{
+		{ID: 1, Content: "修复登录问题。记得改完 schema 后运行 make migrate。"},
+		{ID: 2, Content: "新增字段，记得改完 schema 后要运行 make migrate"},
+		{ID: 3, Content: "不要直接修改 generated/api.go，它由 protoc 生成"},
+		{ID: 4, Content: "调整 UI 样式"},
+	}
+	got := MineFactSuggestions(memos, nil, 2)
+	if len(got) != 1 {
+		t.Fatalf("got %d suggestions, want 1: %+v", len(got), got)
+	}
+	if got[0].Type != "铁律" || got[0].Occurrences != 2 {
+		t.Errorf("unexpected suggestion: %+v", got[0])
+	}
+	if len(got[0].MemoIDs) != 2 || got[0].MemoIDs[0] != 1 || got[0].MemoIDs[1] != 2 {
+		t.Errorf("memo ids = %v, want [1 2]", got[0].MemoIDs)
+	}
+
+	// 已有近似事实时不再建议
+	facts := []KnownFact{{ID: 1, Type: "铁律", Summarize: "改完 schema 后运行 make migrate"}}
+	if got := MineFactSuggestions(memos, facts, 2); len(got) != 0 {
+		t.Errorf("expected no suggestions when fact exists, got %+v", got)
+	}
+}
+
+func TestExtractRulePhrases_Types(t *testing.T) {
+	phrases := extractRulePhrases("Never edit vendor/ directly. Always run go generate after changing tools")
+	if len(phrases) != 2 {
+		t.Fatalf("got %d phrases: %+v", len(phrases), phrases)
+	}
+	if phrases[0].Type != "避坑" || phrases[1].Type != "铁律" {
+		t.Errorf("unexpected types: %+v", phrases)
+	}
+}
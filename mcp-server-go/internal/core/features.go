@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ========== 项目级功能开关 (settings.json 的 features 段) ==========
+//
+// 实验性子系统各自挂在一个开关后面，团队按项目逐个启用，无需分叉整份配置：
+//
+//	{"features": {"parallel_subtasks": true, "scope_suggestions": false}}
+
+// FeatureFlag 已知的功能开关
+type FeatureFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// KnownFeatures 可配置的功能开关及默认值
+var KnownFeatures = []FeatureFlag{
+	{Name: "parallel_subtasks", Description: "task_chain spawn 支持 depends_on 依赖与 parallel 并行子任务", Default: false},
+	{Name: "custom_protocols", Description: "task_chain 加载 .mcp-config/protocols.yaml 中的自定义协议", Default: true},
+	{Name: "scope_suggestions", Description: "搜索/锚点解析落空时按文件名模糊匹配给出候选目录", Default: true},
+	{Name: "auto_symbols", Description: "manager_analyze 未提供 symbols 时从任务描述中自动匹配索引符号", Default: true},
+	{Name: "fact_suggest", Description: "从 memo 中挖掘候选事实 (suggest_facts 工具与定时任务)", Default: true},
+}
+
+// LookupFeature 按名称查找已知开关
+func LookupFeature(name string) (FeatureFlag, bool) {
+	for _, f := range KnownFeatures {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FeatureFlag{}, false
+}
+
+// FeatureEnabled 功能是否启用：settings.json 显式配置优先，否则取默认值；未知功能视为关闭
+func (s *ProjectSettings) FeatureEnabled(name string) bool {
+	if v, ok := s.Features[name]; ok {
+		return v
+	}
+	f, ok := LookupFeature(name)
+	return ok && f.Default
+}
+
+// FeatureEnabled 读取项目配置判断功能是否启用
+func FeatureEnabled(projectRoot, name string) bool {
+	return LoadProjectSettings(projectRoot).FeatureEnabled(name)
+}
+
+// SetFeatureFlag 修改 settings.json 中的单个开关；只改写 features 段，其余配置原样保留
+func SetFeatureFlag(projectRoot, name string, enabled bool) error {
+	if _, ok := LookupFeature(name); !ok {
+		return fmt.Errorf("未知功能: %s", name)
+	}
+	path := SettingsPath(projectRoot)
+	raw := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("settings.json 解析失败，未修改: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	features := make(map[string]bool)
+	if v, ok := raw["features"]; ok {
+		if err := json.Unmarshal(v, &features); err != nil {
+			return fmt.Errorf("settings.json 的 features 段格式错误: %w", err)
+		}
+	}
+	features[name] = enabled
+	encoded, err := json.Marshal(features)
+	if err != nil {
+		return err
+	}
+	raw["features"] = encoded
+
+	content, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetFeatureFlag_PreservesOtherSettings(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(root+"/.mcp-config", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(SettingsPath(root), []byte(`{"output": {"quiet": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if FeatureEnabled(root, "parallel_subtasks") {
+		t.Fatal("parallel_subtasks should default to off")
+	}
+	if err := SetFeatureFlag(root, "parallel_subtasks", true); err != nil {
+		t.Fatal(err)
+	}
+	s := LoadProjectSettings(root)
+	if !s.FeatureEnabled("parallel_subtasks") || !s.Output.Quiet {
+		t.Errorf("flag or existing settings lost: %+v", s)
+	}
+	data, _ := os.ReadFile(SettingsPath(root))
+	if strings.Contains(string(data), "liveness_minutes") {
+		t.Errorf("defaults should not be written back: %s", data)
+	}
+	if err := SetFeatureFlag(root, "no_such_feature", true); err == nil {
+		t.Error("expected error for unknown feature")
+	}
+}
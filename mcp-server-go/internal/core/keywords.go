@@ -0,0 +1,114 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ========== 检索关键词扩展 ==========
+
+// maxExpandedKeywords 扩展后的关键词上限，避免 LIKE 条件过多
+const maxExpandedKeywords = 24
+
+// keywordStopWords 拆分标识符后过于宽泛、不单独参与检索的词
+var keywordStopWords = map[string]bool{
+	"get": true, "set": true, "new": true, "init": true, "impl": true, "util": true, "utils": true,
+	"data": true, "info": true, "item": true, "list": true, "func": true, "handle": true, "handler": true,
+	"the": true, "and": true, "for": true, "with": true,
+}
+
+// splitIdentifier 将 camelCase / PascalCase / snake_case / kebab-case 拆为小写单词
+// "SessionManager" -> [session manager]，"parseHTTPRequest" -> [parse http request]
+func splitIdentifier(s string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == '/':
+			flush()
+		case unicode.IsUpper(r):
+			// 小写→大写 或 连续大写后接小写（HTTPRequest 的 R）处断开
+			if len(cur) > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					flush()
+				}
+			}
+			cur = append(cur, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// ExpandKeywords 拆分检索关键词（空格/逗号分隔），并为复合标识符补充变体：
+// "SessionManager" -> SessionManager, session manager, session_manager, session-manager, sessionmanager, session, manager
+// 中文等非 ASCII 关键词原样保留
+func ExpandKeywords(keywords string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(s string) {
+		key := strings.ToLower(strings.TrimSpace(s))
+		if key == "" || seen[key] || len(out) >= maxExpandedKeywords {
+			return
+		}
+		seen[key] = true
+		out = append(out, s)
+	}
+
+	tokens := strings.Fields(strings.ReplaceAll(keywords, ",", " "))
+	// 先放入原始关键词，保证其优先于扩展词
+	for _, tok := range tokens {
+		add(tok)
+	}
+	for _, tok := range tokens {
+		parts := splitIdentifier(tok)
+		if len(parts) < 2 {
+			continue
+		}
+		add(strings.Join(parts, " "))
+		add(strings.Join(parts, "_"))
+		add(strings.Join(parts, "-"))
+		add(strings.Join(parts, ""))
+	}
+	for _, tok := range tokens {
+		parts := splitIdentifier(tok)
+		if len(parts) < 2 {
+			continue
+		}
+		for _, p := range parts {
+			if len(p) >= 4 && !keywordStopWords[p] {
+				add(p)
+			}
+		}
+	}
+	return out
+}
+
+// QueryTokens 把查询/路径拆成去重的小写单词，丢弃过短与过于宽泛的词，用于模糊匹配
+// "handleUserLogin auth" -> [user login auth]
+func QueryTokens(s string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, w := range splitIdentifier(s) {
+		if len(w) < 3 || keywordStopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		out = append(out, w)
+	}
+	return out
+}
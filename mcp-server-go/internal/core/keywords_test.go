@@ -0,0 +1,28 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitIdentifier(t *testing.T) {
+	cases := map[string][]string{
+		"SessionManager":   {"session", "manager"},
+		"parseHTTPRequest": {"parse", "http", "request"},
+		"build_fact_kw":    {"build", "fact", "kw"},
+		"记忆层":              {"记忆层"},
+	}
+	for in, want := range cases {
+		if got := splitIdentifier(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitIdentifier(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestExpandKeywords(t *testing.T) {
+	got := ExpandKeywords("SessionManager, 鉴权")
+	want := []string{"SessionManager", "鉴权", "session manager", "session_manager", "session-manager", "session", "manager"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandKeywords = %v, want %v", got, want)
+	}
+}
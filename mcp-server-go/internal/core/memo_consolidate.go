@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ========== 近似重复 memo 的检测与合并 ==========
+//
+// 同一实体下内容高度相似的 memo 归为一组（传递闭包），组内最新的一条作为规范记录，
+// 其余记录软归档并记下 merged_into，作为规范记录的版本历史保留，可逐条 unarchive 拆回。
+
+// DefaultMemoDuplicateThreshold 判定 memo 内容近似重复的相似度阈值
+const DefaultMemoDuplicateThreshold = 0.75
+
+// MemoGroup 一组近似重复的 memo
+type MemoGroup struct {
+	Canonical  Memo    `json:"canonical"`
+	Duplicates []Memo  `json:"duplicates"` // 按时间升序
+	Similarity float64 `json:"similarity"` // 重复项与规范记录的最低相似度
+}
+
+// FindDuplicateMemos 查找近似重复的 memo 组，按组大小降序
+func (m *MemoryLayer) FindDuplicateMemos(ctx context.Context, threshold float64) ([]MemoGroup, error) {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultMemoDuplicateThreshold
+	}
+	rows, err := m.dbManager.Query(
+		"SELECT id, category, entity, act, path, content, session_id, timestamp FROM memos WHERE archived = 0 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	byEntity := make(map[string][]Memo)
+	for rows.Next() {
+		var memo Memo
+		if err := rows.Scan(&memo.ID, &memo.Category, &memo.Entity, &memo.Act, &memo.Path, &memo.Content, &memo.SessionID, &memo.Timestamp); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if key := normalizeFactText(memo.Entity); key != "" {
+			byEntity[key] = append(byEntity[key], memo)
+		}
+	}
+	rows.Close()
+
+	var groups []MemoGroup
+	for _, memos := range byEntity {
+		if len(memos) < 2 {
+			continue
+		}
+		// 并查集：相似度达标的两条 memo 连通
+		parent := make([]int, len(memos))
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+			return parent[i]
+		}
+		for i := range memos {
+			for j := i + 1; j < len(memos); j++ {
+				if FactSimilarity(memos[i].Content, memos[j].Content) >= threshold {
+					parent[find(i)] = find(j)
+				}
+			}
+		}
+		clusters := make(map[int][]Memo)
+		for i := range memos {
+			r := find(i)
+			clusters[r] = append(clusters[r], memos[i])
+		}
+		for _, c := range clusters {
+			if len(c) < 2 {
+				continue
+			}
+			// memos 按 id 升序读取，最后一条即最新
+			g := MemoGroup{Canonical: c[len(c)-1], Duplicates: c[:len(c)-1], Similarity: 1}
+			for _, d := range g.Duplicates {
+				if s := FactSimilarity(d.Content, g.Canonical.Content); s < g.Similarity {
+					g.Similarity = s
+				}
+			}
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Duplicates) != len(groups[j].Duplicates) {
+			return len(groups[i].Duplicates) > len(groups[j].Duplicates)
+		}
+		return groups[i].Canonical.ID > groups[j].Canonical.ID
+	})
+	return groups, nil
+}
+
+// CompactMemos 把各组重复项合并到规范记录（软归档 + merged_into），返回合并的条数
+func (m *MemoryLayer) CompactMemos(ctx context.Context, groups []MemoGroup) (int, error) {
+	merged := 0
+	for _, g := range groups {
+		for _, d := range g.Duplicates {
+			if _, err := m.dbManager.Exec("UPDATE memos SET archived = 1, merged_into = ? WHERE id = ? AND archived = 0",
+				g.Canonical.ID, d.ID); err != nil {
+				return merged, err
+			}
+			// 重复项自身的历史版本一并转到规范记录下
+			m.dbManager.Exec("UPDATE memos SET merged_into = ? WHERE merged_into = ?", g.Canonical.ID, d.ID)
+			m.appendMemoArchive([]memoArchiveEntry{{
+				Op:         MemoOpMerge,
+				Reason:     fmt.Sprintf("近似重复，合并到 memo %d", g.Canonical.ID),
+				MergedInto: g.Canonical.ID,
+				ID:         d.ID,
+				Timestamp:  time.Now(),
+			}})
+			merged++
+		}
+	}
+	if merged > 0 {
+		m.requestDevLogSync()
+	}
+	return merged, nil
+}
+
+// MemoHistory 返回合并到该 memo 的历史版本（按时间升序）
+func (m *MemoryLayer) MemoHistory(ctx context.Context, id int64) ([]Memo, error) {
+	rows, err := m.dbManager.Query(
+		"SELECT id, category, entity, act, path, content, session_id, timestamp FROM memos WHERE merged_into = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var history []Memo
+	for rows.Next() {
+		var memo Memo
+		if err := rows.Scan(&memo.ID, &memo.Category, &memo.Entity, &memo.Act, &memo.Path, &memo.Content, &memo.SessionID, &memo.Timestamp); err != nil {
+			return nil, err
+		}
+		history = append(history, memo)
+	}
+	return history, nil
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompactMemos(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, mem)
+	ids, err := mem.AddMemos(ctx, []Memo{
+		{Category: "修改", Entity: "SessionManager", Act: "修复", Content: "添加 nil 检查，防止未初始化的配置导致 panic"},
+		{Category: "修改", Entity: "Renderer", Act: "优化", Content: "添加 nil 检查，防止未初始化的配置导致 panic"},
+		{Category: "修改", Entity: "sessionmanager", Act: "修复", Content: "添加 nil 检查, 防止未初始化的配置导致 panic!"},
+		{Category: "修改", Entity: "SessionManager", Act: "重构", Content: "拆分 session 存储到独立文件"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := mem.FindDuplicateMemos(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Canonical.ID != ids[2] || len(groups[0].Duplicates) != 1 || groups[0].Duplicates[0].ID != ids[0] {
+		t.Fatalf("groups = %+v, want memo %d merged into %d", groups, ids[0], ids[2])
+	}
+
+	if n, err := mem.CompactMemos(ctx, groups); err != nil || n != 1 {
+		t.Fatalf("CompactMemos = %d, %v", n, err)
+	}
+	history, _ := mem.MemoHistory(ctx, ids[2])
+	if len(history) != 1 || history[0].ID != ids[0] {
+		t.Fatalf("history = %+v", history)
+	}
+	if visible, _ := mem.SearchMemos(ctx, "", "", 10); len(visible) != 3 {
+		t.Fatalf("visible memos = %d, want 3", len(visible))
+	}
+	if again, _ := mem.FindDuplicateMemos(ctx, 0); len(again) != 0 {
+		t.Fatalf("groups after compact = %+v", again)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,12 @@ import (
 type MemoryLayer struct {
 	dbManager   *DatabaseManager
 	projectRoot string
+
+	// dev-log.md 单写者：devLogWriteMu 串行化实际写入，devLogSyncMu 保护合并标记
+	devLogWriteMu sync.Mutex
+	devLogSyncMu  sync.Mutex
+	devLogRunning bool
+	devLogDirty   bool
 }
 
 // NewMemoryLayer 创建记忆层实例
@@ -84,9 +91,13 @@ func (m *MemoryLayer) GetTask(ctx context.Context, taskID string) (*Task, error)
 // ========== Memo Management ==========
 
 // memoArchiveEntry 用于持久化到 dev-log-archive 的备份条目
-// 设计目标：即使 .mcp-data/mcp_memory.db 丢失，也可以通过重放此日志恢复 memos 表的核心字段。
+// 设计目标：即使数据目录中的 mcp_memory.db 丢失，也可以通过重放此日志恢复 memos 表的核心字段。
+// Op 为空表示新增；update/delete/archive/unarchive/merge 为墓碑条目，按 ID 作用于此前新增的记录。
 type memoArchiveEntry struct {
-	ID        int64     `json:"id"`
+	Op         string    `json:"op,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	MergedInto int64     `json:"merged_into,omitempty"`
+	ID         int64     `json:"id"`
 	Category  string    `json:"category"`
 	Entity    string    `json:"entity"`
 	Act       string    `json:"act"`
@@ -94,6 +105,7 @@ type memoArchiveEntry struct {
 	Content   string    `json:"content"`
 	SessionID string    `json:"session_id,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
+	GitCommit string    `json:"git_commit,omitempty"`
 }
 
 var devLogMemoLinePattern = regexp.MustCompile(`^- \[(.*)\] \*\*([^*]+)\*\*: (.*?) \((.*?)\)\s*(.*)$`)
@@ -128,7 +140,7 @@ func (m *MemoryLayer) ensureMemoData() error {
 }
 
 func (m *MemoryLayer) recoverMemosFromArchive() (int, error) {
-	archivePath := filepath.Join(m.projectRoot, "dev-log-archive", "memo_archive.jsonl")
+	archivePath := MemoArchivePath(m.projectRoot)
 	if _, err := os.Stat(archivePath); os.IsNotExist(err) {
 		return 0, nil
 	}
@@ -143,6 +155,7 @@ func (m *MemoryLayer) recoverMemosFromArchive() (int, error) {
 	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
 
 	recovered := 0
+	idMap := make(map[int64]int64) // 归档中的原 ID → 重放后的新 ID
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
@@ -153,19 +166,28 @@ func (m *MemoryLayer) recoverMemosFromArchive() (int, error) {
 		if err := json.Unmarshal([]byte(line), &entry); err != nil {
 			continue
 		}
+		if entry.Op != "" {
+			if id, ok := idMap[entry.ID]; ok {
+				m.replayMemoTombstone(id, entry, idMap)
+			}
+			continue
+		}
 
 		ts := entry.Timestamp
 		if ts.IsZero() {
 			ts = time.Now()
 		}
 
-		_, err := m.dbManager.Exec(
-			"INSERT INTO memos (category, entity, act, path, content, session_id, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?)",
-			entry.Category, entry.Entity, entry.Act, entry.Path, entry.Content, entry.SessionID, ts.Format("2006-01-02 15:04:05"),
+		res, err := m.dbManager.Exec(
+			"INSERT INTO memos (category, entity, act, path, content, session_id, timestamp, git_commit) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			entry.Category, entry.Entity, entry.Act, entry.Path, entry.Content, entry.SessionID, ts.Format("2006-01-02 15:04:05"), entry.GitCommit,
 		)
 		if err != nil {
 			continue
 		}
+		if id, err := res.LastInsertId(); err == nil {
+			idMap[entry.ID] = id
+		}
 		recovered++
 	}
 
@@ -177,7 +199,7 @@ func (m *MemoryLayer) recoverMemosFromArchive() (int, error) {
 }
 
 func (m *MemoryLayer) recoverMemosFromDevLog() (int, error) {
-	devLogPath := filepath.Join(m.projectRoot, "dev-log.md")
+	devLogPath := DevLogPath(m.projectRoot)
 	if _, err := os.Stat(devLogPath); os.IsNotExist(err) {
 		return 0, nil
 	}
@@ -262,8 +284,8 @@ func (m *MemoryLayer) AddMemos(ctx context.Context, items []Memo) ([]int64, erro
 
 	for _, item := range items {
 		res, err := m.dbManager.Exec(
-			"INSERT INTO memos (category, entity, act, path, content, session_id) VALUES (?, ?, ?, ?, ?, ?)",
-			item.Category, item.Entity, item.Act, item.Path, item.Content, sessionID,
+			"INSERT INTO memos (category, entity, act, path, content, session_id, git_commit, git_dirty) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			item.Category, item.Entity, item.Act, item.Path, item.Content, sessionID, item.GitCommit, item.GitDirty,
 		)
 		if err != nil {
 			return nil, err
@@ -281,6 +303,7 @@ func (m *MemoryLayer) AddMemos(ctx context.Context, items []Memo) ([]int64, erro
 			Content:  item.Content,
 			// 这里使用 AddMemos 调用时的时间戳，精度足以支撑后续审计与恢复
 			Timestamp: now,
+			GitCommit: item.GitCommit,
 		}
 		if sessionID != "" {
 			entry.SessionID = sessionID
@@ -288,20 +311,28 @@ func (m *MemoryLayer) AddMemos(ctx context.Context, items []Memo) ([]int64, erro
 		archives = append(archives, entry)
 	}
 
-	// 触发同步 dev-log.md
-	go m.SyncDevLog()
+	// 触发同步 dev-log.md（合并并发请求，由单个后台写者完成）
+	m.requestDevLogSync()
 
-	// 异步追加写入 dev-log-archive 作为独立物理备份
+	// 追加写入 dev-log-archive 作为独立物理备份；同步写入，保证与之后的墓碑条目顺序一致
 	if len(archives) > 0 {
-		go m.appendMemoArchive(archives)
+		m.appendMemoArchive(archives)
 	}
 
 	return ids, nil
 }
 
+// memoColumns 读取 memo 的列（与 Scan 顺序一致）
+const memoColumns = "id, category, entity, act, path, content, session_id, timestamp, COALESCE(git_commit, ''), COALESCE(git_dirty, '')"
+
 // SearchMemos 搜索备忘录
 func (m *MemoryLayer) SearchMemos(ctx context.Context, keywords string, category string, limit int) ([]Memo, error) {
-	query := "SELECT id, category, entity, act, path, content, session_id, timestamp FROM memos WHERE 1=1"
+	return m.SearchMemosInRange(ctx, keywords, category, TimeRange{}, limit)
+}
+
+// SearchMemosInRange 搜索备忘录，并按 timestamp 限定时间范围
+func (m *MemoryLayer) SearchMemosInRange(ctx context.Context, keywords string, category string, within TimeRange, limit int) ([]Memo, error) {
+	query := "SELECT " + memoColumns + " FROM memos WHERE archived = 0"
 	var args []interface{}
 
 	if category != "" {
@@ -311,8 +342,8 @@ func (m *MemoryLayer) SearchMemos(ctx context.Context, keywords string, category
 
 	if keywords != "" {
 		// 宽进严出：支持空格和逗号拆分关键词，实现逻辑或(OR)匹配
-		keywords = strings.ReplaceAll(keywords, ",", " ")
-		words := strings.Fields(keywords)
+		// 复合标识符额外展开 camelCase/snake_case 变体
+		words := ExpandKeywords(keywords)
 		if len(words) > 0 {
 			var orConditions []string
 			for _, word := range words {
@@ -324,14 +355,18 @@ func (m *MemoryLayer) SearchMemos(ctx context.Context, keywords string, category
 		}
 	}
 
-	query += " ORDER BY timestamp DESC LIMIT ?"
 	if limit <= 0 {
 		limit = 20
 	}
-	args = append(args, limit)
+	// 时间戳存储格式不统一，时间过滤在读取后进行，此时不在 SQL 中截断
+	query += " ORDER BY timestamp DESC"
+	if within.IsZero() {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
 
 	// DEBUG: Log the final query and args
-	debugPath := filepath.Join(m.projectRoot, ".mcp-data", "recall_debug.log")
+	debugPath := DataPath(m.projectRoot, "recall_debug.log")
 	debugMsg := fmt.Sprintf("Query: %s\nArgs: %v\n", query, args)
 	_ = os.WriteFile(debugPath, []byte(debugMsg), 0644)
 
@@ -345,20 +380,78 @@ func (m *MemoryLayer) SearchMemos(ctx context.Context, keywords string, category
 	var memos []Memo
 	for rows.Next() {
 		var m Memo
-		if err := rows.Scan(&m.ID, &m.Category, &m.Entity, &m.Act, &m.Path, &m.Content, &m.SessionID, &m.Timestamp); err != nil {
+		if err := rows.Scan(&m.ID, &m.Category, &m.Entity, &m.Act, &m.Path, &m.Content, &m.SessionID, &m.Timestamp, &m.GitCommit, &m.GitDirty); err != nil {
 			return nil, err
 		}
+		if !within.Contains(m.Timestamp) {
+			continue
+		}
 		memos = append(memos, m)
+		if len(memos) >= limit {
+			break
+		}
 	}
 	return memos, nil
 }
 
-// SyncDevLog 同步更新 dev-log.md
+// requestDevLogSync 标记 dev-log 需要刷新；已有写者在运行时只置脏标记，
+// 写者完成当前快照后会再写一次，因此突发的 AddMemos 最多产生两次写入
+func (m *MemoryLayer) requestDevLogSync() {
+	m.devLogSyncMu.Lock()
+	m.devLogDirty = true
+	if m.devLogRunning {
+		m.devLogSyncMu.Unlock()
+		return
+	}
+	m.devLogRunning = true
+	m.devLogSyncMu.Unlock()
+
+	go func() {
+		for {
+			m.devLogSyncMu.Lock()
+			if !m.devLogDirty {
+				m.devLogRunning = false
+				m.devLogSyncMu.Unlock()
+				return
+			}
+			m.devLogDirty = false
+			m.devLogSyncMu.Unlock()
+
+			m.SyncDevLog()
+		}
+	}()
+}
+
+// FlushDevLog 退出前同步写出尚未落盘的 dev-log（后台写者可能还没轮到），
+// 并等待后台写者退出（最多约 1 秒），避免退出或清理目录时仍有写入
+func (m *MemoryLayer) FlushDevLog() {
+	m.devLogSyncMu.Lock()
+	pending := m.devLogDirty || m.devLogRunning
+	m.devLogSyncMu.Unlock()
+	if !pending {
+		return
+	}
+	m.SyncDevLog()
+	for i := 0; i < 100; i++ {
+		m.devLogSyncMu.Lock()
+		running := m.devLogRunning
+		m.devLogSyncMu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// SyncDevLog 同步更新 dev-log.md（串行写入，临时文件 + rename 保证原子替换）
 func (m *MemoryLayer) SyncDevLog() {
+	m.devLogWriteMu.Lock()
+	defer m.devLogWriteMu.Unlock()
+
 	rows, err := m.dbManager.Query(`
 		SELECT 
 			id, content, timestamp, category, entity, act, path, session_id 
-		FROM memos ORDER BY id DESC LIMIT 100`)
+		FROM memos WHERE archived = 0 ORDER BY id DESC LIMIT 100`)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[SyncDevLog] Query failed: %v\n", err)
 		return
@@ -403,12 +496,20 @@ func (m *MemoryLayer) SyncDevLog() {
 		lines = append(lines, line)
 	}
 
-	devLogPath := filepath.Join(m.projectRoot, "dev-log.md")
-	os.WriteFile(devLogPath, []byte(strings.Join(lines, "\n")), 0644)
+	devLogPath := DevLogPath(m.projectRoot)
+	tmpPath := devLogPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "[SyncDevLog] Write failed: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmpPath, devLogPath); err != nil {
+		fmt.Fprintf(os.Stderr, "[SyncDevLog] Rename failed: %v\n", err)
+		_ = os.Remove(tmpPath)
+	}
 }
 
 // appendMemoArchive 将新增的 memo 以 JSONL 形式追加写入 dev-log-archive 目录
-// 路径示例：<project_root>/dev-log-archive/memo_archive.jsonl
+// 路径示例：<project_root>/dev-log-archive/memo_archive.jsonl（显式配置数据目录时位于数据目录下）
 // 说明：
 // - 采用 append-only 设计，不做就地修改，便于事后重放恢复数据库
 // - 写入失败不会影响主流程，只在 stderr 打印告警
@@ -417,13 +518,12 @@ func (m *MemoryLayer) appendMemoArchive(entries []memoArchiveEntry) {
 		return
 	}
 
-	archiveDir := filepath.Join(m.projectRoot, "dev-log-archive")
-	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+	if err := os.MkdirAll(MemoArchiveDir(m.projectRoot), 0755); err != nil {
 		fmt.Fprintf(os.Stderr, "[MemoArchive] MkdirAll failed: %v\n", err)
 		return
 	}
 
-	archivePath := filepath.Join(archiveDir, "memo_archive.jsonl")
+	archivePath := MemoArchivePath(m.projectRoot)
 	f, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[MemoArchive] OpenFile failed: %v\n", err)
@@ -447,7 +547,7 @@ func (m *MemoryLayer) QueryMemos(ctx context.Context, keywords, category string,
 	query := `
 		SELECT 
 			id, content, timestamp, category, entity, act, path, session_id 
-		FROM memos WHERE 1=1`
+		FROM memos WHERE archived = 0`
 	var params []interface{}
 
 	if category != "" {
@@ -458,7 +558,7 @@ func (m *MemoryLayer) QueryMemos(ctx context.Context, keywords, category string,
 	if keywords != "" {
 		// 亮窃谓：此处将词句拆解，若有一词相合，即入奏报。
 		// 待日后功力深厚，再行复杂之权重排序。
-		words := strings.Fields(strings.ReplaceAll(keywords, ",", " "))
+		words := ExpandKeywords(keywords)
 		if len(words) > 0 {
 			var subConditions []string
 			for _, w := range words {
@@ -547,14 +647,19 @@ func (m *MemoryLayer) QueryTasks(ctx context.Context, keywords string, limit int
 
 // QueryFacts 检索事实
 func (m *MemoryLayer) QueryFacts(ctx context.Context, keywords string, limit int) ([]KnownFact, error) {
+	return m.QueryFactsInRange(ctx, keywords, TimeRange{}, limit)
+}
+
+// QueryFactsInRange 查询事实，并按 created_at 限定时间范围
+func (m *MemoryLayer) QueryFactsInRange(ctx context.Context, keywords string, within TimeRange, limit int) ([]KnownFact, error) {
 	query := `
 		SELECT 
-			id, type, summarize, created_at 
-		FROM known_facts WHERE 1=1`
-	var params []interface{}
+			` + factColumns + `
+		FROM known_facts WHERE ` + activeFactCondition
+	params := []interface{}{factExpiryNow()}
 
 	if keywords != "" {
-		words := strings.Fields(strings.ReplaceAll(keywords, ",", " "))
+		words := ExpandKeywords(keywords)
 		if len(words) > 0 {
 			var subConditions []string
 			for _, w := range words {
@@ -566,8 +671,11 @@ func (m *MemoryLayer) QueryFacts(ctx context.Context, keywords string, limit int
 		}
 	}
 
-	query += " ORDER BY id DESC LIMIT ?"
-	params = append(params, limit)
+	query += " ORDER BY id DESC"
+	if within.IsZero() {
+		query += " LIMIT ?"
+		params = append(params, limit)
+	}
 
 	rows, err := m.dbManager.Query(query, params...)
 	if err != nil {
@@ -577,24 +685,24 @@ func (m *MemoryLayer) QueryFacts(ctx context.Context, keywords string, limit int
 
 	var results []KnownFact
 	for rows.Next() {
-		var f KnownFact
-		err := rows.Scan(&f.ID, &f.Type, &f.Summarize, &f.CreatedAt)
+		f, err := scanFact(rows)
 		if err != nil {
 			continue
 		}
+		if !within.Contains(f.CreatedAt) {
+			continue
+		}
 		results = append(results, f)
+		if limit > 0 && len(results) >= limit {
+			break
+		}
 	}
 	return results, nil
 }
 
-// SaveFact 保存事实
+// SaveFact 保存全局、永久的事实
 func (m *MemoryLayer) SaveFact(ctx context.Context, factType, summarize string) (int64, error) {
-	query := "INSERT INTO known_facts (type, summarize, created_at) VALUES (?, ?, ?)"
-	res, err := m.dbManager.Exec(query, factType, summarize, time.Now())
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+	return m.SaveFactWithOptions(ctx, factType, summarize, FactOptions{})
 }
 
 // GetRecentTasks 获取近期任务
@@ -736,7 +844,7 @@ func (m *MemoryLayer) ListHooks(ctx context.Context, status string) ([]Hook, err
 // ReleaseHook 释放钩子
 func (m *MemoryLayer) ReleaseHook(ctx context.Context, hookID string, resultSummary string) error {
 	_, err := m.dbManager.Exec(
-		"UPDATE pending_hooks SET status = 'closed', result_summary = ? WHERE hook_id = ?",
+		"UPDATE pending_hooks SET status = 'closed', result_summary = ?, released_at = CURRENT_TIMESTAMP WHERE hook_id = ?",
 		resultSummary, hookID,
 	)
 	return err
@@ -0,0 +1,392 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ========== 记忆库运维：整包备份 / 恢复 / VACUUM / 完整性检查 ==========
+//
+// 整包备份 (bundle) 是 backups/ 下的一个目录：mcp_memory.db 在线备份 + dev-log-archive/ 副本 + manifest.json。
+// 与单文件备份 (memory-*.db) 不同，它连同 memo_archive.jsonl 一起保存，恢复后数据库与归档仍能相互印证。
+
+const (
+	bundlePrefix     = "bundle-"
+	bundleDBName     = "mcp_memory.db"
+	bundleArchiveDir = "dev-log-archive"
+	bundleManifest   = "manifest.json"
+)
+
+// BundleInfo 一份整包备份
+type BundleInfo struct {
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	Kind          string    `json:"kind"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"`
+	Memos         int       `json:"memos"`
+	Files         []string  `json:"files"`
+	Size          int64     `json:"size"`
+}
+
+// parseBundleName 解析 bundle-<kind>-<时间>
+func parseBundleName(name string) (kind string, at time.Time, ok bool) {
+	if !strings.HasPrefix(name, bundlePrefix) {
+		return "", time.Time{}, false
+	}
+	// 复用单文件备份的命名规则
+	return parseBackupName("memory-" + strings.TrimPrefix(name, bundlePrefix) + ".db")
+}
+
+// CreateBundle 备份记忆库与 dev-log-archive 到一个带时间戳的目录，并按类别轮转
+func (m *MemoryLayer) CreateBundle(ctx context.Context, kind string) (*BundleInfo, error) {
+	info, err := m.createBundle(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	m.rotateBundles(kind, LoadProjectSettings(m.projectRoot).Storage.BackupKeep)
+	return info, nil
+}
+
+// createBundle 创建整包备份，不做轮转
+func (m *MemoryLayer) createBundle(ctx context.Context, kind string) (*BundleInfo, error) {
+	now := time.Now()
+	name := fmt.Sprintf("%s%s-%s", bundlePrefix, kind, now.Format(backupTimeLayout))
+	dir := filepath.Join(BackupDir(m.projectRoot), name)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("备份 %s 已存在，请稍后重试", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*BundleInfo, error) {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	dbPath := filepath.Join(dir, bundleDBName)
+	if err := copyDatabase(ctx, m.dbManager.db, false, dbPath); err != nil {
+		return fail(fmt.Errorf("备份数据库失败: %w", err))
+	}
+	if err := ValidateBackup(dbPath); err != nil {
+		return fail(err)
+	}
+	archive := MemoArchiveDir(m.projectRoot)
+	if st, err := os.Stat(archive); err == nil && st.IsDir() {
+		if err := copyDir(archive, filepath.Join(dir, bundleArchiveDir)); err != nil {
+			return fail(fmt.Errorf("复制 %s 失败: %w", bundleArchiveDir, err))
+		}
+	}
+
+	info := &BundleInfo{Name: name, Path: dir, Kind: kind, CreatedAt: now}
+	info.SchemaVersion, _ = m.dbManager.SchemaVersion()
+	_ = m.dbManager.QueryRow("SELECT COUNT(*) FROM memos").Scan(&info.Memos)
+	info.Files, info.Size = bundleFiles(dir)
+	data, _ := json.MarshalIndent(info, "", "  ")
+	if err := os.WriteFile(filepath.Join(dir, bundleManifest), data, 0644); err != nil {
+		return fail(err)
+	}
+	return info, nil
+}
+
+// bundleFiles 目录内文件（相对路径）与总大小
+func bundleFiles(dir string) ([]string, int64) {
+	var files []string
+	var size int64
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files = append(files, filepath.ToSlash(rel))
+		if st, err := d.Info(); err == nil {
+			size += st.Size()
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, size
+}
+
+// rotateBundles 删除超出保留份数的最旧整包备份；protect 中的备份不删除
+func (m *MemoryLayer) rotateBundles(kind string, keep int, protect ...string) {
+	bundles, err := ListBundles(m.projectRoot)
+	if err != nil {
+		return
+	}
+	n := 0
+	for _, b := range bundles { // 新 → 旧
+		if b.Kind != kind {
+			continue
+		}
+		n++
+		if n > keep && !containsName(protect, b.Name) {
+			os.RemoveAll(b.Path)
+		}
+	}
+}
+
+// ListBundles 列出整包备份，最新的在前
+func ListBundles(projectRoot string) ([]BundleInfo, error) {
+	entries, err := os.ReadDir(BackupDir(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []BundleInfo
+	for _, e := range entries {
+		kind, at, ok := parseBundleName(e.Name())
+		if !e.IsDir() || !ok {
+			continue
+		}
+		dir := filepath.Join(BackupDir(projectRoot), e.Name())
+		info := BundleInfo{Name: e.Name(), Path: dir, Kind: kind, CreatedAt: at}
+		if data, err := os.ReadFile(filepath.Join(dir, bundleManifest)); err == nil {
+			_ = json.Unmarshal(data, &info)
+			info.Path = dir
+		}
+		info.Files, info.Size = bundleFiles(dir)
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// RestoreBundle 校验整包备份，先把当前状态保存为 pre-restore 整包，再恢复数据库与 dev-log-archive；返回安全副本
+func (m *MemoryLayer) RestoreBundle(ctx context.Context, name string) (*BundleInfo, error) {
+	name = filepath.Base(strings.TrimSpace(name))
+	if _, _, ok := parseBundleName(name); !ok {
+		return nil, fmt.Errorf("无效的整包备份名: %s", name)
+	}
+	dir := filepath.Join(BackupDir(m.projectRoot), name)
+	dbPath := filepath.Join(dir, bundleDBName)
+	if err := ValidateBackup(dbPath); err != nil {
+		return nil, err
+	}
+	safety, err := m.createBundle(ctx, BackupPreRestore)
+	if err != nil {
+		return nil, fmt.Errorf("创建恢复前安全副本失败，已中止恢复: %w", err)
+	}
+	if err := copyDatabase(ctx, m.dbManager.db, true, dbPath); err != nil {
+		return safety, fmt.Errorf("恢复数据库失败（安全副本 %s 可用于回退）: %w", safety.Name, err)
+	}
+	if err := m.dbManager.healSchema(); err != nil {
+		fmt.Fprintf(os.Stderr, "[DB][WARN] 恢复后 Schema 修复失败: %v\n", err)
+	}
+	archive := filepath.Join(dir, bundleArchiveDir)
+	if st, err := os.Stat(archive); err == nil && st.IsDir() {
+		if err := copyDir(archive, MemoArchiveDir(m.projectRoot)); err != nil {
+			return safety, fmt.Errorf("数据库已恢复，但恢复 %s 失败（安全副本 %s 可用于回退）: %w", bundleArchiveDir, safety.Name, err)
+		}
+	}
+	// 与 RestoreBackup 相同：恢复完成后再轮转，且不删除刚恢复的整包
+	m.rotateBundles(BackupPreRestore, LoadProjectSettings(m.projectRoot).Storage.BackupKeep, name)
+	return safety, nil
+}
+
+// VacuumResult VACUUM 前后的库体积（含 WAL）
+type VacuumResult struct {
+	Before int64 `json:"before"`
+	After  int64 `json:"after"`
+}
+
+// Vacuum 合并 WAL 并重建数据库文件，回收删除/归档后留下的空闲页
+func (m *MemoryLayer) Vacuum(ctx context.Context) (*VacuumResult, error) {
+	res := &VacuumResult{Before: m.dbFileSize()}
+	if _, err := m.dbManager.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return nil, fmt.Errorf("VACUUM 失败: %w", err)
+	}
+	if _, err := m.dbManager.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return nil, fmt.Errorf("WAL checkpoint 失败: %w", err)
+	}
+	res.After = m.dbFileSize()
+	return res, nil
+}
+
+func (m *MemoryLayer) dbFileSize() int64 {
+	var total int64
+	for _, suffix := range []string{"", "-wal"} {
+		if st, err := os.Stat(m.dbManager.dbPath + suffix); err == nil {
+			total += st.Size()
+		}
+	}
+	return total
+}
+
+// IntegrityReport 记忆库完整性检查结果
+type IntegrityReport struct {
+	Integrity       []string `json:"integrity"` // PRAGMA integrity_check 输出，仅 "ok" 表示无损坏
+	OK              bool     `json:"ok"`
+	ArchivePresent  bool     `json:"archive_present"`
+	ArchiveLive     int      `json:"archive_live"`     // 重放归档后应处于活跃状态的 memo 数
+	ArchiveArchived int      `json:"archive_archived"` // 重放归档后应已归档/合并的 memo 数
+	DBLive          int      `json:"db_live"`
+	DBArchived      int      `json:"db_archived"`
+	Missing         int      `json:"missing"` // 归档中有、数据库里没有
+	Extra           int      `json:"extra"`   // 数据库里有、归档中没有（例如归档机制引入前的旧 memo）
+	MissingSamples  []string `json:"missing_samples,omitempty"`
+	ExtraSamples    []string `json:"extra_samples,omitempty"`
+}
+
+const integritySampleLimit = 10
+
+// IntegrityCheck 运行 PRAGMA integrity_check，并把 memo_archive.jsonl 的重放结果与数据库比对。
+// 比对按 (category, content, 是否归档) 做多重集合匹配，不依赖 ID，恢复重放后 ID 变化也能对齐。
+func (m *MemoryLayer) IntegrityCheck(ctx context.Context) (*IntegrityReport, error) {
+	report := &IntegrityReport{}
+	rows, err := m.dbManager.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("integrity_check 失败: %w", err)
+	}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Integrity = append(report.Integrity, line)
+	}
+	rows.Close()
+	report.OK = len(report.Integrity) == 1 && report.Integrity[0] == "ok"
+
+	expected, present, err := m.replayMemoArchive()
+	if err != nil {
+		return report, fmt.Errorf("读取 memo_archive.jsonl 失败: %w", err)
+	}
+	report.ArchivePresent = present
+	if !present {
+		return report, nil
+	}
+
+	remaining := make(map[string]int)
+	for _, e := range expected {
+		remaining[memoCompareKey(e.Category, e.Content, e.archived)]++
+		if e.archived {
+			report.ArchiveArchived++
+		} else {
+			report.ArchiveLive++
+		}
+	}
+
+	dbRows, err := m.dbManager.db.QueryContext(ctx, "SELECT id, COALESCE(category,''), COALESCE(content,''), COALESCE(archived,0) FROM memos ORDER BY id")
+	if err != nil {
+		return report, err
+	}
+	defer dbRows.Close()
+	for dbRows.Next() {
+		var id int64
+		var category, content string
+		var archived int
+		if err := dbRows.Scan(&id, &category, &content, &archived); err != nil {
+			return report, err
+		}
+		if archived != 0 {
+			report.DBArchived++
+		} else {
+			report.DBLive++
+		}
+		key := memoCompareKey(category, content, archived != 0)
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		report.Extra++
+		if len(report.ExtraSamples) < integritySampleLimit {
+			report.ExtraSamples = append(report.ExtraSamples, fmt.Sprintf("#%d [%s] %s", id, category, truncateRunes(content, 60)))
+		}
+	}
+	for _, e := range expected {
+		key := memoCompareKey(e.Category, e.Content, e.archived)
+		if remaining[key] == 0 {
+			continue
+		}
+		remaining[key]--
+		report.Missing++
+		if len(report.MissingSamples) < integritySampleLimit {
+			report.MissingSamples = append(report.MissingSamples, fmt.Sprintf("归档#%d [%s] %s", e.ID, e.Category, truncateRunes(e.Content, 60)))
+		}
+	}
+	return report, dbRows.Err()
+}
+
+func memoCompareKey(category, content string, archived bool) string {
+	return fmt.Sprintf("%s\x00%s\x00%t", category, strings.TrimSpace(content), archived)
+}
+
+func truncateRunes(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// replayedMemo 重放归档后的 memo 状态
+type replayedMemo struct {
+	memoArchiveEntry
+	archived bool
+}
+
+// replayMemoArchive 在内存中重放 memo_archive.jsonl（新增 + 墓碑），得到数据库应有的 memo 集合（按归档 ID 排序）
+func (m *MemoryLayer) replayMemoArchive() ([]*replayedMemo, bool, error) {
+	f, err := os.Open(MemoArchivePath(m.projectRoot))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	memos := make(map[int64]*replayedMemo)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry memoArchiveEntry
+		if json.Unmarshal([]byte(line), &entry) != nil {
+			continue
+		}
+		if entry.Op == "" {
+			memos[entry.ID] = &replayedMemo{memoArchiveEntry: entry}
+			continue
+		}
+		cur, ok := memos[entry.ID]
+		if !ok {
+			continue
+		}
+		switch entry.Op {
+		case MemoOpUpdate:
+			cur.Category, cur.Entity, cur.Act, cur.Path, cur.Content = entry.Category, entry.Entity, entry.Act, entry.Path, entry.Content
+		case MemoOpDelete:
+			delete(memos, entry.ID)
+		case MemoOpArchive, MemoOpMerge:
+			cur.archived = true
+		case MemoOpUnarchive:
+			cur.archived = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, true, err
+	}
+
+	out := make([]*replayedMemo, 0, len(memos))
+	for _, r := range memos {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, true, nil
+}
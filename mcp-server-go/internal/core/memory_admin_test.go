@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBundleRestoreAndIntegrityCheck(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, mem)
+	ids, err := mem.AddMemos(ctx, []Memo{
+		{Category: "修改", Entity: "auth", Act: "修复", Path: "auth.go", Content: "修复登录超时"},
+		{Category: "决策", Entity: "db", Act: "选型", Path: "db.go", Content: "改用 WAL 模式"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := mem.IntegrityCheck(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK || !report.ArchivePresent || report.ArchiveLive != 2 || report.Missing != 0 || report.Extra != 0 {
+		t.Fatalf("fresh db should match archive: %+v", report)
+	}
+
+	bundle, err := mem.CreateBundle(ctx, BackupManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Memos != 2 || len(bundle.Files) < 2 {
+		t.Errorf("bundle = %+v", bundle)
+	}
+
+	// 绕过墓碑直接删库：归档比对应发现缺失
+	if _, err := mem.dbManager.Exec("DELETE FROM memos WHERE id = ?", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if report, _ = mem.IntegrityCheck(ctx); report.Missing != 1 || len(report.MissingSamples) != 1 {
+		t.Fatalf("expected one missing memo, got %+v", report)
+	}
+
+	safety, err := mem.RestoreBundle(ctx, bundle.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if safety.Kind != BackupPreRestore {
+		t.Errorf("safety bundle kind = %s", safety.Kind)
+	}
+	if report, _ = mem.IntegrityCheck(ctx); report.Missing != 0 || report.DBLive != 2 {
+		t.Errorf("restored db should match archive again: %+v", report)
+	}
+	if bundles, _ := ListBundles(root); len(bundles) != 2 {
+		t.Errorf("expected manual + pre-restore bundles, got %d", len(bundles))
+	}
+
+	if _, err := mem.Vacuum(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
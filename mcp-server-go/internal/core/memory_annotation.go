@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ========== 时间线人工标注 ==========
+
+// AnnotationKinds 支持的标注类型
+var AnnotationKinds = []string{"milestone", "release", "incident", "note"}
+
+// TimelineAnnotation 人工添加的时间线标注（里程碑、发布、事故等）
+type TimelineAnnotation struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	At        time.Time `json:"at"`
+	CreatedAt string    `json:"created_at,omitempty"`
+}
+
+// NormalizeAnnotationKind 校验标注类型，空值视为 note
+func NormalizeAnnotationKind(kind string) (string, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind == "" {
+		return "note", nil
+	}
+	for _, k := range AnnotationKinds {
+		if k == kind {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("未知标注类型 %q（支持 %s）", kind, strings.Join(AnnotationKinds, "/"))
+}
+
+// AddTimelineAnnotation 新增标注，At 为零值时取当前时间
+func (m *MemoryLayer) AddTimelineAnnotation(ctx context.Context, a TimelineAnnotation) (int64, error) {
+	kind, err := NormalizeAnnotationKind(a.Kind)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(a.Title) == "" {
+		return 0, fmt.Errorf("标注标题不能为空")
+	}
+	if a.At.IsZero() {
+		a.At = time.Now()
+	}
+	res, err := m.dbManager.Exec(
+		"INSERT INTO timeline_annotations (kind, title, content, author, at) VALUES (?, ?, ?, ?, ?)",
+		kind, strings.TrimSpace(a.Title), a.Content, a.Author, a.At.Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DeleteTimelineAnnotation 删除标注，返回是否存在
+func (m *MemoryLayer) DeleteTimelineAnnotation(ctx context.Context, id int64) (bool, error) {
+	res, err := m.dbManager.Exec("DELETE FROM timeline_annotations WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListTimelineAnnotations 按时间倒序列出标注；kind 为空时不限类型，limit <= 0 时不限条数
+func (m *MemoryLayer) ListTimelineAnnotations(ctx context.Context, kind string, within TimeRange, limit int) ([]TimelineAnnotation, error) {
+	query := `SELECT id, kind, title, COALESCE(content, ''), COALESCE(author, ''), at, COALESCE(created_at, '')
+		FROM timeline_annotations`
+	var params []interface{}
+	if kind = strings.TrimSpace(kind); kind != "" {
+		query += " WHERE kind = ?"
+		params = append(params, strings.ToLower(kind))
+	}
+	query += " ORDER BY at DESC, id DESC"
+
+	rows, err := m.dbManager.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []TimelineAnnotation
+	for rows.Next() {
+		var a TimelineAnnotation
+		var at string
+		if err := rows.Scan(&a.ID, &a.Kind, &a.Title, &a.Content, &a.Author, &at, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.At, _ = time.Parse(time.RFC3339, at)
+		if !within.Contains(a.At) {
+			continue
+		}
+		items = append(items, a)
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// ========== 记忆库整库备份与恢复 ==========
+//
+// 用 SQLite 在线备份 API 把 mcp_memory.db 完整复制到数据目录 backups/ 下，按类别轮转保留。
+// 恢复前先校验备份（quick_check + 核心表），再把当前库备份为 pre-restore 安全副本，
+// 最后通过备份 API 反向写回正在使用的库，已打开的连接无需重建。
+
+// 备份类别
+const (
+	BackupScheduled  = "scheduled"
+	BackupManual     = "manual"
+	BackupPreRestore = "pre-restore"
+)
+
+const backupTimeLayout = "20060102-150405"
+
+// BackupInfo 一份数据库备份
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Kind      string    `json:"kind"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sqliteBackuper modernc 驱动连接提供的在线备份接口
+type sqliteBackuper interface {
+	NewBackup(dstUri string) (*sqlite.Backup, error)
+	NewRestore(srcUri string) (*sqlite.Backup, error)
+}
+
+// BackupDir 备份目录
+func BackupDir(projectRoot string) string {
+	return DataPath(projectRoot, "backups")
+}
+
+// copyDatabase 在 db 的一条连接上运行备份/恢复，直到所有页复制完成
+func copyDatabase(ctx context.Context, db *sql.DB, restore bool, path string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc interface{}) error {
+		b, ok := dc.(sqliteBackuper)
+		if !ok {
+			return fmt.Errorf("数据库驱动不支持在线备份")
+		}
+		var bk *sqlite.Backup
+		if restore {
+			bk, err = b.NewRestore(path)
+		} else {
+			bk, err = b.NewBackup(path)
+		}
+		if err != nil {
+			return err
+		}
+		for {
+			more, err := bk.Step(-1)
+			if err != nil {
+				bk.Finish()
+				return err
+			}
+			if !more {
+				break
+			}
+		}
+		return bk.Finish()
+	})
+}
+
+// CreateBackup 备份记忆库并按类别轮转，返回新备份
+func (m *MemoryLayer) CreateBackup(ctx context.Context, kind string) (*BackupInfo, error) {
+	info, err := m.createBackup(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	m.rotateBackups(kind, LoadProjectSettings(m.projectRoot).Storage.BackupKeep)
+	return info, nil
+}
+
+// createBackup 备份记忆库，不做轮转
+func (m *MemoryLayer) createBackup(ctx context.Context, kind string) (*BackupInfo, error) {
+	dir := BackupDir(m.projectRoot)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("memory-%s-%s.db", kind, now.Format(backupTimeLayout))
+	path := filepath.Join(dir, name)
+	if err := copyDatabase(ctx, m.dbManager.db, false, path); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("备份失败: %w", err)
+	}
+	if err := ValidateBackup(path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	info := &BackupInfo{Name: name, Path: path, Kind: kind, CreatedAt: now}
+	if st, err := os.Stat(path); err == nil {
+		info.Size = st.Size()
+	}
+	return info, nil
+}
+
+// rotateBackups 删除超出保留份数的最旧备份；protect 中的备份不删除
+func (m *MemoryLayer) rotateBackups(kind string, keep int, protect ...string) {
+	backups, err := ListBackups(m.projectRoot)
+	if err != nil {
+		return
+	}
+	n := 0
+	for _, b := range backups { // 新 → 旧
+		if b.Kind != kind {
+			continue
+		}
+		n++
+		if n > keep && !containsName(protect, b.Name) {
+			os.Remove(b.Path)
+		}
+	}
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ListBackups 列出备份，最新的在前
+func ListBackups(projectRoot string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(BackupDir(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []BackupInfo
+	for _, e := range entries {
+		kind, at, ok := parseBackupName(e.Name())
+		if e.IsDir() || !ok {
+			continue
+		}
+		info := BackupInfo{Name: e.Name(), Path: filepath.Join(BackupDir(projectRoot), e.Name()), Kind: kind, CreatedAt: at}
+		if st, err := e.Info(); err == nil {
+			info.Size = st.Size()
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// parseBackupName 解析 memory-<kind>-<时间>.db
+func parseBackupName(name string) (kind string, at time.Time, ok bool) {
+	if !strings.HasPrefix(name, "memory-") || !strings.HasSuffix(name, ".db") {
+		return "", time.Time{}, false
+	}
+	rest := strings.TrimSuffix(strings.TrimPrefix(name, "memory-"), ".db")
+	if len(rest) <= len(backupTimeLayout)+1 {
+		return "", time.Time{}, false
+	}
+	stamp := rest[len(rest)-len(backupTimeLayout):]
+	at, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return strings.TrimSuffix(rest[:len(rest)-len(backupTimeLayout)], "-"), at, true
+}
+
+// ValidateBackup 校验备份文件完整且包含记忆库核心表
+func ValidateBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("备份不存在: %s", filepath.Base(path))
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("备份校验失败: %v", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("备份已损坏: %s", check)
+	}
+	for _, table := range []string{"memos", "known_facts"} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&n); err != nil || n == 0 {
+			return fmt.Errorf("备份缺少 %s 表，不是有效的记忆库", table)
+		}
+	}
+	return nil
+}
+
+// RestoreBackup 校验指定备份，先为当前库生成 pre-restore 安全副本，再恢复；返回安全副本。
+// 安全副本的轮转推迟到恢复之后且跳过目标备份，否则恢复最旧的 pre-restore 备份时它会先被删掉
+func (m *MemoryLayer) RestoreBackup(ctx context.Context, name string) (*BackupInfo, error) {
+	name = filepath.Base(strings.TrimSpace(name))
+	if _, _, ok := parseBackupName(name); !ok {
+		return nil, fmt.Errorf("无效的备份名: %s", name)
+	}
+	path := filepath.Join(BackupDir(m.projectRoot), name)
+	if err := ValidateBackup(path); err != nil {
+		return nil, err
+	}
+	safety, err := m.createBackup(ctx, BackupPreRestore)
+	if err != nil {
+		return nil, fmt.Errorf("创建恢复前安全副本失败，已中止恢复: %w", err)
+	}
+	if err := copyDatabase(ctx, m.dbManager.db, true, path); err != nil {
+		return safety, fmt.Errorf("恢复失败（安全副本 %s 可用于回退）: %w", safety.Name, err)
+	}
+	m.rotateBackups(BackupPreRestore, LoadProjectSettings(m.projectRoot).Storage.BackupKeep, name)
+	// 备份可能来自旧版本，补齐缺失的表与列
+	if err := m.dbManager.healSchema(); err != nil {
+		fmt.Fprintf(os.Stderr, "[DB][WARN] 恢复后 Schema 修复失败: %v\n", err)
+	}
+	return safety, nil
+}
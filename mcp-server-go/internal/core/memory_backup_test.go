@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.SaveFact(ctx, "铁律", "改完 schema 后运行 make migrate"); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := mem.CreateBackup(ctx, BackupManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.SaveFact(ctx, "避坑", "备份之后写入的事实"); err != nil {
+		t.Fatal(err)
+	}
+
+	safety, err := mem.RestoreBackup(ctx, backup.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	facts, err := mem.QueryFacts(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facts) != 1 {
+		t.Errorf("restored facts = %d, want 1", len(facts))
+	}
+
+	backups, _ := ListBackups(root)
+	if len(backups) != 2 || safety.Kind != BackupPreRestore {
+		t.Errorf("expected manual + pre-restore backups, got %+v", backups)
+	}
+	if _, err := mem.RestoreBackup(ctx, "../mcp_memory.db"); err == nil {
+		t.Error("expected invalid backup name to be rejected")
+	}
+}
+
+func TestRestoreOldestPreRestoreBackup(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.SaveFact(ctx, "铁律", "备份中的事实"); err != nil {
+		t.Fatal(err)
+	}
+	src, err := mem.CreateBackup(ctx, BackupManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(src.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := mem.CreateBundle(ctx, BackupManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 填满 pre-restore 的保留份数，再恢复其中最旧的一份
+	keep := LoadProjectSettings(root).Storage.BackupKeep
+	for i := 1; i <= keep; i++ {
+		stamp := fmt.Sprintf("202001%02d-000000", i)
+		if err := os.WriteFile(filepath.Join(BackupDir(root), "memory-pre-restore-"+stamp+".db"), raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyDir(bundle.Path, filepath.Join(BackupDir(root), bundlePrefix+"pre-restore-"+stamp)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldest := "memory-pre-restore-20200101-000000.db"
+	if _, err := mem.RestoreBackup(ctx, oldest); err != nil {
+		t.Fatalf("restore oldest pre-restore backup: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(BackupDir(root), oldest)); err != nil {
+		t.Errorf("restored backup should survive rotation: %v", err)
+	}
+	if facts, _ := mem.QueryFacts(ctx, "", 10); len(facts) != 1 {
+		t.Errorf("restored facts = %d, want the fact from the backup", len(facts))
+	}
+
+	oldestBundle := bundlePrefix + "pre-restore-20200101-000000"
+	if _, err := mem.RestoreBundle(ctx, oldestBundle); err != nil {
+		t.Fatalf("restore oldest pre-restore bundle: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(BackupDir(root), oldestBundle)); err != nil {
+		t.Errorf("restored bundle should survive rotation: %v", err)
+	}
+	if facts, _ := mem.QueryFacts(ctx, "", 10); len(facts) != 1 {
+		t.Errorf("facts after bundle restore = %d, want the fact from the bundle", len(facts))
+	}
+}
@@ -0,0 +1,346 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== 编辑检查点与回滚 ==========
+//
+// 高风险阶段开始前把选定文件复制到数据目录 checkpoints/<id>/ 下，元数据记录在 checkpoints 表。
+// 回滚前先为同一批文件创建 pre-rollback 检查点，再逐个写回；检查点时不存在的文件在回滚时删除。
+
+// 检查点限制
+const (
+	CheckpointMaxFiles = 500
+	CheckpointMaxBytes = 50 << 20
+	CheckpointKeep     = 30
+)
+
+// CheckpointFile 检查点中的一个文件
+type CheckpointFile struct {
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"` // 创建检查点时文件是否存在
+	Size    int64  `json:"size,omitempty"`
+}
+
+// Checkpoint 一次文件快照
+type Checkpoint struct {
+	ID         int64            `json:"id"`
+	Name       string           `json:"name"`
+	TaskID     string           `json:"task_id,omitempty"`
+	Files      []CheckpointFile `json:"files"`
+	CreatedAt  time.Time        `json:"created_at"`
+	RestoredAt string           `json:"restored_at,omitempty"`
+}
+
+// CheckpointRestore 回滚结果
+type CheckpointRestore struct {
+	Restored []string `json:"restored"`
+	Removed  []string `json:"removed"` // 检查点时不存在、回滚时删除的文件
+	Backup   int64    `json:"backup"`  // 回滚前自动创建的 pre-rollback 检查点
+}
+
+// CheckpointDir 检查点文件的存放目录
+func CheckpointDir(projectRoot string, id int64) string {
+	return DataPath(projectRoot, "checkpoints", strconv.FormatInt(id, 10))
+}
+
+// expandCheckpointPaths 规范化路径并把目录展开为其中的文件（跳过隐藏目录与依赖目录）
+func expandCheckpointPaths(projectRoot string, paths []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(rel string) error {
+		if seen[rel] {
+			return nil
+		}
+		seen[rel] = true
+		out = append(out, rel)
+		if len(out) > CheckpointMaxFiles {
+			return fmt.Errorf("文件数超过上限 %d，请缩小检查点范围", CheckpointMaxFiles)
+		}
+		return nil
+	}
+	for _, raw := range paths {
+		rel := NormalizeLockPath(projectRoot, raw)
+		if rel == "" {
+			continue
+		}
+		if rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+			return nil, fmt.Errorf("路径不在项目内: %s", raw)
+		}
+		abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+		info, err := os.Stat(abs)
+		if err != nil || !info.IsDir() {
+			if err := add(rel); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if p != abs && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			r, err := filepath.Rel(projectRoot, p)
+			if err != nil {
+				return nil
+			}
+			return add(filepath.ToSlash(r))
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func copyCheckpointFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// CreateCheckpoint 为给定文件/目录创建检查点，并删除超出保留数的最旧检查点
+func (m *MemoryLayer) CreateCheckpoint(ctx context.Context, name, taskID string, paths []string) (*Checkpoint, error) {
+	cp, err := m.createCheckpoint(ctx, name, taskID, paths)
+	if err != nil {
+		return nil, err
+	}
+	m.rotateCheckpoints(CheckpointKeep)
+	return cp, nil
+}
+
+// createCheckpoint 快照文件并登记检查点，不做轮转
+func (m *MemoryLayer) createCheckpoint(ctx context.Context, name, taskID string, paths []string) (*Checkpoint, error) {
+	rels, err := expandCheckpointPaths(m.projectRoot, paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(rels) == 0 {
+		return nil, fmt.Errorf("没有可快照的文件")
+	}
+
+	now := time.Now()
+	if strings.TrimSpace(name) == "" {
+		name = "checkpoint " + now.Format("01-02 15:04:05")
+	}
+	res, err := m.dbManager.Exec(
+		"INSERT INTO checkpoints (name, task_id, files_json, created_at) VALUES (?, ?, '[]', ?)",
+		strings.TrimSpace(name), taskID, now.Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{ID: id, Name: strings.TrimSpace(name), TaskID: taskID, CreatedAt: now}
+	dir := CheckpointDir(m.projectRoot, id)
+	fail := func(err error) (*Checkpoint, error) {
+		os.RemoveAll(dir)
+		m.dbManager.Exec("DELETE FROM checkpoints WHERE id = ?", id)
+		return nil, err
+	}
+	var total int64
+	for _, rel := range rels {
+		src := filepath.Join(m.projectRoot, filepath.FromSlash(rel))
+		info, err := os.Stat(src)
+		if os.IsNotExist(err) {
+			cp.Files = append(cp.Files, CheckpointFile{Path: rel})
+			continue
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if total += info.Size(); total > CheckpointMaxBytes {
+			return fail(fmt.Errorf("文件总大小超过上限 %d MB，请缩小检查点范围", CheckpointMaxBytes>>20))
+		}
+		if err := copyCheckpointFile(src, filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+			return fail(fmt.Errorf("快照 %s 失败: %w", rel, err))
+		}
+		cp.Files = append(cp.Files, CheckpointFile{Path: rel, Existed: true, Size: info.Size()})
+	}
+
+	data, _ := json.Marshal(cp.Files)
+	if _, err := m.dbManager.Exec("UPDATE checkpoints SET files_json = ? WHERE id = ?", string(data), id); err != nil {
+		return fail(err)
+	}
+	return cp, nil
+}
+
+// rotateCheckpoints 删除超出保留数的最旧检查点；protect 中的检查点不删除
+func (m *MemoryLayer) rotateCheckpoints(keep int, protect ...int64) {
+	rows, err := m.dbManager.Query("SELECT id FROM checkpoints ORDER BY id DESC LIMIT -1 OFFSET ?", keep)
+	if err != nil {
+		return
+	}
+	var stale []int64
+	for rows.Next() {
+		var id int64
+		if rows.Scan(&id) == nil {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	for _, id := range stale {
+		if containsID(protect, id) {
+			continue
+		}
+		os.RemoveAll(CheckpointDir(m.projectRoot, id))
+		m.dbManager.Exec("DELETE FROM checkpoints WHERE id = ?", id)
+	}
+}
+
+const checkpointColumns = "id, name, COALESCE(task_id, ''), files_json, created_at, COALESCE(restored_at, '')"
+
+func scanCheckpoint(scan func(dest ...interface{}) error) (*Checkpoint, error) {
+	var cp Checkpoint
+	var files, created string
+	if err := scan(&cp.ID, &cp.Name, &cp.TaskID, &files, &created, &cp.RestoredAt); err != nil {
+		return nil, err
+	}
+	cp.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	_ = json.Unmarshal([]byte(files), &cp.Files)
+	return &cp, nil
+}
+
+// GetCheckpoint 按 ID 读取检查点
+func (m *MemoryLayer) GetCheckpoint(ctx context.Context, id int64) (*Checkpoint, error) {
+	cp, err := scanCheckpoint(m.dbManager.QueryRow("SELECT "+checkpointColumns+" FROM checkpoints WHERE id = ?", id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("检查点 #%d 不存在", id)
+	}
+	return cp, err
+}
+
+// LatestCheckpoint 任务最近一次检查点（不含 pre-rollback 自动检查点），没有时返回 nil
+func (m *MemoryLayer) LatestCheckpoint(ctx context.Context, taskID string) (*Checkpoint, error) {
+	cp, err := scanCheckpoint(m.dbManager.QueryRow(
+		"SELECT "+checkpointColumns+" FROM checkpoints WHERE task_id = ? AND name NOT LIKE 'pre-rollback%' ORDER BY id DESC LIMIT 1",
+		taskID,
+	).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return cp, err
+}
+
+// ListCheckpoints 按创建时间倒序列出检查点；taskID 为空时不限任务
+func (m *MemoryLayer) ListCheckpoints(ctx context.Context, taskID string, limit int) ([]Checkpoint, error) {
+	query := "SELECT " + checkpointColumns + " FROM checkpoints"
+	var params []interface{}
+	if taskID = strings.TrimSpace(taskID); taskID != "" {
+		query += " WHERE task_id = ?"
+		params = append(params, taskID)
+	}
+	query += " ORDER BY id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := m.dbManager.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Checkpoint
+	for rows.Next() {
+		cp, err := scanCheckpoint(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *cp)
+	}
+	return items, rows.Err()
+}
+
+// RestoreCheckpoint 回滚到检查点：先创建 pre-rollback 检查点，再写回文件并删除检查点时不存在的文件。
+// 轮转推迟到写回之后且跳过目标检查点，否则回滚最旧的检查点时其快照会在写回前被删掉
+func (m *MemoryLayer) RestoreCheckpoint(ctx context.Context, id int64) (*CheckpointRestore, error) {
+	cp, err := m.GetCheckpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dir := CheckpointDir(m.projectRoot, id)
+	for _, f := range cp.Files {
+		if f.Existed {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Path))); err != nil {
+				return nil, fmt.Errorf("检查点 #%d 的快照文件缺失: %s", id, f.Path)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(cp.Files))
+	for _, f := range cp.Files {
+		paths = append(paths, f.Path)
+	}
+	backup, err := m.createCheckpoint(ctx, fmt.Sprintf("pre-rollback #%d", id), cp.TaskID, paths)
+	if err != nil {
+		return nil, fmt.Errorf("创建 pre-rollback 检查点失败: %w", err)
+	}
+
+	result := &CheckpointRestore{Backup: backup.ID}
+	for _, f := range cp.Files {
+		dst := filepath.Join(m.projectRoot, filepath.FromSlash(f.Path))
+		if !f.Existed {
+			if err := os.Remove(dst); err == nil {
+				result.Removed = append(result.Removed, f.Path)
+			} else if !os.IsNotExist(err) {
+				return result, fmt.Errorf("删除 %s 失败: %w", f.Path, err)
+			}
+			continue
+		}
+		if err := copyCheckpointFile(filepath.Join(dir, filepath.FromSlash(f.Path)), dst); err != nil {
+			return result, fmt.Errorf("恢复 %s 失败: %w", f.Path, err)
+		}
+		result.Restored = append(result.Restored, f.Path)
+	}
+	_, err = m.dbManager.Exec("UPDATE checkpoints SET restored_at = ? WHERE id = ?", time.Now().Format(time.RFC3339), id)
+	m.rotateCheckpoints(CheckpointKeep, id)
+	return result, err
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
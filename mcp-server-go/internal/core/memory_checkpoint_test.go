@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointRollback(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(rel, content string) {
+		p := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("pkg/a.go", "package pkg // v1")
+
+	cp, err := mem.CreateCheckpoint(ctx, "重构前", "T1", []string{"pkg", "pkg/new.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Files) != 2 {
+		t.Fatalf("checkpoint files = %+v, want a.go + new.go", cp.Files)
+	}
+
+	write("pkg/a.go", "package pkg // v2")
+	write("pkg/new.go", "package pkg")
+
+	res, err := mem.RestoreCheckpoint(ctx, cp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "pkg/a.go")); string(data) != "package pkg // v1" {
+		t.Errorf("a.go = %q, want v1", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg/new.go")); !os.IsNotExist(err) {
+		t.Error("new.go should be removed by rollback")
+	}
+	if len(res.Removed) != 1 || res.Backup == 0 {
+		t.Errorf("unexpected restore result %+v", res)
+	}
+
+	latest, err := mem.LatestCheckpoint(ctx, "T1")
+	if err != nil || latest == nil || latest.ID != cp.ID {
+		t.Errorf("latest checkpoint = %+v, want #%d (pre-rollback excluded)", latest, cp.ID)
+	}
+	if _, err := mem.CreateCheckpoint(ctx, "", "", []string{"../outside.txt"}); err == nil {
+		t.Error("expected path outside project to be rejected")
+	}
+}
+
+func TestRestoreOldestCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "a.txt")
+	var oldest int64
+	for i := 0; i < CheckpointKeep; i++ {
+		if err := os.WriteFile(file, []byte(fmt.Sprintf("v%d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cp, err := mem.CreateCheckpoint(ctx, "", "", []string{"a.txt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			oldest = cp.ID
+		}
+	}
+
+	// 创建 pre-rollback 检查点会超出保留数；目标是最旧的一个，回滚必须仍然成功
+	if _, err := mem.RestoreCheckpoint(ctx, oldest); err != nil {
+		t.Fatalf("restore oldest checkpoint: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "v0" {
+		t.Errorf("a.txt = %q, want v0", data)
+	}
+	if _, err := mem.GetCheckpoint(ctx, oldest); err != nil {
+		t.Errorf("restored checkpoint should survive rotation: %v", err)
+	}
+}
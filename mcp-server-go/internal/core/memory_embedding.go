@@ -0,0 +1,227 @@
+package core
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ========== memo / fact 向量存储与语义检索 ==========
+//
+// 向量按 (kind, ref_id, model) 存在 embeddings 表，content_hash 记录向量化时的文本，
+// 文本变化或更换模型后在下次同步时重建。同步是增量的，在语义检索前惰性执行。
+
+// 向量化对象类别
+const (
+	EmbeddingMemo = "memo"
+	EmbeddingFact = "fact"
+)
+
+// SemanticHit 语义检索命中
+type SemanticHit struct {
+	Kind  string
+	ID    int64
+	Score float64
+}
+
+type embeddingSource struct {
+	kind string
+	id   int64
+	text string
+	hash string
+}
+
+func contentHash(text string) string {
+	sum := sha1.Sum([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SyncEmbeddings 为缺少向量或文本已变化的 memo/fact 生成向量，返回新生成的条数
+func (m *MemoryLayer) SyncEmbeddings(ctx context.Context, e Embedder, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	// 清理已删除、已归档或已过期记录的向量
+	m.dbManager.Exec("DELETE FROM embeddings WHERE kind = ? AND ref_id NOT IN (SELECT id FROM memos WHERE archived = 0)", EmbeddingMemo)
+	m.dbManager.Exec("DELETE FROM embeddings WHERE kind = ? AND ref_id NOT IN (SELECT id FROM known_facts WHERE "+activeFactCondition+")",
+		EmbeddingFact, factExpiryNow())
+
+	existing := make(map[string]string)
+	rows, err := m.dbManager.Query("SELECT kind, ref_id, content_hash FROM embeddings WHERE model = ?", e.Model())
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var kind, hash string
+		var id int64
+		if rows.Scan(&kind, &id, &hash) == nil {
+			existing[fmt.Sprintf("%s:%d", kind, id)] = hash
+		}
+	}
+	rows.Close()
+
+	sources, err := m.embeddingSources()
+	if err != nil {
+		return 0, err
+	}
+	var pending []embeddingSource
+	for _, s := range sources {
+		if existing[fmt.Sprintf("%s:%d", s.kind, s.id)] != s.hash {
+			pending = append(pending, s)
+		}
+	}
+
+	done := 0
+	now := time.Now().Format(time.RFC3339)
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		texts := make([]string, len(batch))
+		for i, s := range batch {
+			texts[i] = s.text
+		}
+		vecs, err := e.Embed(ctx, texts)
+		if err != nil {
+			return done, err
+		}
+		for i, s := range batch {
+			if _, err := m.dbManager.Exec(
+				`INSERT INTO embeddings (kind, ref_id, model, dim, vector, content_hash, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+				 ON CONFLICT(kind, ref_id, model) DO UPDATE SET dim=excluded.dim, vector=excluded.vector,
+				 content_hash=excluded.content_hash, updated_at=excluded.updated_at`,
+				s.kind, s.id, e.Model(), len(vecs[i]), encodeVector(vecs[i]), s.hash, now,
+			); err != nil {
+				return done, err
+			}
+			done++
+		}
+	}
+	return done, nil
+}
+
+// embeddingSources 读取需要向量化的 memo 与 fact 文本
+func (m *MemoryLayer) embeddingSources() ([]embeddingSource, error) {
+	var out []embeddingSource
+	rows, err := m.dbManager.Query("SELECT id, category, entity, act, content FROM memos WHERE archived = 0")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var category, entity, act, content string
+		if rows.Scan(&id, &category, &entity, &act, &content) != nil {
+			continue
+		}
+		text := strings.Join([]string{category, entity, act, content}, " ")
+		out = append(out, embeddingSource{EmbeddingMemo, id, text, contentHash(text)})
+	}
+	rows.Close()
+
+	rows, err = m.dbManager.Query("SELECT id, type, summarize FROM known_facts WHERE "+activeFactCondition, factExpiryNow())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var factType, summarize string
+		if rows.Scan(&id, &factType, &summarize) != nil {
+			continue
+		}
+		text := factType + " " + summarize
+		out = append(out, embeddingSource{EmbeddingFact, id, text, contentHash(text)})
+	}
+	return out, nil
+}
+
+// SemanticSearch 按与 query 的余弦相似度返回命中（降序），低于 minScore 的丢弃
+func (m *MemoryLayer) SemanticSearch(ctx context.Context, e Embedder, query string, minScore float64, limit int) ([]SemanticHit, error) {
+	qv, err := e.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.dbManager.Query("SELECT kind, ref_id, vector FROM embeddings WHERE model = ?", e.Model())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []SemanticHit
+	for rows.Next() {
+		var h SemanticHit
+		var blob []byte
+		if rows.Scan(&h.Kind, &h.ID, &blob) != nil {
+			continue
+		}
+		if h.Score = CosineSimilarity(qv[0], decodeVector(blob)); h.Score >= minScore {
+			hits = append(hits, h)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// GetMemosByIDs 按 ID 读取 memo（顺序不保证）
+func (m *MemoryLayer) GetMemosByIDs(ctx context.Context, ids []int64) ([]Memo, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := "SELECT " + memoColumns + " FROM memos WHERE id IN (" + placeholders(len(ids)) + ")"
+	rows, err := m.dbManager.Query(query, int64Args(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var memos []Memo
+	for rows.Next() {
+		var memo Memo
+		if err := rows.Scan(&memo.ID, &memo.Category, &memo.Entity, &memo.Act, &memo.Path, &memo.Content, &memo.SessionID, &memo.Timestamp, &memo.GitCommit, &memo.GitDirty); err != nil {
+			return nil, err
+		}
+		memos = append(memos, memo)
+	}
+	return memos, nil
+}
+
+// GetFactsByIDs 按 ID 读取事实（顺序不保证）
+func (m *MemoryLayer) GetFactsByIDs(ctx context.Context, ids []int64) ([]KnownFact, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := m.dbManager.Query("SELECT "+factColumns+" FROM known_facts WHERE id IN ("+placeholders(len(ids))+")", int64Args(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var facts []KnownFact
+	for rows.Next() {
+		f, err := scanFact(rows)
+		if err != nil {
+			return nil, err
+		}
+		facts = append(facts, f)
+	}
+	return facts, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
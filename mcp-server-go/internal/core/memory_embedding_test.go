@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSemanticSearchWithHashEmbedder(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, mem)
+	ids, err := mem.AddMemos(ctx, []Memo{
+		{Category: "修改", Entity: "Login", Act: "重构", Content: "authentication retries now use exponential backoff"},
+		{Category: "修改", Entity: "Render", Act: "优化", Content: "cache rendered markdown templates"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewEmbedder(t.TempDir(), EmbeddingSettings{Provider: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := mem.SyncEmbeddings(ctx, e, 0); err != nil || n != 2 {
+		t.Fatalf("first sync = %d, %v; want 2", n, err)
+	}
+	if n, _ := mem.SyncEmbeddings(ctx, e, 0); n != 0 {
+		t.Fatalf("second sync = %d, want 0 (incremental)", n)
+	}
+
+	hits, err := mem.SemanticSearch(ctx, e, "authenticate retry", 0.1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) == 0 || hits[0].Kind != EmbeddingMemo || hits[0].ID != ids[0] {
+		t.Fatalf("hits = %+v, want memo %d first", hits, ids[0])
+	}
+}
+
+func TestNewEmbedderRejectsUnknownProvider(t *testing.T) {
+	if e, err := NewEmbedder(t.TempDir(), EmbeddingSettings{}); e != nil || err != nil {
+		t.Fatalf("empty provider should disable embedding, got %v, %v", e, err)
+	}
+	if _, err := NewEmbedder(t.TempDir(), EmbeddingSettings{Provider: "bogus"}); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
+
+func TestCommandEmbedderUsesCommandRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	ctx := context.Background()
+	root := t.TempDir()
+	script := filepath.Join(root, "embed.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat >/dev/null\necho '[[1,0],[0,1]]'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEmbedder(root, EmbeddingSettings{Provider: "command", Command: script})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未加入 commands.allow 时拒绝执行并留下审计
+	if _, err := e.Embed(ctx, []string{"a", "b"}); !errors.Is(err, ErrCommandDenied) {
+		t.Fatalf("command outside allowlist should be denied, got %v", err)
+	}
+
+	settings := DefaultProjectSettings()
+	settings.Commands.Allow = []string{script}
+	if err := SaveProjectSettings(root, settings); err != nil {
+		t.Fatal(err)
+	}
+	vecs, err := e.Embed(ctx, []string{"a", "b"})
+	if err != nil || len(vecs) != 2 || vecs[1][1] != 1 {
+		t.Fatalf("embed = %v, %v", vecs, err)
+	}
+	recs, _ := NewCommandRunner(root).RecentAudits("embedding", 10)
+	if len(recs) != 2 || !recs[0].Allowed || recs[1].Allowed {
+		t.Fatalf("unexpected audits: %+v", recs)
+	}
+}
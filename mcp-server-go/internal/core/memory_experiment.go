@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ========== 协议对照实验 ==========
+
+// ExperimentRun 一条任务链在实验中的运行记录
+type ExperimentRun struct {
+	TaskID      string `json:"task_id"`
+	Experiment  string `json:"experiment"`
+	Protocol    string `json:"protocol"`
+	Status      string `json:"status"`
+	StartedAt   string `json:"started_at"`
+	FinishedAt  string `json:"finished_at,omitempty"`
+	DurationSec int64  `json:"duration_sec"`
+	Retries     int    `json:"retries"`
+	Reinits     int    `json:"reinits"`
+}
+
+// ExperimentStat 按实验 + 协议聚合的对比指标
+type ExperimentStat struct {
+	Experiment     string  `json:"experiment"`
+	Protocol       string  `json:"protocol"`
+	Runs           int     `json:"runs"`
+	Finished       int     `json:"finished"`
+	Failed         int     `json:"failed"`
+	AvgDurationSec float64 `json:"avg_duration_sec"` // 仅统计已结束的运行
+	AvgRetries     float64 `json:"avg_retries"`
+	AvgReinits     float64 `json:"avg_reinits"`
+}
+
+// StartExperimentRun 登记任务链参与实验；同一 task_id 重复登记（re-init）时更新协议但保留开始时间
+func (m *MemoryLayer) StartExperimentRun(ctx context.Context, taskID, experiment, protocol string) error {
+	_, err := m.dbManager.Exec(`INSERT INTO experiments (task_id, experiment, protocol, status, started_at)
+		VALUES (?, ?, ?, 'running', ?)
+		ON CONFLICT(task_id) DO UPDATE SET experiment=excluded.experiment, protocol=excluded.protocol`,
+		taskID, experiment, protocol, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// FinishExperimentRun 记录运行结果；任务链未参与实验或已记录结果时不做任何事
+func (m *MemoryLayer) FinishExperimentRun(ctx context.Context, taskID, status string, retries, reinits int) error {
+	var startedAt string
+	err := m.dbManager.QueryRow("SELECT started_at FROM experiments WHERE task_id = ? AND status = 'running'", taskID).Scan(&startedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var duration int64
+	if started, err := time.Parse(time.RFC3339, startedAt); err == nil {
+		duration = int64(now.Sub(started).Seconds())
+	}
+	_, err = m.dbManager.Exec(`UPDATE experiments SET status = ?, finished_at = ?, duration_sec = ?, retries = ?, reinits = ?
+		WHERE task_id = ?`, status, now.Format(time.RFC3339), duration, retries, reinits, taskID)
+	return err
+}
+
+// ExperimentStats 聚合对比视图；experiment 为空时返回全部实验
+func (m *MemoryLayer) ExperimentStats(ctx context.Context, experiment string) ([]ExperimentStat, error) {
+	query := `SELECT experiment, COALESCE(protocol, ''), COUNT(*),
+			SUM(CASE WHEN status = 'finished' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END),
+			COALESCE(AVG(CASE WHEN status != 'running' THEN duration_sec END), 0),
+			COALESCE(AVG(retries), 0), COALESCE(AVG(reinits), 0)
+		FROM experiments`
+	var params []interface{}
+	if experiment != "" {
+		query += " WHERE experiment = ?"
+		params = append(params, experiment)
+	}
+	query += " GROUP BY experiment, protocol ORDER BY experiment, protocol"
+
+	rows, err := m.dbManager.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ExperimentStat
+	for rows.Next() {
+		var s ExperimentStat
+		if err := rows.Scan(&s.Experiment, &s.Protocol, &s.Runs, &s.Finished, &s.Failed,
+			&s.AvgDurationSec, &s.AvgRetries, &s.AvgReinits); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
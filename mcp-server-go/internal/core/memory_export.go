@@ -0,0 +1,271 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ========== 记忆导出 / 导入（Markdown 知识库的数据层） ==========
+//
+// 导出内容：有效事实、未归档 memo、任务、全部钩子。渲染为 Markdown 由 tools 层完成，
+// 这里只负责收集与回灌；导入按自然键去重，可重复执行，用于从提交到 git 的知识库重建新库。
+
+// ExportFact 导出的事实
+type ExportFact struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Summary   string `json:"summary"`
+	Scope     string `json:"scope,omitempty"`
+	Priority  string `json:"priority,omitempty"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// ExportMemo 导出的 memo
+type ExportMemo struct {
+	ID        int64  `json:"id"`
+	Category  string `json:"category"`
+	Entity    string `json:"entity"`
+	Act       string `json:"act"`
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Timestamp string `json:"timestamp"`
+	GitCommit string `json:"git_commit,omitempty"`
+}
+
+// ExportTask 导出的任务
+type ExportTask struct {
+	TaskID      string `json:"task_id"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Summary     string `json:"summary,omitempty"`
+	Pitfalls    string `json:"pitfalls,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// ExportHook 导出的钩子
+type ExportHook struct {
+	HookID        string `json:"hook_id"`
+	Description   string `json:"description"`
+	Priority      string `json:"priority"`
+	Tag           string `json:"tag,omitempty"`
+	Status        string `json:"status"`
+	RelatedTaskID string `json:"related_task_id,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	ResultSummary string `json:"result_summary,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+}
+
+// MemoryExport 一次导出的全部记忆
+type MemoryExport struct {
+	Facts []ExportFact
+	Memos []ExportMemo
+	Tasks []ExportTask
+	Hooks []ExportHook
+}
+
+// ImportResult 导入统计：新增与因已存在而跳过的条数
+type ImportResult struct {
+	Facts, Memos, Tasks, Hooks int
+	Skipped                    int
+}
+
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(exportTimeLayout)
+}
+
+func parseExportTime(s string) time.Time {
+	if t, err := time.ParseInLocation(exportTimeLayout, strings.TrimSpace(s), time.Local); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+// CollectExport 收集待导出的记忆，各类均按创建顺序排列
+func (m *MemoryLayer) CollectExport(ctx context.Context) (*MemoryExport, error) {
+	exp := &MemoryExport{}
+
+	rows, err := m.dbManager.Query("SELECT "+factColumns+" FROM known_facts WHERE "+activeFactCondition+" ORDER BY id", factExpiryNow())
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		f, err := scanFact(rows)
+		if err != nil {
+			continue
+		}
+		ef := ExportFact{ID: f.ID, Type: f.Type, Summary: f.Summarize, Scope: f.Scope, Priority: f.Priority, CreatedAt: exportTime(f.CreatedAt)}
+		if f.ExpiresAt.Valid {
+			ef.ExpiresAt = exportTime(f.ExpiresAt.Time)
+		}
+		exp.Facts = append(exp.Facts, ef)
+	}
+	rows.Close()
+
+	rows, err = m.dbManager.Query("SELECT " + memoColumns + " FROM memos WHERE archived = 0 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var memo Memo
+		if err := rows.Scan(&memo.ID, &memo.Category, &memo.Entity, &memo.Act, &memo.Path, &memo.Content, &memo.SessionID, &memo.Timestamp, &memo.GitCommit, &memo.GitDirty); err != nil {
+			continue
+		}
+		exp.Memos = append(exp.Memos, ExportMemo{
+			ID: memo.ID, Category: memo.Category, Entity: memo.Entity, Act: memo.Act, Path: memo.Path,
+			Content: memo.Content, Timestamp: exportTime(memo.Timestamp), GitCommit: memo.GitCommit,
+		})
+	}
+	rows.Close()
+
+	rows, err = m.dbManager.Query("SELECT task_id, COALESCE(description,''), COALESCE(status,''), summary, pitfalls, created_at, updated_at FROM tasks ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t ExportTask
+		var summary, pitfalls sql.NullString
+		var created, updated time.Time
+		if err := rows.Scan(&t.TaskID, &t.Description, &t.Status, &summary, &pitfalls, &created, &updated); err != nil {
+			continue
+		}
+		t.Summary, t.Pitfalls = summary.String, pitfalls.String
+		t.CreatedAt, t.UpdatedAt = exportTime(created), exportTime(updated)
+		exp.Tasks = append(exp.Tasks, t)
+	}
+	rows.Close()
+
+	rows, err = m.dbManager.Query(`SELECT hook_id, COALESCE(description,''), COALESCE(priority,''), COALESCE(tag,''), COALESCE(status,''),
+		related_task_id, summary, result_summary, created_at, expires_at FROM pending_hooks ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h ExportHook
+		var related, summary, result sql.NullString
+		var created time.Time
+		var expires sql.NullTime
+		if err := rows.Scan(&h.HookID, &h.Description, &h.Priority, &h.Tag, &h.Status, &related, &summary, &result, &created, &expires); err != nil {
+			continue
+		}
+		h.RelatedTaskID, h.Summary, h.ResultSummary = related.String, summary.String, result.String
+		h.CreatedAt = exportTime(created)
+		if expires.Valid {
+			h.ExpiresAt = exportTime(expires.Time)
+		}
+		exp.Hooks = append(exp.Hooks, h)
+	}
+	return exp, nil
+}
+
+// ImportExport 把导出的记忆写回数据库。已存在的条目跳过：
+// 事实按 (type, summary)、memo 按 (category, content)、任务按 task_id、钩子按 hook_id 判重。
+// 新增的 memo 同时追加到 memo_archive.jsonl 并刷新 dev-log.md。
+func (m *MemoryLayer) ImportExport(ctx context.Context, exp *MemoryExport) (*ImportResult, error) {
+	res := &ImportResult{}
+	exists := func(query string, args ...interface{}) bool {
+		var n int
+		return m.dbManager.QueryRow(query, args...).Scan(&n) == nil && n > 0
+	}
+
+	for _, f := range exp.Facts {
+		if exists("SELECT COUNT(*) FROM known_facts WHERE type = ? AND summarize = ? AND superseded_by IS NULL", f.Type, f.Summary) {
+			res.Skipped++
+			continue
+		}
+		scope, err := NormalizeFactScope(f.Scope)
+		if err != nil {
+			return res, fmt.Errorf("事实 #%d: %w", f.ID, err)
+		}
+		priority, err := NormalizeFactPriority(f.Priority)
+		if err != nil {
+			return res, fmt.Errorf("事实 #%d: %w", f.ID, err)
+		}
+		var expires interface{}
+		if f.ExpiresAt != "" {
+			expires = parseExportTime(f.ExpiresAt).UTC().Format(factExpiryLayout)
+		}
+		if _, err := m.dbManager.Exec("INSERT INTO known_facts (type, summarize, created_at, scope, priority, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+			f.Type, f.Summary, parseExportTime(f.CreatedAt), scope, priority, expires); err != nil {
+			return res, err
+		}
+		res.Facts++
+	}
+
+	var archives []memoArchiveEntry
+	for _, memo := range exp.Memos {
+		if exists("SELECT COUNT(*) FROM memos WHERE category = ? AND content = ?", memo.Category, memo.Content) {
+			res.Skipped++
+			continue
+		}
+		ts := parseExportTime(memo.Timestamp)
+		r, err := m.dbManager.Exec("INSERT INTO memos (category, entity, act, path, content, session_id, timestamp, git_commit) VALUES (?, ?, ?, ?, ?, 'import', ?, ?)",
+			memo.Category, memo.Entity, memo.Act, memo.Path, memo.Content, ts.Format(exportTimeLayout), memo.GitCommit)
+		if err != nil {
+			return res, err
+		}
+		id, _ := r.LastInsertId()
+		archives = append(archives, memoArchiveEntry{
+			ID: id, Category: memo.Category, Entity: memo.Entity, Act: memo.Act, Path: memo.Path,
+			Content: memo.Content, SessionID: "import", Timestamp: ts, GitCommit: memo.GitCommit,
+		})
+		res.Memos++
+	}
+	if len(archives) > 0 {
+		m.appendMemoArchive(archives)
+		m.requestDevLogSync()
+	}
+
+	for _, t := range exp.Tasks {
+		r, err := m.dbManager.Exec(`INSERT OR IGNORE INTO tasks (task_id, description, status, summary, pitfalls, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			t.TaskID, t.Description, fallbackText(t.Status, "in_progress"), t.Summary, t.Pitfalls, parseExportTime(t.CreatedAt), parseExportTime(t.UpdatedAt))
+		if err != nil {
+			return res, err
+		}
+		if n, _ := r.RowsAffected(); n > 0 {
+			res.Tasks++
+		} else {
+			res.Skipped++
+		}
+	}
+
+	for _, h := range exp.Hooks {
+		var expires interface{}
+		if h.ExpiresAt != "" {
+			expires = parseExportTime(h.ExpiresAt)
+		}
+		r, err := m.dbManager.Exec(`INSERT OR IGNORE INTO pending_hooks (hook_id, description, priority, tag, status, related_task_id, summary, result_summary, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			h.HookID, h.Description, fallbackText(h.Priority, "medium"), h.Tag, fallbackText(h.Status, "open"), h.RelatedTaskID,
+			h.Summary, h.ResultSummary, parseExportTime(h.CreatedAt), expires)
+		if err != nil {
+			return res, err
+		}
+		if n, _ := r.RowsAffected(); n > 0 {
+			res.Hooks++
+		} else {
+			res.Skipped++
+		}
+	}
+	return res, nil
+}
+
+// fallbackText 空白值取默认值
+func fallbackText(v, def string) string {
+	if strings.TrimSpace(v) == "" {
+		return def
+	}
+	return v
+}
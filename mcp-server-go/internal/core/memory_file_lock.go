@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ========== 文件软锁 ==========
+
+// DefaultFileLockTTL 文件软锁默认有效期
+const DefaultFileLockTTL = 30 * time.Minute
+
+// FileLock 文件软锁记录（仅用于协作提醒，不阻止写入）
+type FileLock struct {
+	Path      string `json:"path"`
+	TaskID    string `json:"task_id"`
+	Holder    string `json:"holder"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NormalizeLockPath 统一锁路径格式（相对路径、正斜杠）
+func NormalizeLockPath(projectRoot, p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+	if filepath.IsAbs(p) && projectRoot != "" {
+		if rel, err := filepath.Rel(projectRoot, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	p = filepath.ToSlash(filepath.Clean(p))
+	return strings.TrimPrefix(p, "./")
+}
+
+// ClaimFiles 为任务声明文件软锁。
+// 已被其他任务持有且未过期的文件不会被抢占，而是作为冲突返回。
+func (m *MemoryLayer) ClaimFiles(ctx context.Context, taskID, holder string, paths []string, ttl time.Duration) (claimed []string, conflicts []FileLock, err error) {
+	if ttl <= 0 {
+		ttl = DefaultFileLockTTL
+	}
+	now := time.Now().UTC()
+
+	existing, err := m.CheckFileLocks(ctx, paths, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocked := make(map[string]bool, len(existing))
+	for _, l := range existing {
+		blocked[l.Path] = true
+	}
+
+	query := `INSERT INTO file_locks (path, task_id, holder, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			task_id=excluded.task_id,
+			holder=excluded.holder,
+			expires_at=excluded.expires_at,
+			created_at=excluded.created_at`
+
+	for _, raw := range paths {
+		p := NormalizeLockPath(m.projectRoot, raw)
+		if p == "" || blocked[p] {
+			continue
+		}
+		if _, err := m.dbManager.Exec(query, p, taskID, holder,
+			now.Add(ttl).Format(time.RFC3339), now.Format(time.RFC3339)); err != nil {
+			return claimed, existing, err
+		}
+		claimed = append(claimed, p)
+	}
+	return claimed, existing, nil
+}
+
+// CheckFileLocks 返回给定文件上由其他任务持有的有效锁
+func (m *MemoryLayer) CheckFileLocks(ctx context.Context, paths []string, excludeTaskID string) ([]FileLock, error) {
+	var results []FileLock
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, raw := range paths {
+		p := NormalizeLockPath(m.projectRoot, raw)
+		if p == "" {
+			continue
+		}
+		var l FileLock
+		err := m.dbManager.QueryRow(
+			`SELECT path, task_id, holder, expires_at, created_at FROM file_locks
+			 WHERE path = ? AND expires_at > ? AND task_id != ?`,
+			p, now, excludeTaskID,
+		).Scan(&l.Path, &l.TaskID, &l.Holder, &l.ExpiresAt, &l.CreatedAt)
+		if err != nil {
+			continue
+		}
+		results = append(results, l)
+	}
+	return results, nil
+}
+
+// ReleaseFileLocks 释放任务持有的文件锁；paths 为空时释放该任务全部锁
+func (m *MemoryLayer) ReleaseFileLocks(ctx context.Context, taskID string, paths []string) (int64, error) {
+	if len(paths) == 0 {
+		res, err := m.dbManager.Exec("DELETE FROM file_locks WHERE task_id = ?", taskID)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	var total int64
+	for _, raw := range paths {
+		p := NormalizeLockPath(m.projectRoot, raw)
+		res, err := m.dbManager.Exec("DELETE FROM file_locks WHERE task_id = ? AND path = ?", taskID, p)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+// ListFileLocks 列出所有有效文件锁（顺带清理过期锁）
+func (m *MemoryLayer) ListFileLocks(ctx context.Context) ([]FileLock, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	m.dbManager.Exec("DELETE FROM file_locks WHERE expires_at <= ?", now)
+
+	rows, err := m.dbManager.Query(
+		`SELECT path, task_id, holder, expires_at, created_at FROM file_locks
+		 ORDER BY task_id, path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []FileLock
+	for rows.Next() {
+		var l FileLock
+		if err := rows.Scan(&l.Path, &l.TaskID, &l.Holder, &l.ExpiresAt, &l.CreatedAt); err != nil {
+			continue
+		}
+		results = append(results, l)
+	}
+	return results, nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLocks(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, mem)
+
+	paths := func(locks []FileLock) []string {
+		var out []string
+		for _, l := range locks {
+			out = append(out, l.Path+"@"+l.TaskID)
+		}
+		return out
+	}
+	expire := func(path string) {
+		past := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+		if _, err := mem.dbManager.Exec("UPDATE file_locks SET expires_at = ? WHERE path = ?", past, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name          string
+		run           func() ([]string, []FileLock, error)
+		wantClaimed   []string
+		wantConflicts []string
+	}{
+		{
+			name: "acquire",
+			run: func() ([]string, []FileLock, error) {
+				return mem.ClaimFiles(ctx, "A", "host:1", []string{"./src/a.go", filepath.Join(root, "src", "b.go")}, 0)
+			},
+			wantClaimed: []string{"src/a.go", "src/b.go"},
+		},
+		{
+			name: "reclaim by the same task",
+			run: func() ([]string, []FileLock, error) {
+				return mem.ClaimFiles(ctx, "A", "host:1", []string{"src/a.go"}, 0)
+			},
+			wantClaimed: []string{"src/a.go"},
+		},
+		{
+			name: "conflict with another task",
+			run: func() ([]string, []FileLock, error) {
+				return mem.ClaimFiles(ctx, "B", "host:2", []string{"src/a.go", "src/c.go"}, 0)
+			},
+			wantClaimed:   []string{"src/c.go"},
+			wantConflicts: []string{"src/a.go@A"},
+		},
+		{
+			name: "expired lock is taken over",
+			run: func() ([]string, []FileLock, error) {
+				expire("src/b.go")
+				return mem.ClaimFiles(ctx, "B", "host:2", []string{"src/b.go"}, 0)
+			},
+			wantClaimed: []string{"src/b.go"},
+		},
+		{
+			name: "release on finish frees all locks of the task",
+			run: func() ([]string, []FileLock, error) {
+				if n, err := mem.ReleaseFileLocks(ctx, "B", nil); err != nil || n != 2 {
+					t.Fatalf("release = %d, %v", n, err)
+				}
+				return mem.ClaimFiles(ctx, "C", "host:3", []string{"src/b.go", "src/c.go", "src/a.go"}, 0)
+			},
+			wantClaimed:   []string{"src/b.go", "src/c.go"},
+			wantConflicts: []string{"src/a.go@A"},
+		},
+	}
+	for _, step := range steps {
+		claimed, conflicts, err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if fmt.Sprint(claimed) != fmt.Sprint(step.wantClaimed) {
+			t.Errorf("%s: claimed = %v, want %v", step.name, claimed, step.wantClaimed)
+		}
+		if got := paths(conflicts); fmt.Sprint(got) != fmt.Sprint(step.wantConflicts) {
+			t.Errorf("%s: conflicts = %v, want %v", step.name, got, step.wantConflicts)
+		}
+	}
+
+	// 检查时排除调用方自己的任务
+	if own, _ := mem.CheckFileLocks(ctx, []string{"src/a.go"}, "A"); len(own) != 0 {
+		t.Errorf("own locks should not be reported: %v", paths(own))
+	}
+	if other, _ := mem.CheckFileLocks(ctx, []string{"src/a.go"}, "C"); len(other) != 1 {
+		t.Errorf("locks of other tasks should be reported: %v", paths(other))
+	}
+
+	// 过期锁在列表中被清理
+	expire("src/a.go")
+	locks, err := mem.ListFileLocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(locks); len(got) != 2 || got[0] != "src/b.go@C" || got[1] != "src/c.go@C" {
+		t.Errorf("list = %v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ========== 项目术语表 ==========
+
+// GlossaryEntry 领域术语与代码位置的映射
+type GlossaryEntry struct {
+	Term       string   `json:"term"`
+	Definition string   `json:"definition,omitempty"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Symbols    []string `json:"symbols,omitempty"`
+	Paths      []string `json:"paths,omitempty"`
+	UpdatedAt  string   `json:"updated_at,omitempty"`
+}
+
+func joinGlossaryList(items []string) string {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, it := range items {
+		it = strings.TrimSpace(it)
+		if it == "" || seen[strings.ToLower(it)] {
+			continue
+		}
+		seen[strings.ToLower(it)] = true
+		cleaned = append(cleaned, it)
+	}
+	return strings.Join(cleaned, ",")
+}
+
+func splitGlossaryList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var items []string
+	for _, it := range strings.Split(s, ",") {
+		if it = strings.TrimSpace(it); it != "" {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// UpsertGlossaryTerm 新增或更新术语（术语名大小写不敏感）
+func (m *MemoryLayer) UpsertGlossaryTerm(ctx context.Context, e GlossaryEntry) error {
+	_, err := m.dbManager.Exec(`INSERT INTO glossary (term, definition, aliases, symbols, paths, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(term) DO UPDATE SET definition=excluded.definition, aliases=excluded.aliases,
+			symbols=excluded.symbols, paths=excluded.paths, updated_at=excluded.updated_at`,
+		strings.TrimSpace(e.Term), strings.TrimSpace(e.Definition), joinGlossaryList(e.Aliases),
+		joinGlossaryList(e.Symbols), joinGlossaryList(e.Paths), time.Now().Format(time.RFC3339))
+	return err
+}
+
+// DeleteGlossaryTerm 删除术语，返回是否存在
+func (m *MemoryLayer) DeleteGlossaryTerm(ctx context.Context, term string) (bool, error) {
+	res, err := m.dbManager.Exec("DELETE FROM glossary WHERE term = ?", strings.TrimSpace(term))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetGlossaryTerm 按术语名精确查找，不存在时返回 nil
+func (m *MemoryLayer) GetGlossaryTerm(ctx context.Context, term string) (*GlossaryEntry, error) {
+	row := m.dbManager.QueryRow(`SELECT term, COALESCE(definition, ''), COALESCE(aliases, ''), COALESCE(symbols, ''),
+		COALESCE(paths, ''), COALESCE(updated_at, '') FROM glossary WHERE term = ?`, strings.TrimSpace(term))
+	e, err := scanGlossaryEntry(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+func scanGlossaryEntry(scan func(dest ...interface{}) error) (*GlossaryEntry, error) {
+	var e GlossaryEntry
+	var aliases, symbols, paths string
+	if err := scan(&e.Term, &e.Definition, &aliases, &symbols, &paths, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	e.Aliases = splitGlossaryList(aliases)
+	e.Symbols = splitGlossaryList(symbols)
+	e.Paths = splitGlossaryList(paths)
+	return &e, nil
+}
+
+// ListGlossary 列出术语；query 非空时在术语/别名/定义/符号/路径中模糊匹配
+func (m *MemoryLayer) ListGlossary(ctx context.Context, query string) ([]GlossaryEntry, error) {
+	sqlQuery := `SELECT term, COALESCE(definition, ''), COALESCE(aliases, ''), COALESCE(symbols, ''),
+		COALESCE(paths, ''), COALESCE(updated_at, '') FROM glossary`
+	var params []interface{}
+	if q := strings.TrimSpace(query); q != "" {
+		like := "%" + q + "%"
+		sqlQuery += ` WHERE term LIKE ? OR aliases LIKE ? OR definition LIKE ? OR symbols LIKE ? OR paths LIKE ?`
+		params = append(params, like, like, like, like, like)
+	}
+	sqlQuery += " ORDER BY term COLLATE NOCASE"
+
+	rows, err := m.dbManager.Query(sqlQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []GlossaryEntry
+	for rows.Next() {
+		e, err := scanGlossaryEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// MatchGlossary 返回在文本中出现的术语（术语名或别名；ASCII 词按词边界匹配，中文按子串匹配）
+func (m *MemoryLayer) MatchGlossary(ctx context.Context, text string) ([]GlossaryEntry, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	entries, err := m.ListGlossary(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	lower := strings.ToLower(text)
+	var matched []GlossaryEntry
+	for _, e := range entries {
+		for _, name := range append([]string{e.Term}, e.Aliases...) {
+			if containsTerm(lower, strings.ToLower(name)) {
+				matched = append(matched, e)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+func containsTerm(text, term string) bool {
+	if term == "" {
+		return false
+	}
+	isWord := func(r rune) bool {
+		return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	}
+	for start := 0; ; {
+		i := strings.Index(text[start:], term)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(term)
+		before, after := ' ', ' '
+		if i > 0 {
+			before = rune(text[i-1])
+		}
+		if end < len(text) && text[end] == 's' {
+			end++ // 复数形式 ledgers
+		}
+		if end < len(text) {
+			after = rune(text[end])
+		}
+		// 术语首尾是 ASCII 字母数字时才要求词边界，避免 "ledger" 命中 "ledgers_backup"
+		first, last := rune(term[0]), rune(term[len(term)-1])
+		if (!isWord(first) || !isWord(before)) && (!isWord(last) || !isWord(after)) {
+			return true
+		}
+		start = i + 1
+	}
+}
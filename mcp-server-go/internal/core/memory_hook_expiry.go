@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// ========== 钩子过期 ==========
+//
+// 设置了 expires_in_hours 的 open 钩子到期后转为 expired 状态（而非直接关闭），
+// 仍可通过 manager_list_hooks(status="expired") 查看，并由 manager_release_hook 正式闭合。
+
+// HookExpired 过期钩子的状态值
+const HookExpired = "expired"
+
+// HookExpiringSoonWindow 距到期不足该时长的 open 钩子视为"即将过期"
+const HookExpiringSoonWindow = 24 * time.Hour
+
+// ExpireHooks 把已到期的 open 钩子标记为 expired，返回本次转换的钩子
+func (m *MemoryLayer) ExpireHooks(ctx context.Context) ([]Hook, error) {
+	hooks, err := m.ListHooks(ctx, "open")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var expired []Hook
+	for _, h := range hooks {
+		if !h.ExpiresAt.Valid || h.ExpiresAt.Time.After(now) {
+			continue
+		}
+		if _, err := m.dbManager.Exec(
+			"UPDATE pending_hooks SET status = ? WHERE hook_id = ? AND status = 'open'",
+			HookExpired, h.HookID,
+		); err != nil {
+			return expired, err
+		}
+		h.Status = HookExpired
+		expired = append(expired, h)
+	}
+	return expired, nil
+}
+
+// ExpiringHooks 返回 window 内即将到期的 open 钩子（按到期时间升序）
+func (m *MemoryLayer) ExpiringHooks(ctx context.Context, window time.Duration) ([]Hook, error) {
+	hooks, err := m.ListHooks(ctx, "open")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var soon []Hook
+	for _, h := range hooks {
+		if h.ExpiresAt.Valid && h.ExpiresAt.Time.After(now) && h.ExpiresAt.Time.Sub(now) <= window {
+			soon = append(soon, h)
+		}
+	}
+	sort.Slice(soon, func(i, j int) bool { return soon[i].ExpiresAt.Time.Before(soon[j].ExpiresAt.Time) })
+	return soon, nil
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExpireHooks(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, _ := mem.CreateHook(ctx, "等待审批", "high", "", "", 1)
+	soon, _ := mem.CreateHook(ctx, "等待密钥", "medium", "", "", 2)
+	if _, err := mem.CreateHook(ctx, "长期待办", "low", "", "", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.dbManager.Exec("UPDATE pending_hooks SET expires_at = ? WHERE hook_id = ?",
+		time.Now().Add(-time.Hour), stale); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := mem.ExpireHooks(ctx)
+	if err != nil || len(expired) != 1 || expired[0].HookID != stale {
+		t.Fatalf("expired = %+v, err = %v", expired, err)
+	}
+	if list, _ := mem.ListHooks(ctx, HookExpired); len(list) != 1 {
+		t.Fatalf("expired list = %d, want 1", len(list))
+	}
+	if open, _ := mem.ListHooks(ctx, "open"); len(open) != 2 {
+		t.Fatalf("open list = %d, want 2", len(open))
+	}
+
+	expiring, err := mem.ExpiringHooks(ctx, HookExpiringSoonWindow)
+	if err != nil || len(expiring) != 1 || expiring[0].HookID != soon {
+		t.Fatalf("expiring = %+v, err = %v", expiring, err)
+	}
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ========== memo 修订、删除与归档 ==========
+//
+// memo 是 SSOT，不允许静默改写：每次修订/删除/归档都会在 memo_archive.jsonl 追加一条墓碑条目
+// （op + 修改后的字段 + 原因），既留审计痕迹，也保证从归档重放恢复时得到相同的结果。
+// 归档是软删除：记录保留在库中，但不再出现在检索、dev-log.md 与语义向量中，可随时取消归档。
+
+// memo 墓碑操作
+const (
+	MemoOpUpdate    = "update"
+	MemoOpDelete    = "delete"
+	MemoOpArchive   = "archive"
+	MemoOpUnarchive = "unarchive"
+	MemoOpMerge     = "merge"
+)
+
+// GetMemo 按 ID 读取 memo（含已归档），返回是否已归档；不存在时返回 sql.ErrNoRows
+func (m *MemoryLayer) GetMemo(ctx context.Context, id int64) (*Memo, bool, error) {
+	var memo Memo
+	var archived sql.NullInt64
+	err := m.dbManager.QueryRow(
+		"SELECT "+memoColumns+", archived FROM memos WHERE id = ?", id,
+	).Scan(&memo.ID, &memo.Category, &memo.Entity, &memo.Act, &memo.Path, &memo.Content, &memo.SessionID, &memo.Timestamp, &memo.GitCommit, &memo.GitDirty, &archived)
+	if err != nil {
+		return nil, false, err
+	}
+	return &memo, archived.Int64 != 0, nil
+}
+
+// UpdateMemo 修订 memo；patch 中的空字段保持原值。返回修订后的记录
+func (m *MemoryLayer) UpdateMemo(ctx context.Context, id int64, patch Memo, reason string) (*Memo, error) {
+	memo, _, err := m.GetMemo(ctx, id)
+	if err != nil {
+		return nil, memoLookupError(id, err)
+	}
+	overwrite := func(dst *string, val string) {
+		if strings.TrimSpace(val) != "" {
+			*dst = val
+		}
+	}
+	overwrite(&memo.Category, patch.Category)
+	overwrite(&memo.Entity, patch.Entity)
+	overwrite(&memo.Act, patch.Act)
+	overwrite(&memo.Path, patch.Path)
+	overwrite(&memo.Content, patch.Content)
+	if _, err := m.dbManager.Exec(
+		"UPDATE memos SET category = ?, entity = ?, act = ?, path = ?, content = ? WHERE id = ?",
+		memo.Category, memo.Entity, memo.Act, memo.Path, memo.Content, id,
+	); err != nil {
+		return nil, err
+	}
+	m.recordMemoTombstone(MemoOpUpdate, memo, reason)
+	return memo, nil
+}
+
+// DeleteMemo 永久删除 memo（归档中保留墓碑条目）
+func (m *MemoryLayer) DeleteMemo(ctx context.Context, id int64, reason string) (*Memo, error) {
+	memo, _, err := m.GetMemo(ctx, id)
+	if err != nil {
+		return nil, memoLookupError(id, err)
+	}
+	if _, err := m.dbManager.Exec("DELETE FROM memos WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+	m.recordMemoTombstone(MemoOpDelete, &Memo{ID: id}, reason)
+	return memo, nil
+}
+
+// SetMemoArchived 归档或取消归档 memo
+func (m *MemoryLayer) SetMemoArchived(ctx context.Context, id int64, archived bool, reason string) (*Memo, error) {
+	memo, _, err := m.GetMemo(ctx, id)
+	if err != nil {
+		return nil, memoLookupError(id, err)
+	}
+	query, op := "UPDATE memos SET archived = 0, merged_into = NULL WHERE id = ?", MemoOpUnarchive
+	if archived {
+		query, op = "UPDATE memos SET archived = 1 WHERE id = ?", MemoOpArchive
+	}
+	if _, err := m.dbManager.Exec(query, id); err != nil {
+		return nil, err
+	}
+	m.recordMemoTombstone(op, &Memo{ID: id}, reason)
+	return memo, nil
+}
+
+// recordMemoTombstone 同步追加墓碑条目（保证与新增条目的先后顺序），并刷新 dev-log.md
+func (m *MemoryLayer) recordMemoTombstone(op string, memo *Memo, reason string) {
+	entry := memoArchiveEntry{
+		Op:        op,
+		Reason:    reason,
+		ID:        memo.ID,
+		Timestamp: time.Now(),
+	}
+	if op == MemoOpUpdate {
+		entry.Category, entry.Entity, entry.Act, entry.Path, entry.Content = memo.Category, memo.Entity, memo.Act, memo.Path, memo.Content
+	}
+	m.appendMemoArchive([]memoArchiveEntry{entry})
+	m.requestDevLogSync()
+}
+
+// replayMemoTombstone 从归档恢复时应用墓碑条目；id 为重放后的新 ID，idMap 用于换算合并目标
+func (m *MemoryLayer) replayMemoTombstone(id int64, entry memoArchiveEntry, idMap map[int64]int64) {
+	switch entry.Op {
+	case MemoOpUpdate:
+		m.dbManager.Exec("UPDATE memos SET category = ?, entity = ?, act = ?, path = ?, content = ? WHERE id = ?",
+			entry.Category, entry.Entity, entry.Act, entry.Path, entry.Content, id)
+	case MemoOpDelete:
+		m.dbManager.Exec("DELETE FROM memos WHERE id = ?", id)
+	case MemoOpArchive:
+		m.dbManager.Exec("UPDATE memos SET archived = 1 WHERE id = ?", id)
+	case MemoOpUnarchive:
+		m.dbManager.Exec("UPDATE memos SET archived = 0, merged_into = NULL WHERE id = ?", id)
+	case MemoOpMerge:
+		if target, ok := idMap[entry.MergedInto]; ok {
+			m.dbManager.Exec("UPDATE memos SET archived = 1, merged_into = ? WHERE id = ?", target, id)
+			m.dbManager.Exec("UPDATE memos SET merged_into = ? WHERE merged_into = ?", target, id)
+		}
+	}
+}
+
+func memoLookupError(id int64, err error) error {
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("memo %d 不存在", id)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// waitDevLogIdle 等待后台 dev-log 写者结束，避免与 TempDir 清理竞争
+func waitDevLogIdle(t *testing.T, m *MemoryLayer) {
+	t.Cleanup(func() {
+		for {
+			m.devLogSyncMu.Lock()
+			running := m.devLogRunning
+			m.devLogSyncMu.Unlock()
+			if !running {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+}
+
+func TestMemoManageAndArchiveReplay(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, mem)
+	ids, err := mem.AddMemos(ctx, []Memo{
+		{Category: "修改", Entity: "Pool", Act: "修复", Content: "原因是超时配置过短"},
+		{Category: "调试", Entity: "Tmp", Act: "记录", Content: "临时调试输出"},
+		{Category: "决策", Entity: "Cache", Act: "选型", Content: "使用 LRU 缓存"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mem.UpdateMemo(ctx, ids[0], Memo{Content: "原因是连接池耗尽"}, "结论有误"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.SetMemoArchived(ctx, ids[1], true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.DeleteMemo(ctx, ids[2], "重复记录"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.DeleteMemo(ctx, ids[2], "again"); err == nil {
+		t.Fatal("deleting a missing memo should fail")
+	}
+
+	check := func(ml *MemoryLayer) {
+		t.Helper()
+		memos, err := ml.SearchMemos(ctx, "", "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(memos) != 1 || memos[0].Content != "原因是连接池耗尽" {
+			t.Fatalf("visible memos = %+v, want only the revised one", memos)
+		}
+	}
+	check(mem)
+
+	// 丢失数据库后从归档重放，墓碑条目同样生效
+	replayRoot := t.TempDir()
+	archive, err := os.ReadFile(MemoArchivePath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(MemoArchiveDir(replayRoot), 0755)
+	os.WriteFile(MemoArchivePath(replayRoot), archive, 0644)
+	replayed, err := NewMemoryLayer(replayRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, replayed)
+	check(replayed)
+}
+
+func TestMemoGitCommitRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDevLogIdle(t, mem)
+	ids, err := mem.AddMemos(ctx, []Memo{
+		{Category: "修改", Entity: "Router", Act: "重构", Content: "拆分路由注册", GitCommit: "0123456789abcdef", GitDirty: "api/router.go"},
+		{Category: "决策", Entity: "Cache", Act: "选型", Content: "非 git 目录下录入"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memos, err := mem.SearchMemos(ctx, "路由", "", 10)
+	if err != nil || len(memos) != 1 {
+		t.Fatalf("search = %v, %v", memos, err)
+	}
+	if memos[0].GitCommit != "0123456789abcdef" || memos[0].GitDirty != "api/router.go" {
+		t.Fatalf("git fields = %q / %q", memos[0].GitCommit, memos[0].GitDirty)
+	}
+	other, _, err := mem.GetMemo(ctx, ids[1])
+	if err != nil || other.GitCommit != "" {
+		t.Fatalf("memo without commit = %+v, %v", other, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -19,10 +20,27 @@ type TaskChainRecord struct {
 	PhasesJSON   string `json:"phases_json"`
 	CurrentPhase string `json:"current_phase"`
 	ReinitCount  int    `json:"reinit_count"`
+	WorkingDir   string `json:"working_dir"`
+	EnvJSON      string `json:"env_json"`
+	RiskBudget   string `json:"risk_budget_json"`
+	Budget       string `json:"budget_json,omitempty"` // 执行预算（步数/重试/token）
+	Owner        string `json:"owner,omitempty"`       // 持有该链的会话标识 (hostname:pid)
+	LeaseUntil   string `json:"lease_until,omitempty"` // 所有权租约到期时间 (RFC3339 UTC)
 	CreatedAt    string `json:"created_at"`
 	UpdatedAt    string `json:"updated_at"`
 }
 
+// TaskChainOwnedError 任务链由其他会话持有且租约未过期
+type TaskChainOwnedError struct {
+	TaskID     string
+	Owner      string
+	LeaseUntil string
+}
+
+func (e *TaskChainOwnedError) Error() string {
+	return fmt.Sprintf("任务链 %s 由会话 %s 持有（租约到期 %s）", e.TaskID, e.Owner, e.LeaseUntil)
+}
+
 // TaskChainEvent 任务链事件
 type TaskChainEvent struct {
 	ID        int64  `json:"id"`
@@ -31,13 +49,14 @@ type TaskChainEvent struct {
 	SubID     string `json:"sub_id"`
 	EventType string `json:"event_type"`
 	Payload   string `json:"payload"`
+	Holder    string `json:"holder"` // 写入事件的会话标识 (hostname:pid)
 	CreatedAt string `json:"created_at"`
 }
 
 // SaveTaskChain 保存或更新任务链
 func (m *MemoryLayer) SaveTaskChain(ctx context.Context, rec *TaskChainRecord) error {
-	query := `INSERT INTO task_chains (task_id, description, protocol, status, phases_json, current_phase, reinit_count, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	query := `INSERT INTO task_chains (task_id, description, protocol, status, phases_json, current_phase, reinit_count, working_dir, env_json, risk_budget_json, budget_json, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(task_id) DO UPDATE SET
 			description=excluded.description,
 			protocol=excluded.protocol,
@@ -45,6 +64,10 @@ func (m *MemoryLayer) SaveTaskChain(ctx context.Context, rec *TaskChainRecord) e
 			phases_json=excluded.phases_json,
 			current_phase=excluded.current_phase,
 			reinit_count=excluded.reinit_count,
+			working_dir=excluded.working_dir,
+			env_json=excluded.env_json,
+			risk_budget_json=excluded.risk_budget_json,
+			budget_json=excluded.budget_json,
 			updated_at=excluded.updated_at`
 
 	now := time.Now().Format(time.RFC3339)
@@ -55,19 +78,22 @@ func (m *MemoryLayer) SaveTaskChain(ctx context.Context, rec *TaskChainRecord) e
 
 	_, err := m.dbManager.Exec(query,
 		rec.TaskID, rec.Description, rec.Protocol, rec.Status,
-		rec.PhasesJSON, rec.CurrentPhase, rec.ReinitCount, createdAt, now)
+		rec.PhasesJSON, rec.CurrentPhase, rec.ReinitCount, rec.WorkingDir, rec.EnvJSON, rec.RiskBudget, rec.Budget, createdAt, now)
 	return err
 }
 
 // LoadTaskChain 加载任务链
 func (m *MemoryLayer) LoadTaskChain(ctx context.Context, taskID string) (*TaskChainRecord, error) {
-	query := `SELECT task_id, description, protocol, status, phases_json, current_phase, reinit_count, created_at, updated_at
+	query := `SELECT task_id, description, protocol, status, phases_json, current_phase, reinit_count,
+			COALESCE(working_dir, ''), COALESCE(env_json, ''), COALESCE(risk_budget_json, ''), COALESCE(budget_json, ''),
+			COALESCE(owner, ''), COALESCE(lease_until, ''), created_at, updated_at
 		FROM task_chains WHERE task_id = ?`
 
 	var rec TaskChainRecord
 	err := m.dbManager.QueryRow(query, taskID).Scan(
 		&rec.TaskID, &rec.Description, &rec.Protocol, &rec.Status,
-		&rec.PhasesJSON, &rec.CurrentPhase, &rec.ReinitCount, &rec.CreatedAt, &rec.UpdatedAt)
+		&rec.PhasesJSON, &rec.CurrentPhase, &rec.ReinitCount,
+		&rec.WorkingDir, &rec.EnvJSON, &rec.RiskBudget, &rec.Budget, &rec.Owner, &rec.LeaseUntil, &rec.CreatedAt, &rec.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -77,6 +103,41 @@ func (m *MemoryLayer) LoadTaskChain(ctx context.Context, taskID string) (*TaskCh
 	return &rec, nil
 }
 
+// ClaimTaskChain 声明或续租任务链所有权，租约期为 lease。
+// 链未被持有、租约已过期或本就由 holder 持有时成功；force=true 时无条件接管。
+// 返回声明前的持有者（可能为空或已过期）；被其他会话持有时返回 *TaskChainOwnedError。
+func (m *MemoryLayer) ClaimTaskChain(ctx context.Context, taskID, holder string, lease time.Duration, force bool) (string, error) {
+	rec, err := m.LoadTaskChain(ctx, taskID)
+	if err != nil {
+		return "", err
+	}
+	if rec == nil {
+		return "", fmt.Errorf("任务链 %s 不存在", taskID)
+	}
+	now := time.Now().UTC()
+	// 条件更新保证两个会话同时声明时只有一个成功
+	res, err := m.dbManager.Exec(`UPDATE task_chains SET owner = ?, lease_until = ?
+		WHERE task_id = ? AND (? OR owner IS NULL OR owner = '' OR owner = ? OR lease_until IS NULL OR lease_until < ?)`,
+		holder, now.Add(lease).Format(time.RFC3339), taskID, force, holder, now.Format(time.RFC3339))
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if cur, err := m.LoadTaskChain(ctx, taskID); err == nil && cur != nil {
+			rec = cur
+		}
+		return rec.Owner, &TaskChainOwnedError{TaskID: taskID, Owner: rec.Owner, LeaseUntil: rec.LeaseUntil}
+	}
+	return rec.Owner, nil
+}
+
+// ReleaseTaskChain 释放 holder 持有的任务链所有权
+func (m *MemoryLayer) ReleaseTaskChain(ctx context.Context, taskID, holder string) error {
+	_, err := m.dbManager.Exec("UPDATE task_chains SET owner = NULL, lease_until = NULL WHERE task_id = ? AND owner = ?",
+		taskID, strings.TrimSpace(holder))
+	return err
+}
+
 // ListTaskChains 列出任务链（按更新时间倒序）
 func (m *MemoryLayer) ListTaskChains(ctx context.Context, status string, limit int) ([]TaskChainRecord, error) {
 	query := `SELECT task_id, description, protocol, status, phases_json, current_phase, created_at, updated_at
@@ -108,6 +169,29 @@ func (m *MemoryLayer) ListTaskChains(ctx context.Context, status string, limit i
 	return results, nil
 }
 
+// FinishedTaskChainsIn 时间范围内完成（最后更新落在范围内）的任务链，按完成时间升序
+func (m *MemoryLayer) FinishedTaskChainsIn(ctx context.Context, within TimeRange) ([]TaskChainRecord, error) {
+	rows, err := m.dbManager.Query(`SELECT task_id, description, protocol, status, phases_json, current_phase, created_at, updated_at
+		FROM task_chains WHERE status = 'finished' ORDER BY updated_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []TaskChainRecord
+	for rows.Next() {
+		var rec TaskChainRecord
+		if err := rows.Scan(&rec.TaskID, &rec.Description, &rec.Protocol, &rec.Status,
+			&rec.PhasesJSON, &rec.CurrentPhase, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if t, ok := parseTimelineTime(rec.UpdatedAt); ok && within.Contains(t) {
+			results = append(results, rec)
+		}
+	}
+	return results, rows.Err()
+}
+
 // DeleteTaskChain 删除任务链及其事件
 func (m *MemoryLayer) DeleteTaskChain(ctx context.Context, taskID string) error {
 	if _, err := m.dbManager.Exec("DELETE FROM task_chain_events WHERE task_id = ?", taskID); err != nil {
@@ -119,11 +203,11 @@ func (m *MemoryLayer) DeleteTaskChain(ctx context.Context, taskID string) error
 
 // AppendTaskChainEvent 追加事件
 func (m *MemoryLayer) AppendTaskChainEvent(ctx context.Context, evt *TaskChainEvent) (int64, error) {
-	query := `INSERT INTO task_chain_events (task_id, phase_id, sub_id, event_type, payload, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO task_chain_events (task_id, phase_id, sub_id, event_type, payload, holder, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
 
 	now := time.Now().Format(time.RFC3339)
-	res, err := m.dbManager.Exec(query, evt.TaskID, evt.PhaseID, evt.SubID, evt.EventType, evt.Payload, now)
+	res, err := m.dbManager.Exec(query, evt.TaskID, evt.PhaseID, evt.SubID, evt.EventType, evt.Payload, evt.Holder, now)
 	if err != nil {
 		return 0, err
 	}
@@ -132,7 +216,7 @@ func (m *MemoryLayer) AppendTaskChainEvent(ctx context.Context, evt *TaskChainEv
 
 // QueryTaskChainEvents 查询任务链事件
 func (m *MemoryLayer) QueryTaskChainEvents(ctx context.Context, taskID string, limit int) ([]TaskChainEvent, error) {
-	query := `SELECT id, task_id, phase_id, sub_id, event_type, payload, created_at
+	query := `SELECT id, task_id, phase_id, sub_id, event_type, payload, COALESCE(holder, ''), created_at
 		FROM task_chain_events WHERE task_id = ? ORDER BY id ASC LIMIT ?`
 
 	rows, err := m.dbManager.Query(query, taskID, limit)
@@ -145,7 +229,7 @@ func (m *MemoryLayer) QueryTaskChainEvents(ctx context.Context, taskID string, l
 	for rows.Next() {
 		var evt TaskChainEvent
 		if err := rows.Scan(&evt.ID, &evt.TaskID, &evt.PhaseID, &evt.SubID,
-			&evt.EventType, &evt.Payload, &evt.CreatedAt); err != nil {
+			&evt.EventType, &evt.Payload, &evt.Holder, &evt.CreatedAt); err != nil {
 			continue
 		}
 		results = append(results, evt)
@@ -153,6 +237,49 @@ func (m *MemoryLayer) QueryTaskChainEvents(ctx context.Context, taskID string, l
 	return results, nil
 }
 
+// RecentTaskChainEvents 查询任务链最近 n 条事件（按时间正序返回）
+func (m *MemoryLayer) RecentTaskChainEvents(ctx context.Context, taskID string, n int) ([]TaskChainEvent, error) {
+	query := `SELECT id, task_id, COALESCE(phase_id, ''), COALESCE(sub_id, ''), event_type, COALESCE(payload, ''), COALESCE(holder, ''), created_at
+		FROM task_chain_events WHERE task_id = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := m.dbManager.Query(query, taskID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []TaskChainEvent
+	for rows.Next() {
+		var evt TaskChainEvent
+		if err := rows.Scan(&evt.ID, &evt.TaskID, &evt.PhaseID, &evt.SubID,
+			&evt.EventType, &evt.Payload, &evt.Holder, &evt.CreatedAt); err != nil {
+			continue
+		}
+		results = append(results, evt)
+	}
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+	return results, rows.Err()
+}
+
+// LatestTaskChainEvent 查询任务链最近一条事件，无事件时返回 nil
+func (m *MemoryLayer) LatestTaskChainEvent(ctx context.Context, taskID string) (*TaskChainEvent, error) {
+	query := `SELECT id, task_id, COALESCE(phase_id, ''), COALESCE(sub_id, ''), event_type, COALESCE(payload, ''), COALESCE(holder, ''), created_at
+		FROM task_chain_events WHERE task_id = ? ORDER BY id DESC LIMIT 1`
+
+	var evt TaskChainEvent
+	err := m.dbManager.QueryRow(query, taskID).Scan(&evt.ID, &evt.TaskID, &evt.PhaseID, &evt.SubID,
+		&evt.EventType, &evt.Payload, &evt.Holder, &evt.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &evt, nil
+}
+
 // MarshalPhasesJSON 辅助：将 phases 序列化为 JSON 字符串
 func MarshalPhasesJSON(v interface{}) (string, error) {
 	data, err := json.Marshal(v)
@@ -169,3 +296,29 @@ func UnmarshalPhasesJSON(s string, v interface{}) error {
 	}
 	return json.Unmarshal([]byte(s), v)
 }
+
+// ImportTaskChain 写入外部导出的任务链及其完整事件（保留原事件时间），task_id 已存在时报错
+func (m *MemoryLayer) ImportTaskChain(ctx context.Context, rec *TaskChainRecord, events []TaskChainEvent) error {
+	existing, err := m.LoadTaskChain(ctx, rec.TaskID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("任务链 %s 已存在", rec.TaskID)
+	}
+	if err := m.SaveTaskChain(ctx, rec); err != nil {
+		return err
+	}
+	for _, evt := range events {
+		createdAt := evt.CreatedAt
+		if createdAt == "" {
+			createdAt = time.Now().Format(time.RFC3339)
+		}
+		if _, err := m.dbManager.Exec(`INSERT INTO task_chain_events (task_id, phase_id, sub_id, event_type, payload, holder, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, rec.TaskID, evt.PhaseID, evt.SubID, evt.EventType, evt.Payload, evt.Holder, createdAt); err != nil {
+			_ = m.DeleteTaskChain(ctx, rec.TaskID)
+			return fmt.Errorf("写入事件失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClaimTaskChainLease(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SaveTaskChain(ctx, &TaskChainRecord{TaskID: "T1", Status: "running"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mem.ClaimTaskChain(ctx, "T1", "host:1", time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	// 持有者续租不受影响，其他会话被拒绝
+	if _, err := mem.ClaimTaskChain(ctx, "T1", "host:1", time.Hour, false); err != nil {
+		t.Errorf("owner heartbeat failed: %v", err)
+	}
+	var owned *TaskChainOwnedError
+	if _, err := mem.ClaimTaskChain(ctx, "T1", "host:2", time.Hour, false); !errors.As(err, &owned) || owned.Owner != "host:1" {
+		t.Fatalf("expected TaskChainOwnedError, got %v", err)
+	}
+
+	prev, err := mem.ClaimTaskChain(ctx, "T1", "host:2", time.Hour, true)
+	if err != nil || prev != "host:1" {
+		t.Fatalf("forced takeover = %q, %v", prev, err)
+	}
+	// SaveTaskChain 不应覆盖所有权
+	if err := mem.SaveTaskChain(ctx, &TaskChainRecord{TaskID: "T1", Status: "running"}); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := mem.LoadTaskChain(ctx, "T1")
+	if rec.Owner != "host:2" || rec.LeaseUntil == "" {
+		t.Errorf("owner = %q lease = %q, want host:2", rec.Owner, rec.LeaseUntil)
+	}
+
+	// 租约过期后可直接声明
+	if _, err := mem.ClaimTaskChain(ctx, "T1", "host:2", -time.Minute, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.ClaimTaskChain(ctx, "T1", "host:3", time.Hour, false); err != nil {
+		t.Errorf("claim after lease expiry failed: %v", err)
+	}
+	if err := mem.ReleaseTaskChain(ctx, "T1", "host:3"); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := mem.LoadTaskChain(ctx, "T1"); rec.Owner != "" {
+		t.Errorf("owner after release = %q", rec.Owner)
+	}
+}
@@ -2,8 +2,11 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -23,6 +26,7 @@ func TestMemoryLayer_AddMemos(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create MemoryLayer: %v", err)
 	}
+	waitDevLogIdle(t, ml)
 
 	ctx := context.Background()
 	memos := []Memo{
@@ -45,7 +49,7 @@ func TestMemoryLayer_AddMemos(t *testing.T) {
 	}
 
 	// 验证日志同步
-	devLogPath := filepath.Join(tempDir, "dev-log.md")
+	devLogPath := DevLogPath(tempDir)
 	created := false
 	for i := 0; i < 20; i++ {
 		if _, err := os.Stat(devLogPath); err == nil {
@@ -72,3 +76,56 @@ func TestMemoryLayer_AddMemos(t *testing.T) {
 		t.Errorf("Expected Entity 'Unit Test', got %s", results[0].Entity)
 	}
 }
+
+func TestMemoryLayer_ConcurrentDevLogSync(t *testing.T) {
+	projectTempRoot := filepath.Join(".", ".tmp-tests")
+	if err := os.MkdirAll(projectTempRoot, 0755); err != nil {
+		t.Fatalf("Failed to create test root dir: %v", err)
+	}
+	tempDir, err := os.MkdirTemp(projectTempRoot, "mcp-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ml, err := NewMemoryLayer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create MemoryLayer: %v", err)
+	}
+	waitDevLogIdle(t, ml)
+
+	ctx := context.Background()
+	const writers = 20
+	for i := 0; i < writers; i++ {
+		if _, err := ml.AddMemos(ctx, []Memo{{Category: "测试", Entity: "Concurrent", Act: "Write", Content: fmt.Sprintf("memo-%02d", i)}}); err != nil {
+			t.Fatalf("AddMemos %d failed: %v", i, err)
+		}
+	}
+
+	// 并发触发同步：后台合并写者与直接同步调用交错进行
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ml.requestDevLogSync()
+			ml.SyncDevLog()
+		}()
+	}
+	wg.Wait()
+	ml.SyncDevLog()
+
+	raw, err := os.ReadFile(DevLogPath(tempDir))
+	if err != nil {
+		t.Fatalf("dev-log.md not readable: %v", err)
+	}
+	content := string(raw)
+	if n := strings.Count(content, "# Dev Log:"); n != 1 {
+		t.Errorf("Expected exactly one header, got %d", n)
+	}
+	for i := 0; i < writers; i++ {
+		if !strings.Contains(content, fmt.Sprintf("memo-%02d", i)) {
+			t.Errorf("dev-log.md missing memo-%02d", i)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ========== 项目时间线数据 ==========
+
+// 时间线条目来源（memo 与人工标注之外的活动）
+const (
+	TimelineSourceTaskChain = "task_chain"
+	TimelineSourceHook      = "hook"
+	TimelineSourceFact      = "fact"
+)
+
+// TimelineEvent 时间线上的一条项目活动
+type TimelineEvent struct {
+	Source  string    `json:"source"`
+	Kind    string    `json:"kind"` // 任务链为 event_type；钩子为 created/released；事实为事实类型
+	Title   string    `json:"title"`
+	Content string    `json:"content,omitempty"`
+	Ref     string    `json:"ref"` // task_id / hook_id / 事实 ID
+	At      time.Time `json:"at"`
+}
+
+// timelinePayloadLimit 任务链事件 payload 在时间线中保留的字数
+const timelinePayloadLimit = 300
+
+// TimelineMemos 时间线用的全部未归档 memo，按录入顺序排列
+func (m *MemoryLayer) TimelineMemos(ctx context.Context) ([]Memo, error) {
+	rows, err := m.dbManager.Query("SELECT " + memoColumns + " FROM memos WHERE archived = 0 ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memos []Memo
+	for rows.Next() {
+		var memo Memo
+		if err := rows.Scan(&memo.ID, &memo.Category, &memo.Entity, &memo.Act, &memo.Path, &memo.Content, &memo.SessionID, &memo.Timestamp, &memo.GitCommit, &memo.GitDirty); err != nil {
+			return nil, err
+		}
+		memos = append(memos, memo)
+	}
+	return memos, rows.Err()
+}
+
+// parseTimelineTime 兼容驱动返回的 RFC3339 与 SQLite CURRENT_TIMESTAMP (UTC) 两种格式
+func parseTimelineTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// TimelineActivity 任务链事件、钩子创建/释放与事实录入，按时间升序
+func (m *MemoryLayer) TimelineActivity(ctx context.Context) ([]TimelineEvent, error) {
+	var events []TimelineEvent
+
+	rows, err := m.dbManager.Query(`SELECT e.task_id, COALESCE(e.phase_id, ''), COALESCE(e.sub_id, ''), e.event_type,
+		COALESCE(e.payload, ''), e.created_at, COALESCE(c.description, '')
+		FROM task_chain_events e LEFT JOIN task_chains c ON c.task_id = e.task_id ORDER BY e.id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var taskID, phase, sub, eventType, payload, at, desc string
+		if err := rows.Scan(&taskID, &phase, &sub, &eventType, &payload, &at, &desc); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t, ok := parseTimelineTime(at)
+		if !ok {
+			continue
+		}
+		title := taskID
+		if desc != "" {
+			title = fmt.Sprintf("%s · %s", taskID, truncateRunes(desc, 40))
+		}
+		kind := eventType
+		if phase != "" {
+			kind += " @" + phase
+			if sub != "" {
+				kind += "/" + sub
+			}
+		}
+		events = append(events, TimelineEvent{Source: TimelineSourceTaskChain, Kind: kind, Title: title,
+			Content: truncateRunes(payload, timelinePayloadLimit), Ref: taskID, At: t})
+	}
+	rows.Close()
+
+	rows, err = m.dbManager.Query(`SELECT hook_id, COALESCE(description, ''), COALESCE(priority, ''), created_at,
+		released_at, COALESCE(result_summary, '') FROM pending_hooks`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, desc, priority, created, result string
+		var released sql.NullString
+		if err := rows.Scan(&id, &desc, &priority, &created, &released, &result); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if t, ok := parseTimelineTime(created); ok {
+			events = append(events, TimelineEvent{Source: TimelineSourceHook, Kind: "created", Title: fmt.Sprintf("%s [%s]", id, priority),
+				Content: desc, Ref: id, At: t})
+		}
+		if t, ok := parseTimelineTime(released.String); ok {
+			events = append(events, TimelineEvent{Source: TimelineSourceHook, Kind: "released", Title: id,
+				Content: fallbackText(result, desc), Ref: id, At: t})
+		}
+	}
+	rows.Close()
+
+	rows, err = m.dbManager.Query("SELECT id, COALESCE(type, ''), COALESCE(summarize, ''), created_at FROM known_facts ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var typ, summary, created string
+		if err := rows.Scan(&id, &typ, &summary, &created); err != nil {
+			return nil, err
+		}
+		if t, ok := parseTimelineTime(created); ok {
+			events = append(events, TimelineEvent{Source: TimelineSourceFact, Kind: typ, Title: fmt.Sprintf("F%d · %s", id, typ),
+				Content: summary, Ref: fmt.Sprint(id), At: t})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events, nil
+}
+
+// TimelineSignature 时间线数据的变更指纹：任一来源新增/归档/释放都会改变结果，供实时时间线轮询
+func (m *MemoryLayer) TimelineSignature(ctx context.Context) (string, error) {
+	var memos, memoMax, notes, noteMax, events, hooks, released, facts int64
+	err := m.dbManager.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM memos WHERE archived = 0), (SELECT COALESCE(MAX(id), 0) FROM memos),
+		(SELECT COUNT(*) FROM timeline_annotations), (SELECT COALESCE(MAX(id), 0) FROM timeline_annotations),
+		(SELECT COALESCE(MAX(id), 0) FROM task_chain_events),
+		(SELECT COUNT(*) FROM pending_hooks), (SELECT COUNT(*) FROM pending_hooks WHERE released_at IS NOT NULL),
+		(SELECT COUNT(*) FROM known_facts)`).Scan(&memos, &memoMax, &notes, &noteMax, &events, &hooks, &released, &facts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d/%d.%d/%d/%d.%d/%d", memos, memoMax, notes, noteMax, events, hooks, released, facts), nil
+}
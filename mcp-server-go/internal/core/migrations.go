@@ -0,0 +1,174 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ========== 记忆库 Schema 版本迁移 ==========
+//
+// schema_version 表记录已执行的迁移。启动（以及整库恢复）时按版本号顺序执行尚未应用的迁移，
+// 每个迁移在独立事务中完成并登记版本，失败则回滚、后续迁移不再执行。
+// 新增列一律追加新的迁移，不要修改已发布迁移的内容；新建库同样从 v1 依次执行，
+// addColumns 会跳过已存在的列，因此早期手工补过列的旧库也能安全升级。
+
+// migration 一次 schema 升级
+type migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// memoryMigrations 按版本号升序排列，版本号连续且只增不改
+var memoryMigrations = []migration{
+	{1, "pending_hooks 结果/关联任务/过期/标签列", func(tx *sql.Tx) error {
+		return addColumns(tx, "pending_hooks", map[string]string{
+			"result_summary":  "TEXT",
+			"related_task_id": "TEXT",
+			"expires_at":      "DATETIME",
+			"status":          "TEXT DEFAULT 'open'",
+			"tag":             "TEXT",
+			"summary":         "TEXT",
+		})
+	}},
+	{2, "task_chains 重入/工作目录/租约/预算列", func(tx *sql.Tx) error {
+		if err := addColumns(tx, "task_chains", map[string]string{
+			"reinit_count":     "INTEGER DEFAULT 0",
+			"working_dir":      "TEXT",
+			"env_json":         "TEXT",
+			"risk_budget_json": "TEXT",
+			"owner":            "TEXT",
+			"lease_until":      "TEXT",
+			"budget_json":      "TEXT",
+		}); err != nil {
+			return err
+		}
+		return addColumns(tx, "task_chain_events", map[string]string{"holder": "TEXT"})
+	}},
+	{3, "memos 归档/合并/git 列", func(tx *sql.Tx) error {
+		return addColumns(tx, "memos", map[string]string{
+			"archived":    "INTEGER DEFAULT 0",
+			"merged_into": "INTEGER",
+			"git_commit":  "TEXT",
+			"git_dirty":   "TEXT",
+		})
+	}},
+	{4, "known_facts 作用域/优先级/过期/替代列", func(tx *sql.Tx) error {
+		return addColumns(tx, "known_facts", map[string]string{
+			"scope":         "TEXT",
+			"priority":      "TEXT DEFAULT 'medium'",
+			"expires_at":    "TEXT",
+			"superseded_by": "INTEGER",
+		})
+	}},
+	{5, "pending_hooks 释放时间列", func(tx *sql.Tx) error {
+		return addColumns(tx, "pending_hooks", map[string]string{"released_at": "DATETIME"})
+	}},
+}
+
+// LatestSchemaVersion 当前程序支持的记忆库 schema 版本
+func LatestSchemaVersion() int {
+	return memoryMigrations[len(memoryMigrations)-1].Version
+}
+
+// SchemaVersion 数据库已应用的 schema 版本（未迁移过为 0）
+func (m *DatabaseManager) SchemaVersion() (int, error) {
+	var v sql.NullInt64
+	err := m.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&v)
+	if err != nil && strings.Contains(err.Error(), "no such table") {
+		return 0, nil
+	}
+	return int(v.Int64), err
+}
+
+// migrate 执行尚未应用的迁移
+func (m *DatabaseManager) migrate() error {
+	if _, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return err
+	}
+	current, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion() {
+		fmt.Fprintf(os.Stderr, "[DB][WARN] 数据库 schema 版本 %d 高于程序支持的 %d，可能由更新版本的 MPM 创建\n", current, LatestSchemaVersion())
+		return nil
+	}
+
+	for _, mig := range memoryMigrations {
+		if mig.Version <= current {
+			continue
+		}
+		if err := m.applyMigration(mig); err != nil {
+			return fmt.Errorf("schema 迁移 v%d (%s) 失败: %w", mig.Version, mig.Name, err)
+		}
+		if current > 0 {
+			fmt.Fprintf(os.Stderr, "[DB] schema 已升级到 v%d: %s\n", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (m *DatabaseManager) applyMigration(mig migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := mig.Up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+		mig.Version, mig.Name, time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addColumns 为表补齐缺失的列（已存在的跳过）
+func addColumns(tx *sql.Tx, table string, columns map[string]string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[strings.ToLower(name)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		if !existing[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, columns[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
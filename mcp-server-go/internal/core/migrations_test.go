@@ -0,0 +1,61 @@
+package core
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestMigrate_UpgradesLegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "mcp_memory.db")
+
+	// 早期版本的库：缺少后来追加的列，也没有 schema_version 表
+	legacy, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"CREATE TABLE pending_hooks (hook_id TEXT PRIMARY KEY, description TEXT, priority TEXT, context TEXT, created_at DATETIME)",
+		"CREATE TABLE known_facts (id INTEGER PRIMARY KEY AUTOINCREMENT, type TEXT, summarize TEXT, created_at DATETIME)",
+		"CREATE TABLE memos (id INTEGER PRIMARY KEY AUTOINCREMENT, category TEXT, entity TEXT, act TEXT, path TEXT, content TEXT, session_id TEXT, timestamp DATETIME, archived INTEGER DEFAULT 0)",
+		"INSERT INTO known_facts (type, summarize) VALUES ('铁律', '旧事实')",
+	} {
+		if _, err := legacy.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy.Close()
+
+	mgr, err := NewDatabaseManager(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := mgr.SchemaVersion(); err != nil || v != LatestSchemaVersion() {
+		t.Fatalf("schema version = %d, %v; want %d", v, err, LatestSchemaVersion())
+	}
+	var scope sql.NullString
+	var priority string
+	if err := mgr.QueryRow("SELECT scope, priority FROM known_facts WHERE summarize = '旧事实'").Scan(&scope, &priority); err != nil {
+		t.Fatalf("known_facts not migrated: %v", err)
+	}
+	if priority != "medium" {
+		t.Errorf("priority default = %q", priority)
+	}
+	if _, err := mgr.Exec("UPDATE pending_hooks SET tag = 'x', expires_at = NULL, status = 'open'"); err != nil {
+		t.Errorf("pending_hooks not migrated: %v", err)
+	}
+	mgr.Close()
+
+	// 再次打开不会重复执行迁移
+	again, err := NewDatabaseManager(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	var applied int
+	if err := again.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&applied); err != nil || applied != len(memoryMigrations) {
+		t.Errorf("schema_version rows = %d, %v", applied, err)
+	}
+}
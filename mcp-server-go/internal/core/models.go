@@ -15,6 +15,8 @@ type Memo struct {
 	Content   string         `db:"content"`
 	SessionID sql.NullString `db:"session_id"`
 	Timestamp time.Time      `db:"timestamp"`
+	GitCommit string         `db:"git_commit"` // 录入时的 git HEAD（非 git 仓库为空）
+	GitDirty  string         `db:"git_dirty"`  // 录入时未提交改动的文件（逗号分隔，可选）
 }
 
 // Task 任务上下文
@@ -53,10 +55,13 @@ type TaskStep struct {
 
 // KnownFact 原子化事实
 type KnownFact struct {
-	ID        int64     `db:"id"`
-	Type      string    `db:"type"`
-	Summarize string    `db:"summarize"`
-	CreatedAt time.Time `db:"created_at"`
+	ID        int64        `db:"id"`
+	Type      string       `db:"type"`
+	Summarize string       `db:"summarize"`
+	CreatedAt time.Time    `db:"created_at"`
+	Scope     string       `db:"scope"`      // 作用域 path:/module:/symbol:，空表示全局
+	Priority  string       `db:"priority"`   // high / medium / low
+	ExpiresAt sql.NullTime `db:"expires_at"` // 到期后不再被检索
 }
 
 // ConstraintRule 约束规则
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ========== 项目级配置 (.mcp-config/settings.json) ==========
+
+// ComplexitySettings 复杂度阈值配置
+type ComplexitySettings struct {
+	// AlertThreshold 超过该分数的符号会在简报/任务链中触发告警
+	AlertThreshold float64 `json:"alert_threshold"`
+}
+
+// OutputSettings 工具输出配置
+type OutputSettings struct {
+	// Quiet 精简输出：去除横幅、emoji 与提示语，仅保留数据行
+	Quiet bool `json:"quiet"`
+	// Render 渲染目标：markdown（默认）或 plain（去除代码围栏/表格/emoji，适配不支持 Markdown 的终端客户端）
+	Render string `json:"render,omitempty"`
+}
+
+// TaskChainSettings 任务链配置
+type TaskChainSettings struct {
+	// LivenessMinutes 其他会话在该时间窗口内写过事件，即视为仍在驱动该任务链
+	LivenessMinutes int `json:"liveness_minutes"`
+	// LeaseMinutes 任务链所有权租约时长，start/complete/heartbeat 会续租，过期后其他会话可直接接管
+	LeaseMinutes int `json:"lease_minutes"`
+	// VerifySummary complete 时默认核对 summary 声称的文件修改与 git 实际变更
+	VerifySummary bool `json:"verify_summary,omitempty"`
+	// MaxSummaryChars complete/complete_sub 的 summary 字数上限
+	MaxSummaryChars int `json:"max_summary_chars"`
+	// SummaryOverflow 超限处理：truncate 截断并将全文另存为附件（默认），reject 拒绝并要求精简
+	SummaryOverflow string `json:"summary_overflow,omitempty"`
+}
+
+// IndexSettings 索引结果过滤配置
+type IndexSettings struct {
+	// VendoredPaths 额外的第三方代码目录（在内置 vendor/node_modules/third_party/site-packages/.venv 之外）
+	VendoredPaths []string `json:"vendored_paths,omitempty"`
+	// GeneratedPaths 额外的生成代码文件名模式 (glob，如 "*_mock.go")
+	GeneratedPaths []string `json:"generated_paths,omitempty"`
+	// IncludeVendored 为 true 时地图/复杂度/影响分析不再排除 vendored/generated 文件
+	IncludeVendored bool `json:"include_vendored"`
+	// Watch 监听项目文件变更并增量刷新索引（默认关闭：递归监听会占用 inotify 配额，需显式开启）
+	Watch bool `json:"watch"`
+	// WatchDebounceMs 变更合并的静默期（毫秒），期间的变更合并为一批索引
+	WatchDebounceMs int `json:"watch_debounce_ms"`
+	// CoverageFiles 覆盖率文件 (相对项目根目录，go coverprofile / lcov / coverage.py json、xml)，留空则自动查找
+	CoverageFiles []string `json:"coverage_files,omitempty"`
+	// ShardBy 超大仓库的索引分片方式：dir（按顶层目录）/ language（按语言）；留空为单一 symbols.db
+	ShardBy string `json:"shard_by,omitempty"`
+}
+
+// ScheduledJob 定时任务定义
+type ScheduledJob struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`  // hook_expiry / digest / index_freshness / retention_gc / fact_suggest / db_backup
+	Every string `json:"every"` // 执行间隔 (Go duration，如 "30m"、"24h")
+}
+
+// SchedulerSettings 内置调度器配置
+type SchedulerSettings struct {
+	Enabled bool           `json:"enabled"`
+	Jobs    []ScheduledJob `json:"jobs,omitempty"` // 为空时使用默认任务集
+	// RetentionDays retention_gc 清理已完成任务链的保留天数
+	RetentionDays int `json:"retention_days"`
+}
+
+// WebhookSettings 任务链生命周期事件的 webhook 订阅
+type WebhookSettings struct {
+	URL string `json:"url"`
+	// Secret 非空时以 HMAC-SHA256 签名请求体，放在 X-MPM-Signature 头
+	Secret string `json:"secret,omitempty"`
+	// Events 订阅的事件 (chain_init / chain_finish / chain_fail / gate_fail)，为空表示全部
+	Events []string `json:"events,omitempty"`
+}
+
+// StorageSettings 数据存放配置
+type StorageSettings struct {
+	// DataDir 数据目录，见 DataDir 的解析规则；为空时使用 .mcp-data
+	DataDir string `json:"data_dir,omitempty"`
+	// BackupKeep 每类数据库备份（定时/手动/恢复前）保留的份数，超出后删除最旧的
+	BackupKeep int `json:"backup_keep"`
+}
+
+// IntentSettings manager_analyze 意图识别配置
+type IntentSettings struct {
+	// Classifier heuristic（内置关键词，默认）/ command（本地命令）/ http（POST JSON 接口）；外部识别失败时回退到 heuristic
+	Classifier string `json:"classifier,omitempty"`
+	// Command command 的命令行：stdin 读入请求 JSON，stdout 输出 {"intent": "..."} 或单个意图词
+	Command string `json:"command,omitempty"`
+	// Endpoint http 的接口地址
+	Endpoint string `json:"endpoint,omitempty"`
+	// Keywords 追加的领域关键词（意图 → 关键词），先于内置关键词匹配，如 {"DEBUG": ["工单", "incident"]}
+	Keywords map[string][]string `json:"keywords,omitempty"`
+	// TimeoutMs 外部识别超时（毫秒），默认 3000
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+// AnalyzeSettings manager_analyze 配置
+type AnalyzeSettings struct {
+	// MaxAnchors 单次最多解析的符号数，超出部分推迟到 resolve_more
+	MaxAnchors int `json:"max_anchors"`
+	// GuardrailAudit 记录任务开始后的文件修改，与 guardrail_check 声明对比并在 manager_analyze 中标出违规
+	GuardrailAudit bool `json:"guardrail_audit"`
+	// Intent 意图识别方式
+	Intent IntentSettings `json:"intent"`
+}
+
+// EmbeddingSettings 语义检索的向量化配置，Provider 为空时不启用
+type EmbeddingSettings struct {
+	// Provider openai（OpenAI 兼容 /embeddings 接口，含本地部署的 ONNX/Ollama 服务）/ command（本地命令）/ hash（内置离线哈希向量）
+	Provider string `json:"provider,omitempty"`
+	// Endpoint openai 的接口基地址，默认 https://api.openai.com/v1
+	Endpoint string `json:"endpoint,omitempty"`
+	// Model 模型名；向量按模型区分存储，更换模型后自动重建
+	Model string `json:"model,omitempty"`
+	// APIKeyEnv 存放 API Key 的环境变量名，默认 OPENAI_API_KEY（密钥不写入配置文件）
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+	// Command command 的命令行：stdin 读入 JSON 字符串数组，stdout 输出等长的向量数组
+	Command string `json:"command,omitempty"`
+	// BatchSize 每次请求向量化的条数
+	BatchSize int `json:"batch_size"`
+}
+
+// SessionSettings 会话级行为配置
+type SessionSettings struct {
+	// IdleCheckpoint 会话空闲时自动存档进行中的任务链与分析状态，并写一条"进度停在哪"的 memo
+	IdleCheckpoint bool `json:"idle_checkpoint"`
+	// IdleMinutes 空闲多少分钟后存档
+	IdleMinutes int `json:"idle_minutes"`
+}
+
+// CommandSettings 代理发起的命令执行（verify 命令、检查脚本等）的安全策略
+type CommandSettings struct {
+	// Allow 允许执行的命令前缀（按参数逐词匹配，如 "go test"、"npm run"）；为空时使用内置白名单
+	Allow []string `json:"allow,omitempty"`
+	// Deny 禁止的命令前缀，优先于 Allow
+	Deny []string `json:"deny,omitempty"`
+	// AllowShell 允许包含管道/重定向等 shell 语法的命令经 shell 执行（默认直接执行程序，不经 shell）
+	AllowShell bool `json:"allow_shell,omitempty"`
+	// TimeoutSeconds 单条命令超时
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// ProjectSettings 项目级配置，缺失字段使用默认值
+type ProjectSettings struct {
+	Complexity ComplexitySettings `json:"complexity"`
+	Output     OutputSettings     `json:"output"`
+	TaskChain  TaskChainSettings  `json:"task_chain"`
+	Index      IndexSettings      `json:"index"`
+	Scheduler  SchedulerSettings  `json:"scheduler"`
+	Webhooks   []WebhookSettings  `json:"webhooks,omitempty"`
+	Storage    StorageSettings    `json:"storage"`
+	Commands   CommandSettings    `json:"commands"`
+	Session    SessionSettings    `json:"session"`
+	Analyze    AnalyzeSettings    `json:"analyze"`
+	Embedding  EmbeddingSettings  `json:"embedding"`
+	// Features 实验性功能开关，未列出的功能使用 KnownFeatures 中的默认值
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// DefaultProjectSettings 返回默认配置
+func DefaultProjectSettings() *ProjectSettings {
+	return &ProjectSettings{
+		Complexity: ComplexitySettings{
+			AlertThreshold: 50,
+		},
+		TaskChain: TaskChainSettings{
+			LivenessMinutes: 10,
+			LeaseMinutes:    30,
+			MaxSummaryChars: 2000,
+		},
+		Index: IndexSettings{
+			WatchDebounceMs: 2000,
+		},
+		Scheduler: SchedulerSettings{
+			RetentionDays: 90,
+		},
+		Storage: StorageSettings{
+			BackupKeep: 7,
+		},
+		Commands: CommandSettings{
+			TimeoutSeconds: 300,
+		},
+		Session: SessionSettings{
+			IdleCheckpoint: true,
+			IdleMinutes:    15,
+		},
+		Analyze: AnalyzeSettings{
+			MaxAnchors: 10,
+		},
+		Embedding: EmbeddingSettings{
+			BatchSize: 32,
+		},
+	}
+}
+
+// SettingsPath 返回项目配置文件路径
+func SettingsPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".mcp-config", "settings.json")
+}
+
+// LoadProjectSettings 读取项目配置；文件不存在或解析失败时返回默认值
+func LoadProjectSettings(projectRoot string) *ProjectSettings {
+	settings := DefaultProjectSettings()
+	if projectRoot == "" {
+		return settings
+	}
+
+	data, err := os.ReadFile(SettingsPath(projectRoot))
+	if err != nil {
+		return settings
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		fmt.Fprintf(os.Stderr, "[Settings][WARN] settings.json 解析失败，使用默认配置: %v\n", err)
+		return DefaultProjectSettings()
+	}
+
+	if settings.Complexity.AlertThreshold <= 0 {
+		settings.Complexity.AlertThreshold = 50
+	}
+	if settings.TaskChain.LivenessMinutes <= 0 {
+		settings.TaskChain.LivenessMinutes = 10
+	}
+	if settings.TaskChain.LeaseMinutes <= 0 {
+		settings.TaskChain.LeaseMinutes = 30
+	}
+	if settings.TaskChain.MaxSummaryChars <= 0 {
+		settings.TaskChain.MaxSummaryChars = 2000
+	}
+	if settings.Index.WatchDebounceMs <= 0 {
+		settings.Index.WatchDebounceMs = 2000
+	}
+	if settings.Scheduler.RetentionDays <= 0 {
+		settings.Scheduler.RetentionDays = 90
+	}
+	if settings.Storage.BackupKeep <= 0 {
+		settings.Storage.BackupKeep = 7
+	}
+	if settings.Commands.TimeoutSeconds <= 0 {
+		settings.Commands.TimeoutSeconds = 300
+	}
+	if settings.Session.IdleMinutes <= 0 {
+		settings.Session.IdleMinutes = 15
+	}
+	if settings.Analyze.MaxAnchors <= 0 {
+		settings.Analyze.MaxAnchors = 10
+	}
+	if settings.Embedding.BatchSize <= 0 {
+		settings.Embedding.BatchSize = 32
+	}
+	return settings
+}
+
+// SaveProjectSettings 写入项目配置
+func SaveProjectSettings(projectRoot string, settings *ProjectSettings) error {
+	path := SettingsPath(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== 召回时间过滤 ==========
+
+// TimeRange 时间范围，零值端点表示不限
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// IsZero 是否未设置任何端点
+func (r TimeRange) IsZero() bool {
+	return r.Since.IsZero() && r.Until.IsZero()
+}
+
+// Contains 判断时间点是否落在范围内（两端均包含）
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && t.After(r.Until) {
+		return false
+	}
+	return true
+}
+
+// String 渲染为人类可读的范围描述
+func (r TimeRange) String() string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "…"
+		}
+		return t.Format("2006-01-02 15:04")
+	}
+	return fmt.Sprintf("%s ~ %s", format(r.Since), format(r.Until))
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimeBound 解析时间端点：ISO 日期/时间，或相对时长 (如 "12h"、"3d"、"2w"、"1m"=30天)
+// isUntil 为 true 且输入只有日期时，取当天结束时刻，使 until=2024-05-01 包含当天
+func ParseTimeBound(s string, now time.Time, isUntil bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	switch strings.ToLower(s) {
+	case "now":
+		return now, nil
+	case "today":
+		return startOfDay(now), nil
+	case "yesterday":
+		return startOfDay(now.AddDate(0, 0, -1)), nil
+	}
+
+	if d, ok := parseRelative(s); ok {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if isUntil && layout == "2006-01-02" {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间 %q（支持 2024-05-01、2024-05-01 15:04、3d、2w、12h、1m）", s)
+}
+
+// ParseTimeRange 解析 since/until 参数
+func ParseTimeRange(since, until string, now time.Time) (TimeRange, error) {
+	var r TimeRange
+	var err error
+	if r.Since, err = ParseTimeBound(since, now, false); err != nil {
+		return r, err
+	}
+	if r.Until, err = ParseTimeBound(until, now, true); err != nil {
+		return r, err
+	}
+	if !r.Since.IsZero() && !r.Until.IsZero() && r.Since.After(r.Until) {
+		return r, fmt.Errorf("since (%s) 晚于 until (%s)", since, until)
+	}
+	return r, nil
+}
+
+// ParseTTL 解析有效期，支持相对时长 (12h / 3d / 2w / 1m)
+func ParseTTL(s string) (time.Duration, error) {
+	d, ok := parseRelative(strings.TrimSpace(s))
+	if !ok || d <= 0 {
+		return 0, fmt.Errorf("无效的有效期 %q（示例: 12h、3d、2w、1m）", s)
+	}
+	return d, nil
+}
+
+func parseRelative(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	unit := s[len(s)-1]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	day := 24 * time.Hour
+	switch unit {
+	case 'h', 'H':
+		return time.Duration(n) * time.Hour, true
+	case 'd', 'D':
+		return time.Duration(n) * day, true
+	case 'w', 'W':
+		return time.Duration(n) * 7 * day, true
+	case 'm', 'M':
+		return time.Duration(n) * 30 * day, true
+	}
+	return 0, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
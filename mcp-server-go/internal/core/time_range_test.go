@@ -0,0 +1,34 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.Local)
+
+	r, err := ParseTimeRange("3d", "2024-05-14", now)
+	if err != nil {
+		t.Fatalf("ParseTimeRange failed: %v", err)
+	}
+	if want := now.Add(-72 * time.Hour); !r.Since.Equal(want) {
+		t.Errorf("since = %v, want %v", r.Since, want)
+	}
+	if !r.Contains(time.Date(2024, 5, 14, 23, 0, 0, 0, time.Local)) {
+		t.Errorf("until date should include the whole day")
+	}
+	if r.Contains(time.Date(2024, 5, 15, 0, 0, 1, 0, time.Local)) {
+		t.Errorf("time after until should be excluded")
+	}
+
+	if _, err := ParseTimeRange("2w", "", now); err != nil {
+		t.Errorf("relative weeks should parse: %v", err)
+	}
+	if _, err := ParseTimeRange("last tuesday", "", now); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+	if _, err := ParseTimeRange("2024-05-10", "2024-05-01", now); err == nil {
+		t.Errorf("expected error when since is after until")
+	}
+}
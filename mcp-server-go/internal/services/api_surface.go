@@ -0,0 +1,318 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ============================================================================
+// API 表面：按语言约定筛选公开符号，按包/模块分组；可保存为基线并与当前代码对比，
+// 删除或签名变化的公开符号视为潜在破坏性变更
+// ============================================================================
+
+// APISymbol 公开符号
+type APISymbol struct {
+	Name      string `json:"name"`
+	Qualified string `json:"qualified,omitempty"`
+	Type      string `json:"type"`
+	Signature string `json:"signature,omitempty"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+}
+
+// APISurface 公开 API 快照（模块 → 符号）
+type APISurface struct {
+	Label     string                 `json:"label"`
+	CreatedAt time.Time              `json:"created_at"`
+	Scope     string                 `json:"scope,omitempty"`
+	Modules   map[string][]APISymbol `json:"modules"`
+}
+
+// APIChange 单个公开符号的变化；Before 为空表示新增，After 为空表示删除
+type APIChange struct {
+	Module string     `json:"module"`
+	Before *APISymbol `json:"before,omitempty"`
+	After  *APISymbol `json:"after,omitempty"`
+}
+
+// APIDiff 两个 API 表面的差异
+type APIDiff struct {
+	Removed []APIChange `json:"removed"` // 破坏性
+	Changed []APIChange `json:"changed"` // 签名变化，可能破坏
+	Added   []APIChange `json:"added"`
+}
+
+// Breaking 是否存在潜在破坏性变更
+func (d *APIDiff) Breaking() bool {
+	return len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// 按目录组织包的语言；其余语言（Python/JS/TS/Rust...）以文件为模块
+var dirModuleExts = map[string]bool{".go": true, ".java": true, ".kt": true, ".cs": true}
+
+var goReceiverRe = regexp.MustCompile(`^func\s*\(\s*\w*\s*\*?\s*([A-Za-z_]\w*)`)
+
+// apiModule 符号所属的包/模块
+func apiModule(file string) string {
+	ext := strings.ToLower(path.Ext(file))
+	if dirModuleExts[ext] {
+		return path.Dir(file)
+	}
+	return strings.TrimSuffix(file, path.Ext(file))
+}
+
+// isGoInternal Go 的 internal 包只对父目录可见，不算对外 API
+func isGoInternal(p string) bool {
+	return strings.HasPrefix(p, "internal/") || strings.Contains(p, "/internal/")
+}
+
+// isPublicSymbol 按语言约定判断符号是否公开；line 为定义所在的源码行（用于 export/pub/public 修饰符）
+func isPublicSymbol(file, name, signature, line string) bool {
+	if isTestPath(file) || name == "" {
+		return false
+	}
+	words := " " + strings.Join(strings.Fields(line), " ") + " "
+	switch strings.ToLower(path.Ext(file)) {
+	case ".go":
+		// 方法还要求接收者类型导出
+		if !unicode.IsUpper([]rune(name)[0]) {
+			return false
+		}
+		if m := goReceiverRe.FindStringSubmatch(signature); m != nil {
+			return unicode.IsUpper([]rune(m[1])[0])
+		}
+		return true
+	case ".py":
+		base := path.Base(file)
+		return !strings.HasPrefix(name, "_") && (base == "__init__.py" || !strings.HasPrefix(base, "_"))
+	case ".js", ".jsx", ".mjs", ".ts", ".tsx":
+		return strings.HasPrefix(words, " export ") || strings.HasPrefix(words, " module.exports") || strings.HasPrefix(words, " exports.")
+	case ".rs":
+		// pub(crate) / pub(super) 只在 crate 内可见
+		return strings.HasPrefix(words, " pub ")
+	case ".java", ".cs":
+		return strings.Contains(words, " public ")
+	case ".kt", ".php":
+		// Kotlin / PHP 成员默认公开
+		return !strings.Contains(words, " private ") && !strings.Contains(words, " protected ") && !strings.Contains(words, " internal ")
+	case ".h", ".hpp":
+		return !strings.HasPrefix(name, "_")
+	}
+	return false
+}
+
+// ExtractAPISurface 从索引库提取 scope 内的公开符号；tagger 非空时排除 vendored/generated 文件
+func (ai *ASTIndexer) ExtractAPISurface(projectRoot, scope string, tagger *PathTagger) (*APISurface, error) {
+	var all []APISymbol
+	err := eachIndexDB(projectRoot, scope, func(db *sql.DB) error {
+		signatureExpr, qualifiedExpr := "''", "''"
+		if hasColumn(db, "symbols", "signature") {
+			signatureExpr = "COALESCE(s.signature, '')"
+		}
+		if hasColumn(db, "symbols", "qualified_name") {
+			qualifiedExpr = "COALESCE(s.qualified_name, '')"
+		}
+		rows, err := db.Query(`
+			SELECT s.name, ` + qualifiedExpr + `, s.symbol_type, ` + signatureExpr + `, COALESCE(f.file_path, ''), COALESCE(s.line_start, 0)
+			FROM symbols s LEFT JOIN files f ON s.file_id = f.file_id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s APISymbol
+			if rows.Scan(&s.Name, &s.Qualified, &s.Type, &s.Signature, &s.File, &s.Line) == nil {
+				all = append(all, s)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scope = strings.Trim(filepath.ToSlash(scope), "/")
+	surface := &APISurface{CreatedAt: time.Now(), Scope: scope, Modules: make(map[string][]APISymbol)}
+	sources := make(map[string][]string)
+	for _, s := range all {
+		s.File = strings.TrimPrefix(filepath.ToSlash(s.File), "./")
+		if scope != "" && s.File != scope && !strings.HasPrefix(s.File, scope+"/") {
+			continue
+		}
+		if tagger != nil && tagger.Excluded(s.File) {
+			continue
+		}
+		// 显式把 scope 指向 internal 包时才列出其导出符号
+		if strings.HasSuffix(s.File, ".go") && isGoInternal(s.File) && !isGoInternal(scope+"/") {
+			continue
+		}
+		lines, ok := sources[s.File]
+		if !ok {
+			if data, err := os.ReadFile(filepath.Join(projectRoot, filepath.FromSlash(s.File))); err == nil {
+				lines = strings.Split(string(data), "\n")
+			}
+			sources[s.File] = lines
+		}
+		line := ""
+		if s.Line > 0 && s.Line <= len(lines) {
+			line = lines[s.Line-1]
+		}
+		if !isPublicSymbol(s.File, s.Name, s.Signature, line) {
+			continue
+		}
+		s.Signature = normalizeSignature(s.Signature)
+		if s.Qualified == s.Name {
+			s.Qualified = ""
+		}
+		mod := apiModule(s.File)
+		surface.Modules[mod] = append(surface.Modules[mod], s)
+	}
+	for _, syms := range surface.Modules {
+		sort.Slice(syms, func(i, j int) bool {
+			if syms[i].File != syms[j].File {
+				return syms[i].File < syms[j].File
+			}
+			return syms[i].Line < syms[j].Line
+		})
+	}
+	return surface, nil
+}
+
+// normalizeSignature 去掉函数体起始与多余空白，避免格式调整被当成签名变化
+func normalizeSignature(sig string) string {
+	sig = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sig), "{"))
+	sig = strings.TrimSuffix(sig, ":")
+	return strings.Join(strings.Fields(sig), " ")
+}
+
+// Count 公开符号总数
+func (s *APISurface) Count() int {
+	n := 0
+	for _, syms := range s.Modules {
+		n += len(syms)
+	}
+	return n
+}
+
+func apiSymbolKey(module string, s APISymbol) string {
+	name := s.Qualified
+	if name == "" {
+		name = s.Name
+	}
+	return module + "\x00" + s.Type + "\x00" + name
+}
+
+// DiffAPISurfaces 对比基线与当前 API 表面
+func DiffAPISurfaces(base, cur *APISurface) *APIDiff {
+	index := func(s *APISurface) map[string]APIChange {
+		m := make(map[string]APIChange)
+		for mod, syms := range s.Modules {
+			for i := range syms {
+				m[apiSymbolKey(mod, syms[i])] = APIChange{Module: mod, After: &syms[i]}
+			}
+		}
+		return m
+	}
+	before, after := index(base), index(cur)
+	d := &APIDiff{}
+	for key, b := range before {
+		a, ok := after[key]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, APIChange{Module: b.Module, Before: b.After})
+		case a.After.Signature != b.After.Signature && b.After.Signature != "":
+			d.Changed = append(d.Changed, APIChange{Module: b.Module, Before: b.After, After: a.After})
+		}
+	}
+	for key, a := range after {
+		if _, ok := before[key]; !ok {
+			d.Added = append(d.Added, a)
+		}
+	}
+	for _, list := range [][]APIChange{d.Removed, d.Changed, d.Added} {
+		sort.Slice(list, func(i, j int) bool {
+			si, sj := list[i].After, list[j].After
+			if si == nil {
+				si, sj = list[i].Before, list[j].Before
+			}
+			if list[i].Module != list[j].Module {
+				return list[i].Module < list[j].Module
+			}
+			return si.Name < sj.Name
+		})
+	}
+	return d
+}
+
+var apiLabelRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// SaveAPISurface 以 label 为文件名保存基线（同名覆盖）
+func SaveAPISurface(dir string, s *APISurface) error {
+	if !apiLabelRe.MatchString(s.Label) {
+		return fmt.Errorf("基线名只能包含字母、数字、点、下划线和连字符: %q", s.Label)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, s.Label+".json"), data, 0644)
+}
+
+// LoadAPISurface 读取基线
+func LoadAPISurface(dir, label string) (*APISurface, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(label)+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("API 基线不存在: %s", label)
+		}
+		return nil, err
+	}
+	var s APISurface
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("API 基线 %s 已损坏: %v", label, err)
+	}
+	return &s, nil
+}
+
+// ListAPISurfaces 基线名列表（按保存时间从旧到新）
+func ListAPISurfaces(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	type item struct {
+		label string
+		mod   time.Time
+	}
+	var items []item
+	for _, e := range entries {
+		label := strings.TrimSuffix(e.Name(), ".json")
+		if e.IsDir() || label == e.Name() {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			items = append(items, item{label, info.ModTime()})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].mod.Before(items[j].mod) })
+	labels := make([]string, len(items))
+	for i, it := range items {
+		labels[i] = it.label
+	}
+	return labels, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAPISurface_ExtractAndDiff(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"pkg/client.go":      "package pkg\n\nfunc NewClient(addr string) *Client {\n\treturn nil\n}\n\nfunc (c *Client) Do() error {\n\treturn nil\n}\n\nfunc helper() {}\n\nfunc (h *handler) Serve() {}\n",
+		"internal/util.go":   "package util\n\nfunc Exported() {}\n",
+		"lib/api.ts":         "export function fetchUser(id: string) {}\nfunction local() {}\n",
+		"src/lib.rs":         "pub fn open() {}\npub(crate) fn inner() {}\n",
+		"py/mod.py":          "def run():\n    pass\n\ndef _private():\n    pass\n",
+		"pkg/client_test.go": "package pkg\n\nfunc TestClient(t *testing.T) {}\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbPath := getDBPath(root)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	execDB(t, dbPath,
+		"CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)",
+		"CREATE TABLE symbols (symbol_id INTEGER PRIMARY KEY, file_id INTEGER, name TEXT, qualified_name TEXT, symbol_type TEXT, line_start INTEGER, signature TEXT)",
+		"INSERT INTO files VALUES (1, 'pkg/client.go'), (2, 'internal/util.go'), (3, 'lib/api.ts'), (4, 'src/lib.rs'), (5, 'py/mod.py'), (6, 'pkg/client_test.go')",
+		`INSERT INTO symbols VALUES
+			(1, 1, 'NewClient', 'NewClient', 'function', 3, 'func NewClient(addr string) *Client {'),
+			(2, 1, 'Do', 'Client::Do', 'method', 7, 'func (c *Client) Do() error {'),
+			(3, 1, 'helper', 'helper', 'function', 11, 'func helper() {}'),
+			(4, 1, 'Serve', 'handler::Serve', 'method', 13, 'func (h *handler) Serve() {}'),
+			(5, 2, 'Exported', 'Exported', 'function', 3, 'func Exported() {}'),
+			(6, 3, 'fetchUser', 'fetchUser', 'function', 1, 'export function fetchUser(id: string) {}'),
+			(7, 3, 'local', 'local', 'function', 2, 'function local() {}'),
+			(8, 4, 'open', 'open', 'function', 1, 'pub fn open() {}'),
+			(9, 4, 'inner', 'inner', 'function', 2, 'pub(crate) fn inner() {}'),
+			(10, 5, 'run', 'run', 'function', 1, 'def run():'),
+			(11, 5, '_private', '_private', 'function', 4, 'def _private():'),
+			(12, 6, 'TestClient', 'TestClient', 'function', 3, 'func TestClient(t *testing.T) {}')`,
+	)
+
+	ai := &ASTIndexer{}
+	base, err := ai.ExtractAPISurface(root, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for mod, syms := range base.Modules {
+		for _, s := range syms {
+			got[mod+":"+s.Name] = true
+		}
+	}
+	want := []string{"pkg:NewClient", "pkg:Do", "lib/api:fetchUser", "src/lib:open", "py/mod:run"}
+	for _, k := range want {
+		if !got[k] {
+			t.Errorf("missing %s in %v", k, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected public symbols: %v", got)
+	}
+	if scoped, _ := ai.ExtractAPISurface(root, "internal", nil); scoped.Count() != 1 {
+		t.Errorf("internal scope should list its exports, got %d", scoped.Count())
+	}
+
+	// 保存基线后：删除 open、改 NewClient 签名、新增 Close
+	base.Label = "v1"
+	dir := filepath.Join(root, "baselines")
+	if err := SaveAPISurface(dir, base); err != nil {
+		t.Fatal(err)
+	}
+	execDB(t, dbPath,
+		"DELETE FROM symbols WHERE symbol_id = 8",
+		"UPDATE symbols SET signature = 'func NewClient(addr string, opts ...Option) *Client {' WHERE symbol_id = 1",
+		"INSERT INTO symbols VALUES (13, 1, 'Close', 'Client::Close', 'method', 9, 'func (c *Client) Close() error {')",
+	)
+	cur, err := ai.ExtractAPISurface(root, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadAPISurface(dir, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := DiffAPISurfaces(loaded, cur)
+	if !d.Breaking() || len(d.Removed) != 1 || d.Removed[0].Before.Name != "open" {
+		t.Errorf("removed = %+v", d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].After.Name != "NewClient" {
+		t.Errorf("changed = %+v", d.Changed)
+	}
+	if len(d.Added) != 1 || d.Added[0].After.Name != "Close" {
+		t.Errorf("added = %+v", d.Added)
+	}
+}
@@ -1,20 +1,25 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"mcp-server-go/internal/core"
+
 	_ "modernc.org/sqlite"
 )
 
@@ -133,6 +138,27 @@ type ASTIndexer struct {
 	BinaryPath  string
 	indexMu     sync.Mutex
 	lastIndexAt map[string]time.Time
+	cacheOnce   sync.Once
+	cache       *queryCache
+	daemonMu    sync.Mutex
+	daemons     map[string]*engineDaemon
+	runStats    IndexRunStats
+	stopMu      sync.Mutex
+	stopCtx     context.Context // 进程级上下文，Shutdown 时取消
+	stopFn      context.CancelFunc
+}
+
+// ErrIndexInterrupted 服务关闭导致索引命令被终止
+var ErrIndexInterrupted = errors.New("索引被中断（服务正在关闭）")
+
+// IndexRunStats 本进程内的索引构建统计（server_stats 展示）
+type IndexRunStats struct {
+	Runs     int64     `json:"runs"`
+	Failures int64     `json:"failures"`
+	LastMs   int64     `json:"last_ms"`
+	MaxMs    int64     `json:"max_ms"`
+	TotalMs  int64     `json:"total_ms"`
+	LastAt   time.Time `json:"last_at"`
 }
 
 const defaultIndexFreshness = 5 * time.Minute
@@ -204,6 +230,60 @@ func getIndexCommandTimeout() time.Duration {
 	return time.Duration(sec) * time.Second
 }
 
+// lifecycle 所有引擎子进程共用的上下文，Shutdown 后已取消
+func (ai *ASTIndexer) lifecycle() context.Context {
+	ai.stopMu.Lock()
+	defer ai.stopMu.Unlock()
+	if ai.stopCtx == nil {
+		ai.stopCtx, ai.stopFn = context.WithCancel(context.Background())
+	}
+	return ai.stopCtx
+}
+
+// Shutdown 终止正在运行的索引命令与常驻引擎进程（可重复调用）
+func (ai *ASTIndexer) Shutdown() {
+	ai.lifecycle()
+	ai.stopFn()
+
+	ai.daemonMu.Lock()
+	daemons := make([]*engineDaemon, 0, len(ai.daemons))
+	for _, d := range ai.daemons {
+		daemons = append(daemons, d)
+	}
+	ai.daemonMu.Unlock()
+	for _, d := range daemons {
+		d.shutdown()
+	}
+}
+
+// Stopping 是否已开始关闭
+func (ai *ASTIndexer) Stopping() bool {
+	return ai.lifecycle().Err() != nil
+}
+
+func (ai *ASTIndexer) recordIndexRun(elapsed time.Duration, err error) {
+	ms := elapsed.Milliseconds()
+	ai.indexMu.Lock()
+	defer ai.indexMu.Unlock()
+	ai.runStats.Runs++
+	if err != nil {
+		ai.runStats.Failures++
+	}
+	ai.runStats.LastMs = ms
+	ai.runStats.TotalMs += ms
+	if ms > ai.runStats.MaxMs {
+		ai.runStats.MaxMs = ms
+	}
+	ai.runStats.LastAt = time.Now()
+}
+
+// IndexRunStats 索引构建次数与耗时
+func (ai *ASTIndexer) IndexRunStats() IndexRunStats {
+	ai.indexMu.Lock()
+	defer ai.indexMu.Unlock()
+	return ai.runStats
+}
+
 func (ai *ASTIndexer) markIndexFresh(projectRoot string) {
 	root := normalizeProjectRoot(projectRoot)
 	ai.indexMu.Lock()
@@ -221,7 +301,12 @@ func (ai *ASTIndexer) shouldSkipIndex(projectRoot string, maxAge time.Duration)
 	}
 	ai.indexMu.Unlock()
 
-	info, err := os.Stat(getDBPath(root))
+	// 分片模式以清单的更新时间作为最近一次索引时间
+	probe := getDBPath(root)
+	if activeShards(root) != nil {
+		probe = shardManifestPath(root)
+	}
+	info, err := os.Stat(probe)
 	if err != nil {
 		return false
 	}
@@ -229,7 +314,11 @@ func (ai *ASTIndexer) shouldSkipIndex(projectRoot string, maxAge time.Duration)
 		return false
 	}
 
-	if !hasUsableIndex(getDBPath(root)) {
+	usable := false
+	for _, p := range IndexDBPaths(root, "") {
+		usable = usable || hasUsableIndex(p)
+	}
+	if !usable {
 		return false
 	}
 
@@ -287,20 +376,65 @@ func getDBPath(projectRoot string) string {
 		// 如果转换失败,使用原路径(但可能有风险)
 		absRoot = projectRoot
 	}
-	return filepath.Join(absRoot, ".mcp-data", "symbols.db")
+	return core.DataPath(absRoot, "symbols.db")
 }
 
-// getOutputPath 获取临时输出路径
-func getOutputPath(projectRoot string, mode string) string {
-	// 【修复】确保返回绝对路径,防止缓存文件跑到C盘
-	absRoot, err := filepath.Abs(projectRoot)
+// engineEnv Rust 引擎的环境变量：附带已解析的数据目录，心跳等文件与 Go 侧写入同一位置
+func engineEnv(projectRoot string) []string {
+	return append(os.Environ(), core.DataDirEnv+"="+core.DataDir(projectRoot))
+}
+
+// runEngine 执行 Rust 引擎，结果 JSON 从子进程 stdout 读取（诊断信息走 stderr），
+// 不经过临时文件，并发调用互不干扰。查询类模式优先交给常驻进程，不可用时单独启动进程
+func (ai *ASTIndexer) runEngine(ctx context.Context, projectRoot string, args []string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if daemonModes[engineMode(args)] && daemonEnabled() {
+		data, err := ai.daemon(projectRoot).call(ctx, args)
+		var reqErr *daemonRequestError
+		if err == nil || errors.As(err, &reqErr) {
+			return data, err
+		}
+	}
+	cmd := exec.CommandContext(ctx, ai.BinaryPath, args...)
+	cmd.Dir = projectRoot
+	cmd.Env = engineEnv(projectRoot)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return engineJSON(stdout.Bytes())
+}
+
+var errNoEngineOutput = errors.New("引擎没有输出结果")
+
+// engineJSON 取 stdout 的最后一个非空行作为结果
+func engineJSON(out []byte) ([]byte, error) {
+	lines := bytes.Split(bytes.TrimSpace(out), []byte("\n"))
+	last := bytes.TrimSpace(lines[len(lines)-1])
+	if len(last) == 0 {
+		return nil, errNoEngineOutput
+	}
+	return last, nil
+}
+
+// removeLegacyResultFiles 清理旧版本遗留在数据目录的 .ast_result_*.json 临时文件
+func removeLegacyResultFiles(projectRoot string) {
+	entries, err := os.ReadDir(core.DataDir(projectRoot))
 	if err != nil {
-		// 如果转换失败,使用原路径(但可能有风险)
-		absRoot = projectRoot
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), ".ast_result_") && strings.HasSuffix(e.Name(), ".json") {
+			_ = os.Remove(core.DataPath(projectRoot, e.Name()))
+		}
 	}
-	mcpData := filepath.Join(absRoot, ".mcp-data")
-	_ = os.MkdirAll(mcpData, 0755)
-	return filepath.Join(mcpData, fmt.Sprintf(".ast_result_%s.json", mode))
 }
 
 // ============================================================================
@@ -397,14 +531,14 @@ func scanProjectExtensions(projectRoot string, ignoreDirs []string, maxDepth int
 		}
 	}
 
+	limits := defaultScanLimits
+	limits.MaxDepth = maxDepth
+	scanner := newDirScanner(projectRoot, limits)
+
 	var walk func(dir string, depth int)
 	walk = func(dir string, depth int) {
-		if depth > maxDepth {
-			return
-		}
-
-		entries, err := os.ReadDir(dir)
-		if err != nil {
+		entries, ok := scanner.readDir(dir, depth)
+		if !ok {
 			return
 		}
 
@@ -412,7 +546,7 @@ func scanProjectExtensions(projectRoot string, ignoreDirs []string, maxDepth int
 			name := e.Name()
 			nameLower := strings.ToLower(name)
 
-			if e.IsDir() {
+			if scanner.isDir(dir, e) {
 				if shouldSkipDetectDir(nameLower, ignoreSet) {
 					continue
 				}
@@ -428,6 +562,7 @@ func scanProjectExtensions(projectRoot string, ignoreDirs []string, maxDepth int
 	}
 
 	walk(projectRoot, 0)
+	scanner.finish(projectRoot)
 	return result
 }
 
@@ -519,25 +654,24 @@ func hasRustProject(projectRoot string) bool {
 	if fileExists(filepath.Join(projectRoot, "Cargo.toml")) {
 		return true
 	}
-	// 递归搜索子目录（最多6层）
-	return hasCargoTomlRecursive(projectRoot, 0, 6)
+	// 递归搜索子目录（最多6层），同样受符号链接环检测与扫描预算约束
+	limits := defaultScanLimits
+	limits.MaxDepth = 5
+	return hasCargoTomlRecursive(newDirScanner(projectRoot, limits), projectRoot, 0)
 }
 
-func hasCargoTomlRecursive(dir string, depth, maxDepth int) bool {
-	if depth >= maxDepth {
-		return false
-	}
-	entries, err := os.ReadDir(dir)
-	if err != nil {
+func hasCargoTomlRecursive(scanner *dirScanner, dir string, depth int) bool {
+	entries, ok := scanner.readDir(dir, depth)
+	if !ok {
 		return false
 	}
 	for _, e := range entries {
-		if e.IsDir() {
+		if scanner.isDir(dir, e) {
 			subdir := filepath.Join(dir, e.Name())
 			if fileExists(filepath.Join(subdir, "Cargo.toml")) {
 				return true
 			}
-			if hasCargoTomlRecursive(subdir, depth+1, maxDepth) {
+			if hasCargoTomlRecursive(scanner, subdir, depth+1) {
 				return true
 			}
 		}
@@ -572,11 +706,8 @@ func (ai *ASTIndexer) MapProject(projectRoot string, detail string) (*MapResult,
 // StructureProjectWithScope 快速目录结构扫描（--mode structure，不依赖符号索引）
 func (ai *ASTIndexer) StructureProjectWithScope(projectRoot string, scope string) (*StructureResult, error) {
 	dbPath := getDBPath(projectRoot)
-	outputPath := getOutputPath(projectRoot, "structure")
 	_, ignoreDirs := detectTechStackAndConfig(projectRoot)
 
-	_ = os.Remove(outputPath)
-
 	if scope == "." || scope == "./" {
 		scope = ""
 	}
@@ -585,7 +716,6 @@ func (ai *ASTIndexer) StructureProjectWithScope(projectRoot string, scope string
 		"--mode", "structure",
 		"--project", projectRoot,
 		"--db", dbPath,
-		"--output", outputPath,
 		"--detail", "standard",
 	}
 	if scope != "" {
@@ -595,22 +725,11 @@ func (ai *ASTIndexer) StructureProjectWithScope(projectRoot string, scope string
 		args = append(args, "--ignore-dirs", ignoreDirs)
 	}
 
-	cmd := exec.Command(ai.BinaryPath, args...)
-	cmd.Dir = projectRoot
-	output, err := cmd.CombinedOutput()
+	data, err := ai.runEngine(ai.lifecycle(), projectRoot, args)
 	if err != nil {
-		msg := strings.TrimSpace(string(output))
-		if msg != "" {
-			return nil, fmt.Errorf("目录结构扫描失败: %v: %s", err, msg)
-		}
 		return nil, fmt.Errorf("目录结构扫描失败: %v", err)
 	}
 
-	data, err := os.ReadFile(outputPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取目录结构结果失败: %v", err)
-	}
-
 	var result StructureResult
 	if err := json.Unmarshal(data, &result); err != nil {
 		return nil, fmt.Errorf("解析目录结构结果失败: %v", err)
@@ -619,14 +738,45 @@ func (ai *ASTIndexer) StructureProjectWithScope(projectRoot string, scope string
 	return &result, nil
 }
 
-// MapProjectWithScope 带范围的项目地图
+// MapProjectWithScope 带范围的项目地图；分片模式只加载 scope 涉及的分片并合并
 func (ai *ASTIndexer) MapProjectWithScope(projectRoot string, detail string, scope string) (*MapResult, error) {
-	dbPath := getDBPath(projectRoot)
-	outputPath := getOutputPath(projectRoot, "map")
-
-	// 清理旧文件
-	_ = os.Remove(outputPath)
+	paths := IndexDBPaths(projectRoot, scope)
+	switch len(paths) {
+	case 0:
+		return nil, fmt.Errorf("scope %q 涉及的索引分片尚未构建，请先刷新索引", scope)
+	case 1:
+		return ai.mapProjectOnDB(projectRoot, paths[0], detail, scope)
+	}
+	merged := &MapResult{Structure: make(map[string][]Node)}
+	var elapsed time.Duration
+	for _, p := range paths {
+		res, err := ai.mapProjectOnDB(projectRoot, p, detail, scope)
+		if err != nil {
+			return nil, fmt.Errorf("分片 %s: %w", filepath.Base(p), err)
+		}
+		merged.Statistics.TotalFiles += res.Statistics.TotalFiles
+		merged.Statistics.TotalSymbols += res.Statistics.TotalSymbols
+		for dir, nodes := range res.Structure {
+			merged.Structure[dir] = append(merged.Structure[dir], nodes...)
+		}
+		for name, score := range res.ComplexityMap {
+			if merged.ComplexityMap == nil {
+				merged.ComplexityMap = make(map[string]float64)
+			}
+			if score > merged.ComplexityMap[name] {
+				merged.ComplexityMap[name] = score
+			}
+		}
+		if d, err := time.ParseDuration(res.Elapsed); err == nil {
+			elapsed += d
+		}
+	}
+	merged.Elapsed = elapsed.String()
+	return merged, nil
+}
 
+// mapProjectOnDB 在指定索引库上生成项目地图
+func (ai *ASTIndexer) mapProjectOnDB(projectRoot, dbPath, detail, scope string) (*MapResult, error) {
 	// 智能技术栈检测
 	_, ignoreDirs := detectTechStackAndConfig(projectRoot)
 
@@ -639,7 +789,6 @@ func (ai *ASTIndexer) MapProjectWithScope(projectRoot string, detail string, sco
 		"--mode", "map",
 		"--project", projectRoot,
 		"--db", dbPath,
-		"--output", outputPath,
 		"--detail", detail,
 	}
 	if scope != "" {
@@ -653,17 +802,9 @@ func (ai *ASTIndexer) MapProjectWithScope(projectRoot string, detail string, sco
 		args = append(args, "--ignore-dirs", ignoreDirs)
 	}
 
-	cmd := exec.Command(ai.BinaryPath, args...)
-	cmd.Dir = projectRoot // 设置工作目录
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("项目地图生成失败: %v", err)
-	}
-
-	// 读取输出文件
-	data, err := os.ReadFile(outputPath)
+	data, err := ai.runEngine(ai.lifecycle(), projectRoot, args)
 	if err != nil {
-		return nil, fmt.Errorf("读取地图结果失败: %v", err)
+		return nil, fmt.Errorf("项目地图生成失败: %v", err)
 	}
 
 	var result MapResult
@@ -679,36 +820,47 @@ func (ai *ASTIndexer) SearchSymbol(projectRoot string, query string) (*QueryResu
 	return ai.SearchSymbolWithScope(projectRoot, query, "")
 }
 
-// SearchSymbolWithScope 带范围的符号搜索
+// SearchSymbolWithScope 带范围的符号搜索；分片模式向 scope 涉及的分片分发并合并
 func (ai *ASTIndexer) SearchSymbolWithScope(projectRoot string, query string, scope string) (*QueryResult, error) {
-	dbPath := getDBPath(projectRoot)
-	outputPath := getOutputPath(projectRoot, "query")
-
-	// 清理旧文件
-	_ = os.Remove(outputPath)
-
-	args := []string{
-		"--mode", "query",
-		"--project", projectRoot,
-		"--db", dbPath,
-		"--output", outputPath,
-		"--query", query,
+	paths := IndexDBPaths(projectRoot, scope)
+	if len(paths) == 1 {
+		return ai.searchSymbolOnDB(projectRoot, paths[0], query, scope)
 	}
-	if scope != "" {
-		args = append(args, "--scope", scope)
+	var results []*QueryResult
+	for _, p := range paths {
+		res, err := ai.searchSymbolOnDB(projectRoot, p, query, scope)
+		if err != nil {
+			return nil, fmt.Errorf("分片 %s: %w", filepath.Base(p), err)
+		}
+		results = append(results, res)
 	}
+	return mergeQueryResults(query, results), nil
+}
 
-	cmd := exec.Command(ai.BinaryPath, args...)
-	cmd.Dir = projectRoot
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("符号搜索失败: %v", err)
+// mergeQueryResults 合并各分片的搜索结果：精确命中优先，候选按得分排序
+func mergeQueryResults(query string, results []*QueryResult) *QueryResult {
+	merged := &QueryResult{Status: "success", Query: query}
+	var best *QueryResult
+	for _, r := range results {
+		if r.FoundSymbol != nil && (best == nil || (r.MatchType == "exact" && best.MatchType != "exact")) {
+			best = r
+		}
+		merged.Candidates = append(merged.Candidates, r.Candidates...)
+	}
+	if best != nil {
+		merged.FoundSymbol, merged.MatchType, merged.RelatedNodes = best.FoundSymbol, best.MatchType, best.RelatedNodes
 	}
+	sort.SliceStable(merged.Candidates, func(i, j int) bool { return merged.Candidates[i].Score > merged.Candidates[j].Score })
+	return merged
+}
 
-	// 读取输出文件
-	data, err := os.ReadFile(outputPath)
+// searchSymbolOnDB 在指定索引库上搜索符号（结果经查询缓存）
+func (ai *ASTIndexer) searchSymbolOnDB(projectRoot, dbPath, query, scope string) (*QueryResult, error) {
+	data, err := ai.cachedQuery(projectRoot, dbPath, "query", query, scope, func() ([]byte, error) {
+		return ai.runSearchQuery(projectRoot, dbPath, query, scope)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("读取搜索结果失败: %v", err)
+		return nil, err
 	}
 
 	var result QueryResult
@@ -719,44 +871,51 @@ func (ai *ASTIndexer) SearchSymbolWithScope(projectRoot string, query string, sc
 	return &result, nil
 }
 
-// GetSymbolAtLine 获取指定文件行号处的符号信息 (--mode query --file --line)
-func (ai *ASTIndexer) GetSymbolAtLine(projectRoot string, filePath string, line int) (*Node, error) {
-	dbPath := getDBPath(projectRoot)
-	outputPath := getOutputPath(projectRoot, fmt.Sprintf("line_%d", line))
-
-	// 清理所有旧的 line_*.json 临时文件（避免泄漏）
-	mcpData := filepath.Join(projectRoot, ".mcp-data")
-	if entries, err := os.ReadDir(mcpData); err == nil {
-		for _, e := range entries {
-			if !e.IsDir() && strings.HasPrefix(e.Name(), ".ast_result_line_") && strings.HasSuffix(e.Name(), ".json") {
-				_ = os.Remove(filepath.Join(mcpData, e.Name()))
-			}
-		}
-	}
-
-	// 清理当前文件
-	_ = os.Remove(outputPath)
-
+func (ai *ASTIndexer) runSearchQuery(projectRoot, dbPath, query, scope string) ([]byte, error) {
 	args := []string{
 		"--mode", "query",
 		"--project", projectRoot,
 		"--db", dbPath,
-		"--output", outputPath,
-		"--file", filePath,
-		"--line", fmt.Sprintf("%d", line),
+		"--query", query,
+	}
+	if scope != "" {
+		args = append(args, "--scope", scope)
 	}
 
-	cmd := exec.Command(ai.BinaryPath, args...)
-	cmd.Dir = projectRoot
+	data, err := ai.runEngine(ai.lifecycle(), projectRoot, args)
+	if err != nil {
+		return nil, fmt.Errorf("符号搜索失败: %v", err)
+	}
+	return data, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("定位符号失败: %v", err)
+// GetSymbolAtLine 获取指定文件行号处的符号信息 (--mode query --file --line)
+func (ai *ASTIndexer) GetSymbolAtLine(projectRoot string, filePath string, line int) (*Node, error) {
+	rel := filePath
+	if filepath.IsAbs(rel) {
+		if r, err := filepath.Rel(projectRoot, rel); err == nil {
+			rel = r
+		}
+	}
+	var firstErr error
+	for _, p := range IndexDBPaths(projectRoot, rel) {
+		node, err := ai.symbolAtLineOnDB(projectRoot, p, filePath, line)
+		if node != nil {
+			return node, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
+	return nil, firstErr
+}
 
-	// 读取输出文件
-	data, err := os.ReadFile(outputPath)
+func (ai *ASTIndexer) symbolAtLineOnDB(projectRoot, dbPath, filePath string, line int) (*Node, error) {
+	data, err := ai.cachedQuery(projectRoot, dbPath, "line", fmt.Sprintf("%s:%d", filePath, line), "", func() ([]byte, error) {
+		return ai.runLineQuery(projectRoot, dbPath, filePath, line)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("读取定位结果失败: %v", err)
+		return nil, err
 	}
 
 	var result QueryResult
@@ -767,80 +926,111 @@ func (ai *ASTIndexer) GetSymbolAtLine(projectRoot string, filePath string, line
 	return result.FoundSymbol, nil
 }
 
+func (ai *ASTIndexer) runLineQuery(projectRoot, dbPath, filePath string, line int) ([]byte, error) {
+	args := []string{
+		"--mode", "query",
+		"--project", projectRoot,
+		"--db", dbPath,
+		"--file", filePath,
+		"--line", fmt.Sprintf("%d", line),
+	}
+
+	data, err := ai.runEngine(ai.lifecycle(), projectRoot, args)
+	if err != nil {
+		return nil, fmt.Errorf("定位符号失败: %v", err)
+	}
+	return data, nil
+}
+
 // Analyze 执行影响分析 (--mode analyze)
 func (ai *ASTIndexer) Analyze(projectRoot string, symbol string, direction string) (*ImpactResult, error) {
 	// 先确保索引是最新的
 	_, _ = ai.EnsureFreshIndex(projectRoot)
 
-	dbPath := getDBPath(projectRoot)
-	outputPath := getOutputPath(projectRoot, "analyze")
+	paths := IndexDBPaths(projectRoot, "")
+	if len(paths) == 1 {
+		return ai.analyzeOnDB(projectRoot, paths[0], symbol, direction)
+	}
+	// 分片模式：以找到符号的第一个分片为准，其余分片中的同名符号调用方一并并入
+	var merged *ImpactResult
+	for _, p := range paths {
+		res, err := ai.analyzeOnDB(projectRoot, p, symbol, direction)
+		if err != nil || res.Status != "success" {
+			continue
+		}
+		if merged == nil {
+			merged = res
+			continue
+		}
+		merged.DirectCallers = append(merged.DirectCallers, res.DirectCallers...)
+		merged.IndirectCallers = append(merged.IndirectCallers, res.IndirectCallers...)
+		merged.AffectedNodes += res.AffectedNodes
+	}
+	if merged == nil {
+		return &ImpactResult{Status: "error", Message: "Symbol not found"}, nil
+	}
+	return merged, nil
+}
+
+func (ai *ASTIndexer) analyzeOnDB(projectRoot, dbPath, symbol, direction string) (*ImpactResult, error) {
+	data, err := ai.cachedQuery(projectRoot, dbPath, "analyze", symbol, direction, func() ([]byte, error) {
+		return ai.runAnalyzeQuery(projectRoot, dbPath, symbol, direction)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// 清理旧文件
-	_ = os.Remove(outputPath)
+	var result ImpactResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析分析结果失败: %v", err)
+	}
+
+	return &result, nil
+}
 
+func (ai *ASTIndexer) runAnalyzeQuery(projectRoot, dbPath, symbol, direction string) ([]byte, error) {
 	args := []string{
 		"--mode", "analyze",
 		"--project", projectRoot,
 		"--db", dbPath,
-		"--output", outputPath,
 		"--query", symbol,
 	}
 	if direction != "" {
 		args = append(args, "--direction", direction)
 	}
 
-	cmd := exec.Command(ai.BinaryPath, args...)
-	cmd.Dir = projectRoot
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("影响分析执行失败: %v", err)
-	}
-
-	// 读取输出文件
-	data, err := os.ReadFile(outputPath)
+	data, err := ai.runEngine(ai.lifecycle(), projectRoot, args)
 	if err != nil {
-		return nil, fmt.Errorf("读取分析结果失败: %v", err)
-	}
-
-	var result ImpactResult
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, fmt.Errorf("解析分析结果失败: %v", err)
+		return nil, fmt.Errorf("影响分析执行失败: %v", err)
 	}
-
-	return &result, nil
+	return data, nil
 }
 
-func (ai *ASTIndexer) runIndexCommand(projectRoot string, args []string) error {
+// runIndexCommand 执行索引命令（带超时），返回引擎输出的 IndexResult JSON
+func (ai *ASTIndexer) runIndexCommand(projectRoot string, args []string) ([]byte, error) {
 	timeout := getIndexCommandTimeout()
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ai.lifecycle(), timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, ai.BinaryPath, args...)
-	cmd.Dir = projectRoot
-	output, err := cmd.CombinedOutput()
+	data, err := ai.runEngine(ctx, projectRoot, args)
+	if ai.Stopping() {
+		return nil, ErrIndexInterrupted
+	}
 	if ctx.Err() == context.DeadlineExceeded {
-		msg := strings.TrimSpace(string(output))
-		if msg != "" {
-			return fmt.Errorf("索引命令超时(%s): %s", timeout, msg)
-		}
-		return fmt.Errorf("索引命令超时(%s)", timeout)
+		return nil, fmt.Errorf("索引命令超时(%s): %v", timeout, err)
 	}
-	if err != nil {
-		msg := strings.TrimSpace(string(output))
-		if msg != "" {
-			return fmt.Errorf("%v: %s", err, msg)
-		}
-		return err
+	if errors.Is(err, errNoEngineOutput) {
+		// 索引可能不输出结果，由调用方使用默认结果
+		return nil, nil
 	}
-	return nil
+	return data, err
 }
 
-func buildIndexArgs(projectRoot, dbPath, outputPath, ignoreDirs, extensions, scope string, useExtensions bool, forceFull bool) []string {
+func buildIndexArgs(projectRoot, dbPath, ignoreDirs, extensions, scope string, useExtensions bool, forceFull bool) []string {
 	args := []string{
 		"--mode", "index",
 		"--project", projectRoot,
 		"--db", dbPath,
-		"--output", outputPath,
 	}
 	if forceFull {
 		args = append(args, "--force-full")
@@ -857,62 +1047,184 @@ func buildIndexArgs(projectRoot, dbPath, outputPath, ignoreDirs, extensions, sco
 	return args
 }
 
-// Index 刷新索引 (--mode index)
+// engineDefaultIgnores 引擎 (ast_indexer_rust) 内置的默认忽略目录，与 main.rs 的 default_ignores 保持一致
+var engineDefaultIgnores = []string{
+	".git", "node_modules", "vendor", "dist", "build", "out", "target", "__pycache__", ".venv", "venv",
+	"site-packages", ".m2", ".gradle", ".idea", ".vscode", "coverage", "_build", ".next", ".nuxt", ".svelte-kit",
+}
+
+// indexPathRules 引擎建索引时实际生效的过滤规则，供文件监听等判断变更是否影响索引：
+// 忽略目录 = 引擎内置 + 技术栈检测 + .gitignore 目录；扩展名白名单默认为空（全量扫描不传 --extensions），
+// 只有按语言分片时取各分片扩展名的并集
+func indexPathRules(projectRoot string) (ignoreDirs, extensions []string) {
+	_, detected := detectTechStackAndConfig(projectRoot)
+	ignoreDirs = append(append([]string{}, engineDefaultIgnores...), strings.Split(detected, ",")...)
+	if ShardMode(projectRoot) == ShardByLanguage {
+		m := activeShards(projectRoot)
+		if m == nil {
+			m = &ShardManifest{By: ShardByLanguage, Shards: PlanShards(projectRoot, ShardByLanguage)}
+		}
+		for _, sh := range m.Shards {
+			extensions = append(extensions, strings.Split(sh.Extensions, ",")...)
+		}
+	}
+	return ignoreDirs, extensions
+}
+
+// Index 刷新索引 (--mode index)；工作区选中了子项目时只索引这些子项目
 func (ai *ASTIndexer) Index(projectRoot string) (*IndexResult, error) {
+	if dirs := selectedSubProjectDirs(projectRoot); len(dirs) > 0 {
+		return ai.indexSubProjects(projectRoot, dirs, false)
+	}
+	if by := ShardMode(projectRoot); by != "" {
+		return ai.indexShards(projectRoot, by, false)
+	}
 	return ai.indexWithOptions(projectRoot, "", false)
 }
 
 // IndexFull 强制全量索引（禁用 bootstrap）
 func (ai *ASTIndexer) IndexFull(projectRoot string) (*IndexResult, error) {
+	if dirs := selectedSubProjectDirs(projectRoot); len(dirs) > 0 {
+		return ai.indexSubProjects(projectRoot, dirs, true)
+	}
+	if by := ShardMode(projectRoot); by != "" {
+		return ai.indexShards(projectRoot, by, true)
+	}
 	return ai.indexWithOptions(projectRoot, "", true)
 }
 
+func selectedSubProjectDirs(projectRoot string) []string {
+	ws, err := LoadWorkspace(projectRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Index][WARN] 读取工作区失败，索引整个项目: %v\n", err)
+		return nil
+	}
+	return ws.SelectedDirs()
+}
+
+// indexSubProjects 逐个子项目按 scope 索引并汇总结果
+func (ai *ASTIndexer) indexSubProjects(projectRoot string, dirs []string, forceFull bool) (*IndexResult, error) {
+	total := &IndexResult{Status: "success", Strategy: "sub_projects"}
+	for _, dir := range dirs {
+		res, err := ai.indexWithOptions(projectRoot, dir, forceFull)
+		if err != nil {
+			return nil, fmt.Errorf("子项目 %s: %w", dir, err)
+		}
+		addIndexResult(total, res)
+	}
+	return total, nil
+}
+
+// indexWithOptions 按 scope 刷新索引；启用分片时路由到 scope 涉及的分片
 func (ai *ASTIndexer) indexWithOptions(projectRoot string, scope string, forceFull bool) (*IndexResult, error) {
-	dbPath := getDBPath(projectRoot)
-	outputPath := getOutputPath(projectRoot, "index")
+	if by := ShardMode(projectRoot); by != "" {
+		return ai.indexShardScope(projectRoot, by, scope, forceFull, false)
+	}
+	return ai.indexInto(projectRoot, getDBPath(projectRoot), scope, "", "", forceFull, false)
+}
+
+// indexInto 在 liveDB 的临时副本上构建并切换；shardExts 非空时只索引这些扩展名，extraIgnores 追加忽略目录。
+// inPlace 为 true 且 scope 非空时（IndexScope 的小范围刷新）只构建该范围并在事务内合并进线上库，不复制整库
+func (ai *ASTIndexer) indexInto(projectRoot, liveDB, scope, shardExts, extraIgnores string, forceFull, inPlace bool) (*IndexResult, error) {
+	started := time.Now()
+	res, err := ai.buildAndSwap(projectRoot, liveDB, scope, shardExts, extraIgnores, forceFull, inPlace)
+	ai.recordIndexRun(time.Since(started), err)
+	return res, err
+}
+
+func (ai *ASTIndexer) buildAndSwap(projectRoot, liveDB, scope, shardExts, extraIgnores string, forceFull, inPlace bool) (*IndexResult, error) {
+	dbPath := liveDB + indexBuildSuffix
+
+	mu := indexSwapLock(projectRoot)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// 确保数据目录（及分片目录）存在
+	_ = os.MkdirAll(filepath.Dir(liveDB), 0755)
+	removeLegacyResultFiles(projectRoot)
+	defer removeSQLiteFiles(dbPath)
+
+	if inPlace && !forceFull && normalizeIndexScope(scope) != "" && fileExists(liveDB) {
+		removeSQLiteFiles(dbPath)
+		data, err := ai.runIndexBuild(projectRoot, dbPath, scope, shardExts, extraIgnores, forceFull)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateBuildDB(dbPath); err != nil {
+			return nil, fmt.Errorf("索引刷新失败，保留旧索引: %v", err)
+		}
+		err = mergeScopeIntoLive(liveDB, dbPath, scope)
+		if err == nil {
+			ai.InvalidateQueryCache(projectRoot)
+			return ai.finishIndexRun(projectRoot, scope, data), nil
+		}
+		// 线上库结构过旧等情况：回退到复制快照构建再切换
+		fmt.Fprintf(os.Stderr, "[Index][WARN] 原地合并 %s 失败，改为整库构建: %v\n", scope, err)
+	}
+
+	// 在临时库上构建，线上 symbols.db 保持可查询的完整快照
+	prepareBuildDB(liveDB, dbPath)
+	data, err := ai.runIndexBuild(projectRoot, dbPath, scope, shardExts, extraIgnores, forceFull)
+	if err != nil {
+		return nil, err
+	}
 
-	// 确保 .mcp-data 目录存在
-	mcpData := filepath.Join(projectRoot, ".mcp-data")
-	_ = os.MkdirAll(mcpData, 0755)
-	// 清理旧文件
-	_ = os.Remove(outputPath)
+	if err := validateBuildDB(dbPath); err != nil {
+		return nil, fmt.Errorf("索引刷新失败，保留旧索引: %v", err)
+	}
+	if err := swapIndexDB(liveDB, dbPath, liveDB+indexPrevSuffix); err != nil {
+		return nil, fmt.Errorf("索引刷新失败，保留旧索引: %v", err)
+	}
+	ai.InvalidateQueryCache(projectRoot)
+	return ai.finishIndexRun(projectRoot, scope, data), nil
+}
 
+// runIndexBuild 调用引擎把索引构建到 dbPath，返回引擎输出的 JSON 结果
+func (ai *ASTIndexer) runIndexBuild(projectRoot, dbPath, scope, shardExts, extraIgnores string, forceFull bool) ([]byte, error) {
 	// 技术栈检测仅用于忽略目录与失败兜底，不再默认启用扩展白名单
 	extensions, ignoreDirs := detectTechStackAndConfig(projectRoot)
+	if extraIgnores != "" {
+		ignoreDirs = uniqueJoin(append(strings.Split(ignoreDirs, ","), strings.Split(extraIgnores, ",")...))
+	}
 
-	// 第一阶段：默认全量扫描（不传 --extensions），让 Rust 端按真实文件扩展自适应
-	args := buildIndexArgs(projectRoot, dbPath, outputPath, ignoreDirs, extensions, scope, false, forceFull)
-	if err := ai.runIndexCommand(projectRoot, args); err != nil {
+	var data []byte
+	var err error
+	if shardExts != "" {
+		// 语言分片：只用分片自己的扩展名白名单，不做全量扫描兜底
+		args := buildIndexArgs(projectRoot, dbPath, ignoreDirs, shardExts, scope, true, forceFull)
+		if data, err = ai.runIndexCommand(projectRoot, args); err != nil {
+			return nil, fmt.Errorf("索引刷新失败: %v", err)
+		}
+	} else if data, err = ai.runIndexCommand(projectRoot, buildIndexArgs(projectRoot, dbPath, ignoreDirs, extensions, scope, false, forceFull)); err != nil {
+		// 第一阶段默认全量扫描（不传 --extensions），让 Rust 端按真实文件扩展自适应；
 		// 第二阶段：仅在全量扫描失败时，退回到扩展白名单模式
 		if extensions != "" {
-			_ = os.Remove(outputPath)
-			retryArgs := buildIndexArgs(projectRoot, dbPath, outputPath, ignoreDirs, extensions, scope, true, forceFull)
-			if retryErr := ai.runIndexCommand(projectRoot, retryArgs); retryErr != nil {
+			retryArgs := buildIndexArgs(projectRoot, dbPath, ignoreDirs, extensions, scope, true, forceFull)
+			var retryErr error
+			if data, retryErr = ai.runIndexCommand(projectRoot, retryArgs); retryErr != nil {
 				return nil, fmt.Errorf("索引刷新失败: 全量扫描失败(%v); 扩展模式重试失败(%v)", err, retryErr)
 			}
 		} else {
 			return nil, fmt.Errorf("索引刷新失败: %v", err)
 		}
 	}
+	return data, nil
+}
 
-	// 读取输出文件
-	data, err := os.ReadFile(outputPath)
-	if err != nil {
-		// 索引可能不输出文件，返回默认结果
-		result := &IndexResult{Status: "success"}
-		ai.markIndexFresh(projectRoot)
-		return result, nil
+// finishIndexRun 标记索引新鲜并解析引擎输出
+func (ai *ASTIndexer) finishIndexRun(projectRoot, scope string, data []byte) *IndexResult {
+	ai.markIndexFresh(projectRoot)
+	if data == nil {
+		// 索引可能不输出结果，返回默认结果
+		return &IndexResult{Status: "success"}
 	}
 
 	var result IndexResult
 	if err := json.Unmarshal(data, &result); err != nil {
-		fallback := &IndexResult{Status: "success"}
-		ai.markIndexFresh(projectRoot)
-		return fallback, nil
+		return &IndexResult{Status: "success"}
 	}
-
-	ai.markIndexFresh(projectRoot)
-	return &result, nil
+	recordIndexThroughput(projectRoot, scope, &result)
+	return &result
 }
 
 // IndexScope 按目录范围增量刷新索引（用于热点补录）
@@ -921,7 +1233,10 @@ func (ai *ASTIndexer) IndexScope(projectRoot string, scope string) (*IndexResult
 	if scope == "" || scope == "." || scope == "./" {
 		return ai.Index(projectRoot)
 	}
-	return ai.indexWithOptions(projectRoot, scope, false)
+	if by := ShardMode(projectRoot); by != "" {
+		return ai.indexShardScope(projectRoot, by, scope, false, true)
+	}
+	return ai.indexInto(projectRoot, getDBPath(projectRoot), scope, "", "", false, true)
 }
 
 // AnalyzeNamingStyle 分析项目命名风格
@@ -932,35 +1247,47 @@ func (ai *ASTIndexer) AnalyzeNamingStyle(projectRoot string) (*NamingAnalysis, e
 		// 什么也不做
 	}
 
-	dbPath := getDBPath(projectRoot)
-	if !fileExists(dbPath) {
+	if !hasAnyIndex(projectRoot) {
 		return &NamingAnalysis{IsNewProject: true}, nil
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("打开数据库失败: %v", err)
-	}
-	defer db.Close()
-
-	// 2. 统计文件数
+	// 2. 统计文件数 + 3. 提取函数名（分片时逐库累计，函数名合计最多 1000 个）
 	var fileCount int
-	if err := db.QueryRow("SELECT COUNT(*) FROM files").Scan(&fileCount); err != nil {
-		// 可能表不存在
+	var names []string
+	errNoTable := fmt.Errorf("files 表不存在")
+	err := eachIndexDB(projectRoot, "", func(db *sql.DB) error {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM files").Scan(&n); err != nil {
+			// 可能表不存在
+			return errNoTable
+		}
+		fileCount += n
+		if len(names) >= 1000 {
+			return nil
+		}
+		rows, err := db.Query("SELECT name FROM symbols WHERE symbol_type IN ('function', 'method') LIMIT ?", 1000-len(names))
+		if err != nil {
+			return fmt.Errorf("查询符号失败: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			rows.Scan(&name)
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err == errNoTable {
 		return &NamingAnalysis{IsNewProject: true}, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	if fileCount < 3 {
 		return &NamingAnalysis{IsNewProject: true, FileCount: fileCount}, nil
 	}
 
-	// 3. 提取所有函数名
-	rows, err := db.Query("SELECT name FROM symbols WHERE symbol_type IN ('function', 'method') LIMIT 1000")
-	if err != nil {
-		return nil, fmt.Errorf("查询符号失败: %v", err)
-	}
-	defer rows.Close()
-
 	var funcNames []string
 	var snakeCount, camelCount int
 	// reSnake := regexp.MustCompile(`^[a-z0-9_]+$`) // Unused
@@ -968,9 +1295,7 @@ func (ai *ASTIndexer) AnalyzeNamingStyle(projectRoot string) (*NamingAnalysis, e
 
 	prefixCounts := make(map[string]int)
 
-	for rows.Next() {
-		var name string
-		rows.Scan(&name)
+	for _, name := range names {
 		funcNames = append(funcNames, name)
 
 		// 风格判定
@@ -1049,83 +1374,71 @@ type RiskInfo struct {
 type ComplexityReport struct {
 	HighRiskSymbols []RiskInfo `json:"high_risk_symbols"`
 	TotalAnalyzed   int        `json:"total_analyzed"`
+	ElapsedMs       int64      `json:"elapsed_ms"`
 }
 
 // AnalyzeComplexity 分析符号复杂度 (基于调用关系)
 // 简单的中心度分析：Fan-out (出度) 高代表依赖复杂，Fan-in (入度) 高代表影响范围广/责任重
 func (ai *ASTIndexer) AnalyzeComplexity(projectRoot string, symbolNames []string) (*ComplexityReport, error) {
+	return ai.AnalyzeComplexityExcluding(projectRoot, symbolNames, nil)
+}
+
+// AnalyzeComplexityExcluding 同 AnalyzeComplexity，但跳过 tagger 标记为 vendored/generated 的文件中的符号
+func (ai *ASTIndexer) AnalyzeComplexityExcluding(projectRoot string, symbolNames []string, tagger *PathTagger) (*ComplexityReport, error) {
 	if len(symbolNames) == 0 {
 		return &ComplexityReport{}, nil
 	}
 
-	dbPath := getDBPath(projectRoot)
-	if !fileExists(dbPath) {
+	if !hasAnyIndex(projectRoot) {
 		return nil, nil // No DB, no analysis
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
-	if err != nil {
-		return nil, err
-	}
-	defer db.Close()
-
+	started := time.Now()
 	var report ComplexityReport
 	report.TotalAnalyzed = len(symbolNames)
 
-	hasCalleeID := hasColumn(db, "calls", "callee_id")
-
+	wanted := make(map[string]bool, len(symbolNames))
 	for _, name := range symbolNames {
-		// 1. 获取 Symbol 信息（ID + canonical_id）
-		rows, err := db.Query("SELECT symbol_id, symbol_type, canonical_id FROM symbols WHERE name = ?", name)
-		if err != nil {
-			continue
-		}
-
-		type symbolRef struct {
-			id          int
-			canonicalID string
-		}
-		var symbols []symbolRef
-		for rows.Next() {
-			var s symbolRef
-			var sType string
-			if err := rows.Scan(&s.id, &sType, &s.canonicalID); err != nil {
-				continue
-			}
-			if sType == "function" || sType == "method" || sType == "class" {
-				symbols = append(symbols, s)
+		wanted[name] = true
+	}
+
+	// 分片时 symbol_id 只在库内唯一，按 (库序号, ID) 区分
+	type symbolKey struct{ shard, id int }
+	type symbolRef struct {
+		key         symbolKey
+		canonicalID string
+	}
+	symbolsByName := make(map[string][]symbolRef)
+	fanOutByID := make(map[symbolKey]int)
+	fanInByCalleeID := make(map[string]int)
+	fanInByName := make(map[string]int)
+
+	shard := -1
+	err := eachIndexDB(projectRoot, "", func(db *sql.DB) error {
+		shard++
+		return collectComplexityStats(db, func(id int, name, canonicalID, filePath string) {
+			if wanted[name] && !tagger.Excluded(filePath) {
+				symbolsByName[name] = append(symbolsByName[name], symbolRef{symbolKey{shard, id}, canonicalID})
 			}
-		}
-		rows.Close()
+		}, func(callerID, n int) {
+			fanOutByID[symbolKey{shard, callerID}] += n
+		}, fanInByCalleeID, fanInByName)
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	for _, name := range symbolNames {
+		symbols := symbolsByName[name]
 		if len(symbols) == 0 {
 			continue
 		}
 
 		// 聚合所有同名符号的指标
 		var maxFanIn, maxFanOut int
-
 		for _, sym := range symbols {
-			// Fan-out: 我调用了谁 (caller_id = symbol_id)
-			var fanOut int
-			db.QueryRow("SELECT COUNT(*) FROM calls WHERE caller_id = ?", sym.id).Scan(&fanOut)
-			if fanOut > maxFanOut {
-				maxFanOut = fanOut
-			}
-
-			// Fan-in: 优先 callee_id，回退 callee_name
-			var fanIn int
-			if hasCalleeID {
-				db.QueryRow(
-					"SELECT COUNT(*) FROM calls WHERE callee_id = ? OR (callee_id IS NULL AND callee_name = ?)",
-					sym.canonicalID, name,
-				).Scan(&fanIn)
-			} else {
-				db.QueryRow("SELECT COUNT(*) FROM calls WHERE callee_name = ?", name).Scan(&fanIn)
-			}
-			if fanIn > maxFanIn {
-				maxFanIn = fanIn
-			}
+			maxFanOut = max(maxFanOut, fanOutByID[sym.key])
+			maxFanIn = max(maxFanIn, fanInByCalleeID[sym.canonicalID]+fanInByName[name])
 		}
 
 		// 简单的评分模型
@@ -1149,9 +1462,64 @@ func (ai *ASTIndexer) AnalyzeComplexity(projectRoot string, symbolNames []string
 		})
 	}
 
+	report.ElapsedMs = time.Since(started).Milliseconds()
 	return &report, nil
 }
 
+// collectComplexityStats 读取单个索引库的候选符号与调用计数
+// 1. 目标符号（ID + canonical_id）；2. Fan-out 按 caller_id 聚合；
+// 3. Fan-in 优先 callee_id，未解析出 callee_id 的调用回退 callee_name
+func collectComplexityStats(db *sql.DB, onSymbol func(id int, name, canonicalID, filePath string), onFanOut func(callerID, n int), fanInByCalleeID, fanInByName map[string]int) error {
+	rows, err := db.Query(`SELECT s.symbol_id, s.name, s.symbol_type, COALESCE(s.canonical_id, ''), COALESCE(f.file_path, '')
+		FROM symbols s LEFT JOIN files f ON s.file_id = f.file_id
+		WHERE s.symbol_type IN ('function', 'method', 'class')`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		var name, sType, canonicalID, filePath string
+		if err := rows.Scan(&id, &name, &sType, &canonicalID, &filePath); err != nil {
+			continue
+		}
+		onSymbol(id, name, canonicalID, filePath)
+	}
+	rows.Close()
+
+	if rows, err := db.Query("SELECT caller_id, COUNT(*) FROM calls GROUP BY caller_id"); err == nil {
+		for rows.Next() {
+			var id, n int
+			if rows.Scan(&id, &n) == nil {
+				onFanOut(id, n)
+			}
+		}
+		rows.Close()
+	}
+
+	if hasColumn(db, "calls", "callee_id") {
+		rows, err = db.Query("SELECT callee_id, callee_name, COUNT(*) FROM calls GROUP BY callee_id, callee_name")
+	} else {
+		rows, err = db.Query("SELECT NULL, callee_name, COUNT(*) FROM calls GROUP BY callee_name")
+	}
+	if err == nil {
+		for rows.Next() {
+			var calleeID sql.NullString
+			var calleeName string
+			var n int
+			if rows.Scan(&calleeID, &calleeName, &n) != nil {
+				continue
+			}
+			if calleeID.Valid {
+				fanInByCalleeID[calleeID.String] += n
+			} else {
+				fanInByName[calleeName] += n
+			}
+		}
+		rows.Close()
+	}
+	return nil
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -4,9 +4,10 @@ use rayon::prelude::*;
 use rusqlite::{params, Connection, OptionalExtension, Result};
 use serde::{Deserialize, Serialize};
 use sha2::{Digest, Sha256};
+use std::cell::RefCell;
 use std::collections::{HashMap, HashSet};
 use std::fs;
-use std::io::Write;
+use std::io::{BufRead, Write};
 use std::path::{Path, PathBuf};
 use std::sync::{
     atomic::{AtomicUsize, Ordering},
@@ -29,7 +30,7 @@ struct Args {
     #[arg(short, long)]
     db: String,
 
-    /// Mode: index, map, query, structure, analyze, snapshot, diff
+    /// Mode: index, map, query, structure, analyze, snapshot, diff, serve
     #[arg(short, long, default_value = "index")]
     mode: String,
 
@@ -41,7 +42,7 @@ struct Args {
     #[arg(short, long)]
     extensions: Option<String>,
 
-    /// Output path for JSON result
+    /// Output path for JSON result (default / "-": one JSON line on stdout)
     #[arg(short, long)]
     output: Option<String>,
 
@@ -235,7 +236,7 @@ fn init_db(conn: &Connection) -> Result<()> {
 
     if !scope_path_exists {
         conn.execute("ALTER TABLE symbols ADD COLUMN scope_path TEXT", [])?;
-        println!("[Migration] Added symbols.scope_path column");
+        eprintln!("[Migration] Added symbols.scope_path column");
     }
 
     // 检查 calls.callee_id 是否存在
@@ -250,7 +251,7 @@ fn init_db(conn: &Connection) -> Result<()> {
 
     if !callee_id_exists {
         conn.execute("ALTER TABLE calls ADD COLUMN callee_id TEXT", [])?;
-        println!("[Migration] Added calls.callee_id column");
+        eprintln!("[Migration] Added calls.callee_id column");
     }
 
     // files 增量字段：file_size, file_mtime
@@ -267,7 +268,7 @@ fn init_db(conn: &Connection) -> Result<()> {
             "ALTER TABLE files ADD COLUMN file_size INTEGER DEFAULT 0",
             [],
         )?;
-        println!("[Migration] Added files.file_size column");
+        eprintln!("[Migration] Added files.file_size column");
     }
 
     let file_mtime_exists: bool = conn
@@ -283,7 +284,7 @@ fn init_db(conn: &Connection) -> Result<()> {
             "ALTER TABLE files ADD COLUMN file_mtime INTEGER DEFAULT 0",
             [],
         )?;
-        println!("[Migration] Added files.file_mtime column");
+        eprintln!("[Migration] Added files.file_mtime column");
     }
 
     let index_level_exists: bool = conn
@@ -299,7 +300,7 @@ fn init_db(conn: &Connection) -> Result<()> {
             "ALTER TABLE files ADD COLUMN index_level TEXT DEFAULT 'symbol'",
             [],
         )?;
-        println!("[Migration] Added files.index_level column");
+        eprintln!("[Migration] Added files.index_level column");
     }
 
     let indexed_at_exists: bool = conn
@@ -315,7 +316,7 @@ fn init_db(conn: &Connection) -> Result<()> {
             "ALTER TABLE files ADD COLUMN indexed_at INTEGER DEFAULT 0",
             [],
         )?;
-        println!("[Migration] Added files.indexed_at column");
+        eprintln!("[Migration] Added files.indexed_at column");
     }
 
     // 新增索引（幂等）
@@ -342,8 +343,10 @@ fn main() -> anyhow::Result<()> {
     let args = Args::parse();
     let project_path = Path::new(&args.project);
 
-    // Heartbeat setup
-    let mcp_data = project_path.join(".mcp-data");
+    // Heartbeat setup (MPM_DATA_DIR is the data dir resolved by the Go server)
+    let mcp_data = std::env::var_os("MPM_DATA_DIR")
+        .map(PathBuf::from)
+        .unwrap_or_else(|| project_path.join(".mcp-data"));
     let _ = fs::create_dir_all(&mcp_data);
     let heartbeat_path = mcp_data.join("heartbeat");
 
@@ -361,13 +364,115 @@ fn main() -> anyhow::Result<()> {
         run_diff(&args)?;
     } else if args.mode == "structure" {
         run_structure(&args)?;
+    } else if args.mode == "serve" {
+        run_serve()?;
     }
 
     Ok(())
 }
 
+thread_local! {
+    /// Set by serve mode: write_output collects the result here instead of stdout.
+    static CAPTURE: RefCell<Option<Vec<u8>>> = RefCell::new(None);
+}
+
+/// Write the JSON result: to `--output <path>` when given, otherwise as a single
+/// line on stdout (the Go server reads stdout; diagnostics go to stderr).
+fn write_output<T: Serialize>(args: &Args, value: &T) -> anyhow::Result<()> {
+    match &args.output {
+        Some(out_path) if out_path != "-" => {
+            let f = fs::File::create(out_path)?;
+            serde_json::to_writer(f, value)?;
+        }
+        _ => {
+            let captured = CAPTURE.with(|c| match c.borrow_mut().as_mut() {
+                Some(buf) => serde_json::to_writer(buf, value).map(|_| true),
+                None => Ok(false),
+            })?;
+            if !captured {
+                let stdout = std::io::stdout();
+                let mut lock = stdout.lock();
+                serde_json::to_writer(&mut lock, value)?;
+                writeln!(lock)?;
+                lock.flush()?;
+            }
+        }
+    }
+    Ok(())
+}
+
+// ============================================================================
+// Serve Mode - long-lived daemon driven by the Go server
+// ============================================================================
+
+#[derive(Deserialize)]
+struct ServeRequest {
+    #[serde(default)]
+    id: u64,
+    #[serde(default)]
+    args: Vec<String>,
+}
+
+/// One JSON request per stdin line: {"id":1,"args":["--mode","query",...]}
+/// (empty args = ping). One JSON response per stdout line:
+/// {"id":1,"ok":true,"result":{...}} or {"id":1,"ok":false,"error":"..."}.
+/// Exits when stdin is closed, i.e. when the Go server goes away.
+fn run_serve() -> anyhow::Result<()> {
+    let stdin = std::io::stdin();
+    let stdout = std::io::stdout();
+    for line in stdin.lock().lines() {
+        let line = line?;
+        if line.trim().is_empty() {
+            continue;
+        }
+        let resp = match serde_json::from_str::<ServeRequest>(&line) {
+            Ok(req) => serve_one(req),
+            Err(e) => {
+                serde_json::json!({"id": 0, "ok": false, "error": format!("bad request: {}", e)})
+            }
+        };
+        let mut out = stdout.lock();
+        serde_json::to_writer(&mut out, &resp)?;
+        writeln!(out)?;
+        out.flush()?;
+    }
+    Ok(())
+}
+
+fn serve_one(req: ServeRequest) -> serde_json::Value {
+    if req.args.is_empty() {
+        return serde_json::json!({"id": req.id, "ok": true, "result": {"status": "pong"}});
+    }
+    let argv = std::iter::once("ast_indexer".to_string()).chain(req.args);
+    let args = match Args::try_parse_from(argv) {
+        Ok(a) => a,
+        Err(e) => return serde_json::json!({"id": req.id, "ok": false, "error": e.to_string()}),
+    };
+    CAPTURE.with(|c| *c.borrow_mut() = Some(Vec::new()));
+    let res = match args.mode.as_str() {
+        "query" => run_query(&args),
+        "map" => run_map(&args),
+        "analyze" => run_analyze(&args),
+        "structure" => run_structure(&args),
+        other => Err(anyhow::anyhow!(
+            "mode {} is not served by the daemon",
+            other
+        )),
+    };
+    let buf = CAPTURE.with(|c| c.borrow_mut().take()).unwrap_or_default();
+    match res {
+        Ok(()) => match serde_json::from_slice::<serde_json::Value>(&buf) {
+            Ok(v) => serde_json::json!({"id": req.id, "ok": true, "result": v}),
+            Err(e) => {
+                serde_json::json!({"id": req.id, "ok": false, "error": format!("no result: {}", e)})
+            }
+        },
+        Err(e) => serde_json::json!({"id": req.id, "ok": false, "error": format!("{:#}", e)}),
+    }
+}
+
 fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
-    println!("Starting indexer for: {}", args.project);
+    eprintln!("Starting indexer for: {}", args.project);
 
     // 1. Setup DB
     let mut conn = Connection::open(&args.db)?;
@@ -456,7 +561,7 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
         })
         .unwrap_or_default();
 
-    println!("Scanning directory...");
+    eprintln!("Scanning directory...");
     let entries: Vec<PathBuf> = builder
         .build()
         .filter_map(|e| e.ok())
@@ -472,7 +577,7 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
         })
         .collect();
 
-    println!("Found {} files", entries.len());
+    eprintln!("Found {} files", entries.len());
 
     // 3. Process Files (Linear for DB safety, Rayon can be used for parsing if we separate Read/Write)
     // To keep it simple and safe for MVP: Sync Loop but fast because Tree-sitter is fast.
@@ -491,7 +596,7 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
     // We wrap it in Arc for cheap sharing.
     let parsers_arc = Arc::new(parsers_setup);
 
-    println!("Found {} files", entries.len());
+    eprintln!("Found {} files", entries.len());
 
     // 4. Pre-load file metadata (Optimization)
     #[derive(Clone)]
@@ -556,7 +661,7 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
     } else {
         "full_or_incremental"
     };
-    println!(
+    eprintln!(
         "Index strategy: {} (total_files={}, threshold={}, parse_budget={})",
         strategy, total, huge_threshold, bootstrap_parse_budget
     );
@@ -1054,7 +1159,7 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
              WHERE callee_id IS NULL",
             [],
         )?;
-        println!("[Linking] Updated {} call edges with callee_id", linked);
+        eprintln!("[Linking] Updated {} call edges with callee_id", linked);
     }
 
     // ========================================================================
@@ -1083,7 +1188,7 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
         }
 
         if deleted_count > 0 {
-            println!(
+            eprintln!(
                 "[Cleanup] Removed {} stale file entries from index",
                 deleted_count
             );
@@ -1105,24 +1210,21 @@ fn run_indexer(args: &Args, heartbeat_path: &Path) -> anyhow::Result<()> {
     let meta_files = meta_counter.load(Ordering::Relaxed);
     let skipped_files = skipped_counter.load(Ordering::Relaxed);
 
-    println!(
+    eprintln!(
         "Indexing completed. Processed {} files. parsed={}, meta={}, skipped={}, strategy={}",
         processed_count, parsed_files, meta_files, skipped_files, strategy
     );
     // Write Output
-    if let Some(out_path) = &args.output {
-        let result = IndexResult {
-            status: "success".into(),
-            total_files: total,
-            parsed_files,
-            meta_files,
-            skipped_files,
-            strategy: strategy.to_string(),
-            elapsed_ms: 0,
-        };
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &result)?;
-    }
+    let result = IndexResult {
+        status: "success".into(),
+        total_files: total,
+        parsed_files,
+        meta_files,
+        skipped_files,
+        strategy: strategy.to_string(),
+        elapsed_ms: 0,
+    };
+    write_output(args, &result)?;
 
     Ok(())
 }
@@ -1620,18 +1722,15 @@ fn run_query(args: &Args) -> anyhow::Result<()> {
     }
 
     // 输出结果
-    if let Some(out_path) = &args.output {
-        let res = QueryResult {
-            status: "success".to_string(),
-            query: args.query.clone().unwrap_or_default(),
-            found_symbol: found,
-            match_type: match_type_str,
-            candidates: candidates,
-            related_nodes: related,
-        };
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &res)?;
-    }
+    let res = QueryResult {
+        status: "success".to_string(),
+        query: args.query.clone().unwrap_or_default(),
+        found_symbol: found,
+        match_type: match_type_str,
+        candidates: candidates,
+        related_nodes: related,
+    };
+    write_output(args, &res)?;
 
     Ok(())
 }
@@ -1765,15 +1864,12 @@ fn run_map(args: &Args) -> anyhow::Result<()> {
         }
     };
 
-    if let Some(out_path) = &args.output {
-        let res = MapResult {
-            statistics: stats,
-            structure,
-            elapsed: "0s".to_string(),
-        };
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &res)?;
-    }
+    let res = MapResult {
+        statistics: stats,
+        structure,
+        elapsed: "0s".to_string(),
+    };
+    write_output(args, &res)?;
 
     Ok(())
 }
@@ -1987,11 +2083,8 @@ fn run_analyze(args: &Args) -> anyhow::Result<()> {
         Some(n) => n,
         None => {
             // Return empty/error JSON
-            if let Some(out_path) = &args.output {
-                let err = serde_json::json!({"status": "error", "message": "Symbol not found"});
-                let f = fs::File::create(out_path)?;
-                serde_json::to_writer(f, &err)?;
-            }
+            let err = serde_json::json!({"status": "error", "message": "Symbol not found"});
+            write_output(args, &err)?;
             return Ok(());
         }
     };
@@ -2005,7 +2098,7 @@ fn run_analyze(args: &Args) -> anyhow::Result<()> {
 
     // Query all calls: caller_id -> callee_id (优先) / callee_name (回退兼容)
 
-    println!("Building dependency graph...");
+    eprintln!("Building dependency graph...");
 
     // 🆕 使用 canonical_id (String) 而不是 symbol_id (i64)
     // Load all symbols into Map: Name -> Vec<canonical_id>
@@ -2224,10 +2317,7 @@ fn run_analyze(args: &Args) -> anyhow::Result<()> {
         modification_checklist: checklist,
     };
 
-    if let Some(out_path) = &args.output {
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &final_res)?;
-    }
+    write_output(args, &final_res)?;
 
     Ok(())
 }
@@ -2337,12 +2427,7 @@ fn run_snapshot(args: &Args) -> anyhow::Result<()> {
         symbols: symbols_map,
     };
 
-    if let Some(out_path) = &args.output {
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &snapshot)?;
-    } else {
-        // Print to stdout? No, binary output usually silent unless error.
-    }
+    write_output(args, &snapshot)?;
 
     Ok(())
 }
@@ -2441,10 +2526,7 @@ fn run_diff(args: &Args) -> anyhow::Result<()> {
         details,
     };
 
-    if let Some(out_path) = &args.output {
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &res)?;
-    }
+    write_output(args, &res)?;
 
     Ok(())
 }
@@ -2599,10 +2681,7 @@ fn run_structure(args: &Args) -> anyhow::Result<()> {
         structure,
     };
 
-    if let Some(out_path) = &args.output {
-        let f = fs::File::create(out_path)?;
-        serde_json::to_writer(f, &result)?;
-    }
+    write_output(args, &result)?;
 
     Ok(())
 }
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -70,7 +73,7 @@ func TestDetectTechStackAndConfig_GoDoesNotIgnorePkgDir(t *testing.T) {
 }
 
 func TestBuildIndexArgs_DefaultDoesNotPassExtensions(t *testing.T) {
-	args := buildIndexArgs("D:/repo", "D:/repo/.mcp-data/symbols.db", "D:/repo/.mcp-data/.ast_result_index.json", "node_modules,.git", "go,py", "", false, false)
+	args := buildIndexArgs("D:/repo", "D:/repo/.mcp-data/symbols.db", "node_modules,.git", "go,py", "", false, false)
 
 	if hasArg(args, "--extensions") {
 		t.Fatalf("default args should not include --extensions, got %v", args)
@@ -78,10 +81,40 @@ func TestBuildIndexArgs_DefaultDoesNotPassExtensions(t *testing.T) {
 	if !hasArg(args, "--ignore-dirs") {
 		t.Fatalf("expected --ignore-dirs in args, got %v", args)
 	}
+	if hasArg(args, "--output") {
+		t.Fatalf("results are read from stdout, --output should not be passed: %v", args)
+	}
+}
+
+func TestRunEngine_ReadsResultFromStdout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake engine is a shell script")
+	}
+	root := t.TempDir()
+	bin := filepath.Join(root, "fake_indexer")
+	script := "#!/bin/sh\necho 'Scanning directory...'\necho 'progress' >&2\nif [ \"$2\" = fail ]; then echo 'db locked' >&2; exit 3; fi\necho '{\"status\":\"success\",\"total_files\":2}'\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ai := &ASTIndexer{BinaryPath: bin}
+
+	data, err := ai.runEngine(context.Background(), root, []string{"--mode", "index"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"status":"success","total_files":2}` {
+		t.Errorf("expected last stdout line as result, got %q", data)
+	}
+	if _, err := ai.runEngine(context.Background(), root, []string{"--mode", "fail"}); err == nil || !strings.Contains(err.Error(), "db locked") {
+		t.Errorf("stderr should be surfaced on failure, got %v", err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 1 {
+		t.Errorf("no temp result files expected, got %d entries", len(entries))
+	}
 }
 
 func TestBuildIndexArgs_RetryCanPassExtensions(t *testing.T) {
-	args := buildIndexArgs("D:/repo", "D:/repo/.mcp-data/symbols.db", "D:/repo/.mcp-data/.ast_result_index.json", "node_modules,.git", "go,py", "", true, false)
+	args := buildIndexArgs("D:/repo", "D:/repo/.mcp-data/symbols.db", "node_modules,.git", "go,py", "", true, false)
 
 	if !hasArg(args, "--extensions") {
 		t.Fatalf("retry args should include --extensions, got %v", args)
@@ -92,9 +125,93 @@ func TestBuildIndexArgs_RetryCanPassExtensions(t *testing.T) {
 }
 
 func TestBuildIndexArgs_ForceFullAddsFlag(t *testing.T) {
-	args := buildIndexArgs("D:/repo", "D:/repo/.mcp-data/symbols.db", "D:/repo/.mcp-data/.ast_result_index.json", "node_modules,.git", "go,py", "", false, true)
+	args := buildIndexArgs("D:/repo", "D:/repo/.mcp-data/symbols.db", "node_modules,.git", "go,py", "", false, true)
 
 	if !hasArg(args, "--force-full") {
 		t.Fatalf("expected --force-full in args, got %v", args)
 	}
 }
+
+func TestAnalyzeComplexity_AggregatesFanInAndFanOut(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".mcp-data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", getDBPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts := []string{
+		`CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)`,
+		`CREATE TABLE symbols (symbol_id INTEGER PRIMARY KEY, name TEXT, symbol_type TEXT, canonical_id TEXT, file_id INTEGER, line_start INTEGER)`,
+		`CREATE TABLE calls (caller_id INTEGER, callee_name TEXT, callee_id TEXT, call_line INTEGER)`,
+		`INSERT INTO files VALUES (1, 'pkg/a.go'), (2, 'vendor/lib/b.go')`,
+		`INSERT INTO symbols VALUES (1, 'hub', 'function', 'pkg.hub', 1, 1), (2, 'leaf', 'function', 'pkg.leaf', 1, 10), (3, 'hub', 'function', 'lib.hub', 2, 1)`,
+		`INSERT INTO calls VALUES (1, 'leaf', 'pkg.leaf', 2), (1, 'fmt', NULL, 3), (2, 'hub', 'pkg.hub', 11), (3, 'hub', NULL, 2)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	db.Close()
+
+	report, err := NewASTIndexer().AnalyzeComplexityExcluding(root, []string{"hub", "leaf"}, NewPathTagger(nil, nil))
+	if err != nil {
+		t.Fatalf("AnalyzeComplexity failed: %v", err)
+	}
+	scores := map[string]float64{}
+	for _, r := range report.HighRiskSymbols {
+		scores[r.SymbolName] = r.Score
+	}
+	// hub: fan-out 2，fan-in = callee_id 命中 1 + 按名回退 1；vendored 的 lib.hub 被排除
+	if scores["hub"] != 3 {
+		t.Errorf("hub score = %v, want 3", scores["hub"])
+	}
+	// leaf: fan-out 1，fan-in 1
+	if scores["leaf"] != 1.5 {
+		t.Errorf("leaf score = %v, want 1.5", scores["leaf"])
+	}
+}
+
+func TestScanProjectExtensions_SymlinkCycleAndOutsideLinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pkg", "inner"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "inner", "main.go"), []byte("package inner"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "lib.rs"), []byte("fn main() {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(outside, "crate"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "crate", "Cargo.toml"), []byte("[package]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 环：pkg/inner/loop -> pkg；外部链接：pkg/ext -> 项目外目录
+	if err := os.Symlink(filepath.Join(root, "pkg"), filepath.Join(root, "pkg", "inner", "loop")); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "pkg", "ext")); err != nil {
+		t.Fatal(err)
+	}
+
+	exts := scanProjectExtensions(root, nil, 8)
+	if !exts["go"] {
+		t.Error("expected go extension")
+	}
+	if exts["rs"] {
+		t.Error("symlink to outside project must not be followed")
+	}
+	report := LastScanReport(root)
+	if report == nil || report.SymlinksSkipped < 2 {
+		t.Errorf("expected skipped cycle and outside link, got %+v", report)
+	}
+	if hasRustProject(root) {
+		t.Error("Cargo detection must not follow outside links")
+	}
+}
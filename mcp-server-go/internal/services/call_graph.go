@@ -0,0 +1,269 @@
+package services
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// ============================================================================
+// 调用图加载 (symbols + calls 全量读入内存，供报告/环检测等离线分析使用)
+// ============================================================================
+
+// GraphSymbol 调用图中的符号节点
+type GraphSymbol struct {
+	SymbolID    int    `json:"symbol_id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	FilePath    string `json:"file_path"`
+	LineStart   int    `json:"line_start"`
+	LineEnd     int    `json:"line_end,omitempty"`
+	CanonicalID string `json:"canonical_id"`
+	Signature   string `json:"signature,omitempty"`
+}
+
+// UnresolvedCall 无法解析到项目内符号的调用（外部库、动态分发或拼写错误）
+type UnresolvedCall struct {
+	CalleeName string `json:"callee_name"`
+	CallerName string `json:"caller_name"`
+	FilePath   string `json:"file_path"`
+	CallLine   int    `json:"call_line"`
+}
+
+// CallGraph 符号级调用图
+type CallGraph struct {
+	Symbols    map[int]*GraphSymbol
+	Edges      map[int][]int // caller -> callees（已解析、去重）
+	FanIn      map[int]int
+	FanOut     map[int]int
+	Unresolved []UnresolvedCall
+	TotalCalls int
+}
+
+// isCallableType 参与调用图的符号类型
+func isCallableType(t string) bool {
+	return t == "function" || t == "method" || t == "class"
+}
+
+// shardIDStride 多个分片库合并时各库 symbol_id 的偏移量，避免不同库的自增 ID 冲突
+const shardIDStride = 1 << 40
+
+// loadCallGraph 从 symbols.db（分片模式为多个分片库）构建调用图
+// 先读入全部库的符号再解析调用，跨分片的调用按名称解析
+func loadCallGraph(dbs ...*sql.DB) (*CallGraph, error) {
+	g := &CallGraph{
+		Symbols: make(map[int]*GraphSymbol),
+		Edges:   make(map[int][]int),
+		FanIn:   make(map[int]int),
+		FanOut:  make(map[int]int),
+	}
+	byName := make(map[string][]int)
+	byCanonical := make(map[string]int)
+	allNames := make(map[string]bool)
+	for i, db := range dbs {
+		if err := loadGraphSymbols(g, db, i*shardIDStride, byName, byCanonical, allNames); err != nil {
+			return nil, err
+		}
+	}
+	seenEdge := make(map[[2]int]bool)
+	for i, db := range dbs {
+		if err := loadGraphCalls(g, db, i*shardIDStride, byName, byCanonical, allNames, seenEdge); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func loadGraphSymbols(g *CallGraph, db *sql.DB, offset int, byName map[string][]int, byCanonical map[string]int, allNames map[string]bool) error {
+	signatureExpr := "''"
+	if hasColumn(db, "symbols", "signature") {
+		signatureExpr = "COALESCE(s.signature, '')"
+	}
+	lineEndExpr := "0"
+	if hasColumn(db, "symbols", "line_end") {
+		lineEndExpr = "COALESCE(s.line_end, 0)"
+	}
+	rows, err := db.Query(`
+		SELECT s.symbol_id, s.name, s.symbol_type, COALESCE(f.file_path, ''),
+		       COALESCE(s.line_start, 0), COALESCE(s.canonical_id, ''), ` + signatureExpr + `, ` + lineEndExpr + `
+		FROM symbols s LEFT JOIN files f ON s.file_id = f.file_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s GraphSymbol
+		if err := rows.Scan(&s.SymbolID, &s.Name, &s.Type, &s.FilePath, &s.LineStart, &s.CanonicalID, &s.Signature, &s.LineEnd); err != nil {
+			continue
+		}
+		s.SymbolID += offset
+		allNames[s.Name] = true
+		if !isCallableType(s.Type) {
+			continue
+		}
+		sym := s
+		g.Symbols[s.SymbolID] = &sym
+		byName[s.Name] = append(byName[s.Name], s.SymbolID)
+		if s.CanonicalID != "" {
+			byCanonical[s.CanonicalID] = s.SymbolID
+		}
+	}
+	return rows.Err()
+}
+
+func loadGraphCalls(g *CallGraph, db *sql.DB, offset int, byName map[string][]int, byCanonical map[string]int, allNames map[string]bool, seenEdge map[[2]int]bool) error {
+	calleeIDExpr := "NULL"
+	if hasColumn(db, "calls", "callee_id") {
+		calleeIDExpr = "callee_id"
+	}
+	callRows, err := db.Query("SELECT caller_id, callee_name, COALESCE(call_line, 0), " + calleeIDExpr + " FROM calls")
+	if err != nil {
+		return err
+	}
+	defer callRows.Close()
+
+	for callRows.Next() {
+		var callerID, line int
+		var calleeName string
+		var calleeID sql.NullString
+		if err := callRows.Scan(&callerID, &calleeName, &line, &calleeID); err != nil {
+			continue
+		}
+		g.TotalCalls++
+		callerID += offset
+		caller, ok := g.Symbols[callerID]
+		if !ok {
+			continue
+		}
+
+		var targets []int
+		if calleeID.Valid && calleeID.String != "" {
+			if id, ok := byCanonical[calleeID.String]; ok {
+				targets = []int{id}
+			}
+		}
+		if len(targets) == 0 {
+			targets = byName[calleeName]
+		}
+		if len(targets) == 0 {
+			if !allNames[calleeName] {
+				g.Unresolved = append(g.Unresolved, UnresolvedCall{
+					CalleeName: calleeName,
+					CallerName: caller.Name,
+					FilePath:   caller.FilePath,
+					CallLine:   line,
+				})
+			}
+			continue
+		}
+
+		g.FanOut[callerID]++
+		for _, t := range targets {
+			key := [2]int{callerID, t}
+			if seenEdge[key] {
+				continue
+			}
+			seenEdge[key] = true
+			g.Edges[callerID] = append(g.Edges[callerID], t)
+			g.FanIn[t]++
+		}
+	}
+	return callRows.Err()
+}
+
+// StronglyConnected 使用 Tarjan 算法返回规模 >= 2 的强连通分量（即调用环）
+// 自递归（单节点自环）不计入，避免把普通递归误报为循环依赖
+func (g *CallGraph) StronglyConnected() [][]int {
+	index := 0
+	indices := make(map[int]int)
+	lowlink := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var result [][]int
+
+	ids := make([]int, 0, len(g.Symbols))
+	for id := range g.Symbols {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var strongConnect func(v int)
+	strongConnect = func(v int) {
+		indices[v] = index
+		lowlink[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.Edges[v] {
+			if _, visited := indices[w]; !visited {
+				strongConnect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && indices[w] < lowlink[v] {
+				lowlink[v] = indices[w]
+			}
+		}
+
+		if lowlink[v] == indices[v] {
+			var comp []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp = append(comp, w)
+				if w == v {
+					break
+				}
+			}
+			if len(comp) >= 2 {
+				sort.Ints(comp)
+				result = append(result, comp)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if _, visited := indices[id]; !visited {
+			strongConnect(id)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return len(result[i]) > len(result[j]) })
+	return result
+}
+
+// CycleContaining 返回包含 ids 中任一符号的调用环，不在环中时返回 nil
+func (g *CallGraph) CycleContaining(ids []int) []int {
+	want := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	for _, comp := range g.StronglyConnected() {
+		for _, id := range comp {
+			if want[id] {
+				return comp
+			}
+		}
+	}
+	return nil
+}
+
+// LoadCallGraph 打开项目索引库（分片模式为全部分片）并加载调用图
+func (ai *ASTIndexer) LoadCallGraph(projectRoot string) (*CallGraph, error) {
+	dbs, closeAll, err := openIndexDBs(projectRoot, "")
+	if err != nil {
+		return nil, err
+	}
+	defer closeAll()
+	return loadCallGraph(dbs...)
+}
+
+// EdgeCount 已解析的调用边数量
+func (g *CallGraph) EdgeCount() int {
+	n := 0
+	for _, callees := range g.Edges {
+		n += len(callees)
+	}
+	return n
+}
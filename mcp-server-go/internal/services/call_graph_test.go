@@ -0,0 +1,76 @@
+package services
+
+import "testing"
+
+func TestCallGraphStronglyConnected_IgnoresSelfRecursion(t *testing.T) {
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "a"},
+			2: {SymbolID: 2, Name: "b"},
+			3: {SymbolID: 3, Name: "c"},
+			4: {SymbolID: 4, Name: "rec"},
+		},
+		Edges: map[int][]int{
+			1: {2},
+			2: {3},
+			3: {1},
+			4: {4},
+		},
+	}
+
+	sccs := g.StronglyConnected()
+	if len(sccs) != 1 {
+		t.Fatalf("expected 1 cycle, got %d: %v", len(sccs), sccs)
+	}
+	if len(sccs[0]) != 3 {
+		t.Fatalf("expected cycle of 3 symbols, got %v", sccs[0])
+	}
+}
+
+func TestCallGraphDeadCandidates_SkipsEntryPointsAndSelfCalls(t *testing.T) {
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "main", FilePath: "cmd/main.go"},
+			2: {SymbolID: 2, Name: "used", FilePath: "pkg/a.go"},
+			3: {SymbolID: 3, Name: "orphan", FilePath: "pkg/a.go", LineStart: 10},
+			4: {SymbolID: 4, Name: "loop", FilePath: "pkg/a.go", LineStart: 20},
+			5: {SymbolID: 5, Name: "TestUsed", FilePath: "pkg/a_test.go"},
+		},
+		Edges: map[int][]int{
+			1: {2},
+			4: {4},
+		},
+	}
+
+	dead := g.DeadCandidates()
+	if len(dead) != 2 || dead[0] != 3 || dead[1] != 4 {
+		t.Fatalf("expected dead candidates [3 4], got %v", dead)
+	}
+}
+
+func TestCallGraphCycleContaining(t *testing.T) {
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "a", FilePath: "x/a.go"},
+			2: {SymbolID: 2, Name: "b", FilePath: "y/b.go"},
+			3: {SymbolID: 3, Name: "c", FilePath: "x/a.go"},
+			4: {SymbolID: 4, Name: "leaf", FilePath: "z/leaf.go"},
+		},
+		Edges: map[int][]int{
+			1: {2},
+			2: {3},
+			3: {1, 4},
+		},
+	}
+
+	comp := g.CycleContaining([]int{2})
+	if len(comp) != 3 {
+		t.Fatalf("expected b to be in a 3-symbol cycle, got %v", comp)
+	}
+	if cycle := g.ReportCycle(comp); len(cycle.Files) != 2 || cycle.Files[0] != "x/a.go" {
+		t.Fatalf("cycle files = %v", cycle.Files)
+	}
+	if comp := g.CycleContaining([]int{4}); comp != nil {
+		t.Fatalf("leaf is not in a cycle, got %v", comp)
+	}
+}
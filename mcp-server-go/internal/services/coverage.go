@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 测试覆盖率叠加：解析 go coverprofile / lcov / coverage.py (json 或 Cobertura xml)
+// ============================================================================
+//
+// 覆盖率文件由项目自己的测试流程生成（go test -coverprofile=coverage.out、
+// nyc/jest 的 lcov.info、coverage json/xml），这里只负责读取并映射到项目相对路径。
+// 未显式配置时在项目根目录及一级子目录中按常见文件名查找，多个文件合并。
+
+// coverageFileNames 自动发现的覆盖率文件名
+var coverageFileNames = []string{
+	"coverage.out", "cover.out", "c.out", "coverage.txt", "profile.cov",
+	"lcov.info", filepath.Join("coverage", "lcov.info"),
+	"coverage.json", "coverage.xml", "cobertura.xml",
+}
+
+// CoverBlock 一段可执行代码（go 的语句块；行级格式为单行）
+type CoverBlock struct {
+	Start int
+	End   int
+	Stmts int
+	Hit   bool
+}
+
+// FileCoverage 单个文件的覆盖数据
+type FileCoverage struct {
+	Blocks []CoverBlock
+}
+
+// CoverageData 项目覆盖率（键为项目相对路径，正斜杠）
+type CoverageData struct {
+	Files   map[string]*FileCoverage
+	Sources []string  // 读取的覆盖率文件（项目相对路径）
+	ModTime time.Time // 最新覆盖率文件的修改时间
+}
+
+// Stats 行号范围 [start, end] 内（end<=0 表示整个文件）已覆盖/总语句数
+func (f *FileCoverage) Stats(start, end int) (covered, total int) {
+	for _, b := range f.Blocks {
+		if end > 0 && (b.Start < start || b.Start > end) {
+			continue
+		}
+		total += b.Stmts
+		if b.Hit {
+			covered += b.Stmts
+		}
+	}
+	return covered, total
+}
+
+func coveragePct(covered, total int) (float64, bool) {
+	if total == 0 {
+		return 0, false
+	}
+	return float64(covered) * 100 / float64(total), true
+}
+
+func normalizeCoveragePath(p string) string {
+	return strings.TrimPrefix(path.Clean(strings.ReplaceAll(p, "\\", "/")), "./")
+}
+
+// RangePct 文件中行号范围的覆盖率；文件不在覆盖率数据中时 ok=false
+func (c *CoverageData) RangePct(file string, start, end int) (float64, bool) {
+	fc, ok := c.Files[normalizeCoveragePath(file)]
+	if !ok {
+		return 0, false
+	}
+	return coveragePct(fc.Stats(start, end))
+}
+
+// FilePct 文件覆盖率
+func (c *CoverageData) FilePct(file string) (float64, bool) {
+	return c.RangePct(file, 0, 0)
+}
+
+// DirPct 目录（含子目录）覆盖率；dir 为空或 "." 表示整个项目
+func (c *CoverageData) DirPct(dir string) (float64, bool) {
+	dir = normalizeCoveragePath(dir)
+	var covered, total int
+	for p, fc := range c.Files {
+		if dir != "." && dir != "" && !strings.HasPrefix(p, dir+"/") {
+			continue
+		}
+		cv, t := fc.Stats(0, 0)
+		covered += cv
+		total += t
+	}
+	return coveragePct(covered, total)
+}
+
+// LoadCoverage 读取项目覆盖率；extra 为额外指定的文件（相对项目根目录），为空时自动发现。
+// 未找到任何覆盖率文件时返回 nil, nil
+func LoadCoverage(projectRoot string, extra []string) (*CoverageData, error) {
+	root := normalizeProjectRoot(projectRoot)
+	files := make([]string, 0, len(extra))
+	for _, f := range extra {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(root, f)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		files = discoverCoverageFiles(root)
+	}
+
+	data := &CoverageData{Files: make(map[string]*FileCoverage)}
+	var errs []string
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		res := newCoveragePathResolver(root, filepath.Dir(f))
+		if err := parseCoverageFile(f, res, data); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", filepath.Base(f), err))
+			continue
+		}
+		rel, _ := filepath.Rel(root, f)
+		data.Sources = append(data.Sources, filepath.ToSlash(rel))
+		if info.ModTime().After(data.ModTime) {
+			data.ModTime = info.ModTime()
+		}
+	}
+	if len(data.Sources) == 0 {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("读取覆盖率失败: %s", strings.Join(errs, "; "))
+		}
+		return nil, nil
+	}
+	return data, nil
+}
+
+// discoverCoverageFiles 在项目根目录及一级子目录中查找常见覆盖率文件
+func discoverCoverageFiles(root string) []string {
+	dirs := []string{root}
+	if entries, err := os.ReadDir(root); err == nil {
+		_, ignoreDirs := detectTechStackAndConfig(root)
+		ignore := make(map[string]bool)
+		for _, d := range strings.Split(ignoreDirs, ",") {
+			ignore[d] = true
+		}
+		for _, e := range entries {
+			if e.IsDir() && !strings.HasPrefix(e.Name(), ".") && !ignore[e.Name()] {
+				dirs = append(dirs, filepath.Join(root, e.Name()))
+			}
+		}
+	}
+	var found []string
+	for _, d := range dirs {
+		for _, name := range coverageFileNames {
+			if p := filepath.Join(d, name); fileExists(p) {
+				found = append(found, p)
+			}
+		}
+	}
+	return found
+}
+
+// parseCoverageFile 按内容识别格式并合并到 data
+func parseCoverageFile(file string, res *coveragePathResolver, data *CoverageData) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(string(content))
+	switch {
+	case strings.HasPrefix(text, "mode:"):
+		return parseGoCoverProfile(text, res, data)
+	case strings.HasPrefix(text, "TN:") || strings.HasPrefix(text, "SF:"):
+		return parseLcov(text, res, data)
+	case strings.HasPrefix(text, "{"):
+		return parseCoveragePyJSON(content, res, data)
+	case strings.HasPrefix(text, "<"):
+		return parseCobertura(content, res, data)
+	}
+	return fmt.Errorf("无法识别的覆盖率格式")
+}
+
+// addBlock 合并覆盖块：同一位置重复出现（多次 go test 合并的 profile）时任一命中即算覆盖
+func (c *CoverageData) addBlock(file string, b CoverBlock) {
+	fc, ok := c.Files[file]
+	if !ok {
+		fc = &FileCoverage{}
+		c.Files[file] = fc
+	}
+	for i := range fc.Blocks {
+		if fc.Blocks[i].Start == b.Start && fc.Blocks[i].End == b.End {
+			fc.Blocks[i].Hit = fc.Blocks[i].Hit || b.Hit
+			return
+		}
+	}
+	fc.Blocks = append(fc.Blocks, b)
+}
+
+// parseGoCoverProfile 解析 go test -coverprofile：file.go:startLine.startCol,endLine.endCol numStmts count
+func parseGoCoverProfile(text string, res *coveragePathResolver, data *CoverageData) error {
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			continue
+		}
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) != 3 {
+			continue
+		}
+		start, end, ok := parseGoBlockRange(fields[0])
+		stmts, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if !ok || err1 != nil || err2 != nil {
+			continue
+		}
+		file, ok := res.resolve(line[:colon])
+		if !ok {
+			continue
+		}
+		data.addBlock(file, CoverBlock{Start: start, End: end, Stmts: stmts, Hit: count > 0})
+	}
+	return sc.Err()
+}
+
+// parseGoBlockRange 解析 "12.5,15.2" 得到起止行号
+func parseGoBlockRange(s string) (int, int, bool) {
+	from, to, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	startLine, _, _ := strings.Cut(from, ".")
+	endLine, _, _ := strings.Cut(to, ".")
+	start, err1 := strconv.Atoi(startLine)
+	end, err2 := strconv.Atoi(endLine)
+	return start, end, err1 == nil && err2 == nil
+}
+
+// parseLcov 解析 lcov：SF:<file> / DA:<line>,<hits> / end_of_record
+func parseLcov(text string, res *coveragePathResolver, data *CoverageData) error {
+	var file string
+	var ok bool
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			file, ok = res.resolve(strings.TrimPrefix(line, "SF:"))
+		case strings.HasPrefix(line, "DA:") && ok:
+			parts := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(parts) < 2 {
+				continue
+			}
+			n, err1 := strconv.Atoi(parts[0])
+			hits, err2 := strconv.Atoi(parts[1])
+			if err1 == nil && err2 == nil {
+				data.addBlock(file, CoverBlock{Start: n, End: n, Stmts: 1, Hit: hits > 0})
+			}
+		case line == "end_of_record":
+			ok = false
+		}
+	}
+	return nil
+}
+
+// parseCoveragePyJSON 解析 coverage json 输出
+func parseCoveragePyJSON(content []byte, res *coveragePathResolver, data *CoverageData) error {
+	var report struct {
+		Files map[string]struct {
+			ExecutedLines []int `json:"executed_lines"`
+			MissingLines  []int `json:"missing_lines"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return err
+	}
+	if report.Files == nil {
+		return fmt.Errorf("缺少 files 字段，不是 coverage.py 的 json 报告")
+	}
+	for name, f := range report.Files {
+		file, ok := res.resolve(name)
+		if !ok {
+			continue
+		}
+		for _, n := range f.ExecutedLines {
+			data.addBlock(file, CoverBlock{Start: n, End: n, Stmts: 1, Hit: true})
+		}
+		for _, n := range f.MissingLines {
+			data.addBlock(file, CoverBlock{Start: n, End: n, Stmts: 1})
+		}
+	}
+	return nil
+}
+
+// parseCobertura 解析 Cobertura xml（coverage xml、jacoco 转换等）
+func parseCobertura(content []byte, res *coveragePathResolver, data *CoverageData) error {
+	var report struct {
+		XMLName xml.Name `xml:"coverage"`
+		Sources []string `xml:"sources>source"`
+		Classes []struct {
+			Filename string `xml:"filename,attr"`
+			Lines    []struct {
+				Number int `xml:"number,attr"`
+				Hits   int `xml:"hits,attr"`
+			} `xml:"lines>line"`
+		} `xml:"packages>package>classes>class"`
+	}
+	if err := xml.Unmarshal(content, &report); err != nil {
+		return err
+	}
+	res.bases = append(res.bases, report.Sources...)
+	for _, cls := range report.Classes {
+		file, ok := res.resolve(cls.Filename)
+		if !ok {
+			continue
+		}
+		for _, l := range cls.Lines {
+			data.addBlock(file, CoverBlock{Start: l.Number, End: l.Number, Stmts: 1, Hit: l.Hits > 0})
+		}
+	}
+	return nil
+}
+
+// coveragePathResolver 把覆盖率文件中的路径（绝对路径、go 导入路径、相对路径）映射为项目相对路径
+type coveragePathResolver struct {
+	root  string
+	bases []string
+	cache map[string]string // 原始目录 -> 项目相对目录（"" 表示无法解析）
+}
+
+func newCoveragePathResolver(root, profileDir string) *coveragePathResolver {
+	return &coveragePathResolver{root: root, bases: []string{profileDir, root}, cache: make(map[string]string)}
+}
+
+func (r *coveragePathResolver) resolve(p string) (string, bool) {
+	p = strings.ReplaceAll(strings.TrimSpace(p), "\\", "/")
+	dir, base := path.Split(p)
+	relDir, cached := r.cache[dir]
+	if !cached {
+		relDir = r.resolveDir(dir, base)
+		r.cache[dir] = relDir
+	}
+	if relDir == "" {
+		return "", false
+	}
+	return normalizeCoveragePath(path.Join(relDir, base)), true
+}
+
+// resolveDir 依次尝试：绝对路径、相对各基准目录、逐段去掉前缀（go 导入路径中的模块名）
+func (r *coveragePathResolver) resolveDir(dir, base string) string {
+	toRel := func(abs string) string {
+		rel, err := filepath.Rel(r.root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return ""
+		}
+		return filepath.ToSlash(filepath.Dir(rel))
+	}
+	if filepath.IsAbs(filepath.FromSlash(dir)) {
+		if abs := filepath.Join(filepath.FromSlash(dir), base); fileExists(abs) {
+			return toRel(abs)
+		}
+	}
+	segs := strings.Split(strings.Trim(dir, "/"), "/")
+	for i := 0; i <= len(segs); i++ {
+		rest := filepath.FromSlash(strings.Join(segs[i:], "/"))
+		for _, b := range r.bases {
+			if abs := filepath.Join(b, rest, base); fileExists(abs) {
+				return toRel(abs)
+			}
+		}
+	}
+	return ""
+}
+
+// CoverageAge 覆盖率文件距今时间的可读描述
+func (c *CoverageData) CoverageAge() string {
+	return HumanizeAge(c.ModTime)
+}
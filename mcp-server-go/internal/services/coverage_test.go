@@ -0,0 +1,60 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCoverage_GoProfileAndLcov(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"svc/internal/api/handler.go", "web/src/app.js"} {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// go 导入路径以模块名开头，需要映射回 svc/ 子目录；重复块任一命中即算覆盖
+	profile := "mode: set\n" +
+		"example.com/svc/internal/api/handler.go:3.10,5.2 2 1\n" +
+		"example.com/svc/internal/api/handler.go:10.10,14.2 3 0\n" +
+		"example.com/svc/internal/api/handler.go:10.10,14.2 3 1\n" +
+		"example.com/svc/internal/api/handler.go:20.10,22.2 5 0\n"
+	if err := os.WriteFile(filepath.Join(root, "svc", "coverage.out"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lcov := "TN:\nSF:" + filepath.Join(root, "web", "src", "app.js") + "\nDA:1,3\nDA:2,0\nend_of_record\n"
+	if err := os.WriteFile(filepath.Join(root, "web", "lcov.info"), []byte(lcov), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cov, err := LoadCoverage(root, nil)
+	if err != nil || cov == nil {
+		t.Fatalf("LoadCoverage: %v, %v", cov, err)
+	}
+	if len(cov.Sources) != 2 {
+		t.Fatalf("sources = %v", cov.Sources)
+	}
+	if pct, ok := cov.FilePct("svc/internal/api/handler.go"); !ok || pct != 50 {
+		t.Fatalf("handler.go pct = %v, %v; want 50", pct, ok)
+	}
+	if pct, ok := cov.RangePct("svc/internal/api/handler.go", 18, 25); !ok || pct != 0 {
+		t.Fatalf("uncovered range pct = %v, %v; want 0", pct, ok)
+	}
+	if pct, ok := cov.DirPct("web"); !ok || pct != 50 {
+		t.Fatalf("web pct = %v, %v; want 50", pct, ok)
+	}
+	if _, ok := cov.FilePct("svc/missing.go"); ok {
+		t.Fatal("missing file should have no coverage")
+	}
+}
+
+func TestLoadCoverage_NoFiles(t *testing.T) {
+	cov, err := LoadCoverage(t.TempDir(), nil)
+	if err != nil || cov != nil {
+		t.Fatalf("want nil, nil; got %v, %v", cov, err)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// 死代码检测：零调用方符号按目录分组，并按可见性/调用方式给出置信度
+// ============================================================================
+
+// 死代码置信度
+const (
+	DeadConfidenceHigh   = "high"
+	DeadConfidenceMedium = "medium"
+	DeadConfidenceLow    = "low"
+)
+
+// DeadCodeItem 死代码候选
+type DeadCodeItem struct {
+	ReportSymbol
+	Confidence string `json:"confidence"`
+	Reason     string `json:"reason"`
+}
+
+// DeadCodeDir 同一目录下的候选
+type DeadCodeDir struct {
+	Dir   string         `json:"dir"`
+	High  int            `json:"high"`
+	Items []DeadCodeItem `json:"items"`
+}
+
+// DeadCodeReport 死代码报告
+type DeadCodeReport struct {
+	TotalSymbols int            `json:"total_symbols"`
+	Total        int            `json:"total"`
+	Counts       map[string]int `json:"counts"` // 各置信度数量
+	Dirs         []DeadCodeDir  `json:"dirs"`
+	Omitted      int            `json:"omitted"` // 超出 limit 未列出的候选数
+}
+
+// interfaceMethodNames 常被接口/trait/框架动态调用的方法名
+var interfaceMethodNames = map[string]bool{
+	// Go
+	"MarshalJSON": true, "UnmarshalJSON": true, "MarshalText": true, "UnmarshalText": true,
+	"MarshalYAML": true, "UnmarshalYAML": true, "Len": true, "Less": true, "Swap": true,
+	"Read": true, "Write": true, "Close": true, "Scan": true, "Value": true, "Format": true, "GoString": true,
+	// Rust
+	"fmt": true, "from": true, "drop": true, "deref": true, "default": true, "clone": true, "eq": true, "hash": true, "next": true,
+	// Java / JS
+	"toString": true, "equals": true, "hashCode": true, "compareTo": true, "run": true, "call": true,
+	"constructor": true, "render": true, "componentDidMount": true, "componentWillUnmount": true,
+}
+
+// httpHandlerMarkers 出现在签名中即视为 HTTP 处理器（由路由注册，调用图中看不到调用方）
+var httpHandlerMarkers = []string{
+	"http.ResponseWriter", "*gin.Context", "echo.Context", "*fiber.Ctx", "*fasthttp.RequestCtx",
+	"HttpServletRequest", "HttpRequest", "(req, res", "(request, response", "req: Request", "request: Request",
+}
+
+// isHTTPHandler 根据签名判断 HTTP 处理器
+func isHTTPHandler(sym *GraphSymbol) bool {
+	for _, m := range httpHandlerMarkers {
+		if strings.Contains(sym.Signature, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRouteFile 按惯例存放路由/视图的文件，其中函数多由框架按名称或装饰器注册
+func isRouteFile(file string) bool {
+	file = strings.ReplaceAll(file, "\\", "/")
+	base := strings.TrimSuffix(path.Base(file), path.Ext(file))
+	switch base {
+	case "views", "routes", "urls", "handlers", "api", "endpoints":
+		return true
+	}
+	return strings.Contains("/"+file, "/pages/") || strings.Contains("/"+file, "/routes/")
+}
+
+// symbolVisibility 按语言判断可见性：private / internal（导出但仅模块内可见）/ public / unknown
+func symbolVisibility(sym *GraphSymbol) string {
+	file := strings.ReplaceAll(sym.FilePath, "\\", "/")
+	first := ' '
+	for _, r := range sym.Name {
+		first = r
+		break
+	}
+	switch strings.ToLower(path.Ext(file)) {
+	case ".go":
+		if !unicode.IsUpper(first) {
+			return "private"
+		}
+		if strings.HasPrefix(file, "internal/") || strings.Contains(file, "/internal/") ||
+			strings.HasPrefix(file, "cmd/") || strings.Contains(file, "/cmd/") {
+			return "internal"
+		}
+		return "public"
+	case ".py":
+		if strings.HasPrefix(sym.Name, "_") {
+			return "private"
+		}
+		return "public"
+	case ".rs":
+		if strings.HasPrefix(strings.TrimSpace(sym.Signature), "pub") {
+			return "public"
+		}
+		return "private"
+	case ".java", ".cs", ".kt":
+		sig := " " + sym.Signature + " "
+		if strings.Contains(sig, " private ") {
+			return "private"
+		}
+		if strings.Contains(sig, " public ") {
+			return "public"
+		}
+	}
+	return "unknown"
+}
+
+// classifyDead 给零调用方符号评估置信度
+func classifyDead(sym *GraphSymbol) (string, string) {
+	switch {
+	case interfaceMethodNames[sym.Name]:
+		return DeadConfidenceLow, "常见接口方法，可能经接口/trait 动态调用"
+	case isRouteFile(sym.FilePath):
+		return DeadConfidenceLow, "位于路由/视图文件，可能由框架注册调用"
+	}
+	switch symbolVisibility(sym) {
+	case "public":
+		return DeadConfidenceLow, "导出符号，可能被项目外部调用"
+	case "internal":
+		return DeadConfidenceMedium, "导出但仅模块内可见，项目内无调用方"
+	case "unknown":
+		return DeadConfidenceMedium, "无法判断可见性，项目内无调用方"
+	}
+	if sym.Type == "method" {
+		return DeadConfidenceMedium, "私有方法，仍可能满足某个接口"
+	}
+	return DeadConfidenceHigh, "未导出且项目内无调用方"
+}
+
+func deadConfidenceRank(c string) int {
+	switch c {
+	case DeadConfidenceHigh:
+		return 0
+	case DeadConfidenceMedium:
+		return 1
+	}
+	return 2
+}
+
+// DeadCode 生成死代码报告：scope 非空时只看该目录；只保留不低于 minConfidence 的候选；limit 为列出的条目上限（<=0 不限）
+func (g *CallGraph) DeadCode(scope, minConfidence string, limit int) *DeadCodeReport {
+	scope = strings.Trim(strings.ReplaceAll(strings.TrimSpace(scope), "\\", "/"), "/")
+	minRank := deadConfidenceRank(minConfidence)
+	if minConfidence == "" {
+		minRank = deadConfidenceRank(DeadConfidenceLow)
+	}
+
+	report := &DeadCodeReport{TotalSymbols: len(g.Symbols), Counts: make(map[string]int)}
+	var items []DeadCodeItem
+	for _, id := range g.DeadCandidates() {
+		sym := g.Symbols[id]
+		file := strings.TrimPrefix(strings.ReplaceAll(sym.FilePath, "\\", "/"), "./")
+		if scope != "" && scope != "." && file != scope && !strings.HasPrefix(file, scope+"/") {
+			continue
+		}
+		conf, reason := classifyDead(sym)
+		if deadConfidenceRank(conf) > minRank {
+			continue
+		}
+		report.Total++
+		report.Counts[conf]++
+		items = append(items, DeadCodeItem{
+			ReportSymbol: toReportSymbol(sym, g.FanIn[id], g.FanOut[id]),
+			Confidence:   conf,
+			Reason:       reason,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return deadConfidenceRank(items[i].Confidence) < deadConfidenceRank(items[j].Confidence)
+	})
+	if limit > 0 && len(items) > limit {
+		report.Omitted = len(items) - limit
+		items = items[:limit]
+	}
+
+	byDir := make(map[string]*DeadCodeDir)
+	for _, it := range items {
+		dir := path.Dir(strings.ReplaceAll(it.FilePath, "\\", "/"))
+		d, ok := byDir[dir]
+		if !ok {
+			d = &DeadCodeDir{Dir: dir}
+			byDir[dir] = d
+		}
+		d.Items = append(d.Items, it)
+		if it.Confidence == DeadConfidenceHigh {
+			d.High++
+		}
+	}
+	for _, d := range byDir {
+		sort.SliceStable(d.Items, func(i, j int) bool {
+			a, b := d.Items[i], d.Items[j]
+			if ra, rb := deadConfidenceRank(a.Confidence), deadConfidenceRank(b.Confidence); ra != rb {
+				return ra < rb
+			}
+			if a.FilePath != b.FilePath {
+				return a.FilePath < b.FilePath
+			}
+			return a.Line < b.Line
+		})
+		report.Dirs = append(report.Dirs, *d)
+	}
+	sort.Slice(report.Dirs, func(i, j int) bool {
+		a, b := report.Dirs[i], report.Dirs[j]
+		if a.High != b.High {
+			return a.High > b.High
+		}
+		if len(a.Items) != len(b.Items) {
+			return len(a.Items) > len(b.Items)
+		}
+		return a.Dir < b.Dir
+	})
+	return report
+}
@@ -0,0 +1,49 @@
+package services
+
+import "testing"
+
+func TestDeadCodeConfidence(t *testing.T) {
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "main", Type: "function", FilePath: "cmd/app/main.go"},
+			2: {SymbolID: 2, Name: "run", Type: "function", FilePath: "cmd/app/main.go"},
+			3: {SymbolID: 3, Name: "unusedHelper", Type: "function", FilePath: "pkg/util/strings.go", LineStart: 5},
+			4: {SymbolID: 4, Name: "Public", Type: "function", FilePath: "pkg/util/strings.go", LineStart: 9},
+			5: {SymbolID: 5, Name: "Helper", Type: "function", FilePath: "internal/core/x.go"},
+			6: {SymbolID: 6, Name: "MarshalJSON", Type: "method", FilePath: "internal/core/x.go"},
+			7: {SymbolID: 7, Name: "Login", Type: "function", FilePath: "internal/api/auth.go", Signature: "func Login(w http.ResponseWriter, r *http.Request)"},
+			8: {SymbolID: 8, Name: "_cleanup", Type: "function", FilePath: "scripts/tool.py"},
+		},
+		Edges: map[int][]int{1: {2}},
+	}
+
+	r := g.DeadCode("", DeadConfidenceLow, 0)
+	want := map[string]string{
+		"unusedHelper": DeadConfidenceHigh,
+		"_cleanup":     DeadConfidenceHigh,
+		"Helper":       DeadConfidenceMedium,
+		"Public":       DeadConfidenceLow,
+		"MarshalJSON":  DeadConfidenceLow,
+	}
+	got := make(map[string]string)
+	for _, d := range r.Dirs {
+		for _, it := range d.Items {
+			got[it.Name] = it.Confidence
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+	for name, conf := range want {
+		if got[name] != conf {
+			t.Fatalf("%s confidence = %q, want %q", name, got[name], conf)
+		}
+	}
+	if r.Dirs[0].Dir != "pkg/util" && r.Dirs[0].Dir != "scripts" {
+		t.Fatalf("dirs with high-confidence items should come first, got %s", r.Dirs[0].Dir)
+	}
+
+	if r := g.DeadCode("pkg", DeadConfidenceHigh, 0); r.Total != 1 || r.Dirs[0].Items[0].Name != "unusedHelper" {
+		t.Fatalf("scoped high-confidence report = %+v", r)
+	}
+}
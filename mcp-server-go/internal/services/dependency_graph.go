@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 文件/模块级依赖图 (由符号调用边聚合，导出 Mermaid / Graphviz DOT)
+// ============================================================================
+
+// DependencyEdge 两个文件/模块之间的依赖，Calls 为聚合的符号调用边数
+type DependencyEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Calls int    `json:"calls"`
+}
+
+// DependencyGraph 聚合后的依赖图
+type DependencyGraph struct {
+	Level   string           `json:"level"` // file / module
+	Nodes   []string         `json:"nodes"`
+	Edges   []DependencyEdge `json:"edges"`
+	Dropped int              `json:"dropped"` // 超出 maxEdges 被省略的边数
+}
+
+// Dependencies 把调用图聚合为 level 粒度（file 或 module=所在目录）的依赖图。
+// scope 非空时只保留至少一端位于该路径下的边；按调用数降序保留前 maxEdges 条（<=0 不限）
+func (g *CallGraph) Dependencies(level, scope string, maxEdges int) *DependencyGraph {
+	if level != "file" {
+		level = "module"
+	}
+	scope = strings.Trim(path.Clean(strings.ReplaceAll(strings.TrimSpace(scope), "\\", "/")), "/")
+	if scope == "." {
+		scope = ""
+	}
+	inScope := func(p string) bool {
+		return scope == "" || p == scope || strings.HasPrefix(p, scope+"/")
+	}
+	key := func(file string) string {
+		file = strings.TrimPrefix(strings.ReplaceAll(file, "\\", "/"), "./")
+		if level == "file" {
+			return file
+		}
+		if dir := path.Dir(file); dir != "." {
+			return dir
+		}
+		return "(root)"
+	}
+
+	weights := make(map[[2]string]int)
+	for caller, callees := range g.Edges {
+		from, ok := g.Symbols[caller]
+		if !ok || from.FilePath == "" {
+			continue
+		}
+		for _, c := range callees {
+			to, ok := g.Symbols[c]
+			if !ok || to.FilePath == "" {
+				continue
+			}
+			if !inScope(from.FilePath) && !inScope(to.FilePath) {
+				continue
+			}
+			a, b := key(from.FilePath), key(to.FilePath)
+			if a != b {
+				weights[[2]string{a, b}]++
+			}
+		}
+	}
+
+	dg := &DependencyGraph{Level: level}
+	for k, n := range weights {
+		dg.Edges = append(dg.Edges, DependencyEdge{From: k[0], To: k[1], Calls: n})
+	}
+	sort.Slice(dg.Edges, func(i, j int) bool {
+		a, b := dg.Edges[i], dg.Edges[j]
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	if maxEdges > 0 && len(dg.Edges) > maxEdges {
+		dg.Dropped = len(dg.Edges) - maxEdges
+		dg.Edges = dg.Edges[:maxEdges]
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range dg.Edges {
+		for _, n := range []string{e.From, e.To} {
+			if !seen[n] {
+				seen[n] = true
+				dg.Nodes = append(dg.Nodes, n)
+			}
+		}
+	}
+	sort.Strings(dg.Nodes)
+	return dg
+}
+
+// Mermaid 渲染为 Mermaid flowchart，边上标注调用数
+func (dg *DependencyGraph) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	ids := make(map[string]string, len(dg.Nodes))
+	for i, n := range dg.Nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+		sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", ids[n], strings.ReplaceAll(n, `"`, "#quot;")))
+	}
+	for _, e := range dg.Edges {
+		sb.WriteString(fmt.Sprintf("    %s -->|%d| %s\n", ids[e.From], e.Calls, ids[e.To]))
+	}
+	return sb.String()
+}
+
+// DOT 渲染为 Graphviz DOT，边宽随调用数增长
+func (dg *DependencyGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n")
+	sb.WriteString("    rankdir=LR;\n")
+	sb.WriteString("    node [shape=box, fontsize=10];\n")
+	for _, n := range dg.Nodes {
+		sb.WriteString(fmt.Sprintf("    %q;\n", n))
+	}
+	for _, e := range dg.Edges {
+		width := 1 + e.Calls/5
+		if width > 5 {
+			width = 5
+		}
+		sb.WriteString(fmt.Sprintf("    %q -> %q [label=\"%d\", penwidth=%d];\n", e.From, e.To, e.Calls, width))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "Handle", FilePath: "api/handler.go"},
+			2: {SymbolID: 2, Name: "Save", FilePath: "store/repo.go"},
+			3: {SymbolID: 3, Name: "Load", FilePath: "store/repo.go"},
+			4: {SymbolID: 4, Name: "helper", FilePath: "api/util.go"},
+			5: {SymbolID: 5, Name: "main", FilePath: "main.go"},
+		},
+		Edges: map[int][]int{
+			1: {2, 3, 4},
+			5: {1},
+		},
+	}
+
+	dg := g.Dependencies("module", "", 0)
+	if len(dg.Edges) != 2 || dg.Edges[0] != (DependencyEdge{From: "api", To: "store", Calls: 2}) {
+		t.Fatalf("module edges = %+v", dg.Edges)
+	}
+	if got := strings.Join(dg.Nodes, ","); got != "(root),api,store" {
+		t.Fatalf("nodes = %s", got)
+	}
+
+	dg = g.Dependencies("file", "api", 1)
+	if len(dg.Edges) != 1 || dg.Dropped != 2 || dg.Edges[0].From != "api/handler.go" {
+		t.Fatalf("scoped file edges = %+v (dropped %d)", dg.Edges, dg.Dropped)
+	}
+	if m := dg.Mermaid(); !strings.Contains(m, `n0["api/handler.go"]`) || !strings.Contains(m, "n0 -->|2| n1") {
+		t.Fatalf("mermaid = %s", m)
+	}
+	if d := dg.DOT(); !strings.Contains(d, `"api/handler.go" -> "store/repo.go"`) {
+		t.Fatalf("dot = %s", d)
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 依赖漏洞审计：识别项目清单文件，给出对应审计器的命令，并把各审计器的 JSON 输出
+// 归一化为同一种漏洞记录（命令的执行交给调用方，经 core.CommandRunner 守卫）
+// ============================================================================
+
+// 归一化后的严重程度
+const (
+	VulnSeverityCritical = "critical"
+	VulnSeverityHigh     = "high"
+	VulnSeverityMedium   = "medium"
+	VulnSeverityLow      = "low"
+	VulnSeverityUnknown  = "unknown"
+)
+
+var vulnSeverityRank = map[string]int{
+	VulnSeverityCritical: 0, VulnSeverityHigh: 1, VulnSeverityMedium: 2, VulnSeverityLow: 3, VulnSeverityUnknown: 4,
+}
+
+// Manifest 识别到的依赖清单
+type Manifest struct {
+	Ecosystem string // go / npm / pypi / cargo
+	File      string // 相对项目根
+	Dir       string // 清单所在目录，相对项目根（"" 为根目录）
+}
+
+// Auditor 审计器命令
+type Auditor struct {
+	Name    string   // 可执行文件名，用于 LookPath
+	Args    []string // 完整命令（含程序名）
+	Install string   // 未安装时的安装提示
+}
+
+// Vulnerability 归一化的漏洞记录
+type Vulnerability struct {
+	ID        string `json:"id"`
+	Package   string `json:"package"`
+	Installed string `json:"installed,omitempty"`
+	FixedIn   string `json:"fixed_in,omitempty"`
+	Severity  string `json:"severity"`
+	Summary   string `json:"summary,omitempty"`
+	Reachable bool   `json:"reachable,omitempty"` // govulncheck：代码实际调用到了漏洞函数
+}
+
+var manifestFiles = []struct {
+	file      string
+	ecosystem string
+}{
+	{"go.mod", "go"},
+	{"package.json", "npm"},
+	{"requirements.txt", "pypi"},
+	{"Cargo.toml", "cargo"},
+}
+
+// manifestSkipDirs 不会包含项目自身清单的目录
+var manifestSkipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "target": true, "dist": true, "build": true,
+	".git": true, ".venv": true, "venv": true, "__pycache__": true,
+}
+
+// DetectManifests 查找项目根及其下两层目录中的依赖清单（monorepo 子项目）
+func DetectManifests(projectRoot string) []Manifest {
+	var out []Manifest
+	var walk func(rel string, depth int)
+	walk = func(rel string, depth int) {
+		abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+		for _, mf := range manifestFiles {
+			if fileExists(filepath.Join(abs, mf.file)) {
+				out = append(out, Manifest{Ecosystem: mf.ecosystem, File: strings.TrimPrefix(rel+"/"+mf.file, "/"), Dir: rel})
+			}
+		}
+		if depth == 2 {
+			return
+		}
+		entries, err := os.ReadDir(abs)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if !e.IsDir() || manifestSkipDirs[e.Name()] || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			walk(strings.TrimPrefix(rel+"/"+e.Name(), "/"), depth+1)
+		}
+	}
+	walk("", 0)
+	return out
+}
+
+// AuditorFor 清单对应的审计器
+func AuditorFor(m Manifest) Auditor {
+	switch m.Ecosystem {
+	case "go":
+		return Auditor{Name: "govulncheck", Args: []string{"govulncheck", "-json", "./..."}, Install: "go install golang.org/x/vuln/cmd/govulncheck@latest"}
+	case "npm":
+		return Auditor{Name: "npm", Args: []string{"npm", "audit", "--json"}, Install: "随 Node.js 安装；需要 package-lock.json"}
+	case "pypi":
+		return Auditor{Name: "pip-audit", Args: []string{"pip-audit", "-r", "requirements.txt", "-f", "json"}, Install: "pip install pip-audit"}
+	case "cargo":
+		return Auditor{Name: "cargo-audit", Args: []string{"cargo", "audit", "--json"}, Install: "cargo install cargo-audit"}
+	}
+	return Auditor{}
+}
+
+// ParseAuditOutput 按生态解析审计器输出（输出可能混有 stderr 文本，从第一个 JSON 起始位置解析）
+func ParseAuditOutput(ecosystem, output string) ([]Vulnerability, error) {
+	start := strings.IndexAny(output, "{[")
+	if start < 0 {
+		return nil, fmt.Errorf("输出中没有 JSON: %s", strings.TrimSpace(truncateLine(output, 200)))
+	}
+	data := []byte(output[start:])
+	var vulns []Vulnerability
+	var err error
+	switch ecosystem {
+	case "go":
+		vulns, err = parseGovulncheck(data)
+	case "npm":
+		vulns, err = parseNpmAudit(data)
+	case "pypi":
+		vulns, err = parsePipAudit(data)
+	case "cargo":
+		vulns, err = parseCargoAudit(data)
+	default:
+		return nil, fmt.Errorf("不支持的生态: %s", ecosystem)
+	}
+	if err != nil {
+		return nil, err
+	}
+	SortVulnerabilities(vulns)
+	return vulns, nil
+}
+
+// SortVulnerabilities 按严重程度、包名、ID 排序
+func SortVulnerabilities(vulns []Vulnerability) {
+	sort.SliceStable(vulns, func(i, j int) bool {
+		a, b := vulns[i], vulns[j]
+		if a.Severity != b.Severity {
+			return vulnSeverityRank[a.Severity] < vulnSeverityRank[b.Severity]
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.ID < b.ID
+	})
+}
+
+func normalizeVulnSeverity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "critical":
+		return VulnSeverityCritical
+	case "high":
+		return VulnSeverityHigh
+	case "moderate", "medium":
+		return VulnSeverityMedium
+	case "low", "info", "informational":
+		return VulnSeverityLow
+	}
+	return VulnSeverityUnknown
+}
+
+// parseGovulncheck govulncheck -json 输出的是 JSON 对象流（osv / finding 消息）。
+// 只调用到漏洞函数（trace 含 function）的为 high，仅依赖模块/导入包的为 low
+func parseGovulncheck(data []byte) ([]Vulnerability, error) {
+	type frame struct {
+		Module   string `json:"module"`
+		Version  string `json:"version"`
+		Function string `json:"function"`
+	}
+	type message struct {
+		OSV *struct {
+			ID      string `json:"id"`
+			Summary string `json:"summary"`
+		} `json:"osv"`
+		Finding *struct {
+			OSV          string  `json:"osv"`
+			FixedVersion string  `json:"fixed_version"`
+			Trace        []frame `json:"trace"`
+		} `json:"finding"`
+	}
+	summaries := make(map[string]string)
+	byKey := make(map[string]*Vulnerability)
+	var order []string
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			if len(byKey) == 0 && len(summaries) == 0 {
+				return nil, fmt.Errorf("解析 govulncheck 输出失败: %v", err)
+			}
+			break
+		}
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		f := msg.Finding
+		if f == nil || len(f.Trace) == 0 {
+			continue
+		}
+		key := f.OSV + "@" + f.Trace[0].Module
+		v, ok := byKey[key]
+		if !ok {
+			v = &Vulnerability{ID: f.OSV, Package: f.Trace[0].Module, Installed: f.Trace[0].Version, FixedIn: f.FixedVersion, Severity: VulnSeverityLow}
+			byKey[key] = v
+			order = append(order, key)
+		}
+		for _, fr := range f.Trace {
+			if fr.Function != "" {
+				v.Reachable, v.Severity = true, VulnSeverityHigh
+			}
+		}
+	}
+	out := make([]Vulnerability, 0, len(order))
+	for _, key := range order {
+		v := byKey[key]
+		v.Summary = summaries[v.ID]
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+// parseNpmAudit npm audit --json (npm 7+)：vulnerabilities 以包名为键，via 为公告对象或传递依赖的包名
+func parseNpmAudit(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Vulnerabilities map[string]struct {
+			Name         string            `json:"name"`
+			Severity     string            `json:"severity"`
+			Range        string            `json:"range"`
+			FixAvailable json.RawMessage   `json:"fixAvailable"`
+			Via          []json.RawMessage `json:"via"`
+		} `json:"vulnerabilities"`
+		Error *struct {
+			Summary string `json:"summary"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("解析 npm audit 输出失败: %v", err)
+	}
+	if report.Error != nil {
+		return nil, fmt.Errorf("npm audit: %s", report.Error.Summary)
+	}
+	var out []Vulnerability
+	for name, v := range report.Vulnerabilities {
+		vuln := Vulnerability{Package: fallbackString(v.Name, name), Installed: v.Range, Severity: normalizeVulnSeverity(v.Severity)}
+		var via []string
+		for _, raw := range v.Via {
+			var adv struct {
+				Source json.RawMessage `json:"source"`
+				Title  string          `json:"title"`
+				URL    string          `json:"url"`
+			}
+			if json.Unmarshal(raw, &adv) == nil && adv.Title != "" {
+				if vuln.ID == "" {
+					vuln.ID = fallbackString(adv.URL[strings.LastIndex(adv.URL, "/")+1:], strings.Trim(string(adv.Source), `"`))
+					vuln.Summary = adv.Title
+				}
+				continue
+			}
+			var dep string
+			if json.Unmarshal(raw, &dep) == nil {
+				via = append(via, dep)
+			}
+		}
+		if vuln.ID == "" && len(via) > 0 {
+			vuln.Summary = "经由 " + strings.Join(via, ", ") // 传递依赖引入
+		}
+		var fix struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		switch {
+		case json.Unmarshal(v.FixAvailable, &fix) == nil && fix.Version != "":
+			vuln.FixedIn = fix.Name + "@" + fix.Version
+		case string(v.FixAvailable) == "true":
+			vuln.FixedIn = "npm audit fix"
+		}
+		out = append(out, vuln)
+	}
+	return out, nil
+}
+
+// parsePipAudit pip-audit -f json：新版为 {"dependencies": [...]}，旧版直接是数组
+func parsePipAudit(data []byte) ([]Vulnerability, error) {
+	type dep struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Vulns   []struct {
+			ID          string   `json:"id"`
+			FixVersions []string `json:"fix_versions"`
+			Description string   `json:"description"`
+		} `json:"vulns"`
+	}
+	var deps []dep
+	var wrapped struct {
+		Dependencies []dep `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Dependencies != nil {
+		deps = wrapped.Dependencies
+	} else if err := json.Unmarshal(data, &deps); err != nil {
+		return nil, fmt.Errorf("解析 pip-audit 输出失败: %v", err)
+	}
+	var out []Vulnerability
+	for _, d := range deps {
+		for _, v := range d.Vulns {
+			out = append(out, Vulnerability{
+				ID: v.ID, Package: d.Name, Installed: d.Version, FixedIn: strings.Join(v.FixVersions, ", "),
+				Severity: VulnSeverityUnknown, Summary: truncateLine(strings.TrimSpace(v.Description), 160),
+			})
+		}
+	}
+	return out, nil
+}
+
+// parseCargoAudit cargo audit --json：vulnerabilities.list[].{advisory, package, versions}
+func parseCargoAudit(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Vulnerabilities struct {
+			List []struct {
+				Advisory struct {
+					ID       string `json:"id"`
+					Title    string `json:"title"`
+					Severity string `json:"severity"`
+				} `json:"advisory"`
+				Package struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"package"`
+				Versions struct {
+					Patched []string `json:"patched"`
+				} `json:"versions"`
+			} `json:"list"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("解析 cargo audit 输出失败: %v", err)
+	}
+	var out []Vulnerability
+	for _, v := range report.Vulnerabilities.List {
+		out = append(out, Vulnerability{
+			ID: v.Advisory.ID, Package: v.Package.Name, Installed: v.Package.Version,
+			FixedIn: strings.Join(v.Versions.Patched, ", "), Severity: normalizeVulnSeverity(v.Advisory.Severity), Summary: v.Advisory.Title,
+		})
+	}
+	return out, nil
+}
+
+func fallbackString(val, def string) string {
+	if val == "" {
+		return def
+	}
+	return val
+}
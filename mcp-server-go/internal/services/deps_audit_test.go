@@ -0,0 +1,68 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectManifests(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"go.mod", "web/package.json", "web/node_modules/x/package.json", "tools/py/requirements.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		_ = os.WriteFile(p, []byte("{}"), 0644)
+	}
+	got := make(map[string]string)
+	for _, m := range DetectManifests(root) {
+		got[m.File] = m.Ecosystem + "@" + m.Dir
+	}
+	want := map[string]string{"go.mod": "go@", "web/package.json": "npm@web", "tools/py/requirements.txt": "pypi@tools/py"}
+	if len(got) != len(want) {
+		t.Fatalf("manifests = %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestParseAuditOutput(t *testing.T) {
+	govuln := `{"config":{"scanner_name":"govulncheck"}}
+{"osv":{"id":"GO-2024-0001","summary":"Request smuggling in net/http"}}
+{"finding":{"osv":"GO-2024-0001","fixed_version":"v1.22.1","trace":[{"module":"stdlib","version":"v1.22.0"}]}}
+{"finding":{"osv":"GO-2024-0001","fixed_version":"v1.22.1","trace":[{"module":"stdlib","version":"v1.22.0","function":"Serve"},{"module":"example.com/app","function":"main"}]}}
+{"osv":{"id":"GO-2024-0002","summary":"DoS in yaml"}}
+{"finding":{"osv":"GO-2024-0002","fixed_version":"v3.0.1","trace":[{"module":"gopkg.in/yaml.v3","version":"v3.0.0"}]}}`
+	vulns, err := ParseAuditOutput("go", govuln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vulns) != 2 || vulns[0].ID != "GO-2024-0001" || !vulns[0].Reachable || vulns[0].Severity != VulnSeverityHigh || vulns[1].Severity != VulnSeverityLow {
+		t.Errorf("govulncheck = %+v", vulns)
+	}
+
+	npm := `npm WARN something
+{"vulnerabilities":{"lodash":{"name":"lodash","severity":"moderate","range":"<4.17.21","fixAvailable":true,
+ "via":[{"source":1523,"title":"Prototype Pollution","url":"https://github.com/advisories/GHSA-35jh-r3h4-6jhm"}]},
+ "express":{"name":"express","severity":"critical","range":"<4.19.0","fixAvailable":{"name":"express","version":"4.19.2"},"via":["body-parser"]}}}`
+	vulns, err = ParseAuditOutput("npm", npm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vulns) != 2 || vulns[0].Package != "express" || vulns[0].FixedIn != "express@4.19.2" ||
+		vulns[1].ID != "GHSA-35jh-r3h4-6jhm" || vulns[1].Severity != VulnSeverityMedium {
+		t.Errorf("npm = %+v", vulns)
+	}
+
+	pip := `{"dependencies":[{"name":"flask","version":"0.5","vulns":[{"id":"PYSEC-2019-179","fix_versions":["1.0"],"description":"DoS"}]},{"name":"ok","version":"1.0","vulns":[]}]}`
+	if vulns, err = ParseAuditOutput("pypi", pip); err != nil || len(vulns) != 1 || vulns[0].FixedIn != "1.0" {
+		t.Errorf("pip-audit = %+v, %v", vulns, err)
+	}
+
+	cargo := `{"vulnerabilities":{"found":true,"list":[{"advisory":{"id":"RUSTSEC-2020-0071","title":"Potential segfault in time"},"package":{"name":"time","version":"0.1.43"},"versions":{"patched":[">=0.2.23"]}}]}}`
+	if vulns, err = ParseAuditOutput("cargo", cargo); err != nil || len(vulns) != 1 || vulns[0].ID != "RUSTSEC-2020-0071" {
+		t.Errorf("cargo audit = %+v, %v", vulns, err)
+	}
+}
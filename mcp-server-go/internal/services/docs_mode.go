@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// 文档模式：无代码栈的仓库（设计/文档库）以 Markdown 标题为"符号"，文档间链接为"调用"
+// ============================================================================
+
+// DocHeading Markdown 标题
+type DocHeading struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Level  int    `json:"level"`
+	Title  string `json:"title"`
+	Anchor string `json:"anchor"`
+}
+
+// DocLink 文档内的相对链接
+type DocLink struct {
+	From    string `json:"from"`
+	Line    int    `json:"line"`
+	Heading string `json:"heading,omitempty"` // 链接所在的最近标题
+	Target  string `json:"target"`            // 项目相对路径；同文档锚点时等于 From
+	Anchor  string `json:"anchor,omitempty"`
+}
+
+// DocsIndex 文档索引
+type DocsIndex struct {
+	Files         []string     `json:"files"`
+	Headings      []DocHeading `json:"headings"`
+	Links         []DocLink    `json:"links"`
+	ExternalLinks int          `json:"external_links"`
+}
+
+var (
+	docHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	docLinkRe    = regexp.MustCompile(`\[[^\]]*\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+)
+
+func isDocFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	}
+	return false
+}
+
+// IsDocsProject 未检测到任何代码技术栈，但存在 Markdown 文档
+func IsDocsProject(projectRoot string) bool {
+	if projectRoot == "" {
+		return false
+	}
+	extensions, ignoreDirs := detectTechStackAndConfig(projectRoot)
+	if extensions != "" {
+		return false
+	}
+	exts := scanProjectExtensions(projectRoot, strings.Split(ignoreDirs, ","), 8)
+	return exts["md"] || exts["markdown"] || exts["mdx"]
+}
+
+// DocAnchor 生成 GitHub 风格的标题锚点
+func DocAnchor(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+// ScanDocs 扫描 scope 内的 Markdown 文件，提取标题与相对链接（跳过围栏代码块）
+func ScanDocs(projectRoot, scope string) (*DocsIndex, error) {
+	_, ignoreDirs := detectTechStackAndConfig(projectRoot)
+	ignoreSet := make(map[string]bool)
+	for _, d := range strings.Split(ignoreDirs, ",") {
+		if d = strings.TrimSpace(strings.ToLower(d)); d != "" {
+			ignoreSet[d] = true
+		}
+	}
+
+	base := projectRoot
+	if s := strings.Trim(filepath.ToSlash(strings.TrimSpace(scope)), "/"); s != "" && s != "." {
+		base = filepath.Join(projectRoot, filepath.FromSlash(s))
+	}
+	if _, err := os.Stat(base); err != nil {
+		return nil, err
+	}
+
+	idx := &DocsIndex{}
+	err := filepath.WalkDir(base, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != base && shouldSkipDetectDir(strings.ToLower(d.Name()), ignoreSet) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isDocFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(projectRoot, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		idx.Files = append(idx.Files, rel)
+		scanDocFile(p, rel, idx)
+		return nil
+	})
+	sort.Strings(idx.Files)
+	return idx, err
+}
+
+func scanDocFile(absPath, rel string, idx *DocsIndex) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	inFence := false
+	current := ""
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if m := docHeadingRe.FindStringSubmatch(text); m != nil {
+			current = m[2]
+			idx.Headings = append(idx.Headings, DocHeading{
+				File: rel, Line: line, Level: len(m[1]), Title: m[2], Anchor: DocAnchor(m[2]),
+			})
+			continue
+		}
+
+		for _, m := range docLinkRe.FindAllStringSubmatch(text, -1) {
+			target := m[1]
+			if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+				idx.ExternalLinks++
+				continue
+			}
+			anchor := ""
+			if i := strings.Index(target, "#"); i >= 0 {
+				target, anchor = target[:i], target[i+1:]
+			}
+			resolved := rel
+			if target != "" {
+				if !isDocFile(target) {
+					continue // 图片、源码等非文档链接
+				}
+				resolved = path.Clean(path.Join(path.Dir(rel), target))
+			}
+			idx.Links = append(idx.Links, DocLink{From: rel, Line: line, Heading: current, Target: resolved, Anchor: anchor})
+		}
+	}
+}
+
+// Outgoing 从 file 发出的跨文档链接
+func (idx *DocsIndex) Outgoing(file string) []DocLink {
+	var result []DocLink
+	for _, l := range idx.Links {
+		if l.From == file && l.Target != file {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// Incoming 指向 file 的跨文档链接
+func (idx *DocsIndex) Incoming(file string) []DocLink {
+	var result []DocLink
+	for _, l := range idx.Links {
+		if l.Target == file && l.From != file {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// FindHeading 按标题文本（不区分大小写，支持子串）定位标题
+func (idx *DocsIndex) FindHeading(query string) *DocHeading {
+	q := strings.ToLower(strings.TrimSpace(query))
+	var partial *DocHeading
+	for i := range idx.Headings {
+		h := &idx.Headings[i]
+		title := strings.ToLower(h.Title)
+		if title == q || h.Anchor == q {
+			return h
+		}
+		if partial == nil && strings.Contains(title, q) {
+			partial = h
+		}
+	}
+	return partial
+}
+
+// HasFile 索引中是否包含该文档
+func (idx *DocsIndex) HasFile(file string) bool {
+	for _, f := range idx.Files {
+		if f == file {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanDocs_HeadingsAndLinks(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("README.md", "# Overview\n\nSee [design](docs/design.md#data-model) and [site](https://example.com).\n")
+	write("docs/design.md", "# Design\n\n## Data Model\n\n```\n# not a heading\n[x](ignored.md)\n```\nBack to [readme](../README.md).\n")
+
+	if !IsDocsProject(root) {
+		t.Fatalf("expected docs project")
+	}
+	idx, err := ScanDocs(root, "")
+	if err != nil {
+		t.Fatalf("ScanDocs failed: %v", err)
+	}
+	if len(idx.Files) != 2 || len(idx.Headings) != 3 {
+		t.Fatalf("unexpected index: files=%v headings=%+v", idx.Files, idx.Headings)
+	}
+	if idx.ExternalLinks != 1 {
+		t.Errorf("expected 1 external link, got %d", idx.ExternalLinks)
+	}
+	in := idx.Incoming("docs/design.md")
+	if len(in) != 1 || in[0].From != "README.md" || in[0].Anchor != "data-model" || in[0].Heading != "Overview" {
+		t.Errorf("unexpected incoming links: %+v", in)
+	}
+	if out := idx.Outgoing("docs/design.md"); len(out) != 1 || out[0].Target != "README.md" {
+		t.Errorf("unexpected outgoing links: %+v", out)
+	}
+	if h := idx.FindHeading("data model"); h == nil || h.File != "docs/design.md" {
+		t.Errorf("FindHeading failed: %+v", h)
+	}
+}
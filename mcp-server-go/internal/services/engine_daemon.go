@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 常驻引擎进程：query / map / analyze / structure 复用一个 --mode serve 子进程
+// ============================================================================
+//
+// 协议为 stdin/stdout 上的 JSON 行：请求 {"id":1,"args":[...]}（args 为空即 ping），
+// 响应 {"id":1,"ok":true,"result":{...}}。读协程按 id 把响应分发给等待者，
+// 调用方只在写请求时短暂持锁，慢查询不会阻塞其他调用与健康检查。
+// 子进程崩溃或超时后下一次调用自动重启；启动后 ping 失败（如旧版引擎不支持 serve）
+// 时按指数退避暂停常驻模式，期间回退为每次查询单独启动进程，退避结束后重新尝试。
+// 索引 (--mode index) 耗时长且带独立超时，始终单独启动进程。
+// MPM_ENGINE_DAEMON=0 关闭常驻进程。
+
+const (
+	daemonPingTimeout = 10 * time.Second
+	daemonCallTimeout = 2 * time.Minute
+	daemonBackoffMin  = 30 * time.Second
+	daemonBackoffMax  = 10 * time.Minute
+)
+
+// daemonModes 由常驻进程处理的引擎模式
+var daemonModes = map[string]bool{"query": true, "map": true, "analyze": true, "structure": true}
+
+var errDaemonUnsupported = errors.New("引擎不支持常驻模式")
+
+// daemonRequestError 引擎对单个请求报告的错误（进程本身仍然可用）
+type daemonRequestError struct{ msg string }
+
+func (e *daemonRequestError) Error() string { return e.msg }
+
+// EngineDaemonHealth 常驻引擎进程状态
+type EngineDaemonHealth struct {
+	Enabled   bool   `json:"enabled"`
+	Supported bool   `json:"supported"`
+	Running   bool   `json:"running"`
+	PID       int    `json:"pid,omitempty"`
+	StartedAt string `json:"started_at,omitempty"`
+	Requests  int64  `json:"requests"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"last_error,omitempty"`
+	RetryAt   string `json:"retry_at,omitempty"` // ping 失败后下次尝试启动常驻进程的时间
+}
+
+type daemonResponse struct {
+	ID     uint64          `json:"id"`
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// daemonProc 一个 serve 子进程；pending 中的等待者由读协程按 id 唤醒
+type daemonProc struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[uint64]chan daemonResponse
+	done    chan struct{} // stdout 结束（进程退出）后关闭
+}
+
+type engineDaemon struct {
+	startMu  sync.Mutex // 串行化启动（含 ping），不影响已在运行的调用
+	mu       sync.Mutex // 保护以下字段，只在短时间内持有
+	bin      string
+	root     string
+	proc     *daemonProc
+	nextID   uint64
+	starts   int
+	failures int       // 连续 ping 失败次数，决定退避时长
+	retryAt  time.Time // 退避结束前不启动常驻进程
+	started  time.Time
+	requests int64
+	lastErr  string
+}
+
+func daemonEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("MPM_ENGINE_DAEMON"))) {
+	case "0", "false", "off":
+		return false
+	}
+	return true
+}
+
+// engineMode 取参数中的 --mode 值
+func engineMode(args []string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--mode" {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// daemon 项目对应的常驻进程（惰性创建，首次调用时才启动）
+func (ai *ASTIndexer) daemon(projectRoot string) *engineDaemon {
+	root := normalizeProjectRoot(projectRoot)
+	ai.daemonMu.Lock()
+	defer ai.daemonMu.Unlock()
+	if ai.daemons == nil {
+		ai.daemons = make(map[string]*engineDaemon)
+	}
+	d, ok := ai.daemons[root]
+	if !ok {
+		d = &engineDaemon{bin: ai.BinaryPath, root: root}
+		ai.daemons[root] = d
+	}
+	return d
+}
+
+// EngineDaemonHealth 项目常驻引擎进程的状态（index_status 展示）
+func (ai *ASTIndexer) EngineDaemonHealth(projectRoot string) EngineDaemonHealth {
+	if !daemonEnabled() {
+		return EngineDaemonHealth{}
+	}
+	return ai.daemon(projectRoot).health()
+}
+
+func (d *engineDaemon) health() EngineDaemonHealth {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h := EngineDaemonHealth{
+		Enabled:   true,
+		Supported: d.failures == 0,
+		Running:   d.proc != nil,
+		Requests:  d.requests,
+		Restarts:  max(d.starts-1, 0),
+		LastError: d.lastErr,
+	}
+	if d.proc != nil {
+		h.PID = d.proc.cmd.Process.Pid
+		h.StartedAt = d.started.Format(time.RFC3339)
+	}
+	if !d.retryAt.IsZero() {
+		h.RetryAt = d.retryAt.Format(time.RFC3339)
+	}
+	return h
+}
+
+// call 经常驻进程执行一次引擎请求；进程异常时重启并重试一次
+func (d *engineDaemon) call(ctx context.Context, args []string) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		p, err := d.running()
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.roundTrip(ctx, p, args, daemonCallTimeout)
+		var reqErr *daemonRequestError
+		if err == nil || errors.As(err, &reqErr) {
+			d.mu.Lock()
+			d.requests++
+			d.mu.Unlock()
+			return data, err
+		}
+		if ctx.Err() != nil {
+			return nil, err // 调用方取消：进程仍在为其他请求服务，迟到的响应由读协程丢弃
+		}
+		lastErr = err
+		d.mu.Lock()
+		d.lastErr = err.Error()
+		d.mu.Unlock()
+		d.stop(p)
+	}
+	return nil, lastErr
+}
+
+// running 返回运行中的子进程，必要时启动；退避期内返回 errDaemonUnsupported
+func (d *engineDaemon) running() (*daemonProc, error) {
+	d.startMu.Lock()
+	defer d.startMu.Unlock()
+	d.mu.Lock()
+	p, retryAt := d.proc, d.retryAt
+	d.mu.Unlock()
+	if p != nil {
+		return p, nil
+	}
+	if time.Now().Before(retryAt) {
+		return nil, errDaemonUnsupported
+	}
+	return d.start()
+}
+
+// start 启动 serve 子进程并 ping 确认可用；ping 失败时按连续失败次数指数退避
+func (d *engineDaemon) start() (*daemonProc, error) {
+	cmd := exec.Command(d.bin, "--mode", "serve", "--project", d.root, "--db", getDBPath(d.root))
+	cmd.Dir = d.root
+	cmd.Env = engineEnv(d.root)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		d.mu.Lock()
+		d.lastErr = err.Error()
+		d.mu.Unlock()
+		return nil, err
+	}
+	p := &daemonProc{cmd: cmd, stdin: stdin, pending: make(map[uint64]chan daemonResponse), done: make(chan struct{})}
+	go p.readLoop(stdout)
+
+	d.mu.Lock()
+	d.starts++
+	d.mu.Unlock()
+
+	if _, err := d.roundTrip(context.Background(), p, nil, daemonPingTimeout); err != nil {
+		p.kill()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.failures++
+		backoff := min(daemonBackoffMin<<min(d.failures-1, 5), daemonBackoffMax)
+		d.retryAt = time.Now().Add(backoff)
+		d.lastErr = fmt.Sprintf("常驻进程未响应 ping: %v（%s 后重试）", err, backoff)
+		return nil, errDaemonUnsupported
+	}
+
+	d.mu.Lock()
+	d.proc, d.started = p, time.Now()
+	d.failures, d.retryAt = 0, time.Time{}
+	d.mu.Unlock()
+	return p, nil
+}
+
+// readLoop 读取响应行并按 id 分发；无人等待的行（超时请求的迟到响应等）直接丢弃
+func (p *daemonProc) readLoop(stdout io.Reader) {
+	defer close(p.done)
+	r := bufio.NewReader(stdout)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var resp daemonResponse
+			if json.Unmarshal(line, &resp) == nil {
+				p.mu.Lock()
+				ch := p.pending[resp.ID]
+				delete(p.pending, resp.ID)
+				p.mu.Unlock()
+				if ch != nil {
+					ch <- resp
+				}
+			}
+		}
+		if err != nil {
+			_ = p.cmd.Wait()
+			return
+		}
+	}
+}
+
+func (d *engineDaemon) roundTrip(ctx context.Context, p *daemonProc, args []string, timeout time.Duration) ([]byte, error) {
+	d.mu.Lock()
+	d.nextID++
+	id := d.nextID
+	d.mu.Unlock()
+
+	ch := make(chan daemonResponse, 1)
+	p.mu.Lock()
+	p.pending[id] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	req, _ := json.Marshal(struct {
+		ID   uint64   `json:"id"`
+		Args []string `json:"args"`
+	}{id, args})
+	p.writeMu.Lock()
+	_, err := p.stdin.Write(append(req, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("写入常驻进程失败: %v", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var resp daemonResponse
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("常驻进程响应超时(%s)", timeout)
+	case resp = <-ch:
+	case <-p.done:
+		select {
+		case resp = <-ch: // 退出前刚好送达的响应
+		default:
+			return nil, errors.New("常驻进程已退出")
+		}
+	}
+	if !resp.OK {
+		return nil, &daemonRequestError{msg: resp.Error}
+	}
+	return resp.Result, nil
+}
+
+// stop 结束仍是当前进程的 p；其他调用已替换为新进程时只清理 p 自身
+func (d *engineDaemon) stop(p *daemonProc) {
+	d.mu.Lock()
+	if d.proc == p {
+		d.proc = nil
+	}
+	d.mu.Unlock()
+	p.kill()
+}
+
+// shutdown 服务退出时结束当前子进程
+func (d *engineDaemon) shutdown() {
+	d.mu.Lock()
+	p := d.proc
+	d.mu.Unlock()
+	if p != nil {
+		d.stop(p)
+	}
+}
+
+// kill 结束子进程（关闭 stdin 后引擎自行退出，再强制 kill 兜底）并等待读协程退出
+func (p *daemonProc) kill() {
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	<-p.done
+}
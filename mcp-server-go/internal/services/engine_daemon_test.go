@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestEngineDaemon_ServesRestartsAndFallsBack(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake engine is a shell script")
+	}
+	root := t.TempDir()
+	bin := filepath.Join(root, "fake_indexer")
+	// serve 模式按行应答；请求里带 crash 时进程直接退出
+	script := `#!/bin/sh
+if [ "$2" != serve ]; then echo '{"status":"spawned"}'; exit 0; fi
+while IFS= read -r line; do
+  case "$line" in *crash*) exit 1;; esac
+  id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+  echo "{\"id\":$id,\"ok\":true,\"result\":{\"status\":\"daemon\"}}"
+done
+`
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ai := &ASTIndexer{BinaryPath: bin}
+	run := func(query string) string {
+		data, err := ai.runEngine(context.Background(), root, []string{"--mode", "query", "--query", query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if got := run("a"); got != `{"status":"daemon"}` {
+		t.Fatalf("query should be served by daemon, got %s", got)
+	}
+	run("b")
+	h := ai.EngineDaemonHealth(root)
+	if !h.Running || !h.Supported || h.Requests != 2 || h.PID == 0 {
+		t.Fatalf("health = %+v", h)
+	}
+
+	// 崩溃：重启重试仍失败后回退为单次进程
+	if got := run("crash"); got != `{"status":"spawned"}` {
+		t.Errorf("crashing request should fall back to a one-off process, got %s", got)
+	}
+	if got := run("c"); got != `{"status":"daemon"}` {
+		t.Errorf("daemon should be restarted transparently, got %s", got)
+	}
+	if h := ai.EngineDaemonHealth(root); h.Restarts != 2 || h.LastError == "" {
+		t.Errorf("health after crash = %+v", h)
+	}
+
+	// 不支持 serve 的旧引擎：标记为不支持并回退
+	old := filepath.Join(root, "old_indexer")
+	if err := os.WriteFile(old, []byte("#!/bin/sh\necho '{\"status\":\"spawned\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := &ASTIndexer{BinaryPath: old}
+	if data, err := legacy.runEngine(context.Background(), root, []string{"--mode", "map"}); err != nil || string(data) != `{"status":"spawned"}` {
+		t.Fatalf("legacy engine: %s, %v", data, err)
+	}
+	if h := legacy.EngineDaemonHealth(root); h.Supported || h.Running {
+		t.Errorf("legacy health = %+v", h)
+	}
+}
+
+func TestEngineDaemon_SlowCallDoesNotBlockAndPingBacksOff(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake engine is a shell script")
+	}
+	root := t.TempDir()
+	bin := filepath.Join(root, "fake_indexer")
+	serve := `#!/bin/sh
+if [ "$2" != serve ]; then echo '{"status":"spawned"}'; exit 0; fi
+while IFS= read -r line; do
+  case "$line" in *slow*) sleep 1;; esac
+  id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+  echo "{\"id\":$id,\"ok\":true,\"result\":{\"status\":\"daemon\"}}"
+done
+`
+	// 先用不支持 serve 的引擎：ping 失败后进入退避而不是永久禁用
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho '{\"status\":\"spawned\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ai := &ASTIndexer{BinaryPath: bin}
+	if data, err := ai.runEngine(context.Background(), root, []string{"--mode", "query"}); err != nil || string(data) != `{"status":"spawned"}` {
+		t.Fatalf("fallback: %s, %v", data, err)
+	}
+	h := ai.EngineDaemonHealth(root)
+	if h.Supported || h.RetryAt == "" {
+		t.Fatalf("failed ping should schedule a retry: %+v", h)
+	}
+	if err := os.WriteFile(bin, []byte(serve), 0755); err != nil {
+		t.Fatal(err)
+	}
+	d := ai.daemon(root)
+	d.mu.Lock()
+	d.retryAt = time.Now() // 模拟退避结束
+	d.mu.Unlock()
+
+	done := make(chan string, 1)
+	go func() {
+		data, _ := ai.runEngine(context.Background(), root, []string{"--mode", "query", "--query", "slow"})
+		done <- string(data)
+	}()
+	time.Sleep(300 * time.Millisecond) // 让慢请求进入等待
+	started := time.Now()
+	h = ai.EngineDaemonHealth(root)
+	if time.Since(started) > 200*time.Millisecond {
+		t.Errorf("health should not wait for an in-flight call")
+	}
+	if !h.Supported || !h.Running {
+		t.Errorf("daemon should be retried after backoff: %+v", h)
+	}
+	if got := <-done; got != `{"status":"daemon"}` {
+		t.Errorf("slow call = %s", got)
+	}
+}
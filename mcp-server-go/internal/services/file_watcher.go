@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ============================================================================
+// 文件监听：变更驱动的增量索引
+// ============================================================================
+//
+// 监听项目目录（跳过与索引相同的忽略目录与扩展名规则，见 indexPathRules），把一段静默期内的变更合并为一批，
+// 按文件/目录范围调用 IndexScope，code_search / code_impact 查询时索引已是最新，
+// 不必等 5 分钟新鲜度过期后再整库刷新。
+// 变更范围过多或内核事件队列溢出时退回一次整库增量索引。
+
+const (
+	defaultWatchDebounce = 2 * time.Second
+	watchMaxScopes       = 8    // 单批最多的索引范围，超出时向上合并到父目录
+	watchMaxDirs         = 4000 // 监听目录上限，防止耗尽 inotify 配额
+)
+
+// FileWatcherStats 监听器运行状态
+type FileWatcherStats struct {
+	Root         string    `json:"root"`
+	WatchedDirs  int       `json:"watched_dirs"`
+	Truncated    bool      `json:"truncated,omitempty"` // 目录数超过上限，部分目录未监听
+	Batches      int       `json:"batches"`
+	FilesChanged int       `json:"files_changed"`
+	LastBatchAt  time.Time `json:"last_batch_at"`
+	LastScopes   []string  `json:"last_scopes,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// FileWatcher 项目文件监听器
+type FileWatcher struct {
+	root     string
+	debounce time.Duration
+	watcher  *fsnotify.Watcher
+	ignore   map[string]bool
+	exts     map[string]bool // 为空时不按扩展名过滤（与引擎全量扫描一致）
+	dataDir  string
+	index    func(scope string) error // scope 为空表示整库增量索引
+
+	pending map[string]bool
+	full    bool
+
+	mu    sync.Mutex
+	stats FileWatcherStats
+}
+
+// NewFileWatcher 创建监听器并注册项目目录；debounce<=0 时使用默认静默期
+func NewFileWatcher(ai *ASTIndexer, projectRoot string, debounce time.Duration) (*FileWatcher, error) {
+	root := normalizeProjectRoot(projectRoot)
+	return newFileWatcher(root, debounce, func(scope string) error {
+		var err error
+		if scope == "" {
+			_, err = ai.Index(root)
+		} else {
+			_, err = ai.IndexScope(root, scope)
+		}
+		return err
+	})
+}
+
+func newFileWatcher(root string, debounce time.Duration, index func(string) error) (*FileWatcher, error) {
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建文件监听失败: %v", err)
+	}
+	ignoreDirs, extensions := indexPathRules(root)
+	fw := &FileWatcher{
+		root:     root,
+		debounce: debounce,
+		watcher:  w,
+		ignore:   make(map[string]bool),
+		exts:     make(map[string]bool),
+		dataDir:  filepath.Clean(core.DataDir(root)),
+		index:    index,
+		pending:  make(map[string]bool),
+		stats:    FileWatcherStats{Root: root},
+	}
+	for _, d := range append(ignoreDirs, core.LegacyDataDirName, ".mcp-config") {
+		if d = strings.TrimSpace(d); d != "" {
+			fw.ignore[d] = true
+		}
+	}
+	for _, e := range extensions {
+		if e = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(e)), "."); e != "" {
+			fw.exts[e] = true
+		}
+	}
+	if err := fw.addTree(root); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return fw, nil
+}
+
+// Stats 返回监听状态快照
+func (fw *FileWatcher) Stats() FileWatcherStats {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	s := fw.stats
+	s.LastScopes = append([]string(nil), fw.stats.LastScopes...)
+	return s
+}
+
+// Run 处理文件事件直至 ctx 取消；阻塞调用，退出时释放监听句柄
+func (fw *FileWatcher) Run(ctx context.Context) {
+	defer fw.watcher.Close()
+	timer := time.NewTimer(fw.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			if fw.handle(ev) {
+				timer.Reset(fw.debounce)
+			}
+		case err, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				fw.full = true
+				timer.Reset(fw.debounce)
+			}
+			fw.setError(err)
+		case <-timer.C:
+			fw.flush()
+		}
+	}
+}
+
+// handle 记录一次变更，返回是否需要（重新）开始静默计时
+func (fw *FileWatcher) handle(ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	rel, ok := fw.relPath(ev.Name)
+	if !ok {
+		return false
+	}
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			if fw.ignoredDir(ev.Name, info.Name()) {
+				return false
+			}
+			if err := fw.addTree(ev.Name); err != nil {
+				fw.setError(err)
+			}
+			fw.pending[rel] = true
+			return true
+		}
+	}
+	if !fw.watchedFile(rel) {
+		return false
+	}
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		// 已不存在的文件按所在目录刷新，让索引清理其符号
+		fw.pending[path.Dir(rel)] = true
+	} else {
+		fw.pending[rel] = true
+	}
+	return true
+}
+
+// flush 把本批变更交给索引器
+func (fw *FileWatcher) flush() {
+	if len(fw.pending) == 0 && !fw.full {
+		return
+	}
+	changed := make([]string, 0, len(fw.pending))
+	for p := range fw.pending {
+		changed = append(changed, p)
+	}
+	fw.pending = make(map[string]bool)
+
+	scopes := batchScopes(changed, watchMaxScopes)
+	if fw.full {
+		scopes = nil
+	}
+	fw.full = false
+
+	var errs []string
+	if len(scopes) == 0 {
+		if err := fw.index(""); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, scope := range scopes {
+		if err := fw.index(scope); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", scope, err))
+		}
+	}
+
+	fw.mu.Lock()
+	fw.stats.Batches++
+	fw.stats.FilesChanged += len(changed)
+	fw.stats.LastBatchAt = time.Now()
+	fw.stats.LastScopes = scopes
+	fw.stats.LastError = strings.Join(errs, "; ")
+	fw.mu.Unlock()
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "[Watcher][WARN] 增量索引失败: %s\n", strings.Join(errs, "; "))
+	}
+}
+
+func (fw *FileWatcher) setError(err error) {
+	fw.mu.Lock()
+	fw.stats.LastError = err.Error()
+	fw.mu.Unlock()
+}
+
+// addTree 递归监听目录（fsnotify 不支持递归监听）
+func (fw *FileWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if p != fw.root && fw.ignoredDir(p, d.Name()) {
+			return filepath.SkipDir
+		}
+		fw.mu.Lock()
+		full := fw.stats.WatchedDirs >= watchMaxDirs
+		if full {
+			fw.stats.Truncated = true
+		}
+		fw.mu.Unlock()
+		if full {
+			return filepath.SkipAll
+		}
+		if err := fw.watcher.Add(p); err != nil {
+			return fmt.Errorf("监听目录失败 %s: %v", p, err)
+		}
+		fw.mu.Lock()
+		fw.stats.WatchedDirs++
+		fw.mu.Unlock()
+		return nil
+	})
+}
+
+func (fw *FileWatcher) ignoredDir(p, name string) bool {
+	return fw.ignore[name] || filepath.Clean(p) == fw.dataDir
+}
+
+// relPath 返回项目内的相对路径 (正斜杠)；位于忽略目录下时 ok=false
+func (fw *FileWatcher) relPath(name string) (string, bool) {
+	rel, err := filepath.Rel(fw.root, name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	if strings.HasPrefix(filepath.Clean(name), fw.dataDir+string(filepath.Separator)) {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, seg := range strings.Split(path.Dir(rel), "/") {
+		if fw.ignore[seg] {
+			return "", false
+		}
+	}
+	return rel, true
+}
+
+// watchedFile 文件是否参与索引（与引擎相同的扩展名规则，跳过编辑器临时文件）
+func (fw *FileWatcher) watchedFile(rel string) bool {
+	base := path.Base(rel)
+	if strings.HasSuffix(base, "~") || strings.HasPrefix(base, ".#") || strings.HasSuffix(base, ".swp") {
+		return false
+	}
+	if len(fw.exts) == 0 {
+		return true
+	}
+	return fw.exts[strings.TrimPrefix(strings.ToLower(path.Ext(base)), ".")]
+}
+
+// batchScopes 把变更路径合并为不超过 max 个互不包含的索引范围；
+// 需要合并到项目根目录时返回 nil，表示整库增量索引
+func batchScopes(changed []string, max int) []string {
+	set := make(map[string]bool)
+	for _, p := range changed {
+		set[path.Clean(p)] = true
+	}
+	for len(set) > max {
+		next := make(map[string]bool)
+		for p := range set {
+			next[path.Dir(p)] = true
+		}
+		set = next
+	}
+	if set["."] {
+		return nil
+	}
+
+	scopes := make([]string, 0, len(set))
+	for p := range set {
+		scopes = append(scopes, p)
+	}
+	sort.Strings(scopes)
+	result := scopes[:0]
+	for _, s := range scopes {
+		if n := len(result); n > 0 && strings.HasPrefix(s, result[n-1]+"/") {
+			continue // 已被父范围覆盖
+		}
+		result = append(result, s)
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchScopes(t *testing.T) {
+	got := batchScopes([]string{"internal/core/a.go", "internal/core", "cmd/main.go"}, 8)
+	if want := []string{"cmd/main.go", "internal/core"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batchScopes = %v, want %v", got, want)
+	}
+
+	var many []string
+	for _, dir := range []string{"a", "b", "c"} {
+		for _, f := range []string{"x.go", "y.go", "z.go"} {
+			many = append(many, "pkg/"+dir+"/"+f)
+		}
+	}
+	if got := batchScopes(many, 8); !reflect.DeepEqual(got, []string{"pkg/a", "pkg/b", "pkg/c"}) {
+		t.Fatalf("batchScopes should collapse to parent dirs, got %v", got)
+	}
+	if got := batchScopes([]string{"a.go", "b/c.go"}, 1); got != nil {
+		t.Fatalf("batchScopes should fall back to full index, got %v", got)
+	}
+}
+
+func TestFileWatcherBatchesChanges(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "go.mod"), []byte("module demo\n"), 0644)
+	os.MkdirAll(filepath.Join(root, "pkg"), 0755)
+	os.MkdirAll(filepath.Join(root, "node_modules"), 0755)
+	os.MkdirAll(filepath.Join(root, ".svelte-kit"), 0755) // 仅引擎内置忽略
+
+	var mu sync.Mutex
+	var scopes []string
+	indexed := make(chan struct{}, 8)
+	fw, err := newFileWatcher(root, 50*time.Millisecond, func(scope string) error {
+		mu.Lock()
+		scopes = append(scopes, scope)
+		mu.Unlock()
+		indexed <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go fw.Run(ctx)
+
+	os.WriteFile(filepath.Join(root, "node_modules", "dep.go"), []byte("package dep\n"), 0644)
+	os.WriteFile(filepath.Join(root, ".svelte-kit", "gen.go"), []byte("package gen\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "notes.md"), []byte("# notes\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "a.go"), []byte("package pkg\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "b.go"), []byte("package pkg\n"), 0644)
+
+	select {
+	case <-indexed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not trigger indexing")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 引擎全量扫描不按扩展名过滤，notes.md 同样会进入索引
+	if !reflect.DeepEqual(scopes, []string{"pkg/a.go", "pkg/b.go", "pkg/notes.md"}) {
+		t.Fatalf("indexed scopes = %v", scopes)
+	}
+	if st := fw.Stats(); st.Batches != 1 || st.FilesChanged != 3 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestIndexPathRules_LanguageShardsFilterExtensions(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644)
+	os.MkdirAll(filepath.Join(root, ".mcp-config"), 0755)
+	os.WriteFile(filepath.Join(root, ".mcp-config", "settings.json"), []byte(`{"index": {"shard_by": "language"}}`), 0644)
+
+	fw, err := newFileWatcher(root, time.Second, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.watcher.Close()
+	if !fw.watchedFile("pkg/a.go") || fw.watchedFile("README.txt") {
+		t.Errorf("language shards should restrict the watcher to shard extensions: %v", fw.exts)
+	}
+	if !fw.ignore["_build"] || !fw.ignore["vendor"] {
+		t.Errorf("engine and detected ignore dirs should both apply: %v", fw.ignore)
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+)
+
+// CalleeNames 符号在源码中的调用名（含未解析到项目内的外部调用，如 db.Select、producer.Produce），
+// 供 flow_trace 按框架/库识别副作用；按 名称 + 文件 定位，去重后按出现顺序返回
+func (ai *ASTIndexer) CalleeNames(projectRoot string, node *Node) []string {
+	if node == nil {
+		return nil
+	}
+	want := strings.TrimPrefix(filepath.ToSlash(node.FilePath), "./")
+	seen := make(map[string]bool)
+	var names []string
+	_ = eachIndexDB(projectRoot, want, func(db *sql.DB) error {
+		rows, err := db.Query(`
+			SELECT c.callee_name, COALESCE(f.file_path, '')
+			FROM calls c JOIN symbols s ON c.caller_id = s.symbol_id LEFT JOIN files f ON s.file_id = f.file_id
+			WHERE s.name = ? ORDER BY c.call_line`, node.Name)
+		if err != nil {
+			return nil
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name, file string
+			if rows.Scan(&name, &file) != nil || name == "" || seen[name] {
+				continue
+			}
+			if want != "" && strings.TrimPrefix(filepath.ToSlash(file), "./") != want {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names
+}
@@ -0,0 +1,101 @@
+package services
+
+import "sort"
+
+// ============================================================================
+// 多入口流程对比：各入口沿调用边向下游 BFS，求共同依赖、各自独有的依赖与分叉点
+// ============================================================================
+
+// FlowSharedNode 所有入口都会到达的下游符号；Depths[i] 为距第 i 个入口的调用深度
+type FlowSharedNode struct {
+	ID     int
+	Depths []int
+}
+
+// FlowComparison 多入口下游对比结果（入口顺序与输入一致）
+type FlowComparison struct {
+	Shared     []FlowSharedNode // 按最大深度、名称升序；最浅的即汇合点
+	Unique     [][]int          // 只有该入口会到达的符号
+	Divergence [][]int          // 该入口的直接被调用者中不被所有入口共享的：流程从这里开始分叉
+	Partial    int              // 被部分（多于一个、少于全部）入口到达的符号数
+}
+
+// CompareFlows 对比多个入口的下游调用；entries[i] 为第 i 个入口在调用图中的符号（同名多定义时可有多个）
+func (g *CallGraph) CompareFlows(entries [][]int, maxDepth int) *FlowComparison {
+	traces := make([]*ImpactTrace, len(entries))
+	isEntry := make(map[int]bool)
+	reachCount := make(map[int]int)
+	for i, ids := range entries {
+		traces[i] = g.TraceImpact(ids, false, maxDepth)
+		for _, id := range ids {
+			isEntry[id] = true
+		}
+	}
+	for _, tr := range traces {
+		for id, d := range tr.Depth {
+			if d > 0 {
+				reachCount[id]++
+			}
+		}
+	}
+
+	byName := func(ids []int) {
+		sort.Slice(ids, func(a, b int) bool {
+			if g.Symbols[ids[a]].Name != g.Symbols[ids[b]].Name {
+				return g.Symbols[ids[a]].Name < g.Symbols[ids[b]].Name
+			}
+			return ids[a] < ids[b]
+		})
+	}
+
+	c := &FlowComparison{Unique: make([][]int, len(entries)), Divergence: make([][]int, len(entries))}
+	for id, n := range reachCount {
+		if isEntry[id] {
+			continue
+		}
+		switch {
+		case n == len(entries):
+			node := FlowSharedNode{ID: id, Depths: make([]int, len(entries))}
+			for i, tr := range traces {
+				node.Depths[i] = tr.Depth[id]
+			}
+			c.Shared = append(c.Shared, node)
+		case n > 1:
+			c.Partial++
+		}
+	}
+	sort.Slice(c.Shared, func(a, b int) bool {
+		da, db := deepest(c.Shared[a].Depths), deepest(c.Shared[b].Depths)
+		if da != db {
+			return da < db
+		}
+		return g.Symbols[c.Shared[a].ID].Name < g.Symbols[c.Shared[b].ID].Name
+	})
+
+	for i, tr := range traces {
+		for id, d := range tr.Depth {
+			if d == 0 || isEntry[id] || reachCount[id] == len(entries) {
+				continue
+			}
+			if reachCount[id] == 1 {
+				c.Unique[i] = append(c.Unique[i], id)
+			}
+			if d == 1 {
+				c.Divergence[i] = append(c.Divergence[i], id)
+			}
+		}
+		byName(c.Unique[i])
+		byName(c.Divergence[i])
+	}
+	return c
+}
+
+func deepest(xs []int) int {
+	m := 0
+	for _, x := range xs {
+		if x > m {
+			m = x
+		}
+	}
+	return m
+}
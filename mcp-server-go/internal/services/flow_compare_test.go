@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestCompareFlows(t *testing.T) {
+	// createOrder → validate → save → exec；importOrder → parse → validate；audit 只被 createOrder 调用
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "createOrder"},
+			2: {SymbolID: 2, Name: "importOrder"},
+			3: {SymbolID: 3, Name: "validate"},
+			4: {SymbolID: 4, Name: "save"},
+			5: {SymbolID: 5, Name: "exec"},
+			6: {SymbolID: 6, Name: "audit"},
+			7: {SymbolID: 7, Name: "parse"},
+		},
+		Edges: map[int][]int{1: {3, 6}, 2: {7}, 7: {3}, 3: {4}, 4: {5}},
+	}
+	c := g.CompareFlows([][]int{{1}, {2}}, 0)
+
+	if len(c.Shared) != 3 || c.Shared[0].ID != 3 {
+		t.Fatalf("shared = %+v, want validate first", c.Shared)
+	}
+	if d := c.Shared[0].Depths; d[0] != 1 || d[1] != 2 {
+		t.Errorf("validate depths = %v, want [1 2]", d)
+	}
+	if len(c.Unique[0]) != 1 || c.Unique[0][0] != 6 {
+		t.Errorf("createOrder unique = %v, want [audit]", c.Unique[0])
+	}
+	if len(c.Divergence[0]) != 1 || c.Divergence[0][0] != 6 {
+		t.Errorf("createOrder divergence = %v, want [audit]", c.Divergence[0])
+	}
+	if len(c.Divergence[1]) != 1 || c.Divergence[1][0] != 7 {
+		t.Errorf("importOrder divergence = %v, want [parse]", c.Divergence[1])
+	}
+}
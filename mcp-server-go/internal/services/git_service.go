@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Git 集成：blame / log 得到"最后修改人/时间"与文件变更频率（churn）
+// ============================================================================
+//
+// 直接调用本机 git，不可用或项目不在 git 仓库中时所有方法返回错误，调用方静默跳过。
+
+// GitChurnDays churn 统计的时间窗口（天）
+const GitChurnDays = 90
+
+const gitTimeout = 10 * time.Second
+
+// gitChurnTTL churn 结果缓存时间，避免每次地图/影响分析都遍历 git log
+const gitChurnTTL = 5 * time.Minute
+
+// GitService 项目的 git 查询
+type GitService struct {
+	Root string
+}
+
+// NewGitService 创建 GitService
+func NewGitService(projectRoot string) *GitService {
+	return &GitService{Root: normalizeProjectRoot(projectRoot)}
+}
+
+// GitLastChange 最后一次修改
+type GitLastChange struct {
+	Commit string    `json:"commit"`
+	Author string    `json:"author"`
+	Time   time.Time `json:"time"`
+}
+
+// GitFileHistory 文件（或行范围）的修改历史摘要
+type GitFileHistory struct {
+	File    string         `json:"file"`
+	Last    *GitLastChange `json:"last,omitempty"`
+	Commits int            `json:"commits"` // 时间窗口内修改该文件的提交数
+	Authors int            `json:"authors"` // 时间窗口内的不同作者数
+}
+
+// GitHotspot 高变更频率 + 高扇入的符号（Score = churn × fanIn）
+type GitHotspot struct {
+	ReportSymbol
+	Churn int `json:"churn"`
+}
+
+func (s *GitService) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", s.Root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func gitRelPath(file string) string {
+	return strings.TrimPrefix(path.Clean(strings.ReplaceAll(file, "\\", "/")), "./")
+}
+
+// LastChange 行范围 [start, end] 内最近一次修改（git blame）；start<=0 时取整个文件的最后一次提交
+func (s *GitService) LastChange(file string, start, end int) (*GitLastChange, error) {
+	file = gitRelPath(file)
+	if start <= 0 {
+		out, err := s.run("log", "-1", "--format=%H%x00%an%x00%at", "--", file)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(strings.TrimSpace(string(out)), "\x00")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%s 没有提交记录", file)
+		}
+		ts, _ := strconv.ParseInt(parts[2], 10, 64)
+		return &GitLastChange{Commit: parts[0], Author: parts[1], Time: time.Unix(ts, 0)}, nil
+	}
+	if end < start {
+		end = start
+	}
+	out, err := s.run("blame", "--line-porcelain", "-L", fmt.Sprintf("%d,%d", start, end), "--", file)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlameLatest(out)
+}
+
+// parseBlameLatest 从 --line-porcelain 输出中取提交时间最新的一行
+func parseBlameLatest(out []byte) (*GitLastChange, error) {
+	var latest *GitLastChange
+	var cur GitLastChange
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// 源码行，本行信息结束；未提交的修改（全 0 哈希）不计入
+			if cur.Commit != "" && strings.Trim(cur.Commit, "0") != "" && (latest == nil || cur.Time.After(latest.Time)) {
+				c := cur
+				latest = &c
+			}
+			cur = GitLastChange{}
+		case strings.HasPrefix(line, "author "):
+			cur.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			ts, _ := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+			cur.Time = time.Unix(ts, 0)
+		case cur.Commit == "" && len(line) >= 40 && !strings.Contains(line[:40], " "):
+			cur.Commit = line[:40]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("没有已提交的修改")
+	}
+	return latest, nil
+}
+
+type gitChurnEntry struct {
+	at      time.Time
+	commits map[string]int
+	authors map[string]map[string]bool
+}
+
+var (
+	gitChurnMu    sync.Mutex
+	gitChurnCache = make(map[string]*gitChurnEntry)
+)
+
+// churn 时间窗口内各文件（项目相对路径）的提交数与作者，结果缓存 gitChurnTTL
+func (s *GitService) churn() (*gitChurnEntry, error) {
+	gitChurnMu.Lock()
+	defer gitChurnMu.Unlock()
+	if e, ok := gitChurnCache[s.Root]; ok && time.Since(e.at) < gitChurnTTL {
+		return e, nil
+	}
+	out, err := s.run("log", fmt.Sprintf("--since=%d.days", GitChurnDays), "--no-merges", "--relative",
+		"--name-only", "--format=\x01%an")
+	if err != nil {
+		return nil, err
+	}
+	e := parseGitChurn(out)
+	gitChurnCache[s.Root] = e
+	return e, nil
+}
+
+// parseGitChurn 解析 log 输出：\x01 开头的行为作者，其后为该提交修改的文件
+func parseGitChurn(out []byte) *gitChurnEntry {
+	e := &gitChurnEntry{at: time.Now(), commits: make(map[string]int), authors: make(map[string]map[string]bool)}
+	var author string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "\x01") {
+			author = line[1:]
+			continue
+		}
+		e.commits[line]++
+		if e.authors[line] == nil {
+			e.authors[line] = make(map[string]bool)
+		}
+		e.authors[line][author] = true
+	}
+	return e
+}
+
+// Churn 时间窗口内各文件的提交数
+func (s *GitService) Churn() (map[string]int, error) {
+	e, err := s.churn()
+	if err != nil {
+		return nil, err
+	}
+	return e.commits, nil
+}
+
+// FileHistory 文件（start>0 时为行范围）的最后修改与变更频率
+func (s *GitService) FileHistory(file string, start, end int) (*GitFileHistory, error) {
+	file = gitRelPath(file)
+	last, err := s.LastChange(file, start, end)
+	if err != nil {
+		return nil, err
+	}
+	h := &GitFileHistory{File: file, Last: last}
+	if e, err := s.churn(); err == nil {
+		h.Commits = e.commits[file]
+		h.Authors = len(e.authors[file])
+	}
+	return h, nil
+}
+
+// Hotspots 变更频率与扇入都高的符号：score = churn × fanIn，只保留 churn>=minChurn 且 fanIn>=minFanIn 的
+func (g *CallGraph) Hotspots(churn map[string]int, minChurn, minFanIn, limit int) []GitHotspot {
+	var out []GitHotspot
+	for id, sym := range g.Symbols {
+		c := churn[gitRelPath(sym.FilePath)]
+		fanIn := g.FanIn[id]
+		if c < minChurn || fanIn < minFanIn {
+			continue
+		}
+		rs := toReportSymbol(sym, fanIn, g.FanOut[id])
+		rs.Score = float64(c * fanIn)
+		out = append(out, GitHotspot{ReportSymbol: rs, Churn: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Name < out[j].Name
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// HumanizeAge 时间距今的可读描述
+func HumanizeAge(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%d 分钟前", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d 小时前", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d 天前", int(d.Hours()/24))
+}
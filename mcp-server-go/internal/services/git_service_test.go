@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestParseBlameLatest(t *testing.T) {
+	out := []byte("1111111111111111111111111111111111111111 3 3 1\n" +
+		"author alice\nauthor-time 1700000000\nsummary old\nfilename a.go\n\tfunc a() {\n" +
+		"2222222222222222222222222222222222222222 4 4 1\n" +
+		"author bob\nauthor-time 1710000000\nsummary new\nfilename a.go\n\treturn\n" +
+		"0000000000000000000000000000000000000000 5 5 1\n" +
+		"author Not Committed Yet\nauthor-time 1720000000\nfilename a.go\n\t}\n")
+	last, err := parseBlameLatest(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Author != "bob" || last.Commit[:4] != "2222" {
+		t.Fatalf("last = %+v, want bob/2222", last)
+	}
+}
+
+func TestHotspotsFromChurn(t *testing.T) {
+	churn := parseGitChurn([]byte("\x01alice\npkg/a.go\npkg/b.go\n\n\x01bob\npkg/a.go\n\n\x01alice\npkg/a.go\n"))
+	if churn.commits["pkg/a.go"] != 3 || len(churn.authors["pkg/a.go"]) != 2 {
+		t.Fatalf("churn a.go = %d commits / %d authors", churn.commits["pkg/a.go"], len(churn.authors["pkg/a.go"]))
+	}
+
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "Hot", FilePath: "pkg/a.go"},
+			2: {SymbolID: 2, Name: "Cold", FilePath: "pkg/b.go"},
+			3: {SymbolID: 3, Name: "Lonely", FilePath: "./pkg/a.go"},
+		},
+		FanIn:  map[int]int{1: 4, 2: 9, 3: 1},
+		FanOut: map[int]int{},
+	}
+	hs := g.Hotspots(churn.commits, 2, 2, 0)
+	if len(hs) != 1 || hs[0].Name != "Hot" || hs[0].Churn != 3 || hs[0].Score != 12 {
+		t.Fatalf("hotspots = %+v", hs)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 影响传播路径 (按深度限制的 BFS + 调用链还原)
+// ============================================================================
+
+// ImpactTrace 从目标符号出发按调用边做有界 BFS 的结果
+type ImpactTrace struct {
+	Backward bool
+	MaxDepth int
+	Depth    map[int]int // 可达符号 -> 与目标的距离（目标本身为 0）
+	parent   map[int]int // BFS 树中更靠近目标的一跳
+	targets  map[int]bool
+}
+
+// SymbolsByName 按名称查找可调用符号（支持 Type.Method / pkg.Func 形式的限定名，按最后一段匹配）
+func (g *CallGraph) SymbolsByName(name string) []int {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	var ids []int
+	for id, s := range g.Symbols {
+		if s.Name == name {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// TraceImpact 从 targets 出发做 BFS：backward 沿"谁调用了我"，否则沿"我调用了谁"；maxDepth<=0 表示不限深度
+func (g *CallGraph) TraceImpact(targets []int, backward bool, maxDepth int) *ImpactTrace {
+	next := g.Edges
+	if backward {
+		next = make(map[int][]int)
+		for caller, callees := range g.Edges {
+			for _, c := range callees {
+				next[c] = append(next[c], caller)
+			}
+		}
+		for id := range next {
+			sort.Ints(next[id])
+		}
+	}
+
+	tr := &ImpactTrace{
+		Backward: backward,
+		MaxDepth: maxDepth,
+		Depth:    make(map[int]int),
+		parent:   make(map[int]int),
+		targets:  make(map[int]bool),
+	}
+	queue := make([]int, 0, len(targets))
+	for _, t := range targets {
+		tr.Depth[t] = 0
+		tr.targets[t] = true
+		queue = append(queue, t)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if maxDepth > 0 && tr.Depth[cur] >= maxDepth {
+			continue
+		}
+		for _, n := range next[cur] {
+			if _, seen := tr.Depth[n]; seen {
+				continue
+			}
+			if _, ok := g.Symbols[n]; !ok {
+				continue
+			}
+			tr.Depth[n] = tr.Depth[cur] + 1
+			tr.parent[n] = cur
+			queue = append(queue, n)
+		}
+	}
+	return tr
+}
+
+// Layers 各层（距离 1..MaxDepth）的符号数
+func (tr *ImpactTrace) Layers() []int {
+	var layers []int
+	for id, d := range tr.Depth {
+		if tr.targets[id] {
+			continue
+		}
+		for len(layers) < d {
+			layers = append(layers, 0)
+		}
+		layers[d-1]++
+	}
+	return layers
+}
+
+// Paths 还原到目标的调用链，只取链的末端（不再有更远的可达符号），按长度降序、名称升序，最多 limit 条。
+// 每条链按调用方向排列：backward 为 入口 → ... → 目标，forward 为 目标 → ... → 被调用者
+func (tr *ImpactTrace) Paths(g *CallGraph, limit int) [][]int {
+	hasChild := make(map[int]bool)
+	for _, p := range tr.parent {
+		hasChild[p] = true
+	}
+	var ends []int
+	for id := range tr.Depth {
+		if !tr.targets[id] && !hasChild[id] {
+			ends = append(ends, id)
+		}
+	}
+	sort.Slice(ends, func(i, j int) bool {
+		di, dj := tr.Depth[ends[i]], tr.Depth[ends[j]]
+		if di != dj {
+			return di > dj
+		}
+		return g.Symbols[ends[i]].Name < g.Symbols[ends[j]].Name
+	})
+	if limit > 0 && len(ends) > limit {
+		ends = ends[:limit]
+	}
+
+	paths := make([][]int, 0, len(ends))
+	for _, end := range ends {
+		chain := []int{end}
+		for cur := end; !tr.targets[cur]; {
+			cur = tr.parent[cur]
+			chain = append(chain, cur)
+		}
+		if !tr.Backward {
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+		}
+		paths = append(paths, chain)
+	}
+	return paths
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTraceImpactDepthAndPaths(t *testing.T) {
+	g := &CallGraph{
+		Symbols: map[int]*GraphSymbol{
+			1: {SymbolID: 1, Name: "handler"},
+			2: {SymbolID: 2, Name: "service"},
+			3: {SymbolID: 3, Name: "repo"},
+			4: {SymbolID: 4, Name: "target"},
+			5: {SymbolID: 5, Name: "job"},
+		},
+		Edges: map[int][]int{
+			1: {2},
+			2: {3},
+			3: {4},
+			5: {3},
+		},
+	}
+
+	targets := g.SymbolsByName("Store.target")
+	tr := g.TraceImpact(targets, true, 0)
+	if got := tr.Layers(); !reflect.DeepEqual(got, []int{1, 2, 1}) {
+		t.Fatalf("layers = %v", got)
+	}
+	if got := tr.Paths(g, 10); !reflect.DeepEqual(got, [][]int{{1, 2, 3, 4}, {5, 3, 4}}) {
+		t.Fatalf("backward paths = %v", got)
+	}
+
+	tr = g.TraceImpact(targets, true, 2)
+	if _, ok := tr.Depth[1]; ok {
+		t.Fatal("handler is beyond max_depth=2")
+	}
+	if got := tr.Paths(g, 10); !reflect.DeepEqual(got, [][]int{{5, 3, 4}, {2, 3, 4}}) {
+		t.Fatalf("bounded paths = %v", got)
+	}
+
+	if got := g.TraceImpact([]int{1}, false, 0).Paths(g, 10); !reflect.DeepEqual(got, [][]int{{1, 2, 3, 4}}) {
+		t.Fatalf("forward paths = %v", got)
+	}
+}
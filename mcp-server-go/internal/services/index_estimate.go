@@ -0,0 +1,204 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ============================================================================
+// 索引预估：不调用 Rust 引擎，快速统计候选文件并按历史吞吐预测耗时
+// ============================================================================
+
+const (
+	indexHistoryLimit = 20
+	// defaultFilesPerSecond 无历史记录时使用的保守吞吐估计
+	defaultFilesPerSecond = 150.0
+)
+
+// IndexThroughputSample 一次索引运行的吞吐记录
+type IndexThroughputSample struct {
+	At        string `json:"at"`
+	Scope     string `json:"scope,omitempty"`
+	Files     int    `json:"files"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// EstimateBucket 按扩展名或目录聚合的文件数
+type EstimateBucket struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+}
+
+// IndexEstimate 索引预估结果
+type IndexEstimate struct {
+	Scope            string           `json:"scope,omitempty"`
+	CandidateFiles   int              `json:"candidate_files"`
+	SkippedDirs      int              `json:"skipped_dirs"`
+	ByExtension      []EstimateBucket `json:"by_extension"`
+	ByDirectory      []EstimateBucket `json:"by_directory"`
+	FilesPerSecond   float64          `json:"files_per_second"`
+	ThroughputSource string           `json:"throughput_source"` // history / default
+	HistorySamples   int              `json:"history_samples"`
+	PredictedMs      int64            `json:"predicted_ms"`
+	ScanMs           int64            `json:"scan_ms"`
+}
+
+func indexHistoryPath(projectRoot string) string {
+	return core.DataPath(projectRoot, "index_history.json")
+}
+
+// LoadIndexHistory 读取历史吞吐记录（最新在后）
+func LoadIndexHistory(projectRoot string) []IndexThroughputSample {
+	raw, err := os.ReadFile(indexHistoryPath(projectRoot))
+	if err != nil {
+		return nil
+	}
+	var samples []IndexThroughputSample
+	if err := json.Unmarshal(raw, &samples); err != nil {
+		return nil
+	}
+	return samples
+}
+
+// recordIndexThroughput 记录一次实际解析的吞吐，仅保留最近 indexHistoryLimit 条
+func recordIndexThroughput(projectRoot, scope string, result *IndexResult) {
+	files := result.ParsedFiles
+	if files <= 0 {
+		files = result.TotalFiles
+	}
+	// 增量运行几乎不解析文件，吞吐无参考价值
+	if files <= 0 || result.ElapsedMs <= 0 {
+		return
+	}
+	samples := append(LoadIndexHistory(projectRoot), IndexThroughputSample{
+		At:        time.Now().Format(time.RFC3339),
+		Scope:     scope,
+		Files:     files,
+		ElapsedMs: result.ElapsedMs,
+	})
+	if len(samples) > indexHistoryLimit {
+		samples = samples[len(samples)-indexHistoryLimit:]
+	}
+	raw, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return
+	}
+	path := indexHistoryPath(projectRoot)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+// EstimateIndex 统计 scope 内的候选文件（按扩展名/scope 下一级目录），并依据历史吞吐预测全量索引耗时
+func EstimateIndex(projectRoot string, scope string) (*IndexEstimate, error) {
+	started := time.Now()
+	extensions, ignoreDirs := detectTechStackAndConfig(projectRoot)
+
+	ignoreSet := make(map[string]bool)
+	for _, dir := range strings.Split(ignoreDirs, ",") {
+		d := strings.TrimSpace(strings.ToLower(strings.Trim(dir, "/\\")))
+		if d != "" {
+			ignoreSet[d] = true
+		}
+	}
+	// 仅统计检测到的技术栈扩展名（引擎可解析的源码），未检测到技术栈时不限制
+	extSet := make(map[string]bool)
+	for _, ext := range strings.Split(extensions, ",") {
+		ext = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(ext)), ".")
+		if ext != "" {
+			extSet[ext] = true
+		}
+	}
+
+	scope = strings.Trim(strings.TrimSpace(filepath.ToSlash(scope)), "/")
+	if scope == "." {
+		scope = ""
+	}
+	base := projectRoot
+	if scope != "" {
+		base = filepath.Join(projectRoot, filepath.FromSlash(scope))
+	}
+	if _, err := os.Stat(base); err != nil {
+		return nil, err
+	}
+
+	est := &IndexEstimate{Scope: scope}
+	byExt := make(map[string]int)
+	byDir := make(map[string]int)
+
+	var walk func(dir, rel string)
+	walk = func(dir, rel string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() {
+				if shouldSkipDetectDir(strings.ToLower(name), ignoreSet) {
+					est.SkippedDirs++
+					continue
+				}
+				walk(filepath.Join(dir, name), filepath.ToSlash(filepath.Join(rel, name)))
+				continue
+			}
+			ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+			if len(extSet) > 0 && !extSet[ext] {
+				continue
+			}
+			est.CandidateFiles++
+			if ext == "" {
+				ext = "(none)"
+			}
+			byExt[ext]++
+			top := "."
+			if rel != "" {
+				top = strings.SplitN(rel, "/", 2)[0]
+			}
+			byDir[top]++
+		}
+	}
+	walk(base, "")
+
+	est.ByExtension = sortedBuckets(byExt)
+	est.ByDirectory = sortedBuckets(byDir)
+
+	est.FilesPerSecond = defaultFilesPerSecond
+	est.ThroughputSource = "default"
+	var files int
+	var ms int64
+	for _, s := range LoadIndexHistory(projectRoot) {
+		files += s.Files
+		ms += s.ElapsedMs
+		est.HistorySamples++
+	}
+	if files > 0 && ms > 0 {
+		est.FilesPerSecond = float64(files) * 1000 / float64(ms)
+		est.ThroughputSource = "history"
+	}
+	est.PredictedMs = int64(float64(est.CandidateFiles) / est.FilesPerSecond * 1000)
+	est.ScanMs = time.Since(started).Milliseconds()
+	return est, nil
+}
+
+func sortedBuckets(counts map[string]int) []EstimateBucket {
+	buckets := make([]EstimateBucket, 0, len(counts))
+	for name, n := range counts {
+		buckets = append(buckets, EstimateBucket{Name: name, Files: n})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Files != buckets[j].Files {
+			return buckets[i].Files > buckets[j].Files
+		}
+		return buckets[i].Name < buckets[j].Name
+	})
+	return buckets
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// 索引问题视图：未解析调用 + 死代码候选 + 调用环 (project_map level=issues)
+// ============================================================================
+
+// UnresolvedGroup 按被调名聚合的未解析调用
+type UnresolvedGroup struct {
+	CalleeName string           `json:"callee_name"`
+	Count      int              `json:"count"`
+	Samples    []UnresolvedCall `json:"samples"`
+}
+
+// DeadSymbol 无任何调用方且不是入口点的符号
+type DeadSymbol struct {
+	ReportSymbol
+	Exported bool `json:"exported"` // 导出符号可能被项目外部调用，置信度较低
+}
+
+// IndexIssues 索引问题报告
+type IndexIssues struct {
+	TotalSymbols    int               `json:"total_symbols"`
+	UnresolvedTotal int               `json:"unresolved_total"`
+	Unresolved      []UnresolvedGroup `json:"unresolved"`
+	DeadTotal       int               `json:"dead_total"`
+	Dead            []DeadSymbol      `json:"dead"`
+	CycleTotal      int               `json:"cycle_total"`
+	Cycles          []ReportCycle     `json:"cycles"`
+}
+
+// entryPointNames 由运行时/框架隐式调用的函数名
+var entryPointNames = map[string]bool{
+	"main": true, "init": true, "__init__": true, "__main__": true, "__call__": true,
+	"setUp": true, "tearDown": true, "setup": true, "teardown": true,
+	"String": true, "Error": true, "ServeHTTP": true,
+}
+
+// isEntryPoint 判断符号是否为隐式入口（main/init、测试函数、测试文件中的符号、HTTP 处理器等）
+func isEntryPoint(sym *GraphSymbol) bool {
+	if entryPointNames[sym.Name] || isHTTPHandler(sym) {
+		return true
+	}
+	for _, prefix := range []string{"Test", "Benchmark", "Example", "Fuzz", "test_"} {
+		if strings.HasPrefix(sym.Name, prefix) {
+			return true
+		}
+	}
+	base := path.Base(strings.ReplaceAll(sym.FilePath, "\\", "/"))
+	return strings.HasSuffix(base, "_test.go") || strings.HasPrefix(base, "test_") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.")
+}
+
+// DeadCandidates 返回没有其他符号调用（自递归不计）且不是入口点的符号 ID，按文件/行号排序
+func (g *CallGraph) DeadCandidates() []int {
+	called := make(map[int]bool)
+	for caller, callees := range g.Edges {
+		for _, c := range callees {
+			if c != caller {
+				called[c] = true
+			}
+		}
+	}
+	var ids []int
+	for id, sym := range g.Symbols {
+		if called[id] || isEntryPoint(sym) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := g.Symbols[ids[i]], g.Symbols[ids[j]]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.LineStart < b.LineStart
+	})
+	return ids
+}
+
+// BuildIndexIssues 生成未解析调用与死代码候选视图
+// scope 非空时只保留该目录下的条目；limit 限制每类条目数量（<=0 表示默认 100）
+func (ai *ASTIndexer) BuildIndexIssues(projectRoot string, scope string, limit int, tagger *PathTagger) (*IndexIssues, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	g, err := ai.LoadCallGraph(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	g.ExcludePaths(tagger)
+
+	scope = strings.Trim(strings.ReplaceAll(strings.TrimSpace(scope), "\\", "/"), "/")
+	inScope := func(file string) bool {
+		if scope == "" || scope == "." {
+			return true
+		}
+		file = strings.TrimPrefix(strings.ReplaceAll(file, "\\", "/"), "./")
+		return file == scope || strings.HasPrefix(file, scope+"/")
+	}
+
+	issues := &IndexIssues{TotalSymbols: len(g.Symbols)}
+
+	groups := make(map[string]*UnresolvedGroup)
+	for _, u := range g.Unresolved {
+		if !inScope(u.FilePath) {
+			continue
+		}
+		issues.UnresolvedTotal++
+		grp, ok := groups[u.CalleeName]
+		if !ok {
+			grp = &UnresolvedGroup{CalleeName: u.CalleeName}
+			groups[u.CalleeName] = grp
+		}
+		grp.Count++
+		if len(grp.Samples) < 3 {
+			grp.Samples = append(grp.Samples, u)
+		}
+	}
+	for _, grp := range groups {
+		issues.Unresolved = append(issues.Unresolved, *grp)
+	}
+	// 偶发的未解析调用更可能是拼写错误，排在前面；高频的多为外部库或动态分发
+	sort.Slice(issues.Unresolved, func(i, j int) bool {
+		a, b := issues.Unresolved[i], issues.Unresolved[j]
+		if a.Count != b.Count {
+			return a.Count < b.Count
+		}
+		return a.CalleeName < b.CalleeName
+	})
+	if len(issues.Unresolved) > limit {
+		issues.Unresolved = issues.Unresolved[:limit]
+	}
+
+	for _, id := range g.DeadCandidates() {
+		sym := g.Symbols[id]
+		if !inScope(sym.FilePath) {
+			continue
+		}
+		issues.DeadTotal++
+		if len(issues.Dead) >= limit {
+			continue
+		}
+		exported := false
+		for _, r := range sym.Name {
+			exported = unicode.IsUpper(r)
+			break
+		}
+		issues.Dead = append(issues.Dead, DeadSymbol{
+			ReportSymbol: toReportSymbol(sym, g.FanIn[id], g.FanOut[id]),
+			Exported:     exported,
+		})
+	}
+
+	// 调用环：任一成员位于 scope 内即列出（环本身可能跨目录）
+	for _, comp := range g.StronglyConnected() {
+		cycle := g.ReportCycle(comp)
+		in := false
+		for _, f := range cycle.Files {
+			if inScope(f) {
+				in = true
+				break
+			}
+		}
+		if !in {
+			continue
+		}
+		issues.CycleTotal++
+		if len(issues.Cycles) < limit {
+			issues.Cycles = append(issues.Cycles, cycle)
+		}
+	}
+	return issues, nil
+}
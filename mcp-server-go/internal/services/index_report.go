@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ============================================================================
+// 索引审查报告 (JSON / SARIF)
+// ============================================================================
+
+// ReportSymbol 报告中的高风险符号
+type ReportSymbol struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	FilePath string  `json:"file_path"`
+	Line     int     `json:"line"`
+	FanIn    int     `json:"fan_in"`
+	FanOut   int     `json:"fan_out"`
+	Score    float64 `json:"score"`
+}
+
+// ReportCycle 调用环
+type ReportCycle struct {
+	Symbols []ReportSymbol `json:"symbols"`
+	Files   []string       `json:"files"`
+}
+
+// IndexReport 索引审查报告
+type IndexReport struct {
+	GeneratedAt     string           `json:"generated_at"`
+	Threshold       float64          `json:"threshold"`
+	TotalSymbols    int              `json:"total_symbols"`
+	TotalCalls      int              `json:"total_calls"`
+	HighRiskSymbols []ReportSymbol   `json:"high_risk_symbols"`
+	Cycles          []ReportCycle    `json:"cycles"`
+	UnresolvedCalls []UnresolvedCall `json:"unresolved_calls"`
+	UnresolvedTotal int              `json:"unresolved_total"`
+}
+
+// BuildIndexReport 基于 symbols.db 生成高风险符号、调用环和未解析调用报告
+// limit 限制每一类条目数量（<=0 表示默认 200）；tagger 非空时排除 vendored/generated 文件
+func (ai *ASTIndexer) BuildIndexReport(projectRoot string, threshold float64, limit int, tagger *PathTagger) (*IndexReport, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+
+	g, err := ai.LoadCallGraph(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	g.ExcludePaths(tagger)
+
+	report := &IndexReport{
+		GeneratedAt:     time.Now().Format(time.RFC3339),
+		Threshold:       threshold,
+		TotalSymbols:    len(g.Symbols),
+		TotalCalls:      g.TotalCalls,
+		UnresolvedTotal: len(g.Unresolved),
+	}
+
+	for id, sym := range g.Symbols {
+		fanIn, fanOut := g.FanIn[id], g.FanOut[id]
+		score := float64(fanOut)*1.0 + float64(fanIn)*0.5
+		if score < threshold {
+			continue
+		}
+		report.HighRiskSymbols = append(report.HighRiskSymbols, toReportSymbol(sym, fanIn, fanOut))
+	}
+	sort.Slice(report.HighRiskSymbols, func(i, j int) bool {
+		return report.HighRiskSymbols[i].Score > report.HighRiskSymbols[j].Score
+	})
+	if len(report.HighRiskSymbols) > limit {
+		report.HighRiskSymbols = report.HighRiskSymbols[:limit]
+	}
+
+	for _, comp := range g.StronglyConnected() {
+		report.Cycles = append(report.Cycles, g.ReportCycle(comp))
+		if len(report.Cycles) >= limit {
+			break
+		}
+	}
+
+	report.UnresolvedCalls = g.Unresolved
+	if len(report.UnresolvedCalls) > limit {
+		report.UnresolvedCalls = report.UnresolvedCalls[:limit]
+	}
+
+	return report, nil
+}
+
+// ReportCycle 把强连通分量转换为报告条目（涉及的文件去重排序）
+func (g *CallGraph) ReportCycle(comp []int) ReportCycle {
+	var cycle ReportCycle
+	files := make(map[string]bool)
+	for _, id := range comp {
+		sym := g.Symbols[id]
+		cycle.Symbols = append(cycle.Symbols, toReportSymbol(sym, g.FanIn[id], g.FanOut[id]))
+		if !files[sym.FilePath] {
+			files[sym.FilePath] = true
+			cycle.Files = append(cycle.Files, sym.FilePath)
+		}
+	}
+	sort.Strings(cycle.Files)
+	return cycle
+}
+
+func toReportSymbol(sym *GraphSymbol, fanIn, fanOut int) ReportSymbol {
+	return ReportSymbol{
+		Name:     sym.Name,
+		Type:     sym.Type,
+		FilePath: sym.FilePath,
+		Line:     sym.LineStart,
+		FanIn:    fanIn,
+		FanOut:   fanOut,
+		Score:    float64(fanOut)*1.0 + float64(fanIn)*0.5,
+	}
+}
+
+// ToSARIF 将报告转换为 SARIF 2.1.0 结构
+func (r *IndexReport) ToSARIF() map[string]interface{} {
+	location := func(file string, line int) []map[string]interface{} {
+		region := map[string]interface{}{}
+		if line > 0 {
+			region["startLine"] = line
+		}
+		return []map[string]interface{}{{
+			"physicalLocation": map[string]interface{}{
+				"artifactLocation": map[string]interface{}{"uri": file},
+				"region":           region,
+			},
+		}}
+	}
+
+	results := []map[string]interface{}{} // SARIF 要求 results 为数组，空报告也不能编码为 null
+	for _, s := range r.HighRiskSymbols {
+		results = append(results, map[string]interface{}{
+			"ruleId":    "MPM001",
+			"level":     "warning",
+			"message":   map[string]interface{}{"text": fmt.Sprintf("%s 复杂度 %.1f (fan-in %d, fan-out %d)", s.Name, s.Score, s.FanIn, s.FanOut)},
+			"locations": location(s.FilePath, s.Line),
+		})
+	}
+	for _, c := range r.Cycles {
+		if len(c.Symbols) == 0 {
+			continue
+		}
+		var names []string
+		for _, s := range c.Symbols {
+			names = append(names, s.Name)
+		}
+		first := c.Symbols[0]
+		results = append(results, map[string]interface{}{
+			"ruleId":    "MPM002",
+			"level":     "warning",
+			"message":   map[string]interface{}{"text": fmt.Sprintf("调用环 (%d 个符号, %d 个文件): %v", len(c.Symbols), len(c.Files), names)},
+			"locations": location(first.FilePath, first.Line),
+		})
+	}
+	for _, u := range r.UnresolvedCalls {
+		results = append(results, map[string]interface{}{
+			"ruleId":    "MPM003",
+			"level":     "note",
+			"message":   map[string]interface{}{"text": fmt.Sprintf("%s 调用了未解析的符号 %s", u.CallerName, u.CalleeName)},
+			"locations": location(u.FilePath, u.CallLine),
+		})
+	}
+
+	rule := func(id, name, desc string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":               id,
+			"name":             name,
+			"shortDescription": map[string]interface{}{"text": desc},
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]interface{}{{
+			"tool": map[string]interface{}{
+				"driver": map[string]interface{}{
+					"name": "mpm-ast-index",
+					"rules": []map[string]interface{}{
+						rule("MPM001", "HighRiskSymbol", "高复杂度/高耦合符号"),
+						rule("MPM002", "CallCycle", "跨符号调用环"),
+						rule("MPM003", "UnresolvedCall", "无法解析到项目内符号的调用"),
+					},
+				},
+			},
+			"results": results,
+		}},
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIndexReportSARIF_EmptyResultsIsArray(t *testing.T) {
+	raw, err := json.Marshal((&IndexReport{}).ToSARIF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"results":[]`) {
+		t.Fatalf("empty report should encode results as an array: %s", raw)
+	}
+
+	report := &IndexReport{HighRiskSymbols: []ReportSymbol{{Name: "Run", FilePath: "main.go", Line: 3}}}
+	var decoded struct {
+		Runs []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	raw, _ = json.Marshal(report.ToSARIF())
+	if err := json.Unmarshal(raw, &decoded); err != nil || len(decoded.Runs) != 1 || len(decoded.Runs[0].Results) != 1 || decoded.Runs[0].Results[0].RuleID != "MPM001" {
+		t.Fatalf("unexpected SARIF: %s", raw)
+	}
+}
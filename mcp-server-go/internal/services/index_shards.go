@@ -0,0 +1,338 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ============================================================================
+// 索引分片：超大仓库按顶层目录或语言拆成多个 symbols 库
+// ============================================================================
+//
+// settings.json index.shard_by = "dir" | "language" 时启用，分片库位于数据目录 shards/，
+// 清单 shards/manifest.json 记录每个分片的范围。查询路由：
+//   - IndexDBPaths(root, scope) 返回 scope 涉及的分片库，SQL 类分析逐库读取后合并
+//   - 引擎查询（符号搜索 / 地图 / 影响分析）逐分片执行并合并结果
+// dir 模式下 _root 分片收纳根目录文件与隐藏目录；跨分片调用边由调用图按名称解析，
+// 引擎的影响分析只能看到同一分片内的调用方。
+
+const (
+	ShardByDir      = "dir"
+	ShardByLanguage = "language"
+
+	shardDirName      = "shards"
+	shardManifestFile = "manifest.json"
+	rootShardName     = "_root"
+	otherShardName    = "other"
+)
+
+// IndexShard 一个索引分片
+type IndexShard struct {
+	Name       string `json:"name"`
+	Scope      string `json:"scope,omitempty"`       // dir 模式：分片目录（_root 为空）
+	Extensions string `json:"extensions,omitempty"`  // language 模式：扩展名白名单
+	IgnoreDirs string `json:"ignore_dirs,omitempty"` // _root 分片额外忽略的顶层目录
+	DB         string `json:"db"`                    // shards/ 下的库文件名
+	Files      int    `json:"files"`
+	BuiltAt    string `json:"built_at,omitempty"`
+}
+
+// ShardManifest 分片清单
+type ShardManifest struct {
+	By     string       `json:"by"`
+	Shards []IndexShard `json:"shards"`
+}
+
+// shardLanguages language 模式的分组（与技术栈检测的分类一致）
+var shardLanguages = []struct {
+	name string
+	exts []string
+}{
+	{"go", []string{"go"}},
+	{"python", []string{"py", "pyi"}},
+	{"frontend", []string{"js", "jsx", "ts", "tsx", "mjs", "cjs", "vue", "svelte"}},
+	{"rust", []string{"rs"}},
+	{"cpp", []string{"c", "h", "cpp", "hpp", "cc"}},
+	{"jvm", []string{"java", "kt"}},
+}
+
+// ShardMode 项目配置的分片方式，未启用时返回空
+func ShardMode(projectRoot string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(core.LoadProjectSettings(projectRoot).Index.ShardBy)); mode {
+	case ShardByDir, ShardByLanguage:
+		return mode
+	default:
+		return ""
+	}
+}
+
+var shardNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func shardDBPath(projectRoot, db string) string {
+	return core.DataPath(projectRoot, shardDirName, db)
+}
+
+func shardManifestPath(projectRoot string) string {
+	return core.DataPath(projectRoot, shardDirName, shardManifestFile)
+}
+
+// PlanShards 按当前文件树规划分片
+func PlanShards(projectRoot, by string) []IndexShard {
+	var shards []IndexShard
+	switch by {
+	case ShardByDir:
+		entries, _ := os.ReadDir(projectRoot)
+		var tops []string
+		for _, e := range entries {
+			name := e.Name()
+			if !e.IsDir() || strings.HasPrefix(name, ".") || shouldSkipDetectDir(strings.ToLower(name), nil) {
+				continue
+			}
+			tops = append(tops, name)
+			shards = append(shards, IndexShard{Name: name, Scope: name, DB: "dir-" + shardNameRe.ReplaceAllString(name, "_") + ".db"})
+		}
+		shards = append(shards, IndexShard{Name: rootShardName, IgnoreDirs: strings.Join(tops, ","), DB: "dir-" + rootShardName + ".db"})
+	case ShardByLanguage:
+		_, ignoreDirs := detectTechStackAndConfig(projectRoot)
+		found := scanProjectExtensions(projectRoot, strings.Split(ignoreDirs, ","), 8)
+		grouped := make(map[string]bool)
+		for _, lang := range shardLanguages {
+			var exts []string
+			for _, ext := range lang.exts {
+				grouped[ext] = true
+				if found[ext] {
+					exts = append(exts, ext)
+				}
+			}
+			if len(exts) > 0 {
+				shards = append(shards, IndexShard{Name: lang.name, Extensions: strings.Join(exts, ","), DB: "lang-" + lang.name + ".db"})
+			}
+		}
+		var rest []string
+		for ext := range found {
+			if !grouped[ext] && shardNameRe.FindString(ext) == "" {
+				rest = append(rest, ext)
+			}
+		}
+		if len(rest) > 0 {
+			sort.Strings(rest)
+			shards = append(shards, IndexShard{Name: otherShardName, Extensions: strings.Join(rest, ","), DB: "lang-" + otherShardName + ".db"})
+		}
+	}
+	return shards
+}
+
+// LoadShardManifest 读取分片清单；未分片过时返回 nil, nil
+func LoadShardManifest(projectRoot string) (*ShardManifest, error) {
+	data, err := os.ReadFile(shardManifestPath(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m ShardManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析分片清单失败: %w", err)
+	}
+	return &m, nil
+}
+
+func saveShardManifest(projectRoot string, m *ShardManifest) error {
+	if err := os.MkdirAll(core.DataPath(projectRoot, shardDirName), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(shardManifestPath(projectRoot), data, 0644)
+}
+
+// activeShards 已启用且已构建的分片清单；未启用分片或清单与配置不一致时返回 nil
+func activeShards(projectRoot string) *ShardManifest {
+	mode := ShardMode(projectRoot)
+	if mode == "" {
+		return nil
+	}
+	m, err := LoadShardManifest(projectRoot)
+	if err != nil || m == nil || m.By != mode || len(m.Shards) == 0 {
+		return nil
+	}
+	return m
+}
+
+// touches 分片是否包含 scope（目录或文件，相对项目根）下的文件
+func (m *ShardManifest) touches(sh IndexShard, scope string) bool {
+	scope = strings.Trim(strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(scope)), "./"), "/")
+	if scope == "" || scope == "." || m.By != ShardByDir {
+		return true
+	}
+	under := func(dir string) bool { return dir != "" && (scope == dir || strings.HasPrefix(scope, dir+"/")) }
+	if sh.Name != rootShardName {
+		return under(sh.Scope)
+	}
+	for _, other := range m.Shards {
+		if other.Name != rootShardName && under(other.Scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// IndexDBPaths 查询路由：scope 涉及的索引库路径（未分片时为 symbols.db）
+func IndexDBPaths(projectRoot, scope string) []string {
+	m := activeShards(projectRoot)
+	if m == nil {
+		return []string{getDBPath(projectRoot)}
+	}
+	var paths []string
+	for _, sh := range m.Shards {
+		if p := shardDBPath(projectRoot, sh.DB); m.touches(sh, scope) && fileExists(p) {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// hasAnyIndex 至少有一个可用的索引库
+func hasAnyIndex(projectRoot string) bool {
+	for _, p := range IndexDBPaths(projectRoot, "") {
+		if fileExists(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// openIndexDBs 同时打开 scope 涉及的全部索引库（需要多轮查询时使用），返回的 closeAll 负责关闭
+func openIndexDBs(projectRoot, scope string) (dbs []*sql.DB, closeAll func(), err error) {
+	closeAll = func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}
+	for _, p := range IndexDBPaths(projectRoot, scope) {
+		if !fileExists(p) {
+			continue
+		}
+		db, err := sql.Open("sqlite", p)
+		if err != nil {
+			closeAll()
+			return nil, func() {}, err
+		}
+		dbs = append(dbs, db)
+	}
+	if len(dbs) == 0 {
+		return nil, closeAll, fmt.Errorf("索引数据库不存在，请先执行 initialize_project")
+	}
+	return dbs, closeAll, nil
+}
+
+// eachIndexDB 依次打开 scope 涉及的索引库执行 fn；没有任何索引库时返回错误
+func eachIndexDB(projectRoot, scope string, fn func(db *sql.DB) error) error {
+	ran := false
+	for _, p := range IndexDBPaths(projectRoot, scope) {
+		if !fileExists(p) {
+			continue
+		}
+		db, err := sql.Open("sqlite", p)
+		if err != nil {
+			return err
+		}
+		err = fn(db)
+		db.Close()
+		if err != nil {
+			return err
+		}
+		ran = true
+	}
+	if !ran {
+		return fmt.Errorf("索引数据库不存在，请先执行 initialize_project")
+	}
+	return nil
+}
+
+// indexShards 重新规划并构建全部分片，清理已不存在的分片库
+func (ai *ASTIndexer) indexShards(projectRoot, by string, forceFull bool) (*IndexResult, error) {
+	plan := PlanShards(projectRoot, by)
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("未规划出任何分片 (shard_by=%s)", by)
+	}
+	old, _ := LoadShardManifest(projectRoot)
+	total := &IndexResult{Status: "success", Strategy: "shards:" + by}
+	for i := range plan {
+		res, err := ai.indexInto(projectRoot, shardDBPath(projectRoot, plan[i].DB), plan[i].Scope, plan[i].Extensions, plan[i].IgnoreDirs, forceFull, false)
+		if err != nil {
+			return nil, fmt.Errorf("分片 %s: %w", plan[i].Name, err)
+		}
+		plan[i].Files = res.TotalFiles
+		plan[i].BuiltAt = time.Now().Format(time.RFC3339)
+		addIndexResult(total, res)
+	}
+	if old != nil {
+		keep := make(map[string]bool)
+		for _, sh := range plan {
+			keep[sh.DB] = true
+		}
+		for _, sh := range old.Shards {
+			if !keep[sh.DB] {
+				p := shardDBPath(projectRoot, sh.DB)
+				removeSQLiteFiles(p)
+				removeSQLiteFiles(p + indexPrevSuffix)
+			}
+		}
+	}
+	if err := saveShardManifest(projectRoot, &ShardManifest{By: by, Shards: plan}); err != nil {
+		return nil, fmt.Errorf("保存分片清单失败: %w", err)
+	}
+	return total, nil
+}
+
+// indexShardScope 按 scope 增量刷新涉及的分片；尚无分片清单时先规划（只构建涉及的分片）
+func (ai *ASTIndexer) indexShardScope(projectRoot, by, scope string, forceFull, inPlace bool) (*IndexResult, error) {
+	m := activeShards(projectRoot)
+	if m == nil {
+		m = &ShardManifest{By: by, Shards: PlanShards(projectRoot, by)}
+	}
+	total := &IndexResult{Status: "success", Strategy: "shards:" + by}
+	for i, sh := range m.Shards {
+		if !m.touches(sh, scope) {
+			continue
+		}
+		shardScope := scope
+		if strings.Trim(scope, "./") == "" {
+			shardScope = sh.Scope
+		}
+		res, err := ai.indexInto(projectRoot, shardDBPath(projectRoot, sh.DB), shardScope, sh.Extensions, sh.IgnoreDirs, forceFull, inPlace && shardScope == scope)
+		if err != nil {
+			return nil, fmt.Errorf("分片 %s: %w", sh.Name, err)
+		}
+		if shardScope == sh.Scope {
+			m.Shards[i].Files = res.TotalFiles
+		}
+		m.Shards[i].BuiltAt = time.Now().Format(time.RFC3339)
+		addIndexResult(total, res)
+	}
+	if err := saveShardManifest(projectRoot, m); err != nil {
+		return nil, fmt.Errorf("保存分片清单失败: %w", err)
+	}
+	return total, nil
+}
+
+func addIndexResult(total, res *IndexResult) {
+	total.TotalFiles += res.TotalFiles
+	total.ParsedFiles += res.ParsedFiles
+	total.MetaFiles += res.MetaFiles
+	total.SkippedFiles += res.SkippedFiles
+	total.ElapsedMs += res.ElapsedMs
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexShards_PlanRouteAndCrossShardGraph(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"api", "web", "node_modules/x", ".git", ".mcp-config"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".mcp-config", "settings.json"), []byte(`{"index": {"shard_by": "dir"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := PlanShards(root, ShardByDir)
+	var names []string
+	for _, sh := range plan {
+		names = append(names, sh.Name)
+	}
+	if want := []string{"api", "web", rootShardName}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("shards = %v, want %v", names, want)
+	}
+	if plan[2].IgnoreDirs != "api,web" {
+		t.Errorf("_root shard should ignore top-level shard dirs, got %q", plan[2].IgnoreDirs)
+	}
+
+	// 未构建清单时仍走单库
+	if paths := IndexDBPaths(root, ""); len(paths) != 1 || paths[0] != getDBPath(root) {
+		t.Fatalf("without manifest: %v", paths)
+	}
+	if err := saveShardManifest(root, &ShardManifest{By: ShardByDir, Shards: plan}); err != nil {
+		t.Fatal(err)
+	}
+	schema := []string{
+		"CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)",
+		"CREATE TABLE symbols (symbol_id INTEGER PRIMARY KEY, file_id INTEGER, name TEXT, symbol_type TEXT, line_start INTEGER, canonical_id TEXT)",
+		"CREATE TABLE calls (caller_id INTEGER, callee_name TEXT, call_line INTEGER)",
+	}
+	execDB(t, shardDBPath(root, plan[0].DB), append(schema,
+		"INSERT INTO files VALUES (1, 'api/handler.go')",
+		"INSERT INTO symbols VALUES (1, 1, 'Handle', 'function', 3, 'api.Handle')",
+		"INSERT INTO calls VALUES (1, 'Render', 4)")...)
+	execDB(t, shardDBPath(root, plan[1].DB), append(schema,
+		"INSERT INTO files VALUES (1, 'web/render.go')",
+		"INSERT INTO symbols VALUES (1, 1, 'Render', 'function', 2, 'web.Render')")...)
+
+	for scope, want := range map[string][]string{
+		"":               {plan[0].DB, plan[1].DB},
+		"api/handler.go": {plan[0].DB},
+		"./web":          {plan[1].DB},
+		"main.go":        nil, // _root 分片尚未构建
+	} {
+		var got []string
+		for _, p := range IndexDBPaths(root, scope) {
+			got = append(got, filepath.Base(p))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("IndexDBPaths(%q) = %v, want %v", scope, got, want)
+		}
+	}
+
+	dbs, closeAll, err := openIndexDBs(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll()
+	g, err := loadCallGraph(dbs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Symbols) != 2 || len(g.Unresolved) != 0 {
+		t.Fatalf("symbols = %d, unresolved = %+v", len(g.Symbols), g.Unresolved)
+	}
+	if callees := g.Edges[1]; len(callees) != 1 || g.Symbols[callees[0]].Name != "Render" {
+		t.Errorf("cross-shard call Handle -> Render not resolved: %v", callees)
+	}
+
+	var total int
+	if err := eachIndexDB(root, "", func(db *sql.DB) error {
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM files").Scan(&n)
+		total += n
+		return err
+	}); err != nil || total != 2 {
+		t.Errorf("eachIndexDB files = %d, %v", total, err)
+	}
+}
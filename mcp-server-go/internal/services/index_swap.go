@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 索引原子切换 ==========
+//
+// 重建索引时不直接写线上 symbols.db：先把当前索引的一致性快照复制到 symbols.db.building，
+// Rust 引擎在临时库上增量/全量构建，校验通过后 rename 直接覆盖 symbols.db（线上路径始终存在）。
+// 切换前的旧库以硬链接保留为 symbols.db.prev，可通过 RollbackIndex 一步回滚。
+// 查询命令每次重新打开 symbols.db，因此始终命中某个完整的快照，不会看到构建中的半成品。
+//
+// 按目录范围的增量刷新（IndexScope，文件监听每批最多触发数次）不复制整库：
+// 引擎只把该范围构建到空的临时库，再由 mergeScopeIntoLive 在一个事务内替换线上库中该范围的记录。
+
+const (
+	indexBuildSuffix = ".building"
+	indexPrevSuffix  = ".prev"
+)
+
+// sqliteSidecars WAL 模式下与主库成对出现的文件
+var sqliteSidecars = []string{"-wal", "-shm"}
+
+// indexSwapLocks 按项目串行化构建与切换，避免并发构建共用同一临时库
+var indexSwapLocks sync.Map
+
+func indexSwapLock(projectRoot string) *sync.Mutex {
+	mu, _ := indexSwapLocks.LoadOrStore(normalizeProjectRoot(projectRoot), &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+func getBuildDBPath(projectRoot string) string {
+	return getDBPath(projectRoot) + indexBuildSuffix
+}
+
+func getPrevDBPath(projectRoot string) string {
+	return getDBPath(projectRoot) + indexPrevSuffix
+}
+
+func removeSQLiteFiles(dbPath string) {
+	_ = os.Remove(dbPath)
+	for _, s := range sqliteSidecars {
+		_ = os.Remove(dbPath + s)
+	}
+}
+
+// checkpointDB 把 WAL 内容合并回主库并截断 WAL，使主库文件自身完整
+func checkpointDB(dbPath string) error {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
+
+// prepareBuildDB 复制线上索引的快照到临时库，让增量构建在其上继续；
+// 线上库不存在或无法复制时临时库为空，由引擎全量构建
+func prepareBuildDB(liveDB, buildDB string) {
+	removeSQLiteFiles(buildDB)
+	if !fileExists(liveDB) {
+		return
+	}
+	db, err := sql.Open("sqlite", liveDB)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec("VACUUM INTO ?", buildDB); err != nil {
+		removeSQLiteFiles(buildDB)
+	}
+}
+
+// validateBuildDB 构建结果校验：库完整且包含 files 表
+func validateBuildDB(buildDB string) error {
+	if err := checkpointDB(buildDB); err != nil {
+		return fmt.Errorf("合并临时索引 WAL 失败: %v", err)
+	}
+	db, err := sql.Open("sqlite", buildDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("临时索引校验失败: %v", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("临时索引校验失败: %s", check)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='files'").Scan(&tables); err != nil || tables == 0 {
+		return fmt.Errorf("临时索引缺少 files 表")
+	}
+	return nil
+}
+
+// renameWithRetry Windows 上目标文件可能被短暂占用，重试几次
+func renameWithRetry(from, to string) error {
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = os.Rename(from, to); err == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return err
+}
+
+// linkOrCopyFile 优先硬链接（不占额外空间），文件系统不支持时复制
+func linkOrCopyFile(from, to string) error {
+	if err := os.Link(from, to); err == nil {
+		return nil
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		_ = os.Remove(to)
+		return err
+	}
+	return dst.Close()
+}
+
+// swapIndexDB 临时库切换为线上库，旧线上库保留为 .prev。
+// 旧库先链接/复制为 .prev，再 rename 直接覆盖线上路径，切换过程中 symbols.db 始终存在
+func swapIndexDB(liveDB, buildDB, prevDB string) error {
+	// 临时库已 checkpoint，附属文件可直接删除，避免与新路径上的主库错配
+	for _, s := range sqliteSidecars {
+		_ = os.Remove(buildDB + s)
+	}
+
+	removeSQLiteFiles(prevDB)
+	if fileExists(liveDB) {
+		_ = checkpointDB(liveDB)
+		if err := linkOrCopyFile(liveDB, prevDB); err != nil {
+			return fmt.Errorf("备份旧索引失败: %v", err)
+		}
+	}
+	if err := renameWithRetry(buildDB, liveDB); err != nil {
+		return fmt.Errorf("切换新索引失败: %v", err)
+	}
+	// 旧库已 checkpoint，其 WAL/SHM 不属于新主库
+	for _, s := range sqliteSidecars {
+		_ = os.Remove(liveDB + s)
+	}
+	return nil
+}
+
+// normalizeIndexScope 与引擎一致地规范化 scope（去掉 ./ 与首尾 /）
+func normalizeIndexScope(scope string) string {
+	return strings.Trim(strings.TrimPrefix(strings.TrimSpace(filepath.ToSlash(scope)), "./"), "/")
+}
+
+// mergeScopeIntoLive 在单个事务内用 scopedDB（只含 scope 范围的构建结果）替换线上库中该范围的记录：
+// 删除范围内的文件/符号/调用，插入新记录（symbol_id 整体平移到线上最大值之后，避免冲突），
+// 再按引擎的规则（同文件优先）为未链接的调用边补 callee_id。提交前读者始终看到旧快照
+func mergeScopeIntoLive(liveDB, scopedDB, scope string) error {
+	scope = normalizeIndexScope(scope)
+	if scope == "" {
+		return fmt.Errorf("scope 为空，不能原地合并")
+	}
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", liveDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx) // ATTACH 只对当前连接生效
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA busy_timeout = 5000"); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS scoped", scopedDB); err != nil {
+		return fmt.Errorf("挂载临时索引失败: %v", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE scoped")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const inScope = "SELECT file_id FROM main.files WHERE file_path = ?1 OR substr(file_path, 1, ?2) = ?3"
+	prefix := scope + "/"
+	scopeArgs := []any{scope, len(prefix), prefix}
+	var offset int64
+	if err := tx.QueryRow("SELECT COALESCE(MAX(symbol_id), 0) FROM main.symbols").Scan(&offset); err != nil {
+		return err
+	}
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{"DELETE FROM main.calls WHERE caller_id IN (SELECT symbol_id FROM main.symbols WHERE file_id IN (" + inScope + "))", scopeArgs},
+		{"DELETE FROM main.symbols WHERE file_id IN (" + inScope + ")", scopeArgs},
+		{"DELETE FROM main.files WHERE file_id IN (" + inScope + ")", scopeArgs},
+		{`INSERT INTO main.files (file_path, file_hash, file_size, file_mtime, language, line_count, index_level, indexed_at, updated_at)
+			SELECT file_path, file_hash, file_size, file_mtime, language, line_count, index_level, indexed_at, updated_at FROM scoped.files`, nil},
+		{`INSERT INTO main.symbols (symbol_id, file_id, name, qualified_name, canonical_id, scope_path, symbol_type, line_start, line_end, signature, parent_id)
+			SELECT s.symbol_id + ?1, mf.file_id, s.name, s.qualified_name, s.canonical_id, s.scope_path, s.symbol_type, s.line_start, s.line_end, s.signature,
+				CASE WHEN s.parent_id IS NULL THEN NULL ELSE s.parent_id + ?1 END
+			FROM scoped.symbols s
+			JOIN scoped.files sf ON sf.file_id = s.file_id
+			JOIN main.files mf ON mf.file_path = sf.file_path`, []any{offset}},
+		{`INSERT INTO main.calls (caller_id, callee_name, call_line, callee_id)
+			SELECT caller_id + ?1, callee_name, call_line, NULL FROM scoped.calls
+			WHERE caller_id IN (SELECT symbol_id FROM scoped.symbols)`, []any{offset}},
+		// 与引擎的 Linking 阶段相同：同文件优先，否则取 symbol_id 最小者
+		{`UPDATE main.calls SET callee_id = (
+				SELECT s2.canonical_id FROM main.symbols sc
+				JOIN main.symbols s2 ON s2.name = calls.callee_name
+				WHERE sc.symbol_id = calls.caller_id
+				ORDER BY CASE WHEN s2.file_id = sc.file_id THEN 0 ELSE 1 END, s2.symbol_id ASC
+				LIMIT 1)
+			WHERE callee_id IS NULL`, nil},
+	}
+	for _, st := range steps {
+		if _, err := tx.Exec(st.query, st.args...); err != nil {
+			return fmt.Errorf("合并 scope 索引失败: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RollbackIndex 用上一版索引 (symbols.db.prev) 替换当前索引，当前索引变为 .prev，可再次回滚
+func RollbackIndex(projectRoot string) error {
+	mu := indexSwapLock(projectRoot)
+	mu.Lock()
+	defer mu.Unlock()
+
+	liveDB, prevDB := getDBPath(projectRoot), getPrevDBPath(projectRoot)
+	if !fileExists(prevDB) {
+		return fmt.Errorf("没有可回滚的上一版索引")
+	}
+	// 借用临时库路径完成两库互换
+	tmpDB := getBuildDBPath(projectRoot)
+	removeSQLiteFiles(tmpDB)
+	_ = checkpointDB(prevDB)
+	for _, s := range sqliteSidecars {
+		_ = os.Remove(prevDB + s)
+	}
+	if err := renameWithRetry(prevDB, tmpDB); err != nil {
+		return fmt.Errorf("回滚失败: %v", err)
+	}
+	if err := swapIndexDB(liveDB, tmpDB, prevDB); err != nil {
+		_ = renameWithRetry(tmpDB, prevDB)
+		return fmt.Errorf("回滚失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func countFiles(t *testing.T, dbPath string) int {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM files").Scan(&n); err != nil {
+		t.Fatalf("%s: %v", dbPath, err)
+	}
+	return n
+}
+
+func execDB(t *testing.T, dbPath string, stmts ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func TestIndexSwap_BuildSwapAndRollback(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".mcp-data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	live, build, prev := getDBPath(root), getBuildDBPath(root), getPrevDBPath(root)
+	execDB(t, live, "PRAGMA journal_mode = WAL",
+		`CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)`,
+		`INSERT INTO files VALUES (1, 'a.go')`)
+
+	// 临时库从线上快照开始，写入不影响线上库
+	prepareBuildDB(live, build)
+	execDB(t, build, `INSERT INTO files VALUES (2, 'b.go')`)
+	if got := countFiles(t, live); got != 1 {
+		t.Fatalf("live during build = %d, want 1", got)
+	}
+
+	if err := validateBuildDB(build); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := swapIndexDB(live, build, prev); err != nil {
+		t.Fatalf("swap: %v", err)
+	}
+	if fileExists(build) {
+		t.Error("build db should be consumed by swap")
+	}
+	if got := countFiles(t, live); got != 2 {
+		t.Errorf("live after swap = %d, want 2", got)
+	}
+	if got := countFiles(t, prev); got != 1 {
+		t.Errorf("prev after swap = %d, want 1", got)
+	}
+
+	if err := RollbackIndex(root); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if got := countFiles(t, live); got != 1 {
+		t.Errorf("live after rollback = %d, want 1", got)
+	}
+	if got := countFiles(t, prev); got != 2 {
+		t.Errorf("prev after rollback = %d, want 2", got)
+	}
+}
+
+func TestValidateBuildDB_RejectsMissingFilesTable(t *testing.T) {
+	build := filepath.Join(t.TempDir(), "symbols.db.building")
+	execDB(t, build, `CREATE TABLE other (id INTEGER)`)
+	if err := validateBuildDB(build); err == nil {
+		t.Error("expected validation error for db without files table")
+	}
+}
+
+// symbolsSchema 与引擎 init_db 一致的最小表结构
+var symbolsSchema = []string{
+	`CREATE TABLE files (file_id INTEGER PRIMARY KEY AUTOINCREMENT, file_path TEXT UNIQUE NOT NULL, file_hash TEXT NOT NULL,
+		file_size INTEGER DEFAULT 0, file_mtime INTEGER DEFAULT 0, language TEXT DEFAULT 'unknown', line_count INTEGER DEFAULT 0,
+		index_level TEXT DEFAULT 'symbol', indexed_at INTEGER DEFAULT 0, updated_at INTEGER NOT NULL)`,
+	`CREATE TABLE symbols (symbol_id INTEGER PRIMARY KEY AUTOINCREMENT, file_id INTEGER NOT NULL, name TEXT NOT NULL,
+		qualified_name TEXT NOT NULL, canonical_id TEXT NOT NULL, scope_path TEXT, symbol_type TEXT NOT NULL,
+		line_start INTEGER, line_end INTEGER, signature TEXT, parent_id INTEGER)`,
+	`CREATE TABLE calls (call_id INTEGER PRIMARY KEY AUTOINCREMENT, caller_id INTEGER NOT NULL, callee_name TEXT NOT NULL,
+		call_line INTEGER, callee_id TEXT)`,
+}
+
+func TestMergeScopeIntoLive(t *testing.T) {
+	dir := t.TempDir()
+	live, scoped := filepath.Join(dir, "symbols.db"), filepath.Join(dir, "symbols.db.building")
+	execDB(t, live, append(symbolsSchema,
+		`INSERT INTO files (file_id, file_path, file_hash, updated_at) VALUES (1, 'pkg/a.go', 'h1', 0), (2, 'pkg/b.go', 'h2', 0), (3, 'pkgx/c.go', 'h3', 0), (4, 'pkg/gone.go', 'h4', 0)`,
+		`INSERT INTO symbols (symbol_id, file_id, name, qualified_name, canonical_id, symbol_type) VALUES
+			(1, 1, 'Old', 'Old', 'pkg/a.go::Old', 'function'), (2, 3, 'Main', 'Main', 'pkgx/c.go::Main', 'function'),
+			(3, 4, 'Gone', 'Gone', 'pkg/gone.go::Gone', 'function')`,
+		`INSERT INTO calls (caller_id, callee_name, callee_id) VALUES (2, 'New', NULL), (1, 'Main', 'pkgx/c.go::Main')`)...)
+	// 范围内 a.go 改名了函数、gone.go 已删除、新增 d.go
+	execDB(t, scoped, append(symbolsSchema,
+		`INSERT INTO files (file_id, file_path, file_hash, updated_at) VALUES (1, 'pkg/a.go', 'h1b', 1), (2, 'pkg/b.go', 'h2', 1), (3, 'pkg/d.go', 'h5', 1)`,
+		`INSERT INTO symbols (symbol_id, file_id, name, qualified_name, canonical_id, symbol_type) VALUES
+			(1, 1, 'New', 'New', 'pkg/a.go::New', 'function'), (2, 3, 'Helper', 'Helper', 'pkg/d.go::Helper', 'function')`,
+		`INSERT INTO calls (caller_id, callee_name, callee_id) VALUES (1, 'Helper', 'pkg/d.go::Helper')`)...)
+
+	if err := mergeScopeIntoLive(live, scoped, "./pkg/"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var files, symbols int
+	db.QueryRow("SELECT COUNT(*) FROM files").Scan(&files)
+	db.QueryRow("SELECT COUNT(*) FROM symbols").Scan(&symbols)
+	if files != 4 || symbols != 3 {
+		t.Fatalf("files=%d symbols=%d, want 4/3 (pkg 替换为 a/b/d，pkgx 保留)", files, symbols)
+	}
+	var hash string
+	db.QueryRow("SELECT file_hash FROM files WHERE file_path = 'pkgx/c.go'").Scan(&hash)
+	if hash != "h3" {
+		t.Errorf("file outside scope (prefix-only match) must be kept, got %q", hash)
+	}
+	var path string
+	if err := db.QueryRow(`SELECT f.file_path FROM calls c JOIN symbols s ON s.symbol_id = c.caller_id JOIN files f ON f.file_id = s.file_id
+		WHERE c.callee_name = 'Helper'`).Scan(&path); err != nil || path != "pkg/a.go" {
+		t.Errorf("merged call should point at remapped caller in pkg/a.go, got %q, %v", path, err)
+	}
+	var linked string
+	db.QueryRow("SELECT callee_id FROM calls WHERE callee_name = 'New'").Scan(&linked)
+	if linked != "pkg/a.go::New" {
+		t.Errorf("unlinked call outside scope should link to new symbol, got %q", linked)
+	}
+	var stale int
+	db.QueryRow("SELECT COUNT(*) FROM calls WHERE callee_name = 'Main'").Scan(&stale)
+	if stale != 0 {
+		t.Errorf("calls of replaced symbols should be removed, got %d", stale)
+	}
+}
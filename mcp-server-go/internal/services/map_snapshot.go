@@ -0,0 +1,264 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 项目地图快照：每次生成 symbols 视图时保存精简快照，按需对比任意两个快照
+// ============================================================================
+
+// MapSnapshotKeep 保留的快照数，超出时删除最旧的
+const MapSnapshotKeep = 30
+
+// mapSnapshotIDLayout 快照 ID（同时是文件名）的时间格式
+const mapSnapshotIDLayout = "20060102-150405"
+
+// complexityRegressionDelta 复杂度上升超过该值才算回退
+const complexityRegressionDelta = 5.0
+
+// SnapshotSymbol 快照中的符号
+type SnapshotSymbol struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Line       int     `json:"line"`
+	Complexity float64 `json:"complexity,omitempty"`
+	File       string  `json:"-"`
+}
+
+// MapSnapshot 项目地图快照（文件 -> 符号）
+type MapSnapshot struct {
+	ID        string                      `json:"id"`
+	CreatedAt time.Time                   `json:"created_at"`
+	Scope     string                      `json:"scope,omitempty"`
+	Scored    bool                        `json:"scored"` // 是否计算了复杂度（skip_complexity 时为 false）
+	Files     map[string][]SnapshotSymbol `json:"files"`
+}
+
+// ComplexityChange 同一符号的复杂度变化
+type ComplexityChange struct {
+	SnapshotSymbol
+	Before float64 `json:"before"`
+}
+
+// DirChange 目录的文件/符号数变化
+type DirChange struct {
+	Dir           string `json:"dir"`
+	FilesBefore   int    `json:"files_before"`
+	FilesAfter    int    `json:"files_after"`
+	SymbolsBefore int    `json:"symbols_before"`
+	SymbolsAfter  int    `json:"symbols_after"`
+}
+
+// MapDiff 两个快照之间的差异
+type MapDiff struct {
+	From         *MapSnapshot
+	To           *MapSnapshot
+	AddedFiles   []string
+	RemovedFiles []string
+	Added        []SnapshotSymbol
+	Removed      []SnapshotSymbol
+	Regressions  []ComplexityChange // 复杂度上升（按增幅降序）
+	Improved     int                // 复杂度下降的符号数
+	Dirs         []DirChange        // 有变化的目录（按符号增量降序）
+}
+
+// NewMapSnapshot 由 MapResult 生成快照（文件路径统一为正斜杠）
+func NewMapSnapshot(result *MapResult, scope string, at time.Time) *MapSnapshot {
+	snap := &MapSnapshot{
+		ID:        at.Format(mapSnapshotIDLayout),
+		CreatedAt: at,
+		Scope:     strings.TrimSpace(scope),
+		Scored:    result.ComplexityMap != nil,
+		Files:     make(map[string][]SnapshotSymbol, len(result.Structure)),
+	}
+	for file, nodes := range result.Structure {
+		file = strings.TrimPrefix(filepath.ToSlash(file), "./")
+		syms := make([]SnapshotSymbol, 0, len(nodes))
+		for _, n := range nodes {
+			name := n.Name
+			if n.QualifiedName != "" {
+				name = n.QualifiedName
+			}
+			syms = append(syms, SnapshotSymbol{Name: name, Type: n.NodeType, Line: n.LineStart, Complexity: result.ComplexityMap[n.Name]})
+		}
+		snap.Files[file] = syms
+	}
+	return snap
+}
+
+// SaveMapSnapshot 写入快照并清理超出 MapSnapshotKeep 的旧快照
+func SaveMapSnapshot(dir string, snap *MapSnapshot) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, snap.ID+".json"), data, 0644); err != nil {
+		return err
+	}
+	ids, err := ListMapSnapshots(dir)
+	if err != nil {
+		return nil
+	}
+	for i := 0; i+MapSnapshotKeep < len(ids); i++ {
+		_ = os.Remove(filepath.Join(dir, ids[i]+".json"))
+	}
+	return nil
+}
+
+// ListMapSnapshots 快照 ID 列表（从旧到新）
+func ListMapSnapshots(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if e.IsDir() || id == e.Name() {
+			continue
+		}
+		if _, err := time.Parse(mapSnapshotIDLayout, id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// LoadMapSnapshot 读取快照
+func LoadMapSnapshot(dir, id string) (*MapSnapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(id)+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("快照不存在: %s", id)
+		}
+		return nil, err
+	}
+	var snap MapSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("快照 %s 已损坏: %v", id, err)
+	}
+	return &snap, nil
+}
+
+func snapshotSymbolKey(s SnapshotSymbol) string {
+	return s.File + "\x00" + s.Type + "\x00" + s.Name
+}
+
+func (s *MapSnapshot) symbols() map[string]SnapshotSymbol {
+	out := make(map[string]SnapshotSymbol)
+	for file, syms := range s.Files {
+		for _, sym := range syms {
+			sym.File = file
+			out[snapshotSymbolKey(sym)] = sym
+		}
+	}
+	return out
+}
+
+// DiffMapSnapshots 对比两个快照：新增/删除的文件与符号、复杂度回退、目录增长
+func DiffMapSnapshots(from, to *MapSnapshot) *MapDiff {
+	d := &MapDiff{From: from, To: to}
+	for f := range to.Files {
+		if _, ok := from.Files[f]; !ok {
+			d.AddedFiles = append(d.AddedFiles, f)
+		}
+	}
+	for f := range from.Files {
+		if _, ok := to.Files[f]; !ok {
+			d.RemovedFiles = append(d.RemovedFiles, f)
+		}
+	}
+	sort.Strings(d.AddedFiles)
+	sort.Strings(d.RemovedFiles)
+
+	before, after := from.symbols(), to.symbols()
+	for k, s := range after {
+		old, ok := before[k]
+		if !ok {
+			d.Added = append(d.Added, s)
+			continue
+		}
+		if !from.Scored || !to.Scored {
+			continue
+		}
+		switch {
+		case s.Complexity-old.Complexity >= complexityRegressionDelta:
+			d.Regressions = append(d.Regressions, ComplexityChange{SnapshotSymbol: s, Before: old.Complexity})
+		case s.Complexity < old.Complexity:
+			d.Improved++
+		}
+	}
+	for k, s := range before {
+		if _, ok := after[k]; !ok {
+			d.Removed = append(d.Removed, s)
+		}
+	}
+	bySymbolPos := func(list []SnapshotSymbol) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].File != list[j].File {
+				return list[i].File < list[j].File
+			}
+			return list[i].Line < list[j].Line
+		})
+	}
+	bySymbolPos(d.Added)
+	bySymbolPos(d.Removed)
+	sort.Slice(d.Regressions, func(i, j int) bool {
+		di := d.Regressions[i].Complexity - d.Regressions[i].Before
+		dj := d.Regressions[j].Complexity - d.Regressions[j].Before
+		if di != dj {
+			return di > dj
+		}
+		return d.Regressions[i].Name < d.Regressions[j].Name
+	})
+
+	dirs := make(map[string]*DirChange)
+	dirOf := func(file string) *DirChange {
+		dir := path.Dir(file)
+		c, ok := dirs[dir]
+		if !ok {
+			c = &DirChange{Dir: dir}
+			dirs[dir] = c
+		}
+		return c
+	}
+	for f, syms := range from.Files {
+		c := dirOf(f)
+		c.FilesBefore++
+		c.SymbolsBefore += len(syms)
+	}
+	for f, syms := range to.Files {
+		c := dirOf(f)
+		c.FilesAfter++
+		c.SymbolsAfter += len(syms)
+	}
+	for _, c := range dirs {
+		if c.FilesBefore != c.FilesAfter || c.SymbolsBefore != c.SymbolsAfter {
+			d.Dirs = append(d.Dirs, *c)
+		}
+	}
+	sort.Slice(d.Dirs, func(i, j int) bool {
+		gi := d.Dirs[i].SymbolsAfter - d.Dirs[i].SymbolsBefore
+		gj := d.Dirs[j].SymbolsAfter - d.Dirs[j].SymbolsBefore
+		if gi != gj {
+			return gi > gj
+		}
+		return d.Dirs[i].Dir < d.Dirs[j].Dir
+	})
+	return d
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffMapSnapshots(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	from := NewMapSnapshot(&MapResult{
+		Structure: map[string][]Node{
+			"api/user.go": {{Name: "GetUser", NodeType: "function", LineStart: 3}, {Name: "oldHelper", NodeType: "function", LineStart: 20}},
+			"db/conn.go":  {{Name: "Open", NodeType: "function", LineStart: 5}},
+		},
+		ComplexityMap: map[string]float64{"GetUser": 8, "Open": 30},
+	}, "", t0)
+	to := NewMapSnapshot(&MapResult{
+		Structure: map[string][]Node{
+			"api/user.go":  {{Name: "GetUser", NodeType: "function", LineStart: 4}},
+			"api/order.go": {{Name: "CreateOrder", NodeType: "function", LineStart: 1}, {Name: "validate", NodeType: "function", LineStart: 9}},
+			"db/conn.go":   {{Name: "Open", NodeType: "function", LineStart: 5}},
+		},
+		ComplexityMap: map[string]float64{"GetUser": 21, "Open": 12},
+	}, "", t0.Add(time.Hour))
+
+	d := DiffMapSnapshots(from, to)
+	if len(d.AddedFiles) != 1 || d.AddedFiles[0] != "api/order.go" || len(d.RemovedFiles) != 0 {
+		t.Fatalf("files +%v -%v", d.AddedFiles, d.RemovedFiles)
+	}
+	if len(d.Added) != 2 || len(d.Removed) != 1 || d.Removed[0].Name != "oldHelper" {
+		t.Fatalf("symbols +%v -%v", d.Added, d.Removed)
+	}
+	if len(d.Regressions) != 1 || d.Regressions[0].Name != "GetUser" || d.Regressions[0].Before != 8 || d.Improved != 1 {
+		t.Fatalf("regressions = %+v, improved = %d", d.Regressions, d.Improved)
+	}
+	if len(d.Dirs) != 1 || d.Dirs[0].Dir != "api" || d.Dirs[0].SymbolsBefore != 2 || d.Dirs[0].SymbolsAfter != 3 {
+		t.Fatalf("dirs = %+v", d.Dirs)
+	}
+}
+
+func TestMapSnapshotSaveAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	for i := 0; i < MapSnapshotKeep+2; i++ {
+		snap := NewMapSnapshot(&MapResult{Structure: map[string][]Node{}}, "", t0.Add(time.Duration(i)*time.Minute))
+		if err := SaveMapSnapshot(dir, snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids, err := ListMapSnapshots(dir)
+	if err != nil || len(ids) != MapSnapshotKeep {
+		t.Fatalf("ids = %d, %v", len(ids), err)
+	}
+	if ids[0] != t0.Add(2*time.Minute).Format(mapSnapshotIDLayout) {
+		t.Fatalf("oldest = %s", ids[0])
+	}
+	if _, err := LoadMapSnapshot(dir, ids[len(ids)-1]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"path"
+	"strings"
+)
+
+// ============================================================================
+// 路径标记：识别 vendored / generated 文件，供地图、复杂度、影响分析过滤
+// ============================================================================
+
+const (
+	PathTagVendored  = "vendored"
+	PathTagGenerated = "generated"
+)
+
+// defaultVendoredDirs 第三方代码目录（按路径段匹配）
+// 只收录含义明确的目录名；external/extern 等常被用来放自研代码，需要时由 settings.json index.vendored_paths 追加
+var defaultVendoredDirs = []string{
+	"vendor", "node_modules", "third_party", "site-packages", ".venv",
+}
+
+// defaultGeneratedGlobs 生成代码文件名模式（按文件名匹配）
+var defaultGeneratedGlobs = []string{
+	"*.pb.go", "*.pb.gw.go", "*_generated.go", "*.gen.go", "*_gen.go", "zz_generated*",
+	"*.generated.*", "*_pb2.py", "*_pb2_grpc.py", "*.g.dart", "*.freezed.dart", "*.min.js",
+}
+
+// PathTagger 基于路径规则标记 vendored / generated 文件
+type PathTagger struct {
+	vendored  []string
+	generated []string
+}
+
+// NewPathTagger 创建标记器；extra 规则追加在默认规则之后
+// 规则以 "/" 结尾或不含通配符时按目录段匹配，否则按文件名 glob 匹配（含 "/" 的 glob 匹配完整相对路径）
+func NewPathTagger(extraVendored, extraGenerated []string) *PathTagger {
+	t := &PathTagger{
+		vendored:  append([]string{}, defaultVendoredDirs...),
+		generated: append([]string{}, defaultGeneratedGlobs...),
+	}
+	t.vendored = append(t.vendored, extraVendored...)
+	t.generated = append(t.generated, extraGenerated...)
+	return t
+}
+
+// Tag 返回路径标记，普通项目文件返回 ""
+func (t *PathTagger) Tag(filePath string) string {
+	if t == nil || filePath == "" {
+		return ""
+	}
+	p := strings.TrimPrefix(strings.ReplaceAll(filePath, "\\", "/"), "./")
+	if matchPathRules(p, t.vendored) {
+		return PathTagVendored
+	}
+	if matchPathRules(p, t.generated) {
+		return PathTagGenerated
+	}
+	return ""
+}
+
+// Excluded 是否为 vendored / generated 文件
+func (t *PathTagger) Excluded(filePath string) bool {
+	return t.Tag(filePath) != ""
+}
+
+func matchPathRules(p string, rules []string) bool {
+	base := path.Base(p)
+	segments := strings.Split(path.Dir(p), "/")
+	for _, rule := range rules {
+		rule = strings.TrimSpace(strings.ReplaceAll(rule, "\\", "/"))
+		if rule == "" {
+			continue
+		}
+		if !strings.ContainsAny(rule, "*?[") {
+			dir := strings.Trim(rule, "/")
+			if strings.Contains(dir, "/") {
+				if p == dir || strings.HasPrefix(p, dir+"/") || strings.Contains(p, "/"+dir+"/") {
+					return true
+				}
+				continue
+			}
+			for _, seg := range segments {
+				if seg == dir {
+					return true
+				}
+			}
+			continue
+		}
+		target := base
+		if strings.Contains(rule, "/") {
+			target = p
+		}
+		if ok, _ := path.Match(rule, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterMap 从项目地图中剔除 vendored / generated 文件，返回剔除的符号数
+func (t *PathTagger) FilterMap(m *MapResult) int {
+	if t == nil || m == nil {
+		return 0
+	}
+	removed := 0
+	for key, nodes := range m.Structure {
+		kept := nodes[:0]
+		for _, n := range nodes {
+			if t.Excluded(n.FilePath) || (n.FilePath == "" && t.Excluded(key)) {
+				removed++
+				if m.ComplexityMap != nil {
+					delete(m.ComplexityMap, n.Name)
+				}
+				continue
+			}
+			kept = append(kept, n)
+		}
+		if len(kept) == 0 {
+			delete(m.Structure, key)
+		} else {
+			m.Structure[key] = kept
+		}
+	}
+	m.Statistics.TotalSymbols -= removed
+	if m.Statistics.TotalSymbols < 0 {
+		m.Statistics.TotalSymbols = 0
+	}
+	return removed
+}
+
+// FilterStructure 从目录结构中剔除 vendored 目录与已列出的 generated 文件，返回剔除的文件数
+func (t *PathTagger) FilterStructure(s *StructureResult) int {
+	if t == nil || s == nil {
+		return 0
+	}
+	removed := 0
+	for dir, info := range s.Structure {
+		if dir != "" && matchPathRules(dir+"/_", t.vendored) {
+			removed += info.FileCount
+			delete(s.Structure, dir)
+			continue
+		}
+		var kept []string
+		for _, f := range info.Files {
+			full := f
+			if dir != "" {
+				full = dir + "/" + f
+			}
+			if t.Excluded(full) {
+				continue
+			}
+			kept = append(kept, f)
+		}
+		dropped := len(info.Files) - len(kept)
+		if dropped == 0 {
+			continue
+		}
+		removed += dropped
+		info.Files = kept
+		info.FileCount -= dropped
+		if info.FileCount <= 0 {
+			delete(s.Structure, dir)
+			continue
+		}
+		s.Structure[dir] = info
+	}
+	s.TotalFiles -= removed
+	if s.TotalFiles < 0 {
+		s.TotalFiles = 0
+	}
+	return removed
+}
+
+// FilterImpact 从影响分析的调用方列表中剔除 vendored / generated 文件，返回剔除数
+func (t *PathTagger) FilterImpact(r *ImpactResult) int {
+	if t == nil || r == nil {
+		return 0
+	}
+	filter := func(callers []CallerInfo) ([]CallerInfo, int) {
+		var kept []CallerInfo
+		n := 0
+		for _, c := range callers {
+			if t.Excluded(c.Node.FilePath) {
+				n++
+				continue
+			}
+			kept = append(kept, c)
+		}
+		return kept, n
+	}
+	var d, i int
+	r.DirectCallers, d = filter(r.DirectCallers)
+	r.IndirectCallers, i = filter(r.IndirectCallers)
+	r.AffectedNodes -= d + i
+	if r.AffectedNodes < 0 {
+		r.AffectedNodes = 0
+	}
+	return d + i
+}
+
+// ExcludePaths 从调用图中移除 vendored / generated 文件中的符号及相关边，返回移除的符号数
+func (g *CallGraph) ExcludePaths(t *PathTagger) int {
+	if t == nil {
+		return 0
+	}
+	removed := make(map[int]bool)
+	for id, sym := range g.Symbols {
+		if t.Excluded(sym.FilePath) {
+			removed[id] = true
+		}
+	}
+	if len(removed) == 0 {
+		return 0
+	}
+
+	for id := range removed {
+		delete(g.Symbols, id)
+		delete(g.Edges, id)
+		delete(g.FanIn, id)
+		delete(g.FanOut, id)
+	}
+	for caller, callees := range g.Edges {
+		kept := callees[:0]
+		for _, c := range callees {
+			if removed[c] {
+				continue
+			}
+			kept = append(kept, c)
+		}
+		g.Edges[caller] = kept
+	}
+	// fan-in 需按剩余边重新计算，fan-out 保留原始调用次数（vendored 调用仍是真实依赖）
+	g.FanIn = make(map[int]int)
+	for _, callees := range g.Edges {
+		for _, c := range callees {
+			g.FanIn[c]++
+		}
+	}
+
+	var unresolved []UnresolvedCall
+	for _, u := range g.Unresolved {
+		if !t.Excluded(u.FilePath) {
+			unresolved = append(unresolved, u)
+		}
+	}
+	g.Unresolved = unresolved
+	return len(removed)
+}
@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestPathTagger_Tag(t *testing.T) {
+	tagger := NewPathTagger([]string{"libs/legacy"}, []string{"*_mock.go"})
+
+	cases := map[string]string{
+		"vendor/github.com/x/y.go":      PathTagVendored,
+		"web/node_modules/react/a.js":   PathTagVendored,
+		"libs/legacy/util.go":           PathTagVendored,
+		"api/v1/service.pb.go":          PathTagGenerated,
+		"internal/store/store_mock.go":  PathTagGenerated,
+		"internal/core/memory.go":       "",
+		"internal/vendoring/handler.go": "",
+		"external/auth/client.go":       "",
+		"src/extern/ffi.c":              "",
+	}
+	for path, want := range cases {
+		if got := tagger.Tag(path); got != want {
+			t.Errorf("Tag(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
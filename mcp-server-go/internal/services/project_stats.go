@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 项目统计：按语言汇总文件数/行数/符号数，并列出最大的文件与目录
+// 行数与语言优先取索引库 files 表，未入索引的文件（文档、配置等）快速扫描补齐
+// ============================================================================
+
+const statsMaxReadBytes = 2 << 20
+
+// LanguageStat 单语言统计
+type LanguageStat struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Lines    int    `json:"lines"`
+	Symbols  int    `json:"symbols"`
+}
+
+// SizeStat 文件或目录的规模
+type SizeStat struct {
+	Path    string `json:"path"`
+	Files   int    `json:"files,omitempty"`
+	Lines   int    `json:"lines"`
+	Symbols int    `json:"symbols"`
+}
+
+// ProjectStats 项目统计结果
+type ProjectStats struct {
+	Scope        string         `json:"scope,omitempty"`
+	TotalFiles   int            `json:"total_files"`
+	TotalLines   int            `json:"total_lines"`
+	TotalSymbols int            `json:"total_symbols"`
+	Indexed      bool           `json:"indexed"`
+	Languages    []LanguageStat `json:"languages"`
+	SymbolTypes  map[string]int `json:"symbol_types"`
+	LargestFiles []SizeStat     `json:"largest_files"`
+	LargestDirs  []SizeStat     `json:"largest_dirs"`
+	ElapsedMs    int64          `json:"elapsed_ms"`
+}
+
+// extLanguage 未入索引文件按扩展名归类
+var extLanguage = map[string]string{
+	"go": "go", "py": "python", "js": "javascript", "mjs": "javascript", "jsx": "javascript",
+	"ts": "typescript", "tsx": "typescript", "rs": "rust", "java": "java", "kt": "kotlin",
+	"swift": "swift", "rb": "ruby", "php": "php", "cs": "csharp", "c": "c", "h": "c",
+	"cc": "cpp", "cpp": "cpp", "hpp": "cpp", "vue": "vue",
+	"md": "markdown", "markdown": "markdown", "json": "json", "yaml": "yaml", "yml": "yaml",
+	"toml": "toml", "xml": "xml", "html": "html", "css": "css", "scss": "css",
+	"sh": "shell", "ps1": "powershell", "bat": "batch", "sql": "sql", "txt": "text",
+}
+
+type statsFileRow struct {
+	language string
+	lines    int
+	symbols  int
+}
+
+// loadIndexedFileStats 读取索引库中每个文件的语言、行数与符号数
+// scope 非空时符号类型只统计该目录下的文件
+func loadIndexedFileStats(projectRoot, scope string) (map[string]*statsFileRow, map[string]int, error) {
+	if !hasAnyIndex(projectRoot) {
+		return nil, nil, nil
+	}
+
+	files := make(map[string]*statsFileRow)
+	types := make(map[string]int)
+	err := eachIndexDB(projectRoot, "", func(db *sql.DB) error {
+		rows, err := db.Query(`
+			SELECT f.file_path, COALESCE(f.language, ''), COALESCE(f.line_count, 0), COUNT(s.symbol_id)
+			FROM files f LEFT JOIN symbols s ON s.file_id = f.file_id
+			GROUP BY f.file_id`)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var p string
+			row := &statsFileRow{}
+			if err := rows.Scan(&p, &row.language, &row.lines, &row.symbols); err != nil {
+				continue
+			}
+			files[strings.TrimPrefix(filepath.ToSlash(p), "./")] = row
+		}
+		rows.Close()
+
+		if trows, err := db.Query(`
+			SELECT s.symbol_type, COUNT(*) FROM symbols s JOIN files f ON s.file_id = f.file_id
+			WHERE f.file_path LIKE ? GROUP BY s.symbol_type`, scopeLikePattern(scope)); err == nil {
+			for trows.Next() {
+				var t string
+				var n int
+				if trows.Scan(&t, &n) == nil {
+					types[t] += n
+				}
+			}
+			trows.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, types, nil
+}
+
+func scopeLikePattern(scope string) string {
+	if scope == "" {
+		return "%"
+	}
+	return scope + "/%"
+}
+
+// countFileLines 统计文本文件行数；二进制或过大文件返回 false
+func countFileLines(p string, size int64) (int, bool) {
+	if size > statsMaxReadBytes {
+		return 0, false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return 0, false
+	}
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return 0, false
+	}
+	if len(data) == 0 {
+		return 0, true
+	}
+	n := bytes.Count(data, []byte{'\n'})
+	if data[len(data)-1] != '\n' {
+		n++
+	}
+	return n, true
+}
+
+// CollectProjectStats 汇总 scope 内的语言/行数/符号统计；top 控制最大文件与目录的条数
+func (ai *ASTIndexer) CollectProjectStats(projectRoot, scope string, top int) (*ProjectStats, error) {
+	started := time.Now()
+	if top <= 0 {
+		top = 10
+	}
+	scope = strings.Trim(strings.TrimSpace(filepath.ToSlash(scope)), "/")
+	if scope == "." {
+		scope = ""
+	}
+	base := projectRoot
+	if scope != "" {
+		base = filepath.Join(projectRoot, filepath.FromSlash(scope))
+	}
+	if _, err := os.Stat(base); err != nil {
+		return nil, err
+	}
+
+	indexed, symbolTypes, err := loadIndexedFileStats(projectRoot, scope)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ProjectStats{Scope: scope, Indexed: indexed != nil, SymbolTypes: symbolTypes}
+
+	_, ignoreDirs := detectTechStackAndConfig(projectRoot)
+	ignoreSet := make(map[string]bool)
+	for _, d := range strings.Split(ignoreDirs, ",") {
+		if d = strings.TrimSpace(strings.ToLower(strings.Trim(d, "/\\"))); d != "" {
+			ignoreSet[d] = true
+		}
+	}
+
+	byLang := make(map[string]*LanguageStat)
+	byDir := make(map[string]*SizeStat)
+	var files []SizeStat
+
+	_ = filepath.WalkDir(base, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != base && shouldSkipDetectDir(strings.ToLower(d.Name()), ignoreSet) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(projectRoot, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(rel)), ".")
+
+		lang, lines, symbols := "", 0, 0
+		if row, ok := indexed[rel]; ok {
+			lang, lines, symbols = row.language, row.lines, row.symbols
+		}
+		switch lang {
+		case "", "unknown", "skip", "meta":
+			lang = extLanguage[ext]
+		}
+		if lines == 0 {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			n, ok := countFileLines(p, info.Size())
+			if !ok {
+				return nil // 二进制或超大文件不计入
+			}
+			lines = n
+		}
+		if lang == "" {
+			if ext == "" {
+				lang = "other"
+			} else {
+				lang = "." + ext
+			}
+		}
+
+		ls := byLang[lang]
+		if ls == nil {
+			ls = &LanguageStat{Language: lang}
+			byLang[lang] = ls
+		}
+		ls.Files++
+		ls.Lines += lines
+		ls.Symbols += symbols
+
+		dir := path.Dir(rel)
+		ds := byDir[dir]
+		if ds == nil {
+			ds = &SizeStat{Path: dir}
+			byDir[dir] = ds
+		}
+		ds.Files++
+		ds.Lines += lines
+		ds.Symbols += symbols
+
+		files = append(files, SizeStat{Path: rel, Lines: lines, Symbols: symbols})
+		stats.TotalFiles++
+		stats.TotalLines += lines
+		stats.TotalSymbols += symbols
+		return nil
+	})
+
+	for _, ls := range byLang {
+		stats.Languages = append(stats.Languages, *ls)
+	}
+	sort.Slice(stats.Languages, func(i, j int) bool {
+		if stats.Languages[i].Lines != stats.Languages[j].Lines {
+			return stats.Languages[i].Lines > stats.Languages[j].Lines
+		}
+		return stats.Languages[i].Language < stats.Languages[j].Language
+	})
+
+	stats.LargestFiles = topSizeStats(files, top)
+	dirs := make([]SizeStat, 0, len(byDir))
+	for _, ds := range byDir {
+		dirs = append(dirs, *ds)
+	}
+	stats.LargestDirs = topSizeStats(dirs, top)
+
+	stats.ElapsedMs = time.Since(started).Milliseconds()
+	return stats, nil
+}
+
+func topSizeStats(items []SizeStat, top int) []SizeStat {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Lines != items[j].Lines {
+			return items[i].Lines > items[j].Lines
+		}
+		return items[i].Path < items[j].Path
+	})
+	if len(items) > top {
+		items = items[:top]
+	}
+	return items
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ============================================================================
+// 引擎查询缓存：进程内 LRU，避免同一会话内对同一符号反复启动 Rust 引擎
+// ============================================================================
+//
+// 缓存键 = (项目根, 索引库, 模式, 查询, scope, 索引代次)。索引代次由本进程的重建计数
+// 与索引库文件 (含 -wal) 的 mtime/大小组成，外部进程重建或回滚索引同样会使旧条目失效。
+// 缓存的是引擎输出的原始 JSON，每次命中重新解析，调用方修改结果不会污染缓存。
+
+const defaultQueryCacheSize = 256
+
+// QueryCacheStats 查询缓存统计
+type QueryCacheStats struct {
+	Size     int   `json:"size"`
+	Capacity int   `json:"capacity"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+type queryCacheEntry struct {
+	key  string
+	root string
+	data []byte
+}
+
+type queryCache struct {
+	mu          sync.Mutex
+	capacity    int
+	order       *list.List // 队首为最近使用
+	items       map[string]*list.Element
+	generations map[string]int
+	hits        int64
+	misses      int64
+}
+
+func newQueryCache(capacity int) *queryCache {
+	return &queryCache{
+		capacity:    capacity,
+		order:       list.New(),
+		items:       make(map[string]*list.Element),
+		generations: make(map[string]int),
+	}
+}
+
+// getQueryCacheSize 缓存容量，MPM_QUERY_CACHE_SIZE=0 关闭缓存
+func getQueryCacheSize() int {
+	raw := strings.TrimSpace(os.Getenv("MPM_QUERY_CACHE_SIZE"))
+	if raw == "" {
+		return defaultQueryCacheSize
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return defaultQueryCacheSize
+	}
+	return n
+}
+
+func (c *queryCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*queryCacheEntry).data, true
+}
+
+func (c *queryCache) put(key, root string, data []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*queryCacheEntry).data = data
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&queryCacheEntry{key: key, root: root, data: data})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*queryCacheEntry).key)
+	}
+}
+
+// invalidate 项目重建索引后进入下一代次，并丢弃该项目的全部条目
+func (c *queryCache) invalidate(root string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[root]++
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*queryCacheEntry); e.root == root {
+			c.order.Remove(el)
+			delete(c.items, e.key)
+		}
+		el = next
+	}
+}
+
+func (c *queryCache) generation(root string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[root]
+}
+
+func (c *queryCache) stats() QueryCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return QueryCacheStats{Size: c.order.Len(), Capacity: c.capacity, Hits: c.hits, Misses: c.misses}
+}
+
+// indexFingerprint 索引库文件指纹，库被替换或写入后随之变化
+func indexFingerprint(dbPath string) string {
+	var sb strings.Builder
+	for _, p := range []string{dbPath, dbPath + "-wal"} {
+		if st, err := os.Stat(p); err == nil {
+			fmt.Fprintf(&sb, "%d.%d;", st.ModTime().UnixNano(), st.Size())
+		} else {
+			sb.WriteString("-;")
+		}
+	}
+	return sb.String()
+}
+
+func (ai *ASTIndexer) queryCache() *queryCache {
+	ai.cacheOnce.Do(func() {
+		ai.cache = newQueryCache(getQueryCacheSize())
+	})
+	return ai.cache
+}
+
+// cachedQuery 命中缓存时直接返回引擎输出，否则执行 run 并缓存成功的结果
+func (ai *ASTIndexer) cachedQuery(projectRoot, dbPath, mode, query, scope string, run func() ([]byte, error)) ([]byte, error) {
+	c := ai.queryCache()
+	if c.capacity <= 0 {
+		return run()
+	}
+	root := normalizeProjectRoot(projectRoot)
+	key := strings.Join([]string{root, dbPath, mode, query, scope, strconv.Itoa(c.generation(root)), indexFingerprint(dbPath)}, "\x00")
+	if data, ok := c.get(key); ok {
+		return data, nil
+	}
+	data, err := run()
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, root, data)
+	return data, nil
+}
+
+// InvalidateQueryCache 丢弃项目的查询缓存（索引重建后自动调用）
+func (ai *ASTIndexer) InvalidateQueryCache(projectRoot string) {
+	ai.queryCache().invalidate(normalizeProjectRoot(projectRoot))
+}
+
+// QueryCacheStats 查询缓存命中统计
+func (ai *ASTIndexer) QueryCacheStats() QueryCacheStats {
+	return ai.queryCache().stats()
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQueryCache_HitEvictAndInvalidate(t *testing.T) {
+	root := t.TempDir()
+	dbPath := filepath.Join(root, "symbols.db")
+	if err := os.WriteFile(dbPath, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ai := &ASTIndexer{cache: newQueryCache(2)}
+	ai.cacheOnce.Do(func() {})
+	runs := 0
+	query := func(q string) string {
+		data, err := ai.cachedQuery(root, dbPath, "query", q, "", func() ([]byte, error) {
+			runs++
+			return []byte(q), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	query("A")
+	if got := query("A"); got != "A" || runs != 1 {
+		t.Fatalf("second lookup should hit: got %q, runs=%d", got, runs)
+	}
+	query("B")
+	query("C") // 容量 2，淘汰最久未用的 A
+	query("A")
+	if runs != 4 {
+		t.Errorf("A should have been evicted, runs=%d", runs)
+	}
+
+	ai.InvalidateQueryCache(root)
+	if st := ai.QueryCacheStats(); st.Size != 0 {
+		t.Errorf("invalidate should drop entries, size=%d", st.Size)
+	}
+	query("A")
+	if runs != 5 {
+		t.Errorf("lookup after invalidate should miss, runs=%d", runs)
+	}
+
+	// 外部进程替换索引库同样失效
+	future := time.Now().Add(time.Minute)
+	if err := os.WriteFile(dbPath, []byte("v2-rebuilt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(dbPath, future, future)
+	query("A")
+	if runs != 6 {
+		t.Errorf("lookup after db change should miss, runs=%d", runs)
+	}
+	if st := ai.QueryCacheStats(); st.Hits != 1 || st.Misses != 6 {
+		t.Errorf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 重命名影响：定义 / 调用点（symbols + calls 表）与文本引用（ripgrep 全词匹配）
+// 文本引用按所在位置归类：测试、注释、字符串、文档、其他代码引用，各带置信度
+// ============================================================================
+
+// 引用类别
+const (
+	RenameKindDefinition = "definition"
+	RenameKindCall       = "call"
+	RenameKindReference  = "reference" // 非调用的代码引用（类型使用、import、方法值等）
+	RenameKindTest       = "test"
+	RenameKindComment    = "comment"
+	RenameKindString     = "string"
+	RenameKindDoc        = "doc"
+)
+
+// 重命名置信度：high 可直接改，medium 需确认是同一符号，low 需人工判断是否应改
+const (
+	RenameConfidenceHigh   = "high"
+	RenameConfidenceMedium = "medium"
+	RenameConfidenceLow    = "low"
+)
+
+// RenameRef 一处待重命名的引用
+type RenameRef struct {
+	Kind       string `json:"kind"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Content    string `json:"content,omitempty"` // 行内容；定义为符号类型
+	Caller     string `json:"caller,omitempty"`  // 调用点所在的函数
+	Confidence string `json:"confidence"`
+}
+
+// RenameRefs 某个符号的全部引用
+type RenameRefs struct {
+	OldName     string      `json:"old_name"`
+	Definitions []RenameRef `json:"definitions"`
+	Refs        []RenameRef `json:"refs"`      // 调用点与文本引用，按文件、行号排序
+	Conflicts   []RenameRef `json:"conflicts"` // 新名称已存在的定义
+	TextSearch  bool        `json:"text_search"`
+}
+
+var docExtensions = map[string]bool{".md": true, ".markdown": true, ".rst": true, ".txt": true, ".adoc": true}
+
+// isTestPath 测试文件判定（与 isEntryPoint 的文件规则一致，另含 tests/ 目录）
+func isTestPath(p string) bool {
+	p = strings.ReplaceAll(p, "\\", "/")
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") || strings.HasPrefix(base, "test_") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(p, "tests/") || strings.Contains(p, "/tests/") ||
+		strings.HasPrefix(p, "test/") || strings.Contains(p, "/test/")
+}
+
+// FindRenameReferences 收集 oldName 的定义、调用点与文本引用；newName 非空时检查同名冲突
+func (ai *ASTIndexer) FindRenameReferences(ctx context.Context, projectRoot, oldName, newName, scope string) (*RenameRefs, error) {
+	// 定义与冲突需要看整个项目（分片模式为全部分片），调用点再按 scope 过滤
+	dbs, closeAll, err := openIndexDBs(projectRoot, "")
+	if err != nil {
+		return nil, err
+	}
+	defer closeAll()
+
+	scope = strings.Trim(filepath.ToSlash(scope), "/")
+	inScope := func(p string) bool { return scope == "" || p == scope || strings.HasPrefix(p, scope+"/") }
+	out := &RenameRefs{OldName: oldName}
+
+	var defs []RenameRef
+	canonical := make(map[string]bool)
+	for _, db := range dbs {
+		d, c, err := loadRenameDefinitions(db, oldName)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, d...)
+		for id := range c {
+			canonical[id] = true
+		}
+		if newName != "" && newName != oldName {
+			conflicts, _, _ := loadRenameDefinitions(db, newName)
+			out.Conflicts = append(out.Conflicts, conflicts...)
+		}
+	}
+	for _, d := range defs {
+		if inScope(d.File) {
+			out.Definitions = append(out.Definitions, d)
+		}
+	}
+
+	// 调用点：已解析到该定义的为 high；仅按名称匹配且存在多个同名定义时为 medium
+	seen := make(map[string]bool)
+	key := func(file string, line int) string { return fmt.Sprintf("%s:%d", file, line) }
+	for _, d := range out.Definitions {
+		seen[key(d.File, d.Line)] = true
+	}
+	for _, db := range dbs {
+		calleeIDExpr := "NULL"
+		if hasColumn(db, "calls", "callee_id") {
+			calleeIDExpr = "c.callee_id"
+		}
+		rows, err := db.Query(`
+			SELECT COALESCE(c.call_line, 0), COALESCE(f.file_path, ''), s.name, `+calleeIDExpr+`
+			FROM calls c JOIN symbols s ON c.caller_id = s.symbol_id LEFT JOIN files f ON s.file_id = f.file_id
+			WHERE c.callee_name = ? OR c.callee_name LIKE ?`, oldName, "%."+oldName)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var ref RenameRef
+			var calleeID sql.NullString
+			if rows.Scan(&ref.Line, &ref.File, &ref.Caller, &calleeID) != nil {
+				continue
+			}
+			ref.File = strings.TrimPrefix(filepath.ToSlash(ref.File), "./")
+			if !inScope(ref.File) || seen[key(ref.File, ref.Line)] {
+				continue
+			}
+			seen[key(ref.File, ref.Line)] = true
+			ref.Kind, ref.Confidence = RenameKindCall, RenameConfidenceHigh
+			if !(calleeID.Valid && canonical[calleeID.String]) && len(defs) > 1 {
+				ref.Confidence = RenameConfidenceMedium
+			}
+			if isTestPath(ref.File) {
+				ref.Kind = RenameKindTest
+			}
+			out.Refs = append(out.Refs, ref)
+		}
+		rows.Close()
+	}
+
+	// 文本引用：全词、区分大小写，排除已收录的定义与调用行
+	root := projectRoot
+	if scope != "" {
+		root = filepath.Join(projectRoot, filepath.FromSlash(scope))
+	}
+	matches, err := NewRipgrepEngine().Search(ctx, SearchOptions{
+		Query: oldName, RootPath: root, CaseSensitive: true, WordMatch: true, MaxCount: 200,
+	})
+	if err == nil {
+		out.TextSearch = true
+		for _, m := range matches {
+			rel := m.FilePath
+			if r, err := filepath.Rel(projectRoot, filepath.FromSlash(m.FilePath)); err == nil && !strings.HasPrefix(r, "..") {
+				rel = filepath.ToSlash(r)
+			}
+			if seen[key(rel, m.LineNumber)] {
+				continue
+			}
+			seen[key(rel, m.LineNumber)] = true
+			ref := RenameRef{File: rel, Line: m.LineNumber, Content: strings.TrimSpace(m.Content)}
+			ref.Kind, ref.Confidence = classifyTextRef(rel, m.Content, oldName)
+			out.Refs = append(out.Refs, ref)
+		}
+	}
+
+	fillRefContent(projectRoot, out.Refs)
+	sort.Slice(out.Refs, func(i, j int) bool {
+		if out.Refs[i].File != out.Refs[j].File {
+			return out.Refs[i].File < out.Refs[j].File
+		}
+		return out.Refs[i].Line < out.Refs[j].Line
+	})
+	return out, nil
+}
+
+// fillRefContent 调用点来自索引库，没有行内容；按文件读一次补上，便于核对
+func fillRefContent(projectRoot string, refs []RenameRef) {
+	cache := make(map[string][]string)
+	for i := range refs {
+		if refs[i].Content != "" || refs[i].Line <= 0 {
+			continue
+		}
+		lines, ok := cache[refs[i].File]
+		if !ok {
+			if data, err := os.ReadFile(filepath.Join(projectRoot, filepath.FromSlash(refs[i].File))); err == nil {
+				lines = strings.Split(string(data), "\n")
+			}
+			cache[refs[i].File] = lines
+		}
+		if refs[i].Line <= len(lines) {
+			refs[i].Content = strings.TrimSpace(lines[refs[i].Line-1])
+		}
+	}
+}
+
+// loadRenameDefinitions 同名符号定义及其 canonical_id 集合
+func loadRenameDefinitions(db *sql.DB, name string) ([]RenameRef, map[string]bool, error) {
+	rows, err := db.Query(`
+		SELECT s.symbol_type, COALESCE(f.file_path, ''), COALESCE(s.line_start, 0), COALESCE(s.canonical_id, '')
+		FROM symbols s LEFT JOIN files f ON s.file_id = f.file_id WHERE s.name = ?`, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var defs []RenameRef
+	canonical := make(map[string]bool)
+	for rows.Next() {
+		var typ, cid string
+		ref := RenameRef{Kind: RenameKindDefinition, Confidence: RenameConfidenceHigh}
+		if rows.Scan(&typ, &ref.File, &ref.Line, &cid) != nil {
+			continue
+		}
+		ref.File = strings.TrimPrefix(filepath.ToSlash(ref.File), "./")
+		ref.Content = typ
+		if cid != "" {
+			canonical[cid] = true
+		}
+		defs = append(defs, ref)
+	}
+	return defs, canonical, nil
+}
+
+// classifyTextRef 文本命中按文件类型与行内位置归类
+func classifyTextRef(file, line, name string) (string, string) {
+	switch {
+	case docExtensions[strings.ToLower(path.Ext(file))]:
+		return RenameKindDoc, RenameConfidenceLow
+	case isTestPath(file):
+		return RenameKindTest, RenameConfidenceMedium
+	}
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", "<!--", "\"\"\""} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return RenameKindComment, RenameConfidenceLow
+		}
+	}
+	if idx := strings.Index(line, name); idx > 0 {
+		before := line[:idx]
+		if strings.Count(before, `"`)%2 == 1 || strings.Count(before, "'")%2 == 1 || strings.Count(before, "`")%2 == 1 {
+			return RenameKindString, RenameConfidenceLow
+		}
+		if strings.Contains(before, "//") {
+			return RenameKindComment, RenameConfidenceLow
+		}
+	}
+	return RenameKindReference, RenameConfidenceMedium
+}
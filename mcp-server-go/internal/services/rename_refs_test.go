@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindRenameReferences(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"pkg/config.go":      "package pkg\n\nfunc LoadConfig() {}\n",
+		"cmd/main.go":        "package main\n\nfunc main() {\n\tpkg.LoadConfig()\n\t// LoadConfig reads settings\n\tlog.Print(\"LoadConfig done\")\n}\n",
+		"pkg/config_test.go": "package pkg\n\nfunc TestLoad(t *testing.T) { LoadConfig() }\n",
+		"README.md":          "Call LoadConfig first.\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbPath := getDBPath(root)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	execDB(t, dbPath,
+		"CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)",
+		"CREATE TABLE symbols (symbol_id INTEGER PRIMARY KEY, file_id INTEGER, name TEXT, symbol_type TEXT, line_start INTEGER, canonical_id TEXT)",
+		"CREATE TABLE calls (caller_id INTEGER, callee_name TEXT, call_line INTEGER)",
+		"INSERT INTO files VALUES (1, 'pkg/config.go'), (2, 'cmd/main.go'), (3, 'pkg/config_test.go')",
+		"INSERT INTO symbols VALUES (1, 1, 'LoadConfig', 'function', 3, 'pkg.LoadConfig'), (2, 2, 'main', 'function', 3, 'main.main'), (3, 3, 'TestLoad', 'function', 3, 'pkg.TestLoad')",
+		"INSERT INTO calls VALUES (2, 'pkg.LoadConfig', 4), (3, 'LoadConfig', 3)",
+	)
+
+	r, err := (&ASTIndexer{}).FindRenameReferences(context.Background(), root, "LoadConfig", "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Definitions) != 1 || r.Definitions[0].File != "pkg/config.go" {
+		t.Fatalf("definitions = %+v", r.Definitions)
+	}
+	if len(r.Conflicts) != 1 {
+		t.Errorf("new name collides with main(), conflicts = %+v", r.Conflicts)
+	}
+	kinds := make(map[string]string)
+	for _, ref := range r.Refs {
+		kinds[fmt.Sprintf("%s:%d", ref.File, ref.Line)] = ref.Kind + "/" + ref.Confidence
+	}
+	want := map[string]string{
+		"cmd/main.go:4":        RenameKindCall + "/" + RenameConfidenceHigh,
+		"cmd/main.go:5":        RenameKindComment + "/" + RenameConfidenceLow,
+		"cmd/main.go:6":        RenameKindString + "/" + RenameConfidenceLow,
+		"pkg/config_test.go:3": RenameKindTest + "/" + RenameConfidenceHigh,
+		"README.md:1":          RenameKindDoc + "/" + RenameConfidenceLow,
+	}
+	for k, v := range want {
+		if kinds[k] != v {
+			t.Errorf("%s = %q, want %q (all: %v)", k, kinds[k], v, kinds)
+		}
+	}
+	if len(r.Refs) != len(want) {
+		t.Errorf("unexpected refs: %v", kinds)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 文件系统探测的递归边界 ==========
+//
+// 技术栈探测会递归扫描项目目录。为避免符号链接环导致死循环、误入挂载的网络目录或超大目录，
+// 扫描器记录已访问目录的真实路径，只跟随指向项目内部的符号链接，并受深度/条目数/总耗时预算约束。
+// 预算耗尽时探测结果可能不完整，ScanReport 会记录实际生效的限制。
+
+// ScanLimits 目录扫描预算
+type ScanLimits struct {
+	MaxDepth   int           `json:"max_depth"`
+	MaxEntries int           `json:"max_entries"`
+	Timeout    time.Duration `json:"timeout"`
+}
+
+// defaultScanLimits 技术栈探测的默认预算
+var defaultScanLimits = ScanLimits{MaxDepth: 8, MaxEntries: 200000, Timeout: 10 * time.Second}
+
+// ScanReport 一次目录扫描的统计与截断原因
+type ScanReport struct {
+	Root             string     `json:"root"`
+	Limits           ScanLimits `json:"limits"`
+	Entries          int        `json:"entries"`
+	ElapsedMs        int64      `json:"elapsed_ms"`
+	SymlinksFollowed int        `json:"symlinks_followed,omitempty"`
+	SymlinksSkipped  int        `json:"symlinks_skipped,omitempty"` // 指向项目外、失效或成环的链接
+	DepthLimited     bool       `json:"depth_limited,omitempty"`    // 存在超过深度上限未扫描的目录
+	Truncated        bool       `json:"truncated"`                  // 条目数或耗时预算耗尽，结果不完整
+	Reasons          []string   `json:"reasons,omitempty"`
+}
+
+// dirScanner 带预算与环检测的目录遍历状态
+type dirScanner struct {
+	rootReal string
+	limits   ScanLimits
+	started  time.Time
+	deadline time.Time
+	visited  map[string]bool
+	report   *ScanReport
+}
+
+func newDirScanner(root string, limits ScanLimits) *dirScanner {
+	rootReal, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		rootReal = root
+	}
+	now := time.Now()
+	return &dirScanner{
+		rootReal: filepath.Clean(rootReal),
+		limits:   limits,
+		started:  now,
+		deadline: now.Add(limits.Timeout),
+		visited:  make(map[string]bool),
+		report:   &ScanReport{Root: filepath.ToSlash(root), Limits: limits},
+	}
+}
+
+func (s *dirScanner) truncate(reason string) {
+	s.report.Truncated = true
+	for _, r := range s.report.Reasons {
+		if r == reason {
+			return
+		}
+	}
+	s.report.Reasons = append(s.report.Reasons, reason)
+}
+
+// readDir 在预算内读取目录；超出深度/条目/时间预算或目录已访问过（符号链接环）时返回 false
+// 深度上限是探测的常规边界，只记录不视为截断
+func (s *dirScanner) readDir(dir string, depth int) ([]os.DirEntry, bool) {
+	if depth > s.limits.MaxDepth {
+		s.report.DepthLimited = true
+		return nil, false
+	}
+	if s.limits.Timeout > 0 && time.Now().After(s.deadline) {
+		s.truncate(fmt.Sprintf("扫描超时 %s", s.limits.Timeout))
+		return nil, false
+	}
+	if s.limits.MaxEntries > 0 && s.report.Entries >= s.limits.MaxEntries {
+		s.truncate(fmt.Sprintf("达到条目上限 %d", s.limits.MaxEntries))
+		return nil, false
+	}
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, false
+	}
+	if s.visited[real] {
+		s.report.SymlinksSkipped++
+		return nil, false
+	}
+	s.visited[real] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, false
+	}
+	s.report.Entries += len(entries)
+	return entries, true
+}
+
+// isDir 普通目录，或指向项目内部目录的符号链接；指向项目外的链接（可能是网络挂载）不跟随
+func (s *dirScanner) isDir(parent string, e os.DirEntry) bool {
+	if e.IsDir() {
+		return true
+	}
+	if e.Type()&os.ModeSymlink == 0 {
+		return false
+	}
+	real, err := filepath.EvalSymlinks(filepath.Join(parent, e.Name()))
+	if err != nil || !pathWithin(s.rootReal, real) {
+		s.report.SymlinksSkipped++
+		return false
+	}
+	info, err := os.Stat(real)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	s.report.SymlinksFollowed++
+	return true
+}
+
+func (s *dirScanner) finish(root string) *ScanReport {
+	s.report.ElapsedMs = time.Since(s.started).Milliseconds()
+	lastScanReports.Store(normalizeProjectRoot(root), s.report)
+	if s.report.Truncated {
+		fmt.Fprintf(os.Stderr, "[Detect][WARN] %s 目录探测不完整 (%s)，技术栈识别可能遗漏\n", root, strings.Join(s.report.Reasons, "；"))
+	}
+	return s.report
+}
+
+func pathWithin(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// lastScanReports 各项目最近一次探测扫描的报告
+var lastScanReports sync.Map
+
+// LastScanReport 返回项目最近一次技术栈探测的扫描报告，尚未扫描时返回 nil
+func LastScanReport(projectRoot string) *ScanReport {
+	if v, ok := lastScanReports.Load(normalizeProjectRoot(projectRoot)); ok {
+		return v.(*ScanReport)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"database/sql"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== 搜索落空时的目录建议 ==========
+//
+// 符号搜索/锚点解析一无所获时，用索引库 files 表中的路径与查询词做模糊匹配，
+// 按目录汇总打分，给出"可能在这里"的候选 scope，让重试有具体的落点。
+// 文件名命中权重高于目录名命中；同一目录下命中的文件越多得分越高。
+
+// ScopeSuggestion 候选目录
+type ScopeSuggestion struct {
+	Scope   string   `json:"scope"`
+	Score   float64  `json:"score"`
+	Files   []string `json:"files"`   // 目录下命中得分最高的文件（最多 3 个）
+	Matched []string `json:"matched"` // 命中的查询词
+}
+
+// 命中权重
+const (
+	scopeExactWeight  = 1.0 // 单词完全相同
+	scopePrefixWeight = 0.6 // 互为前缀或包含（auth ↔ authentication）
+	scopeFuzzyWeight  = 0.4 // 编辑距离 1（拼写差异）
+	scopeDirFactor    = 0.5 // 目录名命中相对文件名命中的折算
+)
+
+// SuggestScopes 返回与 query 模糊匹配的目录，按得分降序；scope 非空时只在该目录下查找
+func SuggestScopes(projectRoot, query, scope string, limit int) []ScopeSuggestion {
+	tokens := core.QueryTokens(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+	paths, err := loadIndexedPaths(projectRoot, scope)
+	if err != nil || len(paths) == 0 {
+		return nil
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+
+	type fileHit struct {
+		path  string
+		score float64
+	}
+	type dirAgg struct {
+		files   []fileHit
+		matched map[string]bool
+	}
+	dirs := make(map[string]*dirAgg)
+	for _, p := range paths {
+		dir := path.Dir(p)
+		base := strings.TrimSuffix(path.Base(p), path.Ext(p))
+		nameWords := core.QueryTokens(base)
+		dirWords := core.QueryTokens(dir)
+
+		var score float64
+		var matched []string
+		for _, t := range tokens {
+			best := bestTokenMatch(t, nameWords)
+			if d := bestTokenMatch(t, dirWords) * scopeDirFactor; d > best {
+				best = d
+			}
+			if best > 0 {
+				score += best
+				matched = append(matched, t)
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		agg := dirs[dir]
+		if agg == nil {
+			agg = &dirAgg{matched: make(map[string]bool)}
+			dirs[dir] = agg
+		}
+		agg.files = append(agg.files, fileHit{p, score})
+		for _, t := range matched {
+			agg.matched[t] = true
+		}
+	}
+
+	out := make([]ScopeSuggestion, 0, len(dirs))
+	for dir, agg := range dirs {
+		sort.SliceStable(agg.files, func(i, j int) bool { return agg.files[i].score > agg.files[j].score })
+		// 最佳文件得分 + 额外命中文件的少量加成（封顶 1 分），避免大目录靠数量压过精确命中
+		bonus := 0.2 * float64(len(agg.files)-1)
+		if bonus > 1 {
+			bonus = 1
+		}
+		s := ScopeSuggestion{Scope: dir, Score: agg.files[0].score + bonus}
+		for i, f := range agg.files {
+			if i == 3 {
+				break
+			}
+			s.Files = append(s.Files, path.Base(f.path))
+		}
+		for _, t := range tokens {
+			if agg.matched[t] {
+				s.Matched = append(s.Matched, t)
+			}
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Scope < out[j].Scope
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// bestTokenMatch 查询词与一组单词的最佳命中权重
+func bestTokenMatch(token string, words []string) float64 {
+	var best float64
+	for _, w := range words {
+		var s float64
+		switch {
+		case w == token:
+			s = scopeExactWeight
+		case strings.HasPrefix(w, token) || strings.HasPrefix(token, w) ||
+			(len(token) >= 4 && strings.Contains(w, token)):
+			s = scopePrefixWeight
+		case len(token) >= 5 && withinOneEdit(token, w):
+			s = scopeFuzzyWeight
+		}
+		if s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// withinOneEdit 两个 ASCII 单词的编辑距离是否不超过 1
+func withinOneEdit(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+	i, j, edits := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(a) == len(b) {
+			i++
+		}
+		j++
+	}
+	return edits+(len(b)-j)+(len(a)-i) <= 1
+}
+
+// loadIndexedPaths 读取索引库中的文件路径（正斜杠、相对项目根）
+func loadIndexedPaths(projectRoot, scope string) ([]string, error) {
+	if !hasAnyIndex(projectRoot) {
+		return nil, nil
+	}
+	scope = strings.Trim(filepath.ToSlash(scope), "/")
+	var out []string
+	err := eachIndexDB(projectRoot, scope, func(db *sql.DB) error {
+		rows, err := db.Query("SELECT file_path FROM files")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var p string
+			if rows.Scan(&p) != nil {
+				continue
+			}
+			p = strings.TrimPrefix(filepath.ToSlash(p), "./")
+			if scope != "" && !strings.HasPrefix(p, scope+"/") {
+				continue
+			}
+			out = append(out, p)
+		}
+		return rows.Err()
+	})
+	return out, err
+}
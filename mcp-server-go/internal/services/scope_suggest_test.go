@@ -0,0 +1,30 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSuggestScopes_FuzzyFileNames(t *testing.T) {
+	root := t.TempDir()
+	dbPath := getDBPath(root)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	execDB(t, dbPath,
+		"CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)",
+		"INSERT INTO files (file_path) VALUES ('internal/auth/login_handler.go'), ('internal/auth/session.go'), ('internal/billing/invoice.go'), ('docs/authentication.md')",
+	)
+
+	got := SuggestScopes(root, "LoginSesion", "", 5)
+	if len(got) == 0 || got[0].Scope != "internal/auth" {
+		t.Fatalf("expected internal/auth first, got %+v", got)
+	}
+	if len(got[0].Matched) != 2 {
+		t.Errorf("expected both tokens matched (login exact, sesion fuzzy), got %v", got[0].Matched)
+	}
+	if got := SuggestScopes(root, "invoice", "internal/auth", 5); len(got) != 0 {
+		t.Errorf("scope filter ignored: %+v", got)
+	}
+}
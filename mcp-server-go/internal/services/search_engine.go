@@ -9,6 +9,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -33,6 +35,7 @@ type SearchOptions struct {
 	CaseSensitive  bool     // 是否区分大小写
 	WordMatch      bool     // 是否全词匹配
 	Extensions     []string // 文件扩展名过滤 (e.g. "go", "py")
+	Languages      []string // 语言过滤 (e.g. "go", "typescript")，按扩展名展开后与 Extensions 合并
 	IncludePattern []string // 包含的文件 glob (e.g. "*.go")
 	IgnorePattern  []string // 忽略的文件 glob
 	ContextLines   int      // 上下文行数
@@ -81,6 +84,41 @@ type RgMatchText struct {
 	Text string `json:"text"`
 }
 
+// LanguageExtensions 语言名展开为扩展名（typescript → ts, tsx）；未知语言按扩展名原样使用
+func LanguageExtensions(langs []string) []string {
+	var exts []string
+	seen := make(map[string]bool)
+	add := func(ext string) {
+		if !seen[ext] {
+			seen[ext] = true
+			exts = append(exts, ext)
+		}
+	}
+	for _, lang := range langs {
+		lang = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(lang), "."))
+		if lang == "" {
+			continue
+		}
+		found := false
+		for ext, l := range extLanguage {
+			if l == lang {
+				found = true
+				add(ext)
+			}
+		}
+		if !found {
+			add(lang)
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// extensions Extensions 与 Languages 展开后的扩展名
+func (o SearchOptions) extensions() []string {
+	return append(append([]string{}, o.Extensions...), LanguageExtensions(o.Languages)...)
+}
+
 // Search 执行搜索
 func (e *RipgrepEngine) Search(ctx context.Context, opts SearchOptions) ([]TextMatch, error) {
 	if opts.RootPath == "" {
@@ -130,7 +168,7 @@ func (e *RipgrepEngine) Search(ctx context.Context, opts SearchOptions) ([]TextM
 
 	// 扩展名过滤
 	// rg -t type 需要预定义类型，较麻烦。直接用 glob 模拟
-	for _, ext := range opts.Extensions {
+	for _, ext := range opts.extensions() {
 		ext = strings.TrimPrefix(ext, ".")
 		args = append(args, "-g", "*."+ext)
 	}
@@ -171,7 +209,7 @@ func (e *RipgrepEngine) Search(ctx context.Context, opts SearchOptions) ([]TextM
 		return nil, fmt.Errorf("ripgrep failed: %v, stderr: %s", err, stderr.String())
 	}
 
-	return e.parseOutput(stdout.Bytes())
+	return e.parseOutput(stdout.Bytes(), opts.ContextLines)
 }
 
 // nativeSearch 使用 Go 原生 遍历进行简单搜索 (兜底方案)
@@ -182,25 +220,42 @@ func (e *RipgrepEngine) nativeSearch(ctx context.Context, opts SearchOptions) ([
 	if !opts.CaseSensitive {
 		query = strings.ToLower(query)
 	}
+	var re *regexp.Regexp
+	if opts.IsRegex {
+		pattern := opts.Query
+		if !opts.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+	}
+	extensions := opts.extensions()
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // 跳过错误
 		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
 		if info.IsDir() {
 			// 简单忽略常见目录
 			name := info.Name()
 			if name == ".git" || name == "node_modules" || name == "vendor" || name == "target" || name == "build" || name == ".mcp-data" {
 				return filepath.SkipDir
 			}
+			if path != root && matchAnyGlob(opts.IgnorePattern, name, rel) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
 		// 检查扩展名
-		if len(opts.Extensions) > 0 {
+		if len(extensions) > 0 {
 			ext := filepath.Ext(path)
 			matched := false
-			for _, e := range opts.Extensions {
+			for _, e := range extensions {
 				if strings.EqualFold(ext, "."+strings.TrimPrefix(e, ".")) {
 					matched = true
 					break
@@ -210,6 +265,13 @@ func (e *RipgrepEngine) nativeSearch(ctx context.Context, opts SearchOptions) ([
 				return nil
 			}
 		}
+		// 检查 glob 过滤
+		if matchAnyGlob(opts.IgnorePattern, info.Name(), rel) {
+			return nil
+		}
+		if len(opts.IncludePattern) > 0 && !matchAnyGlob(opts.IncludePattern, info.Name(), rel) {
+			return nil
+		}
 
 		// 读取文件内容进行简单搜索
 		data, err := os.ReadFile(path)
@@ -219,20 +281,28 @@ func (e *RipgrepEngine) nativeSearch(ctx context.Context, opts SearchOptions) ([
 
 		content := string(data)
 		lines := strings.Split(content, "\n")
+		fileMatches := 0
 		for i, line := range lines {
 			displayLine := line
 			if !opts.CaseSensitive {
 				line = strings.ToLower(line)
 			}
 
-			if strings.Contains(line, query) {
-				results = append(results, TextMatch{
+			if (re != nil && re.MatchString(displayLine)) || (re == nil && strings.Contains(line, query)) {
+				m := TextMatch{
 					FilePath:   filepath.ToSlash(path),
 					LineNumber: i + 1,
 					Content:    strings.TrimSpace(displayLine),
-				})
-				if opts.MaxCount > 0 && len(results) >= opts.MaxCount {
-					return fmt.Errorf("limit reached")
+				}
+				if opts.ContextLines > 0 {
+					from, to := max(0, i-opts.ContextLines), min(len(lines), i+1+opts.ContextLines)
+					m.ContextBefore = strings.TrimRight(strings.Join(lines[from:i], "\n"), "\r\n")
+					m.ContextAfter = strings.TrimRight(strings.Join(lines[i+1:to], "\n"), "\r\n")
+				}
+				results = append(results, m)
+				// 与 rg -m 一致：MaxCount 为单文件上限
+				if fileMatches++; opts.MaxCount > 0 && fileMatches >= opts.MaxCount {
+					return nil
 				}
 			}
 
@@ -247,21 +317,47 @@ func (e *RipgrepEngine) nativeSearch(ctx context.Context, opts SearchOptions) ([
 		return nil
 	})
 
-	if err != nil && err.Error() != "limit reached" {
+	if err != nil {
 		return nil, err
 	}
 
 	return results, nil
 }
 
-// parseOutput 解析 JSON 输出
-func (e *RipgrepEngine) parseOutput(output []byte) ([]TextMatch, error) {
+// matchAnyGlob 文件名或相对路径匹配任一 glob（与 rg -g 的常见用法对齐："*.go"、"internal/**"）
+func matchAnyGlob(patterns []string, name, rel string) bool {
+	for _, p := range patterns {
+		p = filepath.ToSlash(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+		if prefix, found := strings.CutSuffix(p, "/**"); found && (rel == prefix || strings.HasPrefix(rel, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOutput 解析 JSON 输出；contextLines > 0 时把 context 消息归并到相邻匹配的上下文
+func (e *RipgrepEngine) parseOutput(output []byte, contextLines int) ([]TextMatch, error) {
 	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 	var results []TextMatch
 
-	// 暂存 context，rg json 的 context 是分开的消息
-	// 目前简化处理，只提取 match 类型的行
-	// TODO: 完整支持 context (rg 输出顺序是 Context -> Match -> Context)
+	// rg 输出顺序为 begin → (context | match)* → end，context 可能同时属于前一匹配的下文与后一匹配的上文
+	type ctxLine struct {
+		line int
+		text string
+	}
+	var pending []ctxLine
+	after := make(map[int][]string)
+	last := -1
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
@@ -270,7 +366,20 @@ func (e *RipgrepEngine) parseOutput(output []byte) ([]TextMatch, error) {
 			continue // 忽略解析错误行
 		}
 
-		if msg.Type == "match" {
+		switch msg.Type {
+		case "begin":
+			pending, last = nil, -1
+		case "context":
+			var ctxData RgMatchData
+			if contextLines <= 0 || json.Unmarshal(msg.Data, &ctxData) != nil {
+				continue
+			}
+			text := strings.TrimRight(ctxData.Lines.Text, "\r\n")
+			if last >= 0 && ctxData.LineNumber-results[last].LineNumber <= contextLines {
+				after[last] = append(after[last], text)
+			}
+			pending = append(pending, ctxLine{ctxData.LineNumber, text})
+		case "match":
 			var matchData RgMatchData
 			if err := json.Unmarshal(msg.Data, &matchData); err != nil {
 				continue
@@ -289,14 +398,27 @@ func (e *RipgrepEngine) parseOutput(output []byte) ([]TextMatch, error) {
 			// 实际上 rg --json 返回的是包含换行符的完整行
 			content := strings.TrimRight(matchData.Lines.Text, "\r\n")
 
+			var before []string
+			for _, c := range pending {
+				if matchData.LineNumber-c.line <= contextLines {
+					before = append(before, c.text)
+				}
+			}
+			pending = nil
+
 			results = append(results, TextMatch{
-				FilePath:   cleanPath,
-				LineNumber: matchData.LineNumber,
-				Content:    content,
-				Submatches: subs,
+				FilePath:      cleanPath,
+				LineNumber:    matchData.LineNumber,
+				Content:       content,
+				ContextBefore: strings.Join(before, "\n"),
+				Submatches:    subs,
 			})
+			last = len(results) - 1
 		}
 	}
+	for i, lines := range after {
+		results[i].ContextAfter = strings.Join(lines, "\n")
+	}
 
 	return results, nil
 }
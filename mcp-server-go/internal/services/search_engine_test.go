@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRipgrepParseOutput_Context(t *testing.T) {
+	// rg --json -C1：第 3、4 行相邻匹配，第 2、5 行为上下文
+	out := strings.Join([]string{
+		`{"type":"begin","data":{"path":{"text":"/p/a.go"}}}`,
+		`{"type":"context","data":{"path":{"text":"/p/a.go"},"lines":{"text":"two\n"},"line_number":2}}`,
+		`{"type":"match","data":{"path":{"text":"/p/a.go"},"lines":{"text":"three\n"},"line_number":3,"submatches":[]}}`,
+		`{"type":"match","data":{"path":{"text":"/p/a.go"},"lines":{"text":"four\n"},"line_number":4,"submatches":[]}}`,
+		`{"type":"context","data":{"path":{"text":"/p/a.go"},"lines":{"text":"five\n"},"line_number":5}}`,
+		`{"type":"end","data":{}}`,
+	}, "\n")
+	got, err := (&RipgrepEngine{}).parseOutput([]byte(out), 1)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("expected 2 matches, got %+v (%v)", got, err)
+	}
+	if got[0].ContextBefore != "two" || got[0].ContextAfter != "" {
+		t.Errorf("first match context = %q / %q", got[0].ContextBefore, got[0].ContextAfter)
+	}
+	if got[1].ContextBefore != "" || got[1].ContextAfter != "five" {
+		t.Errorf("second match context = %q / %q", got[1].ContextBefore, got[1].ContextAfter)
+	}
+}
+
+func TestNativeSearch_FiltersAndContext(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"pkg/a.go":      "package pkg\n// TODO(alice): fix\nfunc A() {}\n",
+		"pkg/a_test.go": "package pkg\n// TODO(bob): test\n",
+		"web/app.ts":    "// TODO(carol): ui\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := (&RipgrepEngine{}).nativeSearch(context.Background(), SearchOptions{
+		Query: `TODO\((\w+)\)`, RootPath: root, IsRegex: true, CaseSensitive: true,
+		Languages: []string{"go"}, IgnorePattern: []string{"*_test.go"}, ContextLines: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !strings.HasSuffix(got[0].FilePath, "pkg/a.go") || got[0].LineNumber != 2 {
+		t.Fatalf("expected only pkg/a.go:2, got %+v", got)
+	}
+	if got[0].ContextBefore != "package pkg" || got[0].ContextAfter != "func A() {}" {
+		t.Errorf("context = %q / %q", got[0].ContextBefore, got[0].ContextAfter)
+	}
+	if exts := LanguageExtensions([]string{"typescript"}); strings.Join(exts, ",") != "ts,tsx" {
+		t.Errorf("typescript extensions = %v", exts)
+	}
+}
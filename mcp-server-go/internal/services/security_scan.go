@@ -0,0 +1,281 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 安全扫描：在已索引文件上按规则查找硬编码凭据、用户输入拼接命令执行、路径穿越风险
+// 规则是行级正则启发式，结果按严重程度排序；测试文件中的命中降一级（多为假数据）。
+// 行内注释 nosec 或 mpm:ignore-security 可屏蔽该行。
+// ============================================================================
+
+// 严重程度
+const (
+	SecuritySeverityHigh   = "high"
+	SecuritySeverityMedium = "medium"
+	SecuritySeverityLow    = "low"
+)
+
+// 类别
+const (
+	SecurityCategorySecret    = "secret"
+	SecurityCategoryExec      = "exec"
+	SecurityCategoryTraversal = "path_traversal"
+)
+
+// securityMaxFileSize 超过该大小的文件（多为生成物/数据）不扫描
+const securityMaxFileSize = 1 << 20
+
+// SecurityFinding 单条命中
+type SecurityFinding struct {
+	Rule     string `json:"rule"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Content  string `json:"content"` // 行内容（凭据已打码）
+	Message  string `json:"message"`
+}
+
+// SecurityReport 扫描结果
+type SecurityReport struct {
+	Findings     []SecurityFinding `json:"findings"`
+	FilesScanned int               `json:"files_scanned"`
+	Suppressed   int               `json:"suppressed"` // nosec 屏蔽的命中数
+}
+
+type securityRule struct {
+	id       string
+	category string
+	severity string
+	exts     map[string]bool // 为空表示所有文件
+	re       *regexp.Regexp
+	secret   int // 凭据值所在的捕获组（用于打码与占位符过滤），0 表示非凭据规则
+	message  string
+}
+
+func extSet(exts ...string) map[string]bool {
+	m := make(map[string]bool, len(exts))
+	for _, e := range exts {
+		m[e] = true
+	}
+	return m
+}
+
+var (
+	goExts     = extSet(".go")
+	pyExts     = extSet(".py")
+	jsExts     = extSet(".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx")
+	phpExts    = extSet(".php")
+	userInputs = `(r\.(URL|Form|PostForm|Header|Body)|req\.|request\.|FormValue|PostFormValue|\.Query\(|\.Param\(|c\.Query|c\.PostForm|ctx\.Query|sys\.argv|input\(|\$_(GET|POST|REQUEST|COOKIE))`
+)
+
+var securityRules = []securityRule{
+	// --- 硬编码凭据 ---
+	{id: "aws-access-key", category: SecurityCategorySecret, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`), secret: 1, message: "AWS Access Key ID"},
+	{id: "private-key", category: SecurityCategorySecret, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY( BLOCK)?-----`), message: "私钥内容"},
+	{id: "github-token", category: SecurityCategorySecret, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,})\b`), secret: 1, message: "GitHub Token"},
+	{id: "slack-token", category: SecurityCategorySecret, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`\b(xox[baprs]-[A-Za-z0-9-]{10,})`), secret: 1, message: "Slack Token"},
+	{id: "openai-key", category: SecurityCategorySecret, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`\b(sk-(?:proj-|ant-)?[A-Za-z0-9_-]{32,})`), secret: 1, message: "LLM API Key"},
+	{id: "credential-in-url", category: SecurityCategorySecret, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^\s:/@"']+:([^\s@/"']{4,})@`), secret: 1, message: "URL 中内嵌账号密码"},
+	{id: "hardcoded-credential", category: SecurityCategorySecret, severity: SecuritySeverityMedium,
+		re:     regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?key|auth[_-]?token|private[_-]?key)["']?\s*(?::=|=|:)\s*["']([^"'\s]{8,})["']`),
+		secret: 1, message: "疑似硬编码凭据，建议改为环境变量或密钥管理"},
+
+	// --- 命令执行 ---
+	{id: "go-exec-user-input", category: SecurityCategoryExec, severity: SecuritySeverityHigh, exts: goExts,
+		re: regexp.MustCompile(`exec\.Command(Context)?\(.*` + userInputs), message: "用户输入直接进入 exec.Command"},
+	{id: "go-exec-shell", category: SecurityCategoryExec, severity: SecuritySeverityMedium, exts: goExts,
+		re: regexp.MustCompile(`exec\.Command(Context)?\((ctx,\s*)?"(sh|bash|cmd|cmd\.exe|powershell)",\s*"(-c|/c|/C|-Command)"`), message: "经 shell 执行命令，参数若含外部输入可被注入"},
+	{id: "py-shell-true", category: SecurityCategoryExec, severity: SecuritySeverityHigh, exts: pyExts,
+		re: regexp.MustCompile(`subprocess\.\w+\(.*shell\s*=\s*True`), message: "subprocess 使用 shell=True"},
+	{id: "py-os-system", category: SecurityCategoryExec, severity: SecuritySeverityMedium, exts: pyExts,
+		re: regexp.MustCompile(`\bos\.(system|popen)\(`), message: "os.system/os.popen 经 shell 执行命令"},
+	{id: "js-child-process-exec", category: SecurityCategoryExec, severity: SecuritySeverityMedium, exts: jsExts,
+		re: regexp.MustCompile("\\b(exec|execSync)\\(\\s*(`[^`]*\\$\\{|[^)]*\\+)"), message: "child_process.exec 拼接字符串执行命令"},
+	{id: "eval-user-input", category: SecurityCategoryExec, severity: SecuritySeverityHigh,
+		re: regexp.MustCompile(`\beval\(.*` + userInputs), message: "eval 执行用户输入"},
+	{id: "php-exec-user-input", category: SecurityCategoryExec, severity: SecuritySeverityHigh, exts: phpExts,
+		re: regexp.MustCompile(`\b(system|exec|shell_exec|passthru|popen)\(.*\$_(GET|POST|REQUEST|COOKIE)`), message: "用户输入直接进入命令执行"},
+
+	// --- 路径穿越 ---
+	{id: "go-path-user-input", category: SecurityCategoryTraversal, severity: SecuritySeverityHigh, exts: goExts,
+		re: regexp.MustCompile(`(filepath\.Join|path\.Join|os\.(Open|OpenFile|ReadFile|WriteFile|Create|Remove|RemoveAll)|http\.ServeFile)\(.*` + userInputs), message: "用户输入拼接文件路径，需 Clean 后校验仍在根目录内"},
+	{id: "py-open-user-input", category: SecurityCategoryTraversal, severity: SecuritySeverityHigh, exts: pyExts,
+		re: regexp.MustCompile(`\b(open|send_file|os\.path\.join)\(.*` + userInputs), message: "用户输入拼接文件路径"},
+	{id: "js-fs-user-input", category: SecurityCategoryTraversal, severity: SecuritySeverityHigh, exts: jsExts,
+		re: regexp.MustCompile(`\b(readFile|readFileSync|createReadStream|writeFile|writeFileSync|sendFile|path\.join|path\.resolve)\(.*req\.(params|query|body)`), message: "用户输入拼接文件路径"},
+	{id: "php-include-user-input", category: SecurityCategoryTraversal, severity: SecuritySeverityHigh, exts: phpExts,
+		re: regexp.MustCompile(`\b(include|require|include_once|require_once|file_get_contents|fopen)\b.*\$_(GET|POST|REQUEST|COOKIE)`), message: "用户输入决定包含/读取的文件"},
+}
+
+// securityPlaceholders 凭据值中出现这些片段视为示例/占位符
+var securityPlaceholders = []string{"example", "changeme", "change_me", "placeholder", "your_", "your-", "xxxx", "****", "<", "${", "{{", "%s", "process.env", "os.getenv", "dummy", "sample", "redacted"}
+
+var securitySuppressRe = regexp.MustCompile(`(?i)\bnosec\b|mpm:ignore-security`)
+
+var securitySeverityRank = map[string]int{SecuritySeverityHigh: 0, SecuritySeverityMedium: 1, SecuritySeverityLow: 2}
+
+// SecuritySeverityAtLeast severity 是否不低于 min
+func SecuritySeverityAtLeast(severity, min string) bool {
+	return securitySeverityRank[severity] <= securitySeverityRank[min]
+}
+
+func downgradeSeverity(s string) string {
+	switch s {
+	case SecuritySeverityHigh:
+		return SecuritySeverityMedium
+	default:
+		return SecuritySeverityLow
+	}
+}
+
+func isPlaceholderSecret(v string) bool {
+	lower := strings.ToLower(v)
+	for _, p := range securityPlaceholders {
+		if strings.Contains(lower, p) {
+			return true
+		}
+	}
+	// 全是同一字符（aaaaaaaa、00000000）
+	return strings.Count(lower, lower[:1]) == len(lower)
+}
+
+func maskSecret(line, secret string) string {
+	keep := 4
+	if len(secret) <= keep {
+		keep = 0
+	}
+	return strings.Replace(line, secret, secret[:keep]+"****", 1)
+}
+
+// ScanSecurity 扫描索引中的文件；scope 限定目录，tagger 非空时排除 vendored/generated 文件
+func (ai *ASTIndexer) ScanSecurity(projectRoot, scope string, tagger *PathTagger) (*SecurityReport, error) {
+	files, err := indexedFiles(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	scope = strings.Trim(filepath.ToSlash(scope), "/")
+	report := &SecurityReport{}
+	for _, rel := range files {
+		if scope != "" && rel != scope && !strings.HasPrefix(rel, scope+"/") {
+			continue
+		}
+		if tagger != nil && tagger.Excluded(rel) {
+			continue
+		}
+		abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+		info, err := os.Stat(abs)
+		if err != nil || info.Size() > securityMaxFileSize {
+			continue
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+		report.FilesScanned++
+		findings, suppressed := scanSecurityContent(rel, string(data))
+		report.Findings = append(report.Findings, findings...)
+		report.Suppressed += suppressed
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Severity != b.Severity {
+			return securitySeverityRank[a.Severity] < securitySeverityRank[b.Severity]
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// scanSecurityContent 对单个文件逐行匹配规则；同一行只报告最严重的一条
+func scanSecurityContent(file, content string) ([]SecurityFinding, int) {
+	ext := strings.ToLower(path.Ext(file))
+	test := isTestPath(file)
+	var findings []SecurityFinding
+	suppressed := 0
+	for i, line := range strings.Split(content, "\n") {
+		if len(line) > 2000 {
+			continue // 压缩后的单行文件
+		}
+		var best *SecurityFinding
+		for _, r := range securityRules {
+			if len(r.exts) > 0 && !r.exts[ext] {
+				continue
+			}
+			m := r.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			shown := strings.TrimSpace(line)
+			if r.secret > 0 {
+				if isPlaceholderSecret(m[r.secret]) {
+					continue
+				}
+				shown = maskSecret(shown, m[r.secret])
+			}
+			f := SecurityFinding{Rule: r.id, Category: r.category, Severity: r.severity, File: file, Line: i + 1, Content: truncateLine(shown, 160), Message: r.message}
+			if test {
+				f.Severity = downgradeSeverity(f.Severity)
+			}
+			if best == nil || securitySeverityRank[f.Severity] < securitySeverityRank[best.Severity] {
+				best = &f
+			}
+		}
+		if best == nil {
+			continue
+		}
+		if securitySuppressRe.MatchString(line) {
+			suppressed++
+			continue
+		}
+		findings = append(findings, *best)
+	}
+	return findings, suppressed
+}
+
+func truncateLine(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "..."
+}
+
+// indexedFiles 索引库中的文件（相对路径、正斜杠）
+func indexedFiles(projectRoot string) ([]string, error) {
+	var files []string
+	err := eachIndexDB(projectRoot, "", func(db *sql.DB) error {
+		rows, err := db.Query(`SELECT file_path FROM files`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var p string
+			if rows.Scan(&p) == nil && p != "" {
+				files = append(files, strings.TrimPrefix(filepath.ToSlash(p), "./"))
+			}
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
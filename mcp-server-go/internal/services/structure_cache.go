@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ============================================================================
+// 结构地图磁盘缓存：warm_up 写入，project_map(level=structure) 复用
+// ============================================================================
+//
+// 按 scope 保存 structure 模式的原始结果，并记录写入时的索引库指纹；
+// 索引重建或增量写入后指纹变化，缓存自动失效，重新扫描并写回。
+
+const structureCacheFile = "structure_cache.json"
+
+type structureCacheEntry struct {
+	Fingerprint string           `json:"fingerprint"`
+	CreatedAt   string           `json:"created_at"`
+	Result      *StructureResult `json:"result"`
+}
+
+func structureCachePath(projectRoot string) string {
+	return core.DataPath(projectRoot, structureCacheFile)
+}
+
+func normalizeStructureScope(scope string) string {
+	if scope == "." || scope == "./" {
+		return ""
+	}
+	return scope
+}
+
+func loadStructureCache(projectRoot string) map[string]structureCacheEntry {
+	entries := map[string]structureCacheEntry{}
+	if raw, err := os.ReadFile(structureCachePath(projectRoot)); err == nil {
+		_ = json.Unmarshal(raw, &entries)
+	}
+	return entries
+}
+
+// readStructureCache 返回与当前索引指纹一致的缓存结果
+func readStructureCache(projectRoot, scope, fingerprint string) (*StructureResult, bool) {
+	entry, ok := loadStructureCache(projectRoot)[normalizeStructureScope(scope)]
+	if !ok || entry.Result == nil || entry.Fingerprint != fingerprint {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// writeStructureCache 写入（覆盖）scope 的缓存条目
+func writeStructureCache(projectRoot, scope, fingerprint string, result *StructureResult) error {
+	entries := loadStructureCache(projectRoot)
+	entries[normalizeStructureScope(scope)] = structureCacheEntry{
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		Result:      result,
+	}
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	path := structureCachePath(projectRoot)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CachedStructure 返回结构地图：refresh=false 且缓存与当前索引一致时直接读取缓存（cached=true），
+// 否则重新扫描并写回缓存
+func (ai *ASTIndexer) CachedStructure(projectRoot, scope string, refresh bool) (result *StructureResult, cached bool, err error) {
+	fingerprint := indexFingerprint(getDBPath(projectRoot))
+	if !refresh {
+		if result, ok := readStructureCache(projectRoot, scope, fingerprint); ok {
+			return result, true, nil
+		}
+	}
+	result, err = ai.StructureProjectWithScope(projectRoot, scope)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.MkdirAll(core.DataDir(projectRoot), 0755); err == nil {
+		_ = writeStructureCache(projectRoot, scope, fingerprint, result)
+	}
+	return result, false, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestStructureCacheFollowsIndexFingerprint(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(core.DataDir(root), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := getDBPath(root)
+	if err := os.WriteFile(dbPath, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fp := indexFingerprint(dbPath)
+	want := &StructureResult{Status: "success", TotalFiles: 3, Structure: map[string]StructureDirInfo{}}
+	if err := writeStructureCache(root, ".", fp, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := readStructureCache(root, "", fp)
+	if !ok || got.TotalFiles != 3 {
+		t.Fatalf("cache miss for the same scope and index: %+v", got)
+	}
+	if _, ok := readStructureCache(root, "internal", fp); ok {
+		t.Error("other scopes should not hit")
+	}
+
+	// 索引库写入后指纹变化，缓存失效
+	if err := os.WriteFile(dbPath, []byte("v2-longer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readStructureCache(root, "", indexFingerprint(dbPath)); ok {
+		t.Error("cache should be invalidated after the index changes")
+	}
+}
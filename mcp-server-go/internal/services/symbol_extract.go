@@ -0,0 +1,184 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== 任务描述 → 符号候选 ==========
+//
+// manager_analyze 依赖调用方预先提取 symbols；调用方漏填时锚点为空。
+// 这里把任务描述切成标识符候选，与索引库 symbols 表做精确 / 忽略大小写 / 分词模糊匹配，
+// 每个命中带置信度：形如代码标识符的词（驼峰、下划线、带数字）精确命中最可信，
+// 普通英文单词只当作弱信号，多个单词拼出符号名（"login session" → LoginSession）折算为模糊命中。
+
+// SymbolCandidate 从任务描述中匹配到的符号
+type SymbolCandidate struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	FilePath   string  `json:"file_path"`
+	Line       int     `json:"line"`
+	Confidence float64 `json:"confidence"`
+	Matched    string  `json:"matched"` // 任务描述中命中的原词
+}
+
+// 置信度
+const (
+	extractExactIdent  = 1.0  // 标识符形态的词精确命中
+	extractFoldIdent   = 0.85 // 标识符形态的词忽略大小写命中
+	extractExactWord   = 0.7  // 普通单词精确命中（main、login）
+	extractFoldWord    = 0.5  // 普通单词忽略大小写命中（user → User）
+	extractFuzzyFactor = 0.7  // 分词模糊命中按覆盖率折算的上限
+	extractMinCoverage = 0.8  // 模糊命中要求的符号分词覆盖率
+	extractMinConfid   = 0.5
+)
+
+var identWordRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// looksLikeIdentifier 是否像代码标识符：含下划线、数字，或大小写混排（getUser、HTTPClient；User、HTTP 不算）
+func looksLikeIdentifier(w string) bool {
+	if strings.ContainsAny(w, "_0123456789") {
+		return true
+	}
+	innerUpper, hasLower := false, false
+	for i, r := range w {
+		innerUpper = innerUpper || (i > 0 && unicode.IsUpper(r))
+		hasLower = hasLower || unicode.IsLower(r)
+	}
+	return innerUpper && hasLower
+}
+
+// ExtractSymbolCandidates 从任务描述中提取符号候选，按置信度降序，同名符号只保留最可信的一个定义
+func ExtractSymbolCandidates(projectRoot, desc, scope string, limit int) []SymbolCandidate {
+	words := identWordRe.FindAllString(desc, -1)
+	descTokens := core.QueryTokens(desc)
+	if len(words) == 0 {
+		return nil
+	}
+	syms, err := loadIndexedSymbols(projectRoot, scope)
+	if err != nil || len(syms) == 0 {
+		return nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	exact := make(map[string]string)  // 原词
+	folded := make(map[string]string) // 小写 → 原词
+	for _, w := range words {
+		if len(w) < 3 || (len(w) < 4 && !looksLikeIdentifier(w)) {
+			continue
+		}
+		exact[w] = w
+		if _, ok := folded[strings.ToLower(w)]; !ok {
+			folded[strings.ToLower(w)] = w
+		}
+	}
+
+	best := make(map[string]SymbolCandidate)
+	for _, s := range syms {
+		c := s
+		switch w, ok := exact[s.Name]; {
+		case ok && looksLikeIdentifier(w):
+			c.Confidence, c.Matched = extractExactIdent, w
+		case ok:
+			c.Confidence, c.Matched = extractExactWord, w
+		default:
+			if w, ok := folded[strings.ToLower(s.Name)]; ok {
+				c.Confidence, c.Matched = extractFoldWord, w
+				if looksLikeIdentifier(w) {
+					c.Confidence = extractFoldIdent
+				}
+			} else if score, matched := fuzzySymbolMatch(s.Name, descTokens); score > 0 {
+				c.Confidence, c.Matched = score, matched
+			}
+		}
+		if c.Confidence < extractMinConfid {
+			continue
+		}
+		if prev, ok := best[s.Name]; !ok || c.Confidence > prev.Confidence ||
+			(c.Confidence == prev.Confidence && isCallableType(c.Type) && !isCallableType(prev.Type)) {
+			best[s.Name] = c
+		}
+	}
+
+	out := make([]SymbolCandidate, 0, len(best))
+	for _, c := range best {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Confidence != out[j].Confidence {
+			return out[i].Confidence > out[j].Confidence
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// fuzzySymbolMatch 多词符号名的分词在任务描述中的覆盖率；单词符号不做模糊匹配（噪音太大）
+func fuzzySymbolMatch(name string, descTokens []string) (float64, string) {
+	nameWords := core.QueryTokens(name)
+	if len(nameWords) < 2 || len(descTokens) == 0 {
+		return 0, ""
+	}
+	var sum float64
+	var matched []string
+	for _, nw := range nameWords {
+		score, hit := 0.0, ""
+		for _, t := range descTokens {
+			if s := bestTokenMatch(t, []string{nw}); s > score {
+				score, hit = s, t
+			}
+		}
+		if score > 0 {
+			sum += score
+			matched = append(matched, hit)
+		}
+	}
+	coverage := sum / float64(len(nameWords))
+	if coverage < extractMinCoverage {
+		return 0, ""
+	}
+	return math.Round(coverage*extractFuzzyFactor*100) / 100, strings.Join(matched, " ")
+}
+
+// loadIndexedSymbols 读取索引库中的符号（路径正斜杠、相对项目根），scope 非空时只取该目录下的
+func loadIndexedSymbols(projectRoot, scope string) ([]SymbolCandidate, error) {
+	if !hasAnyIndex(projectRoot) {
+		return nil, nil
+	}
+	scope = strings.Trim(filepath.ToSlash(scope), "/")
+	var out []SymbolCandidate
+	err := eachIndexDB(projectRoot, scope, func(db *sql.DB) error {
+		rows, err := db.Query(`
+			SELECT s.name, s.symbol_type, COALESCE(f.file_path, ''), COALESCE(s.line_start, 0)
+			FROM symbols s LEFT JOIN files f ON s.file_id = f.file_id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var c SymbolCandidate
+			if rows.Scan(&c.Name, &c.Type, &c.FilePath, &c.Line) != nil || len(c.Name) < 3 {
+				continue
+			}
+			c.FilePath = strings.TrimPrefix(filepath.ToSlash(c.FilePath), "./")
+			if scope != "" && !strings.HasPrefix(c.FilePath, scope+"/") {
+				continue
+			}
+			out = append(out, c)
+		}
+		return nil
+	})
+	return out, err
+}
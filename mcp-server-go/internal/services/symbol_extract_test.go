@@ -0,0 +1,47 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractSymbolCandidates(t *testing.T) {
+	root := t.TempDir()
+	dbPath := getDBPath(root)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	execDB(t, dbPath,
+		"CREATE TABLE files (file_id INTEGER PRIMARY KEY, file_path TEXT)",
+		"CREATE TABLE symbols (symbol_id INTEGER PRIMARY KEY, file_id INTEGER, name TEXT, symbol_type TEXT, line_start INTEGER)",
+		"INSERT INTO files (file_id, file_path) VALUES (1, 'internal/auth/session.go'), (2, 'internal/billing/invoice.go')",
+		`INSERT INTO symbols (file_id, name, symbol_type, line_start) VALUES
+			(1, 'refreshToken', 'function', 10), (1, 'LoginSession', 'struct', 20), (1, 'User', 'struct', 30),
+			(2, 'Invoice', 'struct', 5), (2, 'fix', 'function', 40)`,
+	)
+
+	got := ExtractSymbolCandidates(root, "fix refreshToken 过期问题, the login session and user", "", 10)
+	conf := make(map[string]float64)
+	for _, c := range got {
+		conf[c.Name] = c.Confidence
+	}
+	if len(got) == 0 || got[0].Name != "refreshToken" || got[0].Confidence != extractExactIdent || got[0].Line != 10 {
+		t.Fatalf("identifier should be the top exact match, got %+v", got)
+	}
+	if conf["LoginSession"] != extractFuzzyFactor {
+		t.Errorf("LoginSession fuzzy confidence = %v, want %v", conf["LoginSession"], extractFuzzyFactor)
+	}
+	if conf["User"] != extractFoldWord {
+		t.Errorf("plain word user → User confidence = %v, want %v", conf["User"], extractFoldWord)
+	}
+	if _, ok := conf["fix"]; ok {
+		t.Errorf("short plain words should be ignored: %+v", got)
+	}
+	if _, ok := conf["Invoice"]; ok {
+		t.Errorf("unmentioned symbol matched: %+v", got)
+	}
+	if got := ExtractSymbolCandidates(root, "refreshToken", "internal/billing", 10); len(got) != 0 {
+		t.Errorf("scope filter ignored: %+v", got)
+	}
+}
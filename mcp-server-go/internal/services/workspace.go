@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ============================================================================
+// 工作区子项目探测：monorepo 中嵌套的 go.mod / package.json / Cargo.toml 边界
+// ============================================================================
+//
+// 探测结果保存在数据目录 workspace.json。选中子项目后索引只覆盖选中目录，
+// project_map / code_search 可按子项目名限定范围。
+
+const (
+	workspaceFile     = "workspace.json"
+	workspaceMaxDepth = 4
+)
+
+// subProjectMarkers 子项目边界文件
+var subProjectMarkers = []string{"go.mod", "package.json", "Cargo.toml"}
+
+// SubProject 工作区中的一个子项目
+type SubProject struct {
+	Name     string   `json:"name"`
+	Dir      string   `json:"dir"` // 相对项目根，/ 分隔
+	Markers  []string `json:"markers"`
+	Selected bool     `json:"selected,omitempty"`
+}
+
+// Workspace 子项目列表；没有选中项时索引整个项目
+type Workspace struct {
+	DiscoveredAt string       `json:"discovered_at"`
+	SubProjects  []SubProject `json:"sub_projects"`
+}
+
+// DiscoverSubProjects 查找项目根以下（不含根本身）的子项目边界，按目录排序
+func DiscoverSubProjects(projectRoot string) []SubProject {
+	var out []SubProject
+	var walk func(rel string, depth int)
+	walk = func(rel string, depth int) {
+		abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+		if rel != "" {
+			var markers []string
+			for _, m := range subProjectMarkers {
+				if fileExists(filepath.Join(abs, m)) {
+					markers = append(markers, m)
+				}
+			}
+			if len(markers) > 0 {
+				out = append(out, SubProject{Name: subProjectName(abs, rel, markers), Dir: rel, Markers: markers})
+			}
+		}
+		if depth == workspaceMaxDepth {
+			return
+		}
+		entries, err := os.ReadDir(abs)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if !e.IsDir() || manifestSkipDirs[e.Name()] || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			walk(strings.TrimPrefix(rel+"/"+e.Name(), "/"), depth+1)
+		}
+	}
+	walk("", 0)
+
+	// 重名（如多个 api 模块）时改用目录作为名称
+	count := make(map[string]int)
+	for _, sp := range out {
+		count[sp.Name]++
+	}
+	for i := range out {
+		if count[out[i].Name] > 1 {
+			out[i].Name = out[i].Dir
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Dir < out[j].Dir })
+	return out
+}
+
+var (
+	goModuleRe   = regexp.MustCompile(`^module\s+"?([^\s"]+)"?`)
+	cargoNameRe  = regexp.MustCompile(`^name\s*=\s*"([^"]+)"`)
+	cargoTableRe = regexp.MustCompile(`^\[([^\]]+)\]`)
+)
+
+// subProjectName 优先取清单中声明的名称，取不到时用目录名
+func subProjectName(abs, rel string, markers []string) string {
+	for _, m := range markers {
+		var name string
+		switch m {
+		case "package.json":
+			var pkg struct {
+				Name string `json:"name"`
+			}
+			if data, err := os.ReadFile(filepath.Join(abs, m)); err == nil && json.Unmarshal(data, &pkg) == nil {
+				name = pkg.Name
+			}
+		case "go.mod":
+			if mod := scanManifestLine(filepath.Join(abs, m), "", goModuleRe); mod != "" {
+				name = path.Base(mod)
+			}
+		case "Cargo.toml":
+			name = scanManifestLine(filepath.Join(abs, m), "package", cargoNameRe)
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return path.Base(rel)
+}
+
+// scanManifestLine 逐行查找第一个匹配 re 的值；table 非空时只在该 TOML 表内查找
+func scanManifestLine(file, table string, re *regexp.Regexp) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	current := ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := cargoTableRe.FindStringSubmatch(line); table != "" && m != nil {
+			current = strings.TrimSpace(m[1])
+			continue
+		}
+		if current != table {
+			continue
+		}
+		if m := re.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func workspacePath(projectRoot string) string {
+	return core.DataPath(projectRoot, workspaceFile)
+}
+
+// LoadWorkspace 读取已保存的工作区；未探测过时返回 nil, nil
+func LoadWorkspace(projectRoot string) (*Workspace, error) {
+	data, err := os.ReadFile(workspacePath(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ws Workspace
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", workspaceFile, err)
+	}
+	return &ws, nil
+}
+
+// SaveWorkspace 保存工作区
+func SaveWorkspace(projectRoot string, ws *Workspace) error {
+	if _, err := core.EnsureDataDir(projectRoot); err != nil {
+		return err
+	}
+	if ws.DiscoveredAt == "" {
+		ws.DiscoveredAt = time.Now().Format(time.RFC3339)
+	}
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(workspacePath(projectRoot), data, 0644)
+}
+
+// Find 按名称（其次按目录，均忽略大小写）查找子项目
+func (ws *Workspace) Find(name string) *SubProject {
+	if ws == nil {
+		return nil
+	}
+	name = strings.Trim(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"), "/")
+	for i := range ws.SubProjects {
+		if ws.SubProjects[i].Name == name {
+			return &ws.SubProjects[i]
+		}
+	}
+	for i := range ws.SubProjects {
+		if strings.EqualFold(ws.SubProjects[i].Name, name) || strings.EqualFold(ws.SubProjects[i].Dir, name) {
+			return &ws.SubProjects[i]
+		}
+	}
+	return nil
+}
+
+// Select 只选中给定的子项目；names 为空时清除选择（索引整个项目）
+func (ws *Workspace) Select(names []string) error {
+	picked := make(map[string]bool)
+	var unknown []string
+	for _, n := range names {
+		sp := ws.Find(n)
+		if sp == nil {
+			unknown = append(unknown, n)
+			continue
+		}
+		picked[sp.Dir] = true
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("未知的子项目 %v（可用: %s）", unknown, strings.Join(ws.Names(), ", "))
+	}
+	for i := range ws.SubProjects {
+		ws.SubProjects[i].Selected = picked[ws.SubProjects[i].Dir]
+	}
+	return nil
+}
+
+// Names 全部子项目名
+func (ws *Workspace) Names() []string {
+	if ws == nil {
+		return nil
+	}
+	names := make([]string, len(ws.SubProjects))
+	for i, sp := range ws.SubProjects {
+		names[i] = sp.Name
+	}
+	return names
+}
+
+// SelectedDirs 选中子项目的目录；嵌套在另一个选中目录下的会被合并
+func (ws *Workspace) SelectedDirs() []string {
+	if ws == nil {
+		return nil
+	}
+	var dirs []string
+	for _, sp := range ws.SubProjects {
+		if !sp.Selected {
+			continue
+		}
+		nested := false
+		for _, d := range dirs {
+			if strings.HasPrefix(sp.Dir+"/", d+"/") {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			dirs = append(dirs, sp.Dir)
+		}
+	}
+	return dirs
+}
+
+// SubProjectScope 把子项目名与子项目内的相对 scope 合成为项目级 scope
+// scope 已经以子项目目录开头时原样保留
+func SubProjectScope(projectRoot, name, scope string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return scope, nil
+	}
+	ws, err := LoadWorkspace(projectRoot)
+	if err != nil {
+		return "", err
+	}
+	if ws == nil {
+		return "", fmt.Errorf("尚未探测子项目，请先执行 initialize_project(discover=true)")
+	}
+	sp := ws.Find(name)
+	if sp == nil {
+		return "", fmt.Errorf("未知的子项目 %q（可用: %s）", name, strings.Join(ws.Names(), ", "))
+	}
+	scope = strings.Trim(strings.ReplaceAll(strings.TrimSpace(scope), "\\", "/"), "/")
+	scope = strings.TrimPrefix(scope, "./")
+	if scope == "" || scope == "." {
+		return sp.Dir, nil
+	}
+	if scope == sp.Dir || strings.HasPrefix(scope, sp.Dir+"/") {
+		return scope, nil
+	}
+	return sp.Dir + "/" + scope, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverSubProjects(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/mono\n")
+	write("services/api/go.mod", "module example.com/mono/services/api\n\ngo 1.22\n")
+	write("web/package.json", `{"name": "@mono/web"}`)
+	write("web/node_modules/left-pad/package.json", `{"name": "left-pad"}`)
+	write("tools/cli/Cargo.toml", "[workspace]\nname = \"ignored\"\n\n[package]\nname = \"mono-cli\"\n")
+	write("legacy/api/package.json", `{"name": "api"}`)
+
+	found := DiscoverSubProjects(root)
+	var names []string
+	for _, sp := range found {
+		names = append(names, sp.Name+"@"+sp.Dir)
+	}
+	want := []string{"legacy/api@legacy/api", "services/api@services/api", "mono-cli@tools/cli", "@mono/web@web"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+
+	ws := &Workspace{SubProjects: found}
+	if err := ws.Select([]string{"@mono/web", "TOOLS/CLI"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Select([]string{"nope"}); err == nil {
+		t.Errorf("unknown sub-project should fail")
+	}
+	ws.Select([]string{"@mono/web", "tools/cli"})
+	if dirs := ws.SelectedDirs(); !reflect.DeepEqual(dirs, []string{"tools/cli", "web"}) {
+		t.Errorf("selected dirs: %v", dirs)
+	}
+	if err := SaveWorkspace(root, ws); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range [][3]string{
+		{"mono-cli", "", "tools/cli"},
+		{"mono-cli", "src", "tools/cli/src"},
+		{"mono-cli", "tools/cli/src/", "tools/cli/src"},
+		{"", "pkg", "pkg"},
+	} {
+		got, err := SubProjectScope(root, c[0], c[1])
+		if err != nil || got != c[2] {
+			t.Errorf("SubProjectScope(%q, %q) = %q, %v; want %q", c[0], c[1], got, err, c[2])
+		}
+	}
+	if _, err := SubProjectScope(root, "missing", ""); err == nil {
+		t.Errorf("unknown sub-project name should fail")
+	}
+}
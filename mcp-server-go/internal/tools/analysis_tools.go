@@ -15,25 +15,37 @@ import (
 
 // ImpactArgs 影响分析参数
 type ImpactArgs struct {
-	SymbolName string `json:"symbol_name" jsonschema:"required,description=要分析的符号名 (函数名或类名)"`
-	Direction  string `json:"direction" jsonschema:"default=backward,enum=backward,enum=forward,enum=both,description=分析方向"`
+	SymbolName      string `json:"symbol_name" jsonschema:"required,description=要分析的符号名 (函数名或类名)"`
+	Direction       string `json:"direction" jsonschema:"default=backward,enum=backward,enum=forward,enum=both,description=分析方向"`
+	IncludeVendored bool   `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件中的调用者"`
+	TaskID          string `json:"task_id" jsonschema:"description=所属任务链ID，结果计入该链的风险预算"`
+	MaxDepth        int    `json:"max_depth" jsonschema:"description=传播深度上限 (1=只看直接调用者，0=不限)"`
+	ShowPaths       bool   `json:"show_paths" jsonschema:"description=输出完整调用链 (如 handler → service → repo → target)"`
 }
 
 // ProjectMapArgs 项目地图参数
 type ProjectMapArgs struct {
-	Scope     string `json:"scope" jsonschema:"description=限定范围 (目录或文件路径，留空=整个项目)"`
-	Level     string `json:"level" jsonschema:"default=symbols,enum=structure,enum=symbols,description=视图层级"`
-	CorePaths string `json:"core_paths" jsonschema:"description=核心目录列表 (JSON 数组字符串)"`
+	Scope           string `json:"scope" jsonschema:"description=限定范围 (目录或文件路径，留空=整个项目)"`
+	SubProject      string `json:"sub_project" jsonschema:"description=限定到工作区子项目 (initialize_project discover=true 探测出的名称)；同时给 scope 时 scope 相对子项目目录"`
+	Level           string `json:"level" jsonschema:"default=symbols,enum=structure,enum=symbols,enum=issues,enum=knowledge,enum=diff,description=视图层级"`
+	CorePaths       string `json:"core_paths" jsonschema:"description=核心目录列表 (JSON 数组字符串)"`
+	IncludeVendored bool   `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件 (默认排除)"`
+	SkipComplexity  bool   `json:"skip_complexity" jsonschema:"description=跳过复杂度热力图计算 (超大仓库提速)"`
+	From            string `json:"from" jsonschema:"description=diff 视图的起点快照 ID (留空=上一个快照)"`
+	To              string `json:"to" jsonschema:"description=diff 视图的终点快照 ID (留空=当前代码)"`
 }
 
 // FlowTraceArgs 业务流程追踪参数
 type FlowTraceArgs struct {
-	SymbolName string `json:"symbol_name" jsonschema:"description=入口符号名（函数/类，与 file_path 二选一；若同时提供则优先 symbol_name）"`
-	FilePath   string `json:"file_path" jsonschema:"description=目标文件路径（与 symbol_name 二选一）"`
-	Scope      string `json:"scope" jsonschema:"description=限定范围（目录，超大仓库建议必填）"`
-	Direction  string `json:"direction" jsonschema:"default=both,enum=backward,enum=forward,enum=both,description=追踪方向"`
-	Mode       string `json:"mode" jsonschema:"default=brief,enum=brief,enum=standard,enum=deep,description=输出层级（brief/standard/deep）"`
-	MaxNodes   int    `json:"max_nodes" jsonschema:"default=40,description=输出节点上限"`
+	SymbolName  string   `json:"symbol_name" jsonschema:"description=入口符号名（函数/类，与 file_path 二选一；若同时提供则优先 symbol_name）"`
+	SymbolNames []string `json:"symbol_names" jsonschema:"description=多个入口符号名（最多 5 个），对比共同下游依赖与分叉点"`
+	FilePath    string   `json:"file_path" jsonschema:"description=目标文件路径（与 symbol_name 二选一）"`
+	Scope       string   `json:"scope" jsonschema:"description=限定范围（目录，超大仓库建议必填）"`
+	Direction   string   `json:"direction" jsonschema:"default=both,enum=backward,enum=forward,enum=both,description=追踪方向"`
+	Mode        string   `json:"mode" jsonschema:"default=brief,enum=brief,enum=standard,enum=deep,description=输出层级（brief/standard/deep）"`
+	MaxNodes    int      `json:"max_nodes" jsonschema:"default=40,description=输出节点上限"`
+	Render      string   `json:"render" jsonschema:"default=text,enum=text,enum=mermaid_sequence,description=输出形式：text 文本摘要 / mermaid_sequence 时序图（保存为 .mmd）"`
+	MaxChains   int      `json:"max_chains" jsonschema:"default=5,description=mermaid_sequence 每个方向最多绘制的调用链数"`
 }
 
 // RegisterAnalysisTools 注册分析类工具
@@ -54,8 +66,25 @@ func RegisterAnalysisTools(s *server.MCPServer, sm *SessionManager, ai *services
     - forward: 我调用了谁（影响下游）
     - both: 双向分析
 
+  include_vendored (默认: false)
+    默认排除 vendor/node_modules/third_party 等第三方目录与 *.pb.go 等生成文件，
+    设为 true 时一并展示（规则可在 .mcp-config/settings.json 的 index 段扩展）。
+
+  task_id (可选)
+    所属任务链 ID。链声明了风险预算时，本次影响节点数与高风险符号会计入预算。
+
+  max_depth (可选，默认不限)
+    传播深度上限。设置后间接调用者只保留该深度以内的，并按层列出数量，
+    用于判断签名变更会传播多远。
+
+  show_paths (默认: false)
+    输出最长的若干条调用链，如 handler → service → repo → target。
+
 返回：
   - 风险等级（low/medium/high）
+  - 目标处在调用环中时的警告（环内函数与涉及文件）
+  - 目标测试覆盖率为 0% 时的警告（读取 go coverprofile / lcov / coverage.py 报告）
+  - Git 信息：最后修改人/时间（git blame）与近 90 天文件提交次数
   - 直接调用者列表（前10个）
   - 间接调用者数量
   - 修改检查清单
@@ -64,6 +93,9 @@ func RegisterAnalysisTools(s *server.MCPServer, sm *SessionManager, ai *services
   code_impact(symbol_name="Login", direction="backward")
     -> 分析谁在调用 Login 函数
 
+  code_impact(symbol_name="SaveUser", max_depth=3, show_paths=true)
+    -> 三层以内的调用者及其调用链
+
 触发词：
   "mpm 影响", "mpm 依赖", "mpm impact"`),
 		mcp.WithInputSchema[ImpactArgs](),
@@ -79,12 +111,34 @@ func RegisterAnalysisTools(s *server.MCPServer, sm *SessionManager, ai *services
   level (默认: symbols)
     - 刚接手/想看架构？ -> "structure" (只看目录树，不看代码)
     - 找代码/准备修改？ -> "symbols" (列出更详细的函数/类)
+    - 规划清理？ -> "issues" (未解析调用 + 无调用方的死代码候选 + 跨函数调用环)
+    - 决定在哪补文档？ -> "knowledge" (各目录被 memos/facts/钩子引用的密度 vs 复杂度，标出高复杂度零记忆的盲区)
+    - 回顾一段时间改了什么？ -> "diff" (对比两次 symbols 快照：新增/删除的文件与符号、复杂度回退、目录增长)
+    - 纯文档/设计仓库（无代码栈）时，symbols 视图自动切换为 Markdown 标题大纲
   
   scope (可选)
     如果不填，默认看整个项目（可能会很长）。建议填入你感兴趣的目录。
 
+  sub_project (可选)
+    monorepo 子项目名（initialize_project discover=true 探测得到），地图只覆盖该子项目；
+    同时给 scope 时 scope 相对子项目目录。
+
+  include_vendored (默认: false)
+    默认排除 vendor/node_modules/third_party 等第三方目录与 *.pb.go 等生成文件，
+    设为 true 时一并展示（规则可在 .mcp-config/settings.json 的 index 段扩展）。
+
+  skip_complexity (默认: false)
+    symbols 视图默认为每个函数/类计算复杂度热力图；超大仓库可设为 true 跳过以加快出图。
+
+  from / to (仅 diff)
+    每次生成 symbols 视图都会在数据目录 map_snapshots/ 保存快照（保留最近 30 个）。
+    to 留空表示当前代码（同时保存新快照），from 留空表示 to 之前的最近一个快照。
+
 返回：
-  一张 ASCII 格式的项目地图 + 复杂度热力图。
+  一张 ASCII 格式的项目地图 + 复杂度热力图（页脚注明热力图计算耗时）。
+  项目中存在覆盖率文件（coverage.out / lcov.info / coverage.json / coverage.xml，
+  或 settings.json 的 index.coverage_files 指定）时，symbols 视图为目录、文件、符号标注 [cov N%]。
+  git 仓库中 symbols 视图额外列出变更热点：近 90 天频繁修改且扇入高的符号。
 
 触发词：
   "mpm 地图", "mpm 结构", "mpm map"`),
@@ -99,11 +153,17 @@ func RegisterAnalysisTools(s *server.MCPServer, sm *SessionManager, ai *services
 
 输入：
   - symbol_name / file_path（二选一）
+  - symbol_names: 多个入口（最多 5 个），逐个追踪后对比共同下游依赖、汇合点与分叉点，用于合并重复业务逻辑
   - 若两者都提供，优先使用 symbol_name
   - scope（可选，建议在大项目中填写）
   - direction: backward/forward/both（默认 both）
   - mode: brief/standard/deep（默认 brief，渐进披露）
   - max_nodes: 输出节点上限（默认 40）
+  - render: text（默认）/ mermaid_sequence：按入口绘制 Top-N 上下游调用链的 Mermaid 时序图，保存到 .mcp-data/flow_diagrams/
+  - max_chains: mermaid_sequence 每个方向最多绘制的链数（默认 5）
+  - 副作用/阶段识别规则可在 .mcp-config/flow_rules.json 中扩展：packs 启用内置规则包
+    (gin/echo/nethttp/sqlx/gorm/redis/kafka/grpc)，side_effects/stages 追加项目规则，thresholds 调整类别阈值
+  - 文档模式：file_path 为 .md，或纯文档仓库中 symbol_name 为标题文本时，沿文档间链接追踪引用方/依赖文档
 
 输出：
   - 入口点
@@ -114,12 +174,277 @@ func RegisterAnalysisTools(s *server.MCPServer, sm *SessionManager, ai *services
 示例：
   flow_trace(symbol_name="run_indexer", scope="mcp-server-go/internal/services", direction="both")
   flow_trace(file_path="mcp-server-go/internal/tools/analysis_tools.go", direction="forward", max_nodes=30)
+  flow_trace(symbol_name="wrapFlowTrace", render="mermaid_sequence", max_chains=3)
+  flow_trace(symbol_names=["handleCreateOrder", "handleImportOrder"], direction="forward")
 
 触发词：
   - mpm 流程
   - mpm flow`),
 		mcp.WithInputSchema[FlowTraceArgs](),
 	), wrapFlowTrace(sm, ai))
+
+	s.AddTool(mcp.NewTool("index_report",
+		mcp.WithDescription(`index_report - 索引审查报告导出 (SARIF/JSON)
+
+用途：
+  为安全/架构评审导出机器可读报告：高风险符号、跨符号调用环、未解析调用。
+
+参数：
+  format (默认: sarif)
+    sarif: SARIF 2.1.0（可直接导入 GitHub Code Scanning 等工具）
+    json: 原始 JSON 结构
+  
+  output (可选)
+    输出文件路径（相对项目根目录），默认数据目录下 index_report.<sarif|json>
+  
+  threshold (可选)
+    高风险分数阈值，默认读取 .mcp-config/settings.json 中的 complexity.alert_threshold
+  
+  limit (默认: 200)
+    每一类条目的最大数量
+
+返回：
+  文件路径 + 摘要（Top 高风险符号、调用环数量、未解析调用数量）
+
+触发词：
+  "mpm 导出报告", "mpm sarif", "mpm report"`),
+		mcp.WithInputSchema[IndexReportArgs](),
+	), wrapIndexReport(sm, ai))
+
+	s.AddTool(mcp.NewTool("project_stats",
+		mcp.WithDescription(`project_stats - 项目规模统计
+
+用途：
+  快速了解代码库规模：按语言汇总文件数、代码行数、符号数，并列出最大的文件与目录。
+  行数与符号来自索引库，未入索引的文件（文档、配置等）通过快速扫描补齐，二进制文件不计入。
+
+参数：
+  scope (可选)
+    仅统计该子目录（相对项目根目录）
+
+  top (默认: 10)
+    最大文件/目录列表的条数
+
+返回：
+  语言分布表 + 最大文件 + 最大目录 + 符号类型分布
+
+触发词：
+  "mpm 统计", "mpm stats", "mpm 代码量"`),
+		mcp.WithInputSchema[ProjectStatsArgs](),
+	), wrapProjectStats(sm, ai))
+
+	s.AddTool(mcp.NewTool("dependency_graph",
+		mcp.WithDescription(`dependency_graph - 模块/文件级依赖图导出 (Mermaid / DOT)
+
+用途：
+  把索引中的函数调用聚合为目录或文件之间的依赖关系，导出 Mermaid 或 Graphviz DOT，
+  可直接贴进 PR 描述解释重构前后的结构。
+
+参数：
+  scope (可选)
+    只保留至少一端位于该目录/文件下的依赖，留空为整个项目
+
+  level (默认: module)
+    module: 按目录聚合
+    file: 按文件聚合
+
+  format (默认: mermaid)
+    mermaid / dot
+
+  max_edges (默认: 60)
+    按调用数降序保留的边数上限，超出部分省略并提示
+
+  output (可选)
+    同时写入文件（相对项目根目录），如 docs/deps.mmd
+
+  include_vendored (默认: false)
+    包含 vendored/generated 文件
+
+返回：
+  节点/边统计 + 图文本（边上标注聚合的调用数）
+
+示例：
+  dependency_graph(scope="internal/tools", level="file")
+    -> internal/tools 内外的文件级依赖
+
+触发词：
+  "mpm 依赖图", "mpm deps graph", "mpm mermaid"`),
+		mcp.WithInputSchema[DependencyGraphArgs](),
+	), wrapDependencyGraph(sm, ai))
+
+	s.AddTool(mcp.NewTool("dead_code",
+		mcp.WithDescription(`dead_code - 死代码 / 无引用符号检测
+
+用途：
+  基于索引调用图找出项目内没有任何调用方的函数/方法/类，按目录分组并给出置信度，用于规划清理。
+  main/init、测试函数 (Test*/test_*)、HTTP 处理器 (http.ResponseWriter、gin.Context 等签名) 视为入口，不计入。
+
+置信度：
+  high: 未导出的函数，且项目内无调用方
+  medium: 私有方法（可能满足接口）、Go internal/cmd 下的导出符号、无法判断可见性的语言
+  low: 公开 API、常见接口方法 (String/MarshalJSON/toString...)、路由/视图文件中的函数
+
+参数：
+  scope (可选)
+    只检查该目录/文件
+
+  min_confidence (默认: medium)
+    high / medium / low
+
+  limit (默认: 100)
+    最多列出的候选数
+
+  include_vendored (默认: false)
+    包含 vendored/generated 文件
+
+示例：
+  dead_code(scope="internal/services", min_confidence="high")
+    -> 只列最可能可删除的符号
+
+触发词：
+  "mpm 死代码", "mpm dead code", "mpm 无引用"`),
+		mcp.WithInputSchema[DeadCodeArgs](),
+	), wrapDeadCode(sm, ai))
+
+	s.AddTool(mcp.NewTool("rename_plan",
+		mcp.WithDescription(`rename_plan - 符号重命名影响清单与分步计划
+
+用途：
+  重命名前一次列出所有要改的位置：索引中的定义与调用点（symbols/calls 表），
+  以及 ripgrep 全词搜到的其他代码引用、测试、注释、字符串和文档提及，按文件分组并标注置信度，
+  最后给出分步计划与可直接交给 task_chain spawn 的 sub_tasks JSON。
+
+置信度：
+  high: 定义、已解析到该符号的调用
+  medium: 存在多个同名定义时的调用、非调用的代码引用、测试中的引用
+  low: 注释、字符串、文档中的提及（配置键/序列化字段等可能不应改）
+
+参数：
+  old_name (必填) / new_name (必填)
+    现有符号名与新名称；new_name 已有定义时会提示命名冲突
+
+  scope (可选)
+    只在该目录下查找引用
+
+示例：
+  rename_plan(old_name="LoadConfig", new_name="LoadSettings")
+    -> 引用清单 + 改定义 → 逐文件改引用 → 改测试 → 复核 → 验证
+
+触发词：
+  "mpm 重命名", "mpm rename"`),
+		mcp.WithInputSchema[RenamePlanArgs](),
+	), wrapRenamePlan(sm, ai))
+
+	s.AddTool(mcp.NewTool("api_surface",
+		mcp.WithDescription(`api_surface - 公开 API 清单与破坏性变更检查
+
+用途：
+  按语言约定从索引中筛出公开符号，连同签名按包/模块分组列出；
+  可把当前 API 保存为基线，改动后与基线对比，删除或签名变化的公开符号标记为潜在破坏性变更。
+
+公开规则：
+  Go: 首字母大写（方法要求接收者类型也导出），internal 包默认不计
+  Python: 不以 _ 开头；JS/TS: export；Rust: pub；Java/C#: public；Kotlin/PHP: 非 private/protected/internal
+  测试文件不计；Go/Java/Kotlin/C# 按目录分组，其余语言按文件分组
+
+参数：
+  scope (可选)
+    只提取该目录下的公开符号
+
+  action (默认: show)
+    show: 列出公开 API
+    save: 保存为基线
+    diff: 与基线对比
+
+  baseline (可选)
+    基线名；save 默认按时间命名，diff 默认取最近一次保存的基线
+
+  include_vendored (默认: false)
+    包含 vendored/generated 文件
+
+示例：
+  api_surface(scope="pkg/client", action="save", baseline="v1.2.0")
+    -> 发版前保存基线
+  api_surface(scope="pkg/client", action="diff", baseline="v1.2.0")
+    -> 列出删除 / 签名变化 / 新增的公开符号
+
+触发词：
+  "mpm 公开接口", "mpm api surface", "mpm 破坏性变更"`),
+		mcp.WithInputSchema[APISurfaceArgs](),
+	), wrapAPISurface(sm, ai))
+
+	s.AddTool(mcp.NewTool("security_scan",
+		mcp.WithDescription(`security_scan - 凭据泄露与危险模式扫描
+
+用途：
+  在已索引的文件上逐行匹配安全规则，按严重程度输出报告；high 级命中自动创建钩子跟踪修复（同一问题不重复创建）。
+
+检查项：
+  硬编码凭据: AWS Key、私钥、GitHub/Slack Token、LLM API Key、URL 内嵌密码、password/secret/api_key 赋值
+  命令执行: 用户输入进入 exec.Command / eval / system，shell=True、sh -c、child_process.exec 拼接
+  路径穿越: 请求参数拼接进 filepath.Join / os.Open / open() / fs.readFile / include
+
+说明：
+  测试文件中的命中降一级；示例占位值 (changeme、${VAR}、your_key...) 忽略；
+  行内注释 nosec 或 mpm:ignore-security 可屏蔽误报。
+
+参数：
+  scope (可选)
+    只扫描该目录/文件
+
+  min_severity (默认: medium)
+    high / medium / low
+
+  skip_hooks (默认: false)
+    不自动创建钩子
+
+  limit (默认: 100)
+    最多列出的命中数
+
+  include_vendored (默认: false)
+    包含 vendored/generated 文件
+
+示例：
+  security_scan(scope="internal/api", min_severity="high")
+    -> 只看高危命中，并为其创建钩子
+
+触发词：
+  "mpm 安全扫描", "mpm security scan", "mpm 密钥泄露"`),
+		mcp.WithInputSchema[SecurityScanArgs](),
+	), wrapSecurityScan(sm, ai))
+
+	s.AddTool(mcp.NewTool("deps_audit",
+		mcp.WithDescription(`deps_audit - 依赖漏洞审计
+
+用途：
+  识别项目根及其下两层目录中的依赖清单，调用对应的审计器并把结果归一化为一份报告，
+  审计摘要自动记入备忘，便于之后追溯"哪次审计发现了什么"。
+
+审计器：
+  go.mod → govulncheck -json ./...（调用到漏洞函数的标为 high）
+  package.json → npm audit --json
+  requirements.txt → pip-audit -r requirements.txt -f json
+  Cargo.toml → cargo audit --json
+  未安装的审计器会给出安装方式；命令经命令守卫执行并写入审计日志（commands.allow 自定义时需包含上述命令）
+
+参数：
+  dir (可选)
+    只审计该目录下的清单
+
+  skip_memo (默认: false)
+    不记录审计摘要备忘
+
+  limit (默认: 50)
+    每个清单最多列出的漏洞数
+
+示例：
+  deps_audit(dir="services/api")
+    -> 只审计 monorepo 中的一个子项目
+
+触发词：
+  "mpm 依赖漏洞", "mpm deps audit", "mpm 漏洞审计"`),
+		mcp.WithInputSchema[DepsAuditArgs](),
+	), wrapDepsAudit(sm))
 }
 
 type flowTraceSnapshot struct {
@@ -277,141 +602,6 @@ func mergeUniqueStrings(items ...[]string) []string {
 	return out
 }
 
-func detectSideEffects(node *services.Node, related []services.CallerInfo) []string {
-	if node == nil {
-		return nil
-	}
-	bags := []string{node.Name, node.QualifiedName, node.FilePath}
-	for _, c := range related {
-		bags = append(bags, c.Node.Name, c.Node.QualifiedName, c.Node.FilePath)
-	}
-	joined := strings.ToLower(strings.Join(bags, " "))
-
-	tokenSet := make(map[string]bool)
-	for _, t := range strings.FieldsFunc(joined, func(r rune) bool {
-		isLower := r >= 'a' && r <= 'z'
-		isDigit := r >= '0' && r <= '9'
-		return !(isLower || isDigit)
-	}) {
-		if t != "" {
-			tokenSet[t] = true
-		}
-	}
-
-	hasToken := func(words ...string) bool {
-		for _, w := range words {
-			if tokenSet[w] {
-				return true
-			}
-		}
-		return false
-	}
-	containsAny := func(words ...string) bool {
-		for _, w := range words {
-			if strings.Contains(joined, w) {
-				return true
-			}
-		}
-		return false
-	}
-
-	scores := map[string]int{
-		"filesystem": 0,
-		"database":   0,
-		"network":    0,
-		"process":    0,
-		"state":      0,
-	}
-
-	// filesystem
-	if hasToken("file", "path", "read", "write", "mkdir", "open", "close", "stat") {
-		scores["filesystem"] += 2
-	}
-	if containsAny("filepath", "rename", "remove", "copy") {
-		scores["filesystem"] += 1
-	}
-
-	// database
-	if hasToken("db", "sql", "sqlite", "insert", "update", "delete", "commit", "transaction") {
-		scores["database"] += 2
-	}
-	if hasToken("query", "exec", "row", "rows") {
-		scores["database"] += 1
-	}
-
-	// network (stricter to reduce false positives)
-	if hasToken("http", "https", "grpc", "tcp", "udp", "socket", "request", "response", "listen", "dial") {
-		scores["network"] += 2
-	}
-	if containsAny("websocket", "endpoint", "url", "api") {
-		scores["network"] += 1
-	}
-
-	// process
-	if hasToken("exec", "command", "spawn", "process", "fork", "subprocess") {
-		scores["process"] += 2
-	}
-	if hasToken("run", "cmd") {
-		scores["process"] += 1
-	}
-
-	// state
-	if hasToken("state", "cache", "memory", "session", "lock", "mutex", "atomic") {
-		scores["state"] += 2
-	}
-	if hasToken("context") {
-		scores["state"] += 1
-	}
-
-	types := make([]string, 0, 5)
-	if scores["filesystem"] >= 2 {
-		types = append(types, "filesystem")
-	}
-	if scores["database"] >= 2 {
-		types = append(types, "database")
-	}
-	if scores["network"] >= 3 {
-		types = append(types, "network")
-	}
-	if scores["process"] >= 2 {
-		types = append(types, "process")
-	}
-	if scores["state"] >= 2 {
-		types = append(types, "state")
-	}
-
-	return mergeUniqueStrings(types)
-}
-
-func detectStages(node *services.Node, related []services.CallerInfo) []string {
-	if node == nil {
-		return nil
-	}
-	bags := []string{node.Name, node.QualifiedName}
-	for _, c := range related {
-		bags = append(bags, c.Node.Name, c.Node.QualifiedName)
-	}
-	joined := strings.ToLower(strings.Join(bags, " "))
-
-	stages := make([]string, 0, 6)
-	if strings.Contains(joined, "init") || strings.Contains(joined, "setup") || strings.Contains(joined, "new") || strings.Contains(joined, "bootstrap") || strings.Contains(joined, "load") {
-		stages = append(stages, "init")
-	}
-	if strings.Contains(joined, "validate") || strings.Contains(joined, "check") || strings.Contains(joined, "verify") || strings.Contains(joined, "guard") {
-		stages = append(stages, "validate")
-	}
-	if strings.Contains(joined, "run") || strings.Contains(joined, "process") || strings.Contains(joined, "handle") || strings.Contains(joined, "execute") || strings.Contains(joined, "build") || strings.Contains(joined, "index") {
-		stages = append(stages, "execute")
-	}
-	if strings.Contains(joined, "query") || strings.Contains(joined, "search") || strings.Contains(joined, "map") || strings.Contains(joined, "trace") || strings.Contains(joined, "analyze") {
-		stages = append(stages, "query")
-	}
-	if strings.Contains(joined, "save") || strings.Contains(joined, "write") || strings.Contains(joined, "insert") || strings.Contains(joined, "commit") || strings.Contains(joined, "persist") {
-		stages = append(stages, "persist")
-	}
-	return mergeUniqueStrings(stages)
-}
-
 func pickCallers(items []services.CallerInfo, limit int) []services.CallerInfo {
 	if limit <= 0 {
 		limit = 10
@@ -435,7 +625,7 @@ func pickCallers(items []services.CallerInfo, limit int) []services.CallerInfo {
 	return out
 }
 
-func buildFlowSnapshot(ai *services.ASTIndexer, projectRoot string, node *services.Node, direction string) (*flowTraceSnapshot, error) {
+func buildFlowSnapshot(ai *services.ASTIndexer, projectRoot string, node *services.Node, direction string, rules *FlowRules) (*flowTraceSnapshot, error) {
 	if node == nil {
 		return nil, fmt.Errorf("入口符号为空")
 	}
@@ -519,8 +709,9 @@ func buildFlowSnapshot(ai *services.ASTIndexer, projectRoot string, node *servic
 	if s.Backward != nil {
 		related = append(related, pickCallers(s.Backward.DirectCallers, 8)...)
 	}
-	s.SideEffects = detectSideEffects(node, related)
-	s.Stages = detectStages(node, related)
+	callees := ai.CalleeNames(projectRoot, node)
+	s.SideEffects = detectSideEffects(rules, node, related, callees)
+	s.Stages = detectStages(rules, node, related, callees)
 
 	return s, nil
 }
@@ -537,9 +728,16 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
 		}
 
-		if strings.TrimSpace(args.SymbolName) == "" && strings.TrimSpace(args.FilePath) == "" {
+		names := flowEntryNames(args)
+		if len(names) == 0 && strings.TrimSpace(args.FilePath) == "" {
 			return mcp.NewToolResultError("flow_trace 需要 symbol_name 或 file_path（至少一个）"), nil
 		}
+		if len(names) > flowCompareMaxEntries {
+			return mcp.NewToolResultError(fmt.Sprintf("symbol_names 最多 %d 个，当前 %d 个", flowCompareMaxEntries, len(names))), nil
+		}
+		if len(names) > 0 {
+			args.SymbolName = names[0]
+		}
 
 		direction := strings.ToLower(strings.TrimSpace(args.Direction))
 		if direction == "" {
@@ -551,6 +749,22 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 
 		mode := normalizeFlowMode(args.Mode)
 
+		render := strings.ToLower(strings.TrimSpace(args.Render))
+		switch render {
+		case "":
+			render = flowRenderText
+		case flowRenderText, flowRenderSequence:
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("不支持的 render: %s（可用: text, mermaid_sequence）", args.Render)), nil
+		}
+		maxChains := args.MaxChains
+		if maxChains <= 0 {
+			maxChains = 5
+		}
+		if maxChains > 20 {
+			maxChains = 20
+		}
+
 		maxNodes := args.MaxNodes
 		if maxNodes <= 0 {
 			maxNodes = 40
@@ -559,22 +773,42 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 			maxNodes = 120
 		}
 
-		var snapshots []*flowTraceSnapshot
-		allSnapshots := 0
-
-		if strings.TrimSpace(args.SymbolName) != "" {
-			searchResult, err := ai.SearchSymbolWithScope(sm.ProjectRoot, args.SymbolName, args.Scope)
+		// 文档模式：无代码栈的仓库沿 Markdown 链接追踪
+		if strings.EqualFold(filepath.Ext(args.FilePath), ".md") || (strings.TrimSpace(args.FilePath) == "" && services.IsDocsProject(sm.ProjectRoot)) {
+			idx, err := services.ScanDocs(sm.ProjectRoot, "")
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("symbol 定位失败: %v", err)), nil
+				return mcp.NewToolResultError(fmt.Sprintf("文档扫描失败: %v", err)), nil
 			}
-			if searchResult == nil || searchResult.FoundSymbol == nil {
-				return mcp.NewToolResultError(fmt.Sprintf("未找到符号: %s", args.SymbolName)), nil
+			entry, ok := resolveDocsEntry(idx, args.FilePath, args.SymbolName)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("未找到文档或标题: %s", fallback(args.FilePath, args.SymbolName))), nil
 			}
-			snap, err := buildFlowSnapshot(ai, sm.ProjectRoot, searchResult.FoundSymbol, direction)
-			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("flow_trace 失败: %v", err)), nil
+			return mcp.NewToolResultText(traceDocsFlow(idx, entry, direction, maxNodes)), nil
+		}
+
+		rules, rulesErr := loadFlowRules(sm.ProjectRoot)
+		if rulesErr != nil {
+			fmt.Fprintf(os.Stderr, "[flow_trace][WARN] %v\n", rulesErr)
+		}
+
+		var snapshots []*flowTraceSnapshot
+		allSnapshots := 0
+
+		if len(names) > 0 {
+			for _, name := range names {
+				searchResult, err := ai.SearchSymbolWithScope(sm.ProjectRoot, name, args.Scope)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("symbol 定位失败: %v", err)), nil
+				}
+				if searchResult == nil || searchResult.FoundSymbol == nil {
+					return mcp.NewToolResultError(fmt.Sprintf("未找到符号: %s", name)), nil
+				}
+				snap, err := buildFlowSnapshot(ai, sm.ProjectRoot, searchResult.FoundSymbol, direction, rules)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("flow_trace 失败: %v", err)), nil
+				}
+				snapshots = append(snapshots, snap)
 			}
-			snapshots = append(snapshots, snap)
 		} else {
 			// file mode
 			_, _ = ai.IndexScope(sm.ProjectRoot, args.FilePath)
@@ -630,7 +864,7 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 			for i := 0; i < candidateLimit; i++ {
 				n := nodes[i]
 				node := n
-				snap, err := buildFlowSnapshot(ai, sm.ProjectRoot, &node, direction)
+				snap, err := buildFlowSnapshot(ai, sm.ProjectRoot, &node, direction, rules)
 				if err == nil {
 					snapshots = append(snapshots, snap)
 				}
@@ -671,9 +905,29 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 			}
 		}
 
+		var graph *services.CallGraph
+		if render == flowRenderSequence || len(names) > 1 {
+			g, err := ai.LoadCallGraph(sm.ProjectRoot)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("加载调用图失败: %v", err)), nil
+			}
+			g.ExcludePaths(resolvePathTagger(sm, false))
+			graph = g
+		}
+		comparison := ""
+		if len(names) > 1 {
+			comparison = renderFlowComparison(graph, snapshots)
+		}
+		if render == flowRenderSequence {
+			return deliverLargeOutput(sm, "flow_trace_sequence.md", renderFlowDiagrams(sm, graph, snapshots, direction, maxChains)+comparison), nil
+		}
+
 		var sb strings.Builder
 		sb.WriteString("### 🔄 业务流程追踪\n\n")
 		sb.WriteString(fmt.Sprintf("**模式**: %s | **视图**: %s | **方向**: %s\n\n", func() string {
+			if len(names) > 1 {
+				return "symbols"
+			}
 			if strings.TrimSpace(args.SymbolName) != "" {
 				return "symbol"
 			}
@@ -687,6 +941,9 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 			n := snap.Node
 			sb.WriteString(fmt.Sprintf("#### 入口 `%s`\n", n.Name))
 			sb.WriteString(fmt.Sprintf("- 类型: `%s` | 位置: `%s:%d` | score=%.1f\n", snap.NodeKind, n.FilePath, n.LineStart, snap.Score))
+			if line := gitHistoryLine(sm, n.FilePath, n.LineStart, n.LineEnd); line != "" {
+				sb.WriteString("- Git: " + line + "\n")
+			}
 			sb.WriteString(fmt.Sprintf("- 跨文件连接: inbound=%d, outbound=%d\n", snap.ExternalIn, snap.ExternalOut))
 
 			upNamesPreview := make([]string, 0)
@@ -774,6 +1031,7 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 
 			sb.WriteString("\n")
 		}
+		sb.WriteString(comparison)
 
 		sb.WriteString("**建议**:\n")
 		sb.WriteString("- 若要精确改动风险，用 `code_impact(symbol_name=入口函数, direction=backward)` 二次确认。\n")
@@ -786,6 +1044,9 @@ func wrapFlowTrace(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandl
 		if omitted > 0 || shownNodes > maxNodes {
 			sb.WriteString(fmt.Sprintf("_注：已按输出预算截断，省略约 %d 个节点（max_nodes=%d）。_\n", omitted, maxNodes))
 		}
+		if rulesErr != nil && mode != "brief" {
+			sb.WriteString(fmt.Sprintf("_⚠️ 副作用/阶段规则: %v_\n", rulesErr))
+		}
 
 		return mcp.NewToolResultText(sb.String()), nil
 	}
@@ -812,6 +1073,7 @@ func wrapImpact(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("AST 分析失败: %v", err)), nil
 		}
+		excluded := resolvePathTagger(sm, args.IncludeVendored).FilterImpact(astResult)
 
 		if astResult == nil || astResult.Status != "success" {
 			errorMessage := fmt.Sprintf("⚠️ `%s` 不是代码函数/类定义。\n\n", args.SymbolName)
@@ -820,11 +1082,26 @@ func wrapImpact(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 			return mcp.NewToolResultText(errorMessage), nil
 		}
 
+		var traceSection, cycleWarning, coverageWarning, gitLine string
+		if g, targets, err := loadImpactGraph(sm, ai, args); err == nil {
+			gitLine = impactGitLine(sm, g, targets)
+			cycleWarning = impactCycleWarning(g, targets, args.SymbolName)
+			coverageWarning = impactCoverageWarning(g, targets, loadProjectCoverage(sm), args.SymbolName, astResult)
+			if args.MaxDepth > 0 || args.ShowPaths {
+				traceSection = impactTraceSection(g, targets, args, astResult)
+			}
+		} else if args.MaxDepth > 0 || args.ShowPaths {
+			traceSection = fmt.Sprintf("\n_⚠️ 调用图不可用，未应用 max_depth/show_paths: %v_\n", err)
+		}
+
 		// 2. 精简输出 (面向 LLM 决策)
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("## `%s` 影响分析\n\n", args.SymbolName))
 		sb.WriteString(fmt.Sprintf("**风险**: %s | **复杂度**: %.0f | **影响节点**: %d\n\n",
 			astResult.RiskLevel, astResult.ComplexityScore, astResult.AffectedNodes))
+		sb.WriteString(gitLine)
+		sb.WriteString(cycleWarning)
+		sb.WriteString(coverageWarning)
 
 		// 直接调用者列表
 		if len(astResult.DirectCallers) > 0 {
@@ -848,6 +1125,10 @@ func wrapImpact(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 		if len(astResult.IndirectCallers) > 0 {
 			sb.WriteString(fmt.Sprintf("\n_间接影响: %d 个函数_\n", len(astResult.IndirectCallers)))
 		}
+		if excluded > 0 {
+			sb.WriteString(fmt.Sprintf("\n_已排除 %d 个 vendored/generated 调用者 (include_vendored=true 可查看)_\n", excluded))
+		}
+		sb.WriteString(traceSection)
 
 		// JSON：直接调用者 + 间接调用者（按距离，前20个）
 		sb.WriteString("\n```json\n")
@@ -880,6 +1161,10 @@ func wrapImpact(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 
 		sb.WriteString("]}\n```\n")
 
+		if strings.TrimSpace(args.TaskID) != "" {
+			sb.WriteString(recordChainImpact(ctx, sm, args.TaskID, args.SymbolName, astResult))
+		}
+
 		return mcp.NewToolResultText(sb.String()), nil
 	}
 }
@@ -894,6 +1179,11 @@ func wrapProjectMap(sm *SessionManager, ai *services.ASTIndexer) server.ToolHand
 		if sm.ProjectRoot == "" {
 			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
 		}
+		scope, err := services.SubProjectScope(sm.ProjectRoot, args.SubProject, args.Scope)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		args.Scope = scope
 
 		level := args.Level
 		if level == "" {
@@ -902,121 +1192,168 @@ func wrapProjectMap(sm *SessionManager, ai *services.ASTIndexer) server.ToolHand
 
 		if level == "structure" {
 			// 结构视图走 Rust structure 模式，不触发全量符号索引，避免超大 JSON
-			structureResult, err := ai.StructureProjectWithScope(sm.ProjectRoot, args.Scope)
+			// 索引未变化时复用 warm_up 缓存的结构结果
+			structureResult, _, err := ai.CachedStructure(sm.ProjectRoot, args.Scope, false)
 			if err != nil {
 				return mcp.NewToolResultError(fmt.Sprintf("生成结构地图失败: %v", err)), nil
 			}
+			resolvePathTagger(sm, args.IncludeVendored).FilterStructure(structureResult)
 
-			type dirCount struct {
-				Path  string
-				Count int
-			}
-			dirs := make([]dirCount, 0, len(structureResult.Structure))
-			for p, info := range structureResult.Structure {
-				dirs = append(dirs, dirCount{Path: p, Count: info.FileCount})
-			}
-			sort.Slice(dirs, func(i, j int) bool {
-				if dirs[i].Count == dirs[j].Count {
-					return dirs[i].Path < dirs[j].Path
-				}
-				return dirs[i].Count > dirs[j].Count
-			})
+			content := renderStructureMap(structureResult, args.Scope)
+			return deliverLargeOutput(sm, "project_map_structure.md", content), nil
+		}
 
-			var sb strings.Builder
-			sb.WriteString("### 🗺️ 项目地图 (Structure)\n\n")
-			sb.WriteString(fmt.Sprintf("**📊 统计**: %d 文件 | %d 目录\n\n", structureResult.TotalFiles, len(dirs)))
-			if strings.TrimSpace(args.Scope) != "" {
-				sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", args.Scope))
+		if level == "knowledge" {
+			dirs, threshold, err := buildKnowledgeMap(ctx, sm, ai, args.Scope, resolvePathTagger(sm, args.IncludeVendored))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("生成知识地图失败: %v", err)), nil
 			}
-			sb.WriteString("**📁 目录结构** (按文件数排序):\n")
 
-			limit := 120
-			if len(dirs) < limit {
-				limit = len(dirs)
-			}
-			for i := 0; i < limit; i++ {
-				path := dirs[i].Path
-				if path == "" {
-					path = "(root)"
-				}
-				sb.WriteString(fmt.Sprintf("- `%s/` (%d files)\n", path, dirs[i].Count))
+			content := renderKnowledgeMap(dirs, threshold, args.Scope)
+			return deliverLargeOutput(sm, "project_map_knowledge.md", content), nil
+		}
+
+		if level == "issues" {
+			if strings.TrimSpace(args.Scope) != "" {
+				_, _ = ai.IndexScope(sm.ProjectRoot, args.Scope)
+			} else {
+				_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
 			}
-			if len(dirs) > limit {
-				sb.WriteString(fmt.Sprintf("\n... 其余 %d 个目录已省略，请使用 scope 下钻。\n", len(dirs)-limit))
+			issues, err := ai.BuildIndexIssues(sm.ProjectRoot, args.Scope, 0, resolvePathTagger(sm, args.IncludeVendored))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("生成问题视图失败: %v", err)), nil
 			}
 
-			content := sb.String()
-			if len(content) > 2000 {
-				mcpDataDir := filepath.Join(sm.ProjectRoot, ".mcp-data")
-				_ = os.MkdirAll(mcpDataDir, 0755)
-				outputPath := filepath.Join(mcpDataDir, "project_map_structure.md")
-				if err := os.WriteFile(outputPath, []byte(content), 0644); err == nil {
-					return mcp.NewToolResultText(fmt.Sprintf("⚠️ Map 内容较长 (%d chars)，已自动保存到项目文件：\n👉 `%s`\n\n请使用 view_file 查看。", len(content), outputPath)), nil
-				}
-			}
+			content := renderIndexIssues(issues, args.Scope)
+			return deliverLargeOutput(sm, "project_map_issues.md", content), nil
+		}
 
-			return mcp.NewToolResultText(content), nil
+		if level == "diff" {
+			content, err := buildMapDiff(sm, ai, args)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("生成地图对比失败: %v", err)), nil
+			}
+			return deliverLargeOutput(sm, "project_map_diff.md", content), nil
 		}
 
-		// symbols 视图：优先按范围补录（热点目录），否则按新鲜度检查全量索引
-		if strings.TrimSpace(args.Scope) != "" {
-			_, _ = ai.IndexScope(sm.ProjectRoot, args.Scope)
-		} else {
-			_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		// 文档模式：无代码栈时以标题大纲代替符号地图
+		if services.IsDocsProject(sm.ProjectRoot) {
+			idx, err := services.ScanDocs(sm.ProjectRoot, args.Scope)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("文档扫描失败: %v", err)), nil
+			}
+			content := renderDocsMap(idx, args.Scope)
+			return deliverLargeOutput(sm, "project_map_docs.md", content), nil
 		}
 
-		// 调用 AST 服务生成数据
-		// 注意：如果 scope 为空，底层会自动处理为整个项目
-		result, err := ai.MapProjectWithScope(sm.ProjectRoot, level, args.Scope)
+		result, tagger, footer, err := buildSymbolsMap(sm, ai, args, level)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("生成地图失败: %v", err)), nil
 		}
+		saveMapSnapshot(sm, result, args.Scope)
 
-		// 🆕 收集所有符号名并分析复杂度
-		var symbolNames []string
-		for _, nodes := range result.Structure {
-			for _, node := range nodes {
-				// 只分析函数、方法和类
-				if node.NodeType == "function" || node.NodeType == "method" || node.NodeType == "class" {
-					symbolNames = append(symbolNames, node.Name)
-				}
+		// 使用 MapRenderer 渲染结果
+		mr := NewMapRenderer(result, sm.ProjectRoot)
+		mr.Coverage = loadProjectCoverage(sm)
+		if g, err := ai.LoadCallGraph(sm.ProjectRoot); err == nil {
+			g.ExcludePaths(tagger)
+			mr.Hotspots = scopeHotspots(sm, g, args.Scope)
+		}
+
+		content := mr.RenderStandard() + footer
+
+		// 🆕 主动接管大输出：> 2000 字符时按客户端能力以嵌入资源返回或保存到文件
+		// 按模式固定命名，每次直接覆盖（不保留历史版本）
+		return deliverLargeOutput(sm, fmt.Sprintf("project_map_%s.md", level), content), nil
+	}
+}
+
+// buildSymbolsMap 生成 symbols 视图数据（含复杂度映射），返回路径过滤器与复杂度页脚
+func buildSymbolsMap(sm *SessionManager, ai *services.ASTIndexer, args ProjectMapArgs, level string) (*services.MapResult, *services.PathTagger, string, error) {
+	// symbols 视图：优先按范围补录（热点目录），否则按新鲜度检查全量索引
+	if strings.TrimSpace(args.Scope) != "" {
+		_, _ = ai.IndexScope(sm.ProjectRoot, args.Scope)
+	} else {
+		_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+	}
+
+	// 调用 AST 服务生成数据
+	// 注意：如果 scope 为空，底层会自动处理为整个项目
+	result, err := ai.MapProjectWithScope(sm.ProjectRoot, level, args.Scope)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	tagger := resolvePathTagger(sm, args.IncludeVendored)
+	tagger.FilterMap(result)
+
+	// 🆕 收集所有符号名并分析复杂度
+	var symbolNames []string
+	for _, nodes := range result.Structure {
+		for _, node := range nodes {
+			// 只分析函数、方法和类
+			if node.NodeType == "function" || node.NodeType == "method" || node.NodeType == "class" {
+				symbolNames = append(symbolNames, node.Name)
 			}
 		}
+	}
 
-		// 调用复杂度分析
+	// 调用复杂度分析
+	footer := "\n---\n⏱ 复杂度热力图：已跳过 (skip_complexity=true)\n"
+	if !args.SkipComplexity {
+		footer = ""
 		if len(symbolNames) > 0 {
-			complexityReport, err := ai.AnalyzeComplexity(sm.ProjectRoot, symbolNames)
+			complexityReport, err := ai.AnalyzeComplexityExcluding(sm.ProjectRoot, symbolNames, tagger)
 			if err == nil && complexityReport != nil {
 				// 构建复杂度映射
 				result.ComplexityMap = make(map[string]float64)
 				for _, risk := range complexityReport.HighRiskSymbols {
 					result.ComplexityMap[risk.SymbolName] = risk.Score
 				}
+				footer = fmt.Sprintf("\n---\n⏱ 复杂度热力图：%d 个符号，耗时 %dms\n", complexityReport.TotalAnalyzed, complexityReport.ElapsedMs)
 			}
 		}
+	}
+	return result, tagger, footer, nil
+}
 
-		// 使用 MapRenderer 渲染结果
-		mr := NewMapRenderer(result, sm.ProjectRoot)
-
-		content := mr.RenderStandard()
-
-		// 🆕 主动接管大输出：如果 > 2000 字符，保存到文件
-		if len(content) > 2000 {
-			mcpDataDir := filepath.Join(sm.ProjectRoot, ".mcp-data")
-			_ = os.MkdirAll(mcpDataDir, 0755)
-
-			// 按模式固定命名，每次直接覆盖（不保留历史版本）
-			filename := fmt.Sprintf("project_map_%s.md", level)
-			outputPath := filepath.Join(mcpDataDir, filename)
-
-			if err := os.WriteFile(outputPath, []byte(content), 0644); err == nil {
-				return mcp.NewToolResultText(fmt.Sprintf(
-					"⚠️ Map 内容较长 (%d chars)，已自动保存到项目文件：\n👉 `%s`\n\n请使用 view_file 查看。",
-					len(content), outputPath)), nil
-			}
-			// 如果保存失败，降级回直接返回
+// renderStructureMap 渲染目录结构视图（按文件数排序）
+func renderStructureMap(structureResult *services.StructureResult, scope string) string {
+	type dirCount struct {
+		Path  string
+		Count int
+	}
+	dirs := make([]dirCount, 0, len(structureResult.Structure))
+	for p, info := range structureResult.Structure {
+		dirs = append(dirs, dirCount{Path: p, Count: info.FileCount})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Count == dirs[j].Count {
+			return dirs[i].Path < dirs[j].Path
 		}
+		return dirs[i].Count > dirs[j].Count
+	})
+
+	var sb strings.Builder
+	sb.WriteString("### 🗺️ 项目地图 (Structure)\n\n")
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 文件 | %d 目录\n\n", structureResult.TotalFiles, len(dirs)))
+	if strings.TrimSpace(scope) != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", scope))
+	}
+	sb.WriteString("**📁 目录结构** (按文件数排序):\n")
 
-		return mcp.NewToolResultText(content), nil
+	limit := 120
+	if len(dirs) < limit {
+		limit = len(dirs)
+	}
+	for i := 0; i < limit; i++ {
+		path := dirs[i].Path
+		if path == "" {
+			path = "(root)"
+		}
+		sb.WriteString(fmt.Sprintf("- `%s/` (%d files)\n", path, dirs[i].Count))
+	}
+	if len(dirs) > limit {
+		sb.WriteString(fmt.Sprintf("\n... 其余 %d 个目录已省略，请使用 scope 下钻。\n", len(dirs)-limit))
 	}
+	return sb.String()
 }
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AnnotateArgs 时间线人工标注参数
+type AnnotateArgs struct {
+	Action  string `json:"action" jsonschema:"default=add,enum=add,enum=list,enum=remove,description=操作类型"`
+	Kind    string `json:"kind" jsonschema:"enum=milestone,enum=release,enum=incident,enum=note,description=标注类型 (默认 note)"`
+	Title   string `json:"title" jsonschema:"description=标注标题 (add 必填)，如 v1.2 发布、支付故障"`
+	Content string `json:"content" jsonschema:"description=补充说明"`
+	At      string `json:"at" jsonschema:"description=标注时间 (默认当前时间)，支持 2024-05-01 15:04 或 3d 等相对时长"`
+	Author  string `json:"author" jsonschema:"description=标注人"`
+	ID      int64  `json:"id" jsonschema:"description=remove 时的标注 ID"`
+	Since   string `json:"since" jsonschema:"description=list 时间下限"`
+	Until   string `json:"until" jsonschema:"description=list 时间上限"`
+	Limit   int    `json:"limit" jsonschema:"default=50,description=list 返回条数"`
+}
+
+// annotationIcons 标注类型在文本输出中的图标
+var annotationIcons = map[string]string{
+	"milestone": "🏁",
+	"release":   "🚀",
+	"incident":  "🔥",
+	"note":      "📌",
+}
+
+func formatAnnotation(a core.TimelineAnnotation) string {
+	line := fmt.Sprintf("- %s **[%d] %s** (%s) %s", annotationIcons[a.Kind], a.ID, a.At.Format("2006-01-02 15:04"), a.Kind, a.Title)
+	if a.Author != "" {
+		line += " — " + a.Author
+	}
+	if a.Content != "" {
+		line += ": " + truncateRunes(a.Content, 200)
+	}
+	return line + "\n"
+}
+
+func wrapAnnotate(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args AnnotateArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化"), nil
+		}
+		now := time.Now()
+
+		switch strings.ToLower(fallback(strings.TrimSpace(args.Action), "add")) {
+		case "add":
+			at, err := core.ParseTimeBound(args.At, now, false)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+			}
+			a := core.TimelineAnnotation{Kind: args.Kind, Title: args.Title, Content: args.Content, Author: args.Author, At: at}
+			id, err := sm.Memory.AddTimelineAnnotation(ctx, a)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("添加标注失败: %v", err)), nil
+			}
+			if at.IsZero() {
+				at = now
+			}
+			kind, _ := core.NormalizeAnnotationKind(args.Kind)
+			return mcp.NewToolResultText(fmt.Sprintf("✅ 已添加时间线标注 [%d] %s %s @ %s\n将在 open_timeline 与每日摘要中单独标记。",
+				id, annotationIcons[kind], strings.TrimSpace(args.Title), at.Format("2006-01-02 15:04"))), nil
+
+		case "remove":
+			if args.ID <= 0 {
+				return mcp.NewToolResultError("remove 需要提供 id"), nil
+			}
+			ok, err := sm.Memory.DeleteTimelineAnnotation(ctx, args.ID)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("删除标注失败: %v", err)), nil
+			}
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("标注不存在: %d", args.ID)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🗑️ 已删除标注: %d", args.ID)), nil
+
+		case "list":
+			within, err := core.ParseTimeRange(args.Since, args.Until, now)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+			}
+			limit := args.Limit
+			if limit <= 0 {
+				limit = 50
+			}
+			items, err := sm.Memory.ListTimelineAnnotations(ctx, args.Kind, within, limit)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询标注失败: %v", err)), nil
+			}
+			if len(items) == 0 {
+				return mcp.NewToolResultText("暂无时间线标注。使用 annotate(kind=\"milestone\", title=...) 添加。"), nil
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("### 📍 时间线标注 (%d)\n\n", len(items)))
+			for _, a := range items {
+				sb.WriteString(formatAnnotation(a))
+			}
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知操作: %s (支持 add/list/remove)", args.Action)), nil
+	}
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// APISurfaceArgs API 表面参数
+type APISurfaceArgs struct {
+	Scope           string `json:"scope" jsonschema:"description=只提取该目录下的公开符号 (留空=整个项目)"`
+	Action          string `json:"action" jsonschema:"default=show,enum=show,enum=save,enum=diff,description=show 列出 / save 保存基线 / diff 与基线对比"`
+	Baseline        string `json:"baseline" jsonschema:"description=基线名 (save 默认按时间命名；diff 默认最近一次保存的基线)"`
+	IncludeVendored bool   `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件 (默认排除)"`
+}
+
+// apiSurfaceListLimit diff 每类最多列出的条目数
+const apiSurfaceListLimit = 100
+
+func apiSurfaceDir(sm *SessionManager) string {
+	return core.DataPath(sm.ProjectRoot, "api_surface")
+}
+
+func wrapAPISurface(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args APISurfaceArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		action := strings.ToLower(strings.TrimSpace(args.Action))
+		switch action {
+		case "":
+			action = "show"
+		case "show", "save", "diff":
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("未知 action: %s（可用: show, save, diff）", args.Action)), nil
+		}
+
+		_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		surface, err := ai.ExtractAPISurface(sm.ProjectRoot, args.Scope, resolvePathTagger(sm, args.IncludeVendored))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("提取公开 API 失败: %v", err)), nil
+		}
+		dir := apiSurfaceDir(sm)
+
+		switch action {
+		case "save":
+			surface.Label = fallback(strings.TrimSpace(args.Baseline), time.Now().Format("20060102-150405"))
+			if err := services.SaveAPISurface(dir, surface); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("保存基线失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("✅ 已保存 API 基线 `%s`：%d 个模块，%d 个公开符号。\n之后执行 `api_surface(action=\"diff\", baseline=\"%s\")` 检查破坏性变更。",
+				surface.Label, len(surface.Modules), surface.Count(), surface.Label)), nil
+		case "diff":
+			label := strings.TrimSpace(args.Baseline)
+			if label == "" {
+				labels, err := services.ListAPISurfaces(dir)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("读取基线失败: %v", err)), nil
+				}
+				if len(labels) == 0 {
+					return mcp.NewToolResultError("还没有保存过 API 基线，请先执行 api_surface(action=\"save\")"), nil
+				}
+				label = labels[len(labels)-1]
+			}
+			base, err := services.LoadAPISurface(dir, label)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return deliverLargeOutput(sm, "api_surface_diff.md", renderAPIDiff(base, surface, services.DiffAPISurfaces(base, surface))), nil
+		}
+		return deliverLargeOutput(sm, "api_surface.md", renderAPISurface(surface)), nil
+	}
+}
+
+// renderAPISurface 按模块分组列出公开符号及签名
+func renderAPISurface(s *services.APISurface) string {
+	var sb strings.Builder
+	sb.WriteString("### 🔌 公开 API\n\n")
+	if s.Scope != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n", s.Scope))
+	}
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 个模块，%d 个公开符号\n", len(s.Modules), s.Count()))
+	if len(s.Modules) == 0 {
+		sb.WriteString("\n未找到公开符号（Go 的 internal 包需要把 scope 指向该目录才会列出）。\n")
+		return sb.String()
+	}
+	modules := make([]string, 0, len(s.Modules))
+	for m := range s.Modules {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	for _, m := range modules {
+		syms := s.Modules[m]
+		sb.WriteString(fmt.Sprintf("\n#### 📦 %s (%d)\n", m, len(syms)))
+		for _, sym := range syms {
+			sb.WriteString("- " + formatAPISymbol(sym) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func formatAPISymbol(sym services.APISymbol) string {
+	name := fallback(sym.Qualified, sym.Name)
+	line := fmt.Sprintf("`%s` [%s] %s:%d", name, sym.Type, sym.File, sym.Line)
+	if sym.Signature != "" {
+		line += fmt.Sprintf(" — `%s`", truncateRunes(sym.Signature, 160))
+	}
+	return line
+}
+
+// renderAPIDiff 删除与签名变化为破坏性变更，新增仅提示
+func renderAPIDiff(base, cur *services.APISurface, d *services.APIDiff) string {
+	var sb strings.Builder
+	sb.WriteString("### 🔌 公开 API (Diff)\n\n")
+	sb.WriteString(fmt.Sprintf("**🕒 基线**: `%s` (%s)，%d 个公开符号 → 当前 %d 个\n",
+		base.Label, base.CreatedAt.Format("2006-01-02 15:04"), base.Count(), cur.Count()))
+	if base.Scope != cur.Scope {
+		sb.WriteString(fmt.Sprintf("> ⚠️ 基线与本次的 scope 不同（`%s` vs `%s`），范围外的符号会显示为删除/新增。\n",
+			fallback(base.Scope, "."), fallback(cur.Scope, ".")))
+	}
+	if d.Breaking() {
+		sb.WriteString(fmt.Sprintf("\n⚠️ **潜在破坏性变更**: 删除 %d，签名变化 %d\n", len(d.Removed), len(d.Changed)))
+	} else {
+		sb.WriteString("\n✅ 没有删除或签名变化的公开符号\n")
+	}
+
+	writeList := func(title string, list []services.APIChange, line func(services.APIChange) string) {
+		if len(list) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("\n#### %s (%d)\n", title, len(list)))
+		for i, c := range list {
+			if i == apiSurfaceListLimit {
+				sb.WriteString(fmt.Sprintf("- ... 另有 %d 项\n", len(list)-i))
+				break
+			}
+			sb.WriteString(line(c) + "\n")
+		}
+	}
+	writeList("❌ 删除 (破坏性)", d.Removed, func(c services.APIChange) string {
+		return fmt.Sprintf("- [%s] %s", c.Module, formatAPISymbol(*c.Before))
+	})
+	writeList("✏️ 签名变化 (可能破坏)", d.Changed, func(c services.APIChange) string {
+		return fmt.Sprintf("- [%s] `%s` %s:%d\n  - 旧: `%s`\n  - 新: `%s`", c.Module, fallback(c.After.Qualified, c.After.Name),
+			c.After.File, c.After.Line, truncateRunes(c.Before.Signature, 160), truncateRunes(fallback(c.After.Signature, "(无签名)"), 160))
+	})
+	writeList("➕ 新增", d.Added, func(c services.APIChange) string {
+		return fmt.Sprintf("- [%s] %s", c.Module, formatAPISymbol(*c.After))
+	})
+	return sb.String()
+}
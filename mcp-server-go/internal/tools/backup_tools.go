@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RestoreBackupArgs 备份与恢复参数
+type RestoreBackupArgs struct {
+	Action string `json:"action" jsonschema:"default=list,enum=list,enum=create,enum=restore,description=操作类型"`
+	Name   string `json:"name" jsonschema:"description=要恢复的备份文件名 (restore 必填，见 list 输出)"`
+}
+
+func wrapRestoreBackup(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args RestoreBackupArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化，请先执行 initialize_project。"), nil
+		}
+
+		switch action := strings.ToLower(fallback(strings.TrimSpace(args.Action), "list")); action {
+		case "list":
+			backups, err := core.ListBackups(sm.ProjectRoot)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("读取备份目录失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(renderBackupList(backups)), nil
+
+		case "create":
+			info, err := sm.Memory.CreateBackup(ctx, core.BackupManual)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("💾 已备份记忆库: %s (%s)", info.Name, humanBytes(info.Size))), nil
+
+		case "restore":
+			if strings.TrimSpace(args.Name) == "" {
+				return mcp.NewToolResultError("restore 需要提供 name（见 restore_backup(action=\"list\")）"), nil
+			}
+			safety, err := sm.Memory.RestoreBackup(ctx, args.Name)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			// 内存中的任务链可能与恢复后的库不一致，丢弃后按需从 DB 重新加载
+			sm.TaskChainsV3 = nil
+			return mcp.NewToolResultText(fmt.Sprintf("♻️ 已从 %s 恢复记忆库。\n恢复前的数据已保存为 %s，如需撤销: restore_backup(action=\"restore\", name=\"%s\")",
+				args.Name, safety.Name, safety.Name)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知操作: %s (支持 list/create/restore)", args.Action)), nil
+	}
+}
+
+func renderBackupList(backups []core.BackupInfo) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 💾 记忆库备份 (%d)\n\n", len(backups)))
+	if len(backups) == 0 {
+		sb.WriteString("暂无备份。手动备份: `restore_backup(action=\"create\")`；定时备份: settings.json 中启用 scheduler（db_backup 任务）。\n")
+		return sb.String()
+	}
+	for _, b := range backups {
+		sb.WriteString(fmt.Sprintf("- `%s` [%s] %s · %s\n", b.Name, b.Kind, b.CreatedAt.Format("2006-01-02 15:04:05"), humanBytes(b.Size)))
+	}
+	sb.WriteString("\n> 恢复: `restore_backup(action=\"restore\", name=\"...\")`，恢复前会自动为当前库生成 pre-restore 安全副本")
+	return sb.String()
+}
+
+// humanBytes 以 KB/MB 显示文件大小
+func humanBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
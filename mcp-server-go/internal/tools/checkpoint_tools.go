@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// CheckpointArgs 检查点参数
+type CheckpointArgs struct {
+	Mode   string   `json:"mode" jsonschema:"default=create,enum=create,enum=rollback,enum=list,enum=show,description=操作模式"`
+	Files  []string `json:"files" jsonschema:"description=要快照的文件或目录 (create 模式必填，相对项目根目录)"`
+	Name   string   `json:"name" jsonschema:"description=检查点名称，如 '重构前'"`
+	TaskID string   `json:"task_id" jsonschema:"description=关联的任务ID (list 时按任务过滤；rollback 未传 id 时回滚该任务最近的检查点)"`
+	ID     int64    `json:"id" jsonschema:"description=检查点ID (rollback/show 模式)"`
+}
+
+func wrapCheckpoint(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args CheckpointArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化"), nil
+		}
+
+		switch args.Mode {
+		case "", "create":
+			if len(args.Files) == 0 {
+				return mcp.NewToolResultError("create 模式需要 files 参数"), nil
+			}
+			cp, err := sm.Memory.CreateCheckpoint(ctx, args.Name, args.TaskID, args.Files)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("创建检查点失败: %v", err)), nil
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("📌 已创建检查点 #%d「%s」，快照 %d 个文件\n", cp.ID, cp.Name, len(cp.Files)))
+			sb.WriteString(renderCheckpointFiles(cp.Files, 20))
+			sb.WriteString(fmt.Sprintf("\n回滚: checkpoint(mode=\"rollback\", id=%d)\n", cp.ID))
+			return mcp.NewToolResultText(sb.String()), nil
+
+		case "rollback":
+			id := args.ID
+			if id <= 0 {
+				if args.TaskID == "" {
+					return mcp.NewToolResultError("rollback 模式需要 id 或 task_id 参数"), nil
+				}
+				cp, err := sm.Memory.LatestCheckpoint(ctx, args.TaskID)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("查询检查点失败: %v", err)), nil
+				}
+				if cp == nil {
+					return mcp.NewToolResultError(fmt.Sprintf("任务 %s 没有检查点", args.TaskID)), nil
+				}
+				id = cp.ID
+			}
+			res, err := sm.Memory.RestoreCheckpoint(ctx, id)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("回滚失败: %v", err)), nil
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("⏪ 已回滚到检查点 #%d：恢复 %d 个文件", id, len(res.Restored)))
+			if len(res.Removed) > 0 {
+				sb.WriteString(fmt.Sprintf("，删除 %d 个检查点后新建的文件", len(res.Removed)))
+			}
+			sb.WriteString("\n")
+			for _, p := range res.Removed {
+				sb.WriteString(fmt.Sprintf("  - 🗑 %s\n", p))
+			}
+			sb.WriteString(fmt.Sprintf("\n回滚前的状态已保存为检查点 #%d，可用 checkpoint(mode=\"rollback\", id=%d) 撤销本次回滚。\n", res.Backup, res.Backup))
+			return mcp.NewToolResultText(sb.String()), nil
+
+		case "list":
+			items, err := sm.Memory.ListCheckpoints(ctx, args.TaskID, 20)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询检查点失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(renderCheckpointList(items)), nil
+
+		case "show":
+			if args.ID <= 0 {
+				return mcp.NewToolResultError("show 模式需要 id 参数"), nil
+			}
+			cp, err := sm.Memory.GetCheckpoint(ctx, args.ID)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("### 📌 检查点 #%d「%s」\n\n", cp.ID, cp.Name))
+			sb.WriteString(fmt.Sprintf("- 创建: %s\n", cp.CreatedAt.Local().Format("2006-01-02 15:04:05")))
+			if cp.TaskID != "" {
+				sb.WriteString(fmt.Sprintf("- 任务: %s\n", cp.TaskID))
+			}
+			if cp.RestoredAt != "" {
+				sb.WriteString(fmt.Sprintf("- 已回滚: %s\n", cp.RestoredAt))
+			}
+			sb.WriteString(fmt.Sprintf("- 文件 (%d):\n", len(cp.Files)))
+			sb.WriteString(renderCheckpointFiles(cp.Files, 0))
+			return mcp.NewToolResultText(sb.String()), nil
+
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("未知模式: %s", args.Mode)), nil
+		}
+	}
+}
+
+// renderCheckpointFiles 列出检查点文件，limit > 0 时只显示前 limit 个
+func renderCheckpointFiles(files []core.CheckpointFile, limit int) string {
+	var sb strings.Builder
+	for i, f := range files {
+		if limit > 0 && i >= limit {
+			sb.WriteString(fmt.Sprintf("  ... 另有 %d 个文件\n", len(files)-limit))
+			break
+		}
+		if f.Existed {
+			sb.WriteString(fmt.Sprintf("  - %s (%d B)\n", f.Path, f.Size))
+		} else {
+			sb.WriteString(fmt.Sprintf("  - %s (尚不存在，回滚时删除)\n", f.Path))
+		}
+	}
+	return sb.String()
+}
+
+func renderCheckpointList(items []core.Checkpoint) string {
+	if len(items) == 0 {
+		return "暂无检查点。"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 📌 检查点列表 (%d)\n\n", len(items)))
+	for _, cp := range items {
+		line := fmt.Sprintf("- #%d「%s」%d 个文件, %s", cp.ID, cp.Name, len(cp.Files), cp.CreatedAt.Local().Format("01-02 15:04"))
+		if cp.TaskID != "" {
+			line += fmt.Sprintf(" [Task: %s]", cp.TaskID)
+		}
+		if cp.RestoredAt != "" {
+			line += " (已回滚)"
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// gateRollbackHint gate 达到最大重试次数后的回滚建议
+func gateRollbackHint(ctx context.Context, sm *SessionManager, taskID string) string {
+	if sm.Memory == nil {
+		return ""
+	}
+	cp, err := sm.Memory.LatestCheckpoint(ctx, taskID)
+	if err != nil {
+		return ""
+	}
+	if cp == nil {
+		return "\n\n💡 该任务没有检查点。下次在高风险阶段开始前可用 checkpoint(mode=\"create\", task_id=..., files=[...]) 保存现场，失败时一键回滚。"
+	}
+	return fmt.Sprintf("\n\n⏪ 可回滚到该任务最近的检查点 #%d「%s」(%d 个文件, %s):\n  checkpoint(mode=\"rollback\", id=%d)",
+		cp.ID, cp.Name, len(cp.Files), cp.CreatedAt.Local().Format("01-02 15:04"), cp.ID)
+}
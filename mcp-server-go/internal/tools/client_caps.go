@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ========== 客户端能力协商 ==========
+//
+// 会话初始化 (initialize) 时记录客户端声明的协议版本、能力与身份，推导出渲染所需的能力画像；
+// 各渲染器据此自动适配（大地图以嵌入资源返回、JSON 结果附带 structuredContent、进度通知等），
+// 不再依赖环境变量或手工配置。客户端也可以在 capabilities.experimental.mpm 中显式声明覆盖：
+//   {"experimental": {"mpm": {"resources": true, "structured_content": false, "markdown": false}}}
+
+// largeOutputThreshold 超过该长度的输出不再内联为纯文本
+const largeOutputThreshold = 2000
+
+// ClientProfile 客户端能力画像
+type ClientProfile struct {
+	Name              string `json:"name"`
+	Version           string `json:"version,omitempty"`
+	ProtocolVersion   string `json:"protocol_version"`
+	Resources         bool   `json:"resources"`          // 支持工具结果中的嵌入资源
+	StructuredContent bool   `json:"structured_content"` // 支持 structuredContent（协议 2025-06-18+）
+	Progress          bool   `json:"progress"`           // 请求中携带过 progressToken
+	Markdown          bool   `json:"markdown"`           // 能渲染 Markdown
+	Roots             bool   `json:"roots,omitempty"`
+	Sampling          bool   `json:"sampling,omitempty"`
+	Elicitation       bool   `json:"elicitation,omitempty"`
+}
+
+// clientQuirks 已知客户端的能力差异（按 clientInfo.name 子串匹配，小写）
+var clientQuirks = map[string]func(p *ClientProfile){
+	// 纯终端客户端对 Markdown 表格/代码围栏渲染较差
+	"codex":  func(p *ClientProfile) { p.Markdown = false },
+	"gemini": func(p *ClientProfile) { p.Markdown = false },
+	// 仅展示文本块，嵌入资源会被忽略
+	"cline":    func(p *ClientProfile) { p.Resources = false },
+	"windsurf": func(p *ClientProfile) { p.Resources = false },
+}
+
+// DetectClientProfile 根据 initialize 请求推导能力画像
+func DetectClientProfile(req *mcp.InitializeRequest) *ClientProfile {
+	p := &ClientProfile{
+		Name:            req.Params.ClientInfo.Name,
+		Version:         req.Params.ClientInfo.Version,
+		ProtocolVersion: req.Params.ProtocolVersion,
+		Markdown:        true,
+		Roots:           req.Params.Capabilities.Roots != nil,
+		Sampling:        req.Params.Capabilities.Sampling != nil,
+		Elicitation:     req.Params.Capabilities.Elicitation != nil,
+	}
+	// 协议版本为 YYYY-MM-DD，可直接按字符串比较
+	p.Resources = p.ProtocolVersion >= "2025-03-26"
+	p.StructuredContent = p.ProtocolVersion >= "2025-06-18"
+
+	name := strings.ToLower(p.Name)
+	for key, quirk := range clientQuirks {
+		if strings.Contains(name, key) {
+			quirk(p)
+		}
+	}
+
+	if declared, ok := req.Params.Capabilities.Experimental["mpm"].(map[string]any); ok {
+		override := func(key string, target *bool) {
+			if v, ok := declared[key].(bool); ok {
+				*target = v
+			}
+		}
+		override("resources", &p.Resources)
+		override("structured_content", &p.StructuredContent)
+		override("progress", &p.Progress)
+		override("markdown", &p.Markdown)
+	}
+	return p
+}
+
+// ClientProfile 当前会话的客户端画像；未完成初始化时返回 nil
+func (sm *SessionManager) ClientProfile() *ClientProfile {
+	return sm.client.Load()
+}
+
+// CapabilityHooks 在 initialize 完成后记录客户端画像
+func CapabilityHooks(sm *SessionManager) *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		p := DetectClientProfile(req)
+		if result != nil && result.ProtocolVersion != "" && result.ProtocolVersion < p.ProtocolVersion {
+			// 服务端协商到更旧的版本时以协商结果为准
+			p.ProtocolVersion = result.ProtocolVersion
+			p.StructuredContent = p.StructuredContent && p.ProtocolVersion >= "2025-06-18"
+		}
+		sm.client.Store(p)
+		fmt.Fprintf(os.Stderr, "[MCP-Go] 客户端 %s %s (protocol %s): resources=%v structured=%v markdown=%v\n",
+			fallback(p.Name, "unknown"), p.Version, p.ProtocolVersion, p.Resources, p.StructuredContent, p.Markdown)
+	})
+	return hooks
+}
+
+// CapabilityMiddleware 按客户端画像适配工具结果：记录进度支持，并为 JSON 结果附带 structuredContent
+func CapabilityMiddleware(sm *SessionManager) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			p := sm.ClientProfile()
+			if p != nil && !p.Progress && request.Params.Meta != nil && request.Params.Meta.ProgressToken != nil {
+				upgraded := *p
+				upgraded.Progress = true
+				sm.client.Store(&upgraded)
+				p = &upgraded
+			}
+			res, err := next(ctx, request)
+			if p == nil || !p.StructuredContent || res == nil || res.IsError || res.StructuredContent != nil || len(res.Content) != 1 {
+				return res, err
+			}
+			if tc, ok := res.Content[0].(mcp.TextContent); ok {
+				var obj map[string]any
+				if json.Unmarshal([]byte(tc.Text), &obj) == nil {
+					res.StructuredContent = obj
+				}
+			}
+			return res, err
+		}
+	}
+}
+
+// reportProgress 客户端请求了进度时发送 notifications/progress，否则静默
+func reportProgress(ctx context.Context, request mcp.CallToolRequest, progress, total int, message string) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+	_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+		"progressToken": request.Params.Meta.ProgressToken,
+		"progress":      progress,
+		"total":         total,
+		"message":       message,
+	})
+}
+
+// deliverLargeOutput 大输出的交付方式：客户端支持资源时以嵌入资源返回全文，否则保存到数据目录并提示查看
+// 两种情况都会写入数据目录，便于之后再次查看
+func deliverLargeOutput(sm *SessionManager, filename, content string) *mcp.CallToolResult {
+	if len(content) <= largeOutputThreshold {
+		return mcp.NewToolResultText(content)
+	}
+	mcpDataDir := core.DataDir(sm.ProjectRoot)
+	_ = os.MkdirAll(mcpDataDir, 0755)
+	outputPath := filepath.Join(mcpDataDir, filename)
+	saveErr := os.WriteFile(outputPath, []byte(content), 0644)
+
+	if p := sm.ClientProfile(); p != nil && p.Resources {
+		res := mcp.NewToolResultText(fmt.Sprintf("Map 内容较长 (%d chars)，全文见附带资源 %s", len(content), filename))
+		res.Content = append(res.Content, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      "file:///" + strings.TrimPrefix(filepath.ToSlash(outputPath), "/"),
+			MIMEType: "text/markdown",
+			Text:     content,
+		}))
+		return res
+	}
+	if saveErr != nil {
+		// 保存失败时降级为直接返回
+		return mcp.NewToolResultText(content)
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("⚠️ Map 内容较长 (%d chars)，已自动保存到项目文件：\n👉 `%s`\n\n请使用 view_file 查看。", len(content), outputPath))
+}
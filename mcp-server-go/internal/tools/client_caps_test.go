@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func initRequest(name, protocol string, experimental map[string]any) *mcp.InitializeRequest {
+	req := &mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = protocol
+	req.Params.ClientInfo = mcp.Implementation{Name: name, Version: "1.0"}
+	req.Params.Capabilities.Experimental = experimental
+	return req
+}
+
+func TestDetectClientProfile(t *testing.T) {
+	p := DetectClientProfile(initRequest("claude-code", "2025-06-18", nil))
+	if !p.Resources || !p.StructuredContent || !p.Markdown {
+		t.Errorf("latest protocol profile = %+v", p)
+	}
+
+	p = DetectClientProfile(initRequest("legacy", "2024-11-05", nil))
+	if p.Resources || p.StructuredContent {
+		t.Errorf("old protocol should disable resources/structured: %+v", p)
+	}
+
+	p = DetectClientProfile(initRequest("codex-cli", "2025-06-18", nil))
+	if p.Markdown {
+		t.Error("codex quirk should disable markdown")
+	}
+
+	p = DetectClientProfile(initRequest("codex-cli", "2025-06-18", map[string]any{
+		"mpm": map[string]any{"markdown": true, "resources": false},
+	}))
+	if !p.Markdown || p.Resources {
+		t.Errorf("experimental override not applied: %+v", p)
+	}
+}
+
+func TestDeliverLargeOutput_AdaptsToClient(t *testing.T) {
+	sm := &SessionManager{ProjectRoot: t.TempDir()}
+	content := strings.Repeat("x", largeOutputThreshold+1)
+
+	res := deliverLargeOutput(sm, "map.md", content)
+	if len(res.Content) != 1 || !strings.Contains(res.Content[0].(mcp.TextContent).Text, "view_file") {
+		t.Errorf("without profile expected file hint, got %+v", res.Content)
+	}
+
+	sm.client.Store(&ClientProfile{Resources: true})
+	res = deliverLargeOutput(sm, "map.md", content)
+	if len(res.Content) != 2 {
+		t.Fatalf("expected text + embedded resource, got %d items", len(res.Content))
+	}
+	er, ok := res.Content[1].(mcp.EmbeddedResource)
+	if !ok || er.Resource.(mcp.TextResourceContents).Text != content {
+		t.Errorf("embedded resource missing full content")
+	}
+}
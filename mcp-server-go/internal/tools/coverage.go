@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"fmt"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// ========== 测试覆盖率叠加 (project_map / code_impact) ==========
+
+// loadProjectCoverage 读取项目覆盖率（settings 中的 coverage_files 或自动发现）。没有覆盖率文件或解析失败时返回 nil
+func loadProjectCoverage(sm *SessionManager) *services.CoverageData {
+	cov, err := services.LoadCoverage(sm.ProjectRoot, core.LoadProjectSettings(sm.ProjectRoot).Index.CoverageFiles)
+	if err != nil {
+		return nil
+	}
+	return cov
+}
+
+// impactCoverageWarning 目标符号完全没有测试覆盖时给出警告，并追加到修改清单
+func impactCoverageWarning(g *services.CallGraph, targets []int, cov *services.CoverageData, symbol string, r *services.ImpactResult) string {
+	if cov == nil || len(targets) == 0 {
+		return ""
+	}
+	measured := false
+	for _, id := range targets {
+		s := g.Symbols[id]
+		pct, ok := cov.RangePct(s.FilePath, s.LineStart, s.LineEnd)
+		if !ok || s.LineEnd < s.LineStart {
+			continue
+		}
+		if pct > 0 {
+			return ""
+		}
+		measured = true
+	}
+	if !measured {
+		return ""
+	}
+	msg := fmt.Sprintf("`%s` 测试覆盖率 0%%，修改前先补测试", symbol)
+	if r != nil {
+		r.ModificationChecklist = append(r.ModificationChecklist, msg)
+	}
+	return fmt.Sprintf("🧪 **覆盖率**: %s\n> 覆盖率来自 %s（%s生成）。\n\n", msg, cov.Sources[0], cov.CoverageAge())
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DeadCodeArgs 死代码检测参数
+type DeadCodeArgs struct {
+	Scope           string `json:"scope" jsonschema:"description=只检查该目录/文件 (留空=整个项目)"`
+	MinConfidence   string `json:"min_confidence" jsonschema:"default=medium,enum=high,enum=medium,enum=low,description=最低置信度"`
+	Limit           int    `json:"limit" jsonschema:"default=100,description=最多列出的候选数"`
+	IncludeVendored bool   `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件 (默认排除)"`
+}
+
+func wrapDeadCode(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args DeadCodeArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		minConf := strings.ToLower(strings.TrimSpace(args.MinConfidence))
+		switch minConf {
+		case "":
+			minConf = services.DeadConfidenceMedium
+		case services.DeadConfidenceHigh, services.DeadConfidenceMedium, services.DeadConfidenceLow:
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("未知置信度: %s（可用: high, medium, low）", args.MinConfidence)), nil
+		}
+		limit := args.Limit
+		if limit <= 0 {
+			limit = 100
+		}
+
+		_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		g, err := ai.LoadCallGraph(sm.ProjectRoot)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("加载调用图失败: %v", err)), nil
+		}
+		g.ExcludePaths(resolvePathTagger(sm, args.IncludeVendored))
+		report := g.DeadCode(args.Scope, minConf, limit)
+		return deliverLargeOutput(sm, "dead_code.md", renderDeadCode(report, args.Scope, minConf)), nil
+	}
+}
+
+var deadConfidenceMark = map[string]string{
+	services.DeadConfidenceHigh:   "🔴 high",
+	services.DeadConfidenceMedium: "🟡 medium",
+	services.DeadConfidenceLow:    "⚪ low",
+}
+
+// renderDeadCode 按目录渲染死代码候选
+func renderDeadCode(r *services.DeadCodeReport, scope, minConf string) string {
+	var sb strings.Builder
+	sb.WriteString("### 💀 死代码检测\n\n")
+	if strings.TrimSpace(scope) != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n", scope))
+	}
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 个符号中 %d 个无调用方 (high %d / medium %d / low %d，最低置信度 %s)\n\n",
+		r.TotalSymbols, r.Total, r.Counts[services.DeadConfidenceHigh], r.Counts[services.DeadConfidenceMedium],
+		r.Counts[services.DeadConfidenceLow], minConf))
+	if r.Total == 0 {
+		sb.WriteString("✅ 未发现候选。\n")
+		return sb.String()
+	}
+	sb.WriteString("> 已排除 main/init、测试函数、HTTP 处理器等入口。经反射、字符串注册或函数值传递的调用在调用图中不可见，删除前请用 code_search 复核。\n")
+
+	for _, d := range r.Dirs {
+		sb.WriteString(fmt.Sprintf("\n#### 📁 %s (%d)\n", d.Dir, len(d.Items)))
+		for _, it := range d.Items {
+			sb.WriteString(fmt.Sprintf("- %s %s `%s` @ %s:%d — %s\n", deadConfidenceMark[it.Confidence], it.Type, it.Name, it.FilePath, it.Line, it.Reason))
+		}
+	}
+	if r.Omitted > 0 {
+		sb.WriteString(fmt.Sprintf("\n... 另有 %d 个候选未列出，可缩小 scope 或调大 limit\n", r.Omitted))
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DependencyGraphArgs 依赖图导出参数
+type DependencyGraphArgs struct {
+	Scope           string `json:"scope" jsonschema:"description=只保留至少一端位于该目录/文件下的依赖 (留空=整个项目)"`
+	Level           string `json:"level" jsonschema:"default=module,enum=module,enum=file,description=聚合粒度：module=目录，file=文件"`
+	Format          string `json:"format" jsonschema:"default=mermaid,enum=mermaid,enum=dot,description=输出格式"`
+	MaxEdges        int    `json:"max_edges" jsonschema:"default=60,description=最多保留的边数 (按调用数降序)"`
+	Output          string `json:"output" jsonschema:"description=同时写入文件 (相对项目根目录)，留空则只返回文本"`
+	IncludeVendored bool   `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件 (默认排除)"`
+}
+
+func wrapDependencyGraph(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args DependencyGraphArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+
+		format := strings.ToLower(strings.TrimSpace(args.Format))
+		if format == "" {
+			format = "mermaid"
+		}
+		if format != "mermaid" && format != "dot" {
+			return mcp.NewToolResultError(fmt.Sprintf("不支持的格式: %s（可用: mermaid, dot）", args.Format)), nil
+		}
+		level := strings.ToLower(strings.TrimSpace(args.Level))
+		if level != "" && level != "module" && level != "file" {
+			return mcp.NewToolResultError(fmt.Sprintf("不支持的粒度: %s（可用: module, file）", args.Level)), nil
+		}
+		maxEdges := args.MaxEdges
+		if maxEdges <= 0 {
+			maxEdges = 60
+		}
+
+		_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		g, err := ai.LoadCallGraph(sm.ProjectRoot)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("加载调用图失败: %v", err)), nil
+		}
+		g.ExcludePaths(resolvePathTagger(sm, args.IncludeVendored))
+		dg := g.Dependencies(level, args.Scope, maxEdges)
+		if len(dg.Edges) == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("范围 `%s` 内没有跨%s的调用依赖。", fallback(args.Scope, "."), levelLabel(dg.Level))), nil
+		}
+
+		diagram, fence := dg.Mermaid(), "mermaid"
+		if format == "dot" {
+			diagram, fence = dg.DOT(), "dot"
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("### 🕸️ %s级依赖图\n\n", levelLabel(dg.Level)))
+		if s := strings.TrimSpace(args.Scope); s != "" {
+			sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n", s))
+		}
+		sb.WriteString(fmt.Sprintf("**节点**: %d | **依赖**: %d", len(dg.Nodes), len(dg.Edges)))
+		if dg.Dropped > 0 {
+			sb.WriteString(fmt.Sprintf(" (另有 %d 条较弱的依赖被省略，可调大 max_edges 或缩小 scope)", dg.Dropped))
+		}
+		sb.WriteString("\n边上的数字为聚合的函数调用数。\n")
+
+		if out := strings.TrimSpace(args.Output); out != "" {
+			if !filepath.IsAbs(out) {
+				out = filepath.Join(sm.ProjectRoot, out)
+			}
+			if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("创建目录失败: %v", err)), nil
+			}
+			if err := os.WriteFile(out, []byte(diagram), 0644); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("写入依赖图失败: %v", err)), nil
+			}
+			sb.WriteString(fmt.Sprintf("**已保存**: %s\n", filepath.ToSlash(out)))
+		}
+		sb.WriteString(fmt.Sprintf("\n```%s\n%s```\n", fence, diagram))
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+func levelLabel(level string) string {
+	if level == "file" {
+		return "文件"
+	}
+	return "模块"
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DepsAuditArgs 依赖漏洞审计参数
+type DepsAuditArgs struct {
+	Dir      string `json:"dir" jsonschema:"description=只审计该目录下的清单 (相对项目根，留空=全部)"`
+	SkipMemo bool   `json:"skip_memo" jsonschema:"description=不自动记录审计摘要备忘"`
+	Limit    int    `json:"limit" jsonschema:"default=50,description=每个清单最多列出的漏洞数"`
+}
+
+// depsAuditResult 单个清单的审计结果
+type depsAuditResult struct {
+	Manifest services.Manifest
+	Auditor  services.Auditor
+	Status   string // ok / missing / denied / error
+	Detail   string
+	Vulns    []services.Vulnerability
+}
+
+var vulnSeverityMark = map[string]string{
+	services.VulnSeverityCritical: "🟣 critical",
+	services.VulnSeverityHigh:     "🔴 high",
+	services.VulnSeverityMedium:   "🟡 medium",
+	services.VulnSeverityLow:      "⚪ low",
+	services.VulnSeverityUnknown:  "❔ unknown",
+}
+
+func wrapDepsAudit(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args DepsAuditArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+
+		dir := strings.Trim(strings.ReplaceAll(strings.TrimSpace(args.Dir), "\\", "/"), "/")
+		var manifests []services.Manifest
+		for _, m := range services.DetectManifests(sm.ProjectRoot) {
+			if dir == "" || dir == "." || m.Dir == dir || strings.HasPrefix(m.Dir, dir+"/") {
+				manifests = append(manifests, m)
+			}
+		}
+		if len(manifests) == 0 {
+			return mcp.NewToolResultError("未找到依赖清单 (go.mod / package.json / requirements.txt / Cargo.toml)"), nil
+		}
+
+		results := make([]depsAuditResult, 0, len(manifests))
+		for _, m := range manifests {
+			results = append(results, runDepsAudit(ctx, sm, m))
+		}
+
+		content := renderDepsAudit(results, args.Limit)
+		if !args.SkipMemo && sm.Memory != nil {
+			head, _ := memoGitContext(sm.ProjectRoot, false)
+			files := make([]string, len(results))
+			for i, r := range results {
+				files[i] = r.Manifest.File
+			}
+			if _, err := sm.Memory.AddMemos(ctx, []core.Memo{{
+				Category:  "安全",
+				Entity:    "deps_audit",
+				Act:       "依赖漏洞审计",
+				Path:      strings.Join(files, ","),
+				Content:   depsAuditSummary(results),
+				GitCommit: head,
+			}}); err != nil {
+				content += fmt.Sprintf("\n⚠️ 记录审计备忘失败: %v\n", err)
+			} else {
+				content += "\n📝 审计摘要已记入备忘。\n"
+			}
+		}
+		return deliverLargeOutput(sm, "deps_audit.md", content), nil
+	}
+}
+
+// runDepsAudit 经 CommandRunner 执行审计器；审计器发现漏洞时多以非零退出，以能否解析输出为准
+func runDepsAudit(ctx context.Context, sm *SessionManager, m services.Manifest) depsAuditResult {
+	r := depsAuditResult{Manifest: m, Auditor: services.AuditorFor(m)}
+	if _, err := exec.LookPath(r.Auditor.Name); err != nil {
+		r.Status, r.Detail = "missing", fmt.Sprintf("未安装 %s（%s）", r.Auditor.Name, r.Auditor.Install)
+		return r
+	}
+	res, err := core.NewCommandRunner(sm.ProjectRoot).Run(ctx, core.CommandRequest{
+		Args:   r.Auditor.Args,
+		Dir:    m.Dir,
+		Source: "deps_audit:" + m.File,
+	})
+	switch {
+	case errors.Is(err, core.ErrCommandDenied):
+		r.Status, r.Detail = "denied", fmt.Sprintf("%v（在 settings.json commands.allow 中加入 %q）", err, strings.Join(r.Auditor.Args[:2], " "))
+		return r
+	case err != nil:
+		r.Status, r.Detail = "error", err.Error()
+		return r
+	}
+	vulns, perr := services.ParseAuditOutput(m.Ecosystem, res.Output)
+	if perr != nil {
+		r.Status = "error"
+		r.Detail = fmt.Sprintf("退出码 %d，%v", res.ExitCode, perr)
+		return r
+	}
+	r.Status, r.Vulns = "ok", vulns
+	return r
+}
+
+func countVulnSeverity(vulns []services.Vulnerability) map[string]int {
+	counts := make(map[string]int)
+	for _, v := range vulns {
+		counts[v.Severity]++
+	}
+	return counts
+}
+
+func formatVulnCounts(counts map[string]int) string {
+	var parts []string
+	for _, sev := range []string{services.VulnSeverityCritical, services.VulnSeverityHigh, services.VulnSeverityMedium, services.VulnSeverityLow, services.VulnSeverityUnknown} {
+		if counts[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", sev, counts[sev]))
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+func renderDepsAudit(results []depsAuditResult, limit int) string {
+	var sb strings.Builder
+	sb.WriteString("### 🧪 依赖漏洞审计\n\n")
+	total := 0
+	for _, r := range results {
+		total += len(r.Vulns)
+	}
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 个清单，%d 个漏洞\n", len(results), total))
+
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("\n#### 📦 %s (%s)\n", r.Manifest.File, r.Auditor.Name))
+		switch r.Status {
+		case "missing", "denied":
+			sb.WriteString("⚠️ 未审计: " + r.Detail + "\n")
+			continue
+		case "error":
+			sb.WriteString("❌ 审计失败: " + truncateRunes(r.Detail, 300) + "\n")
+			continue
+		}
+		if len(r.Vulns) == 0 {
+			sb.WriteString("✅ 未发现已知漏洞\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%d 个漏洞: %s\n\n", len(r.Vulns), formatVulnCounts(countVulnSeverity(r.Vulns))))
+		sb.WriteString("| 严重程度 | 包 | 当前 | 修复版本 | ID | 说明 |\n|---|---|---|---|---|---|\n")
+		for i, v := range r.Vulns {
+			if i == limit {
+				sb.WriteString(fmt.Sprintf("\n... 另有 %d 个，提高 limit 查看\n", len(r.Vulns)-i))
+				break
+			}
+			summary := truncateRunes(strings.ReplaceAll(v.Summary, "|", "/"), 80)
+			if v.Reachable {
+				summary = "⚡ 代码已调用 · " + summary
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+				vulnSeverityMark[v.Severity], v.Package, fallback(v.Installed, "-"), fallback(v.FixedIn, "无"), fallback(v.ID, "-"), summary))
+		}
+	}
+	return sb.String()
+}
+
+// depsAuditSummary 备忘内容：每个清单一行
+func depsAuditSummary(results []depsAuditResult) string {
+	var lines []string
+	for _, r := range results {
+		switch r.Status {
+		case "ok":
+			if len(r.Vulns) == 0 {
+				lines = append(lines, fmt.Sprintf("%s: 无已知漏洞", r.Manifest.File))
+				continue
+			}
+			line := fmt.Sprintf("%s: %d 个漏洞 (%s)", r.Manifest.File, len(r.Vulns), formatVulnCounts(countVulnSeverity(r.Vulns)))
+			top := r.Vulns[0]
+			lines = append(lines, line+fmt.Sprintf("，最严重 %s %s → %s", top.Package, top.ID, fallback(top.FixedIn, "暂无修复")))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: 未审计 (%s)", r.Manifest.File, truncateRunes(r.Detail, 120)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// ========== 文档模式 (无代码栈的设计/文档仓库) ==========
+
+// renderDocsMap 以标题大纲代替符号地图
+func renderDocsMap(idx *services.DocsIndex, scope string) string {
+	var sb strings.Builder
+	sb.WriteString("### 🗺️ 项目地图 (Docs)\n\n")
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 文档 | %d 标题 | %d 内部链接 | %d 外部链接\n\n",
+		len(idx.Files), len(idx.Headings), len(idx.Links), idx.ExternalLinks))
+	if strings.TrimSpace(scope) != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", scope))
+	}
+	sb.WriteString("> 未检测到代码技术栈，已切换文档模式：标题视为符号，文档间链接视为调用。\n\n")
+
+	byFile := make(map[string][]services.DocHeading)
+	for _, h := range idx.Headings {
+		byFile[h.File] = append(byFile[h.File], h)
+	}
+	for _, file := range idx.Files {
+		in := len(idx.Incoming(file))
+		out := len(idx.Outgoing(file))
+		sb.WriteString(fmt.Sprintf("📄 **%s** (入链 %d / 出链 %d)\n", file, in, out))
+		for _, h := range byFile[file] {
+			if h.Level > 3 {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s- %s `L%d`\n", strings.Repeat("  ", h.Level-1), h.Title, h.Line))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// traceDocsFlow 沿文档链接追踪：backward=谁引用了该文档，forward=该文档引用了谁
+func traceDocsFlow(idx *services.DocsIndex, entry, direction string, maxNodes int) string {
+	var sb strings.Builder
+	sb.WriteString("### 🔀 文档链路 (Docs)\n\n")
+	sb.WriteString(fmt.Sprintf("**入口**: `%s`\n\n", entry))
+
+	walk := func(next func(string) []services.DocLink, target func(services.DocLink) string, title string) {
+		sb.WriteString(fmt.Sprintf("#### %s\n\n", title))
+		seen := map[string]bool{entry: true}
+		frontier := []string{entry}
+		count := 0
+		for depth := 1; depth <= 3 && len(frontier) > 0 && count < maxNodes; depth++ {
+			var nextFrontier []string
+			for _, file := range frontier {
+				links := next(file)
+				sort.Slice(links, func(i, j int) bool { return target(links[i]) < target(links[j]) })
+				for _, l := range links {
+					if count >= maxNodes {
+						break
+					}
+					other := target(l)
+					ref := other
+					if l.Anchor != "" {
+						ref += "#" + l.Anchor
+					}
+					ctx := ""
+					if l.Heading != "" {
+						ctx = fmt.Sprintf(" (§ %s)", l.Heading)
+					}
+					sb.WriteString(fmt.Sprintf("%s- [d%d] %s:%d%s → %s\n", strings.Repeat("  ", depth-1), depth, l.From, l.Line, ctx, ref))
+					count++
+					if !seen[other] {
+						seen[other] = true
+						nextFrontier = append(nextFrontier, other)
+					}
+				}
+			}
+			frontier = nextFrontier
+		}
+		if count == 0 {
+			sb.WriteString("- (无)\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if direction == "backward" || direction == "both" {
+		walk(idx.Incoming, func(l services.DocLink) string { return l.From }, "⬆️ 引用方 (修改/移动前需同步更新)")
+	}
+	if direction == "forward" || direction == "both" {
+		walk(idx.Outgoing, func(l services.DocLink) string { return l.Target }, "⬇️ 依赖文档")
+	}
+
+	var broken []string
+	for _, l := range idx.Links {
+		if !idx.HasFile(l.Target) {
+			broken = append(broken, fmt.Sprintf("%s:%d → %s", l.From, l.Line, l.Target))
+		}
+	}
+	if len(broken) > 0 {
+		sb.WriteString(fmt.Sprintf("#### ⚠️ 失效链接 (%d)\n\n", len(broken)))
+		for i, b := range broken {
+			if i >= 20 {
+				sb.WriteString(fmt.Sprintf("- ... 另有 %d 条\n", len(broken)-i))
+				break
+			}
+			sb.WriteString("- " + b + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// resolveDocsEntry 将 flow_trace 的 file_path / symbol_name 解析为文档路径
+func resolveDocsEntry(idx *services.DocsIndex, filePath, symbolName string) (string, bool) {
+	if strings.TrimSpace(filePath) != "" {
+		fp := path.Clean(strings.ReplaceAll(strings.TrimSpace(filePath), "\\", "/"))
+		return fp, idx.HasFile(fp)
+	}
+	if h := idx.FindHeading(symbolName); h != nil {
+		return h.File, true
+	}
+	return "", false
+}
+
+// applyDocsGuardrails 文档仓库的任务约束
+func applyDocsGuardrails(g *Guardrails) {
+	g.Critical = append(g.Critical, "LINK_INTEGRITY: 重命名/移动文档或标题前，先用 flow_trace(file_path=...) 查看入链并同步更新引用")
+	g.Advisory = append(g.Advisory,
+		"DOCS_MODE: 项目无代码栈，project_map/flow_trace 以标题为符号、文档链接为调用",
+		"STABLE_ANCHORS: 尽量保持已有标题文本不变，避免外部锚点失效")
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// SuggestFactsArgs 候选事实参数
+type SuggestFactsArgs struct {
+	Action         string `json:"action" jsonschema:"default=list,enum=list,enum=scan,enum=accept,enum=dismiss,description=操作类型"`
+	ID             int64  `json:"id" jsonschema:"description=accept/dismiss 的候选 ID"`
+	Type           string `json:"type" jsonschema:"description=accept 时覆盖事实类型 (默认沿用候选类型)"`
+	Summarize      string `json:"summarize" jsonschema:"description=accept 时覆盖事实描述 (默认沿用候选原句)"`
+	MinOccurrences int    `json:"min_occurrences" jsonschema:"default=2,description=至少在多少条不同 memo 中出现"`
+}
+
+func wrapSuggestFacts(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args SuggestFactsArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化，请先执行 initialize_project。"), nil
+		}
+		if res := requireFeature(sm, "fact_suggest"); res != nil {
+			return res, nil
+		}
+
+		switch action := strings.ToLower(fallback(strings.TrimSpace(args.Action), "list")); action {
+		case "list", "scan":
+			pending, err := sm.Memory.ListFactSuggestions(ctx, "pending")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询候选失败: %v", err)), nil
+			}
+			added := -1
+			if action == "scan" || len(pending) == 0 {
+				if added, err = sm.Memory.RefreshFactSuggestions(ctx, args.MinOccurrences); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("扫描 memo 失败: %v", err)), nil
+				}
+				if pending, err = sm.Memory.ListFactSuggestions(ctx, "pending"); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("查询候选失败: %v", err)), nil
+				}
+			}
+			return mcp.NewToolResultText(renderFactSuggestions(pending, added)), nil
+
+		case "accept", "dismiss":
+			if args.ID <= 0 {
+				return mcp.NewToolResultError(action + " 需要提供 id"), nil
+			}
+			sug, err := sm.Memory.GetFactSuggestion(ctx, args.ID)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询候选失败: %v", err)), nil
+			}
+			if sug == nil {
+				return mcp.NewToolResultError(fmt.Sprintf("候选不存在: %d", args.ID)), nil
+			}
+			if sug.Status != "pending" {
+				return mcp.NewToolResultError(fmt.Sprintf("候选 %d 已处理 (%s)", sug.ID, sug.Status)), nil
+			}
+			if action == "dismiss" {
+				if err := sm.Memory.SetFactSuggestionStatus(ctx, sug.ID, "dismissed"); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("更新候选失败: %v", err)), nil
+				}
+				return mcp.NewToolResultText(fmt.Sprintf("🙈 已忽略候选 %d，之后不再提示", sug.ID)), nil
+			}
+
+			factType := fallback(strings.TrimSpace(args.Type), sug.Type)
+			summary := fallback(strings.TrimSpace(args.Summarize), sug.Summary)
+			id, err := sm.Memory.SaveFact(ctx, factType, summary)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("保存事实失败: %v", err)), nil
+			}
+			if err := sm.Memory.SetFactSuggestionStatus(ctx, sug.ID, "accepted"); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("更新候选失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("✅ 已采纳为事实 (ID: %d): [%s] %s", id, factType, summary)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知操作: %s (支持 list/scan/accept/dismiss)", args.Action)), nil
+	}
+}
+
+// renderFactSuggestions added < 0 表示本次未扫描
+func renderFactSuggestions(pending []core.FactSuggestion, added int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 💡 候选事实 (%d)\n\n", len(pending)))
+	if added >= 0 {
+		sb.WriteString(fmt.Sprintf("本次扫描新增 %d 条。\n\n", added))
+	}
+	if len(pending) == 0 {
+		sb.WriteString("暂无候选：memo 中没有在多条记录里重复出现的规则性说法。\n")
+		return sb.String()
+	}
+	for _, s := range pending {
+		sb.WriteString(fmt.Sprintf("- **[%d]** [%s] %s _(出现于 %d 条 memo: %s)_\n",
+			s.ID, s.Type, s.Summary, s.Occurrences, formatMemoIDs(s.MemoIDs)))
+	}
+	sb.WriteString("\n> 采纳: `suggest_facts(action=\"accept\", id=N)`；忽略: `suggest_facts(action=\"dismiss\", id=N)`")
+	return sb.String()
+}
+
+func formatMemoIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for i, id := range ids {
+		if i == 5 {
+			parts = append(parts, "…")
+			break
+		}
+		parts = append(parts, fmt.Sprintf("#%d", id))
+	}
+	return strings.Join(parts, " ")
+}
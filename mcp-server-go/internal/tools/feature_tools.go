@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// FeaturesArgs 功能开关参数
+type FeaturesArgs struct {
+	Action string `json:"action" jsonschema:"default=list,enum=list,enum=enable,enum=disable,description=操作类型"`
+	Name   string `json:"name" jsonschema:"description=功能名 (enable/disable 必填)"`
+}
+
+// requireFeature 功能未启用时返回提示启用方式的错误结果
+func requireFeature(sm *SessionManager, name string) *mcp.CallToolResult {
+	if core.FeatureEnabled(sm.ProjectRoot, name) {
+		return nil
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("实验性功能 %s 未启用。启用: features(action=\"enable\", name=\"%s\")", name, name))
+}
+
+func wrapFeatures(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args FeaturesArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project。"), nil
+		}
+
+		switch action := strings.ToLower(fallback(strings.TrimSpace(args.Action), "list")); action {
+		case "list":
+			return mcp.NewToolResultText(renderFeatureList(core.LoadProjectSettings(sm.ProjectRoot))), nil
+		case "enable", "disable":
+			name := strings.TrimSpace(args.Name)
+			if name == "" {
+				return mcp.NewToolResultError(action + " 需要提供 name"), nil
+			}
+			if err := core.SetFeatureFlag(sm.ProjectRoot, name, action == "enable"); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%v（features(action=\"list\") 查看可用功能）", err)), nil
+			}
+			state := "已启用"
+			if action == "disable" {
+				state = "已关闭"
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🚩 %s %s（写入 .mcp-config/settings.json 的 features 段，立即生效）", name, state)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知操作: %s (支持 list/enable/disable)", args.Action)), nil
+	}
+}
+
+func renderFeatureList(settings *core.ProjectSettings) string {
+	var sb strings.Builder
+	sb.WriteString("### 🚩 功能开关\n\n")
+	for _, f := range core.KnownFeatures {
+		mark := "⬜"
+		if settings.FeatureEnabled(f.Name) {
+			mark = "✅"
+		}
+		source := "默认"
+		if _, ok := settings.Features[f.Name]; ok {
+			source = "项目配置"
+		}
+		sb.WriteString(fmt.Sprintf("- %s **%s** — %s _(%s)_\n", mark, f.Name, f.Description, source))
+	}
+	for name := range settings.Features {
+		if _, ok := core.LookupFeature(name); !ok {
+			sb.WriteString(fmt.Sprintf("- ⚠️ **%s** — 未知功能（settings.json 中的多余配置）\n", name))
+		}
+	}
+	sb.WriteString("\n> 启用: `features(action=\"enable\", name=\"...\")`；关闭: `features(action=\"disable\", name=\"...\")`")
+	return sb.String()
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ClaimFilesArgs 文件软锁参数
+type ClaimFilesArgs struct {
+	Mode       string   `json:"mode" jsonschema:"default=claim,enum=claim,enum=release,enum=list,description=操作模式"`
+	TaskID     string   `json:"task_id" jsonschema:"description=持有锁的任务ID (claim/release 模式必填)"`
+	Files      []string `json:"files" jsonschema:"description=文件路径列表 (release 模式不传则释放该任务全部锁)"`
+	TTLMinutes int      `json:"ttl_minutes" jsonschema:"default=30,description=锁有效期(分钟)，到期自动失效"`
+}
+
+// sessionHolder 当前 MCP 进程的持有者标识
+func sessionHolder() string {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "local"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+func wrapClaimFiles(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args ClaimFilesArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化"), nil
+		}
+
+		switch args.Mode {
+		case "", "claim":
+			if args.TaskID == "" || len(args.Files) == 0 {
+				return mcp.NewToolResultError("claim 模式需要 task_id 和 files 参数"), nil
+			}
+			ttl := time.Duration(args.TTLMinutes) * time.Minute
+			claimed, conflicts, err := sm.Memory.ClaimFiles(ctx, args.TaskID, sessionHolder(), args.Files, ttl)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("声明文件锁失败: %v", err)), nil
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("🔒 已为任务 %s 声明 %d 个文件软锁\n", args.TaskID, len(claimed)))
+			for _, p := range claimed {
+				sb.WriteString(fmt.Sprintf("  - %s\n", p))
+			}
+			sb.WriteString(renderFileLockConflicts(conflicts))
+			return mcp.NewToolResultText(sb.String()), nil
+
+		case "release":
+			if args.TaskID == "" {
+				return mcp.NewToolResultError("release 模式需要 task_id 参数"), nil
+			}
+			n, err := sm.Memory.ReleaseFileLocks(ctx, args.TaskID, args.Files)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("释放文件锁失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🔓 已释放任务 %s 的 %d 个文件锁。", args.TaskID, n)), nil
+
+		case "list":
+			locks, err := sm.Memory.ListFileLocks(ctx)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询文件锁失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(renderFileLockList(locks)), nil
+
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("未知模式: %s", args.Mode)), nil
+		}
+	}
+}
+
+// sessionTaskID 本会话唯一运行中的任务链 ID；没有或有多条时返回空
+func sessionTaskID(sm *SessionManager) string {
+	taskID := ""
+	for id, chain := range sm.TaskChainsV3 {
+		if chain.Status != "running" {
+			continue
+		}
+		if taskID != "" {
+			return ""
+		}
+		taskID = id
+	}
+	return taskID
+}
+
+// warnFileLocks 检查文件是否被其他任务持有，返回警告文本（无冲突时为空）
+func warnFileLocks(ctx context.Context, sm *SessionManager, taskID string, paths []string) string {
+	if sm.Memory == nil || len(paths) == 0 {
+		return ""
+	}
+	conflicts, err := sm.Memory.CheckFileLocks(ctx, paths, taskID)
+	if err != nil {
+		return ""
+	}
+	return renderFileLockConflicts(conflicts)
+}
+
+func renderFileLockConflicts(conflicts []core.FileLock) string {
+	if len(conflicts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n⚠️ 以下 %d 个文件正被其他任务编辑（软锁，仅提醒）：\n", len(conflicts)))
+	for _, l := range conflicts {
+		sb.WriteString(fmt.Sprintf("  - %s ← 任务 %s (%s, 到期 %s)\n", l.Path, l.TaskID, l.Holder, formatLockExpiry(l.ExpiresAt)))
+	}
+	sb.WriteString("  → 请与持有者协调，或等待锁过期后再修改\n")
+	return sb.String()
+}
+
+func renderFileLockList(locks []core.FileLock) string {
+	if len(locks) == 0 {
+		return "当前没有生效的文件锁。"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 🔒 文件锁列表 (%d)\n\n", len(locks)))
+	for _, l := range locks {
+		sb.WriteString(fmt.Sprintf("- `%s` [Task: %s] %s (到期 %s)\n", l.Path, l.TaskID, l.Holder, formatLockExpiry(l.ExpiresAt)))
+	}
+	return sb.String()
+}
+
+func formatLockExpiry(expiresAt string) string {
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return expiresAt
+	}
+	return t.Local().Format("01-02 15:04")
+}
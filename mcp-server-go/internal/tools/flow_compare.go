@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// ========== flow_trace(symbol_names=[...])：多入口流程对比 ==========
+
+const (
+	flowCompareMaxEntries = 5
+	flowCompareMaxDepth   = 8
+	flowCompareListLimit  = 15
+)
+
+// flowEntryNames 合并 symbol_name 与 symbol_names（去空、去重，保持顺序）
+func flowEntryNames(args FlowTraceArgs) []string {
+	return mergeUniqueStrings(append([]string{args.SymbolName}, args.SymbolNames...))
+}
+
+// renderFlowComparison 共同下游（汇合点在前）、各入口独有依赖与分叉点
+func renderFlowComparison(g *services.CallGraph, snapshots []*flowTraceSnapshot) string {
+	entries := make([][]int, 0, len(snapshots))
+	labels := make([]string, 0, len(snapshots))
+	for _, snap := range snapshots {
+		ids := resolveFlowGraphIDs(g, snap.Node)
+		if len(ids) == 0 {
+			return fmt.Sprintf("#### 🔀 多入口对比\n_调用图中未找到入口 `%s`，无法对比。_\n\n", snap.Node.Name)
+		}
+		entries = append(entries, ids)
+		labels = append(labels, snap.Node.Name)
+	}
+	c := g.CompareFlows(entries, flowCompareMaxDepth)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("#### 🔀 多入口对比: %s\n", strings.Join(backtickAll(labels), " vs ")))
+	sb.WriteString(fmt.Sprintf("- 共同下游依赖: %d | 部分共享: %d | 追踪深度: %d\n", len(c.Shared), c.Partial, flowCompareMaxDepth))
+
+	if len(c.Shared) == 0 {
+		sb.WriteString("- 没有共同的下游依赖：这些入口的业务逻辑完全独立。\n")
+	} else {
+		sb.WriteString("- 共同依赖（汇合点在前，括号内为距各入口的调用深度）:\n")
+		for i, s := range c.Shared {
+			if i == flowCompareListLimit {
+				sb.WriteString(fmt.Sprintf("  - ... 另有 %d 个\n", len(c.Shared)-i))
+				break
+			}
+			depths := make([]string, len(s.Depths))
+			for j, d := range s.Depths {
+				depths[j] = fmt.Sprint(d)
+			}
+			sym := g.Symbols[s.ID]
+			sb.WriteString(fmt.Sprintf("  - `%s` %s:%d (%s)\n", sym.Name, sym.FilePath, sym.LineStart, strings.Join(depths, "/")))
+		}
+	}
+
+	for i, label := range labels {
+		sb.WriteString(fmt.Sprintf("- `%s` 分叉点: %s\n", label, flowSymbolList(g, c.Divergence[i], "无（直接调用均为共同依赖）")))
+		sb.WriteString(fmt.Sprintf("  - 独有依赖 (%d): %s\n", len(c.Unique[i]), flowSymbolList(g, c.Unique[i], "无")))
+	}
+	if len(c.Shared) > 0 {
+		sb.WriteString("- 💡 合并思路：共同依赖已是可复用的公共部分，重点比较各入口分叉点的实现差异。\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func flowSymbolList(g *services.CallGraph, ids []int, empty string) string {
+	if len(ids) == 0 {
+		return empty
+	}
+	names := make([]string, 0, flowCompareListLimit)
+	for i, id := range ids {
+		if i == flowCompareListLimit {
+			names = append(names, fmt.Sprintf("... 另有 %d 个", len(ids)-i))
+			break
+		}
+		names = append(names, "`"+g.Symbols[id].Name+"`")
+	}
+	return strings.Join(names, ", ")
+}
+
+func backtickAll(items []string) []string {
+	out := make([]string, len(items))
+	for i, s := range items {
+		out[i] = "`" + s + "`"
+	}
+	return out
+}
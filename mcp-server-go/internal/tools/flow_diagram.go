@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// ========== flow_trace(render="mermaid_sequence")：流程时序图 ==========
+// 以入口为中心，从调用图还原最长的 Top-N 条上游链（入口 → ... → 目标）与下游链（目标 → ... → 被调用者），
+// 渲染为 Mermaid sequenceDiagram，保存在数据目录 flow_diagrams/<入口>.mmd（每次覆盖为最新）。
+
+const (
+	flowRenderText     = "text"
+	flowRenderSequence = "mermaid_sequence"
+
+	flowDiagramMaxDepth = 6
+)
+
+// resolveFlowGraphIDs 在调用图中定位快照入口：同名符号优先取同文件的定义
+func resolveFlowGraphIDs(g *services.CallGraph, node *services.Node) []int {
+	ids := g.SymbolsByName(node.Name)
+	var sameFile []int
+	for _, id := range ids {
+		if g.Symbols[id].FilePath == node.FilePath {
+			sameFile = append(sameFile, id)
+		}
+	}
+	if len(sameFile) > 0 {
+		return sameFile
+	}
+	return ids
+}
+
+// renderFlowSequence 生成单个入口的 sequenceDiagram；没有任何调用链时返回空串
+func renderFlowSequence(g *services.CallGraph, targets []int, direction string, maxChains int) string {
+	type chain struct {
+		label string
+		ids   []int
+	}
+	var chains []chain
+	if direction != "forward" {
+		for i, p := range g.TraceImpact(targets, true, flowDiagramMaxDepth).Paths(g, maxChains) {
+			chains = append(chains, chain{fmt.Sprintf("上游链 %d", i+1), p})
+		}
+	}
+	if direction != "backward" {
+		for i, p := range g.TraceImpact(targets, false, flowDiagramMaxDepth).Paths(g, maxChains) {
+			chains = append(chains, chain{fmt.Sprintf("下游链 %d", i+1), p})
+		}
+	}
+	if len(chains) == 0 {
+		return ""
+	}
+
+	// 参与者按首次出现顺序声明，入口固定在最前
+	var sb strings.Builder
+	sb.WriteString("sequenceDiagram\n")
+	declared := make(map[int]bool)
+	declare := func(id int) {
+		if declared[id] {
+			return
+		}
+		declared[id] = true
+		s := g.Symbols[id]
+		sb.WriteString(fmt.Sprintf("    participant %s as %s\n", flowParticipantID(id), mermaidLabel(s.Name)))
+	}
+	for _, t := range targets {
+		declare(t)
+	}
+	for _, c := range chains {
+		for _, id := range c.ids {
+			declare(id)
+		}
+	}
+
+	seenEdge := make(map[[2]int]bool)
+	for _, c := range chains {
+		first, last := flowParticipantID(c.ids[0]), flowParticipantID(c.ids[len(c.ids)-1])
+		if first == last {
+			sb.WriteString(fmt.Sprintf("    Note over %s: %s\n", first, c.label))
+		} else {
+			sb.WriteString(fmt.Sprintf("    Note over %s,%s: %s\n", first, last, c.label))
+		}
+		for i := 0; i+1 < len(c.ids); i++ {
+			from, to := c.ids[i], c.ids[i+1]
+			arrow := "->>"
+			// 多条链共享的调用只在首次出现时画实线，之后用虚线表示复用
+			if seenEdge[[2]int{from, to}] {
+				arrow = "-->>"
+			}
+			seenEdge[[2]int{from, to}] = true
+			sb.WriteString(fmt.Sprintf("    %s%s%s: %s\n", flowParticipantID(from), arrow, flowParticipantID(to), mermaidLabel(g.Symbols[to].Name)))
+		}
+	}
+	return sb.String()
+}
+
+func flowParticipantID(id int) string {
+	return fmt.Sprintf("n%d", id)
+}
+
+// renderFlowDiagrams 为每个入口生成时序图并保存 .mmd，返回汇总 Markdown
+func renderFlowDiagrams(sm *SessionManager, g *services.CallGraph, snapshots []*flowTraceSnapshot, direction string, maxChains int) string {
+	var sb strings.Builder
+	sb.WriteString("### 🔄 业务流程时序图\n\n")
+	sb.WriteString(fmt.Sprintf("**方向**: %s | **每个方向最多链数**: %d | **最大深度**: %d\n\n", direction, maxChains, flowDiagramMaxDepth))
+	for _, snap := range snapshots {
+		n := snap.Node
+		sb.WriteString(fmt.Sprintf("#### 入口 `%s` (%s:%d)\n", n.Name, n.FilePath, n.LineStart))
+		targets := resolveFlowGraphIDs(g, n)
+		if len(targets) == 0 {
+			sb.WriteString("_调用图中未找到该入口（可能不是函数/方法），无法绘制时序图。_\n\n")
+			continue
+		}
+		diagram := renderFlowSequence(g, targets, direction, maxChains)
+		if diagram == "" {
+			sb.WriteString("_该入口在当前方向上没有项目内调用链。_\n\n")
+			continue
+		}
+		path := core.DataPath(sm.ProjectRoot, "flow_diagrams", safeFileName(n.Name)+".mmd")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			if err := os.WriteFile(path, []byte(diagram), 0644); err == nil {
+				sb.WriteString(fmt.Sprintf("已保存: `%s`\n", filepath.ToSlash(path)))
+			}
+		}
+		sb.WriteString("\n```mermaid\n" + diagram + "```\n\n")
+	}
+	sb.WriteString("实线为调用链中首次出现的调用，虚线表示与前面的链共享的调用。\n")
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"mcp-server-go/internal/services"
+)
+
+func TestRenderFlowSequence(t *testing.T) {
+	g := &services.CallGraph{
+		Symbols: map[int]*services.GraphSymbol{
+			1: {SymbolID: 1, Name: "main", Type: "function"},
+			2: {SymbolID: 2, Name: "handle", Type: "function"},
+			3: {SymbolID: 3, Name: "save", Type: "function"},
+			4: {SymbolID: 4, Name: "exec", Type: "function"},
+		},
+		Edges: map[int][]int{1: {2}, 2: {3}, 3: {4}},
+	}
+	diagram := renderFlowSequence(g, []int{2}, "both", 5)
+	for _, want := range []string{
+		"sequenceDiagram",
+		"participant n2 as handle",
+		"Note over n1,n2: 上游链 1",
+		"n1->>n2: handle",
+		"Note over n2,n4: 下游链 1",
+		"n2->>n3: save",
+		"n3->>n4: exec",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("missing %q in:\n%s", want, diagram)
+		}
+	}
+	if strings.Index(diagram, "participant n2") > strings.Index(diagram, "participant n1") {
+		t.Errorf("entry should be declared first:\n%s", diagram)
+	}
+	if renderFlowSequence(g, []int{1}, "backward", 5) != "" {
+		t.Error("main has no callers, backward diagram should be empty")
+	}
+}
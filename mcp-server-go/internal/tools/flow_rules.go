@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// ========== flow_trace 副作用 / 阶段识别规则 (.mcp-config/flow_rules.json) ==========
+//
+// 副作用按 类别 累加命中规则的权重，达到该类别阈值即标记；阶段命中任一规则即标记。
+// tokens 按完整分词匹配（db.Select → db, select），contains 按小写子串匹配（gin.context）。
+// 识别素材：入口与直接上下游的名称、限定名、文件路径，入口签名，以及入口源码中的调用名（含外部库调用）。
+//
+//	{
+//	  "packs": ["gin", "sqlx", "kafka"],
+//	  "replace_defaults": false,
+//	  "side_effects": [{"category": "payment", "tokens": ["stripe", "charge"], "weight": 2}],
+//	  "stages": [{"stage": "validate", "contains": ["shouldbind"]}],
+//	  "thresholds": {"network": 2}
+//	}
+//
+// 规则顺序：内置默认（replace_defaults=true 时跳过）→ packs → 项目自定义；文件每次调用重新读取。
+
+// FlowEffectRule 副作用规则
+type FlowEffectRule struct {
+	Category string   `json:"category"`
+	Tokens   []string `json:"tokens,omitempty"`
+	Contains []string `json:"contains,omitempty"`
+	Weight   int      `json:"weight,omitempty"` // 默认 2
+}
+
+// FlowStageRule 阶段规则
+type FlowStageRule struct {
+	Stage    string   `json:"stage"`
+	Tokens   []string `json:"tokens,omitempty"`
+	Contains []string `json:"contains,omitempty"`
+}
+
+// FlowRules flow_rules.json 内容；解析后 SideEffects/Stages 为合并后的完整规则
+type FlowRules struct {
+	Packs           []string         `json:"packs,omitempty"`
+	ReplaceDefaults bool             `json:"replace_defaults,omitempty"`
+	SideEffects     []FlowEffectRule `json:"side_effects,omitempty"`
+	Stages          []FlowStageRule  `json:"stages,omitempty"`
+	Thresholds      map[string]int   `json:"thresholds,omitempty"` // 类别 → 阈值，默认 2
+}
+
+const (
+	defaultFlowRuleWeight    = 2
+	defaultFlowRuleThreshold = 2
+)
+
+// defaultFlowRules 内置通用规则（与框架无关的英文分词启发式）
+var defaultFlowRules = FlowRules{
+	SideEffects: []FlowEffectRule{
+		{Category: "filesystem", Tokens: []string{"file", "path", "read", "write", "mkdir", "open", "close", "stat"}, Weight: 2},
+		{Category: "filesystem", Contains: []string{"filepath", "rename", "remove", "copy"}, Weight: 1},
+		{Category: "database", Tokens: []string{"db", "sql", "sqlite", "insert", "update", "delete", "commit", "transaction"}, Weight: 2},
+		{Category: "database", Tokens: []string{"query", "exec", "row", "rows"}, Weight: 1},
+		{Category: "network", Tokens: []string{"http", "https", "grpc", "tcp", "udp", "socket", "request", "response", "listen", "dial"}, Weight: 2},
+		{Category: "network", Contains: []string{"websocket", "endpoint", "url", "api"}, Weight: 1},
+		{Category: "process", Tokens: []string{"exec", "command", "spawn", "process", "fork", "subprocess"}, Weight: 2},
+		{Category: "process", Tokens: []string{"run", "cmd"}, Weight: 1},
+		{Category: "state", Tokens: []string{"state", "cache", "memory", "session", "lock", "mutex", "atomic"}, Weight: 2},
+		{Category: "state", Tokens: []string{"context"}, Weight: 1},
+	},
+	Stages: []FlowStageRule{
+		{Stage: "init", Contains: []string{"init", "setup", "new", "bootstrap", "load"}},
+		{Stage: "validate", Contains: []string{"validate", "check", "verify", "guard"}},
+		{Stage: "execute", Contains: []string{"run", "process", "handle", "execute", "build", "index"}},
+		{Stage: "query", Contains: []string{"query", "search", "map", "trace", "analyze"}},
+		{Stage: "persist", Contains: []string{"save", "write", "insert", "commit", "persist"}},
+	},
+	// 网络类词汇（request/response/api）在业务代码中很常见，要求更强的证据
+	Thresholds: map[string]int{"network": 3},
+}
+
+// flowRulePacks 内置语言/框架规则包
+var flowRulePacks = map[string]FlowRules{
+	"gin": {
+		SideEffects: []FlowEffectRule{{Category: "network", Contains: []string{"gin.context", "gin.engine", "gin.routergroup"}, Weight: 3}},
+		Stages: []FlowStageRule{
+			{Stage: "validate", Contains: []string{"shouldbind", "bindjson"}},
+			{Stage: "respond", Contains: []string{"c.json", "c.string", "abortwithstatus"}},
+		},
+	},
+	"echo": {
+		SideEffects: []FlowEffectRule{{Category: "network", Contains: []string{"echo.context", "echo.echo"}, Weight: 3}},
+		Stages:      []FlowStageRule{{Stage: "validate", Contains: []string{"c.bind", "c.validate"}}},
+	},
+	"nethttp": {
+		SideEffects: []FlowEffectRule{{Category: "network", Contains: []string{"http.responsewriter", "http.request", "http.client", "http.get", "http.post"}, Weight: 3}},
+	},
+	"sqlx": {
+		SideEffects: []FlowEffectRule{{Category: "database", Tokens: []string{"sqlx", "namedexec", "queryx", "queryrowx", "mustexec", "beginx"}, Weight: 2}},
+		Stages:      []FlowStageRule{{Stage: "persist", Tokens: []string{"namedexec", "mustexec"}}},
+	},
+	"gorm": {
+		SideEffects: []FlowEffectRule{{Category: "database", Contains: []string{"gorm."}, Tokens: []string{"gorm", "automigrate"}, Weight: 2}},
+		Stages:      []FlowStageRule{{Stage: "persist", Contains: []string{".create", ".save", ".updates"}}},
+	},
+	"redis": {
+		SideEffects: []FlowEffectRule{
+			{Category: "cache", Tokens: []string{"redis", "hset", "hget", "setex", "expire", "lpush", "rpop"}, Weight: 2},
+			{Category: "network", Tokens: []string{"redis"}, Weight: 1},
+		},
+	},
+	"kafka": {
+		SideEffects: []FlowEffectRule{
+			{Category: "messaging", Tokens: []string{"kafka", "sarama", "producer", "consumer", "produce", "writemessages", "readmessage", "sendmessage"}, Weight: 2},
+		},
+		Stages: []FlowStageRule{{Stage: "publish", Tokens: []string{"produce", "publish", "writemessages", "sendmessage"}}},
+	},
+	"grpc": {
+		SideEffects: []FlowEffectRule{{Category: "network", Contains: []string{"grpc.", "pb.", "unimplemented"}, Tokens: []string{"grpc", "protobuf"}, Weight: 3}},
+	},
+}
+
+func flowRulesPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".mcp-config", "flow_rules.json")
+}
+
+// loadFlowRules 合并内置默认、packs 与项目规则；配置无效时回退到内置默认并返回错误说明
+func loadFlowRules(projectRoot string) (*FlowRules, error) {
+	var custom FlowRules
+	if projectRoot != "" {
+		data, err := os.ReadFile(flowRulesPath(projectRoot))
+		if err != nil && !os.IsNotExist(err) {
+			return mergeFlowRules(FlowRules{}), err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &custom); err != nil {
+				return mergeFlowRules(FlowRules{}), fmt.Errorf("解析 flow_rules.json 失败: %w", err)
+			}
+		}
+	}
+	var unknown []string
+	for _, p := range custom.Packs {
+		if _, ok := flowRulePacks[strings.ToLower(strings.TrimSpace(p))]; !ok {
+			unknown = append(unknown, p)
+		}
+	}
+	rules := mergeFlowRules(custom)
+	if len(unknown) > 0 {
+		return rules, fmt.Errorf("flow_rules.json: 未知的 packs %v（可用: %s）", unknown, strings.Join(flowRulePackNames(), ", "))
+	}
+	return rules, nil
+}
+
+func mergeFlowRules(custom FlowRules) *FlowRules {
+	out := &FlowRules{Packs: custom.Packs, ReplaceDefaults: custom.ReplaceDefaults, Thresholds: make(map[string]int)}
+	layers := make([]FlowRules, 0, len(custom.Packs)+2)
+	if !custom.ReplaceDefaults {
+		layers = append(layers, defaultFlowRules)
+	}
+	for _, p := range custom.Packs {
+		if pack, ok := flowRulePacks[strings.ToLower(strings.TrimSpace(p))]; ok {
+			layers = append(layers, pack)
+		}
+	}
+	layers = append(layers, custom)
+	for _, l := range layers {
+		out.SideEffects = append(out.SideEffects, l.SideEffects...)
+		out.Stages = append(out.Stages, l.Stages...)
+		for k, v := range l.Thresholds {
+			out.Thresholds[k] = v
+		}
+	}
+	return out
+}
+
+func flowRulePackNames() []string {
+	names := make([]string, 0, len(flowRulePacks))
+	for name := range flowRulePacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flowBag 识别素材：小写全文 + 分词集合
+type flowBag struct {
+	joined string
+	tokens map[string]bool
+}
+
+func newFlowBag(parts []string) flowBag {
+	joined := strings.ToLower(strings.Join(parts, " "))
+	tokens := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(joined, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		tokens[t] = true
+	}
+	return flowBag{joined: joined, tokens: tokens}
+}
+
+func (b flowBag) matches(tokens, contains []string) bool {
+	for _, t := range tokens {
+		if b.tokens[strings.ToLower(t)] {
+			return true
+		}
+	}
+	for _, c := range contains {
+		if c != "" && strings.Contains(b.joined, strings.ToLower(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectSideEffects 按规则给入口打副作用标签（按类别首次出现的顺序输出）
+func detectSideEffects(rules *FlowRules, node *services.Node, related []services.CallerInfo, callees []string) []string {
+	if node == nil {
+		return nil
+	}
+	parts := []string{node.Name, node.QualifiedName, node.FilePath, node.Signature}
+	for _, c := range related {
+		parts = append(parts, c.Node.Name, c.Node.QualifiedName, c.Node.FilePath)
+	}
+	bag := newFlowBag(append(parts, callees...))
+
+	scores := make(map[string]int)
+	var order []string
+	for _, r := range rules.SideEffects {
+		if _, ok := scores[r.Category]; !ok {
+			scores[r.Category] = 0
+			order = append(order, r.Category)
+		}
+		if bag.matches(r.Tokens, r.Contains) {
+			w := r.Weight
+			if w <= 0 {
+				w = defaultFlowRuleWeight
+			}
+			scores[r.Category] += w
+		}
+	}
+	var types []string
+	for _, cat := range order {
+		threshold, ok := rules.Thresholds[cat]
+		if !ok {
+			threshold = defaultFlowRuleThreshold
+		}
+		if cat != "" && scores[cat] >= threshold {
+			types = append(types, cat)
+		}
+	}
+	return mergeUniqueStrings(types)
+}
+
+// detectStages 按规则推断入口所处的流程阶段（按规则顺序输出）
+func detectStages(rules *FlowRules, node *services.Node, related []services.CallerInfo, callees []string) []string {
+	if node == nil {
+		return nil
+	}
+	parts := []string{node.Name, node.QualifiedName}
+	for _, c := range related {
+		parts = append(parts, c.Node.Name, c.Node.QualifiedName)
+	}
+	bag := newFlowBag(append(parts, callees...))
+
+	var stages []string
+	for _, r := range rules.Stages {
+		if r.Stage != "" && bag.matches(r.Tokens, r.Contains) {
+			stages = append(stages, r.Stage)
+		}
+	}
+	return mergeUniqueStrings(stages)
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mcp-server-go/internal/services"
+)
+
+func TestFlowRules_PacksAndOverrides(t *testing.T) {
+	root := t.TempDir()
+	node := &services.Node{Name: "CreateOrder", FilePath: "api/order.go", Signature: "func (h *Handler) CreateOrder(c *gin.Context)"}
+	callees := []string{"c.ShouldBindJSON", "h.db.NamedExec", "h.producer.Produce", "stripe.Charge"}
+
+	rules, err := loadFlowRules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := detectSideEffects(rules, node, nil, callees), []string{"database"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("defaults = %v, want %v (framework calls need packs)", got, want)
+	}
+
+	cfg := `{
+		"packs": ["gin", "sqlx", "kafka"],
+		"side_effects": [{"category": "payment", "tokens": ["stripe"]}],
+		"stages": [{"stage": "charge", "contains": ["stripe.charge"]}]
+	}`
+	_ = os.MkdirAll(filepath.Join(root, ".mcp-config"), 0755)
+	if err := os.WriteFile(flowRulesPath(root), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err = loadFlowRules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := detectSideEffects(rules, node, nil, callees), []string{"database", "network", "messaging", "payment"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("side effects = %v, want %v", got, want)
+	}
+	if got, want := detectStages(rules, node, nil, callees), []string{"validate", "persist", "publish", "charge"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stages = %v, want %v", got, want)
+	}
+
+	_ = os.WriteFile(flowRulesPath(root), []byte(`{"packs": ["nope"], "replace_defaults": true}`), 0644)
+	rules, err = loadFlowRules(root)
+	if err == nil {
+		t.Error("unknown pack should be reported")
+	}
+	if len(rules.SideEffects) != 0 {
+		t.Errorf("replace_defaults should drop built-in rules, got %d", len(rules.SideEffects))
+	}
+}
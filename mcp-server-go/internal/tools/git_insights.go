@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// ========== Git blame / churn 信息 (code_impact / flow_trace / project_map) ==========
+
+const (
+	hotspotMinChurn = 3
+	hotspotMinFanIn = 3
+	hotspotLimit    = 5
+)
+
+// gitHistoryLine 符号所在行范围的最后修改人/时间与文件变更频率；非 git 仓库或无提交记录时返回空
+func gitHistoryLine(sm *SessionManager, file string, start, end int) string {
+	if sm.ProjectRoot == "" || file == "" {
+		return ""
+	}
+	h, err := services.NewGitService(sm.ProjectRoot).FileHistory(file, start, end)
+	if err != nil {
+		return ""
+	}
+	commit := h.Last.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return fmt.Sprintf("最近修改 %s · %s (`%s`) | 近 %d 天该文件 %d 次提交 / %d 位作者",
+		h.Last.Author, services.HumanizeAge(h.Last.Time), commit, services.GitChurnDays, h.Commits, h.Authors)
+}
+
+// impactGitLine code_impact 目标符号的 git 信息
+func impactGitLine(sm *SessionManager, g *services.CallGraph, targets []int) string {
+	if len(targets) == 0 {
+		return ""
+	}
+	s := g.Symbols[targets[0]]
+	line := gitHistoryLine(sm, s.FilePath, s.LineStart, s.LineEnd)
+	if line == "" {
+		return ""
+	}
+	return "🕒 **Git**: " + line + "\n\n"
+}
+
+// scopeHotspots 范围内的变更热点（高 churn × 高扇入）；git 不可用时返回 nil
+func scopeHotspots(sm *SessionManager, g *services.CallGraph, scope string) []services.GitHotspot {
+	churn, err := services.NewGitService(sm.ProjectRoot).Churn()
+	if err != nil || len(churn) == 0 {
+		return nil
+	}
+	all := g.Hotspots(churn, hotspotMinChurn, hotspotMinFanIn, 0)
+	scope = strings.Trim(strings.ReplaceAll(strings.TrimSpace(scope), "\\", "/"), "/")
+	var out []services.GitHotspot
+	for _, h := range all {
+		file := strings.TrimPrefix(strings.ReplaceAll(h.FilePath, "\\", "/"), "./")
+		if scope != "" && scope != "." && file != scope && !strings.HasPrefix(file, scope+"/") {
+			continue
+		}
+		out = append(out, h)
+		if len(out) >= hotspotLimit {
+			break
+		}
+	}
+	return out
+}
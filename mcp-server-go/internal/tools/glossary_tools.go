@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// GlossaryArgs 术语表参数
+type GlossaryArgs struct {
+	Action     string   `json:"action" jsonschema:"required,enum=add,enum=list,enum=search,enum=remove,description=操作类型"`
+	Term       string   `json:"term" jsonschema:"description=术语名 (add/remove 必填)"`
+	Definition string   `json:"definition" jsonschema:"description=术语定义"`
+	Aliases    []string `json:"aliases" jsonschema:"description=别名/同义词 (如中英文对照)"`
+	Symbols    []string `json:"symbols" jsonschema:"description=对应的代码符号"`
+	Paths      []string `json:"paths" jsonschema:"description=对应的文件或目录 (相对项目根)"`
+	Query      string   `json:"query" jsonschema:"description=search 时的检索关键词"`
+}
+
+func wrapGlossary(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args GlossaryArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化"), nil
+		}
+		term := strings.TrimSpace(args.Term)
+
+		switch strings.ToLower(strings.TrimSpace(args.Action)) {
+		case "add":
+			if term == "" {
+				return mcp.NewToolResultError("add 需要提供 term"), nil
+			}
+			entry := core.GlossaryEntry{Term: term, Definition: args.Definition, Aliases: args.Aliases, Symbols: args.Symbols}
+			for _, p := range args.Paths {
+				entry.Paths = append(entry.Paths, core.NormalizeLockPath(sm.ProjectRoot, p))
+			}
+			// 已有术语时合并映射，定义仅在显式提供时覆盖
+			existing, err := sm.Memory.GetGlossaryTerm(ctx, term)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询术语失败: %v", err)), nil
+			}
+			if existing != nil {
+				entry.Term = existing.Term
+				if strings.TrimSpace(entry.Definition) == "" {
+					entry.Definition = existing.Definition
+				}
+				entry.Aliases = append(existing.Aliases, entry.Aliases...)
+				entry.Symbols = append(existing.Symbols, entry.Symbols...)
+				entry.Paths = append(existing.Paths, entry.Paths...)
+			}
+			if err := sm.Memory.UpsertGlossaryTerm(ctx, entry); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("保存术语失败: %v", err)), nil
+			}
+			saved, _ := sm.Memory.GetGlossaryTerm(ctx, entry.Term)
+			verb := "已添加"
+			if existing != nil {
+				verb = "已更新"
+			}
+			if saved == nil {
+				saved = &entry
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("✅ 术语%s\n\n%s", verb, formatGlossaryEntry(*saved))), nil
+
+		case "remove":
+			if term == "" {
+				return mcp.NewToolResultError("remove 需要提供 term"), nil
+			}
+			ok, err := sm.Memory.DeleteGlossaryTerm(ctx, term)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("删除术语失败: %v", err)), nil
+			}
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("术语不存在: %s", term)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🗑️ 已删除术语: %s", term)), nil
+
+		case "list", "search":
+			query := strings.TrimSpace(args.Query)
+			if query == "" {
+				query = term
+			}
+			entries, err := sm.Memory.ListGlossary(ctx, query)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询术语失败: %v", err)), nil
+			}
+			if len(entries) == 0 {
+				return mcp.NewToolResultText("术语表为空或无匹配。使用 glossary(action=\"add\", term=..., symbols=[...], paths=[...]) 添加。"), nil
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("### 📖 项目术语表 (%d)\n\n", len(entries)))
+			for _, e := range entries {
+				sb.WriteString(formatGlossaryEntry(e))
+				sb.WriteString("\n")
+			}
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知操作: %s (支持 add/list/search/remove)", args.Action)), nil
+	}
+}
+
+func formatGlossaryEntry(e core.GlossaryEntry) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("- **%s**", e.Term))
+	if len(e.Aliases) > 0 {
+		sb.WriteString(fmt.Sprintf(" (%s)", strings.Join(e.Aliases, ", ")))
+	}
+	if e.Definition != "" {
+		sb.WriteString(": " + e.Definition)
+	}
+	sb.WriteString("\n")
+	if len(e.Symbols) > 0 {
+		sb.WriteString(fmt.Sprintf("  - 符号: `%s`\n", strings.Join(e.Symbols, "`, `")))
+	}
+	if len(e.Paths) > 0 {
+		sb.WriteString(fmt.Sprintf("  - 路径: `%s`\n", strings.Join(e.Paths, "`, `")))
+	}
+	return sb.String()
+}
+
+// glossarySymbolsFor 任务描述中命中的术语及其映射的符号，供 manager_analyze 扩充符号列表
+func glossarySymbolsFor(ctx context.Context, sm *SessionManager, desc string) ([]core.GlossaryEntry, []string) {
+	if sm.Memory == nil {
+		return nil, nil
+	}
+	matched, err := sm.Memory.MatchGlossary(ctx, desc)
+	if err != nil || len(matched) == 0 {
+		return nil, nil
+	}
+	var symbols []string
+	for _, e := range matched {
+		symbols = append(symbols, e.Symbols...)
+	}
+	return matched, symbols
+}
+
+// glossaryPathAnchors 术语映射的路径作为文件级锚点
+func glossaryPathAnchors(matched []core.GlossaryEntry) []CodeAnchor {
+	var anchors []CodeAnchor
+	for _, e := range matched {
+		for _, p := range e.Paths {
+			anchors = append(anchors, CodeAnchor{Symbol: e.Term, File: p, Type: "glossary"})
+		}
+	}
+	return anchors
+}
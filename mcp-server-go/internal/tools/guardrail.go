@@ -0,0 +1,279 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ========== Guardrail 执行：修改前 guardrail_check + 可选的写入审计 ==========
+//
+// manager_analyze 生成的 Critical 约束原本只是交给 LLM 的文字。guardrail_check 在编辑前按约束判定
+// 计划修改的文件是否允许；开启 analyze.guardrail_audit 后，manager_analyze(step=1) 记录基线，
+// 之后的 step=2/3 扫描实际修改的文件（git status 或 mtime），与声明过的文件对比并标出违规。
+
+const (
+	guardrailAuditFile = "guardrail_audit.jsonl"
+	mtimeScanLimit     = 200
+)
+
+// GuardrailAudit 任务的写入审计状态
+type GuardrailAudit struct {
+	StartedAt time.Time    `json:"started_at"`
+	Baseline  *GitBaseline `json:"baseline,omitempty"` // 非 git 仓库时为 nil，改用 mtime 扫描
+	Declared  []string     `json:"declared,omitempty"` // guardrail_check 放行过的文件
+	Reported  []string     `json:"reported,omitempty"` // 已报告过违规的文件，避免重复告警
+}
+
+// GuardrailCheckArgs 修改前检查参数
+type GuardrailCheckArgs struct {
+	TaskID string   `json:"task_id" jsonschema:"description=manager_analyze 返回的 task_id (留空则只检查文件锁)"`
+	Files  []string `json:"files" jsonschema:"required,description=计划修改的文件 (相对项目根目录)"`
+	Intent string   `json:"intent" jsonschema:"description=本次修改意图，写入审计日志"`
+}
+
+// guardrailAuditEntry 审计日志条目
+type guardrailAuditEntry struct {
+	Time    time.Time `json:"time"`
+	TaskID  string    `json:"task_id,omitempty"`
+	Event   string    `json:"event"` // check / violation
+	Intent  string    `json:"intent,omitempty"`
+	Files   []string  `json:"files"`
+	Allowed bool      `json:"allowed"`
+	Reasons []string  `json:"reasons,omitempty"`
+}
+
+func wrapGuardrailCheck(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args GuardrailCheckArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		files := normalizeGuardrailFiles(args.Files)
+		if len(files) == 0 {
+			return mcp.NewToolResultError("files 不能为空"), nil
+		}
+
+		var state *AnalysisState
+		if id := strings.TrimSpace(args.TaskID); id != "" {
+			st, ok := sm.AnalysisState[id]
+			if !ok {
+				return mcp.NewToolResultError("⚠️ 未找到该 task_id 的分析状态（可能已过期或服务已重启），请重新调用 manager_analyze(step=1)"), nil
+			}
+			state = st
+		}
+
+		var blocked, reminders []string
+		if state != nil {
+			blocked, reminders = evaluateGuardrails(state.Guardrails, files)
+		}
+		allowed := len(blocked) == 0
+
+		var sb strings.Builder
+		if allowed {
+			sb.WriteString(fmt.Sprintf("### ✅ 允许修改 %d 个文件\n", len(files)))
+		} else {
+			sb.WriteString("### ⛔ 禁止修改\n")
+			for _, b := range blocked {
+				sb.WriteString(fmt.Sprintf("- %s\n", b))
+			}
+		}
+		if state == nil {
+			sb.WriteString("\n_未提供 task_id，只检查了文件锁。_\n")
+		}
+		if len(reminders) > 0 {
+			sb.WriteString("\n**修改前自查**:\n")
+			for _, r := range reminders {
+				sb.WriteString(fmt.Sprintf("- [ ] %s\n", r))
+			}
+		}
+		sb.WriteString(warnFileLocks(ctx, sm, args.TaskID, files))
+
+		if state != nil && state.Audit != nil {
+			if allowed {
+				state.Audit.Declared = mergeUnique(state.Audit.Declared, files)
+			}
+			appendGuardrailAudit(sm, guardrailAuditEntry{
+				Time: time.Now(), TaskID: args.TaskID, Event: "check", Intent: truncateRunes(args.Intent, 200),
+				Files: files, Allowed: allowed, Reasons: blocked,
+			})
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+func normalizeGuardrailFiles(files []string) []string {
+	var out []string
+	for _, f := range files {
+		f = strings.TrimSpace(strings.ReplaceAll(f, "\\", "/"))
+		if f == "" {
+			continue
+		}
+		out = append(out, strings.TrimPrefix(path.Clean(f), "./"))
+	}
+	return mergeUnique(nil, out)
+}
+
+func mergeUnique(base, add []string) []string {
+	seen := make(map[string]bool, len(base)+len(add))
+	out := make([]string, 0, len(base)+len(add))
+	for _, f := range append(append([]string{}, base...), add...) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// guardrailCode 约束的代号（冒号前部分）
+func guardrailCode(rule string) string {
+	code, _, _ := strings.Cut(rule, ":")
+	return strings.TrimSpace(code)
+}
+
+// evaluateGuardrails 按 Critical 约束判定文件：可机器判定的约束（READ_ONLY / MD_ONLY / NO_CODE_EDIT）给出禁止原因，
+// 其余约束作为修改前的自查项返回
+func evaluateGuardrails(g Guardrails, files []string) (blocked, reminders []string) {
+	for _, rule := range g.Critical {
+		switch guardrailCode(rule) {
+		case "READ_ONLY":
+			for _, f := range files {
+				blocked = append(blocked, fmt.Sprintf("READ_ONLY: 只读任务不允许修改 `%s`", f))
+			}
+		case "MD_ONLY", "NO_CODE_EDIT":
+			for _, f := range files {
+				if !strings.EqualFold(path.Ext(f), ".md") {
+					blocked = append(blocked, fmt.Sprintf("%s: 只允许修改 .md 文档，`%s` 不在允许范围", guardrailCode(rule), f))
+				}
+			}
+		default:
+			reminders = append(reminders, rule)
+		}
+	}
+	return mergeUnique(nil, blocked), reminders
+}
+
+// startGuardrailAudit 开启审计时记录基线
+func startGuardrailAudit(sm *SessionManager) *GuardrailAudit {
+	if sm.ProjectRoot == "" || !core.LoadProjectSettings(sm.ProjectRoot).Analyze.GuardrailAudit {
+		return nil
+	}
+	return &GuardrailAudit{StartedAt: time.Now(), Baseline: captureGitBaseline(sm.ProjectRoot)}
+}
+
+// guardrailViolations 对比基线之后实际修改的文件，返回新发现的违规（已报告过的不再返回）
+func guardrailViolations(sm *SessionManager, taskID string, state *AnalysisState) []string {
+	a := state.Audit
+	if a == nil {
+		return nil
+	}
+	var changed []string
+	if a.Baseline != nil {
+		files, err := gitChangedSince(sm.ProjectRoot, a.Baseline)
+		if err != nil {
+			return nil
+		}
+		changed = files
+	} else {
+		changed = filesModifiedSince(sm.ProjectRoot, a.StartedAt)
+	}
+
+	declared := make(map[string]bool, len(a.Declared))
+	for _, f := range a.Declared {
+		declared[f] = true
+	}
+	reported := make(map[string]bool, len(a.Reported))
+	for _, f := range a.Reported {
+		reported[f] = true
+	}
+
+	var alerts []string
+	for _, f := range changed {
+		if reported[f] {
+			continue
+		}
+		var reasons []string
+		if blocked, _ := evaluateGuardrails(state.Guardrails, []string{f}); len(blocked) > 0 {
+			reasons = blocked
+		} else if !declared[f] {
+			reasons = []string{fmt.Sprintf("UNDECLARED: `%s` 未经 guardrail_check 放行即被修改", f)}
+		} else {
+			continue
+		}
+		a.Reported = append(a.Reported, f)
+		for _, r := range reasons {
+			alerts = append(alerts, "⛔ [Guardrail] "+r)
+		}
+		appendGuardrailAudit(sm, guardrailAuditEntry{Time: time.Now(), TaskID: taskID, Event: "violation", Files: []string{f}, Reasons: reasons})
+	}
+	return alerts
+}
+
+// filesModifiedSince 非 git 仓库时按修改时间找出变更的文件（跳过隐藏目录与常见依赖/构建目录）
+func filesModifiedSince(root string, since time.Time) []string {
+	var out []string
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" ||
+				name == "dist" || name == "build" || name == "target" || name == "__pycache__") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.ModTime().After(since) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		if rel = filepath.ToSlash(rel); !isMPMArtifact(rel) {
+			out = append(out, rel)
+		}
+		if len(out) >= mtimeScanLimit {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	sort.Strings(out)
+	return out
+}
+
+func appendGuardrailAudit(sm *SessionManager, entry guardrailAuditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	p := core.DataPath(sm.ProjectRoot, guardrailAuditFile)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[guardrail] 写入审计日志失败: %v\n", err)
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(data, '\n'))
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluateGuardrails(t *testing.T) {
+	design := buildGuardrails("DESIGN", false)
+	blocked, _ := evaluateGuardrails(design, []string{"docs/plan.md", "api/user.go"})
+	if len(blocked) != 2 || !strings.Contains(blocked[0], "api/user.go") {
+		t.Fatalf("DESIGN blocked = %v", blocked)
+	}
+
+	debug := buildGuardrails("DEBUG", false)
+	blocked, reminders := evaluateGuardrails(debug, []string{"api/user.go"})
+	if len(blocked) != 0 || len(reminders) != 2 {
+		t.Fatalf("DEBUG blocked = %v, reminders = %v", blocked, reminders)
+	}
+}
+
+func TestGuardrailViolations_MtimeAudit(t *testing.T) {
+	root := t.TempDir()
+	sm := &SessionManager{ProjectRoot: root}
+	state := &AnalysisState{
+		Guardrails: buildGuardrails("DEVELOP", false),
+		Audit:      &GuardrailAudit{StartedAt: time.Now().Add(-time.Minute), Declared: []string{"api/user.go"}},
+	}
+	for _, f := range []string{"api/user.go", "api/order.go"} {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("package api"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alerts := guardrailViolations(sm, "t1", state)
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "UNDECLARED") || !strings.Contains(alerts[0], "api/order.go") {
+		t.Fatalf("alerts = %v", alerts)
+	}
+	if again := guardrailViolations(sm, "t1", state); len(again) != 0 {
+		t.Fatalf("violations should be reported once, got %v", again)
+	}
+}
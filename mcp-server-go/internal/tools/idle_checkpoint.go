@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ========== 会话空闲自动存档 ==========
+//
+// 会话空闲超过 session.idle_minutes 且存在进行中的任务链或分析状态时，把当前工作上下文
+// （任务链快照、分析状态、当前人格）写入数据目录 checkpoints/，并记录一条"进度停在哪"的 memo，
+// 防止 IDE 崩溃或会话中断后丢失上下文。每个空闲周期只存档一次。
+
+const idleCheckpointKeep = 10
+
+// IdleCheckpoint 空闲存档内容
+type IdleCheckpoint struct {
+	CreatedAt     string                    `json:"created_at"`
+	IdleMinutes   int                       `json:"idle_minutes"`
+	ActivePersona string                    `json:"active_persona,omitempty"`
+	Chains        []*TaskChainV3            `json:"chains,omitempty"`
+	Analyses      map[string]*AnalysisState `json:"analyses,omitempty"`
+}
+
+// ActivityMiddleware 记录工具调用时间与在途调用数，供空闲存档判断。
+// 调用期间持有 callGate 读锁，空闲存档读取 TaskChainsV3 / AnalysisState 时不会与工具处理并发
+func ActivityMiddleware(sm *SessionManager) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			sm.inFlight.Add(1)
+			sm.lastActivity.Store(time.Now().UnixNano())
+			sm.callGate.RLock()
+			defer func() {
+				sm.callGate.RUnlock()
+				sm.lastActivity.Store(time.Now().UnixNano()) // 长耗时调用结束后重新计时
+				sm.inFlight.Add(-1)
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// StartIdleCheckpointer 启动空闲检测循环，ctx 取消时退出
+func StartIdleCheckpointer(ctx context.Context, sm *SessionManager) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				checkIdle(ctx, sm, now)
+			}
+		}
+	}()
+}
+
+func checkIdle(ctx context.Context, sm *SessionManager, now time.Time) {
+	if sm.ProjectRoot == "" || sm.Memory == nil {
+		return
+	}
+	last := sm.lastActivity.Load()
+	if last == 0 || sm.lastCheckpoint.Load() == last {
+		return // 尚无活动，或本空闲周期已存档
+	}
+	if sm.inFlight.Load() > 0 {
+		return // 长耗时调用（如全量索引）仍在执行，不算空闲
+	}
+	settings := core.LoadProjectSettings(sm.ProjectRoot).Session
+	if !settings.IdleCheckpoint {
+		return
+	}
+	idle := now.Sub(time.Unix(0, last))
+	if idle < time.Duration(settings.IdleMinutes)*time.Minute {
+		return
+	}
+	// 与工具调用互斥；恰好有调用开始时本轮跳过，下个周期重试
+	if !sm.callGate.TryLock() {
+		return
+	}
+	defer sm.callGate.Unlock()
+	if sm.lastActivity.Load() != last {
+		return
+	}
+	sm.lastCheckpoint.Store(last)
+
+	path, err := writeIdleCheckpoint(ctx, sm, int(idle.Minutes()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Checkpoint][WARN] 空闲存档失败: %v\n", err)
+		return
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "[Checkpoint] 会话空闲 %d 分钟，已存档到 %s\n", int(idle.Minutes()), path)
+	}
+}
+
+// writeIdleCheckpoint 写入存档与 memo；没有进行中的工作时返回空路径。
+// 调用方须持有 sm.callGate 写锁
+func writeIdleCheckpoint(ctx context.Context, sm *SessionManager, idleMinutes int) (string, error) {
+	cp := IdleCheckpoint{
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		IdleMinutes: idleMinutes,
+		Analyses:    make(map[string]*AnalysisState),
+	}
+	for _, chain := range sm.TaskChainsV3 {
+		if chain.Status == "running" || chain.Status == "paused" {
+			cp.Chains = append(cp.Chains, chain)
+		}
+	}
+	sort.Slice(cp.Chains, func(i, j int) bool { return cp.Chains[i].TaskID < cp.Chains[j].TaskID })
+	for id, st := range sm.AnalysisState {
+		cp.Analyses[id] = st
+	}
+	if len(cp.Chains) == 0 && len(cp.Analyses) == 0 {
+		return "", nil
+	}
+	cp.ActivePersona, _ = sm.Memory.GetState(ctx, "active_persona")
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	dir := core.DataPath(sm.ProjectRoot, "checkpoints")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("idle-%s.json", time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	pruneIdleCheckpoints(dir)
+
+	rel := filepath.ToSlash(path)
+	if r, err := filepath.Rel(sm.ProjectRoot, path); err == nil && !strings.HasPrefix(r, "..") {
+		rel = filepath.ToSlash(r)
+	}
+	_, err = sm.Memory.AddMemos(ctx, []core.Memo{{
+		Category: "checkpoint",
+		Entity:   "session",
+		Act:      "空闲自动存档",
+		Path:     rel,
+		Content:  renderWhereWeLeftOff(&cp),
+	}})
+	return rel, err
+}
+
+// renderWhereWeLeftOff "进度停在哪"摘要
+func renderWhereWeLeftOff(cp *IdleCheckpoint) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("会话空闲 %d 分钟自动存档。", cp.IdleMinutes))
+	for _, c := range cp.Chains {
+		sb.WriteString(fmt.Sprintf("\n- 任务链 %s (%s, %s): %s", c.TaskID, c.Protocol, c.Status, truncateRunes(c.Description, 80)))
+		if p := c.findPhase(c.CurrentPhase); p != nil {
+			sb.WriteString(fmt.Sprintf("；当前阶段 %s [%s]", fallback(p.Name, p.ID), p.Status))
+			for _, sub := range p.SubTasks {
+				if sub.Status == SubTaskActive {
+					sb.WriteString(fmt.Sprintf("，子任务 %s 进行中", sub.ID))
+				}
+			}
+		}
+		if last := lastPassedSummary(c); last != "" {
+			sb.WriteString("；最近完成: " + truncateRunes(last, 120))
+		}
+		sb.WriteString(fmt.Sprintf("。恢复: task_chain(mode=\"resume\", task_id=\"%s\")", c.TaskID))
+	}
+	ids := make([]string, 0, len(cp.Analyses))
+	for id := range cp.Analyses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		st := cp.Analyses[id]
+		sb.WriteString(fmt.Sprintf("\n- 分析 %s [%s]: %s（%d 个锚点）", id, st.Intent, truncateRunes(st.UserDirective, 80), len(st.ContextAnchors)))
+	}
+	if cp.ActivePersona != "" {
+		sb.WriteString("\n- 当前人格: " + cp.ActivePersona)
+	}
+	return sb.String()
+}
+
+func lastPassedSummary(c *TaskChainV3) string {
+	for i := len(c.Phases) - 1; i >= 0; i-- {
+		if c.Phases[i].Status == PhasePassed && c.Phases[i].Summary != "" {
+			return c.Phases[i].Summary
+		}
+	}
+	return ""
+}
+
+// pruneIdleCheckpoints 仅保留最近的若干份存档
+func pruneIdleCheckpoints(dir string) {
+	matches, err := filepath.Glob(filepath.Join(dir, "idle-*.json"))
+	if err != nil || len(matches) <= idleCheckpointKeep {
+		return
+	}
+	sort.Strings(matches)
+	for _, m := range matches[:len(matches)-idleCheckpointKeep] {
+		_ = os.Remove(m)
+	}
+}
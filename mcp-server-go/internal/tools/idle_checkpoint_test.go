@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestCheckIdle_SkipsWhileCallInFlight(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := core.NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog) // 存档 memo 会触发后台写 dev-log，清理临时目录前等其结束
+	sm := &SessionManager{
+		Memory:       mem,
+		ProjectRoot:  root,
+		TaskChainsV3: map[string]*TaskChainV3{"T1": {TaskID: "T1", Status: "running"}},
+	}
+	now := time.Now()
+	sm.lastActivity.Store(now.Add(-time.Hour).UnixNano())
+	written := func() int {
+		matches, _ := filepath.Glob(filepath.Join(core.DataPath(root, "checkpoints"), "idle-*.json"))
+		return len(matches)
+	}
+
+	// 全量索引这类长调用执行期间：上次活动时间很早，但不应视为空闲
+	sm.inFlight.Add(1)
+	checkIdle(ctx, sm, now)
+	if n := written(); n != 0 {
+		t.Fatalf("checkpoint written while a call is in flight (%d files)", n)
+	}
+
+	sm.inFlight.Add(-1)
+	checkIdle(ctx, sm, now)
+	if n := written(); n != 1 {
+		t.Fatalf("idle checkpoints = %d, want 1 once no call is running", n)
+	}
+}
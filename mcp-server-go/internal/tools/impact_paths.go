@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// ========== code_impact 深度控制、调用链与调用环警告 ==========
+
+const impactPathLimit = 10
+
+// loadImpactGraph 加载调用图（已按 include_vendored 过滤）并定位目标符号
+func loadImpactGraph(sm *SessionManager, ai *services.ASTIndexer, args ImpactArgs) (*services.CallGraph, []int, error) {
+	g, err := ai.LoadCallGraph(sm.ProjectRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	g.ExcludePaths(resolvePathTagger(sm, args.IncludeVendored))
+	return g, g.SymbolsByName(args.SymbolName), nil
+}
+
+// impactCycleWarning 目标符号位于调用环中时给出警告
+func impactCycleWarning(g *services.CallGraph, targets []int, symbol string) string {
+	comp := g.CycleContaining(targets)
+	if comp == nil {
+		return ""
+	}
+	return fmt.Sprintf("⚠️ **调用环**: `%s` 处在 %s\n> 环内函数相互依赖，修改签名或行为会沿环传回自身，是重构中风险最高的改动。\n\n",
+		symbol, describeCycle(g.ReportCycle(comp), 8))
+}
+
+// impactTraceSection 按 max_depth 裁剪间接调用者，并输出分层统计与调用链（show_paths）。
+// 在 Go 侧调用图上做有界 BFS；调用图中找不到目标时保留引擎结果并给出说明
+func impactTraceSection(g *services.CallGraph, targets []int, args ImpactArgs, r *services.ImpactResult) string {
+	if len(targets) == 0 {
+		return fmt.Sprintf("\n_⚠️ 调用图中未找到 `%s`，未应用 max_depth/show_paths_\n", args.SymbolName)
+	}
+
+	var traces []*services.ImpactTrace
+	if args.Direction != "forward" {
+		traces = append(traces, g.TraceImpact(targets, true, args.MaxDepth))
+	}
+	if args.Direction != "backward" {
+		traces = append(traces, g.TraceImpact(targets, false, args.MaxDepth))
+	}
+
+	var sb strings.Builder
+	if args.MaxDepth > 0 {
+		reached := make(map[string]bool)
+		for _, tr := range traces {
+			for id := range tr.Depth {
+				s := g.Symbols[id]
+				reached[s.Name+"@"+s.FilePath] = true
+			}
+		}
+		kept := r.IndirectCallers[:0]
+		for _, c := range r.IndirectCallers {
+			if reached[c.Node.Name+"@"+c.Node.FilePath] {
+				kept = append(kept, c)
+			}
+		}
+		r.IndirectCallers = kept
+
+		for _, tr := range traces {
+			label := "上游（调用者）"
+			if !tr.Backward {
+				label = "下游（被调用者）"
+			}
+			sb.WriteString(fmt.Sprintf("\n### 传播深度 · %s (max_depth=%d)\n", label, args.MaxDepth))
+			layers := tr.Layers()
+			if len(layers) == 0 {
+				sb.WriteString("- 无\n")
+			}
+			for i, n := range layers {
+				note := ""
+				if i+1 == args.MaxDepth {
+					note = "（已到深度上限，更远的未展开）"
+				}
+				sb.WriteString(fmt.Sprintf("- 第 %d 层: %d 个%s\n", i+1, n, note))
+			}
+		}
+	}
+
+	if args.ShowPaths {
+		for _, tr := range traces {
+			paths := tr.Paths(g, impactPathLimit)
+			label := "谁调用了我"
+			if !tr.Backward {
+				label = "我调用了谁"
+			}
+			sb.WriteString(fmt.Sprintf("\n### 调用链 · %s (最长的 %d 条)\n", label, len(paths)))
+			if len(paths) == 0 {
+				sb.WriteString("- 无\n")
+			}
+			for _, p := range paths {
+				names := make([]string, len(p))
+				for i, id := range p {
+					names[i] = "`" + g.Symbols[id].Name + "`"
+				}
+				end := g.Symbols[p[len(p)-1]]
+				if tr.Backward {
+					end = g.Symbols[p[0]]
+				}
+				sb.WriteString(fmt.Sprintf("- %s _(%s:%d)_\n", strings.Join(names, " → "), end.FilePath, end.LineStart))
+			}
+		}
+	}
+	return sb.String()
+}
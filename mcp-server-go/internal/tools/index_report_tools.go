@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// IndexReportArgs 索引报告导出参数
+type IndexReportArgs struct {
+	Format          string  `json:"format" jsonschema:"default=sarif,enum=sarif,enum=json,description=输出格式"`
+	Output          string  `json:"output" jsonschema:"description=输出文件路径 (默认数据目录下 index_report.<format>)"`
+	Threshold       float64 `json:"threshold" jsonschema:"description=高风险分数阈值 (默认读取项目配置)"`
+	Limit           int     `json:"limit" jsonschema:"default=200,description=每类条目上限"`
+	IncludeVendored bool    `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件 (默认排除)"`
+}
+
+func wrapIndexReport(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args IndexReportArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+
+		format := strings.ToLower(strings.TrimSpace(args.Format))
+		if format == "" {
+			format = "sarif"
+		}
+		if format != "sarif" && format != "json" {
+			return mcp.NewToolResultError(fmt.Sprintf("不支持的格式: %s", args.Format)), nil
+		}
+
+		threshold := args.Threshold
+		if threshold <= 0 {
+			threshold = core.LoadProjectSettings(sm.ProjectRoot).Complexity.AlertThreshold
+		}
+
+		report, err := ai.BuildIndexReport(sm.ProjectRoot, threshold, args.Limit, resolvePathTagger(sm, args.IncludeVendored))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("生成报告失败: %v", err)), nil
+		}
+
+		var payload interface{} = report
+		if format == "sarif" {
+			payload = report.ToSARIF()
+		}
+		content, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("序列化报告失败: %v", err)), nil
+		}
+
+		outputPath := args.Output
+		if outputPath == "" {
+			outputPath = core.DataPath(sm.ProjectRoot, "index_report."+format)
+		}
+		if !filepath.IsAbs(outputPath) {
+			outputPath = filepath.Join(sm.ProjectRoot, outputPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("创建输出目录失败: %v", err)), nil
+		}
+		if err := os.WriteFile(outputPath, content, 0644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("写入报告失败: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(renderIndexReportSummary(report, outputPath, format)), nil
+	}
+}
+
+func renderIndexReportSummary(report *services.IndexReport, outputPath, format string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 📄 索引审查报告 (%s)\n\n", strings.ToUpper(format)))
+	sb.WriteString(fmt.Sprintf("**文件**: `%s`\n", outputPath))
+	sb.WriteString(fmt.Sprintf("**规模**: %d 个符号 / %d 条调用\n\n", report.TotalSymbols, report.TotalCalls))
+	sb.WriteString(fmt.Sprintf("- 高风险符号 (score >= %.0f): %d\n", report.Threshold, len(report.HighRiskSymbols)))
+	sb.WriteString(fmt.Sprintf("- 调用环: %d\n", len(report.Cycles)))
+	sb.WriteString(fmt.Sprintf("- 未解析调用: %d\n", report.UnresolvedTotal))
+
+	if len(report.HighRiskSymbols) > 0 {
+		sb.WriteString("\n**Top 高风险符号**:\n")
+		for i, s := range report.HighRiskSymbols {
+			if i >= 5 {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("  %d. %s (%.1f) @ %s:%d\n", i+1, s.Name, s.Score, s.FilePath, s.Line))
+		}
+	}
+	if len(report.Cycles) > 0 {
+		c := report.Cycles[0]
+		sb.WriteString(fmt.Sprintf("\n**最大调用环**: %d 个符号横跨 %d 个文件\n", len(c.Symbols), len(c.Files)))
+	}
+	return sb.String()
+}
+
+// renderIndexIssues 渲染 project_map(level="issues") 视图
+func renderIndexIssues(issues *services.IndexIssues, scope string) string {
+	var sb strings.Builder
+	sb.WriteString("### 🧹 项目地图 (Issues)\n\n")
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 个符号 | 未解析调用 %d | 死代码候选 %d | 调用环 %d\n\n", issues.TotalSymbols, issues.UnresolvedTotal, issues.DeadTotal, issues.CycleTotal))
+	if strings.TrimSpace(scope) != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", scope))
+	}
+
+	sb.WriteString(fmt.Sprintf("#### ❓ 未解析调用 (%d 个被调名)\n\n", len(issues.Unresolved)))
+	sb.WriteString("> 按出现次数升序：偶发的更可能是拼写错误，高频的多为外部库或动态分发。\n\n")
+	for _, grp := range issues.Unresolved {
+		var sites []string
+		for _, u := range grp.Samples {
+			sites = append(sites, fmt.Sprintf("%s:%d (%s)", u.FilePath, u.CallLine, u.CallerName))
+		}
+		sb.WriteString(fmt.Sprintf("- `%s` ×%d — %s\n", grp.CalleeName, grp.Count, strings.Join(sites, ", ")))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n#### 💀 死代码候选 (%d)\n\n", issues.DeadTotal))
+	sb.WriteString("> 无任何项目内调用方且不是入口点。方法可能经接口/反射调用，导出符号可能被外部使用，删除前请用 code_search 复核。\n\n")
+	for _, d := range issues.Dead {
+		mark := ""
+		if d.Exported {
+			mark = " [exported]"
+		}
+		sb.WriteString(fmt.Sprintf("- %s `%s`%s @ %s:%d\n", d.Type, d.Name, mark, d.FilePath, d.Line))
+	}
+	if len(issues.Dead) < issues.DeadTotal {
+		sb.WriteString(fmt.Sprintf("\n... 另有 %d 个候选未列出，可缩小 scope 查看\n", issues.DeadTotal-len(issues.Dead)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n#### 🔁 调用环 (%d)\n\n", issues.CycleTotal))
+	sb.WriteString("> 相互调用的函数组（强连通分量，单函数递归不计）。环内任一函数的改动都会沿环传播，重构时优先拆环。\n\n")
+	for _, c := range issues.Cycles {
+		sb.WriteString("- " + describeCycle(c, 8) + "\n")
+	}
+	if len(issues.Cycles) < issues.CycleTotal {
+		sb.WriteString(fmt.Sprintf("\n... 另有 %d 个调用环未列出，可缩小 scope 查看\n", issues.CycleTotal-len(issues.Cycles)))
+	}
+	return sb.String()
+}
+
+// describeCycle 一行描述调用环："N 个函数跨 M 个文件构成的调用环: a, b, ... (files)"
+func describeCycle(c services.ReportCycle, maxNames int) string {
+	names := make([]string, 0, maxNames)
+	for i, s := range c.Symbols {
+		if i >= maxNames {
+			names = append(names, fmt.Sprintf("… +%d", len(c.Symbols)-maxNames))
+			break
+		}
+		names = append(names, "`"+s.Name+"`")
+	}
+	return fmt.Sprintf("%d 个函数跨 %d 个文件构成的调用环: %s（%s）",
+		len(c.Symbols), len(c.Files), strings.Join(names, ", "), strings.Join(c.Files, ", "))
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// ========== 文件监听增量索引 (settings.json index.watch) ==========
+
+const indexWatcherTick = 30 * time.Second
+
+// IndexWatcher 跟随当前绑定的项目启停文件监听；项目晚绑定、切换或修改配置后无需重启
+type IndexWatcher struct {
+	sm *SessionManager
+	ai *services.ASTIndexer
+
+	mu       sync.Mutex
+	root     string
+	debounce int
+	fw       *services.FileWatcher
+	cancel   context.CancelFunc
+	failed   string // 启动失败的项目，避免每个周期重复报错
+}
+
+// StartIndexWatcher 启动监听管理循环，ctx 取消时停止监听并退出
+func StartIndexWatcher(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer) *IndexWatcher {
+	w := &IndexWatcher{sm: sm, ai: ai}
+	go func() {
+		ticker := time.NewTicker(indexWatcherTick)
+		defer ticker.Stop()
+		w.sync(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				w.stop()
+				return
+			case <-ticker.C:
+				w.sync(ctx)
+			}
+		}
+	}()
+	return w
+}
+
+// Stats 返回当前监听状态；未在监听时 ok=false
+func (w *IndexWatcher) Stats() (services.FileWatcherStats, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fw == nil {
+		return services.FileWatcherStats{}, false
+	}
+	return w.fw.Stats(), true
+}
+
+// sync 按当前项目与配置启动、重启或停止监听
+func (w *IndexWatcher) sync(ctx context.Context) {
+	root, _ := w.sm.Binding()
+	settings := core.LoadProjectSettings(root).Index
+	if root == "" || !settings.Watch {
+		w.stop()
+		return
+	}
+
+	w.mu.Lock()
+	running := w.fw != nil && w.root == root && w.debounce == settings.WatchDebounceMs
+	w.mu.Unlock()
+	if running || w.failed == root {
+		return
+	}
+	w.stop()
+
+	fw, err := services.NewFileWatcher(w.ai, root, time.Duration(settings.WatchDebounceMs)*time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Watcher][WARN] 文件监听未启动: %v\n", err)
+		w.failed = root
+		return
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.root, w.debounce, w.fw, w.cancel, w.failed = root, settings.WatchDebounceMs, fw, cancel, ""
+	w.mu.Unlock()
+	go fw.Run(runCtx)
+	fmt.Fprintf(os.Stderr, "[Watcher] 已监听 %s (%d 个目录)\n", root, fw.Stats().WatchedDirs)
+}
+
+func (w *IndexWatcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.fw, w.cancel, w.root = nil, nil, ""
+}
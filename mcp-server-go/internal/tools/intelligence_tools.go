@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mcp-server-go/internal/core"
 	"mcp-server-go/internal/services"
 	"path/filepath"
 	"strings"
@@ -17,25 +18,33 @@ import (
 // AnalyzeArgs 任务分析参数
 type AnalyzeArgs struct {
 	TaskDescription string   `json:"task_description" jsonschema:"required,description=用户的原始指令/任务详情"`
-	Intent          string   `json:"intent" jsonschema:"description=LLM 自行判断的意向 (DEBUG/DEVELOP/REFACTOR/DESIGN/RESEARCH)"`
+	Intent          string   `json:"intent" jsonschema:"description=LLM 自行判断的意向 (DEBUG/DEVELOP/REFACTOR/DESIGN/RESEARCH/ONBOARD)"`
 	Symbols         []string `json:"symbols" jsonschema:"description=提取的代码符号"`
 	ReadOnly        bool     `json:"read_only" jsonschema:"description=是否为只读分析模式"`
 	Scope           string   `json:"scope" jsonschema:"description=任务范围描述"`
-	Step            int      `json:"step" jsonschema:"description=执行步骤 (1=分析, 2=生成策略)，默认为1"`
-	TaskID          string   `json:"task_id" jsonschema:"description=步骤2时必填，步骤1返回的 task_id"`
+	Step            int      `json:"step" jsonschema:"description=执行步骤 (1=分析, 2=生成策略, 3=增量更新)，默认为1"`
+	TaskID          string   `json:"task_id" jsonschema:"description=步骤2/3时必填，步骤1返回的 task_id"`
+	MaxAnchors      int      `json:"max_anchors" jsonschema:"description=单次最多解析的符号数 (默认读取 settings.json analyze.max_anchors，缺省 10)"`
+	ResolveMore     bool     `json:"resolve_more" jsonschema:"description=配合 task_id 使用：继续解析上次因数量上限被推迟的符号"`
+	Quiet           bool     `json:"quiet" jsonschema:"description=精简输出 (去除 emoji 并压缩 JSON)"`
 }
 
 // FactArgs 事实存档参数
 type FactArgs struct {
-	Type      string `json:"type" jsonschema:"required,description=事实类型 (如：铁律、避坑)"`
-	Summarize string `json:"summarize" jsonschema:"required,description=事实描述"`
+	Type       string  `json:"type" jsonschema:"required,description=事实类型 (如：铁律、避坑)"`
+	Summarize  string  `json:"summarize" jsonschema:"required,description=事实描述"`
+	Force      bool    `json:"force" jsonschema:"description=存在近似重复事实时仍强制保存"`
+	Scope      string  `json:"scope" jsonschema:"description=作用域 (path:internal/core / module:tools / symbol:SaveFact)，为空表示全局"`
+	Priority   string  `json:"priority" jsonschema:"default=medium,enum=high,enum=medium,enum=low,description=优先级"`
+	TTL        string  `json:"ttl" jsonschema:"description=有效期 (如 30d、2w、12h)，到期后不再被检索；为空表示永久"`
+	Supersedes []int64 `json:"supersedes" jsonschema:"description=被新事实取代的旧事实 ID，旧事实不再被检索"`
 }
 
 // MissionBriefing 情报包结构
 type MissionBriefing struct {
 	MissionControl   MissionControl         `json:"mission_control"`
 	ContextAnchors   []CodeAnchor           `json:"context_anchors"`
-	VerifiedFacts    []string               `json:"verified_facts"`
+	VerifiedFacts    []VerifiedFact         `json:"verified_facts"`
 	Telemetry        map[string]interface{} `json:"telemetry"`
 	Guardrails       Guardrails             `json:"guardrails"`
 	Alerts           []string               `json:"alerts"`
@@ -50,7 +59,7 @@ type MissionControl struct {
 // RegisterIntelligenceTools 注册智能分析工具
 func RegisterIntelligenceTools(s *server.MCPServer, sm *SessionManager, ai *services.ASTIndexer) {
 	s.AddTool(mcp.NewTool("manager_analyze",
-		mcp.WithDescription(`manager_analyze - 任务情报聚合与战术简报（两步自迭代）
+		mcp.WithDescription(`manager_analyze - 任务情报聚合与战术简报（两步自迭代 + 增量更新）
 
 用途：
   【必选】复杂任务启动入口。采用两步自迭代模式：
@@ -67,6 +76,13 @@ func RegisterIntelligenceTools(s *server.MCPServer, sm *SessionManager, ai *serv
     - 动态生成战术建议
     - 返回：完整的 Mission Briefing（含 strategic_handoff）
 
+  步骤3（step=3）：增量更新
+    - 任务中途出现新线索时使用，无需从头重新分析
+    - 仅解析新增 symbols 的锚点，合并新事实与复杂度告警
+    - task_description 可填写新补充的信息（会追加到简报，不覆盖原指令）
+    - resolve_more=true 时继续解析此前因 max_anchors 上限被推迟的符号
+    - 返回：变更摘要（delta）+ 更新后的 strategic_handoff
+
   ⚠️ 注意：此工具不具备自然语言理解能力。
   你必须先运用逻辑能力，从用户指令中解析出「意图」和「关键符号」，填入参数。
 
@@ -80,20 +96,33 @@ func RegisterIntelligenceTools(s *server.MCPServer, sm *SessionManager, ai *serv
     - DEVELOP: 新功能开发
     - REFACTOR: 代码重构
     - RESEARCH: 技术调研
+    - ONBOARD: 新人接手简报（汇总目录结构、命名规范、复杂度热点、近期 memo、未完成 hook，
+      保存到 .mcp-data/onboarding.md；symbols 可留空，无需 step=2）
 
   symbols (必填)
     基于你的分析，提取指令中涉及的核心函数名、类名或文件名。
-    (工具将仅据此列表锁定代码物理位置，漏填将导致上下文丢失)
+    (工具将据此列表锁定代码物理位置；漏填时仅能从 task_description 中自动匹配索引符号，
+     结果带 confidence 置信度，准确性不如显式提供)
 
   step (可选，默认=1)
-    执行步骤：1=分析，2=生成策略
+    执行步骤：1=分析，2=生成策略，3=增量更新
 
-  task_id (步骤2时必填)
+  task_id (步骤2/3时必填)
     步骤1返回的 task_id，用于获取上一步的分析结果。
 
+  max_anchors (可选)
+    单次最多解析的符号数，默认 10（可在 settings.json analyze.max_anchors 修改）。
+    超出部分会在结果的 deferred_symbols 中列出，用 resolve_more=true 继续解析。
+
+  quiet (可选)
+    精简输出（去除 emoji、压缩 JSON），也可在 .mcp-config/settings.json 中设置 output.quiet 全局开启。
+
 返回：
   步骤1：分析结果 + task_id
   步骤2：完整的 Mission Briefing JSON
+  步骤3：delta 简报 JSON
+  Critical 约束由 guardrail_check 执行；开启 analyze.guardrail_audit 时，步骤2/3 的告警中
+  会列出未经 guardrail_check 放行或违反约束的文件修改。
 
 触发词：
   "mpm 分析", "mpm 任务", "mpm mg", "mpm analyze"`),
@@ -112,15 +141,131 @@ func RegisterIntelligenceTools(s *server.MCPServer, sm *SessionManager, ai *serv
   
   summarize (必填)
     事实的具体描述，应简洁明了。
+  
+  force (可选)
+    检测到近似重复的已有事实时默认不保存并返回已有 ID；传 true 强制保存。
+
+  scope (可选)
+    作用域：path:<路径前缀> / module:<模块名> / symbol:<符号名>。
+    带作用域的事实只在 manager_analyze 涉及相应路径/符号时加载；为空表示全局事实。
+
+  priority (可选，默认 medium)
+    high / medium / low，简报中高优先级事实排在前面。
+
+  ttl (可选)
+    有效期，如 "30d"、"2w"、"12h"。适合迁移期间的临时规则，到期后自动失效。
+
+  supersedes (可选)
+    被新事实取代的旧事实 ID 列表。保存前会检测与已有事实的冲突（一条要求、一条禁止，或同一规则不同取值），
+    发现冲突时不保存并列出候选；确认新规则替代旧规则时传入旧 ID，二者并存时传 force=true。
 
 示例：
   known_facts(type="避坑", summarize="修改 context 逻辑前必须先备份 session 数据")
     -> 保存一条重要的经验法则
 
+  known_facts(type="规范", summarize="core 包内禁止依赖 tools 包", scope="path:internal/core", priority="high")
+    -> 只在改动 internal/core 时加载
+
+  known_facts(type="铁律", summarize="迁移期间旧接口 /v1/login 不得删除", ttl="30d")
+    -> 30 天后自动失效
+
+  known_facts(type="规范", summarize="Go 变量一律使用 camelCase", supersedes=[12])
+    -> 新规则取代 ID 12 的旧规则
+
 触发词：
   "mpm 铁律", "mpm 避坑", "mpm fact"`),
 		mcp.WithInputSchema[FactArgs](),
 	), wrapSaveFact(sm))
+
+	s.AddTool(mcp.NewTool("glossary",
+		mcp.WithDescription(`glossary - 项目术语表
+
+用途：
+  维护团队领域术语（如 "ledger"、"活动"）到代码符号/路径的映射。
+  manager_analyze 会自动匹配任务描述中的术语，把对应符号加入代码定位、路径作为锚点，
+  让自然语言描述的任务也能落到正确的代码区域。
+
+参数：
+  action (必填)
+    add: 新增术语；术语已存在时合并 aliases/symbols/paths，definition 非空时覆盖
+    list: 列出全部术语
+    search: 按 query 在术语/别名/定义/符号/路径中检索
+    remove: 删除术语
+
+  term (add/remove 必填)
+  definition / aliases / symbols / paths (add 可选)
+  query (search 可选)
+
+示例：
+  glossary(action="add", term="ledger", aliases=["账本"], symbols=["LedgerService"], paths=["internal/billing"])
+  glossary(action="search", query="billing")
+
+触发词：
+  "mpm 术语", "mpm glossary"`),
+		mcp.WithInputSchema[GlossaryArgs](),
+	), wrapGlossary(sm))
+
+	s.AddTool(mcp.NewTool("suggest_facts",
+		mcp.WithDescription(`suggest_facts - 从 memo 中挖掘候选事实
+
+用途：
+  扫描 memo 中反复出现的规则性说法（"记得先 X 再 Y"、"不要直接改 Z"），
+  汇总为候选事实。一键采纳即存入 known_facts，让隐性经验逐步变成 manager_analyze 会加载的护栏。
+
+参数：
+  action (默认 list)
+    list: 列出待定候选（没有待定候选时自动扫描一次）
+    scan: 重新扫描 memo
+    accept: 采纳候选 id，存为 known_facts（可用 type/summarize 修改措辞）
+    dismiss: 忽略候选 id，之后不再提示
+
+  min_occurrences (可选，默认 2)
+    至少在多少条不同 memo 中出现
+
+说明：
+  - 调度器启用时 fact_suggest 任务每天自动扫描一次。
+
+示例：
+  suggest_facts()
+  suggest_facts(action="accept", id=3)
+
+触发词：
+  "mpm 候选事实", "mpm suggest facts"`),
+		mcp.WithInputSchema[SuggestFactsArgs](),
+	), wrapSuggestFacts(sm))
+
+	s.AddTool(mcp.NewTool("guardrail_check",
+		mcp.WithDescription(`guardrail_check - 修改前的约束检查
+
+用途：
+  【编辑前必选】manager_analyze 生成的 Critical 约束（READ_ONLY / MD_ONLY 等）在这里被执行：
+  按任务约束判定计划修改的文件是否允许，并检查文件锁。
+
+参数：
+  task_id (可选)
+    manager_analyze 返回的 task_id。留空时只检查文件锁。
+
+  files (必填)
+    计划修改的文件列表（相对项目根目录）。
+
+  intent (可选)
+    本次修改意图，开启审计时写入审计日志。
+
+审计：
+  settings.json 中 analyze.guardrail_audit=true 时，manager_analyze(step=1) 记录基线，
+  step=2/3 对比实际修改的文件（git status，非 git 仓库按修改时间），
+  未经本工具放行或违反约束的修改会作为告警返回，并写入数据目录 guardrail_audit.jsonl。
+
+返回：
+  ✅ 允许 / ⛔ 禁止（附违反的约束）+ 需自查的约束清单 + 文件锁冲突。
+
+示例：
+  guardrail_check(task_id="analyze_xxx", files=["internal/core/session.go"], intent="修复空指针")
+
+触发词：
+  "mpm 检查约束", "mpm guardrail"`),
+		mcp.WithInputSchema[GuardrailCheckArgs](),
+	), wrapGuardrailCheck(sm))
 }
 
 func wrapAnalyze(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
@@ -140,25 +285,37 @@ func wrapAnalyze(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandler
 			step = 1
 		}
 
+		if args.ResolveMore {
+			step = 3 // resolve_more 即针对已有分析的增量更新
+		}
+
 		// 生成或使用任务 ID
 		var taskID string
 		if step == 1 {
 			// Step 1: 生成新的 taskID
 			taskID = fmt.Sprintf("analyze_%d", time.Now().UnixNano())
 		} else {
-			// Step 2: 使用用户传入的 taskID
+			// Step 2/3: 使用用户传入的 taskID
 			taskID = args.TaskID
 			if taskID == "" {
-				return mcp.NewToolResultError("⚠️ Step 2 需要提供 task_id 参数（来自 Step 1 的返回值）"), nil
+				return mcp.NewToolResultError(fmt.Sprintf("⚠️ Step %d 需要提供 task_id 参数（来自 Step 1 的返回值）", step)), nil
 			}
 		}
 
-		if step == 1 {
+		quiet := resolveQuiet(sm, args.Quiet)
+		switch step {
+		case 1:
 			// ===== 步骤1：真实分析 =====
-			return handleAnalyzeStep1(ctx, sm, ai, args, taskID)
-		} else {
+			res, err := handleAnalyzeStep1(ctx, sm, ai, args, taskID)
+			return applyQuiet(res, quiet), err
+		case 3:
+			// ===== 步骤3：增量更新 =====
+			res, err := handleAnalyzeUpdate(ctx, sm, ai, args, taskID)
+			return applyQuiet(res, quiet), err
+		default:
 			// ===== 步骤2：动态策略 =====
-			return handleAnalyzeStep2(sm, ai, args, taskID)
+			res, err := handleAnalyzeStep2(sm, ai, args, taskID)
+			return applyQuiet(res, quiet), err
 		}
 	}
 }
@@ -166,7 +323,7 @@ func wrapAnalyze(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandler
 // handleAnalyzeStep1 执行第一步：真实分析，保存状态
 func handleAnalyzeStep1(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, args AnalyzeArgs, taskID string) (*mcp.CallToolResult, error) {
 	// 1. 意图识别
-	intent := determineIntent(args.TaskDescription, args.Intent, args.ReadOnly)
+	intent, intentSource := classifyIntent(ctx, sm.ProjectRoot, args.TaskDescription, args.Intent, args.ReadOnly)
 
 	// 1.1 索引预热（避免 manager_analyze 使用过期索引）
 	if strings.TrimSpace(args.Scope) != "" {
@@ -174,65 +331,73 @@ func handleAnalyzeStep1(ctx context.Context, sm *SessionManager, ai *services.AS
 	} else {
 		_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
 	}
+	if intent == "ONBOARD" {
+		return handleAnalyzeOnboard(ctx, sm, ai, args)
+	}
+
+	// 1.2 术语表：任务描述中的领域术语映射到符号/路径（显式 symbols 优先）
+	glossaryHits, glossarySymbols := glossarySymbolsFor(ctx, sm, args.TaskDescription)
+	candidates := append(append([]string{}, args.Symbols...), glossarySymbols...)
 
-	// 2. 符号预搜索 (Code Anchors)
+	// 2. 符号预搜索 (Code Anchors)：超出上限的符号推迟到 resolve_more
 	var anchors []CodeAnchor
-	limit := 10
-	if len(args.Symbols) < limit {
-		limit = len(args.Symbols)
-	}
+	maxAnchors := resolveMaxAnchors(sm, args.MaxAnchors)
 
 	uniqueSymbols := make(map[string]bool)
-	for i := 0; i < limit; i++ {
-		sym := args.Symbols[i]
-		if uniqueSymbols[sym] {
+	var symbols, unresolved, deferred []string
+
+	// 2.1 调用方未提供符号时，从任务描述中自动匹配索引符号
+	var autoSymbols []services.SymbolCandidate
+	if len(candidates) == 0 && core.FeatureEnabled(sm.ProjectRoot, "auto_symbols") {
+		autoSymbols = services.ExtractSymbolCandidates(sm.ProjectRoot, args.TaskDescription, args.Scope, maxAnchors)
+		for _, c := range autoSymbols {
+			uniqueSymbols[c.Name] = true
+			symbols = append(symbols, c.Name)
+			anchors = append(anchors, CodeAnchor{Symbol: c.Name, File: c.FilePath, Line: c.Line, Type: c.Type, Confidence: c.Confidence})
+		}
+	}
+	for _, sym := range candidates {
+		sym = strings.TrimSpace(sym)
+		if sym == "" || uniqueSymbols[sym] {
 			continue
 		}
 		uniqueSymbols[sym] = true
+		if len(symbols) >= maxAnchors {
+			deferred = append(deferred, sym)
+			continue
+		}
+
+		symbols = append(symbols, sym)
 
 		anchor := resolveCodeAnchor(ctx, sm, ai, sym, args.Scope)
 		if anchor == nil {
+			unresolved = append(unresolved, sym)
 			continue
 		}
 		anchors = append(anchors, *anchor)
 	}
+	anchors = append(anchors, glossaryPathAnchors(glossaryHits)...)
 
-	// 3. 记忆加载（仅 Facts）
-	var facts []string
-	if sm.Memory != nil {
-		keywords := buildFactKeywords(args.TaskDescription, args.Symbols)
-		knownFacts, _ := sm.Memory.QueryFacts(ctx, keywords, 10)
-		for _, f := range knownFacts {
-			facts = append(facts, f.Summarize)
-		}
-	}
+	// 3. 记忆加载（仅 Facts）：全局事实按关键词，作用域事实按任务符号/路径
+	facts := loadVerifiedFacts(ctx, sm, args.TaskDescription, factScopeContext(args.Symbols, anchors, args.Scope))
 
 	// 4. 构建禁令 (Guardrails)
 	guardrails := buildGuardrails(intent, args.ReadOnly)
+	if services.IsDocsProject(sm.ProjectRoot) {
+		applyDocsGuardrails(&guardrails)
+	}
 
 	// 5. 复杂度分析与遥测
 	telemetry := make(map[string]interface{})
 	var complexityAlerts []string
 
 	if len(args.Symbols) > 0 {
-		compReport, err := ai.AnalyzeComplexity(sm.ProjectRoot, args.Symbols)
-		if err == nil && compReport != nil {
-			maxScore := 0.0
-			for _, risk := range compReport.HighRiskSymbols {
-				if risk.Score > maxScore {
-					maxScore = risk.Score
-				}
-				if risk.Score >= 50 {
-					complexityAlerts = append(complexityAlerts, fmt.Sprintf("⚠️ [Complexity] %s: %.1f - %s", risk.SymbolName, risk.Score, risk.Reason))
-				}
-			}
-
-			level := getComplexityLevel(maxScore)
-			if level == "High" {
-				telemetry["complexity"] = map[string]interface{}{
-					"score": maxScore,
-					"level": level,
-				}
+		var maxScore float64
+		complexityAlerts, maxScore = analyzeComplexityAlerts(sm, ai, args.Symbols)
+		if level := getComplexityLevel(maxScore); level == "High" {
+			telemetry["complexity"] = map[string]interface{}{
+				"score": maxScore,
+				"level": level,
 			}
 		}
 	}
@@ -240,6 +405,7 @@ func handleAnalyzeStep1(ctx context.Context, sm *SessionManager, ai *services.AS
 	// 6. 生成综合警告
 	alerts := generateAlerts(args.TaskDescription, intent, args.ReadOnly)
 	alerts = append(alerts, complexityAlerts...)
+	alerts = append(alerts, hookExpiryAlerts(ctx, sm)...)
 
 	// 7. 保存状态到 Session
 	directive := truncateRunes(args.TaskDescription, 300)
@@ -252,11 +418,19 @@ func handleAnalyzeStep1(ctx context.Context, sm *SessionManager, ai *services.AS
 		Telemetry:      telemetry,
 		Guardrails:     guardrails,
 		Alerts:         alerts,
+		Symbols:        symbols,
+		Glossary:       glossaryHits,
+		Deferred:       deferred,
+		Scope:          args.Scope,
+		ReadOnly:       args.ReadOnly,
+		Audit:          startGuardrailAudit(sm),
+		UpdatedAt:      time.Now(),
 	}
 
 	if sm.AnalysisState == nil {
 		sm.AnalysisState = make(map[string]*AnalysisState)
 	}
+	pruneAnalysisStates(sm)
 	sm.AnalysisState[taskID] = state
 
 	// 8. 返回第一步结果（不包含 strategic_handoff）
@@ -272,8 +446,40 @@ func handleAnalyzeStep1(ctx context.Context, sm *SessionManager, ai *services.AS
 		"telemetry":       telemetry,
 		"guardrails":      guardrails,
 		"alerts":          alerts,
+		"glossary":        glossaryHits,
 		"next_step":       "调用 manager_analyze(step=2, task_id=\"" + taskID + "\") 生成战术策略",
 	}
+	if len(guardrails.Critical) > 0 || state.Audit != nil {
+		step1Result["guardrail_hint"] = "编辑前调用 guardrail_check(task_id=\"" + taskID + "\", files=[...]) 检查约束"
+	}
+	if state.Audit != nil {
+		step1Result["guardrail_audit"] = "已开启：未经 guardrail_check 放行或违反约束的修改会在 step=2/3 中告警"
+	}
+	if intentSource != "explicit" && intentSource != "heuristic" {
+		step1Result["intent_source"] = intentSource
+	}
+	if len(autoSymbols) > 0 {
+		step1Result["auto_symbols_hint"] = "未提供 symbols，已根据 task_description 自动匹配索引符号（见 context_anchors.confidence，低于 0.7 的仅供参考）；可显式传入 symbols 覆盖"
+	}
+	if len(unresolved) > 0 {
+		step1Result["unresolved_symbols"] = unresolved
+	}
+	if len(unresolved) > 0 && core.FeatureEnabled(sm.ProjectRoot, "scope_suggestions") {
+		// 未解析的符号给出候选目录，便于带 scope 重试
+		suggestions := make(map[string][]services.ScopeSuggestion)
+		for _, sym := range unresolved {
+			if sug := services.SuggestScopes(sm.ProjectRoot, sym, args.Scope, 3); len(sug) > 0 {
+				suggestions[sym] = sug
+			}
+		}
+		if len(suggestions) > 0 {
+			step1Result["scope_suggestions"] = suggestions
+		}
+	}
+	if len(deferred) > 0 {
+		step1Result["deferred_symbols"] = deferred
+		step1Result["deferred_hint"] = fmt.Sprintf("已达解析上限 %d，剩余 %d 个符号可通过 manager_analyze(step=3, task_id=\"%s\", resolve_more=true) 继续解析", maxAnchors, len(deferred), taskID)
+	}
 
 	jsonData, err := json.MarshalIndent(step1Result, "", "  ")
 	if err != nil {
@@ -283,6 +489,109 @@ func handleAnalyzeStep1(ctx context.Context, sm *SessionManager, ai *services.AS
 	return mcp.NewToolResultText(string(jsonData)), nil
 }
 
+// resolveMaxAnchors 参数 > settings.json analyze.max_anchors > 默认 10
+func resolveMaxAnchors(sm *SessionManager, explicit int) int {
+	if explicit > 0 {
+		return explicit
+	}
+	return core.LoadProjectSettings(sm.ProjectRoot).Analyze.MaxAnchors
+}
+
+// loadVerifiedFacts 按任务描述与符号/路径检索相关事实
+func loadVerifiedFacts(ctx context.Context, sm *SessionManager, desc string, tc core.FactScopeContext) []VerifiedFact {
+	facts := []VerifiedFact{}
+	if sm.Memory == nil {
+		return facts
+	}
+	knownFacts, _ := sm.Memory.QueryFactsForTask(ctx, buildFactKeywords(desc, tc.Symbols), tc, 10)
+	for _, f := range knownFacts {
+		vf := VerifiedFact{
+			ID:        f.ID,
+			Type:      f.Type,
+			Summary:   f.Summarize,
+			CreatedAt: f.CreatedAt.Format("2006-01-02 15:04"),
+			Source:    "project",
+			Scope:     f.Scope,
+		}
+		if f.Priority != core.FactPriorityMedium {
+			vf.Priority = f.Priority
+		}
+		if f.ExpiresAt.Valid {
+			vf.ExpiresAt = f.ExpiresAt.Time.Format("2006-01-02 15:04")
+		}
+		facts = append(facts, vf)
+	}
+	return facts
+}
+
+// factScopeContext 汇总任务涉及的符号与路径（锚点文件 + scope），用于匹配作用域事实
+func factScopeContext(symbols []string, anchors []CodeAnchor, scope string) core.FactScopeContext {
+	tc := core.FactScopeContext{Symbols: symbols}
+	for _, a := range anchors {
+		if a.File != "" {
+			tc.Paths = append(tc.Paths, a.File)
+		}
+		if a.Symbol != "" {
+			tc.Symbols = append(tc.Symbols, a.Symbol)
+		}
+	}
+	if s := strings.TrimSpace(scope); s != "" {
+		tc.Paths = append(tc.Paths, s)
+	}
+	return tc
+}
+
+// hookExpiryAlerts 已过期 / 即将过期的待办钩子提醒
+func hookExpiryAlerts(ctx context.Context, sm *SessionManager) []string {
+	if sm.Memory == nil {
+		return nil
+	}
+	_, _ = sm.Memory.ExpireHooks(ctx)
+
+	var alerts []string
+	if expired, err := sm.Memory.ListHooks(ctx, core.HookExpired); err == nil && len(expired) > 0 {
+		alerts = append(alerts, fmt.Sprintf("⏰ [Hooks] %d 个钩子已过期未处理: %s。查看 manager_list_hooks(status=\"expired\")，处理后用 manager_release_hook 闭合。",
+			len(expired), hookBrief(expired)))
+	}
+	if soon, err := sm.Memory.ExpiringHooks(ctx, core.HookExpiringSoonWindow); err == nil && len(soon) > 0 {
+		alerts = append(alerts, fmt.Sprintf("⏳ [Hooks] %d 个钩子将在 24 小时内过期: %s", len(soon), hookBrief(soon)))
+	}
+	return alerts
+}
+
+// hookBrief 钩子的简短列表（最多 3 个）
+func hookBrief(hooks []core.Hook) string {
+	parts := make([]string, 0, 3)
+	for i, h := range hooks {
+		if i == 3 {
+			parts = append(parts, fmt.Sprintf("等 %d 个", len(hooks)))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", fallback(h.Summary, h.HookID), truncateRunes(h.Description, 40)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// analyzeComplexityAlerts 超过阈值的符号生成告警，并返回最高分
+func analyzeComplexityAlerts(sm *SessionManager, ai *services.ASTIndexer, symbols []string) ([]string, float64) {
+	compReport, err := ai.AnalyzeComplexityExcluding(sm.ProjectRoot, symbols, resolvePathTagger(sm, false))
+	if err != nil || compReport == nil {
+		return nil, 0
+	}
+	threshold := core.LoadProjectSettings(sm.ProjectRoot).Complexity.AlertThreshold
+	var alerts []string
+	maxScore := 0.0
+	for _, risk := range compReport.HighRiskSymbols {
+		if risk.Score > maxScore {
+			maxScore = risk.Score
+		}
+		if risk.Score >= threshold {
+			alerts = append(alerts, fmt.Sprintf("⚠️ [Complexity] %s: %.1f - %s", risk.SymbolName, risk.Score, risk.Reason))
+		}
+	}
+	return alerts, maxScore
+}
+
 // handleAnalyzeStep2 执行第二步：基于第一步结果动态生成 strategic_handoff
 func handleAnalyzeStep2(sm *SessionManager, ai *services.ASTIndexer, args AnalyzeArgs, taskID string) (*mcp.CallToolResult, error) {
 	// 1. 从 Session 读取第一步的状态
@@ -291,14 +600,17 @@ func handleAnalyzeStep2(sm *SessionManager, ai *services.ASTIndexer, args Analyz
 		return mcp.NewToolResultError("⚠️ 未找到第一步的分析结果，请先调用 manager_analyze(step=1)"), nil
 	}
 
-	// 2. 基于第一步结果动态生成 strategic_handoff
+	// 2. 写入审计：新发现的违规并入告警
+	state.Alerts = append(state.Alerts, guardrailViolations(sm, taskID, state)...)
+
+	// 3. 基于第一步结果动态生成 strategic_handoff
 	strategicHandoff := generateDynamicStrategicHandoff(state)
 
-	// 3. 组装完整的 Mission Briefing
+	// 4. 组装完整的 Mission Briefing
 	briefing := MissionBriefing{
 		MissionControl: MissionControl{
 			Intent:        state.Intent,
-			UserDirective: state.UserDirective,
+			UserDirective: directiveWithUpdates(state),
 		},
 		ContextAnchors:   state.ContextAnchors,
 		VerifiedFacts:    state.VerifiedFacts,
@@ -308,10 +620,9 @@ func handleAnalyzeStep2(sm *SessionManager, ai *services.ASTIndexer, args Analyz
 		StrategicHandoff: strategicHandoff,
 	}
 
-	// 4. 清理临时状态
-	delete(sm.AnalysisState, taskID)
+	// 5. 保留状态，供 step=3 增量更新（过期状态在下次 step=1 时清理）
 
-	// 5. 返回第二步结果
+	// 6. 返回第二步结果
 	jsonData, err := json.MarshalIndent(briefing, "", "  ")
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("JSON 序列化失败: %v", err)), nil
@@ -341,6 +652,21 @@ func generateDynamicStrategicHandoff(state *AnalysisState) string {
 		parts = append(parts, fmt.Sprintf("已定位到 %d 个代码符号", len(state.ContextAnchors)))
 	}
 
+	// 2.1.1 术语表命中
+	if len(state.Glossary) > 0 {
+		parts = append(parts, "[领域术语] 任务描述命中项目术语表：")
+		for _, g := range state.Glossary {
+			line := fmt.Sprintf("- %s", g.Term)
+			if g.Definition != "" {
+				line += ": " + g.Definition
+			}
+			if len(g.Paths) > 0 {
+				line += fmt.Sprintf(" (路径: %s)", strings.Join(g.Paths, ", "))
+			}
+			parts = append(parts, line)
+		}
+	}
+
 	// 2.2 复杂度评估
 	if comp, ok := state.Telemetry["complexity"].(map[string]interface{}); ok {
 		if level, ok := comp["level"].(string); ok {
@@ -366,6 +692,15 @@ func generateDynamicStrategicHandoff(state *AnalysisState) string {
 		parts = append(parts, "!!! END OF CRITICAL CONSTRAINTS !!!")
 	}
 
+	// 2.4 已验证事实：要求引用 ID，便于追溯行为来源
+	if len(state.VerifiedFacts) > 0 {
+		parts = append(parts, "")
+		parts = append(parts, "[已验证事实] (遵循某条事实时请注明 fact #ID)")
+		for _, f := range state.VerifiedFacts {
+			parts = append(parts, fmt.Sprintf("- fact #%d [%s] %s (%s, %s)", f.ID, f.Type, f.Summary, f.Source, f.CreatedAt))
+		}
+	}
+
 	// 3. 执行策略（按 intent 差异化）
 	parts = append(parts, "")
 	parts = append(parts, "[执行策略]")
@@ -702,38 +1037,21 @@ func getIntentToolStrategy(intent string, hasAnchors bool) []string {
 
 // 辅助逻辑
 
-func determineIntent(desc, explicitIntent string, readOnly bool) string {
-	validIntents := map[string]bool{
-		"DEBUG": true, "DEVELOP": true, "REFACTOR": true,
-		"DESIGN": true, "RESEARCH": true, "PERFORMANCE": true, "REFLECT": true,
-	}
+// validIntents manager_analyze 支持的意图
+var validIntents = map[string]bool{
+	"DEBUG": true, "DEVELOP": true, "REFACTOR": true,
+	"DESIGN": true, "RESEARCH": true, "PERFORMANCE": true, "REFLECT": true, "ONBOARD": true,
+}
 
+// determineIntent 显式意图优先，否则使用内置关键词启发式（不读取项目配置）
+func determineIntent(desc, explicitIntent string, readOnly bool) string {
 	if explicitIntent != "" {
 		upper := strings.ToUpper(explicitIntent)
 		if validIntents[upper] {
 			return upper
 		}
 	}
-
-	descLower := strings.ToLower(desc)
-	if strings.Contains(descLower, "debug") || strings.Contains(descLower, "fix") || strings.Contains(descLower, "修复") || strings.Contains(descLower, "报错") {
-		return "DEBUG"
-	}
-	if strings.Contains(descLower, "refactor") || strings.Contains(descLower, "重构") {
-		return "REFACTOR"
-	}
-	if strings.Contains(descLower, "analy") || strings.Contains(descLower, "分析") || strings.Contains(descLower, "调研") || strings.Contains(descLower, "research") {
-		return "RESEARCH"
-	}
-	if strings.Contains(descLower, "design") || strings.Contains(descLower, "设计") || strings.Contains(descLower, "架构") {
-		return "DESIGN"
-	}
-
-	if readOnly {
-		return "RESEARCH"
-	}
-
-	return ""
+	return heuristicIntent(desc, readOnly, nil)
 }
 
 func buildGuardrails(intent string, readOnly bool) Guardrails {
@@ -840,11 +1158,75 @@ func wrapSaveFact(sm *SessionManager) server.ToolHandlerFunc {
 			return mcp.NewToolResultError(fmt.Sprintf("参数格式错误: %v", err)), nil
 		}
 
-		id, err := sm.Memory.SaveFact(ctx, args.Type, args.Summarize)
+		if !args.Force {
+			dups, err := sm.Memory.FindSimilarFacts(ctx, args.Summarize, core.DefaultFactDuplicateThreshold, 3)
+			if err == nil && len(dups) > 0 {
+				var sb strings.Builder
+				best := dups[0].Fact
+				sb.WriteString(fmt.Sprintf("ℹ️ 已存在近似事实，未重复保存 (ID: %d): [%s] %s\n", best.ID, best.Type, best.Summarize))
+				if len(dups) > 1 {
+					sb.WriteString("\n其他相似事实:\n")
+					for _, d := range dups[1:] {
+						sb.WriteString(fmt.Sprintf("  - (ID: %d, 相似度 %.0f%%) [%s] %s\n", d.Fact.ID, d.Similarity*100, d.Fact.Type, d.Fact.Summarize))
+					}
+				}
+				sb.WriteString("\n> 如确需另存一条，请使用 `known_facts(..., force=true)`。")
+				return mcp.NewToolResultText(sb.String()), nil
+			}
+		}
+
+		if !args.Force && len(args.Supersedes) == 0 {
+			embedder, _ := core.NewEmbedder(sm.ProjectRoot, core.LoadProjectSettings(sm.ProjectRoot).Embedding)
+			conflicts, err := sm.Memory.FindConflictingFacts(ctx, args.Summarize, embedder, 5)
+			if err == nil && len(conflicts) > 0 {
+				var sb strings.Builder
+				sb.WriteString(fmt.Sprintf("⚠️ 新事实可能与 %d 条已有事实冲突，未保存:\n\n", len(conflicts)))
+				sb.WriteString(fmt.Sprintf("  + [%s] %s\n", args.Type, args.Summarize))
+				ids := make([]string, 0, len(conflicts))
+				for _, c := range conflicts {
+					sb.WriteString(fmt.Sprintf("  - (ID: %d, %s, 相似度 %.0f%%) [%s] %s\n", c.Fact.ID, c.Reason, c.Similarity*100, c.Fact.Type, c.Fact.Summarize))
+					ids = append(ids, fmt.Sprintf("%d", c.Fact.ID))
+				}
+				sb.WriteString(fmt.Sprintf("\n> 新规则替代旧规则: `known_facts(..., supersedes=[%s])`\n", strings.Join(ids, ", ")))
+				sb.WriteString("> 二者并不矛盾、需要并存: `known_facts(..., force=true)`")
+				return mcp.NewToolResultText(sb.String()), nil
+			}
+		}
+
+		opts := core.FactOptions{Scope: args.Scope, Priority: args.Priority}
+		if ttl := strings.TrimSpace(args.TTL); ttl != "" {
+			d, err := core.ParseTTL(ttl)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+			}
+			opts.TTL = d
+		}
+		id, err := sm.Memory.SaveFactWithOptions(ctx, args.Type, args.Summarize, opts)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("保存事实失败: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("✅ 事实已存入数据库 (ID: %d): [%s] %s", id, args.Type, args.Summarize)), nil
+		var extra []string
+		for _, old := range args.Supersedes {
+			if err := sm.Memory.SupersedeFact(ctx, old, id); err != nil {
+				extra = append(extra, fmt.Sprintf("⚠️ 取代 %d 失败: %v", old, err))
+			} else {
+				extra = append(extra, fmt.Sprintf("已取代 ID %d", old))
+			}
+		}
+		if scope, _ := core.NormalizeFactScope(args.Scope); scope != "" {
+			extra = append(extra, "作用域 "+scope)
+		}
+		if p, _ := core.NormalizeFactPriority(args.Priority); p != core.FactPriorityMedium {
+			extra = append(extra, "优先级 "+p)
+		}
+		if opts.TTL > 0 {
+			extra = append(extra, "有效期至 "+time.Now().Add(opts.TTL).Format("2006-01-02 15:04"))
+		}
+		suffix := ""
+		if len(extra) > 0 {
+			suffix = "\n" + strings.Join(extra, " · ")
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("✅ 事实已存入数据库 (ID: %d): [%s] %s%s", id, args.Type, args.Summarize, suffix)), nil
 	}
 }
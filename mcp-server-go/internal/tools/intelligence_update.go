@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== manager_analyze step=3：在已有分析状态上增量更新 ==========
+
+// analysisStateTTL 分析状态在会话中的保留时长
+const analysisStateTTL = 24 * time.Hour
+
+// AnalysisDelta 增量更新的变更摘要
+type AnalysisDelta struct {
+	NewAnchors        []CodeAnchor   `json:"new_anchors"`
+	NewTerms          []string       `json:"new_terms,omitempty"` // 新命中的术语表条目
+	UnresolvedSymbols []string       `json:"unresolved_symbols,omitempty"`
+	SkippedSymbols    []string       `json:"skipped_symbols,omitempty"`  // 之前已解析过
+	DeferredSymbols   []string       `json:"deferred_symbols,omitempty"` // 超出解析上限，留待下次 resolve_more
+	NewFacts          []VerifiedFact `json:"new_facts"`
+	NewAlerts         []string       `json:"new_alerts"`
+	IntentChanged     string         `json:"intent_changed,omitempty"`
+	AddedContext      string         `json:"added_context,omitempty"`
+}
+
+func (d *AnalysisDelta) empty() bool {
+	return len(d.NewAnchors) == 0 && len(d.NewTerms) == 0 && len(d.NewFacts) == 0 && len(d.NewAlerts) == 0 &&
+		d.IntentChanged == "" && d.AddedContext == ""
+}
+
+// pruneAnalysisStates 清理超过保留时长的分析状态
+func pruneAnalysisStates(sm *SessionManager) {
+	for id, st := range sm.AnalysisState {
+		if !st.UpdatedAt.IsZero() && time.Since(st.UpdatedAt) > analysisStateTTL {
+			delete(sm.AnalysisState, id)
+		}
+	}
+}
+
+// directiveWithUpdates 原始指令 + 增量补充的信息
+func directiveWithUpdates(state *AnalysisState) string {
+	if len(state.Updates) == 0 {
+		return state.UserDirective
+	}
+	var sb strings.Builder
+	sb.WriteString(state.UserDirective)
+	for i, u := range state.Updates {
+		sb.WriteString(fmt.Sprintf("\n[补充 %d] %s", i+1, u))
+	}
+	return sb.String()
+}
+
+// handleAnalyzeUpdate 合并新符号/事实到已有分析状态，仅解析新增锚点并输出 delta 简报
+func handleAnalyzeUpdate(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, args AnalyzeArgs, taskID string) (*mcp.CallToolResult, error) {
+	state, exists := sm.AnalysisState[taskID]
+	if !exists {
+		return mcp.NewToolResultError("⚠️ 未找到该 task_id 的分析状态（可能已过期或服务已重启），请重新调用 manager_analyze(step=1)"), nil
+	}
+
+	delta := &AnalysisDelta{}
+	scope := state.Scope
+	if strings.TrimSpace(args.Scope) != "" {
+		scope = args.Scope
+	}
+
+	// 0. 新补充信息命中的术语（已命中过的不再重复）
+	knownTerms := make(map[string]bool, len(state.Glossary))
+	for _, g := range state.Glossary {
+		knownTerms[strings.ToLower(g.Term)] = true
+	}
+	candidates := append([]string{}, args.Symbols...)
+	if args.ResolveMore {
+		candidates = append(append([]string{}, state.Deferred...), candidates...)
+		state.Deferred = nil
+	}
+	var newTerms []core.GlossaryEntry
+	if hits, _ := glossarySymbolsFor(ctx, sm, args.TaskDescription); len(hits) > 0 {
+		for _, g := range hits {
+			if knownTerms[strings.ToLower(g.Term)] {
+				continue
+			}
+			newTerms = append(newTerms, g)
+			candidates = append(candidates, g.Symbols...)
+			state.Glossary = append(state.Glossary, g)
+			delta.NewTerms = append(delta.NewTerms, g.Term)
+		}
+	}
+
+	// 1. 仅解析新增符号
+	known := make(map[string]bool, len(state.Symbols))
+	for _, sym := range state.Symbols {
+		known[sym] = true
+	}
+	anchored := make(map[string]bool, len(state.ContextAnchors))
+	for _, a := range state.ContextAnchors {
+		anchored[fmt.Sprintf("%s:%d", a.File, a.Line)] = true
+	}
+	var newSymbols []string
+	for _, sym := range candidates {
+		sym = strings.TrimSpace(sym)
+		if sym == "" {
+			continue
+		}
+		if known[sym] {
+			delta.SkippedSymbols = append(delta.SkippedSymbols, sym)
+			continue
+		}
+		known[sym] = true
+		newSymbols = append(newSymbols, sym)
+	}
+	if maxAnchors := resolveMaxAnchors(sm, args.MaxAnchors); len(newSymbols) > maxAnchors {
+		delta.DeferredSymbols = newSymbols[maxAnchors:]
+		state.Deferred = append(state.Deferred, delta.DeferredSymbols...)
+		newSymbols = newSymbols[:maxAnchors]
+	}
+	if len(newSymbols) > 0 {
+		if strings.TrimSpace(scope) != "" {
+			_, _ = ai.IndexScope(sm.ProjectRoot, scope)
+		} else {
+			_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		}
+	}
+	for _, sym := range newSymbols {
+		state.Symbols = append(state.Symbols, sym)
+		anchor := resolveCodeAnchor(ctx, sm, ai, sym, scope)
+		if anchor == nil {
+			delta.UnresolvedSymbols = append(delta.UnresolvedSymbols, sym)
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", anchor.File, anchor.Line)
+		if anchored[key] {
+			continue
+		}
+		anchored[key] = true
+		state.ContextAnchors = append(state.ContextAnchors, *anchor)
+		delta.NewAnchors = append(delta.NewAnchors, *anchor)
+	}
+	for _, a := range glossaryPathAnchors(newTerms) {
+		state.ContextAnchors = append(state.ContextAnchors, a)
+		delta.NewAnchors = append(delta.NewAnchors, a)
+	}
+
+	// 2. 新补充的任务信息
+	if info := strings.TrimSpace(args.TaskDescription); info != "" && info != state.UserDirective {
+		delta.AddedContext = truncateRunes(info, 300)
+		state.Updates = append(state.Updates, delta.AddedContext)
+	}
+
+	// 3. 显式改变意图时重建禁令
+	if explicit := strings.ToUpper(strings.TrimSpace(args.Intent)); explicit != "" {
+		intent := determineIntent(args.TaskDescription, explicit, state.ReadOnly || args.ReadOnly)
+		if intent == explicit && intent != state.Intent {
+			delta.IntentChanged = fmt.Sprintf("%s → %s", state.Intent, intent)
+			state.Intent = intent
+			state.ReadOnly = state.ReadOnly || args.ReadOnly
+			state.Guardrails = buildGuardrails(intent, state.ReadOnly)
+			if services.IsDocsProject(sm.ProjectRoot) {
+				applyDocsGuardrails(&state.Guardrails)
+			}
+		}
+	}
+
+	// 4. 合并新事实（按 ID 去重）
+	if len(newSymbols) > 0 || delta.AddedContext != "" {
+		seenFacts := make(map[int64]bool, len(state.VerifiedFacts))
+		for _, f := range state.VerifiedFacts {
+			seenFacts[f.ID] = true
+		}
+		for _, f := range loadVerifiedFacts(ctx, sm, args.TaskDescription, factScopeContext(newSymbols, state.ContextAnchors, state.Scope)) {
+			if seenFacts[f.ID] {
+				continue
+			}
+			seenFacts[f.ID] = true
+			state.VerifiedFacts = append(state.VerifiedFacts, f)
+			delta.NewFacts = append(delta.NewFacts, f)
+		}
+	}
+
+	// 5. 新符号的复杂度告警
+	if len(newSymbols) > 0 {
+		alerts, maxScore := analyzeComplexityAlerts(sm, ai, newSymbols)
+		existing := make(map[string]bool, len(state.Alerts))
+		for _, a := range state.Alerts {
+			existing[a] = true
+		}
+		for _, a := range alerts {
+			if !existing[a] {
+				state.Alerts = append(state.Alerts, a)
+				delta.NewAlerts = append(delta.NewAlerts, a)
+			}
+		}
+		prev := 0.0
+		if comp, ok := state.Telemetry["complexity"].(map[string]interface{}); ok {
+			prev, _ = comp["score"].(float64)
+		}
+		if level := getComplexityLevel(maxScore); level == "High" && maxScore > prev {
+			if state.Telemetry == nil {
+				state.Telemetry = make(map[string]interface{})
+			}
+			state.Telemetry["complexity"] = map[string]interface{}{
+				"score": maxScore,
+				"level": level,
+			}
+		}
+	}
+
+	// 6. 写入审计：新发现的违规
+	if violations := guardrailViolations(sm, taskID, state); len(violations) > 0 {
+		state.Alerts = append(state.Alerts, violations...)
+		delta.NewAlerts = append(delta.NewAlerts, violations...)
+	}
+
+	state.Revision++
+	state.UpdatedAt = time.Now()
+
+	result := map[string]interface{}{
+		"step":     3,
+		"task_id":  taskID,
+		"revision": state.Revision,
+		"delta":    delta,
+		"totals": map[string]int{
+			"context_anchors": len(state.ContextAnchors),
+			"verified_facts":  len(state.VerifiedFacts),
+			"alerts":          len(state.Alerts),
+			"deferred":        len(state.Deferred),
+		},
+	}
+	if delta.empty() {
+		result["note"] = "没有新增信息，简报保持不变"
+	} else {
+		result["strategic_handoff"] = generateDynamicStrategicHandoff(state)
+	}
+	if len(state.Deferred) > 0 {
+		result["deferred_hint"] = fmt.Sprintf("仍有 %d 个符号待解析，可再次调用 manager_analyze(step=3, task_id=\"%s\", resolve_more=true)", len(state.Deferred), taskID)
+	}
+	result["next_step"] = "继续执行；出现新线索时可再次调用 manager_analyze(step=3, task_id=\"" + taskID + "\")，或 step=2 获取完整简报"
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("JSON 序列化失败: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== 意图识别 (settings.json analyze.intent 段) ==========
+//
+// manager_analyze 只依赖 IntentClassifier 接口，识别方式可插拔：
+//   heuristic — 内置中英文关键词（默认），可用 keywords 追加团队领域词
+//   command   — 本地命令，stdin 读入请求 JSON，stdout 输出 {"intent": "DEBUG"} 或单个意图词
+//   http      — POST 请求 JSON 到 endpoint，响应同 command
+// 外部识别失败、超时或返回未知意图时回退到启发式，不阻断分析。
+//
+// 请求 JSON: {"task_description": "...", "read_only": false, "intents": ["DEBUG", ...]}
+
+// IntentClassifier 从任务描述推断意图；返回空串表示无法判断
+type IntentClassifier interface {
+	Name() string
+	Classify(ctx context.Context, desc string, readOnly bool) (string, error)
+}
+
+const defaultIntentTimeout = 3 * time.Second
+
+// intentRules 内置关键词规则，按顺序匹配，先命中者优先
+var intentRules = []struct {
+	intent   string
+	keywords []string
+}{
+	{"ONBOARD", []string{"onboard", "熟悉项目", "接手项目"}},
+	{"DEBUG", []string{"debug", "fix", "修复", "报错"}},
+	{"REFACTOR", []string{"refactor", "重构"}},
+	{"RESEARCH", []string{"analy", "分析", "调研", "research"}},
+	{"DESIGN", []string{"design", "设计", "架构"}},
+}
+
+// heuristicIntent 关键词启发式；extra 中的团队关键词先于内置规则匹配
+func heuristicIntent(desc string, readOnly bool, extra map[string][]string) string {
+	descLower := strings.ToLower(desc)
+
+	intents := make([]string, 0, len(extra))
+	for intent := range extra {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+	for _, intent := range intents {
+		upper := strings.ToUpper(intent)
+		if !validIntents[upper] {
+			continue
+		}
+		for _, kw := range extra[intent] {
+			if kw = strings.ToLower(strings.TrimSpace(kw)); kw != "" && strings.Contains(descLower, kw) {
+				return upper
+			}
+		}
+	}
+
+	for _, rule := range intentRules {
+		for _, kw := range rule.keywords {
+			if strings.Contains(descLower, kw) {
+				return rule.intent
+			}
+		}
+	}
+
+	if readOnly {
+		return "RESEARCH"
+	}
+	return ""
+}
+
+// NewIntentClassifier 按配置创建识别器；classifier 为空时使用启发式。
+// command 识别器经项目的 CommandRunner 执行（须在 commands.allow 白名单中）
+func NewIntentClassifier(projectRoot string, cfg core.IntentSettings) (IntentClassifier, error) {
+	timeout := defaultIntentTimeout
+	if cfg.TimeoutMs > 0 {
+		timeout = time.Duration(cfg.TimeoutMs) * time.Millisecond
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Classifier)) {
+	case "", "heuristic":
+		return heuristicClassifier{keywords: cfg.Keywords}, nil
+	case "command":
+		argv, err := core.SplitCommandLine(cfg.Command)
+		if err != nil || len(argv) == 0 {
+			return nil, fmt.Errorf("analyze.intent.command 无效: %q", cfg.Command)
+		}
+		return &commandIntentClassifier{projectRoot: projectRoot, argv: argv, timeout: timeout}, nil
+	case "http":
+		if strings.TrimSpace(cfg.Endpoint) == "" {
+			return nil, fmt.Errorf("analyze.intent.classifier=http 需要配置 endpoint")
+		}
+		return &httpIntentClassifier{endpoint: cfg.Endpoint, client: &http.Client{Timeout: timeout}}, nil
+	default:
+		return nil, fmt.Errorf("未知的 analyze.intent.classifier: %s（可用: heuristic, command, http）", cfg.Classifier)
+	}
+}
+
+// classifyIntent 显式意图 > 配置的识别器 > 启发式；返回意图及其来源
+func classifyIntent(ctx context.Context, projectRoot, desc, explicitIntent string, readOnly bool) (string, string) {
+	if upper := strings.ToUpper(strings.TrimSpace(explicitIntent)); validIntents[upper] {
+		return upper, "explicit"
+	}
+	cfg := core.LoadProjectSettings(projectRoot).Analyze.Intent
+	heuristic := func() (string, string) { return heuristicIntent(desc, readOnly, cfg.Keywords), "heuristic" }
+
+	c, err := NewIntentClassifier(projectRoot, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Intent][WARN] %v，回退到启发式\n", err)
+		return heuristic()
+	}
+	if _, ok := c.(heuristicClassifier); ok {
+		return heuristic()
+	}
+	intent, err := c.Classify(ctx, desc, readOnly)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Intent][WARN] %s 识别失败，回退到启发式: %v\n", c.Name(), err)
+		return heuristic()
+	}
+	if intent = strings.ToUpper(strings.TrimSpace(intent)); !validIntents[intent] {
+		return heuristic()
+	}
+	return intent, c.Name()
+}
+
+// --- heuristic ---
+
+type heuristicClassifier struct {
+	keywords map[string][]string
+}
+
+func (heuristicClassifier) Name() string { return "heuristic" }
+
+func (h heuristicClassifier) Classify(ctx context.Context, desc string, readOnly bool) (string, error) {
+	return heuristicIntent(desc, readOnly, h.keywords), nil
+}
+
+// --- 外部识别器共用的请求/响应 ---
+
+func intentRequestBody(desc string, readOnly bool) []byte {
+	intents := make([]string, 0, len(validIntents))
+	for intent := range validIntents {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+	body, _ := json.Marshal(map[string]interface{}{
+		"task_description": desc,
+		"read_only":        readOnly,
+		"intents":          intents,
+	})
+	return body
+}
+
+// parseIntentResponse 接受 {"intent": "..."} 或纯文本意图词
+func parseIntentResponse(data []byte) string {
+	var parsed struct {
+		Intent string `json:"intent"`
+	}
+	if json.Unmarshal(data, &parsed) == nil {
+		return parsed.Intent
+	}
+	return strings.Trim(strings.TrimSpace(string(data)), `"`)
+}
+
+// --- command ---
+
+type commandIntentClassifier struct {
+	projectRoot string
+	argv        []string
+	timeout     time.Duration
+}
+
+func (c *commandIntentClassifier) Name() string { return "command:" + c.argv[0] }
+
+func (c *commandIntentClassifier) Classify(ctx context.Context, desc string, readOnly bool) (string, error) {
+	res, err := core.NewCommandRunner(c.projectRoot).Run(ctx, core.CommandRequest{
+		Args: c.argv, Source: "intent", Timeout: c.timeout,
+		Stdin: intentRequestBody(desc, readOnly), SeparateStderr: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("命令执行失败: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("命令退出码 %d: %s", res.ExitCode, truncateRunes(strings.TrimSpace(res.Stderr), 200))
+	}
+	return parseIntentResponse([]byte(res.Output)), nil
+}
+
+// --- http ---
+
+type httpIntentClassifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (c *httpIntentClassifier) Name() string { return "http" }
+
+func (c *httpIntentClassifier) Classify(ctx context.Context, desc string, readOnly bool) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(intentRequestBody(desc, readOnly)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("接口返回 %d: %s", resp.StatusCode, truncateRunes(string(data), 200))
+	}
+	return parseIntentResponse(data), nil
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestClassifyIntentPluggable(t *testing.T) {
+	ctx := context.Background()
+	reply := `{"intent": "performance"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, reply)
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	settings := core.DefaultProjectSettings()
+	settings.Analyze.Intent = core.IntentSettings{Classifier: "http", Endpoint: srv.URL, Keywords: map[string][]string{"DEBUG": {"工单"}}}
+	if err := core.SaveProjectSettings(root, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if intent, src := classifyIntent(ctx, root, "接口变慢", "", false); intent != "PERFORMANCE" || src != "http" {
+		t.Errorf("http classifier: got %s/%s", intent, src)
+	}
+	if intent, src := classifyIntent(ctx, root, "接口变慢", "refactor", false); intent != "REFACTOR" || src != "explicit" {
+		t.Errorf("explicit intent should win: got %s/%s", intent, src)
+	}
+
+	reply = "SOMETHING_ELSE"
+	if intent, src := classifyIntent(ctx, root, "处理工单 #42 的设计问题", "", false); intent != "DEBUG" || src != "heuristic" {
+		t.Errorf("unknown intent should fall back to heuristic with team keywords first: got %s/%s", intent, src)
+	}
+
+	if _, err := NewIntentClassifier(root, core.IntentSettings{Classifier: "bogus"}); err == nil {
+		t.Error("unknown classifier should be rejected")
+	}
+}
@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// ========== 知识地图 (project_map level=knowledge) ==========
+// 在目录结构上叠加记忆密度：每个目录被 memos/facts/未完成钩子引用的次数，
+// 与该目录的调用复杂度对照，找出"高复杂度、零记忆"的风险盲区。
+
+// knowledgeDir 单个目录的复杂度与记忆密度
+type knowledgeDir struct {
+	Path     string
+	Files    int
+	Symbols  int
+	MaxScore float64
+	HighRisk int
+	Memos    int
+	Facts    int
+	Hooks    int
+}
+
+func (d *knowledgeDir) refs() int { return d.Memos + d.Facts + d.Hooks }
+
+// knowledgeResolver 将记忆中提到的文件引用解析到目录
+type knowledgeResolver struct {
+	dirs  map[string]*knowledgeDir
+	files map[string]string // 项目相对文件路径 -> 目录
+}
+
+func (r *knowledgeResolver) addFile(file string) {
+	file = strings.TrimPrefix(strings.ReplaceAll(file, "\\", "/"), "./")
+	if file == "" {
+		return
+	}
+	r.files[file] = path.Dir(file)
+}
+
+// resolve 目录本身 > 精确文件路径 > 唯一后缀匹配 > 引用中的目录部分
+func (r *knowledgeResolver) resolve(ref string) *knowledgeDir {
+	ref = strings.TrimPrefix(strings.ReplaceAll(strings.TrimSpace(ref), "\\", "/"), "./")
+	if i := strings.LastIndex(ref, ":"); i > 0 {
+		ref = ref[:i] // file:line 标签
+	}
+	if ref == "" {
+		return nil
+	}
+	if d, ok := r.dirs[strings.TrimSuffix(ref, "/")]; ok {
+		return d
+	}
+	if dir, ok := r.files[ref]; ok {
+		return r.dir(dir)
+	}
+	match := ""
+	for f, dir := range r.files {
+		if strings.HasSuffix(f, "/"+ref) {
+			if match != "" && match != dir {
+				return nil // 多个目录同名文件，无法归属
+			}
+			match = dir
+		}
+	}
+	if match != "" {
+		return r.dir(match)
+	}
+	if d, ok := r.dirs[path.Dir(ref)]; ok {
+		return d
+	}
+	return nil
+}
+
+func (r *knowledgeResolver) dir(p string) *knowledgeDir {
+	if p == "." {
+		p = ""
+	}
+	return r.dirs[p]
+}
+
+// refsOf 一条记忆引用的目录（同一条记忆对同一目录只计一次）
+func (r *knowledgeResolver) refsOf(texts ...string) []*knowledgeDir {
+	seen := make(map[*knowledgeDir]bool)
+	var result []*knowledgeDir
+	for _, t := range texts {
+		candidates := extractClaimedFiles(t)
+		if len(candidates) == 0 && !strings.ContainsAny(t, " \t\n") {
+			candidates = []string{t} // memo.path / hook tag 本身即路径
+		}
+		for _, c := range candidates {
+			if d := r.resolve(c); d != nil && !seen[d] {
+				seen[d] = true
+				result = append(result, d)
+			}
+		}
+	}
+	return result
+}
+
+// buildKnowledgeMap 汇总目录复杂度与记忆引用
+func buildKnowledgeMap(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, scope string, tagger *services.PathTagger) ([]*knowledgeDir, float64, error) {
+	structure, err := ai.StructureProjectWithScope(sm.ProjectRoot, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	tagger.FilterStructure(structure)
+
+	r := &knowledgeResolver{dirs: make(map[string]*knowledgeDir), files: make(map[string]string)}
+	for p, info := range structure.Structure {
+		r.dirs[p] = &knowledgeDir{Path: p, Files: info.FileCount}
+		for _, f := range info.Files {
+			r.addFile(path.Join(p, f))
+		}
+	}
+
+	threshold := float64(core.LoadProjectSettings(sm.ProjectRoot).Complexity.AlertThreshold)
+	if g, err := ai.LoadCallGraph(sm.ProjectRoot); err == nil {
+		g.ExcludePaths(tagger)
+		for id, sym := range g.Symbols {
+			r.addFile(sym.FilePath)
+			d := r.resolve(sym.FilePath)
+			if d == nil {
+				continue
+			}
+			score := float64(g.FanOut[id])*1.0 + float64(g.FanIn[id])*0.5
+			d.Symbols++
+			if score > d.MaxScore {
+				d.MaxScore = score
+			}
+			if score >= threshold {
+				d.HighRisk++
+			}
+		}
+	}
+
+	if sm.Memory != nil {
+		if memos, err := sm.Memory.QueryMemos(ctx, "", "", 5000); err == nil {
+			for _, m := range memos {
+				for _, d := range r.refsOf(m.Path, m.Content) {
+					d.Memos++
+				}
+			}
+		}
+		if facts, err := sm.Memory.QueryFacts(ctx, "", 5000); err == nil {
+			for _, f := range facts {
+				for _, d := range r.refsOf(f.Summarize) {
+					d.Facts++
+				}
+			}
+		}
+		if hooks, err := sm.Memory.ListHooks(ctx, "open"); err == nil {
+			for _, h := range hooks {
+				for _, d := range r.refsOf(h.Tag, h.Description) {
+					d.Hooks++
+				}
+			}
+		}
+	}
+
+	dirs := make([]*knowledgeDir, 0, len(r.dirs))
+	for _, d := range r.dirs {
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Path < dirs[j].Path })
+	return dirs, threshold, nil
+}
+
+// renderKnowledgeMap 渲染知识地图：风险盲区、记忆密集区与目录明细
+func renderKnowledgeMap(dirs []*knowledgeDir, threshold float64, scope string) string {
+	var sb strings.Builder
+	sb.WriteString("### 🗺️ 项目地图 (Knowledge)\n\n")
+	if strings.TrimSpace(scope) != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", scope))
+	}
+
+	covered := 0
+	var blind, dense []*knowledgeDir
+	for _, d := range dirs {
+		if d.refs() > 0 {
+			covered++
+			dense = append(dense, d)
+		} else if d.HighRisk > 0 {
+			blind = append(blind, d)
+		}
+	}
+	sb.WriteString(fmt.Sprintf("**📊 统计**: %d 目录 | %d 有记忆覆盖 | %d 风险盲区 (复杂度 ≥ %.0f 且零记忆)\n\n", len(dirs), covered, len(blind), threshold))
+
+	dirName := func(p string) string {
+		if p == "" {
+			return "(root)"
+		}
+		return p
+	}
+
+	sb.WriteString("#### 🕳 风险盲区（优先补充文档/记忆）\n\n")
+	sort.Slice(blind, func(i, j int) bool { return blind[i].MaxScore > blind[j].MaxScore })
+	if len(blind) == 0 {
+		sb.WriteString("- (无)\n")
+	}
+	for i, d := range blind {
+		if i >= 20 {
+			sb.WriteString(fmt.Sprintf("- ... 另有 %d 个\n", len(blind)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- `%s/` 最高复杂度 %.1f，高风险符号 %d 个，%d 文件\n", dirName(d.Path), d.MaxScore, d.HighRisk, d.Files))
+	}
+
+	sb.WriteString("\n#### 📚 记忆密集区\n\n")
+	sort.Slice(dense, func(i, j int) bool { return dense[i].refs() > dense[j].refs() })
+	if len(dense) == 0 {
+		sb.WriteString("- (无，memo 时填写 path 或在内容中写明文件路径可提高归属准确度)\n")
+	}
+	for i, d := range dense {
+		if i >= 10 {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- `%s/` memos %d | facts %d | hooks %d\n", dirName(d.Path), d.Memos, d.Facts, d.Hooks))
+	}
+
+	sb.WriteString("\n#### 📁 目录明细\n\n")
+	sb.WriteString("| 目录 | 文件 | 符号 | 最高复杂度 | memos | facts | hooks |\n")
+	sb.WriteString("|------|------|------|------------|-------|-------|-------|\n")
+	for i, d := range dirs {
+		if i >= 120 {
+			sb.WriteString(fmt.Sprintf("\n... 其余 %d 个目录已省略，请使用 scope 下钻。\n", len(dirs)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d | %.1f | %d | %d | %d |\n", dirName(d.Path), d.Files, d.Symbols, d.MaxScore, d.Memos, d.Facts, d.Hooks))
+	}
+	return sb.String()
+}
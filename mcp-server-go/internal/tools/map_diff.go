@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// ========== project_map(level="diff")：地图快照对比 ==========
+
+const mapDiffListLimit = 30
+
+func mapSnapshotDir(sm *SessionManager) string {
+	return core.DataPath(sm.ProjectRoot, "map_snapshots")
+}
+
+// saveMapSnapshot 保存 symbols 视图快照；失败只记日志，不影响地图输出
+func saveMapSnapshot(sm *SessionManager, result *services.MapResult, scope string) *services.MapSnapshot {
+	snap := services.NewMapSnapshot(result, scope, time.Now())
+	if err := services.SaveMapSnapshot(mapSnapshotDir(sm), snap); err != nil {
+		fmt.Fprintf(os.Stderr, "[project_map] 保存地图快照失败: %v\n", err)
+		return nil
+	}
+	return snap
+}
+
+// buildMapDiff 解析 from/to：to 留空时生成当前快照，from 留空时取 to 之前的最近一个快照
+func buildMapDiff(sm *SessionManager, ai *services.ASTIndexer, args ProjectMapArgs) (string, error) {
+	dir := mapSnapshotDir(sm)
+	var to *services.MapSnapshot
+	if id := strings.TrimSpace(args.To); id != "" {
+		snap, err := services.LoadMapSnapshot(dir, id)
+		if err != nil {
+			return "", err
+		}
+		to = snap
+	} else {
+		result, _, _, err := buildSymbolsMap(sm, ai, args, "symbols")
+		if err != nil {
+			return "", fmt.Errorf("生成当前地图失败: %v", err)
+		}
+		if to = saveMapSnapshot(sm, result, args.Scope); to == nil {
+			to = services.NewMapSnapshot(result, args.Scope, time.Now())
+		}
+	}
+
+	ids, err := services.ListMapSnapshots(dir)
+	if err != nil {
+		return "", err
+	}
+	fromID := strings.TrimSpace(args.From)
+	if fromID == "" {
+		for i := len(ids) - 1; i >= 0; i-- {
+			if ids[i] < to.ID {
+				fromID = ids[i]
+				break
+			}
+		}
+	}
+	if fromID == "" {
+		return fmt.Sprintf("### 🗺️ 项目地图 (Diff)\n\n暂无更早的快照可对比，已记录当前快照 `%s`。\n之后再执行 `project_map(level=\"diff\")` 即可看到这段时间的结构变化。\n", to.ID), nil
+	}
+	from, err := services.LoadMapSnapshot(dir, fromID)
+	if err != nil {
+		return "", err
+	}
+	return renderMapDiff(services.DiffMapSnapshots(from, to), ids), nil
+}
+
+func renderMapDiff(d *services.MapDiff, ids []string) string {
+	var sb strings.Builder
+	sb.WriteString("### 🗺️ 项目地图 (Diff)\n\n")
+	sb.WriteString(fmt.Sprintf("**🕒 区间**: `%s` → `%s` (%s)\n", d.From.ID, d.To.ID, d.To.CreatedAt.Sub(d.From.CreatedAt).Round(time.Second)))
+	if d.From.Scope != d.To.Scope {
+		sb.WriteString(fmt.Sprintf("> ⚠️ 两个快照的 scope 不同（`%s` vs `%s`），范围外的文件会显示为新增/删除。\n",
+			fallback(d.From.Scope, "."), fallback(d.To.Scope, ".")))
+	}
+	sb.WriteString(fmt.Sprintf("**📊 变化**: 文件 +%d/-%d | 符号 +%d/-%d | 复杂度回退 %d (下降 %d)\n",
+		len(d.AddedFiles), len(d.RemovedFiles), len(d.Added), len(d.Removed), len(d.Regressions), d.Improved))
+
+	if len(d.AddedFiles)+len(d.RemovedFiles)+len(d.Added)+len(d.Removed)+len(d.Regressions) == 0 {
+		sb.WriteString("\n✅ 两个快照之间结构没有变化。\n")
+	}
+
+	if len(d.Dirs) > 0 {
+		sb.WriteString("\n#### 📁 目录变化\n")
+		for i, c := range d.Dirs {
+			if i >= mapDiffListLimit {
+				sb.WriteString(fmt.Sprintf("- ... 还有 %d 个目录\n", len(d.Dirs)-i))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- `%s/` 符号 %d → %d (%+d) | 文件 %d → %d\n",
+				c.Dir, c.SymbolsBefore, c.SymbolsAfter, c.SymbolsAfter-c.SymbolsBefore, c.FilesBefore, c.FilesAfter))
+		}
+	}
+	if len(d.Regressions) > 0 {
+		sb.WriteString("\n#### 🔥 复杂度回退\n")
+		for i, r := range d.Regressions {
+			if i >= mapDiffListLimit {
+				sb.WriteString(fmt.Sprintf("- ... 还有 %d 个\n", len(d.Regressions)-i))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- `%s` @ %s:%d %.1f → %.1f (+%.1f)\n", r.Name, r.File, r.Line, r.Before, r.Complexity, r.Complexity-r.Before))
+		}
+	}
+	writeFiles := func(title string, files []string) {
+		if len(files) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("\n#### %s (%d)\n", title, len(files)))
+		for i, f := range files {
+			if i >= mapDiffListLimit {
+				sb.WriteString(fmt.Sprintf("- ... 还有 %d 个\n", len(files)-i))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- %s\n", f))
+		}
+	}
+	writeSymbols := func(title string, syms []services.SnapshotSymbol) {
+		if len(syms) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("\n#### %s (%d)\n", title, len(syms)))
+		for i, s := range syms {
+			if i >= mapDiffListLimit {
+				sb.WriteString(fmt.Sprintf("- ... 还有 %d 个\n", len(syms)-i))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- %s `%s` @ %s:%d\n", s.Type, s.Name, s.File, s.Line))
+		}
+	}
+	writeFiles("📄 新增文件", d.AddedFiles)
+	writeFiles("🗑️ 删除文件", d.RemovedFiles)
+	writeSymbols("➕ 新增符号", d.Added)
+	writeSymbols("➖ 删除符号", d.Removed)
+
+	if len(ids) > 0 {
+		recent := ids
+		if len(recent) > 10 {
+			recent = recent[len(recent)-10:]
+		}
+		sb.WriteString(fmt.Sprintf("\n---\n可用快照 (最近 %d 个，共 %d): %s\n", len(recent), len(ids), "`"+strings.Join(recent, "`, `")+"`"))
+		sb.WriteString("指定区间: `project_map(level=\"diff\", from=\"<ID>\", to=\"<ID>\")`\n")
+	}
+	return sb.String()
+}
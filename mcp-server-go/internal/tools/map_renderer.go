@@ -10,8 +10,10 @@ import (
 
 // MapRenderer 负责将 MapResult 渲染为Markdown
 type MapRenderer struct {
-	Result *services.MapResult
-	Root   string // 项目根路径，用于计算相对路径
+	Result   *services.MapResult
+	Root     string                 // 项目根路径，用于计算相对路径
+	Coverage *services.CoverageData // 测试覆盖率（可选），非空时标注目录/文件/符号覆盖率
+	Hotspots []services.GitHotspot  // 变更热点（可选）：高 churn × 高扇入
 }
 
 func NewMapRenderer(result *services.MapResult, root string) *MapRenderer {
@@ -331,6 +333,18 @@ func (mr *MapRenderer) RenderStandard() string {
 	} else {
 		sb.WriteString("\n")
 	}
+	if len(mr.Hotspots) > 0 {
+		sb.WriteString(fmt.Sprintf("**🔥 变更热点** (近 %d 天高频修改 × 高扇入，改动前优先补测试/拆分):\n", services.GitChurnDays))
+		for _, h := range mr.Hotspots {
+			sb.WriteString(fmt.Sprintf("- `%s` @ %s:%d — %d 次提交，%d 个调用方\n", h.Name, h.FilePath, h.Line, h.Churn, h.FanIn))
+		}
+		sb.WriteString("\n")
+	}
+	if mr.Coverage != nil {
+		if pct, ok := mr.Coverage.DirPct("."); ok {
+			sb.WriteString(fmt.Sprintf("**🧪 覆盖率**: %.1f%% (来自 %s，%s生成)\n\n", pct, strings.Join(mr.Coverage.Sources, ", "), mr.Coverage.CoverageAge()))
+		}
+	}
 
 	mr.renderWithMode(&sb, "Standard", true)
 	return sb.String()
@@ -399,7 +413,11 @@ func (mr *MapRenderer) renderWithMode(sb *strings.Builder, mode string, truncate
 			return files[i].AvgComp > files[j].AvgComp
 		})
 
-		sb.WriteString(fmt.Sprintf("\n📂 **%s/**\n", dir))
+		dirTag := ""
+		if dir != "(root)" {
+			dirTag = mr.coverageTag(dir, 0, 0)
+		}
+		sb.WriteString(fmt.Sprintf("\n📂 **%s/**%s\n", dir, dirTag))
 
 		// 自适应折叠策略 (仅Standard模式)
 		topLimit := 10
@@ -423,7 +441,7 @@ func (mr *MapRenderer) renderWithMode(sb *strings.Builder, mode string, truncate
 				if f.AvgComp >= 10 {
 					compTag = fmt.Sprintf(" [Avg:%.1f]", f.AvgComp)
 				}
-				sb.WriteString(fmt.Sprintf("  📄 **%s** (%d)%s\n", f.Name, f.NodeCount, compTag))
+				sb.WriteString(fmt.Sprintf("  📄 **%s** (%d)%s%s\n", f.Name, f.NodeCount, compTag, mr.coverageTag(f.Path, 0, 0)))
 				continue
 			}
 
@@ -432,7 +450,7 @@ func (mr *MapRenderer) renderWithMode(sb *strings.Builder, mode string, truncate
 			if f.AvgComp >= 10 {
 				fileTag = fmt.Sprintf(" [Avg:%.1f]", f.AvgComp)
 			}
-			sb.WriteString(fmt.Sprintf("  📄 **%s** (%d)%s\n", f.Name, f.NodeCount, fileTag))
+			sb.WriteString(fmt.Sprintf("  📄 **%s** (%d)%s%s\n", f.Name, f.NodeCount, fileTag, mr.coverageTag(f.Path, 0, 0)))
 
 			// 渲染符号 (按复杂度排序)
 			sort.Slice(f.Nodes, func(i, j int) bool {
@@ -482,5 +500,30 @@ func (mr *MapRenderer) renderNode(sb *strings.Builder, node services.Node, inden
 		}
 	}
 
-	sb.WriteString(fmt.Sprintf("%s%s `%s` L%d%s\n", indent, icon, desc, node.LineStart, complexityMarker))
+	covMarker := ""
+	if node.LineEnd >= node.LineStart && node.LineStart > 0 {
+		covMarker = mr.coverageTag(node.FilePath, node.LineStart, node.LineEnd)
+	}
+
+	sb.WriteString(fmt.Sprintf("%s%s `%s` L%d%s%s\n", indent, icon, desc, node.LineStart, complexityMarker, covMarker))
+}
+
+// coverageTag 生成覆盖率标记；path 为目录时 start=end=0 表示整个目录/文件。无覆盖数据时返回空
+func (mr *MapRenderer) coverageTag(path string, start, end int) string {
+	if mr.Coverage == nil || path == "" {
+		return ""
+	}
+	if filepath.IsAbs(path) && mr.Root != "" {
+		if rel, err := filepath.Rel(mr.Root, path); err == nil {
+			path = rel
+		}
+	}
+	pct, ok := mr.Coverage.RangePct(path, start, end)
+	if !ok && start == 0 {
+		pct, ok = mr.Coverage.DirPct(path)
+	}
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" [cov %.0f%%]", pct)
 }
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ========== memo 关联 git 提交：录入时记录 HEAD（可选未提交文件），便于从决策记录跳到 diff ==========
+
+const memoDirtyFileLimit = 30
+
+// memoGitContext 当前 HEAD 与（withDirty 时）未提交改动的文件列表；非 git 仓库时返回空
+func memoGitContext(projectRoot string, withDirty bool) (head, dirty string) {
+	if projectRoot == "" {
+		return "", ""
+	}
+	out, err := runGit(projectRoot, "rev-parse", "HEAD")
+	if err != nil {
+		return "", ""
+	}
+	head = strings.TrimSpace(string(out))
+	if !withDirty {
+		return head, ""
+	}
+	files, err := gitDirtyFiles(projectRoot)
+	if err != nil || len(files) == 0 {
+		return head, ""
+	}
+	if len(files) > memoDirtyFileLimit {
+		files = append(files[:memoDirtyFileLimit], fmt.Sprintf("...(+%d)", len(files)-memoDirtyFileLimit))
+	}
+	return head, strings.Join(files, ",")
+}
+
+// shortCommit 提交哈希的短格式
+func shortCommit(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// memoCommitTag recall 等列表中 memo 的提交标记（git show <hash> 可查看对应 diff）
+func memoCommitTag(commit, dirty string) string {
+	if commit == "" {
+		return ""
+	}
+	if dirty != "" {
+		return fmt.Sprintf(" `@%s+未提交`", shortCommit(commit))
+	}
+	return fmt.Sprintf(" `@%s`", shortCommit(commit))
+}
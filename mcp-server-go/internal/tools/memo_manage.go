@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MemoManageArgs memo 修订/删除/归档参数
+type MemoManageArgs struct {
+	Action   string `json:"action" jsonschema:"required,enum=show,enum=update,enum=delete,enum=archive,enum=unarchive,enum=compact,description=操作"`
+	ID       int64  `json:"id" jsonschema:"description=memo ID (memo 返回的 IDs / system_recall 中的 [ID])，compact 以外必填"`
+	Category string `json:"category" jsonschema:"description=update: 新分类"`
+	Entity   string `json:"entity" jsonschema:"description=update: 新实体"`
+	Act      string `json:"act" jsonschema:"description=update: 新行为"`
+	Path     string `json:"path" jsonschema:"description=update: 新文件路径"`
+	Content  string `json:"content" jsonschema:"description=update: 新内容"`
+	Reason   string `json:"reason" jsonschema:"description=修改原因 (写入归档墓碑条目，update/delete 必填)"`
+	// compact
+	Threshold float64 `json:"threshold" jsonschema:"description=compact: 内容相似度阈值 (0~1，默认 0.75)"`
+	Apply     bool    `json:"apply" jsonschema:"description=compact: true 执行合并，默认仅预览"`
+}
+
+func wrapMemoManage(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化，请先执行 initialize_project 任务。"), nil
+		}
+		var args MemoManageArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数格式错误： %v", err)), nil
+		}
+		action := strings.ToLower(strings.TrimSpace(args.Action))
+		if action == "compact" {
+			return compactMemos(ctx, sm, args)
+		}
+		if args.ID <= 0 {
+			return mcp.NewToolResultError("需要提供 memo id"), nil
+		}
+		reason := strings.TrimSpace(args.Reason)
+		if (action == core.MemoOpUpdate || action == core.MemoOpDelete) && reason == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("%s 需要提供 reason，说明为什么修正/撤回这条记录", action)), nil
+		}
+
+		switch action {
+		case "show":
+			memo, archived, err := sm.Memory.GetMemo(ctx, args.ID)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("memo %d 不存在", args.ID)), nil
+			}
+			state := ""
+			if archived {
+				state = " (已归档)"
+			}
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("### memo %d%s\n\n%s", memo.ID, state, renderMemoDetail(memo)))
+			if history, _ := sm.Memory.MemoHistory(ctx, memo.ID); len(history) > 0 {
+				sb.WriteString(fmt.Sprintf("\n**历史版本 (%d，已合并)**\n", len(history)))
+				for _, h := range history {
+					sb.WriteString(fmt.Sprintf(formatMemo, h.ID, h.Timestamp.Format("2006-01-02 15:04"), h.Category, h.Act, truncateRunes(h.Content, 120)))
+				}
+			}
+			return mcp.NewToolResultText(sb.String()), nil
+
+		case core.MemoOpUpdate:
+			patch := core.Memo{Category: args.Category, Entity: args.Entity, Act: args.Act, Path: args.Path, Content: args.Content}
+			if patch == (core.Memo{}) {
+				return mcp.NewToolResultError("update 至少需要提供 category/entity/act/path/content 中的一项"), nil
+			}
+			before, _, err := sm.Memory.GetMemo(ctx, args.ID)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("memo %d 不存在", args.ID)), nil
+			}
+			after, err := sm.Memory.UpdateMemo(ctx, args.ID, patch, reason)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("修订失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("✏️ memo %d 已修订（原因: %s）\n\n**修订前**\n%s\n**修订后**\n%s",
+				args.ID, reason, renderMemoDetail(before), renderMemoDetail(after))), nil
+
+		case core.MemoOpDelete:
+			memo, err := sm.Memory.DeleteMemo(ctx, args.ID, reason)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("删除失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🗑️ memo %d 已删除（原因: %s），归档中保留墓碑条目。\n\n%s",
+				args.ID, reason, renderMemoDetail(memo))), nil
+
+		case core.MemoOpArchive, core.MemoOpUnarchive:
+			archive := action == core.MemoOpArchive
+			if _, err := sm.Memory.SetMemoArchived(ctx, args.ID, archive, reason); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("操作失败: %v", err)), nil
+			}
+			if archive {
+				return mcp.NewToolResultText(fmt.Sprintf("📦 memo %d 已归档，不再出现在检索与 dev-log.md 中。\n> 撤销: memo_manage(action=\"unarchive\", id=%d)", args.ID, args.ID)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("📤 memo %d 已取消归档。", args.ID)), nil
+
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("未知 action: %s（可用: show, update, delete, archive, unarchive, compact）", args.Action)), nil
+		}
+	}
+}
+
+// compactMemos 预览或执行近似重复 memo 的合并
+func compactMemos(ctx context.Context, sm *SessionManager, args MemoManageArgs) (*mcp.CallToolResult, error) {
+	groups, err := sm.Memory.FindDuplicateMemos(ctx, args.Threshold)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("查找重复 memo 失败: %v", err)), nil
+	}
+	if len(groups) == 0 {
+		return mcp.NewToolResultText("未发现近似重复的 memo。"), nil
+	}
+
+	total := 0
+	var sb strings.Builder
+	for _, g := range groups {
+		total += len(g.Duplicates)
+		c := g.Canonical
+		sb.WriteString(fmt.Sprintf("\n**%s** → 保留 [%d] %s (相似度 ≥ %.2f)\n", c.Entity, c.ID, c.Timestamp.Format("2006-01-02 15:04"), g.Similarity))
+		sb.WriteString(fmt.Sprintf("  = %s\n", truncateRunes(c.Content, 100)))
+		for _, d := range g.Duplicates {
+			sb.WriteString(fmt.Sprintf("  - [%d] %s %s\n", d.ID, d.Timestamp.Format("2006-01-02 15:04"), truncateRunes(d.Content, 100)))
+		}
+	}
+
+	if !args.Apply {
+		return mcp.NewToolResultText(fmt.Sprintf("### 🧹 近似重复 memo: %d 组，可合并 %d 条\n%s\n> 确认后执行: memo_manage(action=\"compact\", apply=true)",
+			len(groups), total, sb.String())), nil
+	}
+	merged, err := sm.Memory.CompactMemos(ctx, groups)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("合并中断（已合并 %d 条）: %v", merged, err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("### 🧹 已合并 %d 组 / %d 条 memo\n%s\n> 被合并的记录作为历史版本保留: memo_manage(action=\"show\", id=<保留的 ID>)；误合并可 unarchive 拆回。",
+		len(groups), merged, sb.String())), nil
+}
+
+func renderMemoDetail(m *core.Memo) string {
+	s := fmt.Sprintf("- 时间: %s\n- 分类: %s\n- 实体: %s\n- 行为: %s\n- 路径: %s\n- 内容: %s\n",
+		m.Timestamp.Format("2006-01-02 15:04"), m.Category, m.Entity, m.Act, m.Path, m.Content)
+	if m.GitCommit != "" {
+		s += fmt.Sprintf("- 提交: %s\n", m.GitCommit)
+	}
+	if m.GitDirty != "" {
+		s += fmt.Sprintf("- 未提交文件: %s\n", m.GitDirty)
+	}
+	return s
+}
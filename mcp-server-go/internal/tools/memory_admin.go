@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MemoryAdminArgs memory_admin 参数
+type MemoryAdminArgs struct {
+	Mode string `json:"mode" jsonschema:"required,enum=backup,enum=list,enum=restore,enum=vacuum,enum=integrity_check,description=backup/list/restore/vacuum/integrity_check"`
+	Name string `json:"name" jsonschema:"description=要恢复的整包备份目录名 (restore 必填，见 list 输出)"`
+}
+
+func wrapMemoryAdmin(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args MemoryAdminArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化，请先执行 initialize_project。"), nil
+		}
+
+		switch mode := strings.ToLower(strings.TrimSpace(args.Mode)); mode {
+		case "backup":
+			info, err := sm.Memory.CreateBundle(ctx, core.BackupManual)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("💾 已创建整包备份: %s (%d 个文件, %s, %d 条 memo, schema v%d)",
+				info.Name, len(info.Files), humanBytes(info.Size), info.Memos, info.SchemaVersion)), nil
+
+		case "list":
+			bundles, err := core.ListBundles(sm.ProjectRoot)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("读取备份目录失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText(renderBundleList(bundles)), nil
+
+		case "restore":
+			if strings.TrimSpace(args.Name) == "" {
+				return mcp.NewToolResultError("restore 需要提供 name（见 memory_admin(mode=\"list\")）"), nil
+			}
+			safety, err := sm.Memory.RestoreBundle(ctx, args.Name)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			sm.TaskChainsV3 = nil
+			return mcp.NewToolResultText(fmt.Sprintf("♻️ 已从 %s 恢复记忆库与 dev-log-archive。\n恢复前的状态已保存为 %s，如需撤销: memory_admin(mode=\"restore\", name=\"%s\")",
+				args.Name, safety.Name, safety.Name)), nil
+
+		case "vacuum":
+			res, err := sm.Memory.Vacuum(ctx)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🧹 VACUUM 完成: %s → %s（回收 %s）",
+				humanBytes(res.Before), humanBytes(res.After), humanBytes(max(res.Before-res.After, 0)))), nil
+
+		case "integrity_check":
+			report, err := sm.Memory.IntegrityCheck(ctx)
+			if err != nil && report == nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			text := renderIntegrityReport(report)
+			if err != nil {
+				text += fmt.Sprintf("\n⚠️ 归档比对未完成: %v\n", err)
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知模式: %s (支持 backup/list/restore/vacuum/integrity_check)", args.Mode)), nil
+	}
+}
+
+func renderBundleList(bundles []core.BundleInfo) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 📦 整包备份 (%d)\n\n", len(bundles)))
+	if len(bundles) == 0 {
+		sb.WriteString("暂无整包备份。创建: `memory_admin(mode=\"backup\")`\n")
+		return sb.String()
+	}
+	for _, b := range bundles {
+		sb.WriteString(fmt.Sprintf("- `%s` [%s] %s · %d 个文件 · %s · %d 条 memo\n",
+			b.Name, b.Kind, b.CreatedAt.Format("2006-01-02 15:04:05"), len(b.Files), humanBytes(b.Size), b.Memos))
+	}
+	sb.WriteString("\n> 恢复: `memory_admin(mode=\"restore\", name=\"...\")`，恢复前会自动生成 pre-restore 整包安全副本")
+	return sb.String()
+}
+
+func renderIntegrityReport(r *core.IntegrityReport) string {
+	var sb strings.Builder
+	sb.WriteString("### 🩺 记忆库完整性检查\n\n")
+	if r.OK {
+		sb.WriteString("- **integrity_check**: ✅ ok\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- **integrity_check**: ❌ 发现 %d 处问题\n", len(r.Integrity)))
+		for i, line := range r.Integrity {
+			if i == 10 {
+				sb.WriteString(fmt.Sprintf("  - …（其余 %d 条省略）\n", len(r.Integrity)-10))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("  - %s\n", line))
+		}
+	}
+
+	if !r.ArchivePresent {
+		sb.WriteString("- **归档比对**: 未找到 dev-log-archive/memo_archive.jsonl，跳过\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- **归档重放**: 活跃 %d / 已归档 %d\n", r.ArchiveLive, r.ArchiveArchived))
+		sb.WriteString(fmt.Sprintf("- **数据库**: 活跃 %d / 已归档 %d\n", r.DBLive, r.DBArchived))
+		if r.Missing == 0 && r.Extra == 0 {
+			sb.WriteString("- **比对结果**: ✅ 数据库与归档一致\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("- **比对结果**: 数据库缺少 %d 条，多出 %d 条（多出的通常是归档机制引入前的旧 memo）\n", r.Missing, r.Extra))
+			for _, s := range r.MissingSamples {
+				sb.WriteString(fmt.Sprintf("  - 缺少: %s\n", s))
+			}
+			for _, s := range r.ExtraSamples {
+				sb.WriteString(fmt.Sprintf("  - 多出: %s\n", s))
+			}
+		}
+	}
+
+	if !r.OK {
+		sb.WriteString("\n> 数据库已损坏：可用 `memory_admin(mode=\"restore\")` 恢复整包备份，或 `restore_backup` 恢复单文件备份")
+	} else if r.Missing > 0 {
+		sb.WriteString("\n> 缺失的 memo 可从最近的整包备份恢复；数据库为空时启动会自动从 memo_archive.jsonl 重放")
+	}
+	return sb.String()
+}
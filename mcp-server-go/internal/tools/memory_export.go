@@ -0,0 +1,347 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// export_memory：把事实 / memo / 任务 / 钩子导出为可提交到 git 的 Markdown 知识库，并可反向导入
+// ============================================================================
+//
+// 每个条目下方附一行 <!-- mpm:<kind> {json} --> 机读注释，导入只解析这些注释，
+// 人工修改正文不影响导入；json 中的 < > 已转义，内容里出现 "-->" 也不会截断注释。
+
+const defaultMemoryExportDir = "docs/memory"
+
+// ExportMemoryArgs export_memory 参数
+type ExportMemoryArgs struct {
+	Mode string `json:"mode" jsonschema:"default=export,enum=export,enum=import,description=export (导出为 Markdown) / import (从导出目录回灌数据库)"`
+	Dir  string `json:"dir" jsonschema:"description=导出目录（相对项目根，默认 docs/memory）"`
+}
+
+var memoryExportMarker = regexp.MustCompile(`^<!-- mpm:(fact|memo|task|hook) (\{.*\}) -->$`)
+
+// renderMemoryExport 导出文件（文件名 → 内容）
+func renderMemoryExport(exp *core.MemoryExport, projectRoot, dir string, now time.Time) map[string]string {
+	files := map[string]string{
+		"README.md": renderExportIndex(exp, now),
+		"facts.md":  renderExportFacts(exp),
+		"memos.md":  renderExportMemos(exp, projectRoot, dir),
+		"tasks.md":  renderExportTasks(exp),
+		"hooks.md":  renderExportHooks(exp),
+	}
+	return files
+}
+
+func exportMarker(kind string, v interface{}) string {
+	data, _ := json.Marshal(v)
+	return fmt.Sprintf("<!-- mpm:%s %s -->\n", kind, data)
+}
+
+// quoteBlock 多行正文渲染为引用块
+func quoteBlock(s string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		sb.WriteString("> " + line + "\n")
+	}
+	return sb.String()
+}
+
+func renderExportIndex(exp *core.MemoryExport, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString("# 项目记忆知识库\n\n")
+	sb.WriteString(fmt.Sprintf("由 MPM `export_memory` 于 %s 生成，可直接提交到 git 供人工审阅。\n\n", now.Format("2006-01-02 15:04")))
+	sb.WriteString("| 类别 | 条数 | 文件 |\n|------|------|------|\n")
+	sb.WriteString(fmt.Sprintf("| 事实（铁律/避坑） | %d | [facts.md](facts.md) |\n", len(exp.Facts)))
+	sb.WriteString(fmt.Sprintf("| 变更备忘 (memo) | %d | [memos.md](memos.md) |\n", len(exp.Memos)))
+	sb.WriteString(fmt.Sprintf("| 任务 | %d | [tasks.md](tasks.md) |\n", len(exp.Tasks)))
+	sb.WriteString(fmt.Sprintf("| 待办钩子 | %d | [hooks.md](hooks.md) |\n", len(exp.Hooks)))
+	sb.WriteString("\n新环境导入：`export_memory(mode=\"import\")`。导入只读取每个条目下的 `<!-- mpm:... -->` 注释，已存在的条目会跳过。\n")
+	return sb.String()
+}
+
+func renderExportFacts(exp *core.MemoryExport) string {
+	var sb strings.Builder
+	sb.WriteString("# 事实\n\n[← 索引](README.md)\n")
+	var types []string
+	byType := make(map[string][]core.ExportFact)
+	for _, f := range exp.Facts {
+		if _, ok := byType[f.Type]; !ok {
+			types = append(types, f.Type)
+		}
+		byType[f.Type] = append(byType[f.Type], f)
+	}
+	if len(types) == 0 {
+		sb.WriteString("\n暂无事实。\n")
+	}
+	for _, t := range types {
+		sb.WriteString(fmt.Sprintf("\n## %s\n", t))
+		for _, f := range byType[t] {
+			sb.WriteString(fmt.Sprintf("\n<a id=\"fact-%d\"></a>\n### F%d\n\n", f.ID, f.ID))
+			sb.WriteString(quoteBlock(f.Summary))
+			meta := []string{"优先级: " + fallback(f.Priority, core.FactPriorityMedium)}
+			if f.Scope != "" {
+				meta = append(meta, "作用域: `"+f.Scope+"`")
+			}
+			meta = append(meta, "记录于 "+f.CreatedAt)
+			if f.ExpiresAt != "" {
+				meta = append(meta, "到期 "+f.ExpiresAt)
+			}
+			sb.WriteString("\n" + strings.Join(meta, " · ") + "\n")
+			sb.WriteString(exportMarker("fact", f))
+		}
+	}
+	return sb.String()
+}
+
+func renderExportMemos(exp *core.MemoryExport, projectRoot, dir string) string {
+	var sb strings.Builder
+	sb.WriteString("# 变更备忘\n\n[← 索引](README.md)\n")
+	var categories []string
+	byCat := make(map[string][]core.ExportMemo)
+	for _, m := range exp.Memos {
+		cat := fallback(m.Category, "未分类")
+		if _, ok := byCat[cat]; !ok {
+			categories = append(categories, cat)
+		}
+		byCat[cat] = append(byCat[cat], m)
+	}
+	sort.Strings(categories)
+	if len(categories) == 0 {
+		sb.WriteString("\n暂无备忘。\n")
+	}
+	for _, cat := range categories {
+		sb.WriteString(fmt.Sprintf("\n## %s (%d)\n", cat, len(byCat[cat])))
+		for _, m := range byCat[cat] {
+			sb.WriteString(fmt.Sprintf("\n<a id=\"memo-%d\"></a>\n### #%d %s · %s %s\n\n", m.ID, m.ID, m.Timestamp, m.Entity, m.Act))
+			sb.WriteString(quoteBlock(m.Content))
+			if p := strings.TrimSpace(m.Path); p != "" && p != "-" {
+				sb.WriteString(fmt.Sprintf("\n文件: %s\n", exportPathLink(projectRoot, dir, p)))
+			}
+			if m.GitCommit != "" {
+				sb.WriteString(fmt.Sprintf("\ncommit: `%s`\n", m.GitCommit))
+			}
+			sb.WriteString(exportMarker("memo", m))
+		}
+	}
+	return sb.String()
+}
+
+// exportPathLink 把 memo 中的项目相对路径渲染为相对导出目录的链接
+func exportPathLink(projectRoot, dir, p string) string {
+	target := filepath.Join(projectRoot, filepath.FromSlash(p))
+	if rel, err := filepath.Rel(dir, target); err == nil && !filepath.IsAbs(p) {
+		return fmt.Sprintf("[`%s`](%s)", p, filepath.ToSlash(rel))
+	}
+	return "`" + p + "`"
+}
+
+func renderExportTasks(exp *core.MemoryExport) string {
+	hooksByTask := make(map[string][]core.ExportHook)
+	for _, h := range exp.Hooks {
+		if h.RelatedTaskID != "" {
+			hooksByTask[h.RelatedTaskID] = append(hooksByTask[h.RelatedTaskID], h)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("# 任务\n\n[← 索引](README.md)\n")
+	if len(exp.Tasks) == 0 {
+		sb.WriteString("\n暂无任务。\n")
+	}
+	for _, t := range exp.Tasks {
+		sb.WriteString(fmt.Sprintf("\n<a id=\"task-%s\"></a>\n## %s [%s]\n\n", anchorID(t.TaskID), t.TaskID, t.Status))
+		sb.WriteString(quoteBlock(t.Description))
+		sb.WriteString(fmt.Sprintf("\n创建 %s · 更新 %s\n", t.CreatedAt, t.UpdatedAt))
+		if t.Summary != "" {
+			sb.WriteString("\n**总结**: " + t.Summary + "\n")
+		}
+		if t.Pitfalls != "" {
+			sb.WriteString("\n**踩坑**: " + t.Pitfalls + "\n")
+		}
+		if hooks := hooksByTask[t.TaskID]; len(hooks) > 0 {
+			sb.WriteString("\n**关联钩子**: ")
+			links := make([]string, len(hooks))
+			for i, h := range hooks {
+				links[i] = fmt.Sprintf("[%s](hooks.md#hook-%s)", h.HookID, anchorID(h.HookID))
+			}
+			sb.WriteString(strings.Join(links, ", ") + "\n")
+		}
+		sb.WriteString(exportMarker("task", t))
+	}
+	return sb.String()
+}
+
+func renderExportHooks(exp *core.MemoryExport) string {
+	tasks := make(map[string]bool)
+	for _, t := range exp.Tasks {
+		tasks[t.TaskID] = true
+	}
+	var open, closed []core.ExportHook
+	for _, h := range exp.Hooks {
+		if h.Status == "open" {
+			open = append(open, h)
+		} else {
+			closed = append(closed, h)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("# 待办钩子\n\n[← 索引](README.md)\n")
+	for _, group := range []struct {
+		title string
+		hooks []core.ExportHook
+	}{{"未完成", open}, {"已释放", closed}} {
+		sb.WriteString(fmt.Sprintf("\n## %s (%d)\n", group.title, len(group.hooks)))
+		for _, h := range group.hooks {
+			sb.WriteString(fmt.Sprintf("\n<a id=\"hook-%s\"></a>\n### %s [%s] %s\n\n", anchorID(h.HookID), h.HookID, h.Priority, h.Summary))
+			sb.WriteString(quoteBlock(h.Description))
+			meta := []string{"创建 " + h.CreatedAt}
+			if h.Tag != "" {
+				meta = append(meta, "标签: "+h.Tag)
+			}
+			if h.ExpiresAt != "" {
+				meta = append(meta, "到期 "+h.ExpiresAt)
+			}
+			if h.RelatedTaskID != "" {
+				if tasks[h.RelatedTaskID] {
+					meta = append(meta, fmt.Sprintf("任务: [%s](tasks.md#task-%s)", h.RelatedTaskID, anchorID(h.RelatedTaskID)))
+				} else {
+					meta = append(meta, "任务: "+h.RelatedTaskID)
+				}
+			}
+			sb.WriteString("\n" + strings.Join(meta, " · ") + "\n")
+			if h.ResultSummary != "" {
+				sb.WriteString("\n**结果**: " + h.ResultSummary + "\n")
+			}
+			sb.WriteString(exportMarker("hook", h))
+		}
+	}
+	return sb.String()
+}
+
+// anchorID 锚点只保留字母数字、- 与 _
+func anchorID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// parseMemoryExport 读取导出目录中所有 .md 的机读注释
+func parseMemoryExport(dir string) (*core.MemoryExport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	exp := &core.MemoryExport{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".md") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+			m := memoryExportMarker.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+			if m == nil {
+				continue
+			}
+			var target interface{}
+			switch m[1] {
+			case "fact":
+				exp.Facts = append(exp.Facts, core.ExportFact{})
+				target = &exp.Facts[len(exp.Facts)-1]
+			case "memo":
+				exp.Memos = append(exp.Memos, core.ExportMemo{})
+				target = &exp.Memos[len(exp.Memos)-1]
+			case "task":
+				exp.Tasks = append(exp.Tasks, core.ExportTask{})
+				target = &exp.Tasks[len(exp.Tasks)-1]
+			case "hook":
+				exp.Hooks = append(exp.Hooks, core.ExportHook{})
+				target = &exp.Hooks[len(exp.Hooks)-1]
+			}
+			if err := json.Unmarshal([]byte(m[2]), target); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s:%d 解析失败: %v", e.Name(), lineNo, err)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return exp, nil
+}
+
+func wrapExportMemory(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args ExportMemoryArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil {
+			return mcp.NewToolResultError("记忆层尚未初始化，请先执行 initialize_project。"), nil
+		}
+		dir := fallback(strings.TrimSpace(args.Dir), defaultMemoryExportDir)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(sm.ProjectRoot, dir)
+		}
+
+		switch mode := strings.ToLower(fallback(strings.TrimSpace(args.Mode), "export")); mode {
+		case "export":
+			exp, err := sm.Memory.CollectExport(ctx)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("读取记忆失败: %v", err)), nil
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("创建目录失败: %v", err)), nil
+			}
+			files := renderMemoryExport(exp, sm.ProjectRoot, dir, time.Now())
+			names := make([]string, 0, len(files))
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("写入 %s 失败: %v", name, err)), nil
+				}
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return mcp.NewToolResultText(fmt.Sprintf("📚 已导出到 %s: %s\n事实 %d · memo %d · 任务 %d · 钩子 %d",
+				filepath.ToSlash(dir), strings.Join(names, ", "), len(exp.Facts), len(exp.Memos), len(exp.Tasks), len(exp.Hooks))), nil
+
+		case "import":
+			exp, err := parseMemoryExport(dir)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("读取导出目录失败: %v", err)), nil
+			}
+			res, err := sm.Memory.ImportExport(ctx, exp)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("导入中断: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("📥 已从 %s 导入: 事实 %d · memo %d · 任务 %d · 钩子 %d（已存在跳过 %d）",
+				filepath.ToSlash(dir), res.Facts, res.Memos, res.Tasks, res.Hooks, res.Skipped)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("未知模式: %s (支持 export/import)", args.Mode)), nil
+	}
+}
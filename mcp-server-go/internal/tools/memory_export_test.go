@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestMemoryExport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, err := core.NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(src.FlushDevLog)
+	if _, err := src.SaveFactWithOptions(ctx, "铁律", "改完 schema 后运行 make migrate --> 别忘了", core.FactOptions{Scope: "path:internal/core", Priority: "high"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.AddMemos(ctx, []core.Memo{{Category: "修改", Entity: "auth", Act: "修复", Path: "auth/login.go", Content: "修复登录超时\n第二行"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.CreateHook(ctx, "补充集成测试", "high", "test", "", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	exp, err := src.CollectExport(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "docs", "memory")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range renderMemoryExport(exp, filepath.Dir(filepath.Dir(dir)), dir, time.Now()) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	memos, _ := os.ReadFile(filepath.Join(dir, "memos.md"))
+	if !strings.Contains(string(memos), "[`auth/login.go`](../../auth/login.go)") || !strings.Contains(string(memos), "> 第二行") {
+		t.Errorf("memos.md missing path link or quoted content:\n%s", memos)
+	}
+
+	parsed, err := parseMemoryExport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Facts) != 1 || parsed.Facts[0].Summary != exp.Facts[0].Summary || len(parsed.Memos) != 1 || len(parsed.Hooks) != 1 {
+		t.Fatalf("parsed export = %+v", parsed)
+	}
+
+	dst, err := core.NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dst.FlushDevLog)
+	res, err := dst.ImportExport(ctx, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Facts != 1 || res.Memos != 1 || res.Hooks != 1 || res.Skipped != 0 {
+		t.Errorf("first import = %+v", res)
+	}
+	if res, _ = dst.ImportExport(ctx, parsed); res.Skipped != 3 || res.Facts+res.Memos+res.Hooks != 0 {
+		t.Errorf("re-import should skip everything, got %+v", res)
+	}
+	facts, _ := dst.QueryFacts(ctx, "", 10)
+	if len(facts) != 1 || facts[0].Scope != "path:internal/core" || facts[0].Priority != "high" {
+		t.Errorf("imported facts = %+v", facts)
+	}
+}
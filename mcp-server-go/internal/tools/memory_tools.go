@@ -25,6 +25,10 @@ type MemoItem struct {
 type MemoArgs struct {
 	Items []MemoItem `json:"items" jsonschema:"required,description=录入事项列表"`
 	Lang  string     `json:"lang" jsonschema:"enum=zh,enum=en,default=zh,description=当前用户对话的语言 (zh=中文, en=英文)"`
+	// WithDirty 额外记录录入时未提交改动的文件列表
+	WithDirty bool `json:"with_dirty" jsonschema:"description=同时记录未提交改动的文件列表 (默认只记录 git HEAD)"`
+	// TaskID 当前任务链，文件锁提醒跳过该任务自己持有的锁
+	TaskID string `json:"task_id" jsonschema:"description=当前任务链 ID，文件锁提醒会跳过该任务自己持有的锁 (省略时取本会话唯一运行中的任务链)"`
 }
 
 // RegisterMemoryTools 注册备忘与检索工具
@@ -50,6 +54,14 @@ func RegisterMemoryTools(s *server.MCPServer, sm *SessionManager) {
   lang (可选，默认 zh): 
     记录语言，建议始终使用中文
 
+  with_dirty (可选，默认 false):
+    每条 memo 自动关联录入时的 git HEAD，system_recall / open_timeline 中显示提交哈希，
+    可用 git show <hash> 查看对应 diff。设为 true 时额外记录未提交改动的文件列表。
+
+  task_id (可选):
+    当前任务链 ID。path 被其他任务声明了文件软锁时会提醒，自己任务持有的锁不提醒；
+    省略时取本会话唯一运行中的任务链。
+
 完整调用示例（JSON格式）：
   {
     "items": [
@@ -69,6 +81,73 @@ func RegisterMemoryTools(s *server.MCPServer, sm *SessionManager) {
 		mcp.WithInputSchema[MemoArgs](),
 	), wrapMemo(sm))
 
+	s.AddTool(mcp.NewTool("memo_manage",
+		mcp.WithDescription(`memo_manage - 修订 / 撤回已录入的 memo
+
+用途：
+  memo 写错了、结论被推翻、或记录了不该留下的内容时使用，避免错误记录污染 dev-log.md 和 system_recall。
+  每次操作都会在 dev-log-archive/memo_archive.jsonl 追加一条墓碑条目（操作 + 原因），保留审计痕迹。
+
+参数：
+  action (必填)
+    show: 查看记录（含已归档）
+    update: 修订字段，只改传入的字段
+    delete: 永久删除
+    archive: 软删除，不再参与检索，可 unarchive 恢复
+    unarchive: 取消归档
+    compact: 查找同一实体下内容近似重复的 memo，合并为一条规范记录（保留最新的一条，其余作为历史版本归档）
+
+  id (compact 以外必填)
+    memo ID（memo 返回的 IDs，或 system_recall 结果中的 [ID]）。
+
+  category / entity / act / path / content (update 时可选)
+
+  reason (update/delete 必填)
+    为什么修正或撤回。
+
+  threshold / apply (compact 可选)
+    相似度阈值（默认 0.75）；apply=true 才执行合并，默认仅预览。
+
+示例：
+  memo_manage(action="update", id=42, content="实际原因是连接池耗尽，不是超时配置", reason="排查后结论有误")
+  memo_manage(action="archive", id=17, reason="临时调试记录")
+  memo_manage(action="compact")             -> 预览可合并的重复记录
+  memo_manage(action="compact", apply=true) -> 执行合并
+
+触发词：
+  "mpm 改memo", "mpm 删除记录", "mpm memo去重", "mpm memo manage"`),
+		mcp.WithInputSchema[MemoManageArgs](),
+	), wrapMemoManage(sm))
+
+	s.AddTool(mcp.NewTool("export_memory",
+		mcp.WithDescription(`export_memory - 把项目记忆导出为 Markdown 知识库 / 从知识库导入
+
+用途：
+  把积累的事实、memo、任务与钩子导出到 docs/ 下的结构化 Markdown（每类一个文件，互相链接），
+  便于提交到 git 供人工审阅；新环境或新库可用 import 从这些文件回灌数据库。
+
+参数：
+  mode (默认 export)
+    export: 写出 README.md / facts.md / memos.md / tasks.md / hooks.md（覆盖同名文件）
+    import: 读取导出目录中各条目的 <!-- mpm:... --> 注释写回数据库，已存在的条目跳过（可重复执行）
+
+  dir (可选)
+    导出目录，相对项目根，默认 docs/memory。
+
+说明：
+  只导出有效事实（未过期、未被替代）和未归档的 memo；导入的 memo 会同步写入 memo_archive.jsonl 与 dev-log.md。
+
+示例：
+  export_memory()
+    -> docs/memory/*.md
+  export_memory(mode="import", dir="docs/memory")
+    -> 从提交到仓库的知识库重建记忆
+
+触发词：
+  "mpm 导出记忆", "mpm 导入记忆", "mpm export memory"`),
+		mcp.WithInputSchema[ExportMemoryArgs](),
+	), wrapExportMemory(sm))
+
 	// 注：known_facts 已在 RegisterIntelligenceTools 中注册,此处删除重复注册
 }
 
@@ -93,12 +172,16 @@ func wrapMemo(sm *SessionManager) server.ToolHandlerFunc {
 			txtManual = "手动录入"
 		}
 
+		head, dirty := memoGitContext(sm.ProjectRoot, args.WithDirty)
+
 		var memos []core.Memo
 		for _, item := range args.Items {
 			memo := core.Memo{
-				Category: fallback(item.Category, "开发"),
-				Path:     fallback(item.Path, "-"),
-				Content:  item.Content,
+				Category:  fallback(item.Category, "开发"),
+				Path:      fallback(item.Path, "-"),
+				Content:   item.Content,
+				GitCommit: head,
+				GitDirty:  dirty,
 			}
 
 			// 智取实体名
@@ -136,7 +219,15 @@ func wrapMemo(sm *SessionManager) server.ToolHandlerFunc {
 			return mcp.NewToolResultError(fmt.Sprintf("保存备忘录失败： %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("已成功录入 %d 条记录 (IDs: %v)。", len(ids), ids)), nil
+		var paths []string
+		for _, memo := range memos {
+			if memo.Path != "-" {
+				paths = append(paths, memo.Path)
+			}
+		}
+		warning := warnFileLocks(ctx, sm, fallback(args.TaskID, sessionTaskID(sm)), paths)
+
+		return mcp.NewToolResultText(fmt.Sprintf("已成功录入 %d 条记录 (IDs: %v)。%s", len(ids), ids, warning)), nil
 	}
 }
 
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// serverStartedAt 进程启动时间，用于计算运行时长
+var serverStartedAt = time.Now()
+
+func wrapSystemMetrics(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var sb strings.Builder
+		sb.WriteString("### 📈 系统指标\n\n")
+		sb.WriteString(fmt.Sprintf("- **运行时长**: %s\n", time.Since(serverStartedAt).Round(time.Second)))
+		sb.WriteString(fmt.Sprintf("- **项目**: %s\n", fallback(sm.ProjectRoot, "(未绑定)")))
+		if p := sm.ClientProfile(); p != nil {
+			sb.WriteString(fmt.Sprintf("- **客户端**: %s %s (protocol %s) resources=%v structured=%v progress=%v markdown=%v → 渲染 %s\n",
+				fallback(p.Name, "unknown"), p.Version, p.ProtocolVersion, p.Resources, p.StructuredContent, p.Progress, p.Markdown, resolveRenderTarget(sm)))
+		}
+
+		if sm.IndexWatcher != nil {
+			if st, ok := sm.IndexWatcher.Stats(); ok {
+				last := "尚无变更"
+				if !st.LastBatchAt.IsZero() {
+					last = fmt.Sprintf("%s %s", st.LastBatchAt.Format("01-02 15:04:05"), fallback(strings.Join(st.LastScopes, ", "), "(整库)"))
+				}
+				sb.WriteString(fmt.Sprintf("- **文件监听**: %d 个目录，已索引 %d 批 / %d 处变更，最近: %s\n", st.WatchedDirs, st.Batches, st.FilesChanged, last))
+				if st.Truncated {
+					sb.WriteString("  - ⚠️ 目录数超过监听上限，部分目录仍依赖定期刷新\n")
+				}
+				if st.LastError != "" {
+					sb.WriteString(fmt.Sprintf("  - ❌ %s\n", st.LastError))
+				}
+			} else {
+				sb.WriteString("- **文件监听**: 未启用\n")
+			}
+		}
+
+		enabled := sm.ProjectRoot != "" && core.LoadProjectSettings(sm.ProjectRoot).Scheduler.Enabled
+		sb.WriteString(fmt.Sprintf("\n#### ⏱ 调度器 (enabled=%v)\n\n", enabled))
+		if sm.Scheduler == nil {
+			sb.WriteString("调度器未启动。\n")
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
+		jobs := sm.Scheduler.Status()
+		if len(jobs) == 0 {
+			sb.WriteString("暂无执行记录（在 .mcp-config/settings.json 中设置 scheduler.enabled=true 启用）。\n")
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+		sb.WriteString("| 任务 | 类型 | 间隔 | 次数 | 最近执行 | 耗时 | 结果 |\n")
+		sb.WriteString("|------|------|------|------|----------|------|------|\n")
+		for _, j := range jobs {
+			result := j.LastResult
+			if j.LastError != "" {
+				result = "❌ " + j.LastError
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %s | %dms | %s |\n",
+				j.Name, j.Kind, j.Every, j.Runs, j.LastRun.Format("01-02 15:04"), j.Elapsed.Milliseconds(), result))
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
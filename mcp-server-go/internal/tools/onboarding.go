@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== manager_analyze(intent=ONBOARD)：新人接手简报 ==========
+// 一次汇总目录结构、命名规范、复杂度热点、近期 memo 与未完成 hook，保存为 onboarding.md，
+// 代替新 clone 后手动依次调用 project_map / initialize_project / system_recall / list_hooks。
+
+const (
+	onboardingFile    = "onboarding.md"
+	onboardingDirs    = 20
+	onboardingHotspot = 10
+	onboardingMemos   = 10
+)
+
+// handleAnalyzeOnboard 生成并保存新人简报（索引已由 step1 预热）
+func handleAnalyzeOnboard(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, args AnalyzeArgs) (*mcp.CallToolResult, error) {
+	content := buildOnboardingBriefing(ctx, sm, ai, args.Scope)
+	path := core.DataPath(sm.ProjectRoot, onboardingFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		if err := os.WriteFile(path, []byte(content), 0644); err == nil {
+			content = fmt.Sprintf("_已保存到 `%s`_\n\n", filepath.ToSlash(path)) + content
+		}
+	}
+	return deliverLargeOutput(sm, onboardingFile, content), nil
+}
+
+func buildOnboardingBriefing(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, scope string) string {
+	tagger := resolvePathTagger(sm, false)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🧭 项目接手简报: %s\n\n", filepath.Base(sm.ProjectRoot)))
+	sb.WriteString(fmt.Sprintf("_生成于 %s", time.Now().Format("2006-01-02 15:04")))
+	if strings.TrimSpace(scope) != "" {
+		sb.WriteString(fmt.Sprintf("，scope: `%s`", scope))
+	}
+	sb.WriteString("_\n\n")
+
+	// 1. 目录结构
+	sb.WriteString("## 1. 目录结构\n\n")
+	if st, err := ai.StructureProjectWithScope(sm.ProjectRoot, scope); err == nil && st != nil {
+		tagger.FilterStructure(st)
+		sb.WriteString(renderOnboardingDirs(st))
+	} else {
+		sb.WriteString("_结构扫描失败，可稍后调用 project_map(level=\"structure\")_\n")
+	}
+
+	// 2. 命名规范
+	sb.WriteString("\n## 2. 命名规范\n\n")
+	if naming, err := ai.AnalyzeNamingStyle(sm.ProjectRoot); err == nil && naming != nil && !naming.IsNewProject {
+		sb.WriteString(fmt.Sprintf("- 函数/变量: **%s** (snake_case %s / camelCase %s)\n", naming.DominantStyle, naming.SnakeCasePct, naming.CamelCasePct))
+		if naming.ClassStyle != "" {
+			sb.WriteString(fmt.Sprintf("- 类型: %s\n", naming.ClassStyle))
+		}
+		if len(naming.CommonPrefixes) > 0 {
+			sb.WriteString(fmt.Sprintf("- 常用前缀: %s\n", strings.Join(naming.CommonPrefixes, ", ")))
+		}
+		if len(naming.SampleNames) > 0 {
+			sb.WriteString(fmt.Sprintf("- 样例: %s\n", strings.Join(naming.SampleNames, ", ")))
+		}
+	} else {
+		sb.WriteString("_符号过少，尚无可参考的命名规范_\n")
+	}
+
+	// 3. 复杂度热点
+	sb.WriteString("\n## 3. 复杂度热点（改动前先 code_impact）\n\n")
+	if report, err := ai.BuildIndexReport(sm.ProjectRoot, 0, onboardingHotspot, tagger); err == nil && len(report.HighRiskSymbols) > 0 {
+		sb.WriteString(fmt.Sprintf("共 %d 个符号 / %d 条调用关系。\n\n", report.TotalSymbols, report.TotalCalls))
+		for i, s := range report.HighRiskSymbols {
+			sb.WriteString(fmt.Sprintf("%d. `%s` (%s) — %s:%d, fan-in %d / fan-out %d\n", i+1, s.Name, s.Type, s.FilePath, s.Line, s.FanIn, s.FanOut))
+		}
+	} else {
+		sb.WriteString("_索引中暂无调用关系数据_\n")
+	}
+
+	// 4. 近期 memo
+	sb.WriteString("\n## 4. 近期变更记录 (memo)\n\n")
+	sb.WriteString(renderOnboardingMemos(ctx, sm))
+
+	// 5. 未完成 hook
+	sb.WriteString("\n## 5. 未完成待办 (hook)\n\n")
+	sb.WriteString(renderOnboardingHooks(ctx, sm))
+
+	sb.WriteString("\n## 下一步\n\n")
+	sb.WriteString("- 细看某个模块: project_map(scope=\"目录\", level=\"symbols\")\n")
+	sb.WriteString("- 修改热点符号前: code_impact(symbol_name=\"...\")\n")
+	sb.WriteString("- 查历史决策: system_recall(keywords=\"...\")\n")
+	return sb.String()
+}
+
+func renderOnboardingDirs(st *services.StructureResult) string {
+	type dirCount struct {
+		path  string
+		count int
+	}
+	dirs := make([]dirCount, 0, len(st.Structure))
+	for p, info := range st.Structure {
+		dirs = append(dirs, dirCount{p, info.FileCount})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].count == dirs[j].count {
+			return dirs[i].path < dirs[j].path
+		}
+		return dirs[i].count > dirs[j].count
+	})
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d 个文件 / %d 个目录，文件最多的目录：\n\n", st.TotalFiles, len(dirs)))
+	for i, d := range dirs {
+		if i >= onboardingDirs {
+			sb.WriteString(fmt.Sprintf("- ... 其余 %d 个目录\n", len(dirs)-onboardingDirs))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- `%s/` (%d files)\n", fallback(d.path, "(root)"), d.count))
+	}
+	return sb.String()
+}
+
+func renderOnboardingMemos(ctx context.Context, sm *SessionManager) string {
+	if sm.Memory == nil {
+		return "_记忆层未初始化_\n"
+	}
+	memos, err := sm.Memory.QueryMemos(ctx, "", "", onboardingMemos)
+	if err != nil || len(memos) == 0 {
+		return "_暂无 memo 记录_\n"
+	}
+	var sb strings.Builder
+	for _, m := range memos {
+		sb.WriteString(fmt.Sprintf("- %s [%s] %s: %s\n", m.Timestamp.Format("01-02"), m.Category, m.Entity, truncateRunes(m.Act, 80)))
+	}
+	return sb.String()
+}
+
+func renderOnboardingHooks(ctx context.Context, sm *SessionManager) string {
+	if sm.Memory == nil {
+		return "_记忆层未初始化_\n"
+	}
+	hooks, err := sm.Memory.ListHooks(ctx, "open")
+	if err != nil || len(hooks) == 0 {
+		return "_没有未完成的 hook_\n"
+	}
+	var sb strings.Builder
+	for _, h := range hooks {
+		sb.WriteString(fmt.Sprintf("- [%s] %s (ID: %s)\n", h.Priority, truncateRunes(h.Description, 100), h.HookID))
+	}
+	return sb.String()
+}
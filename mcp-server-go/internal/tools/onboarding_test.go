@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"mcp-server-go/internal/services"
+)
+
+func TestOnboardIntentAndDirs(t *testing.T) {
+	if got := determineIntent("帮我熟悉项目结构", "", false); got != "ONBOARD" {
+		t.Errorf("keyword intent = %q, want ONBOARD", got)
+	}
+	if got := determineIntent("随便看看", "onboard", false); got != "ONBOARD" {
+		t.Errorf("explicit intent = %q, want ONBOARD", got)
+	}
+
+	st := &services.StructureResult{TotalFiles: 0, Structure: map[string]services.StructureDirInfo{}}
+	for i := 0; i < onboardingDirs+3; i++ {
+		st.Structure[fmt.Sprintf("pkg/d%02d", i)] = services.StructureDirInfo{FileCount: i + 1}
+		st.TotalFiles += i + 1
+	}
+	out := renderOnboardingDirs(st)
+	if !strings.HasPrefix(strings.SplitN(out, "\n- ", 2)[1], "`pkg/d22/` (23 files)") {
+		t.Errorf("largest dir should come first:\n%s", out)
+	}
+	if !strings.Contains(out, "其余 3 个目录") || strings.Contains(out, "pkg/d00/") {
+		t.Errorf("dirs beyond limit should be folded:\n%s", out)
+	}
+}
@@ -0,0 +1,16 @@
+package tools
+
+import (
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// resolvePathTagger 返回用于排除 vendored/generated 文件的标记器
+// include 参数或 settings.json 中 index.include_vendored 为 true 时返回 nil（不过滤）
+func resolvePathTagger(sm *SessionManager, include bool) *services.PathTagger {
+	settings := core.LoadProjectSettings(sm.ProjectRoot)
+	if include || settings.Index.IncludeVendored {
+		return nil
+	}
+	return services.NewPathTagger(settings.Index.VendoredPaths, settings.Index.GeneratedPaths)
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ProjectStatsArgs 项目统计参数
+type ProjectStatsArgs struct {
+	Scope string `json:"scope" jsonschema:"description=仅统计该子目录（相对项目根）"`
+	Top   int    `json:"top" jsonschema:"default=10,description=最大文件/目录列表的条数"`
+	Quiet bool   `json:"quiet" jsonschema:"description=精简输出 (去除横幅/emoji/提示)"`
+}
+
+func wrapProjectStats(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args ProjectStatsArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+
+		stats, err := ai.CollectProjectStats(sm.ProjectRoot, args.Scope, args.Top)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("统计失败: %v", err)), nil
+		}
+		return applyQuiet(mcp.NewToolResultText(renderProjectStats(stats)), resolveQuiet(sm, args.Quiet)), nil
+	}
+}
+
+// renderProjectStats 渲染语言分布、最大文件/目录与符号类型
+func renderProjectStats(st *services.ProjectStats) string {
+	var sb strings.Builder
+	sb.WriteString("### 📊 项目统计\n\n")
+	if st.Scope != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", st.Scope))
+	}
+	sb.WriteString(fmt.Sprintf("**总计**: %d 文件 | %d 行 | %d 符号 (耗时 %dms)\n\n", st.TotalFiles, st.TotalLines, st.TotalSymbols, st.ElapsedMs))
+	if !st.Indexed {
+		sb.WriteString("> ⚠️ 尚未建立索引，符号数为 0，行数来自快速扫描。可先执行 initialize_project 或 project_map 触发索引。\n\n")
+	}
+
+	sb.WriteString("#### 🗂 语言分布\n\n")
+	sb.WriteString("| 语言 | 文件 | 行数 | 占比 | 符号 |\n")
+	sb.WriteString("|------|------|------|------|------|\n")
+	for _, l := range st.Languages {
+		share := 0.0
+		if st.TotalLines > 0 {
+			share = float64(l.Lines) * 100 / float64(st.TotalLines)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d | %.1f%% | %d |\n", l.Language, l.Files, l.Lines, share, l.Symbols))
+	}
+
+	sb.WriteString("\n#### 📄 最大文件\n\n")
+	for _, f := range st.LargestFiles {
+		sb.WriteString(fmt.Sprintf("- `%s` %d 行, %d 符号\n", f.Path, f.Lines, f.Symbols))
+	}
+
+	sb.WriteString("\n#### 📁 最大目录（仅计直属文件）\n\n")
+	for _, d := range st.LargestDirs {
+		name := d.Path
+		if name == "." {
+			name = "(root)"
+		}
+		sb.WriteString(fmt.Sprintf("- `%s/` %d 文件, %d 行, %d 符号\n", name, d.Files, d.Lines, d.Symbols))
+	}
+
+	if len(st.SymbolTypes) > 0 {
+		types := make([]string, 0, len(st.SymbolTypes))
+		for t := range st.SymbolTypes {
+			types = append(types, t)
+		}
+		sort.Slice(types, func(i, j int) bool { return st.SymbolTypes[types[i]] > st.SymbolTypes[types[j]] })
+		parts := make([]string, 0, len(types))
+		for _, t := range types {
+			parts = append(parts, fmt.Sprintf("%s %d", t, st.SymbolTypes[t]))
+		}
+		sb.WriteString("\n#### 🔣 符号类型\n\n" + strings.Join(parts, " | ") + "\n")
+	}
+	return sb.String()
+}
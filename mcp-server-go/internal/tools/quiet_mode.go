@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 精简输出 (quiet mode) ==========
+// 供批量编排场景使用：去掉装饰性横幅、emoji 与提示语，只保留必要数据行。
+
+// resolveQuiet 参数显式开启优先，否则读取项目配置 output.quiet
+func resolveQuiet(sm *SessionManager, quiet bool) bool {
+	if quiet {
+		return true
+	}
+	if sm == nil || sm.ProjectRoot == "" {
+		return false
+	}
+	return core.LoadProjectSettings(sm.ProjectRoot).Output.Quiet
+}
+
+// isEmojiRune 判断装饰性 emoji（保留 → ▶ ○ 等状态/指向符号）
+func isEmojiRune(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r == 0xFE0F || r == 0x200D || r == 0x20E3:
+		return true
+	}
+	return false
+}
+
+// isBannerLine 整行由分隔符组成
+func isBannerLine(line string) bool {
+	t := strings.TrimSpace(line)
+	if len([]rune(t)) < 3 {
+		return false
+	}
+	return strings.Trim(t, "═─━=-*#") == ""
+}
+
+// isTipLine 提示/引导类行
+func isTipLine(line string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, "> ") || strings.HasPrefix(t, "💡") || strings.HasPrefix(t, "🔍")
+}
+
+// quietText 精简文本输出
+func quietText(s string) string {
+	var out []string
+	inTipBlock := false
+	for _, line := range strings.Split(s, "\n") {
+		if isBannerLine(line) {
+			continue
+		}
+		if isTipLine(line) {
+			inTipBlock = true
+			continue
+		}
+		t := strings.TrimSpace(line)
+		if inTipBlock && (strings.HasPrefix(t, "•") || strings.HasPrefix(t, "⚠")) {
+			continue
+		}
+		inTipBlock = false
+
+		line = strings.Map(func(r rune) rune {
+			if isEmojiRune(r) {
+				return -1
+			}
+			return r
+		}, line)
+		line = strings.TrimRight(line, " ")
+		if strings.TrimSpace(line) == "" {
+			if len(out) == 0 || out[len(out)-1] == "" {
+				continue
+			}
+			line = ""
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// applyQuiet 对工具结果中的所有文本内容执行精简；JSON 结果会被压缩为单行
+func applyQuiet(res *mcp.CallToolResult, quiet bool) *mcp.CallToolResult {
+	if !quiet || res == nil {
+		return res
+	}
+	for i, c := range res.Content {
+		tc, ok := c.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		text := quietText(tc.Text)
+		if json.Valid([]byte(text)) {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, []byte(text)); err == nil {
+				text = buf.String()
+			}
+		}
+		tc.Text = text
+		res.Content[i] = tc
+	}
+	return res
+}
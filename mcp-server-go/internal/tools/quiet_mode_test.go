@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuietText_StripsDecoration(t *testing.T) {
+	in := "\n══════════════\n   【任务链完成】T1\n══════════════\n\n✅ 所有阶段已完成。\n→ 下一阶段: verify\n\n🔍 自审：当前发现是否与初始目标一致？\n  • 一切正常 → 继续\n  • 发现偏差 → re-init\n> 使用 manager_release_hook 释放"
+	out := quietText(in)
+
+	for _, banned := range []string{"══", "✅", "🔍", "自审", "•", "> 使用"} {
+		if strings.Contains(out, banned) {
+			t.Errorf("quiet output still contains %q:\n%s", banned, out)
+		}
+	}
+	for _, kept := range []string{"【任务链完成】T1", "所有阶段已完成。", "→ 下一阶段: verify"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("quiet output lost %q:\n%s", kept, out)
+		}
+	}
+}
+
+func TestPlainText_StripsMarkdown(t *testing.T) {
+	in := "### ✅ 结果\n\n| 文件 | 行 |\n|---|---|\n| `a.go` | 12 |\n\n```go\nfunc A() {}\n```\n**注意** 见 [文档](docs/a.md)"
+	out := plainText(in)
+
+	for _, banned := range []string{"###", "|", "```", "**", "`", "✅"} {
+		if strings.Contains(out, banned) {
+			t.Errorf("plain output still contains %q:\n%s", banned, out)
+		}
+	}
+	for _, kept := range []string{"[OK] 结果", "a.go  12", "    func A() {}", "文档 (docs/a.md)"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("plain output lost %q:\n%s", kept, out)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== system_recall 语义混合检索 ==========
+//
+// semantic=true 时，在关键词结果之外按向量相似度召回 memo/fact，两路结果用 RRF (Reciprocal Rank Fusion)
+// 合并排序：两路都命中的记录排在前面，只被语义命中的改写表述也能被找回。
+// 未配置 embedding.provider 或向量化失败时退回纯关键词结果，并在输出中说明。
+
+const (
+	rrfK             = 60   // RRF 平滑常数
+	semanticMinScore = 0.25 // 低于该余弦相似度的语义命中视为噪声
+)
+
+// semanticRecall 返回融合后的 memo 与 fact，以及一行检索说明
+func semanticRecall(ctx context.Context, sm *SessionManager, args SystemRecallArgs, within core.TimeRange,
+	memos []core.Memo, facts []core.KnownFact) ([]core.Memo, []core.KnownFact, string) {
+	cfg := core.LoadProjectSettings(sm.ProjectRoot).Embedding
+	embedder, err := core.NewEmbedder(sm.ProjectRoot, cfg)
+	if err != nil {
+		return memos, facts, fmt.Sprintf("⚠️ 语义检索不可用（%v），仅返回关键词结果", err)
+	}
+	if embedder == nil {
+		return memos, facts, "⚠️ 未配置 embedding.provider（.mcp-config/settings.json），仅返回关键词结果"
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	note := fmt.Sprintf("🧭 混合检索: 关键词 + 语义 (%s)", embedder.Model())
+	added, syncErr := sm.Memory.SyncEmbeddings(ctx, embedder, cfg.BatchSize)
+	if added > 0 {
+		note += fmt.Sprintf("，新增向量 %d 条", added)
+	}
+	if syncErr != nil {
+		note += fmt.Sprintf("；⚠️ 向量同步未完成: %v", syncErr)
+	}
+
+	hits, err := sm.Memory.SemanticSearch(ctx, embedder, args.Keywords, semanticMinScore, limit*3)
+	if err != nil {
+		return memos, facts, fmt.Sprintf("⚠️ 语义检索失败（%v），仅返回关键词结果", err)
+	}
+
+	memoScores := make(map[int64]float64)
+	factScores := make(map[int64]float64)
+	for i, m := range memos {
+		memoScores[m.ID] += 1.0 / float64(rrfK+i+1)
+	}
+	for i, f := range facts {
+		factScores[f.ID] += 1.0 / float64(rrfK+i+1)
+	}
+	var memoRank, factRank int
+	var extraMemos, extraFacts []int64
+	for _, h := range hits {
+		switch h.Kind {
+		case core.EmbeddingMemo:
+			memoRank++
+			if _, ok := memoScores[h.ID]; !ok {
+				extraMemos = append(extraMemos, h.ID)
+			}
+			memoScores[h.ID] += 1.0 / float64(rrfK+memoRank)
+		case core.EmbeddingFact:
+			factRank++
+			if _, ok := factScores[h.ID]; !ok {
+				extraFacts = append(extraFacts, h.ID)
+			}
+			factScores[h.ID] += 1.0 / float64(rrfK+factRank)
+		}
+	}
+
+	// 只被语义命中的记录需补读，并套用与关键词检索相同的分类/时间过滤
+	if more, err := sm.Memory.GetMemosByIDs(ctx, extraMemos); err == nil {
+		for _, m := range more {
+			if (args.Category == "" || m.Category == args.Category) && within.Contains(m.Timestamp) {
+				memos = append(memos, m)
+			}
+		}
+	}
+	if more, err := sm.Memory.GetFactsByIDs(ctx, extraFacts); err == nil {
+		for _, f := range more {
+			if within.Contains(f.CreatedAt) {
+				facts = append(facts, f)
+			}
+		}
+	}
+
+	sort.SliceStable(memos, func(i, j int) bool { return memoScores[memos[i].ID] > memoScores[memos[j].ID] })
+	sort.SliceStable(facts, func(i, j int) bool { return factScores[facts[i].ID] > factScores[facts[j].ID] })
+	if len(memos) > limit {
+		memos = memos[:limit]
+	}
+	if len(facts) > limit {
+		facts = facts[:limit]
+	}
+	return memos, facts, note
+}
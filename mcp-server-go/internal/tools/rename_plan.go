@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RenamePlanArgs 重命名计划参数
+type RenamePlanArgs struct {
+	OldName string `json:"old_name" jsonschema:"required,description=现有符号名"`
+	NewName string `json:"new_name" jsonschema:"required,description=新符号名"`
+	Scope   string `json:"scope" jsonschema:"description=只在该目录下查找引用 (留空=整个项目)"`
+}
+
+// renamePlanMaxFileSteps 按文件拆分的步骤上限，其余文件合并为一步
+const renamePlanMaxFileSteps = 15
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func wrapRenamePlan(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args RenamePlanArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		args.OldName, args.NewName = strings.TrimSpace(args.OldName), strings.TrimSpace(args.NewName)
+		if !identifierRe.MatchString(args.OldName) || !identifierRe.MatchString(args.NewName) {
+			return mcp.NewToolResultError("old_name / new_name 必须是合法标识符（字母、数字、下划线，不以数字开头）"), nil
+		}
+		if args.OldName == args.NewName {
+			return mcp.NewToolResultError("new_name 与 old_name 相同"), nil
+		}
+
+		if strings.TrimSpace(args.Scope) != "" {
+			_, _ = ai.IndexScope(sm.ProjectRoot, args.Scope)
+		} else {
+			_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		}
+		refs, err := ai.FindRenameReferences(ctx, sm.ProjectRoot, args.OldName, args.NewName, args.Scope)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("查找引用失败: %v", err)), nil
+		}
+		if len(refs.Definitions) == 0 && len(refs.Refs) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("未找到「%s」的定义或引用，请确认拼写或 scope", args.OldName)), nil
+		}
+		return deliverLargeOutput(sm, "rename_plan.md", renderRenamePlan(refs, args)), nil
+	}
+}
+
+var renameKindLabel = map[string]string{
+	services.RenameKindDefinition: "定义",
+	services.RenameKindCall:       "调用",
+	services.RenameKindReference:  "引用",
+	services.RenameKindTest:       "测试",
+	services.RenameKindComment:    "注释",
+	services.RenameKindString:     "字符串",
+	services.RenameKindDoc:        "文档",
+}
+
+var renameConfidenceMark = map[string]string{
+	services.RenameConfidenceHigh:   "🔴 high",
+	services.RenameConfidenceMedium: "🟡 medium",
+	services.RenameConfidenceLow:    "⚪ low",
+}
+
+// renderRenamePlan 引用清单（按文件分组）+ 分步计划 + 可直接用于 task_chain spawn 的 sub_tasks
+func renderRenamePlan(r *services.RenameRefs, args RenamePlanArgs) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### ✏️ 重命名计划: `%s` → `%s`\n\n", args.OldName, args.NewName))
+	if args.Scope != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n", args.Scope))
+	}
+
+	counts := make(map[string]int)
+	for _, ref := range r.Refs {
+		counts[ref.Kind]++
+	}
+	sb.WriteString(fmt.Sprintf("**📊 统计**: 定义 %d，调用 %d，代码引用 %d，测试 %d，注释/字符串/文档 %d\n\n",
+		len(r.Definitions), counts[services.RenameKindCall], counts[services.RenameKindReference], counts[services.RenameKindTest],
+		counts[services.RenameKindComment]+counts[services.RenameKindString]+counts[services.RenameKindDoc]))
+	if len(r.Conflicts) > 0 {
+		sb.WriteString(fmt.Sprintf("⚠️ **命名冲突**: `%s` 已存在 %d 处定义：\n", args.NewName, len(r.Conflicts)))
+		for _, c := range r.Conflicts {
+			sb.WriteString(fmt.Sprintf("- %s:%d (%s)\n", c.File, c.Line, c.Content))
+		}
+		sb.WriteString("\n")
+	}
+	if len(r.Definitions) == 0 {
+		sb.WriteString("⚠️ 索引中没有该符号的定义（可能来自外部依赖或未索引的语言），以下仅为文本引用。\n\n")
+	}
+	if !r.TextSearch {
+		sb.WriteString("⚠️ 文本搜索失败，字符串/注释/文档中的引用未收录。\n\n")
+	}
+
+	// 引用清单
+	sb.WriteString("#### 📍 定义\n")
+	for _, d := range r.Definitions {
+		sb.WriteString(fmt.Sprintf("- %s:%d (%s)\n", d.File, d.Line, d.Content))
+	}
+	files, byFile := groupRenameRefs(r.Refs)
+	for _, f := range files {
+		sb.WriteString(fmt.Sprintf("\n#### 📄 %s (%d)\n", f, len(byFile[f])))
+		for _, ref := range byFile[f] {
+			line := fmt.Sprintf("- L%d %s %s `%s`", ref.Line, renameConfidenceMark[ref.Confidence], renameKindLabel[ref.Kind], truncateRunes(ref.Content, 100))
+			if ref.Caller != "" {
+				line += fmt.Sprintf(" in `%s`", ref.Caller)
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	subs := buildRenameSteps(r, args, files, byFile)
+	sb.WriteString("\n#### 🪜 执行步骤\n")
+	for i, st := range subs {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, st["name"]))
+	}
+	data, _ := json.MarshalIndent(subs, "", "  ")
+	sb.WriteString("\n#### 🔗 task_chain 子任务\n")
+	sb.WriteString("在任务链的执行阶段调用 `task_chain(mode=\"spawn\", task_id=..., phase_id=..., sub_tasks=<下列 JSON>)`：\n")
+	sb.WriteString("```json\n" + string(data) + "\n```\n")
+	return sb.String()
+}
+
+func groupRenameRefs(refs []services.RenameRef) ([]string, map[string][]services.RenameRef) {
+	byFile := make(map[string][]services.RenameRef)
+	var files []string
+	for _, ref := range refs {
+		if _, ok := byFile[ref.File]; !ok {
+			files = append(files, ref.File)
+		}
+		byFile[ref.File] = append(byFile[ref.File], ref)
+	}
+	return files, byFile
+}
+
+// buildRenameSteps 改定义 → 逐文件改代码引用 → 改测试 → 复核低置信度提及 → 验证
+func buildRenameSteps(r *services.RenameRefs, args RenamePlanArgs, files []string, byFile map[string][]services.RenameRef) []map[string]interface{} {
+	var subs []map[string]interface{}
+	add := func(id, name string, deps ...string) {
+		st := map[string]interface{}{"id": id, "name": name}
+		if len(deps) > 0 {
+			st["depends_on"] = deps
+		}
+		subs = append(subs, st)
+	}
+
+	first := ""
+	if len(r.Definitions) > 0 {
+		defFiles := make(map[string]bool)
+		for _, d := range r.Definitions {
+			defFiles[d.File] = true
+		}
+		add("rename_def", fmt.Sprintf("修改定义 %s → %s（%s）", args.OldName, args.NewName, strings.Join(sortedKeys(defFiles), ", ")))
+		first = "rename_def"
+	}
+	deps := func() []string {
+		if first == "" {
+			return nil
+		}
+		return []string{first}
+	}
+
+	var codeFiles, testFiles []string
+	lowRefs := 0
+	for _, f := range files {
+		code, test := 0, 0
+		for _, ref := range byFile[f] {
+			switch {
+			case ref.Confidence == services.RenameConfidenceLow:
+				lowRefs++
+			case ref.Kind == services.RenameKindTest:
+				test++
+			default:
+				code++
+			}
+		}
+		if code > 0 {
+			codeFiles = append(codeFiles, fmt.Sprintf("%s (%d)", f, code))
+		}
+		if test > 0 {
+			testFiles = append(testFiles, fmt.Sprintf("%s (%d)", f, test))
+		}
+	}
+
+	var stepIDs []string
+	for i, f := range codeFiles {
+		if i == renamePlanMaxFileSteps {
+			id := "rename_refs_rest"
+			add(id, fmt.Sprintf("更新其余 %d 个文件中的引用: %s", len(codeFiles)-i, strings.Join(codeFiles[i:], ", ")), deps()...)
+			stepIDs = append(stepIDs, id)
+			break
+		}
+		id := fmt.Sprintf("rename_refs_%02d", i+1)
+		add(id, "更新引用: "+f, deps()...)
+		stepIDs = append(stepIDs, id)
+	}
+	if len(testFiles) > 0 {
+		add("rename_tests", "更新测试: "+strings.Join(testFiles, ", "), deps()...)
+		stepIDs = append(stepIDs, "rename_tests")
+	}
+	if lowRefs > 0 {
+		add("rename_review", fmt.Sprintf("人工复核注释/字符串/文档中的 %d 处提及（配置键、序列化字段、对外文档可能不应改名）", lowRefs), deps()...)
+		stepIDs = append(stepIDs, "rename_review")
+	}
+	if first != "" {
+		stepIDs = append([]string{first}, stepIDs...)
+	}
+	add("rename_verify", fmt.Sprintf("编译并运行测试；code_search(query=\"%s\", search_type=\"content\") 确认无残留", args.OldName), stepIDs...)
+	return subs
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
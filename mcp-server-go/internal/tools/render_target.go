@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ========== 渲染目标 (markdown / plain) ==========
+// 部分终端 MCP 客户端渲染 Markdown 效果差：plain 模式下去除代码围栏、表格与 emoji，
+// 转为等价的纯文本。所有工具统一经由 RenderMiddleware 生效。
+
+const (
+	RenderMarkdown = "markdown"
+	RenderPlain    = "plain"
+)
+
+// resolveRenderTarget 会话设置优先，其次项目配置 output.render，最后按客户端能力画像自动选择
+func resolveRenderTarget(sm *SessionManager) string {
+	if sm == nil {
+		return RenderMarkdown
+	}
+	if sm.RenderTarget != "" {
+		return sm.RenderTarget
+	}
+	if sm.ProjectRoot != "" {
+		switch core.LoadProjectSettings(sm.ProjectRoot).Output.Render {
+		case RenderPlain:
+			return RenderPlain
+		case RenderMarkdown:
+			return RenderMarkdown
+		}
+	}
+	if p := sm.ClientProfile(); p != nil && !p.Markdown {
+		return RenderPlain
+	}
+	return RenderMarkdown
+}
+
+// plainEmoji 有语义的 emoji 替换为文字，其余装饰性 emoji 直接去除
+var plainEmoji = strings.NewReplacer(
+	"✅", "[OK]", "✔", "[OK]", "❌", "[FAIL]", "✗", "[FAIL]",
+	"⚠️", "[WARN]", "⚠", "[WARN]", "💡", "Tip:", "🔴", "[HIGH]", "🟡", "[MEDIUM]", "🟢", "[LOW]",
+)
+
+var (
+	mdHeadingRe = regexp.MustCompile(`^#{1,6}\s+`)
+	mdLinkRe    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdTableSep  = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+)
+
+func isTableRow(line string) bool {
+	t := strings.TrimSpace(line)
+	return len(t) > 1 && strings.HasPrefix(t, "|") && strings.HasSuffix(t, "|")
+}
+
+// renderPlainTable 将 Markdown 表格转为按列对齐的纯文本
+func renderPlainTable(rows []string) []string {
+	var cells [][]string
+	var widths []int
+	for _, row := range rows {
+		t := strings.TrimSpace(row)
+		if mdTableSep.MatchString(t) {
+			continue
+		}
+		parts := strings.Split(strings.Trim(t, "|"), "|")
+		for i := range parts {
+			parts[i] = plainInline(strings.TrimSpace(parts[i]))
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := len([]rune(parts[i])); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		cells = append(cells, parts)
+	}
+	out := make([]string, 0, len(cells))
+	for _, parts := range cells {
+		var sb strings.Builder
+		for i, c := range parts {
+			sb.WriteString(c)
+			if i < len(parts)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[i]-len([]rune(c))+2))
+			}
+		}
+		out = append(out, strings.TrimRight(sb.String(), " "))
+	}
+	return out
+}
+
+// plainInline 去除行内 Markdown 标记与 emoji
+func plainInline(s string) string {
+	s = mdLinkRe.ReplaceAllString(s, "$1 ($2)")
+	s = strings.ReplaceAll(s, "**", "")
+	s = strings.ReplaceAll(s, "`", "")
+	s = plainEmoji.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if isEmojiRune(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// plainText 将 Markdown 文本转换为纯文本：代码块缩进显示、表格对齐、标题与行内标记去除
+func plainText(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, "    "+line)
+			continue
+		}
+		if isTableRow(line) {
+			j := i
+			for j < len(lines) && isTableRow(lines[j]) {
+				j++
+			}
+			out = append(out, renderPlainTable(lines[i:j])...)
+			i = j - 1
+			continue
+		}
+		if mdHeadingRe.MatchString(t) {
+			line = mdHeadingRe.ReplaceAllString(t, "")
+		} else if strings.HasPrefix(t, "> ") {
+			line = "  " + strings.TrimPrefix(t, "> ")
+		}
+		out = append(out, strings.TrimRight(plainInline(line), " "))
+	}
+	return strings.Join(out, "\n")
+}
+
+// applyRenderTarget 按渲染目标转换工具结果中的文本内容；JSON 结果保持原样
+func applyRenderTarget(res *mcp.CallToolResult, target string) *mcp.CallToolResult {
+	if target != RenderPlain || res == nil {
+		return res
+	}
+	for i, c := range res.Content {
+		tc, ok := c.(mcp.TextContent)
+		if !ok || json.Valid([]byte(tc.Text)) {
+			continue
+		}
+		tc.Text = plainText(tc.Text)
+		res.Content[i] = tc
+	}
+	return res
+}
+
+// RenderMiddleware 对所有工具结果应用会话渲染目标
+func RenderMiddleware(sm *SessionManager) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := next(ctx, request)
+			return applyRenderTarget(res, resolveRenderTarget(sm)), err
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// report：按时间窗口汇总 memo / 完成的任务链 / 释放的钩子 / 索引统计为 Markdown 周报、日报
+// ============================================================================
+
+// ReportArgs 报告参数
+type ReportArgs struct {
+	Since   string `json:"since" jsonschema:"default=7d,description=起始时间 (ISO 日期如 2024-05-01，或相对时长如 1d/7d/2w)"`
+	Until   string `json:"until" jsonschema:"description=截止时间 (格式同 since，纯日期包含当天)，默认现在"`
+	GroupBy string `json:"group_by" jsonschema:"description=memo 分组方式：day 按日期（默认）、category 按类型、entity 按实体,enum=day,enum=category,enum=entity"`
+	Save    bool   `json:"save" jsonschema:"description=同时写入数据目录 reports/report-<起>-<止>.md"`
+}
+
+// reportMemoLimit 单份报告最多收录的 memo 数
+const reportMemoLimit = 500
+
+// reportData 一份报告的原始数据
+type reportData struct {
+	Within      core.TimeRange
+	Memos       []core.Memo // 时间升序
+	Chains      []core.TaskChainRecord
+	Hooks       []core.TimelineEvent // 释放事件
+	Annotations []core.TimelineAnnotation
+	Index       *index_build_status
+	Stats       *services.ProjectStats
+}
+
+func wrapReport(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args ReportArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" || sm.Memory == nil {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		groupBy := strings.ToLower(strings.TrimSpace(args.GroupBy))
+		switch groupBy {
+		case "":
+			groupBy = "day"
+		case "day", "category", "entity":
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: 不支持的 group_by %q（可选 day/category/entity）", args.GroupBy)), nil
+		}
+
+		now := time.Now()
+		within, err := core.ParseTimeRange(fallback(args.Since, "7d"), args.Until, now)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if within.Until.IsZero() {
+			within.Until = now
+		}
+
+		data, err := collectReport(ctx, sm, ai, within)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("生成报告失败: %v", err)), nil
+		}
+		report := renderReport(filepath.Base(sm.ProjectRoot), data, groupBy)
+
+		if args.Save {
+			dir := core.DataPath(sm.ProjectRoot, "reports")
+			path := filepath.Join(dir, fmt.Sprintf("report-%s-%s.md", within.Since.Format("20060102"), within.Until.Format("20060102")))
+			if err := os.MkdirAll(dir, 0755); err == nil {
+				err = os.WriteFile(path, []byte(report), 0644)
+			}
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("写入报告失败: %v", err)), nil
+			}
+			report += fmt.Sprintf("\n---\n💾 已保存: %s\n", filepath.ToSlash(path))
+		}
+		return mcp.NewToolResultText(report), nil
+	}
+}
+
+// collectReport 汇总时间窗口内的记忆与索引数据；索引统计失败不影响报告
+func collectReport(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, within core.TimeRange) (*reportData, error) {
+	data := &reportData{Within: within}
+
+	memos, err := sm.Memory.SearchMemosInRange(ctx, "", "", within, reportMemoLimit)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(memos, func(i, j int) bool { return memos[i].Timestamp.Before(memos[j].Timestamp) })
+	data.Memos = memos
+
+	if data.Chains, err = sm.Memory.FinishedTaskChainsIn(ctx, within); err != nil {
+		return nil, err
+	}
+
+	events, err := sm.Memory.TimelineActivity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if e.Source == core.TimelineSourceHook && e.Kind == "released" && within.Contains(e.At) {
+			data.Hooks = append(data.Hooks, e)
+		}
+	}
+
+	annotations, err := sm.Memory.ListTimelineAnnotations(ctx, "", within, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(annotations) - 1; i >= 0; i-- { // 倒序 → 升序
+		data.Annotations = append(data.Annotations, annotations[i])
+	}
+
+	if raw, err := os.ReadFile(indexStatusFile(sm.ProjectRoot)); err == nil {
+		var st index_build_status
+		if json.Unmarshal(raw, &st) == nil {
+			data.Index = &st
+		}
+	}
+	if ai != nil {
+		if stats, err := ai.CollectProjectStats(sm.ProjectRoot, "", 0); err == nil {
+			data.Stats = stats
+		}
+	}
+	return data, nil
+}
+
+// renderReport 渲染 Markdown 摘要：概览 → 标注 → 完成的任务链 → 释放的钩子 → memo 明细 → 索引
+func renderReport(project string, d *reportData, groupBy string) string {
+	const day = "2006-01-02"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s 项目报告 %s ~ %s\n\n", project, d.Within.Since.Format(day), d.Within.Until.Format(day)))
+	sb.WriteString(fmt.Sprintf("> %d 条变更记录 · %d 条任务链完成 · %d 个钩子释放", len(d.Memos), len(d.Chains), len(d.Hooks)))
+	if len(d.Annotations) > 0 {
+		sb.WriteString(fmt.Sprintf(" · %d 个标注", len(d.Annotations)))
+	}
+	sb.WriteString("\n\n")
+
+	if len(d.Memos) > 0 {
+		counts := make(map[string]int)
+		for _, m := range d.Memos {
+			counts[m.Category]++
+		}
+		cats := make([]string, 0, len(counts))
+		for c := range counts {
+			cats = append(cats, c)
+		}
+		sort.Slice(cats, func(i, j int) bool {
+			if counts[cats[i]] != counts[cats[j]] {
+				return counts[cats[i]] > counts[cats[j]]
+			}
+			return cats[i] < cats[j]
+		})
+		parts := make([]string, 0, len(cats))
+		for _, c := range cats {
+			parts = append(parts, fmt.Sprintf("%s %d", c, counts[c]))
+		}
+		sb.WriteString("**变更分布**: " + strings.Join(parts, " | ") + "\n\n")
+	}
+
+	if len(d.Annotations) > 0 {
+		sb.WriteString("## 📍 里程碑与标注\n\n")
+		for _, a := range d.Annotations {
+			sb.WriteString(formatAnnotation(a))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Chains) > 0 {
+		sb.WriteString(fmt.Sprintf("## ⛓ 完成的任务链 (%d)\n\n", len(d.Chains)))
+		for _, c := range d.Chains {
+			sb.WriteString(fmt.Sprintf("- **%s** (%s) %s — 完成于 %s\n", c.TaskID, fallback(c.Protocol, "-"), truncateRunes(c.Description, 120), reportTime(c.UpdatedAt)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Hooks) > 0 {
+		sb.WriteString(fmt.Sprintf("## 🪝 释放的钩子 (%d)\n\n", len(d.Hooks)))
+		for _, h := range d.Hooks {
+			sb.WriteString(fmt.Sprintf("- %s **%s** %s\n", h.At.Local().Format("2006-01-02 15:04"), h.Ref, truncateRunes(h.Content, 120)))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("## 📝 变更明细 (%d)\n\n", len(d.Memos)))
+	if len(d.Memos) == 0 {
+		sb.WriteString("（该时间段内没有 memo 记录）\n\n")
+	}
+	writeReportGroups(&sb, d.Memos, groupBy)
+
+	sb.WriteString("## 🗂 索引\n\n")
+	if d.Index != nil {
+		sb.WriteString(fmt.Sprintf("- 最近一次索引: %s", fallback(d.Index.Status, "unknown")))
+		if d.Index.FinishedAt != "" {
+			sb.WriteString(" @ " + reportTime(d.Index.FinishedAt))
+		}
+		if d.Index.TotalFiles > 0 {
+			sb.WriteString(fmt.Sprintf("，%d 文件，耗时 %dms", d.Index.TotalFiles, d.Index.ElapsedMs))
+		}
+		sb.WriteString("\n")
+	}
+	if d.Stats != nil {
+		sb.WriteString(fmt.Sprintf("- 项目规模: %d 文件 | %d 行 | %d 符号\n", d.Stats.TotalFiles, d.Stats.TotalLines, d.Stats.TotalSymbols))
+	}
+	if d.Index == nil && d.Stats == nil {
+		sb.WriteString("（暂无索引信息）\n")
+	}
+	return sb.String()
+}
+
+// writeReportGroups 按日期 / 类型 / 实体分组输出 memo；组内保持时间顺序
+func writeReportGroups(sb *strings.Builder, memos []core.Memo, groupBy string) {
+	var order []string
+	groups := make(map[string][]core.Memo)
+	for _, m := range memos {
+		var key string
+		switch groupBy {
+		case "category":
+			key = fallback(m.Category, "未分类")
+		case "entity":
+			key = fallback(m.Entity, "(无实体)")
+		default:
+			key = m.Timestamp.Local().Format("2006-01-02")
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], m)
+	}
+	if groupBy != "day" {
+		sort.SliceStable(order, func(i, j int) bool { return len(groups[order[i]]) > len(groups[order[j]]) })
+	}
+
+	for _, key := range order {
+		sb.WriteString(fmt.Sprintf("### %s (%d)\n\n", key, len(groups[key])))
+		for _, m := range groups[key] {
+			label := m.Category
+			if groupBy == "category" {
+				label = fallback(m.Entity, "-")
+			}
+			sb.WriteString(fmt.Sprintf("- %s [%s] %s: %s\n", m.Timestamp.Local().Format("01-02 15:04"), label, m.Act, truncateRunes(m.Content, 160)))
+		}
+		sb.WriteString("\n")
+	}
+}
+
+// reportTime RFC3339 时间转本地短格式，无法解析时原样返回
+func reportTime(s string) string {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Local().Format("2006-01-02 15:04")
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestRenderReport_GroupsActivity(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := core.NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+	if _, err := mem.AddMemos(ctx, []core.Memo{
+		{Category: "修改", Entity: "auth", Act: "修复", Path: "auth.go", Content: "修复 token 过期未刷新"},
+		{Category: "开发", Entity: "report", Act: "新增", Path: "report.go", Content: "新增周报工具"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SaveTaskChain(ctx, &core.TaskChainRecord{TaskID: "T-login", Description: "登录重构", Protocol: "linear", Status: "finished"}); err != nil {
+		t.Fatal(err)
+	}
+	hookID, err := mem.CreateHook(ctx, "补充登录测试", "high", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.ReleaseHook(ctx, hookID, "测试已补齐"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	within := core.TimeRange{Since: now.Add(-24 * time.Hour), Until: now.Add(time.Minute)}
+	data, err := collectReport(ctx, &SessionManager{Memory: mem, ProjectRoot: root}, nil, within)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := renderReport("demo", data, "category")
+	for _, want := range []string{"2 条变更记录", "1 条任务链完成", "1 个钩子释放", "T-login", "测试已补齐", "### 修改 (1)", "[auth] 修复: 修复 token 过期未刷新"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q\n%s", want, out)
+		}
+	}
+
+	old := core.TimeRange{Since: now.Add(-48 * time.Hour), Until: now.Add(-24 * time.Hour)}
+	if data, err = collectReport(ctx, &SessionManager{Memory: mem, ProjectRoot: root}, nil, old); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Memos)+len(data.Chains)+len(data.Hooks) != 0 {
+		t.Errorf("window before activity should be empty, got %d memos / %d chains / %d hooks", len(data.Memos), len(data.Chains), len(data.Hooks))
+	}
+}
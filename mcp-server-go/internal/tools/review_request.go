@@ -0,0 +1,372 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ========== 评审请求打包 (request_review) ==========
+//
+// 将任务链的变更统计、阶段摘要、链内影响分析与未解决风险整理成一份可直接交给人工评审的
+// Markdown 文档；可选通过 gh CLI 发布为 PR 评论（受 settings.json commands 安全策略约束）。
+
+// RequestReviewArgs 评审请求参数
+type RequestReviewArgs struct {
+	TaskID string `json:"task_id" jsonschema:"required,description=任务链 ID"`
+	Output string `json:"output" jsonschema:"description=输出文件路径 (默认数据目录下 reviews/<task_id>.md)"`
+	PostPR string `json:"post_pr" jsonschema:"description=PR 编号或 URL；非空时通过 gh pr comment 发布评论"`
+}
+
+// reviewFileStat 单文件变更行数
+type reviewFileStat struct {
+	Path    string
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// firstGitBaseline 任务链最早记录的 git 基线（阶段优先，其次子任务）
+func firstGitBaseline(chain *TaskChainV3) *GitBaseline {
+	for _, p := range chain.Phases {
+		if p.GitBaseline != nil {
+			return p.GitBaseline
+		}
+		for _, sub := range p.SubTasks {
+			if sub.GitBaseline != nil {
+				return sub.GitBaseline
+			}
+		}
+	}
+	return nil
+}
+
+// gitDiffStats 基线提交到当前工作区的逐文件增删行数（含未跟踪文件）
+func gitDiffStats(projectRoot string, base *GitBaseline) ([]reviewFileStat, error) {
+	top, err := gitTopLevel(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	rev := "HEAD"
+	if base != nil && base.Head != "" {
+		rev = base.Head
+	}
+	toRel := func(f string) string {
+		if r, err := filepath.Rel(projectRoot, filepath.Join(top, filepath.FromSlash(f))); err == nil && !strings.HasPrefix(r, "..") {
+			return filepath.ToSlash(r)
+		}
+		return f
+	}
+
+	var stats []reviewFileStat
+	out, err := runGit(projectRoot, "diff", "--numstat", rev)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		st := reviewFileStat{Path: toRel(fields[2])}
+		if fields[0] == "-" {
+			st.Binary = true
+		} else {
+			st.Added, _ = strconv.Atoi(fields[0])
+			st.Deleted, _ = strconv.Atoi(fields[1])
+		}
+		stats = append(stats, st)
+	}
+
+	if untracked, err := runGit(projectRoot, "ls-files", "--others", "--exclude-standard", "--full-name", ":/"); err == nil {
+		for _, f := range strings.Split(strings.TrimSpace(string(untracked)), "\n") {
+			if f == "" {
+				continue
+			}
+			st := reviewFileStat{Path: toRel(f)}
+			if data, err := os.ReadFile(filepath.Join(top, filepath.FromSlash(f))); err == nil {
+				st.Added = strings.Count(string(data), "\n")
+			}
+			stats = append(stats, st)
+		}
+	}
+
+	filtered := stats[:0]
+	for _, st := range stats {
+		if !isMPMArtifact(st.Path) {
+			filtered = append(filtered, st)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].Added+filtered[i].Deleted > filtered[j].Added+filtered[j].Deleted
+	})
+	return filtered, nil
+}
+
+// changedFileRisks 变更文件中复杂度超过阈值的符号
+func changedFileRisks(sm *SessionManager, ai *services.ASTIndexer, files []reviewFileStat) []string {
+	if len(files) == 0 {
+		return nil
+	}
+	g, err := ai.LoadCallGraph(sm.ProjectRoot)
+	if err != nil {
+		return nil
+	}
+	changed := make(map[string]bool, len(files))
+	for _, f := range files {
+		changed[f.Path] = true
+	}
+	threshold := core.LoadProjectSettings(sm.ProjectRoot).Complexity.AlertThreshold
+	type scored struct {
+		line  string
+		score float64
+	}
+	var risks []scored
+	for id, sym := range g.Symbols {
+		if !changed[strings.TrimPrefix(filepath.ToSlash(sym.FilePath), "./")] {
+			continue
+		}
+		score := float64(g.FanOut[id])*1.0 + float64(g.FanIn[id])*0.5
+		if score >= threshold {
+			risks = append(risks, scored{fmt.Sprintf("`%s` (%s) 复杂度 %.1f，被 %d 处调用", sym.Name, sym.FilePath, score, g.FanIn[id]), score})
+		}
+	}
+	sort.Slice(risks, func(i, j int) bool { return risks[i].score > risks[j].score })
+	var lines []string
+	for i, r := range risks {
+		if i >= 10 {
+			break
+		}
+		lines = append(lines, r.line)
+	}
+	return lines
+}
+
+// buildReviewDocument 生成评审文档
+func buildReviewDocument(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, chain *TaskChainV3) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 评审请求: %s\n\n", chain.TaskID))
+	sb.WriteString(fmt.Sprintf("> %s\n\n", strings.ReplaceAll(strings.TrimSpace(chain.Description), "\n", "\n> ")))
+
+	passed := 0
+	for _, p := range chain.Phases {
+		if p.Status == PhasePassed || p.Status == PhaseSkipped {
+			passed++
+		}
+	}
+	sb.WriteString(fmt.Sprintf("- **协议**: %s | **状态**: %s | **阶段进度**: %d/%d\n", chain.Protocol, chain.Status, passed, len(chain.Phases)))
+	sb.WriteString(fmt.Sprintf("- **生成时间**: %s\n\n", time.Now().Format("2006-01-02 15:04")))
+
+	// 1. 变更统计
+	base := firstGitBaseline(chain)
+	sb.WriteString("## 变更统计\n\n")
+	stats, statErr := gitDiffStats(sm.ProjectRoot, base)
+	switch {
+	case statErr != nil:
+		sb.WriteString(fmt.Sprintf("_无法读取 git 变更: %v_\n\n", statErr))
+	case len(stats) == 0:
+		sb.WriteString("_无文件变更_\n\n")
+	default:
+		if base == nil || base.Head == "" {
+			sb.WriteString("_任务链未记录 git 基线，以下为相对 HEAD 的未提交改动_\n\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("_基线提交 `%s`_\n\n", base.Head[:min(len(base.Head), 10)]))
+		}
+		added, deleted := 0, 0
+		sb.WriteString("| 文件 | + | - |\n|------|---|---|\n")
+		for i, st := range stats {
+			added += st.Added
+			deleted += st.Deleted
+			if i >= 50 {
+				continue
+			}
+			if st.Binary {
+				sb.WriteString(fmt.Sprintf("| `%s` | bin | bin |\n", st.Path))
+			} else {
+				sb.WriteString(fmt.Sprintf("| `%s` | %d | %d |\n", st.Path, st.Added, st.Deleted))
+			}
+		}
+		if len(stats) > 50 {
+			sb.WriteString(fmt.Sprintf("| ... 另有 %d 个文件 | | |\n", len(stats)-50))
+		}
+		sb.WriteString(fmt.Sprintf("\n共 %d 个文件，+%d / -%d 行\n\n", len(stats), added, deleted))
+	}
+
+	// 2. 阶段摘要
+	sb.WriteString("## 阶段摘要\n\n")
+	var summaries []string
+	for _, p := range chain.Phases {
+		sb.WriteString(fmt.Sprintf("### %s %s (%s)\n\n", phaseStatusIcon(p.Status), fallback(p.Name, p.ID), p.Type))
+		if p.Summary != "" {
+			sb.WriteString(truncateRunes(p.Summary, 800) + "\n\n")
+			summaries = append(summaries, p.Summary)
+		}
+		for _, sub := range p.SubTasks {
+			line := fmt.Sprintf("- [%s] %s", sub.Status, fallback(sub.Name, sub.ID))
+			if sub.Summary != "" {
+				line += ": " + truncateRunes(sub.Summary, 300)
+				summaries = append(summaries, sub.Summary)
+			}
+			sb.WriteString(line + "\n")
+		}
+		if len(p.SubTasks) > 0 {
+			sb.WriteString("\n")
+		}
+	}
+
+	// 3. 影响分析
+	sb.WriteString("## 影响分析\n\n")
+	var impacts []string
+	if sm.Memory != nil {
+		if events, err := sm.Memory.QueryTaskChainEvents(ctx, chain.TaskID, 1000); err == nil {
+			for _, evt := range events {
+				if evt.EventType == "impact" {
+					impacts = append(impacts, fmt.Sprintf("- %s `%s` (阶段 %s)", evt.CreatedAt, evt.Payload, fallback(evt.PhaseID, "-")))
+				}
+			}
+		}
+	}
+	if len(impacts) == 0 {
+		sb.WriteString("_链内未执行 code_impact（调用时传入 task_id 可计入本报告）_\n")
+	} else {
+		sb.WriteString(strings.Join(impacts, "\n") + "\n")
+	}
+	if chain.RiskBudget != nil {
+		sb.WriteString("\n" + renderRiskBudget(chain.RiskBudget))
+	}
+	sb.WriteString("\n")
+
+	// 4. 未解决风险
+	var risks []string
+	for _, p := range chain.Phases {
+		if p.Status == PhaseFailed {
+			risks = append(risks, fmt.Sprintf("门控 %s 未通过", fallback(p.Name, p.ID)))
+		} else if p.RetryCount > 0 {
+			risks = append(risks, fmt.Sprintf("门控 %s 曾失败 %d 次后通过", fallback(p.Name, p.ID), p.RetryCount))
+		}
+		if p.Status == PhaseSkipped {
+			risks = append(risks, fmt.Sprintf("阶段 %s 被跳过", fallback(p.Name, p.ID)))
+		}
+	}
+	if b := chain.RiskBudget; b.Exceeded() {
+		if b.Acknowledged {
+			risks = append(risks, fmt.Sprintf("风险预算超出，已由用户确认: %s", fallback(b.AckNote, "-")))
+		} else {
+			risks = append(risks, "风险预算超出且未确认")
+		}
+	}
+	if statErr == nil && len(summaries) > 0 {
+		if v, err := verifySummaryAgainstGit(sm.ProjectRoot, base, strings.Join(summaries, "\n")); err == nil {
+			for _, f := range v.ChangedUnmentioned {
+				risks = append(risks, fmt.Sprintf("`%s` 有改动但阶段摘要未提及", f))
+			}
+			for _, f := range v.ClaimedUnchanged {
+				risks = append(risks, fmt.Sprintf("摘要声称修改 `%s`，但未检测到变更", f))
+			}
+		}
+	}
+	if sm.Memory != nil {
+		if hooks, err := sm.Memory.ListHooks(ctx, "open"); err == nil {
+			for _, h := range hooks {
+				if h.RelatedTaskID == chain.TaskID {
+					risks = append(risks, fmt.Sprintf("未关闭钩子 %s [%s] %s", h.HookID, h.Priority, h.Description))
+				}
+			}
+		}
+	}
+	sb.WriteString("## 未解决风险\n\n")
+	if len(risks) == 0 {
+		sb.WriteString("_无_\n")
+	}
+	for _, r := range risks {
+		sb.WriteString("- ⚠️ " + r + "\n")
+	}
+
+	// 5. 建议重点审阅
+	if focus := changedFileRisks(sm, ai, stats); len(focus) > 0 {
+		sb.WriteString("\n## 建议重点审阅（变更文件中的高复杂度符号）\n\n")
+		for _, f := range focus {
+			sb.WriteString("- " + f + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func phaseStatusIcon(s PhaseStatus) string {
+	switch s {
+	case PhasePassed:
+		return "✅"
+	case PhaseActive:
+		return "▶️"
+	case PhaseFailed:
+		return "❌"
+	case PhaseSkipped:
+		return "⏭"
+	}
+	return "⏳"
+}
+
+func wrapRequestReview(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args RequestReviewArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		chain, err := getOrLoadV3Chain(ctx, sm, strings.TrimSpace(args.TaskID))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("读取任务链失败: %v", err)), nil
+		}
+
+		doc := buildReviewDocument(ctx, sm, ai, chain)
+		outputPath := args.Output
+		if outputPath == "" {
+			outputPath = core.DataPath(sm.ProjectRoot, "reviews", safeFileName(chain.TaskID)+".md")
+		}
+		if !filepath.IsAbs(outputPath) {
+			outputPath = filepath.Join(sm.ProjectRoot, outputPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("创建输出目录失败: %v", err)), nil
+		}
+		if err := os.WriteFile(outputPath, []byte(doc), 0644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("写入评审文档失败: %v", err)), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("📦 评审文档已生成: %s\n", filepath.ToSlash(outputPath)))
+		if pr := strings.TrimSpace(args.PostPR); pr != "" {
+			res, err := core.NewCommandRunner(sm.ProjectRoot).Run(ctx, core.CommandRequest{
+				Args:   []string{"gh", "pr", "comment", pr, "--body-file", outputPath},
+				Source: "request_review:" + chain.TaskID,
+			})
+			switch {
+			case errors.Is(err, core.ErrCommandDenied):
+				sb.WriteString(fmt.Sprintf("⚠️ 未发布 PR 评论: %v\n   → 在 settings.json commands.allow 中加入 \"gh pr comment\" 后重试\n", err))
+			case err != nil:
+				sb.WriteString(fmt.Sprintf("⚠️ 发布 PR 评论失败: %v\n", err))
+			case res.ExitCode != 0:
+				sb.WriteString(fmt.Sprintf("⚠️ gh 退出码 %d: %s\n", res.ExitCode, truncateRunes(strings.TrimSpace(res.Output), 300)))
+			default:
+				sb.WriteString(fmt.Sprintf("💬 已发布到 PR %s: %s\n", pr, strings.TrimSpace(res.Output)))
+			}
+		}
+		sb.WriteString("\n---\n\n")
+		sb.WriteString(doc)
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
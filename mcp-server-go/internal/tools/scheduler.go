@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+// ========== 内置调度器 (settings.json scheduler 段) ==========
+
+const schedulerTick = time.Minute
+
+// hookSweepEvery 内置过期钩子清扫间隔（不依赖 scheduler.enabled）
+const hookSweepEvery = 5 * time.Minute
+
+// defaultScheduledJobs scheduler.enabled=true 且未声明 jobs 时使用
+var defaultScheduledJobs = []core.ScheduledJob{
+	{Name: "hook-expiry", Kind: "hook_expiry", Every: "1h"},
+	{Name: "index-freshness", Kind: "index_freshness", Every: "30m"},
+	{Name: "nightly-digest", Kind: "digest", Every: "24h"},
+	{Name: "retention-gc", Kind: "retention_gc", Every: "24h"},
+	{Name: "fact-suggest", Kind: "fact_suggest", Every: "24h"},
+	{Name: "db-backup", Kind: "db_backup", Every: "24h"},
+}
+
+// SchedulerJobStatus 定时任务的最近执行状态
+type SchedulerJobStatus struct {
+	Name       string        `json:"name"`
+	Kind       string        `json:"kind"`
+	Every      string        `json:"every"`
+	Runs       int           `json:"runs"`
+	LastRun    time.Time     `json:"last_run"`
+	LastResult string        `json:"last_result"`
+	LastError  string        `json:"last_error,omitempty"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+// Scheduler 周期性维护任务调度器；每分钟重新读取配置，项目晚绑定或修改配置后无需重启
+type Scheduler struct {
+	sm      *SessionManager
+	ai      *services.ASTIndexer
+	mu      sync.Mutex
+	status  map[string]*SchedulerJobStatus
+	started time.Time
+	now     func() time.Time // 时钟，测试时注入
+
+	lastHookSweep time.Time
+}
+
+// StartScheduler 启动调度器后台循环，ctx 取消时退出
+func StartScheduler(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer) *Scheduler {
+	s := &Scheduler{
+		sm:      sm,
+		ai:      ai,
+		status:  make(map[string]*SchedulerJobStatus),
+		started: time.Now(),
+		now:     time.Now,
+	}
+	go func() {
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runDue(ctx)
+			}
+		}
+	}()
+	return s
+}
+
+// Status 返回各任务最近执行状态（按名称排序）
+func (s *Scheduler) Status() []SchedulerJobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]SchedulerJobStatus, 0, len(s.status))
+	for _, st := range s.status {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// runDue 执行到期的任务；项目绑定每轮只读取一次，initialize_project 切换项目不影响本轮
+func (s *Scheduler) runDue(ctx context.Context) {
+	root, mem := s.sm.Binding()
+	if root == "" || mem == nil {
+		return
+	}
+	now := s.now()
+	if now.Sub(s.lastHookSweep) >= hookSweepEvery {
+		s.lastHookSweep = now
+		if _, err := sweepExpiredHooks(ctx, mem); err != nil {
+			fmt.Fprintf(os.Stderr, "[Scheduler][WARN] 过期钩子清扫失败: %v\n", err)
+		}
+	}
+	settings := core.LoadProjectSettings(root).Scheduler
+	if !settings.Enabled {
+		return
+	}
+	jobs := settings.Jobs
+	if len(jobs) == 0 {
+		jobs = defaultScheduledJobs
+	}
+
+	for _, job := range jobs {
+		every, err := time.ParseDuration(job.Every)
+		if err != nil || every < schedulerTick {
+			every = schedulerTick
+		}
+		name := fallback(job.Name, job.Kind)
+
+		s.mu.Lock()
+		st, ok := s.status[name]
+		if !ok {
+			st = &SchedulerJobStatus{Name: name, Kind: job.Kind, Every: every.String()}
+			s.status[name] = st
+		}
+		due := st.LastRun.IsZero() || now.Sub(st.LastRun) >= every
+		s.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		started := s.now()
+		result, err := s.runJob(ctx, root, mem, job.Kind, settings)
+
+		s.mu.Lock()
+		st.Runs++
+		st.LastRun = started
+		st.Elapsed = s.now().Sub(started)
+		st.LastResult = result
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+			fmt.Fprintf(os.Stderr, "[Scheduler][WARN] %s 执行失败: %v\n", name, err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, root string, mem *core.MemoryLayer, kind string, settings core.SchedulerSettings) (string, error) {
+	switch kind {
+	case "hook_expiry":
+		return sweepExpiredHooks(ctx, mem)
+	case "index_freshness":
+		res, err := s.ai.EnsureFreshIndex(root)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("索引状态 %s (%d 个文件)", res.Status, res.TotalFiles), nil
+	case "digest":
+		return writeDailyDigest(ctx, root, mem)
+	case "retention_gc":
+		return runRetentionGC(ctx, mem, settings.RetentionDays)
+	case "fact_suggest":
+		if !core.FeatureEnabled(root, "fact_suggest") {
+			return "功能 fact_suggest 未启用，跳过", nil
+		}
+		added, err := mem.RefreshFactSuggestions(ctx, core.DefaultSuggestMinOccurrences)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("新增 %d 条候选事实", added), nil
+	case "db_backup":
+		info, err := mem.CreateBackup(ctx, core.BackupScheduled)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已备份 %s (%s)", info.Name, humanBytes(info.Size)), nil
+	default:
+		return "", fmt.Errorf("未知任务类型: %s", kind)
+	}
+}
+
+// sweepExpiredHooks 把已到期的 open 钩子转为 expired
+func sweepExpiredHooks(ctx context.Context, mem *core.MemoryLayer) (string, error) {
+	expired, err := mem.ExpireHooks(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d 个钩子转为 expired", len(expired)), nil
+}
+
+// writeDailyDigest 生成过去 24 小时的摘要到 数据目录 digests/
+func writeDailyDigest(ctx context.Context, root string, mem *core.MemoryLayer) (string, error) {
+	now := time.Now()
+	within := core.TimeRange{Since: now.Add(-24 * time.Hour)}
+	memos, err := mem.SearchMemosInRange(ctx, "", "", within, 200)
+	if err != nil {
+		return "", err
+	}
+	hooks, _ := mem.ListHooks(ctx, "open")
+	chains, _ := mem.ListTaskChains(ctx, "running", 50)
+	annotations, _ := mem.ListTimelineAnnotations(ctx, "", within, 0)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 每日摘要 %s\n\n", now.Format("2006-01-02")))
+	if len(annotations) > 0 {
+		sb.WriteString(fmt.Sprintf("## 📍 人工标注 (%d)\n\n", len(annotations)))
+		for _, a := range annotations {
+			sb.WriteString(formatAnnotation(a))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("## 📝 近 24 小时变更 (%d)\n\n", len(memos)))
+	for _, m := range memos {
+		sb.WriteString(fmt.Sprintf(formatMemo, m.ID, m.Timestamp.Format("2006-01-02 15:04"), m.Category, m.Act, truncateRunes(m.Content, 120)))
+	}
+	sb.WriteString(fmt.Sprintf("\n## 🪝 未完成钩子 (%d)\n\n", len(hooks)))
+	for _, h := range hooks {
+		sb.WriteString(fmt.Sprintf("- %s [%s] %s\n", h.Summary, h.Priority, h.Description))
+	}
+	sb.WriteString(fmt.Sprintf("\n## ⛓ 进行中任务链 (%d)\n\n", len(chains)))
+	for _, c := range chains {
+		sb.WriteString(fmt.Sprintf("- %s (%s) 当前阶段 %s\n", c.TaskID, c.Protocol, fallback(c.CurrentPhase, "-")))
+	}
+
+	dir := core.DataPath(root, "digests")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("digest-%s.md", now.Format("2006-01-02")))
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d memos / %d hooks / %d chains → %s", len(memos), len(hooks), len(chains), filepath.ToSlash(path)), nil
+}
+
+// runRetentionGC 清理过期文件锁，并删除超过保留期的已完成任务链（含事件）
+func runRetentionGC(ctx context.Context, mem *core.MemoryLayer, retentionDays int) (string, error) {
+	locks, err := mem.ListFileLocks(ctx) // 读取时会顺带删除过期锁
+	if err != nil {
+		return "", err
+	}
+	chains, err := mem.ListTaskChains(ctx, "finished", 1000)
+	if err != nil {
+		return "", err
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	deleted := 0
+	for _, c := range chains {
+		updated, err := time.Parse(time.RFC3339, c.UpdatedAt)
+		if err != nil || updated.After(cutoff) {
+			continue
+		}
+		if err := mem.DeleteTaskChain(ctx, c.TaskID); err == nil {
+			deleted++
+		}
+	}
+	return fmt.Sprintf("删除 %d 条超过 %d 天的已完成任务链，有效文件锁 %d 个", deleted, retentionDays, len(locks)), nil
+}
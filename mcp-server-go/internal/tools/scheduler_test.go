@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestSchedulerRunDue(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := core.NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+	saveJobs := func(jobs ...core.ScheduledJob) {
+		settings := core.DefaultProjectSettings()
+		settings.Scheduler.Enabled = true
+		settings.Scheduler.Jobs = jobs
+		if err := core.SaveProjectSettings(root, settings); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveJobs(
+		core.ScheduledJob{Name: "hooks", Kind: "hook_expiry", Every: "10m"},
+		core.ScheduledJob{Name: "fast", Kind: "hook_expiry", Every: "5s"}, // 低于 tick 的间隔按 1 分钟
+		core.ScheduledJob{Name: "broken", Kind: "nope", Every: "1h"},
+	)
+
+	clock := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	sm := &SessionManager{}
+	s := &Scheduler{sm: sm, status: map[string]*SchedulerJobStatus{}, now: func() time.Time { return clock }}
+	runs := func() map[string]int {
+		got := map[string]int{}
+		for _, st := range s.Status() {
+			got[st.Name] = st.Runs
+		}
+		return got
+	}
+	expect := func(step string, want map[string]int) {
+		t.Helper()
+		got := runs()
+		for name, n := range want {
+			if got[name] != n {
+				t.Errorf("%s: %s runs = %d, want %d (all: %v)", step, name, got[name], n, got)
+			}
+		}
+	}
+
+	s.runDue(ctx)
+	if len(s.Status()) != 0 {
+		t.Fatal("unbound session should not run jobs")
+	}
+
+	sm.Bind(root, mem)
+	s.runDue(ctx)
+	expect("first tick", map[string]int{"hooks": 1, "fast": 1, "broken": 1})
+
+	clock = clock.Add(time.Minute)
+	s.runDue(ctx)
+	expect("after 1m", map[string]int{"hooks": 1, "fast": 2, "broken": 1})
+
+	clock = clock.Add(9 * time.Minute)
+	s.runDue(ctx)
+	expect("after 10m", map[string]int{"hooks": 2, "fast": 3, "broken": 1})
+
+	for _, st := range s.Status() {
+		switch st.Name {
+		case "hooks":
+			if !st.LastRun.Equal(clock) || st.Every != "10m0s" || st.LastError != "" {
+				t.Errorf("hooks status not persisted: %+v", st)
+			}
+		case "fast":
+			if st.Every != "1m0s" {
+				t.Errorf("interval below the tick should be clamped: %+v", st)
+			}
+		case "broken":
+			if st.LastError == "" || !st.LastRun.Equal(clock.Add(-10*time.Minute)) {
+				t.Errorf("failed job should record the error and its run time: %+v", st)
+			}
+		}
+	}
+
+	// 修改配置后下一轮按新间隔判断，已有的最近执行时间保留
+	saveJobs(core.ScheduledJob{Name: "hooks", Kind: "hook_expiry", Every: "30m"})
+	clock = clock.Add(20 * time.Minute)
+	s.runDue(ctx)
+	expect("after 20m with 30m interval", map[string]int{"hooks": 2})
+	clock = clock.Add(10 * time.Minute)
+	s.runDue(ctx)
+	expect("after 30m with 30m interval", map[string]int{"hooks": 3})
+}
+
+func TestSchedulerBindingConcurrentWithTick(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := core.NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+
+	sm := &SessionManager{}
+	s := &Scheduler{sm: sm, status: map[string]*SchedulerJobStatus{}, now: time.Now}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			sm.Bind(root, mem)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			s.runDue(ctx)
+		}
+	}()
+	wg.Wait()
+}
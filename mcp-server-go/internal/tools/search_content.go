@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== code_search(search_type=content)：全文/正则搜索 ==========
+// 跳过符号索引直接走 RipgrepEngine（rg 不可用时原生遍历兜底），支持正则、include/exclude glob、
+// 语言过滤与上下文行；每处匹配仍通过 GetSymbolAtLine 反查所属符号，免得退回裸 grep 再逐个打开文件。
+
+const (
+	contentSearchDefaultLimit = 50
+	contentSearchMaxContext   = 10
+	// contentSearchMaxOwners 反查所属符号的匹配数上限（每次反查都会调用一次索引引擎）
+	contentSearchMaxOwners = 20
+)
+
+func handleContentSearch(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, args SearchArgs) *mcp.CallToolResult {
+	searchRoot := sm.ProjectRoot
+	if args.Scope != "" {
+		searchRoot = filepath.Join(sm.ProjectRoot, args.Scope)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = contentSearchDefaultLimit
+	}
+	contextLines := min(max(args.ContextLines, 0), contentSearchMaxContext)
+
+	matches, err := services.NewRipgrepEngine().Search(ctx, services.SearchOptions{
+		Query:          args.Query,
+		RootPath:       searchRoot,
+		IsRegex:        args.Regex,
+		CaseSensitive:  args.CaseSensitive,
+		IncludePattern: args.Include,
+		IgnorePattern:  args.Exclude,
+		Languages:      args.Languages,
+		ContextLines:   contextLines,
+		MaxCount:       limit, // 单文件上限，总数在下面截断
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("内容搜索失败: %v", err))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 「%s」的内容搜索结果%s\n\n", args.Query, describeContentFilters(args)))
+	if len(matches) == 0 {
+		sb.WriteString("⚠️ 未找到匹配 → 检查正则转义、放宽 include/languages 过滤，或去掉 case_sensitive\n")
+		return mcp.NewToolResultText(sb.String())
+	}
+	total := len(matches)
+	if total > limit {
+		matches = matches[:limit]
+	}
+
+	// 按文件分组，保持 rg 输出顺序
+	var order []string
+	grouped := make(map[string][]services.TextMatch)
+	for _, m := range matches {
+		if _, ok := grouped[m.FilePath]; !ok {
+			order = append(order, m.FilePath)
+		}
+		grouped[m.FilePath] = append(grouped[m.FilePath], m)
+	}
+
+	owners := 0
+	for _, path := range order {
+		display := path
+		if rel, err := filepath.Rel(sm.ProjectRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = filepath.ToSlash(rel)
+		}
+		sb.WriteString(fmt.Sprintf("📄 **%s**\n", display))
+		for _, m := range grouped[path] {
+			owner := ""
+			if owners < contentSearchMaxOwners {
+				owners++
+				if node, _ := ai.GetSymbolAtLine(sm.ProjectRoot, path, m.LineNumber); node != nil {
+					owner = fmt.Sprintf(" in `%s` (%s)", node.Name, node.NodeType)
+				}
+			}
+			sb.WriteString(fmt.Sprintf("  L%d: `%s`%s\n", m.LineNumber, truncateRunes(strings.TrimSpace(m.Content), 160), owner))
+			writeContextLines(&sb, m.ContextBefore, m.LineNumber, true)
+			writeContextLines(&sb, m.ContextAfter, m.LineNumber, false)
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n共 %d 处匹配，分布在 %d 个文件", total, len(order)))
+	if total > limit {
+		sb.WriteString(fmt.Sprintf("（仅显示前 %d 处，可用 limit 调大或加 include/scope 收窄）", limit))
+	}
+	if owners >= contentSearchMaxOwners && len(matches) > contentSearchMaxOwners {
+		sb.WriteString(fmt.Sprintf("；前 %d 处已标注所属符号", contentSearchMaxOwners))
+	}
+	sb.WriteString("\n")
+	return mcp.NewToolResultText(sb.String())
+}
+
+// writeContextLines 输出上下文行，行号由匹配行推算
+func writeContextLines(sb *strings.Builder, block string, matchLine int, before bool) {
+	if block == "" {
+		return
+	}
+	lines := strings.Split(block, "\n")
+	start := matchLine + 1
+	if before {
+		start = matchLine - len(lines)
+	}
+	for i, l := range lines {
+		sb.WriteString(fmt.Sprintf("    %d│ %s\n", start+i, truncateRunes(l, 160)))
+	}
+}
+
+func describeContentFilters(args SearchArgs) string {
+	var parts []string
+	if args.Regex {
+		parts = append(parts, "regex")
+	}
+	if args.CaseSensitive {
+		parts = append(parts, "区分大小写")
+	}
+	if args.Scope != "" {
+		parts = append(parts, "scope="+args.Scope)
+	}
+	if len(args.Languages) > 0 {
+		parts = append(parts, "languages="+strings.Join(args.Languages, ","))
+	}
+	if len(args.Include) > 0 {
+		parts = append(parts, "include="+strings.Join(args.Include, ","))
+	}
+	if len(args.Exclude) > 0 {
+		parts = append(parts, "exclude="+strings.Join(args.Exclude, ","))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
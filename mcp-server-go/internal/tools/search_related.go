@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ========== code_search show_related：符号关联的记忆 ==========
+
+const relatedItemLimit = 5
+
+// renderRelatedMemory 汇总提及该符号的 memos / facts / 未完成钩子 / 任务链
+// filePath 非空时，路径命中该文件的 memo 也计入
+func renderRelatedMemory(ctx context.Context, sm *SessionManager, symbol, filePath string) string {
+	if sm.Memory == nil || strings.TrimSpace(symbol) == "" {
+		return ""
+	}
+	needle := strings.ToLower(strings.TrimSpace(symbol))
+	mentions := func(texts ...string) bool {
+		for _, t := range texts {
+			if strings.Contains(strings.ToLower(t), needle) {
+				return true
+			}
+		}
+		return false
+	}
+	filePath = strings.ReplaceAll(filePath, "\\", "/")
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 🧠 关联记忆「%s」\n\n", symbol))
+	total := 0
+
+	if memos, err := sm.Memory.SearchMemos(ctx, symbol, "", 50); err == nil {
+		var lines []string
+		for _, m := range memos {
+			samePath := filePath != "" && m.Path != "" && strings.HasSuffix(filePath, strings.ReplaceAll(m.Path, "\\", "/"))
+			if !samePath && !mentions(m.Content, m.Entity) {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf(formatMemo, m.ID, m.Timestamp.Format("2006-01-02 15:04"), m.Category, m.Act, truncateRunes(m.Content, 120)))
+		}
+		total += writeRelatedSection(&sb, "📝 Memos", lines)
+	}
+
+	if facts, err := sm.Memory.QueryFacts(ctx, symbol, 50); err == nil {
+		var lines []string
+		for _, f := range facts {
+			if !mentions(f.Summarize) {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf(formatFact, f.Type, f.Summarize, f.ID, f.CreatedAt.Format("2006-01-02")))
+		}
+		total += writeRelatedSection(&sb, "📌 Known Facts", lines)
+	}
+
+	if hooks, err := sm.Memory.ListHooks(ctx, "open"); err == nil {
+		var lines []string
+		for _, h := range hooks {
+			if !mentions(h.Description, h.Summary) {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("- %s [%s] %s\n", h.HookID, h.Priority, truncateRunes(h.Description, 120)))
+		}
+		total += writeRelatedSection(&sb, "🪝 未完成钩子", lines)
+	}
+
+	if chains, err := sm.Memory.ListTaskChains(ctx, "", 200); err == nil {
+		var lines []string
+		for _, c := range chains {
+			if !mentions(c.Description, c.PhasesJSON) {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("- %s (%s, %s) %s\n", c.TaskID, c.Protocol, c.Status, truncateRunes(c.Description, 80)))
+		}
+		total += writeRelatedSection(&sb, "⛓ 涉及的任务链", lines)
+	}
+
+	if total == 0 {
+		sb.WriteString("（无关联记忆）\n")
+	}
+	return sb.String()
+}
+
+func writeRelatedSection(sb *strings.Builder, title string, lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	sb.WriteString(fmt.Sprintf("**%s** (%d)\n", title, len(lines)))
+	for i, l := range lines {
+		if i >= relatedItemLimit {
+			sb.WriteString(fmt.Sprintf("- ... 另有 %d 条\n", len(lines)-i))
+			break
+		}
+		sb.WriteString(l)
+	}
+	sb.WriteString("\n")
+	return len(lines)
+}
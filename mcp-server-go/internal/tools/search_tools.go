@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"mcp-server-go/internal/core"
 	"mcp-server-go/internal/services"
 	"path/filepath"
 	"strings"
@@ -13,9 +14,20 @@ import (
 
 // SearchArgs 搜索参数
 type SearchArgs struct {
-	Query      string `json:"query" jsonschema:"required,description=搜索关键词"`
-	Scope      string `json:"scope" jsonschema:"description=限定范围"`
-	SearchType string `json:"search_type" jsonschema:"default=any,enum=any,enum=function,enum=class,description=符号类型过滤"`
+	Query       string `json:"query" jsonschema:"required,description=搜索关键词"`
+	Scope       string `json:"scope" jsonschema:"description=限定范围"`
+	SubProject  string `json:"sub_project" jsonschema:"description=限定到工作区子项目 (按名称)；同时给 scope 时 scope 相对子项目目录"`
+	SearchType  string `json:"search_type" jsonschema:"default=any,enum=any,enum=function,enum=class,enum=content,description=符号类型过滤；content 为全文/正则搜索"`
+	ShowRelated bool   `json:"show_related" jsonschema:"description=附带关联记忆：提及该符号的 memos/facts/未完成钩子/任务链"`
+
+	// 以下参数用于文本搜索（content 模式，以及符号未命中时的文本兜底）
+	Regex         bool     `json:"regex" jsonschema:"description=query 按正则解析（隐含 search_type=content）"`
+	CaseSensitive bool     `json:"case_sensitive" jsonschema:"description=区分大小写 (content 模式)"`
+	Include       []string `json:"include" jsonschema:"description=只搜索匹配这些 glob 的文件，如 *.go、internal/**"`
+	Exclude       []string `json:"exclude" jsonschema:"description=排除匹配这些 glob 的文件或目录，如 *_test.go"`
+	Languages     []string `json:"languages" jsonschema:"description=语言过滤，如 go、python、typescript"`
+	ContextLines  int      `json:"context_lines" jsonschema:"description=匹配行前后的上下文行数 (content 模式，最多 10)"`
+	Limit         int      `json:"limit" jsonschema:"description=content 模式最多返回的匹配数，默认 50"`
 }
 
 // RegisterSearchTools 注册搜索工具
@@ -35,10 +47,23 @@ func RegisterSearchTools(s *server.MCPServer, sm *SessionManager, ai *services.A
   scope (可选)
     知道大概在哪个目录？填进来（如 "internal/core"），能大幅提高准确率。
   
+  sub_project (可选)
+    monorepo 子项目名（initialize_project discover=true 探测得到），只在该子项目内搜索。
+  
   search_type (可选)
     - 找函数实现？ -> "function"
     - 找数据结构？ -> "class"
     - 只要是代码？ -> "any" (默认)
+    - 找字符串/注释/调用写法？ -> "content"：跳过符号索引直接全文搜索，每处匹配标注所属符号
+
+  content 模式参数 (可选)
+    regex=true 按正则搜索（隐含 content）；case_sensitive 区分大小写；
+    include / exclude 文件 glob（如 "*.go"、"*_test.go"）；languages 语言过滤（如 ["go"]）；
+    context_lines 上下文行数；limit 最多返回的匹配数（默认 50）
+
+  show_related (可选)
+    准备修改该函数？设为 true，一并返回提及它的 memos、facts、未完成钩子与任务链，
+    编辑前一次调用即可掌握历史背景。
 
 返回：
   告诉代码符号定义所在的精确文件路径和行号。
@@ -59,6 +84,11 @@ func wrapSearch(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 		if err := request.BindArguments(&args); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("参数格式错误: %v", err)), nil
 		}
+		scope, err := services.SubProjectScope(sm.ProjectRoot, args.SubProject, args.Scope)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		args.Scope = scope
 
 		// 优先按范围补录（热点目录），否则按新鲜度检查全量索引
 		if strings.TrimSpace(args.Scope) != "" {
@@ -67,6 +97,10 @@ func wrapSearch(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 			_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
 		}
 
+		if args.SearchType == "content" || args.Regex {
+			return handleContentSearch(ctx, sm, ai, args), nil
+		}
+
 		// 1. AST Search (Core Strategy)
 		astResult, err := ai.SearchSymbolWithScope(sm.ProjectRoot, args.Query, args.Scope)
 		if err != nil {
@@ -159,12 +193,15 @@ func wrapSearch(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 			}
 
 			matches, err := rg.Search(ctx, services.SearchOptions{
-				Query:         args.Query,
-				RootPath:      searchRoot,
-				CaseSensitive: false, // 默认不区分大小写
-				WordMatch:     false,
-				MaxCount:      20, // 限制数量以防爆炸
-				ContextLines:  0,
+				Query:          args.Query,
+				RootPath:       searchRoot,
+				CaseSensitive:  false, // 默认不区分大小写
+				WordMatch:      false,
+				MaxCount:       20, // 限制数量以防爆炸
+				ContextLines:   0,
+				IncludePattern: args.Include,
+				IgnorePattern:  args.Exclude,
+				Languages:      args.Languages,
 			})
 
 			if err == nil && len(matches) > 0 {
@@ -219,10 +256,36 @@ func wrapSearch(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerF
 			} else {
 				if len(matches) == 0 && (astResult == nil || (astResult.FoundSymbol == nil && len(astResult.Candidates) == 0)) {
 					sb.WriteString(fmt.Sprintf("⚠️ **未找到「%s」** → 换词重试（同义词/缩写/驼峰变体），或用 `project_map` 先看结构\n", args.Query))
+					if core.FeatureEnabled(sm.ProjectRoot, "scope_suggestions") {
+						sb.WriteString(renderScopeSuggestions(services.SuggestScopes(sm.ProjectRoot, args.Query, args.Scope, 5)))
+					}
 				}
 			}
 		}
 
+		if args.ShowRelated {
+			filePath := ""
+			if astResult != nil && astResult.FoundSymbol != nil {
+				filePath = astResult.FoundSymbol.FilePath
+			}
+			sb.WriteString("\n")
+			sb.WriteString(renderRelatedMemory(ctx, sm, args.Query, filePath))
+		}
+
 		return mcp.NewToolResultText(sb.String()), nil
 	}
 }
+
+// renderScopeSuggestions 搜索落空时列出文件名与查询词模糊匹配的目录
+func renderScopeSuggestions(suggestions []services.ScopeSuggestion) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n📂 **可能相关的目录**（按文件名模糊匹配，可作为 scope 重试）:\n")
+	for _, s := range suggestions {
+		sb.WriteString(fmt.Sprintf("- `%s` (score: %.1f, 命中: %s) → %s\n",
+			s.Scope, s.Score, strings.Join(s.Matched, ", "), strings.Join(s.Files, ", ")))
+	}
+	return sb.String()
+}
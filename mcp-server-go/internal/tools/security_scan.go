@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// SecurityScanArgs 安全扫描参数
+type SecurityScanArgs struct {
+	Scope           string `json:"scope" jsonschema:"description=只扫描该目录/文件 (留空=整个项目)"`
+	MinSeverity     string `json:"min_severity" jsonschema:"default=medium,enum=high,enum=medium,enum=low,description=最低严重程度"`
+	SkipHooks       bool   `json:"skip_hooks" jsonschema:"description=不为 high 级命中自动创建钩子"`
+	Limit           int    `json:"limit" jsonschema:"default=100,description=最多列出的命中数"`
+	IncludeVendored bool   `json:"include_vendored" jsonschema:"description=包含 vendored/generated 文件 (默认排除)"`
+}
+
+var securitySeverityMark = map[string]string{
+	services.SecuritySeverityHigh:   "🔴 high",
+	services.SecuritySeverityMedium: "🟡 medium",
+	services.SecuritySeverityLow:    "⚪ low",
+}
+
+var securityCategoryLabel = map[string]string{
+	services.SecurityCategorySecret:    "硬编码凭据",
+	services.SecurityCategoryExec:      "命令执行",
+	services.SecurityCategoryTraversal: "路径穿越",
+}
+
+// securityHookDescription 钩子描述，同时作为去重键（不含行号，代码行移动后不会重复创建）
+func securityHookDescription(f services.SecurityFinding) string {
+	return fmt.Sprintf("[security:%s] %s (%s)", f.Rule, f.Message, f.File)
+}
+
+func wrapSecurityScan(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args SecurityScanArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+		minSev := strings.ToLower(strings.TrimSpace(args.MinSeverity))
+		switch minSev {
+		case "":
+			minSev = services.SecuritySeverityMedium
+		case services.SecuritySeverityHigh, services.SecuritySeverityMedium, services.SecuritySeverityLow:
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("未知严重程度: %s（可用: high, medium, low）", args.MinSeverity)), nil
+		}
+		limit := args.Limit
+		if limit <= 0 {
+			limit = 100
+		}
+
+		_, _ = ai.EnsureFreshIndex(sm.ProjectRoot)
+		report, err := ai.ScanSecurity(sm.ProjectRoot, args.Scope, resolvePathTagger(sm, args.IncludeVendored))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("安全扫描失败: %v", err)), nil
+		}
+		var findings []services.SecurityFinding
+		for _, f := range report.Findings {
+			if services.SecuritySeverityAtLeast(f.Severity, minSev) {
+				findings = append(findings, f)
+			}
+		}
+
+		hookLines := ""
+		if !args.SkipHooks && sm.Memory != nil {
+			hookLines = createSecurityHooks(ctx, sm, findings)
+		}
+		return deliverLargeOutput(sm, "security_scan.md", renderSecurityScan(report, findings, args.Scope, minSev, limit)+hookLines), nil
+	}
+}
+
+// createSecurityHooks 为 high 级命中创建钩子，已有同描述钩子（含已关闭）的不再重复创建
+func createSecurityHooks(ctx context.Context, sm *SessionManager, findings []services.SecurityFinding) string {
+	existing := make(map[string]bool)
+	for _, status := range []string{"open", "closed", "expired"} {
+		hooks, err := sm.Memory.ListHooks(ctx, status)
+		if err != nil {
+			return fmt.Sprintf("\n⚠️ 查询 Hook 失败，未自动创建: %v\n", err)
+		}
+		for _, h := range hooks {
+			existing[h.Description] = true
+		}
+	}
+	var sb strings.Builder
+	created := 0
+	for _, f := range findings {
+		desc := securityHookDescription(f)
+		if f.Severity != services.SecuritySeverityHigh || existing[desc] {
+			continue
+		}
+		existing[desc] = true
+		id, err := sm.Memory.CreateHook(ctx, desc, "high", fmt.Sprintf("%s:%d", f.File, f.Line), "", 0)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("- ❌ %s:%d: %v\n", f.File, f.Line, err))
+			continue
+		}
+		created++
+		sb.WriteString(fmt.Sprintf("- 📌 %s %s\n", id, desc))
+	}
+	if created == 0 && sb.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n#### 📌 已为 high 级命中创建 %d 个钩子\n%s", created, sb.String())
+}
+
+func renderSecurityScan(report *services.SecurityReport, findings []services.SecurityFinding, scope, minSev string, limit int) string {
+	var sb strings.Builder
+	sb.WriteString("### 🛡️ 安全扫描\n\n")
+	if scope != "" {
+		sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n", scope))
+	}
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	sb.WriteString(fmt.Sprintf("**📊 统计**: 扫描 %d 个文件，命中 %d 条 (high %d / medium %d / low %d，min_severity=%s)",
+		report.FilesScanned, len(findings), counts[services.SecuritySeverityHigh], counts[services.SecuritySeverityMedium], counts[services.SecuritySeverityLow], minSev))
+	if report.Suppressed > 0 {
+		sb.WriteString(fmt.Sprintf("，nosec 屏蔽 %d 条", report.Suppressed))
+	}
+	sb.WriteString("\n")
+	if len(findings) == 0 {
+		sb.WriteString("\n✅ 未发现达到阈值的风险。\n")
+		return sb.String()
+	}
+
+	for i, f := range findings {
+		if i == limit {
+			sb.WriteString(fmt.Sprintf("\n- ... 另有 %d 条，提高 limit 或缩小 scope 查看\n", len(findings)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("\n- %s [%s] %s:%d — %s (`%s`)\n  `%s`\n",
+			securitySeverityMark[f.Severity], securityCategoryLabel[f.Category], f.File, f.Line, f.Message, f.Rule, f.Content))
+	}
+	sb.WriteString("\n> 规则为启发式匹配，请逐条确认；误报可在该行加 `nosec` 或 `mpm:ignore-security` 注释屏蔽。测试文件中的命中已降一级。\n")
+	return sb.String()
+}
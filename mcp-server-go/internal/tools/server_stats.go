@@ -0,0 +1,326 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ============================================================================
+// server_stats：进程内指标（工具调用次数/耗时分位数、索引耗时、库体积、查询缓存命中率）
+// ============================================================================
+
+// latencySampleSize 每个工具保留的最近耗时样本数（分位数基于这些样本计算）
+const latencySampleSize = 512
+
+// ServerStatsArgs server_stats 参数
+type ServerStatsArgs struct {
+	Format string `json:"format" jsonschema:"enum=table,enum=prometheus,description=输出格式：table (默认，Markdown 表格) / prometheus (文本暴露格式)"`
+	Top    int    `json:"top" jsonschema:"description=表格只列出总耗时最高的前 N 个工具 (默认 20)"`
+	Audit  int    `json:"audit" jsonschema:"description=附带最近 N 条命令执行审计 (auto_verify / embedding / intent 等经 CommandRunner 的命令)，0 = 不显示"`
+	// AuditSource 按来源前缀过滤审计，如 auto_verify、embedding
+	AuditSource string `json:"audit_source" jsonschema:"description=审计来源前缀过滤 (如 auto_verify、embedding、intent)"`
+}
+
+// toolMetric 单个工具的调用统计
+type toolMetric struct {
+	Calls   int64
+	Errors  int64
+	TotalMs float64
+	MaxMs   float64
+	samples []float64 // 环形缓冲
+	next    int
+}
+
+// toolMetricsRegistry 工具调用指标，进程级
+type toolMetricsRegistry struct {
+	mu    sync.Mutex
+	tools map[string]*toolMetric
+}
+
+var toolMetrics = &toolMetricsRegistry{tools: make(map[string]*toolMetric)}
+
+func (r *toolMetricsRegistry) observe(tool string, elapsed time.Duration, failed bool) {
+	ms := float64(elapsed.Microseconds()) / 1000
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.tools[tool]
+	if !ok {
+		m = &toolMetric{}
+		r.tools[tool] = m
+	}
+	m.Calls++
+	if failed {
+		m.Errors++
+	}
+	m.TotalMs += ms
+	if ms > m.MaxMs {
+		m.MaxMs = ms
+	}
+	if len(m.samples) < latencySampleSize {
+		m.samples = append(m.samples, ms)
+	} else {
+		m.samples[m.next] = ms
+		m.next = (m.next + 1) % latencySampleSize
+	}
+}
+
+// toolStat 某个工具的统计快照
+type toolStat struct {
+	Tool          string
+	Calls, Errors int64
+	TotalMs       float64
+	MaxMs         float64
+	P50, P90, P99 float64
+}
+
+// snapshot 按总耗时降序返回各工具统计
+func (r *toolMetricsRegistry) snapshot() []toolStat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]toolStat, 0, len(r.tools))
+	for name, m := range r.tools {
+		sorted := append([]float64(nil), m.samples...)
+		sort.Float64s(sorted)
+		out = append(out, toolStat{
+			Tool: name, Calls: m.Calls, Errors: m.Errors, TotalMs: m.TotalMs, MaxMs: m.MaxMs,
+			P50: percentile(sorted, 0.5), P90: percentile(sorted, 0.9), P99: percentile(sorted, 0.99),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalMs != out[j].TotalMs {
+			return out[i].TotalMs > out[j].TotalMs
+		}
+		return out[i].Tool < out[j].Tool
+	})
+	return out
+}
+
+// percentile 最近秩法，sorted 须已升序
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(q*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// MetricsMiddleware 记录每次工具调用的耗时与成败
+func MetricsMiddleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			started := time.Now()
+			result, err := next(ctx, request)
+			toolMetrics.observe(request.Params.Name, time.Since(started), err != nil || (result != nil && result.IsError))
+			return result, err
+		}
+	}
+}
+
+// dataDBSizes 数据目录下各 SQLite 库（含 WAL 与索引分片）的体积
+func dataDBSizes(projectRoot string) map[string]int64 {
+	sizes := make(map[string]int64)
+	if projectRoot == "" {
+		return sizes
+	}
+	for _, dir := range []string{core.DataDir(projectRoot), core.DataPath(projectRoot, "shards")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !(strings.HasSuffix(name, ".db") || strings.HasSuffix(name, ".db-wal")) {
+				continue
+			}
+			if info, err := e.Info(); err == nil {
+				rel, _ := filepath.Rel(core.DataDir(projectRoot), filepath.Join(dir, name))
+				sizes[filepath.ToSlash(rel)] = info.Size()
+			}
+		}
+	}
+	return sizes
+}
+
+func wrapServerStats(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args ServerStatsArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if args.Top <= 0 {
+			args.Top = 20
+		}
+
+		tools := toolMetrics.snapshot()
+		index := ai.IndexRunStats()
+		cache := ai.QueryCacheStats()
+		sizes := dataDBSizes(sm.ProjectRoot)
+
+		if args.Format == "prometheus" {
+			return mcp.NewToolResultText(renderPrometheusStats(tools, index, cache, sizes)), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString("### 📊 服务统计\n\n")
+		sb.WriteString(fmt.Sprintf("- **运行时长**: %s\n", time.Since(serverStartedAt).Round(time.Second)))
+		var calls int64
+		var totalMs float64
+		for _, t := range tools {
+			calls += t.Calls
+			totalMs += t.TotalMs
+		}
+		sb.WriteString(fmt.Sprintf("- **工具调用**: %d 次，累计 %s\n", calls, (time.Duration(totalMs) * time.Millisecond).Round(time.Millisecond)))
+		if index.Runs > 0 {
+			sb.WriteString(fmt.Sprintf("- **索引构建**: %d 次（失败 %d），最近 %dms / 平均 %dms / 最长 %dms\n",
+				index.Runs, index.Failures, index.LastMs, index.TotalMs/index.Runs, index.MaxMs))
+		} else {
+			sb.WriteString("- **索引构建**: 本进程尚未构建\n")
+		}
+		hitRate := 0.0
+		if lookups := cache.Hits + cache.Misses; lookups > 0 {
+			hitRate = float64(cache.Hits) / float64(lookups) * 100
+		}
+		sb.WriteString(fmt.Sprintf("- **查询缓存**: 命中率 %.1f%% (%d 命中 / %d 未命中，%d/%d 条)\n", hitRate, cache.Hits, cache.Misses, cache.Size, cache.Capacity))
+
+		if len(sizes) > 0 {
+			names := make([]string, 0, len(sizes))
+			for name := range sizes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			parts := make([]string, len(names))
+			for i, name := range names {
+				parts[i] = fmt.Sprintf("%s %s", name, humanBytes(sizes[name]))
+			}
+			sb.WriteString(fmt.Sprintf("- **数据库体积**: %s\n", strings.Join(parts, ", ")))
+		}
+
+		sb.WriteString("\n#### ⏱ 工具耗时（按累计耗时排序）\n\n")
+		if len(tools) == 0 {
+			sb.WriteString("暂无调用记录。\n")
+		} else {
+			sb.WriteString("| 工具 | 调用 | 失败 | p50 | p90 | p99 | 最长 | 累计 |\n")
+			sb.WriteString("|------|------|------|-----|-----|-----|------|------|\n")
+		}
+		for i, t := range tools {
+			if i == args.Top {
+				sb.WriteString(fmt.Sprintf("\n（其余 %d 个工具省略，可调大 top）\n", len(tools)-args.Top))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %d | %d | %.0fms | %.0fms | %.0fms | %.0fms | %.1fs |\n",
+				t.Tool, t.Calls, t.Errors, t.P50, t.P90, t.P99, t.MaxMs, t.TotalMs/1000))
+		}
+
+		if args.Audit > 0 && sm.ProjectRoot != "" {
+			recs, err := core.NewCommandRunner(sm.ProjectRoot).RecentAudits(args.AuditSource, args.Audit)
+			if err != nil {
+				sb.WriteString(fmt.Sprintf("\n> ⚠️ 读取命令审计失败: %v\n", err))
+			} else {
+				sb.WriteString(renderCommandAudits(recs))
+			}
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// renderCommandAudits 命令审计表格（最新在前）
+func renderCommandAudits(recs []core.CommandAuditRecord) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n#### 🛡 命令审计（最近 %d 条）\n\n", len(recs)))
+	if len(recs) == 0 {
+		sb.WriteString("暂无命令执行记录。\n")
+		return sb.String()
+	}
+	sb.WriteString("| 时间 | 来源 | 命令 | 结果 | 退出码 | 耗时 |\n")
+	sb.WriteString("|------|------|------|------|--------|------|\n")
+	for _, r := range recs {
+		result := "✅ 执行"
+		if !r.Allowed {
+			result = "⛔ 拒绝: " + truncateRunes(r.Reason, 80)
+		}
+		cmd := strings.Join(r.Argv, " ")
+		if r.Shell {
+			cmd += " (shell)"
+		}
+		cmd = strings.ReplaceAll(truncateRunes(cmd, 100), "|", "\\|")
+		sb.WriteString(fmt.Sprintf("| %s | %s | `%s` | %s | %d | %dms |\n",
+			reportTime(r.Time), fallback(r.Source, "-"), cmd, result, r.ExitCode, r.DurationMs))
+	}
+	return sb.String()
+}
+
+// renderPrometheusStats Prometheus 文本暴露格式
+func renderPrometheusStats(tools []toolStat, index services.IndexRunStats, cache services.QueryCacheStats, sizes map[string]int64) string {
+	var sb strings.Builder
+	metric := func(name, typ, help string) {
+		sb.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ))
+	}
+
+	metric("mpm_uptime_seconds", "gauge", "Seconds since the server process started.")
+	sb.WriteString(fmt.Sprintf("mpm_uptime_seconds %.0f\n", time.Since(serverStartedAt).Seconds()))
+
+	metric("mpm_tool_calls_total", "counter", "Tool invocations.")
+	for _, t := range tools {
+		sb.WriteString(fmt.Sprintf("mpm_tool_calls_total{tool=%q} %d\n", t.Tool, t.Calls))
+	}
+	metric("mpm_tool_errors_total", "counter", "Tool invocations that returned an error.")
+	for _, t := range tools {
+		sb.WriteString(fmt.Sprintf("mpm_tool_errors_total{tool=%q} %d\n", t.Tool, t.Errors))
+	}
+	metric("mpm_tool_latency_ms", "summary", "Tool latency in milliseconds (quantiles over recent calls).")
+	for _, t := range tools {
+		for _, q := range []struct {
+			label string
+			v     float64
+		}{{"0.5", t.P50}, {"0.9", t.P90}, {"0.99", t.P99}} {
+			sb.WriteString(fmt.Sprintf("mpm_tool_latency_ms{tool=%q,quantile=%q} %.3f\n", t.Tool, q.label, q.v))
+		}
+		sb.WriteString(fmt.Sprintf("mpm_tool_latency_ms_sum{tool=%q} %.3f\n", t.Tool, t.TotalMs))
+		sb.WriteString(fmt.Sprintf("mpm_tool_latency_ms_count{tool=%q} %d\n", t.Tool, t.Calls))
+	}
+
+	metric("mpm_index_runs_total", "counter", "Index builds in this process.")
+	sb.WriteString(fmt.Sprintf("mpm_index_runs_total %d\n", index.Runs))
+	metric("mpm_index_failures_total", "counter", "Failed index builds in this process.")
+	sb.WriteString(fmt.Sprintf("mpm_index_failures_total %d\n", index.Failures))
+	metric("mpm_index_duration_ms_total", "counter", "Total time spent building the index.")
+	sb.WriteString(fmt.Sprintf("mpm_index_duration_ms_total %d\n", index.TotalMs))
+	metric("mpm_index_last_duration_ms", "gauge", "Duration of the most recent index build.")
+	sb.WriteString(fmt.Sprintf("mpm_index_last_duration_ms %d\n", index.LastMs))
+
+	metric("mpm_query_cache_hits_total", "counter", "Engine query cache hits.")
+	sb.WriteString(fmt.Sprintf("mpm_query_cache_hits_total %d\n", cache.Hits))
+	metric("mpm_query_cache_misses_total", "counter", "Engine query cache misses.")
+	sb.WriteString(fmt.Sprintf("mpm_query_cache_misses_total %d\n", cache.Misses))
+	metric("mpm_query_cache_entries", "gauge", "Entries currently held by the engine query cache.")
+	sb.WriteString(fmt.Sprintf("mpm_query_cache_entries %d\n", cache.Size))
+
+	metric("mpm_db_size_bytes", "gauge", "Size of SQLite files in the data directory.")
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("mpm_db_size_bytes{file=%q} %d\n", name, sizes[name]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+func TestToolMetricsRegistry_PercentilesAndPrometheus(t *testing.T) {
+	r := &toolMetricsRegistry{tools: make(map[string]*toolMetric)}
+	for i := 1; i <= 100; i++ {
+		r.observe("code_search", time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+	r.observe("memo", 500*time.Millisecond, false)
+
+	stats := r.snapshot()
+	if len(stats) != 2 || stats[0].Tool != "code_search" {
+		t.Fatalf("expected code_search first (largest total), got %+v", stats)
+	}
+	cs := stats[0]
+	if cs.Calls != 100 || cs.Errors != 10 || cs.P50 != 50 || cs.P90 != 90 || cs.P99 != 99 || cs.MaxMs != 100 {
+		t.Errorf("code_search stats = %+v", cs)
+	}
+
+	// 环形缓冲只保留最近的样本
+	for i := 0; i < latencySampleSize; i++ {
+		r.observe("memo", time.Millisecond, false)
+	}
+	for _, s := range r.snapshot() {
+		if s.Tool == "memo" && (s.P99 != 1 || s.MaxMs != 500) {
+			t.Errorf("memo quantiles should use recent samples only: %+v", s)
+		}
+	}
+
+	out := renderPrometheusStats(stats, services.IndexRunStats{Runs: 2, TotalMs: 300}, services.QueryCacheStats{Hits: 3, Misses: 1}, map[string]int64{"symbols.db": 4096})
+	for _, want := range []string{
+		"# TYPE mpm_tool_calls_total counter",
+		`mpm_tool_calls_total{tool="code_search"} 100`,
+		`mpm_tool_latency_ms{tool="code_search",quantile="0.9"} 90.000`,
+		"mpm_index_runs_total 2",
+		"mpm_query_cache_hits_total 3",
+		`mpm_db_size_bytes{file="symbols.db"} 4096`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("prometheus output missing %q", want)
+		}
+	}
+}
+
+func TestRenderCommandAudits(t *testing.T) {
+	out := renderCommandAudits([]core.CommandAuditRecord{
+		{Time: "2026-01-02T03:04:05Z", Source: "auto_verify:T1", Argv: []string{"go", "test", "./..."}, Allowed: true, ExitCode: 1, DurationMs: 1200},
+		{Time: "2026-01-02T03:04:00Z", Source: "embedding", Argv: []string{"sh", "-c", "a|b"}, Shell: true, Reason: "不在白名单"},
+	})
+	for _, want := range []string{"最近 2 条", "| auto_verify:T1 | `go test ./...` | ✅ 执行 | 1 | 1200ms |", "⛔ 拒绝: 不在白名单", `a\|b (shell)`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("audit table missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(renderCommandAudits(nil), "暂无命令执行记录") {
+		t.Error("empty audit should say so")
+	}
+}
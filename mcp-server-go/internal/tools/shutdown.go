@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"mcp-server-go/internal/services"
+)
+
+// indexBuilds 正在运行的后台索引任务（initialize_project 启动），退出时等待其收尾
+var indexBuilds sync.WaitGroup
+
+// Shutdown 收到 SIGINT/SIGTERM 或 stdin 关闭后的协调退出：
+// 终止索引命令与常驻引擎，等待后台索引写完状态（超时则标记 interrupted），
+// 保存内存中进行中的任务链，并写出尚未落盘的 dev-log
+func Shutdown(sm *SessionManager, ai *services.ASTIndexer, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	ai.Shutdown()
+	stopLiveTimeline()
+
+	done := make(chan struct{})
+	go func() {
+		indexBuilds.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		fmt.Fprintf(os.Stderr, "[Shutdown][WARN] 后台索引未在 %s 内结束\n", timeout)
+	}
+
+	if sm.ProjectRoot != "" {
+		markIndexInterrupted(sm.ProjectRoot)
+	}
+
+	if sm.Memory != nil {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(5*time.Second))
+		defer cancel()
+		for _, chain := range sm.TaskChainsV3 {
+			if chain.Status != "running" {
+				continue
+			}
+			if err := persistV3Chain(ctx, sm, chain, "", "", "", ""); err != nil {
+				fmt.Fprintf(os.Stderr, "[Shutdown][WARN] 保存任务链 %s 失败: %v\n", chain.TaskID, err)
+			}
+		}
+		sm.Memory.FlushDevLog()
+	}
+}
+
+// markIndexInterrupted 状态文件仍停在 running 时改为 interrupted，避免 index_status 永远显示进行中
+func markIndexInterrupted(projectRoot string) {
+	raw, err := os.ReadFile(indexStatusFile(projectRoot))
+	if err != nil {
+		return
+	}
+	var st index_build_status
+	if json.Unmarshal(raw, &st) != nil || st.Status != "running" {
+		return
+	}
+	st.Status = "interrupted"
+	st.FinishedAt = time.Now().Format(time.RFC3339)
+	st.Error = services.ErrIndexInterrupted.Error()
+	writeIndexStatus(projectRoot, st)
+}
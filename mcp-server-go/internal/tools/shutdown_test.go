@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+)
+
+func TestShutdown_MarksRunningIndexInterrupted(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(core.DataDir(root), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeIndexStatus(root, index_build_status{Status: "running", Mode: "auto", StartedAt: time.Now().Format(time.RFC3339)})
+
+	ai := services.NewASTIndexer()
+	Shutdown(&SessionManager{ProjectRoot: root}, ai, time.Second)
+
+	if !ai.Stopping() {
+		t.Error("indexer should report stopping after Shutdown")
+	}
+	raw, err := os.ReadFile(indexStatusFile(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st index_build_status
+	if err := json.Unmarshal(raw, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != "interrupted" || st.FinishedAt == "" || st.Error == "" || st.Mode != "auto" {
+		t.Errorf("status after shutdown = %+v", st)
+	}
+}
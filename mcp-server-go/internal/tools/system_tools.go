@@ -7,9 +7,10 @@ import (
 	"mcp-server-go/internal/core"
 	"mcp-server-go/internal/services"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -35,7 +36,7 @@ type index_build_status struct {
 }
 
 func indexStatusFile(projectRoot string) string {
-	return filepath.Join(projectRoot, ".mcp-data", "index_status.json")
+	return core.DataPath(projectRoot, "index_status.json")
 }
 
 func writeIndexStatus(projectRoot string, st index_build_status) {
@@ -64,7 +65,9 @@ func startAsyncIndexBuild(projectRoot string, ai *services.ASTIndexer, forceFull
 		StartedAt: startedAt.Format(time.RFC3339),
 	})
 
+	indexBuilds.Add(1)
 	go func(root string, started time.Time) {
+		defer indexBuilds.Done()
 		var (
 			result *services.IndexResult
 			err    error
@@ -75,8 +78,12 @@ func startAsyncIndexBuild(projectRoot string, ai *services.ASTIndexer, forceFull
 			result, err = ai.Index(root)
 		}
 		if err != nil {
+			status := "failed"
+			if ai.Stopping() {
+				status = "interrupted"
+			}
 			writeIndexStatus(root, index_build_status{
-				Status:     "failed",
+				Status:     status,
 				Mode:       mode,
 				StartedAt:  started.Format(time.RFC3339),
 				FinishedAt: time.Now().Format(time.RFC3339),
@@ -103,26 +110,63 @@ func startAsyncIndexBuild(projectRoot string, ai *services.ASTIndexer, forceFull
 
 // InitArgs 初始化参数
 type InitArgs struct {
-	ProjectRoot    string `json:"project_root" jsonschema:"description=项目根路径 (绝对路径)"`
-	ForceFullIndex bool   `json:"force_full_index" jsonschema:"description=强制全量索引（禁用大仓库bootstrap策略，默认false）"`
+	ProjectRoot    string   `json:"project_root" jsonschema:"description=项目根路径 (绝对路径)"`
+	ForceFullIndex bool     `json:"force_full_index" jsonschema:"description=强制全量索引（禁用大仓库bootstrap策略，默认false）"`
+	RenderTarget   string   `json:"render_target" jsonschema:"description=本会话的输出渲染目标：markdown 或 plain（纯文本，适配不支持 Markdown 的终端客户端）,enum=markdown,enum=plain"`
+	Discover       bool     `json:"discover" jsonschema:"description=探测嵌套子项目 (go.mod / package.json / Cargo.toml 边界)，适用于 monorepo"`
+	SubProjects    []string `json:"sub_projects" jsonschema:"description=只索引这些子项目 (名称或目录)；[\"*\"] 清除选择、索引整个项目"`
 }
 
 type SessionManager struct {
 	Memory        *core.MemoryLayer
 	ProjectRoot   string
 	TaskChainsV3  map[string]*TaskChainV3   // 协议状态机任务链
-	AnalysisState map[string]*AnalysisState // manager_analyze 两步调用的中间状态
+	AnalysisState map[string]*AnalysisState // manager_analyze 各步调用间保存的分析状态
+	Scheduler     *Scheduler                // 内置调度器（未启动时为 nil）
+	IndexWatcher  *IndexWatcher             // 文件变更增量索引（未启动时为 nil）
+	RenderTarget  string                    // 会话级渲染目标 (markdown/plain)，为空时读取 settings.json output.render
+
+	bindMu         sync.RWMutex                  // 保护 ProjectRoot/Memory 的重新绑定，后台协程经 Binding 读取
+	lastActivity   atomic.Int64                  // 最近一次工具调用时间 (UnixNano)，空闲存档据此判断
+	lastCheckpoint atomic.Int64                  // 已存档的空闲周期（对应的 lastActivity）
+	inFlight       atomic.Int64                  // 正在执行的工具调用数，长耗时调用期间不算空闲
+	callGate       sync.RWMutex                  // 工具调用持读锁；空闲存档以 TryLock 持写锁读取会话状态
+	client         atomic.Pointer[ClientProfile] // initialize 时协商出的客户端能力画像
+}
+
+// Bind 绑定（或切换）项目根目录与记忆层
+func (sm *SessionManager) Bind(projectRoot string, mem *core.MemoryLayer) {
+	sm.bindMu.Lock()
+	defer sm.bindMu.Unlock()
+	sm.ProjectRoot = projectRoot
+	sm.Memory = mem
+}
+
+// Binding 返回当前绑定的项目根目录与记忆层快照；调度器、文件监听、时间线服务等后台协程应只通过它读取
+func (sm *SessionManager) Binding() (string, *core.MemoryLayer) {
+	sm.bindMu.RLock()
+	defer sm.bindMu.RUnlock()
+	return sm.ProjectRoot, sm.Memory
 }
 
-// AnalysisState 第一步分析结果（临时存储）
+// AnalysisState 分析结果（会话内存储，step=3 增量更新时复用）
 type AnalysisState struct {
 	Intent         string                 `json:"intent"`
 	UserDirective  string                 `json:"user_directive"`
 	ContextAnchors []CodeAnchor           `json:"context_anchors"`
-	VerifiedFacts  []string               `json:"verified_facts"`
+	VerifiedFacts  []VerifiedFact         `json:"verified_facts"`
 	Telemetry      map[string]interface{} `json:"telemetry"`
 	Guardrails     Guardrails             `json:"guardrails"`
 	Alerts         []string               `json:"alerts"`
+	Symbols        []string               `json:"symbols,omitempty"`  // 已解析过的符号（含未定位到的）
+	Glossary       []core.GlossaryEntry   `json:"glossary,omitempty"` // 任务描述命中的术语
+	Deferred       []string               `json:"deferred,omitempty"` // 超出解析上限、待 resolve_more 处理的符号
+	Scope          string                 `json:"scope,omitempty"`
+	ReadOnly       bool                   `json:"read_only,omitempty"`
+	Updates        []string               `json:"updates,omitempty"` // 增量更新时补充的任务信息
+	Audit          *GuardrailAudit        `json:"audit,omitempty"`   // 写入审计（analyze.guardrail_audit 开启时）
+	Revision       int                    `json:"revision"`
+	UpdatedAt      time.Time              `json:"-"`
 }
 
 // CodeAnchor 代码锚点
@@ -131,6 +175,20 @@ type CodeAnchor struct {
 	File   string `json:"file"`
 	Line   int    `json:"line"`
 	Type   string `json:"type"`
+	// Confidence 自动提取的锚点置信度 (0-1)；调用方显式给出的符号不填
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// VerifiedFact 情报包中引用的已验证事实，带来源信息以便追溯
+type VerifiedFact struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Summary   string `json:"summary"`
+	CreatedAt string `json:"created_at"`
+	Source    string `json:"source"` // project: 当前项目数据目录中的 known_facts
+	Scope     string `json:"scope,omitempty"`
+	Priority  string `json:"priority,omitempty"` // 仅非默认 (medium) 时输出
+	ExpiresAt string `json:"expires_at,omitempty"`
 }
 
 // Guardrails 约束规则
@@ -144,11 +202,23 @@ type SystemRecallArgs struct {
 	Keywords string `json:"keywords" jsonschema:"required,description=检索关键词"`
 	Category string `json:"category" jsonschema:"description=过滤类型 (开发/重构/避坑等)"`
 	Limit    int    `json:"limit" jsonschema:"default=20,description=返回条数"`
+	Since    string `json:"since" jsonschema:"description=起始时间 (ISO 日期如 2024-05-01，或相对时长如 3d/2w/12h)"`
+	Until    string `json:"until" jsonschema:"description=截止时间 (格式同 since，纯日期包含当天)"`
+	Semantic bool   `json:"semantic" jsonschema:"description=关键词 + 向量语义混合检索 (需配置 settings.json embedding)"`
 }
 
 // IndexStatusArgs 索引状态参数
 type IndexStatusArgs struct {
 	ProjectRoot string `json:"project_root" jsonschema:"description=可选项目根路径，留空时使用当前会话项目"`
+	Mode        string `json:"mode" jsonschema:"description=status 查看后台任务状态；index_estimate 不建索引，快速统计候选文件并预测耗时；rollback 回滚到上一版索引,default=status,enum=status,enum=index_estimate,enum=rollback"`
+	Scope       string `json:"scope" jsonschema:"description=index_estimate 模式下仅统计该子目录（相对路径）"`
+}
+
+// OpenTimelineArgs 时间线参数
+type OpenTimelineArgs struct {
+	Live bool `json:"live" jsonschema:"description=启动本机 HTTP 服务实时展示时间线，记忆写入后页面自动刷新"`
+	Port int  `json:"port" jsonschema:"description=live 模式监听端口（仅 127.0.0.1），0 表示自动分配"`
+	Stop bool `json:"stop" jsonschema:"description=关闭正在运行的实时时间线服务"`
 }
 
 // RegisterSystemTools 注册系统工具
@@ -164,6 +234,14 @@ func RegisterSystemTools(s *server.MCPServer, sm *SessionManager, ai *services.A
     项目根目录的绝对路径。如果留空，工具会尝试自动探测。
   force_full_index (可选)
     强制全量索引（禁用大仓库 bootstrap 策略）。默认 false。
+  render_target (可选)
+    本会话的输出渲染目标：markdown（默认）或 plain。终端客户端渲染 Markdown 效果差时设为 plain，
+    所有工具输出将去除代码围栏、表格与 emoji。也可在 settings.json 中设置 output.render。
+  discover (可选)
+    monorepo 模式：探测项目根以下 4 层内的嵌套子项目（go.mod / package.json / Cargo.toml），
+    结果保存在数据目录 workspace.json。之后 project_map / code_search 可用 sub_project 参数按名称限定范围。
+  sub_projects (可选)
+    只索引选中的子项目，避免无关包拖慢索引；选择会持久保存，传 ["*"] 恢复索引整个项目。
 
 说明：
   - 手动指定 project_root 时必须使用绝对路径。
@@ -173,6 +251,9 @@ func RegisterSystemTools(s *server.MCPServer, sm *SessionManager, ai *services.A
   initialize_project(project_root="D:/AI_Project/MyProject")
     -> 初始化指定路径的项目
 
+  initialize_project(project_root="D:/AI_Project/Mono", discover=true, sub_projects=["api", "web"])
+    -> 探测子项目，只索引 api 与 web
+
 触发词：
   "mpm 初始化", "mpm init"`),
 		mcp.WithInputSchema[InitArgs](),
@@ -185,20 +266,61 @@ func RegisterSystemTools(s *server.MCPServer, sm *SessionManager, ai *services.A
   生成并展示交互式时间线，可视化项目的开发历史和决策演进。
 
 参数：
-  无
+  live (可选, 默认 false)
+    true 时不写静态文件，而是在 127.0.0.1 启动实时时间线服务：
+    新的 memo、标注、任务链事件、钩子与事实写入后，页面通过 SSE 自动刷新，适合放在副屏持续观察。
+    服务已在运行时直接返回其地址。
+  port (可选, 默认 0)
+    live 模式的监听端口，0 表示自动分配。
+  stop (可选)
+    关闭实时时间线服务。
 
 说明：
-  - 基于 memo 记录生成 project_timeline.html。
+  - 基于 memo 记录与 annotate 人工标注生成 project_timeline.html（服务内置生成，无需 Python）。
+  - 内置生成失败时才回退到项目中旧版的 visualize_history.py。
+  - 任务链事件、钩子创建/释放与事实录入按时间穿插显示，各自独立分类，可按来源筛选。
   - 会尝试自动在默认浏览器中打开生成的文件。
 
 示例：
   open_timeline()
     -> 在浏览器中打开项目演进时间线
+  open_timeline(live=true, port=7788)
+    -> 打开 http://127.0.0.1:7788/ 实时时间线
 
 触发词：
   "mpm 时间线", "mpm timeline"`),
+		mcp.WithInputSchema[OpenTimelineArgs](),
 	), wrapOpenTimeline(sm))
 
+	s.AddTool(mcp.NewTool("annotate",
+		mcp.WithDescription(`annotate - 时间线人工标注
+
+用途：
+  为项目时间线添加人工标注（里程碑、发布节点、事故记录、备注），
+  把人的上下文混入由 Agent 生成的演进历史。
+
+参数：
+  action (默认 add)
+    add: 添加标注 / list: 列出标注 / remove: 删除标注
+  kind (可选)
+    milestone / release / incident / note（默认 note）
+  title (add 必填)
+    标注标题
+  at (可选)
+    标注时间，默认当前时间；支持 "2024-05-01 15:04" 或相对时长 "3d"
+
+说明：
+  - 标注在 open_timeline 中以独立标记显示，并写入每日摘要。
+
+示例：
+  annotate(kind="release", title="v1.2 发布", content="包含支付重构")
+    -> 在时间线上标记一次发布
+
+触发词：
+  "mpm 标注", "mpm annotate"`),
+		mcp.WithInputSchema[AnnotateArgs](),
+	), wrapAnnotate(sm))
+
 	s.AddTool(mcp.NewTool("system_recall",
 		mcp.WithDescription(`system_recall - 你的记忆回溯器 (少走弯路)
 
@@ -213,6 +335,22 @@ func RegisterSystemTools(s *server.MCPServer, sm *SessionManager, ai *services.A
   category (可选)
     缩小范围：如 "避坑" / "开发" / "决策"
 
+  since / until (可选)
+    时间过滤，作用于 memo 时间戳与 fact 创建时间。
+    支持 ISO 日期 ("2024-05-01"、"2024-05-01 15:04") 或相对时长 ("12h"、"3d"、"2w"、"1m")。
+
+  semantic (可选)
+    true 时额外按向量相似度召回换了说法的记录，与关键词结果融合排序。
+    需在 .mcp-config/settings.json 配置 embedding.provider：
+      openai（OpenAI 兼容接口，也可指向本地 ONNX/Ollama 服务）/ command（本地命令）/ hash（内置离线）。
+
+示例：
+  system_recall(keywords="auth 鉴权", since="1w")
+    -> 上周以来关于鉴权的决策与修改
+
+  system_recall(keywords="登录失败后重试", semantic=true)
+    -> 同时找回 "auth retry backoff" 这类措辞不同的记录
+
 触发词：
   "mpm 召回", "mpm 历史", "mpm recall"`),
 		mcp.WithInputSchema[SystemRecallArgs](),
@@ -228,15 +366,209 @@ func RegisterSystemTools(s *server.MCPServer, sm *SessionManager, ai *services.A
   project_root (可选)
     指定项目根路径。留空时使用当前会话项目。
 
+  mode (默认: status)
+    - status: 后台索引任务状态
+    - index_estimate: 不调用索引引擎，按扩展名/一级目录统计候选文件，并依据历史吞吐预测全量索引耗时
+    - rollback: 新索引有问题时，切回上一版索引 (symbols.db.prev)
+
+  scope (可选)
+    index_estimate 模式下仅统计该子目录，便于对比范围索引的代价。
+
 返回：
-  - status/mode/started_at/finished_at
+  - status/mode/started_at/finished_at（running/success/failed；服务退出时被打断为 interrupted）
   - heartbeat(processed/total)
-  - symbols.db / symbols.db-wal / symbols.db-shm 文件大小
+  - symbols.db / symbols.db-wal / symbols.db-shm / symbols.db.prev 文件大小
+    （重建索引写入临时库，成功后原子切换，构建期间查询始终命中旧的完整索引）
+  - engine_daemon: 常驻查询引擎进程状态（running/pid/requests/restarts/last_error），崩溃后自动重启
+  - shards: settings.json index.shard_by 启用分片后，各分片范围/文件数/库大小
+  - index_estimate: candidate_files / by_extension / by_directory / predicted_ms
+
+示例：
+  index_status(mode="index_estimate")
+    -> 预估全量索引耗时，决定是否改用 initialize_project 后的范围索引
 
 触发词：
   "mpm 索引状态", "mpm index status"`),
 		mcp.WithInputSchema[IndexStatusArgs](),
-	), wrapIndexStatus(sm))
+	), wrapIndexStatus(sm, ai))
+
+	s.AddTool(mcp.NewTool("warm_up",
+		mcp.WithDescription(`warm_up - 会话预热 (重度任务开始前一键准备)
+
+用途：
+  在开始大规模 Agent 会话前，一次性完成：范围索引、结构地图缓存、反向调用索引校验、facts/memos 预取，
+  并输出带耗时的汇总进度报告。
+
+参数：
+  scope (可选)
+    预热范围（目录）。留空则对整个项目做新鲜度检查。
+  
+  keywords (可选)
+    记忆预取关键词，留空时使用 scope 的目录名。
+
+示例：
+  warm_up(scope="internal/services")
+    -> 补录 services 索引并预取相关记忆
+
+触发词：
+  "mpm 预热", "mpm warmup"`),
+		mcp.WithInputSchema[WarmUpArgs](),
+	), wrapWarmUp(sm, ai))
+
+	s.AddTool(mcp.NewTool("system_metrics",
+		mcp.WithDescription(`system_metrics - 查看服务运行指标
+
+用途：
+  查看服务运行时长、绑定项目，以及内置调度器各定时任务（钩子过期清理、每日摘要、索引新鲜度、保留期清理）的最近执行状态。
+
+说明：
+  调度器在 .mcp-config/settings.json 中配置：
+    {"scheduler": {"enabled": true, "jobs": [{"name": "nightly-digest", "kind": "digest", "every": "24h"}]}}
+  kind 可选 hook_expiry / digest / index_freshness / retention_gc / fact_suggest / db_backup；jobs 为空时启用全部默认任务。
+
+触发词：
+  "mpm 指标", "mpm metrics"`),
+	), wrapSystemMetrics(sm))
+
+	s.AddTool(mcp.NewTool("server_stats",
+		mcp.WithDescription(`server_stats - 服务性能统计
+
+用途：
+  找出拖慢 Agent 的工具：各工具调用次数、失败数、耗时分位数 (p50/p90/p99)，
+  以及索引构建耗时、数据库体积、引擎查询缓存命中率。统计从本次进程启动开始累计。
+
+参数：
+  format (默认: table)
+    - table: Markdown 表格，按累计耗时排序
+    - prometheus: Prometheus 文本暴露格式 (mpm_tool_calls_total / mpm_tool_latency_ms 等)
+  top (默认: 20)
+    表格只列出累计耗时最高的前 N 个工具。
+  audit (默认: 0)
+    附带最近 N 条命令执行审计（auto_verify、embedding、intent 等经命令白名单执行的命令，含被拒绝的）。
+  audit_source (可选)
+    按来源前缀过滤审计，如 auto_verify、embedding、intent。
+
+触发词：
+  "mpm 统计", "mpm stats"`),
+		mcp.WithInputSchema[ServerStatsArgs](),
+	), wrapServerStats(sm, ai))
+
+	s.AddTool(mcp.NewTool("features",
+		mcp.WithDescription(`features - 项目级实验性功能开关
+
+用途：
+  列出/启用/关闭实验性子系统（并行子任务、自定义协议、目录建议、候选事实等）。
+  开关保存在 .mcp-config/settings.json 的 features 段，按项目生效，修改后立即生效。
+
+参数：
+  action (默认 list)
+    list / enable / disable
+  
+  name (enable/disable 必填)
+    功能名，如 parallel_subtasks。
+
+示例：
+  features(action="enable", name="parallel_subtasks")
+    -> task_chain spawn 可使用 depends_on / parallel
+
+触发词：
+  "mpm 功能开关", "mpm features"`),
+		mcp.WithInputSchema[FeaturesArgs](),
+	), wrapFeatures(sm))
+
+	s.AddTool(mcp.NewTool("restore_backup",
+		mcp.WithDescription(`restore_backup - 记忆库整库备份与恢复
+
+用途：
+  列出/创建记忆库 (mcp_memory.db) 的完整备份，或从指定备份恢复，防止数月的项目记忆因损坏或误操作丢失。
+  备份保存在数据目录 backups/ 下，按类别（scheduled / manual / pre-restore）各保留 storage.backup_keep 份（默认 7）。
+  启用 scheduler 后 db_backup 任务每天自动备份一次。
+
+参数：
+  action (默认 list)
+    list: 列出备份
+    create: 立即手动备份
+    restore: 从备份恢复（先校验备份完整性，并为当前库生成 pre-restore 安全副本）
+  
+  name (restore 必填)
+    备份文件名，如 memory-scheduled-20260101-030000.db。
+
+示例：
+  restore_backup(action="restore", name="memory-scheduled-20260101-030000.db")
+    -> 恢复到该时间点，返回可用于撤销的安全副本名
+
+触发词：
+  "mpm 备份", "mpm 恢复备份"`),
+		mcp.WithInputSchema[RestoreBackupArgs](),
+	), wrapRestoreBackup(sm))
+
+	s.AddTool(mcp.NewTool("memory_admin",
+		mcp.WithDescription(`memory_admin - 记忆库运维（整包备份 / 恢复 / VACUUM / 完整性检查）
+
+用途：
+  mcp_memory.db 损坏或误操作后无需手工修库：整包备份把数据库与 dev-log-archive/ 一起保存，
+  完整性检查会运行 PRAGMA integrity_check，并把 memo_archive.jsonl 的重放结果与数据库逐条比对。
+
+参数：
+  mode (必填)
+    backup: 创建整包备份（backups/bundle-manual-<时间>/，含 mcp_memory.db、dev-log-archive/、manifest.json）
+    list: 列出整包备份
+    restore: 从整包备份恢复数据库与 dev-log-archive（先为当前状态生成 pre-restore 整包安全副本）
+    vacuum: 合并 WAL 并重建数据库文件，回收空闲页
+    integrity_check: 损坏检查 + 归档重放比对（报告缺少/多出的 memo）
+
+  name (restore 必填)
+    整包备份目录名，如 bundle-manual-20260101-030000。
+
+说明：
+  整包备份与 restore_backup 的单文件备份共用 storage.backup_keep 轮转份数（按类别分别计数）。
+
+示例：
+  memory_admin(mode="integrity_check")
+    -> integrity_check 结果与归档比对
+  memory_admin(mode="restore", name="bundle-manual-20260101-030000")
+    -> 恢复并返回可用于撤销的安全副本名
+
+触发词：
+  "mpm 记忆库体检", "mpm 整包备份", "mpm vacuum"`),
+		mcp.WithInputSchema[MemoryAdminArgs](),
+	), wrapMemoryAdmin(sm))
+
+	s.AddTool(mcp.NewTool("report",
+		mcp.WithDescription(`report - 周报 / 日报生成
+
+用途：
+  汇总一段时间内"改了什么、为什么改"，生成可直接发给团队的 Markdown 摘要，
+  不再需要从 dev-log.md 手工整理。
+
+参数：
+  since (可选, 默认 7d)
+    起始时间，ISO 日期（2024-05-01）或相对时长（1d / 7d / 2w）。
+  until (可选, 默认现在)
+    截止时间，格式同 since，纯日期包含当天。
+  group_by (可选, 默认 day)
+    memo 明细的分组方式：day 按日期 / category 按类型 / entity 按实体。
+  save (可选)
+    同时写入数据目录 reports/report-<起>-<止>.md。
+
+内容：
+  - 概览：变更条数、类型分布、完成的任务链数、释放的钩子数
+  - 里程碑与人工标注
+  - 完成的任务链（status=finished，完成时间落在窗口内）
+  - 释放的钩子及其结果摘要
+  - memo 明细（按 group_by 分组，附 act 与 content 说明原因）
+  - 索引状态与项目规模（文件 / 行 / 符号）
+
+示例：
+  report()
+    -> 最近 7 天周报，按日期分组
+  report(since="1d", group_by="category")
+    -> 今日日报，按变更类型分组
+
+触发词：
+  "mpm 周报", "mpm 日报", "mpm report", "mpm weekly"`),
+		mcp.WithInputSchema[ReportArgs](),
+	), wrapReport(sm, ai))
 }
 
 func wrapInit(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
@@ -245,6 +577,9 @@ func wrapInit(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFun
 		if err := request.BindArguments(&args); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("参数格式错误： %v", err)), nil
 		}
+		if args.RenderTarget != "" {
+			sm.RenderTarget = args.RenderTarget
+		}
 
 		root := args.ProjectRoot
 
@@ -283,9 +618,9 @@ func wrapInit(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFun
 			return mcp.NewToolResultError(fmt.Sprintf("⛔ 敏感路径（系统或 IDE 目录），禁止在此初始化项目： %s", absRoot)), nil
 		}
 
-		// 3. 确保 .mcp-data 存在
-		mcpDataDir := filepath.Join(absRoot, ".mcp-data")
-		if err := os.MkdirAll(mcpDataDir, 0755); err != nil {
+		// 3. 确保数据目录存在（默认 .mcp-data，可由 settings.json storage.data_dir 配置）
+		mcpDataDir, err := core.EnsureDataDir(absRoot)
+		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("创建数据目录失败： %v", err)), nil
 		}
 
@@ -303,15 +638,21 @@ func wrapInit(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFun
 			return mcp.NewToolResultError(fmt.Sprintf("初始化记忆层失败： %v", err)), nil
 		}
 
-		sm.Memory = mem
-		sm.ProjectRoot = absRoot
+		sm.Bind(absRoot, mem)
+
+		// 5.1 工作区子项目（monorepo）：探测并保存选择，索引据此只覆盖选中目录
+		workspaceMsg, err := setupWorkspace(absRoot, args.Discover, args.SubProjects)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
-		// 6. 植入 visualize_history.py (Timeline 生成脚本)
-		// 写入到项目根目录，如果不存在或强制更新（这里简化为覆盖）
+		// 6. 时间线已由 Go 原生生成；仅刷新旧项目中已存在的 visualize_history.py（兜底脚本），不再为新项目植入
 		scriptPath := filepath.Join(absRoot, "visualize_history.py")
-		if err := os.WriteFile(scriptPath, []byte(VisualizeHistoryScript), 0644); err != nil {
-			// 记录警告但不阻断
-			fmt.Printf("Warning: Failed to inject visualize_history.py: %v\n", err)
+		if _, err := os.Stat(scriptPath); err == nil {
+			if err := os.WriteFile(scriptPath, []byte(VisualizeHistoryScript), 0644); err != nil {
+				// 记录警告但不阻断
+				fmt.Fprintf(os.Stderr, "Warning: Failed to refresh visualize_history.py: %v\n", err)
+			}
 		}
 
 		// 7. 立即写入一份规则模板，索引完成后会在后台自动刷新为真实统计
@@ -328,7 +669,7 @@ func wrapInit(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFun
 		}
 		indexStatus := fmt.Sprintf("🚀 后台构建中（mode=%s, 状态文件: %s）", mode, statusPath)
 
-		return mcp.NewToolResultText(fmt.Sprintf("✅ 项目初始化成功！\n\n项目目录: %s\n数据库已准备就绪。\nAST 索引: %s%s", absRoot, indexStatus, rulesMsg)), nil
+		return mcp.NewToolResultText(fmt.Sprintf("✅ 项目初始化成功！\n\n项目目录: %s\n数据库已准备就绪。\nAST 索引: %s%s%s", absRoot, indexStatus, workspaceMsg, rulesMsg)), nil
 	}
 }
 
@@ -443,7 +784,7 @@ func generateProjectRules(path string, analysis *services.NamingAnalysis) error
 	return os.WriteFile(path, []byte(content), 0644)
 }
 
-func wrapIndexStatus(sm *SessionManager) server.ToolHandlerFunc {
+func wrapIndexStatus(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		_ = ctx
 
@@ -466,6 +807,29 @@ func wrapIndexStatus(sm *SessionManager) server.ToolHandlerFunc {
 		}
 		absRoot = filepath.ToSlash(filepath.Clean(absRoot))
 
+		if args.Mode == "index_estimate" {
+			est, err := services.EstimateIndex(absRoot, args.Scope)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("索引预估失败: %v", err)), nil
+			}
+			rawOut, _ := json.MarshalIndent(map[string]interface{}{
+				"project_root":   absRoot,
+				"index_estimate": est,
+				"predicted":      (time.Duration(est.PredictedMs) * time.Millisecond).Round(time.Second).String(),
+			}, "", "  ")
+			return mcp.NewToolResultText(string(rawOut)), nil
+		}
+
+		if args.Mode == "rollback" {
+			if mode := services.ShardMode(absRoot); mode != "" {
+				return mcp.NewToolResultError(fmt.Sprintf("分片索引 (shard_by=%s) 暂不支持 rollback，请用 initialize_project(force_full_index=true) 重建", mode)), nil
+			}
+			if err := services.RollbackIndex(absRoot); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("索引回滚失败: %v", err)), nil
+			}
+			return mcp.NewToolResultText("✅ 已回滚到上一版索引；被替换的索引保留为 symbols.db.prev，可再次 rollback 切回。"), nil
+		}
+
 		result := map[string]interface{}{
 			"project_root": absRoot,
 		}
@@ -483,7 +847,7 @@ func wrapIndexStatus(sm *SessionManager) server.ToolHandlerFunc {
 			result["index_status_error"] = err.Error()
 		}
 
-		heartbeatPath := filepath.Join(absRoot, ".mcp-data", "heartbeat")
+		heartbeatPath := core.DataPath(absRoot, "heartbeat")
 		result["heartbeat_file"] = filepath.ToSlash(heartbeatPath)
 		if raw, err := os.ReadFile(heartbeatPath); err == nil {
 			var heartbeat map[string]interface{}
@@ -497,13 +861,35 @@ func wrapIndexStatus(sm *SessionManager) server.ToolHandlerFunc {
 		}
 
 		sizeMap := map[string]int64{}
-		for _, name := range []string{"symbols.db", "symbols.db-wal", "symbols.db-shm"} {
-			p := filepath.Join(absRoot, ".mcp-data", name)
+		for _, name := range []string{"symbols.db", "symbols.db-wal", "symbols.db-shm", "symbols.db.prev", "symbols.db.building"} {
+			p := core.DataPath(absRoot, name)
 			if st, err := os.Stat(p); err == nil {
 				sizeMap[name] = st.Size()
 			}
 		}
 		result["db_file_sizes"] = sizeMap
+		result["engine_daemon"] = ai.EngineDaemonHealth(absRoot)
+
+		// 分片索引：列出清单中的分片及库文件大小
+		if m, err := services.LoadShardManifest(absRoot); err == nil && m != nil {
+			shardSizes := map[string]int64{}
+			for _, sh := range m.Shards {
+				if st, err := os.Stat(core.DataPath(absRoot, "shards", sh.DB)); err == nil {
+					shardSizes[sh.DB] = st.Size()
+				}
+			}
+			result["shards"] = map[string]interface{}{
+				"shard_by":   m.By,
+				"active":     services.ShardMode(absRoot) == m.By,
+				"shards":     m.Shards,
+				"file_sizes": shardSizes,
+			}
+		}
+
+		// 技术栈探测被预算截断或跳过了符号链接时，报告实际生效的限制
+		if scan := services.LastScanReport(absRoot); scan != nil && (scan.Truncated || scan.SymlinksSkipped > 0) {
+			result["detect_scan"] = scan
+		}
 
 		rawOut, _ := json.MarshalIndent(result, "", "  ")
 		return mcp.NewToolResultText(string(rawOut)), nil
@@ -512,42 +898,61 @@ func wrapIndexStatus(sm *SessionManager) server.ToolHandlerFunc {
 
 func wrapOpenTimeline(sm *SessionManager) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args OpenTimelineArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if args.Stop {
+			stopLiveTimeline()
+			return mcp.NewToolResultText("⏹️ 实时时间线服务已关闭"), nil
+		}
+
 		root := sm.ProjectRoot
 		if root == "" {
 			return mcp.NewToolResultError("❌ 项目未初始化，请先调用 initialize_project"), nil
 		}
 
-		// 1. 定位脚本 (优先 scripts/, 其次 root)
-		scriptPath := filepath.Join(root, "scripts", "visualize_history.py")
-		if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-			scriptPath = filepath.Join(root, "visualize_history.py")
-			if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-				return mcp.NewToolResultError(fmt.Sprintf("❌ 找不到生成脚本: %s (checked scripts/ and root)", "visualize_history.py")), nil
+		if args.Live {
+			if sm.Memory == nil {
+				return mcp.NewToolResultError("❌ 记忆层尚未初始化，无法启动实时时间线"), nil
+			}
+			if args.Port < 0 || args.Port > 65535 {
+				return mcp.NewToolResultError(fmt.Sprintf("参数错误: port 超出范围: %d", args.Port)), nil
+			}
+			url, reused, err := startLiveTimeline(sm, args.Port)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("❌ 启动实时时间线失败: %v", err)), nil
 			}
+			status := "已启动"
+			if reused {
+				status = "已在运行"
+			}
+			msg := fmt.Sprintf("📡 实时时间线%s: %s\n记忆写入后页面自动刷新；关闭请调用 open_timeline(stop=true)。", status, url)
+			if err := openInBrowser(url); err != nil {
+				msg += "\n⚠️ 无法自动打开浏览器，请手动访问上述地址。"
+			}
+			return mcp.NewToolResultText(msg), nil
 		}
 
-		// 2. 生成 HTML (Python)
-		cmd := exec.Command("python", scriptPath)
-		cmd.Dir = root
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("❌ 生成 Timeline 失败:\n%s\nOutput: %s", err, string(output))), nil
+		// 1. Go 原生生成；记忆层不可用或生成失败时回退到旧版 Python 脚本
+		var htmlPath string
+		var genErr error
+		if sm.Memory != nil {
+			htmlPath, _, genErr = generateTimeline(ctx, sm)
+		} else {
+			genErr = fmt.Errorf("记忆层尚未初始化")
 		}
-
-		// 3. 定位 HTML
-		htmlPath := filepath.Join(root, "project_timeline.html")
-		if _, err := os.Stat(htmlPath); os.IsNotExist(err) {
-			return mcp.NewToolResultError("❌ 脚本执行成功但未生成 project_timeline.html"), nil
+		if genErr != nil {
+			var pyErr error
+			if htmlPath, pyErr = generateTimelineWithPython(root); pyErr != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("❌ 生成 Timeline 失败: %v\n旧版脚本兜底也失败: %v", genErr, pyErr)), nil
+			}
 		}
 
-		// 4. 打开浏览器
-		htmlURL := "file:///" + filepath.ToSlash(htmlPath)
-		edgeCmd := exec.Command("cmd", "/c", "start", "msedge", fmt.Sprintf("--app=%s", htmlURL))
-		if err := edgeCmd.Start(); err != nil {
-			fallbackCmd := exec.Command("cmd", "/c", "start", htmlURL)
-			if err := fallbackCmd.Start(); err != nil {
-				return mcp.NewToolResultText(fmt.Sprintf("⚠️ Timeline 已生成但无法自动打开。\n路径: %s", htmlPath)), nil
-			}
+		// 2. 打开浏览器
+		htmlURL := "file:///" + strings.TrimPrefix(filepath.ToSlash(htmlPath), "/")
+		if err := openInBrowser(htmlURL); err != nil {
+			return mcp.NewToolResultText(fmt.Sprintf("⚠️ Timeline 已生成但无法自动打开。\n路径: %s", htmlPath)), nil
 		}
 
 		return mcp.NewToolResultText(fmt.Sprintf("✅ Timeline 已生成并尝试打开。\n文件: %s", htmlPath)), nil
@@ -565,25 +970,48 @@ func wrapSystemRecall(sm *SessionManager) server.ToolHandlerFunc {
 			return mcp.NewToolResultError("项目未初始化"), nil
 		}
 
+		within, err := core.ParseTimeRange(args.Since, args.Until, time.Now())
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+
 		// 1. 查询 Memos（历史修改记录）
-		memos, err := sm.Memory.SearchMemos(ctx, args.Keywords, args.Category, args.Limit)
+		memos, err := sm.Memory.SearchMemosInRange(ctx, args.Keywords, args.Category, within, args.Limit)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("检索 memos 失败: %v", err)), nil
 		}
 
 		// 2. 查询 Known Facts（铁律/避坑经验）
-		facts, err := sm.Memory.QueryFacts(ctx, args.Keywords, args.Limit)
+		facts, err := sm.Memory.QueryFactsInRange(ctx, args.Keywords, within, args.Limit)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("检索 known_facts 失败: %v", err)), nil
 		}
 
+		// 2.1 语义混合检索
+		var recallNote string
+		if args.Semantic && strings.TrimSpace(args.Keywords) != "" {
+			memos, facts, recallNote = semanticRecall(ctx, sm, args, within, memos, facts)
+		}
+
 		// 3. 检查是否有结果
 		if len(memos) == 0 && len(facts) == 0 {
+			if recallNote != "" {
+				return mcp.NewToolResultText(recallNote + "\n\n未找到相关记录"), nil
+			}
+			if !within.IsZero() {
+				return mcp.NewToolResultText(fmt.Sprintf("未找到相关记录（时间范围: %s）", within)), nil
+			}
 			return mcp.NewToolResultText("未找到相关记录"), nil
 		}
 
 		// 4. 构建返回结果
 		var sb strings.Builder
+		if recallNote != "" {
+			sb.WriteString(recallNote + "\n\n")
+		}
+		if !within.IsZero() {
+			sb.WriteString(fmt.Sprintf("**🕒 时间范围**: %s\n\n", within))
+		}
 
 		// 输出 Known Facts
 		if len(facts) > 0 {
@@ -607,7 +1035,7 @@ func wrapSystemRecall(sm *SessionManager) server.ToolHandlerFunc {
 					m.Timestamp.Format("2006-01-02 15:04"),
 					m.Category,
 					m.Act,
-					m.Content))
+					m.Content+memoCommitTag(m.GitCommit, m.GitDirty)))
 			}
 		}
 
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 任务链执行期复杂度告警 ==========
+
+var summaryIdentRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]{2,60}`)
+
+// extractSummarySymbols 从 summary 中提取疑似代码标识符
+// 仅保留含大写字母(非首字母)/下划线、或紧跟 "(" 的 token，避免普通英文单词误报
+func extractSummarySymbols(summary string, limit int) []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, loc := range summaryIdentRe.FindAllStringIndex(summary, -1) {
+		tok := summary[loc[0]:loc[1]]
+		if seen[tok] {
+			continue
+		}
+		looksLikeCode := strings.Contains(tok, "_") ||
+			strings.ToLower(tok[1:]) != tok[1:] ||
+			(loc[1] < len(summary) && summary[loc[1]] == '(')
+		if !looksLikeCode {
+			continue
+		}
+		seen[tok] = true
+		symbols = append(symbols, tok)
+		if len(symbols) >= limit {
+			break
+		}
+	}
+	return symbols
+}
+
+// buildChainComplexityAlerts 对 summary 中提及且超过项目阈值的符号生成告警
+func buildChainComplexityAlerts(sm *SessionManager, ai *services.ASTIndexer, summary string) string {
+	if ai == nil || sm.ProjectRoot == "" || strings.TrimSpace(summary) == "" {
+		return ""
+	}
+	symbols := extractSummarySymbols(summary, 20)
+	if len(symbols) == 0 {
+		return ""
+	}
+
+	report, err := ai.AnalyzeComplexityExcluding(sm.ProjectRoot, symbols, resolvePathTagger(sm, false))
+	if err != nil || report == nil {
+		return ""
+	}
+
+	threshold := core.LoadProjectSettings(sm.ProjectRoot).Complexity.AlertThreshold
+	var hits []services.RiskInfo
+	for _, risk := range report.HighRiskSymbols {
+		if risk.Score >= threshold {
+			hits = append(hits, risk)
+		}
+	}
+	if len(hits) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n⚠️ [Complexity] 本次总结涉及 %d 个高复杂度符号 (阈值 %.0f)：\n", len(hits), threshold))
+	for _, h := range hits {
+		reason := h.Reason
+		if reason == "" {
+			reason = "-"
+		}
+		sb.WriteString(fmt.Sprintf("  - %s: %.1f (%s)\n", h.SymbolName, h.Score, reason))
+		sb.WriteString(fmt.Sprintf("    → 建议 code_impact(symbol_name=\"%s\") 确认影响面，并补充/运行相关测试\n", h.SymbolName))
+	}
+	return sb.String()
+}
+
+// appendComplexityAlerts 在 complete/complete_sub 结果后追加复杂度告警
+func appendComplexityAlerts(result *mcp.CallToolResult, sm *SessionManager, ai *services.ASTIndexer, summary string) *mcp.CallToolResult {
+	if result == nil || result.IsError {
+		return result
+	}
+	if alert := buildChainComplexityAlerts(sm, ai, summary); alert != "" {
+		result.Content = append(result.Content, mcp.NewTextContent(alert))
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== gate / 子任务的自动验证 ==========
+// complete(auto_verify=true) 与 complete_sub(auto_verify=true) 经命令守卫执行阶段或子任务上声明的 verify 命令，
+// 在任务链的 working_dir / env 下运行，以退出码决定 pass/fail，并把命令与结果附加到 summary。
+// 命令被策略拒绝或无法启动时不改变任务链状态，可改为手动传 result。
+
+const autoVerifyTailLines = 30
+
+// autoVerifyRun 一次自动验证的结果
+type autoVerifyRun struct {
+	Command  string
+	Result   string // pass / fail
+	ExitCode int
+	Duration time.Duration
+	Output   string
+}
+
+// runAutoVerify 执行 verify 命令；target 用于审计来源（phase_id 或 phase_id/sub_id）
+func runAutoVerify(ctx context.Context, sm *SessionManager, chain *TaskChainV3, target, command string) (*autoVerifyRun, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, fmt.Errorf("%s 未配置 verify 命令，无法 auto_verify（请手动传 result）", target)
+	}
+	res, err := core.NewCommandRunner(sm.ProjectRoot).Run(ctx, core.CommandRequest{
+		Line:   command,
+		Dir:    chain.WorkingDir,
+		Env:    chain.Env,
+		Source: fmt.Sprintf("task_chain:%s/%s/auto_verify", chain.TaskID, target),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("auto_verify 未能执行 `%s`: %v（任务链状态未改变，可手动传 result）", command, err)
+	}
+	run := &autoVerifyRun{Command: command, Result: "pass", ExitCode: res.ExitCode, Duration: res.Duration, Output: res.Output}
+	if res.ExitCode != 0 {
+		run.Result = "fail"
+	}
+	return run, nil
+}
+
+// summaryLine 附加到 summary 的一行验证记录
+func (r *autoVerifyRun) summaryLine() string {
+	return fmt.Sprintf("[auto_verify] `%s` 退出码 %d (%.1fs) → %s", r.Command, r.ExitCode, r.Duration.Seconds(), r.Result)
+}
+
+// mergeSummary 把验证记录并入调用方给出的 summary（可为空）
+func (r *autoVerifyRun) mergeSummary(summary string) string {
+	if strings.TrimSpace(summary) == "" {
+		return r.summaryLine()
+	}
+	return summary + "\n" + r.summaryLine()
+}
+
+// render 验证结果与输出末尾若干行
+func (r *autoVerifyRun) render(requested string) string {
+	var sb strings.Builder
+	icon := "✅"
+	if r.Result != "pass" {
+		icon = "❌"
+	}
+	sb.WriteString(fmt.Sprintf("%s auto_verify: `%s` 退出码 %d (%.1fs)\n", icon, r.Command, r.ExitCode, r.Duration.Seconds()))
+	if requested != "" && requested != r.Result {
+		sb.WriteString(fmt.Sprintf("⚠️ 传入的 result=%s 已被验证结果 %s 覆盖\n", requested, r.Result))
+	}
+	if out := strings.TrimRight(r.Output, "\n"); out != "" {
+		lines := strings.Split(out, "\n")
+		if len(lines) > autoVerifyTailLines {
+			sb.WriteString(fmt.Sprintf("（输出共 %d 行，仅显示最后 %d 行）\n", len(lines), autoVerifyTailLines))
+			lines = lines[len(lines)-autoVerifyTailLines:]
+		}
+		sb.WriteString("```\n")
+		sb.WriteString(strings.Join(lines, "\n"))
+		sb.WriteString("\n```\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRunAutoVerifyGate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("超时用例依赖 sleep")
+	}
+	cases := []struct {
+		name      string
+		verify    string
+		summary   string
+		requested string
+		wantErr   string      // 非空时工具返回错误，任务链状态不变
+		wantState PhaseStatus // gate 完成后的状态（fail 回退为 pending）
+		wantSum   string      // 写入 gate 的 summary
+		wantOut   []string
+	}{
+		{name: "pass", verify: "go version", summary: "实现完成",
+			wantState: PhasePassed, wantSum: "实现完成\n[auto_verify] `go version` 退出码 0",
+			wantOut: []string{"✅ auto_verify: `go version` 退出码 0", "go version go"}},
+		{name: "fail overrides requested result", verify: "go bogus", requested: "pass",
+			wantState: PhasePending, wantSum: "[auto_verify] `go bogus` 退出码 2",
+			wantOut: []string{"❌ auto_verify: `go bogus` 退出码 2", "传入的 result=pass 已被验证结果 fail 覆盖"}},
+		{name: "denied by guard", verify: "rm -rf build", summary: "清理",
+			wantErr: "auto_verify 未能执行 `rm -rf build`", wantState: PhaseActive},
+		{name: "timeout", verify: "sleep 5",
+			wantErr: "超时", wantState: PhaseActive},
+		{name: "missing verify", verify: "",
+			wantErr: "未配置 verify 命令", wantState: PhaseActive},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			os.MkdirAll(filepath.Join(root, ".mcp-config"), 0755)
+			settings := `{"commands": {"allow": ["go version", "go bogus", "sleep"], "timeout_seconds": 1}}`
+			if err := os.WriteFile(filepath.Join(root, ".mcp-config", "settings.json"), []byte(settings), 0644); err != nil {
+				t.Fatal(err)
+			}
+			chain := &TaskChainV3{TaskID: "T", Status: "running", CurrentPhase: "check", Phases: []Phase{
+				{ID: "impl", Type: PhaseExecute, Status: PhasePassed},
+				{ID: "check", Type: PhaseGate, Status: PhaseActive, Verify: tc.verify, OnFail: "impl"},
+			}}
+			sm := &SessionManager{ProjectRoot: root, TaskChainsV3: map[string]*TaskChainV3{"T": chain}}
+
+			res, err := completePhaseV3(context.Background(), sm, TaskChainArgs{
+				Mode: "complete", TaskID: "T", PhaseID: "check", AutoVerify: true, Summary: tc.summary, Result: tc.requested,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := res.Content[0].(mcp.TextContent).Text
+			gate := chain.findPhase("check")
+			if tc.wantErr != "" {
+				if !res.IsError || !strings.Contains(text, tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v %q", tc.wantErr, res.IsError, text)
+				}
+				if gate.Status != tc.wantState || gate.Summary != "" {
+					t.Errorf("chain must stay unchanged on error: %+v", gate)
+				}
+				return
+			}
+			if res.IsError {
+				t.Fatalf("unexpected error: %s", text)
+			}
+			if gate.Status != tc.wantState || !strings.HasPrefix(gate.Summary, tc.wantSum) {
+				t.Errorf("gate = %s %q, want %s with summary prefix %q", gate.Status, gate.Summary, tc.wantState, tc.wantSum)
+			}
+			for _, want := range tc.wantOut {
+				if !strings.Contains(text, want) {
+					t.Errorf("output missing %q:\n%s", want, text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 任务链执行预算 ==========
+// init 时声明步数 / gate 重试总数 / 估算 token 上限，每次 complete/complete_sub 累计。
+// 用量过半、超过 80% 时逐级警告；用尽后强制停在"总结并询问用户"关口，直到 ack_budget 追加预算。
+
+// StepBudget 任务链执行预算
+type StepBudget struct {
+	MaxSteps        int `json:"max_steps,omitempty"`         // complete/complete_sub 调用次数上限 (0=不限)
+	MaxRetriesTotal int `json:"max_retries_total,omitempty"` // 全链 gate 失败次数上限 (0=不限)
+	MaxTokens       int `json:"max_tokens,omitempty"`        // 估算 token 上限 (0=不限)
+
+	Steps   int `json:"steps"`
+	Retries int `json:"retries"`
+	Tokens  int `json:"tokens"`
+}
+
+// budgetRatio 各项预算中的最高使用率
+func (b *StepBudget) budgetRatio() float64 {
+	ratio := 0.0
+	for _, pair := range [][2]int{{b.Steps, b.MaxSteps}, {b.Retries, b.MaxRetriesTotal}, {b.Tokens, b.MaxTokens}} {
+		if pair[1] > 0 {
+			if r := float64(pair[0]) / float64(pair[1]); r > ratio {
+				ratio = r
+			}
+		}
+	}
+	return ratio
+}
+
+// Exhausted 是否有任一预算用尽
+func (b *StepBudget) Exhausted() bool {
+	return b != nil && b.budgetRatio() >= 1
+}
+
+// Summary 预算使用情况单行描述
+func (b *StepBudget) Summary() string {
+	limit := func(n int) string {
+		if n <= 0 {
+			return "∞"
+		}
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("步数 %d/%s，gate 重试 %d/%s，估算 token %d/%s",
+		b.Steps, limit(b.MaxSteps), b.Retries, limit(b.MaxRetriesTotal), b.Tokens, limit(b.MaxTokens))
+}
+
+// estimateTokens 粗略估算 token：ASCII 约 4 字符 1 token，其余（中文等）按 1 字 1 token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii/4 + other
+}
+
+// checkStepBudgetGate 预算用尽时阻止阶段推进
+func checkStepBudgetGate(chain *TaskChainV3) error {
+	if !chain.Budget.Exhausted() {
+		return nil
+	}
+	return fmt.Errorf("⛔ 任务链 %s 执行预算已用尽 (%s)。\n"+
+		"请停止继续尝试：总结当前进度与卡点，询问用户是否继续。\n"+
+		"用户同意后调用 task_chain(mode=\"ack_budget\", task_id=\"%s\", summary=\"用户确认说明\"[, max_steps=新上限]) 追加预算",
+		chain.TaskID, chain.Budget.Summary(), chain.TaskID)
+}
+
+// recordStepBudget 累计一次 complete/complete_sub 的消耗，返回附加到输出的预算提示
+func recordStepBudget(ctx context.Context, sm *SessionManager, args TaskChainArgs, res *mcp.CallToolResult) *mcp.CallToolResult {
+	if res == nil || res.IsError {
+		return res
+	}
+	chain, err := getOrLoadV3Chain(ctx, sm, args.TaskID)
+	if err != nil || chain.Budget == nil {
+		return res
+	}
+	b := chain.Budget
+	b.Steps++
+	if args.Mode == "complete" && args.Result == "fail" {
+		b.Retries++
+	}
+	if args.TokensUsed > 0 {
+		b.Tokens += args.TokensUsed
+	} else {
+		text := args.Summary
+		for _, c := range res.Content {
+			if tc, ok := c.(mcp.TextContent); ok {
+				text += tc.Text
+			}
+		}
+		b.Tokens += estimateTokens(text)
+	}
+	_ = persistV3Chain(ctx, sm, chain, "", "", "", "")
+
+	var note string
+	switch ratio := b.budgetRatio(); {
+	case ratio >= 1:
+		note = fmt.Sprintf("\n⛔ 执行预算已用尽 (%s)。后续 complete/complete_sub 将被阻止：请总结进度并询问用户是否继续。\n", b.Summary())
+	case ratio >= 0.8:
+		note = fmt.Sprintf("\n⚠️ 执行预算即将用尽 (%s)。请收敛范围，优先完成关键阶段。\n", b.Summary())
+	case ratio >= 0.5:
+		note = fmt.Sprintf("\nℹ️ 执行预算已用过半 (%s)\n", b.Summary())
+	default:
+		return res
+	}
+	res.Content = append(res.Content, mcp.NewTextContent(note))
+	return res
+}
+
+// ackBudgetV3 用户同意后追加预算：传入 max_* 则设为新上限，否则已用尽的项目按原上限的一半追加
+func ackBudgetV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	chain, err := getOrLoadV3Chain(ctx, sm, args.TaskID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if chain.Budget == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("任务链 %s 未声明执行预算", args.TaskID)), nil
+	}
+	if strings.TrimSpace(args.Summary) == "" {
+		return mcp.NewToolResultError("ack_budget 模式需要 summary（记录用户的确认内容）"), nil
+	}
+
+	b := chain.Budget
+	extend := func(max *int, used, requested int) {
+		switch {
+		case requested > 0:
+			*max = requested
+		case *max > 0 && used >= *max:
+			*max += (*max + 1) / 2
+		}
+	}
+	extend(&b.MaxSteps, b.Steps, args.MaxSteps)
+	extend(&b.MaxRetriesTotal, b.Retries, args.MaxRetriesTotal)
+	extend(&b.MaxTokens, b.Tokens, args.MaxTokens)
+
+	_ = persistV3Chain(ctx, sm, chain, "ack_budget", chain.CurrentPhase, "", args.Summary)
+	msg := fmt.Sprintf("已记录预算确认: %s\n执行预算: %s\n", args.Summary, b.Summary())
+	if b.Exhausted() {
+		msg += "⚠️ 新上限仍不高于已用量，complete 仍会被阻止，请提高上限。\n"
+	}
+	return mcp.NewToolResultText(msg), nil
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestStepBudgetEscalation(t *testing.T) {
+	ctx := context.Background()
+	chain := &TaskChainV3{TaskID: "T", Status: "running", Budget: &StepBudget{MaxSteps: 4, MaxRetriesTotal: 1}}
+	sm := &SessionManager{ProjectRoot: t.TempDir(), TaskChainsV3: map[string]*TaskChainV3{"T": chain}}
+
+	step := func(result string) string {
+		args := TaskChainArgs{Mode: "complete", TaskID: "T", Result: result, Summary: "done", TokensUsed: 10}
+		res := recordStepBudget(ctx, sm, args, mcp.NewToolResultText("ok"))
+		var out []string
+		for _, c := range res.Content {
+			out = append(out, c.(mcp.TextContent).Text)
+		}
+		return strings.Join(out, "")
+	}
+
+	if out := step("pass"); strings.Contains(out, "预算") {
+		t.Errorf("1/4 steps should not warn: %q", out)
+	}
+	if out := step("pass"); !strings.Contains(out, "已用过半") {
+		t.Errorf("2/4 steps should warn half: %q", out)
+	}
+	if out := step("fail"); !strings.Contains(out, "已用尽") {
+		t.Errorf("gate retry budget 1/1 should be exhausted: %q", out)
+	}
+	if err := checkStepBudgetGate(chain); err == nil {
+		t.Fatal("exhausted budget should block complete")
+	}
+	if chain.Budget.Tokens != 30 {
+		t.Errorf("tokens = %d, want 30", chain.Budget.Tokens)
+	}
+
+	if res, _ := ackBudgetV3(ctx, sm, TaskChainArgs{TaskID: "T", Summary: "用户同意再试一次"}); res.IsError {
+		t.Fatalf("ack_budget failed: %+v", res.Content)
+	}
+	if chain.Budget.MaxRetriesTotal != 2 || chain.Budget.MaxSteps != 4 {
+		t.Errorf("ack should only extend exhausted limits: %+v", chain.Budget)
+	}
+	if err := checkStepBudgetGate(chain); err != nil {
+		t.Errorf("budget should be available after ack: %v", err)
+	}
+}
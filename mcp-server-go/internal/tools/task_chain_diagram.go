@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 任务链状态机可视化 (Mermaid) ==========
+// export(format="mermaid") 把协议链渲染为 stateDiagram：阶段、gate 的 pass/fail 跳转、
+// loop 子任务进度与当前位置，保存在数据目录 chain_diagrams/<task_id>.mmd（每次导出覆盖为最新状态）。
+
+// exportChainDiagramV3 渲染并保存任务链的 Mermaid 状态图
+func exportChainDiagramV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	if strings.TrimSpace(args.TaskID) == "" {
+		return mcp.NewToolResultError("export 需要提供 task_id"), nil
+	}
+	chain, err := getOrLoadV3Chain(ctx, sm, args.TaskID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	diagram := renderChainMermaid(chain)
+
+	path := strings.TrimSpace(args.File)
+	if path == "" {
+		path = core.DataPath(sm.ProjectRoot, "chain_diagrams", safeFileName(chain.TaskID)+".mmd")
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(sm.ProjectRoot, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("创建目录失败: %v", err)), nil
+	}
+	if err := os.WriteFile(path, []byte(diagram), 0644); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("写入状态图失败: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("🗺️ 任务链 %s 状态图已保存: %s\n\n```mermaid\n%s```\n",
+		chain.TaskID, filepath.ToSlash(path), diagram)), nil
+}
+
+// renderChainMermaid 生成 stateDiagram-v2 文本
+func renderChainMermaid(chain *TaskChainV3) string {
+	var sb strings.Builder
+	sb.WriteString("stateDiagram-v2\n")
+	sb.WriteString("    direction LR\n")
+	if len(chain.Phases) == 0 {
+		sb.WriteString("    [*] --> [*]\n")
+		return sb.String()
+	}
+
+	node := mermaidStateID
+	classes := make(map[string][]string)
+
+	for _, p := range chain.Phases {
+		label := p.Name
+		if label == "" {
+			label = p.ID
+		}
+		switch p.Type {
+		case PhaseGate:
+			label = "◆ " + label
+		case PhaseLoop:
+			done := 0
+			for _, s := range p.SubTasks {
+				if s.Status == SubTaskPassed || s.Status == SubTaskFailed {
+					done++
+				}
+			}
+			mode := "↻"
+			if p.Parallel {
+				mode = "∥"
+			}
+			label = fmt.Sprintf("%s %s (%d/%d)", mode, label, done, len(p.SubTasks))
+		}
+		sb.WriteString(fmt.Sprintf("    state \"%s\" as %s\n", mermaidLabel(label), node(p.ID)))
+
+		cls := string(p.Status)
+		if p.ID == chain.CurrentPhase && chain.Status != "finished" {
+			cls = "current"
+		}
+		classes[cls] = append(classes[cls], node(p.ID))
+	}
+
+	sb.WriteString(fmt.Sprintf("    [*] --> %s\n", node(chain.Phases[0].ID)))
+	for i, p := range chain.Phases {
+		next := "[*]"
+		if i+1 < len(chain.Phases) {
+			next = node(chain.Phases[i+1].ID)
+		}
+		if p.Type != PhaseGate {
+			sb.WriteString(fmt.Sprintf("    %s --> %s\n", node(p.ID), next))
+			continue
+		}
+		pass := next
+		if p.OnPass != "" {
+			pass = node(p.OnPass)
+		}
+		sb.WriteString(fmt.Sprintf("    %s --> %s : pass\n", node(p.ID), pass))
+		if p.OnFail != "" {
+			maxRetries := p.MaxRetries
+			if maxRetries <= 0 {
+				maxRetries = 3
+			}
+			sb.WriteString(fmt.Sprintf("    %s --> %s : fail (%d/%d)\n", node(p.ID), node(p.OnFail), p.RetryCount, maxRetries))
+		}
+	}
+
+	styles := []struct{ class, style string }{
+		{"passed", "fill:#d1fae5,stroke:#059669"},
+		{"failed", "fill:#fee2e2,stroke:#dc2626"},
+		{"skipped", "fill:#f3f4f6,stroke:#9ca3af,color:#6b7280"},
+		{"active", "fill:#dbeafe,stroke:#2563eb"},
+		{"current", "fill:#fde68a,stroke:#d97706,stroke-width:3px"},
+	}
+	for _, st := range styles {
+		if ids := classes[st.class]; len(ids) > 0 {
+			sb.WriteString(fmt.Sprintf("    classDef %s %s\n", st.class, st.style))
+			sb.WriteString(fmt.Sprintf("    class %s %s\n", strings.Join(ids, ","), st.class))
+		}
+	}
+	return sb.String()
+}
+
+// mermaidStateID 状态 ID 只允许字母数字与下划线
+func mermaidStateID(id string) string {
+	var sb strings.Builder
+	for _, r := range id {
+		if r == '_' || (r < 128 && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "phase"
+	}
+	return "s_" + sb.String()
+}
+
+func mermaidLabel(s string) string {
+	return strings.NewReplacer(`"`, "'", "\n", " ").Replace(s)
+}
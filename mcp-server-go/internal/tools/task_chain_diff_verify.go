@@ -0,0 +1,310 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== 完成签名核对：summary 声称的文件修改 vs git 实际变更 ==========
+//
+// 阶段/子任务开始时记录 git 基线（HEAD + 已有未提交改动的文件哈希），complete 时
+// 对比 summary 中提到的文件与基线之后的实际变更，在决策点标出不一致，及早发现"幻觉进度"。
+
+const gitBaselineMaxHashBytes = 5 << 20
+
+// GitBaseline 阶段开始时的 git 状态
+type GitBaseline struct {
+	Head  string            `json:"head,omitempty"`
+	Dirty map[string]string `json:"dirty,omitempty"` // 开始时已有未提交改动的文件（仓库相对路径）→ 内容哈希
+}
+
+// DiffVerification 核对结果（路径均为项目相对路径）
+type DiffVerification struct {
+	Changed            []string
+	Claimed            []string
+	ClaimedUnchanged   []string // summary 声称修改但实际无变更
+	ChangedUnmentioned []string // 实际有变更但 summary 未提及
+}
+
+// claimedFileRe summary 中形如 path/to/file.ext 的文件引用
+var claimedFileRe = regexp.MustCompile(`[A-Za-z0-9_\-./\\]+\.([A-Za-z]{1,6})\b`)
+
+var claimedFileExts = map[string]bool{
+	"go": true, "py": true, "js": true, "ts": true, "tsx": true, "jsx": true, "mjs": true, "vue": true,
+	"rs": true, "java": true, "kt": true, "swift": true, "rb": true, "php": true, "cs": true,
+	"c": true, "h": true, "cc": true, "cpp": true, "hpp": true, "sh": true, "ps1": true, "sql": true,
+	"html": true, "css": true, "scss": true, "md": true, "json": true, "yaml": true, "yml": true,
+	"toml": true, "xml": true, "proto": true, "mod": true, "txt": true, "gradle": true,
+}
+
+// diffVerifyEnabled complete 参数显式开启，或 settings.json task_chain.verify_summary
+func diffVerifyEnabled(sm *SessionManager, explicit bool) bool {
+	if explicit {
+		return true
+	}
+	return sm.ProjectRoot != "" && core.LoadProjectSettings(sm.ProjectRoot).TaskChain.VerifySummary
+}
+
+func runGit(projectRoot string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", projectRoot}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func gitTopLevel(projectRoot string) (string, error) {
+	out, err := runGit(projectRoot, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(out))), nil
+}
+
+// gitDirtyFiles 未提交改动（含未跟踪文件）的仓库相对路径
+func gitDirtyFiles(projectRoot string) ([]string, error) {
+	out, err := runGit(projectRoot, "status", "--porcelain=v1", "-z", "-uall")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		e := entries[i]
+		if len(e) < 4 {
+			continue
+		}
+		files = append(files, e[3:])
+		if e[0] == 'R' || e[0] == 'C' {
+			i++ // 重命名/复制的原路径
+		}
+	}
+	return files, nil
+}
+
+func hashWorkFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return "deleted"
+	}
+	defer f.Close()
+	if st, err := f.Stat(); err == nil && st.Size() > gitBaselineMaxHashBytes {
+		return fmt.Sprintf("large:%d:%d", st.Size(), st.ModTime().UnixNano())
+	}
+	h := fnv.New64a()
+	_, _ = io.Copy(h, f)
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// captureGitBaseline 记录当前 git 状态；非 git 仓库或 git 不可用时返回 nil
+func captureGitBaseline(projectRoot string) *GitBaseline {
+	if projectRoot == "" {
+		return nil
+	}
+	top, err := gitTopLevel(projectRoot)
+	if err != nil {
+		return nil
+	}
+	base := &GitBaseline{Dirty: make(map[string]string)}
+	if out, err := runGit(projectRoot, "rev-parse", "HEAD"); err == nil {
+		base.Head = strings.TrimSpace(string(out))
+	}
+	dirty, err := gitDirtyFiles(projectRoot)
+	if err != nil {
+		return nil
+	}
+	for _, f := range dirty {
+		base.Dirty[f] = hashWorkFile(filepath.Join(top, filepath.FromSlash(f)))
+	}
+	return base
+}
+
+// gitChangedSince 基线之后变更的文件（项目相对路径）；基线为空时退化为全部未提交改动
+func gitChangedSince(projectRoot string, base *GitBaseline) ([]string, error) {
+	top, err := gitTopLevel(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool)
+
+	if base != nil && base.Head != "" {
+		if out, err := runGit(projectRoot, "rev-parse", "HEAD"); err == nil && strings.TrimSpace(string(out)) != base.Head {
+			diff, err := runGit(projectRoot, "diff", "--name-only", base.Head, "HEAD")
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range strings.Split(strings.TrimSpace(string(diff)), "\n") {
+				if f != "" {
+					changed[f] = true
+				}
+			}
+		}
+	}
+
+	dirty, err := gitDirtyFiles(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	stillDirty := make(map[string]bool, len(dirty))
+	for _, f := range dirty {
+		stillDirty[f] = true
+		if base != nil {
+			if h, ok := base.Dirty[f]; ok && h == hashWorkFile(filepath.Join(top, filepath.FromSlash(f))) {
+				continue // 开始前已有的改动，本阶段未再触碰
+			}
+		}
+		changed[f] = true
+	}
+	if base != nil {
+		for f := range base.Dirty {
+			if !stillDirty[f] {
+				changed[f] = true // 开始时有改动、现在已还原
+			}
+		}
+	}
+
+	var result []string
+	for f := range changed {
+		rel := f
+		if r, err := filepath.Rel(projectRoot, filepath.Join(top, filepath.FromSlash(f))); err == nil && !strings.HasPrefix(r, "..") {
+			rel = filepath.ToSlash(r)
+		}
+		if isMPMArtifact(rel) {
+			continue
+		}
+		result = append(result, rel)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// isMPMArtifact MPM 自身生成的文件（数据目录、开发日志），不计入任务变更
+func isMPMArtifact(rel string) bool {
+	return strings.HasPrefix(rel, core.LegacyDataDirName+"/") || strings.HasPrefix(rel, ".mcp-config/") ||
+		rel == "dev-log.md" || strings.HasPrefix(rel, "dev-log-archive/") || rel == "_MPM_PROJECT_RULES.md"
+}
+
+// extractClaimedFiles 提取 summary 中提到的文件路径
+func extractClaimedFiles(summary string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, m := range claimedFileRe.FindAllStringSubmatch(summary, -1) {
+		if !claimedFileExts[strings.ToLower(m[1])] {
+			continue
+		}
+		f := strings.TrimPrefix(strings.TrimPrefix(strings.ReplaceAll(m[0], "\\", "/"), "./"), "/")
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		files = append(files, f)
+	}
+	return files
+}
+
+func pathMatches(changed, claimed string) bool {
+	return changed == claimed || strings.HasSuffix(changed, "/"+claimed)
+}
+
+// verifySummaryAgainstGit 对比 summary 声称的修改与 git 实际变更
+func verifySummaryAgainstGit(projectRoot string, base *GitBaseline, summary string) (*DiffVerification, error) {
+	changed, err := gitChangedSince(projectRoot, base)
+	if err != nil {
+		return nil, err
+	}
+	v := &DiffVerification{Changed: changed, Claimed: extractClaimedFiles(summary)}
+	for _, c := range v.Claimed {
+		found := false
+		for _, f := range changed {
+			if pathMatches(f, c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.ClaimedUnchanged = append(v.ClaimedUnchanged, c)
+		}
+	}
+	for _, f := range changed {
+		mentioned := false
+		for _, c := range v.Claimed {
+			if pathMatches(f, c) {
+				mentioned = true
+				break
+			}
+		}
+		if !mentioned {
+			v.ChangedUnmentioned = append(v.ChangedUnmentioned, f)
+		}
+	}
+	return v, nil
+}
+
+// renderDiffVerification 决策点中的核对结果；非 git 仓库时返回空串
+func renderDiffVerification(projectRoot string, base *GitBaseline, summary string) string {
+	v, err := verifySummaryAgainstGit(projectRoot, base, summary)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("🧾 Summary/Git 核对")
+	if base == nil {
+		sb.WriteString("（无阶段基线，对比全部未提交改动）")
+	}
+	sb.WriteString(fmt.Sprintf(": 实际变更 %d 个文件，summary 提及 %d 个\n", len(v.Changed), len(v.Claimed)))
+	if len(v.ClaimedUnchanged) == 0 && len(v.ChangedUnmentioned) == 0 {
+		sb.WriteString("  ✅ 一致\n\n")
+		return sb.String()
+	}
+	writeList := func(title string, files []string) {
+		if len(files) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("  ⚠️ %s (%d):\n", title, len(files)))
+		for i, f := range files {
+			if i >= 10 {
+				sb.WriteString(fmt.Sprintf("    ... 另有 %d 个\n", len(files)-i))
+				break
+			}
+			sb.WriteString("    - " + f + "\n")
+		}
+	}
+	writeList("声称修改但无变更", v.ClaimedUnchanged)
+	writeList("有变更但 summary 未提及", v.ChangedUnmentioned)
+	sb.WriteString("  → 确认进度属实后再继续；若 summary 有误，请补充修改或更正说明。\n\n")
+	return sb.String()
+}
+
+// markGitBaseline 在 execute 阶段或子任务开始时记录基线（gate/loop 阶段本身不产生修改）
+func markGitBaseline(sm *SessionManager, chain *TaskChainV3, phaseID, subID string) {
+	p := chain.findPhase(phaseID)
+	if p == nil {
+		return
+	}
+	if subID == "" {
+		if p.Type == PhaseExecute {
+			p.GitBaseline = captureGitBaseline(sm.ProjectRoot)
+		}
+		return
+	}
+	if sub := findSubTask(p, subID); sub != nil {
+		sub.GitBaseline = captureGitBaseline(sm.ProjectRoot)
+	}
+}
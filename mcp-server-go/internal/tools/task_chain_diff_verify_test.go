@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerifySummaryAgainstGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("a.go", "package a\n")
+	write("b.go", "package a\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	write("b.go", "package a // dirty before phase\n")
+	base := captureGitBaseline(root)
+	if base == nil || base.Head == "" || len(base.Dirty) != 1 {
+		t.Fatalf("unexpected baseline: %+v", base)
+	}
+
+	write("a.go", "package a\n\nfunc A() {}\n")
+	write("c.go", "package a\n")
+
+	v, err := verifySummaryAgainstGit(root, base, "修改了 a.go 并更新 docs/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v.Changed, []string{"a.go", "c.go"}) {
+		t.Errorf("changed = %v", v.Changed)
+	}
+	if !reflect.DeepEqual(v.ClaimedUnchanged, []string{"docs/README.md"}) {
+		t.Errorf("claimed unchanged = %v", v.ClaimedUnchanged)
+	}
+	if !reflect.DeepEqual(v.ChangedUnmentioned, []string{"c.go"}) {
+		t.Errorf("changed unmentioned = %v", v.ChangedUnmentioned)
+	}
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 协议对照实验 ==========
+
+// recordExperimentOutcome 任务链结束或失败时写入实验结果（未参与实验的链由存储层忽略）
+func recordExperimentOutcome(ctx context.Context, sm *SessionManager, chain *TaskChainV3, eventType string) {
+	if sm.Memory == nil {
+		return
+	}
+	if eventType != "finish" && !(eventType == "fail" && chain.Status == "failed") {
+		return
+	}
+	retries := 0
+	for _, p := range chain.Phases {
+		retries += p.RetryCount
+	}
+	_ = sm.Memory.FinishExperimentRun(ctx, chain.TaskID, chain.Status, retries, chain.ReinitCount)
+}
+
+// experimentStatsV3 渲染实验对比视图
+func experimentStatsV3(ctx context.Context, sm *SessionManager, experiment string) (*mcp.CallToolResult, error) {
+	if sm.Memory == nil {
+		return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+	}
+	stats, err := sm.Memory.ExperimentStats(ctx, experiment)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("查询实验数据失败: %v", err)), nil
+	}
+	if len(stats) == 0 {
+		return mcp.NewToolResultText("暂无实验数据。init 时传入 experiment=\"实验名\" 即可将任务链纳入对照实验。"), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### 🧪 协议对照实验\n\n")
+	sb.WriteString("| 实验 | 协议 | 运行 | 完成 | 失败 | 平均耗时 | 平均重试 | 平均 re-init |\n")
+	sb.WriteString("|------|------|------|------|------|----------|----------|--------------|\n")
+	for _, s := range stats {
+		avg := (time.Duration(s.AvgDurationSec) * time.Second).Round(time.Second)
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | %d | %d | %s | %.1f | %.1f |\n",
+			s.Experiment, s.Protocol, s.Runs, s.Finished, s.Failed, avg, s.AvgRetries, s.AvgReinits))
+	}
+	sb.WriteString("\n平均耗时仅统计已结束的运行；样本量较小时结论仅供参考。\n")
+	return mcp.NewToolResultText(sb.String()), nil
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== task_chain export / import ==========
+// 导出完整任务链（记录、阶段、子任务、事件、summary 附件）为 JSON，可在其他机器/项目中原样恢复，
+// 用于迁移长期任务或在问题报告中附上出错的任务链。
+
+const chainExportFormat = "mpm-task-chain"
+const chainExportVersion = 1
+
+// TaskChainExport 任务链导出包
+type TaskChainExport struct {
+	Format      string                `json:"format"`
+	Version     int                   `json:"version"`
+	ExportedAt  string                `json:"exported_at"`
+	SourceRoot  string                `json:"source_root,omitempty"`
+	Chain       core.TaskChainRecord  `json:"chain"`
+	Phases      []Phase               `json:"phases"` // 与 chain.phases_json 相同，便于阅读
+	Events      []core.TaskChainEvent `json:"events"`
+	Attachments map[string]string     `json:"attachments,omitempty"` // 截断 summary 的全文附件：文件名 → 内容
+}
+
+// exportTaskChainV3 导出任务链到 file（默认数据目录 exports/<task_id>-<时间>.json）
+func exportTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	if sm.Memory == nil {
+		return mcp.NewToolResultError("记忆层尚未初始化"), nil
+	}
+	if strings.TrimSpace(args.TaskID) == "" {
+		return mcp.NewToolResultError("export 需要提供 task_id"), nil
+	}
+	chain, err := getOrLoadV3Chain(ctx, sm, args.TaskID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// 内存中的最新状态先落库，导出与 DB 一致
+	if err := persistV3Chain(ctx, sm, chain, "", "", "", ""); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("保存任务链失败: %v", err)), nil
+	}
+	rec, err := sm.Memory.LoadTaskChain(ctx, chain.TaskID)
+	if err != nil || rec == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("加载任务链记录失败: %v", err)), nil
+	}
+	events, err := sm.Memory.QueryTaskChainEvents(ctx, chain.TaskID, 1000000)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("加载任务链事件失败: %v", err)), nil
+	}
+
+	bundle := TaskChainExport{
+		Format:      chainExportFormat,
+		Version:     chainExportVersion,
+		ExportedAt:  time.Now().Format(time.RFC3339),
+		SourceRoot:  filepath.ToSlash(sm.ProjectRoot),
+		Chain:       *rec,
+		Phases:      chain.Phases,
+		Events:      events,
+		Attachments: readChainAttachments(sm, chain.TaskID),
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("JSON 序列化失败: %v", err)), nil
+	}
+
+	path := strings.TrimSpace(args.File)
+	if path == "" {
+		path = core.DataPath(sm.ProjectRoot, "exports", fmt.Sprintf("%s-%s.json", safeFileName(chain.TaskID), time.Now().Format("20060102-150405")))
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(sm.ProjectRoot, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("创建目录失败: %v", err)), nil
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("写入导出文件失败: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("📦 任务链 %s 已导出: %s\n阶段 %d 个 / 事件 %d 条 / 附件 %d 个\n导入: task_chain(mode=\"import\", file=\"...\"[, task_id=\"新ID\"])",
+		chain.TaskID, filepath.ToSlash(path), len(chain.Phases), len(events), len(bundle.Attachments))), nil
+}
+
+// importTaskChainV3 从导出文件恢复任务链；task_id 非空时以新 ID 导入
+func importTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	if sm.Memory == nil {
+		return mcp.NewToolResultError("记忆层尚未初始化"), nil
+	}
+	path := strings.TrimSpace(args.File)
+	if path == "" {
+		return mcp.NewToolResultError("import 需要提供 file（export 生成的 JSON 文件）"), nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(sm.ProjectRoot, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("读取导出文件失败: %v", err)), nil
+	}
+	var bundle TaskChainExport
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("解析导出文件失败: %v", err)), nil
+	}
+	if bundle.Format != chainExportFormat || bundle.Version > chainExportVersion {
+		return mcp.NewToolResultError(fmt.Sprintf("不支持的导出格式: %s v%d", bundle.Format, bundle.Version)), nil
+	}
+	if _, err := UnmarshalPhases(bundle.Chain.PhasesJSON); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("导出文件中的 phases 无效: %v", err)), nil
+	}
+
+	rec := bundle.Chain
+	originalID := rec.TaskID
+	if id := strings.TrimSpace(args.TaskID); id != "" {
+		rec.TaskID = id
+	}
+	events := bundle.Events
+	if len(bundle.Attachments) > 0 {
+		// summary 中的附件路径指向导出方的数据目录与原 task_id，改写为本项目的附件目录
+		newDir := filepath.ToSlash(core.DataPath(sm.ProjectRoot, "chain_attachments", safeFileName(rec.TaskID)))
+		rec.PhasesJSON = rewriteAttachmentRefs(rec.PhasesJSON, originalID, newDir)
+		events = make([]core.TaskChainEvent, len(bundle.Events))
+		for i, evt := range bundle.Events {
+			evt.Payload = rewriteAttachmentRefs(evt.Payload, originalID, newDir)
+			events[i] = evt
+		}
+	}
+	if err := sm.Memory.ImportTaskChain(ctx, &rec, events); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("导入失败: %v（可传 task_id 以新 ID 导入）", err)), nil
+	}
+	writeChainAttachments(sm, rec.TaskID, bundle.Attachments)
+	ensureV3Map(sm)
+	delete(sm.TaskChainsV3, rec.TaskID) // 下次访问从 DB 重新加载
+
+	payload, _ := json.Marshal(map[string]string{"source_task_id": originalID, "source_root": bundle.SourceRoot, "exported_at": bundle.ExportedAt})
+	_, _ = sm.Memory.AppendTaskChainEvent(ctx, &core.TaskChainEvent{
+		TaskID: rec.TaskID, EventType: "imported", Payload: string(payload), Holder: sessionHolder(),
+	})
+	return mcp.NewToolResultText(fmt.Sprintf("📥 已导入任务链 %s（来源 %s，状态 %s，当前阶段 %s）\n事件 %d 条 / 附件 %d 个\n继续: task_chain(mode=\"resume\", task_id=\"%s\")",
+		rec.TaskID, originalID, rec.Status, fallback(rec.CurrentPhase, "-"), len(bundle.Events), len(bundle.Attachments), rec.TaskID)), nil
+}
+
+// rewriteAttachmentRefs 把 "见附件: <旧数据目录>/chain_attachments/<旧ID>/" 形式的引用改写到 newDir
+func rewriteAttachmentRefs(s, oldTaskID, newDir string) string {
+	re := regexp.MustCompile(`(见附件: )[^\]\n"]*?chain_attachments/` + regexp.QuoteMeta(safeFileName(oldTaskID)) + `/`)
+	return re.ReplaceAllLiteralString(s, "见附件: "+newDir+"/")
+}
+
+func readChainAttachments(sm *SessionManager, taskID string) map[string]string {
+	dir := core.DataPath(sm.ProjectRoot, "chain_attachments", safeFileName(taskID))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, e.Name())); err == nil {
+			out[e.Name()] = string(data)
+		}
+	}
+	return out
+}
+
+func writeChainAttachments(sm *SessionManager, taskID string, attachments map[string]string) {
+	if len(attachments) == 0 {
+		return
+	}
+	dir := core.DataPath(sm.ProjectRoot, "chain_attachments", safeFileName(taskID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	for name, content := range attachments {
+		_ = os.WriteFile(filepath.Join(dir, filepath.Base(name)), []byte(content), 0644)
+	}
+}
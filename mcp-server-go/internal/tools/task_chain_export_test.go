@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestTaskChainExportImport_RenamesAttachmentRefs(t *testing.T) {
+	ctx := context.Background()
+	newSession := func() *SessionManager {
+		root := t.TempDir()
+		mem, err := core.NewMemoryLayer(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(mem.FlushDevLog)
+		settings := core.DefaultProjectSettings()
+		settings.TaskChain.MaxSummaryChars = 20
+		if err := core.SaveProjectSettings(root, settings); err != nil {
+			t.Fatal(err)
+		}
+		return &SessionManager{ProjectRoot: root, Memory: mem, TaskChainsV3: map[string]*TaskChainV3{}}
+	}
+
+	src := newSession()
+	chain := &TaskChainV3{TaskID: "T1", Status: "running", CurrentPhase: "check", Phases: []Phase{
+		{ID: "impl", Type: PhaseExecute, Status: PhasePassed},
+		{ID: "check", Type: PhaseGate, Status: PhaseActive},
+	}}
+	src.TaskChainsV3["T1"] = chain
+	full := strings.Repeat("很长的验证输出 ", 10)
+	stored, _, rejected := enforceSummaryLimit(src, "T1", "impl", "", full)
+	if rejected != nil || !strings.Contains(stored, "chain_attachments/T1/") {
+		t.Fatalf("summary should be truncated into an attachment: %q", stored)
+	}
+	chain.Phases[0].Summary = stored
+	payload, _ := json.Marshal(map[string]string{"result": "pass", "summary": stored})
+	if err := persistV3Chain(ctx, src, chain, "complete", "impl", "", string(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "t1.json")
+	if res, _ := exportTaskChainV3(ctx, src, TaskChainArgs{TaskID: "T1", File: file}); res.IsError {
+		t.Fatalf("export: %v", res.Content)
+	}
+	srcEvents, _ := src.Memory.QueryTaskChainEvents(ctx, "T1", 100)
+
+	dst := newSession()
+	res, _ := importTaskChainV3(ctx, dst, TaskChainArgs{File: file, TaskID: "T2"})
+	if res.IsError {
+		t.Fatalf("import: %s", res.Content[0].(mcp.TextContent).Text)
+	}
+
+	wantDir := filepath.ToSlash(core.DataPath(dst.ProjectRoot, "chain_attachments", "T2")) + "/"
+	imported, err := getOrLoadV3Chain(ctx, dst, "T2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Phases) != 2 || imported.Phases[1].Status != PhaseActive {
+		t.Fatalf("phases not restored: %+v", imported.Phases)
+	}
+	sum := imported.Phases[0].Summary
+	if !strings.Contains(sum, "见附件: "+wantDir) || strings.Contains(sum, src.ProjectRoot) {
+		t.Fatalf("phase summary should reference the imported attachment dir %s: %q", wantDir, sum)
+	}
+	ref := sum[strings.Index(sum, wantDir) : len(sum)-1] // 去掉结尾的 ]
+	if data, err := os.ReadFile(filepath.FromSlash(ref)); err != nil || string(data) != full {
+		t.Fatalf("attachment %s not restored: %v", ref, err)
+	}
+
+	events, _ := dst.Memory.QueryTaskChainEvents(ctx, "T2", 100)
+	if len(events) != len(srcEvents)+1 {
+		t.Fatalf("events = %d, want %d exported + imported", len(events), len(srcEvents))
+	}
+	var rewritten bool
+	for _, e := range events {
+		if strings.Contains(e.Payload, "chain_attachments/T1/") {
+			t.Errorf("event payload still references the source attachment: %s", e.Payload)
+		}
+		rewritten = rewritten || strings.Contains(e.Payload, wantDir)
+	}
+	if !rewritten {
+		t.Error("complete event payload should reference the imported attachment dir")
+	}
+}
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== task_chain history：事件回放 ==========
+// 把 task_chain_events 按时间回放为可读的执行轨迹（谁/何时/哪个阶段/gate 结果/重试），
+// resume 时附上最近几条，让接手的会话知道任务是怎么走到当前状态的。
+
+// resumeHistoryEvents resume 输出附带的最近事件数
+const resumeHistoryEvents = 8
+
+// chainHistoryV3 history 模式：回放任务链事件，limit > 0 时只显示最近 limit 条
+func chainHistoryV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	if args.TaskID == "" {
+		return mcp.NewToolResultError("history 模式需要 task_id 参数"), nil
+	}
+	if sm.Memory == nil {
+		return mcp.NewToolResultError("记忆层尚未初始化"), nil
+	}
+	events, err := sm.Memory.QueryTaskChainEvents(ctx, args.TaskID, 1000000)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("加载任务链事件失败: %v", err)), nil
+	}
+	if len(events) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("任务链 %s 没有事件记录", args.TaskID)), nil
+	}
+
+	lines := replayChainEvents(events)
+	shown := lines
+	if args.Limit > 0 && len(lines) > args.Limit {
+		shown = lines[len(lines)-args.Limit:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### 📜 任务链 %s 执行轨迹\n\n", args.TaskID))
+	if len(shown) < len(lines) {
+		sb.WriteString(fmt.Sprintf("_（省略前 %d 条，显示最近 %d 条）_\n", len(lines)-len(shown), len(shown)))
+	}
+	for _, l := range shown {
+		sb.WriteString(l + "\n")
+	}
+	sb.WriteString("\n" + chainHistoryStats(events))
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+// recentChainHistory resume 输出中附带的最近事件
+func recentChainHistory(ctx context.Context, sm *SessionManager, taskID string) string {
+	if sm.Memory == nil || taskID == "" {
+		return ""
+	}
+	events, err := sm.Memory.RecentTaskChainEvents(ctx, taskID, resumeHistoryEvents)
+	if err != nil || len(events) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📜 最近 %d 条事件（完整轨迹: task_chain(mode=\"history\", task_id=\"%s\")）:\n", len(events), taskID))
+	for _, l := range replayChainEvents(events) {
+		sb.WriteString(l + "\n")
+	}
+	return sb.String()
+}
+
+// replayChainEvents 将事件转为逐行描述；会话标识只在变化时显示，gate 失败按阶段标注第几次
+func replayChainEvents(events []core.TaskChainEvent) []string {
+	lines := make([]string, 0, len(events))
+	gateFails := make(map[string]int)
+	lastHolder := ""
+	for _, evt := range events {
+		line := "- " + formatEventTime(evt.CreatedAt)
+		if evt.Holder != "" && evt.Holder != lastHolder {
+			line += fmt.Sprintf(" [%s]", evt.Holder)
+			lastHolder = evt.Holder
+		}
+		lines = append(lines, line+" "+describeChainEvent(evt, gateFails))
+	}
+	return lines
+}
+
+func formatEventTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("01-02 15:04:05")
+}
+
+// eventPayload 解析 complete/complete_sub 的 JSON payload
+func eventPayload(evt core.TaskChainEvent) map[string]string {
+	var m map[string]string
+	_ = json.Unmarshal([]byte(evt.Payload), &m)
+	return m
+}
+
+func describeChainEvent(evt core.TaskChainEvent, gateFails map[string]int) string {
+	switch evt.EventType {
+	case "init":
+		return fmt.Sprintf("🆕 初始化: %s", truncateRunes(evt.Payload, 80))
+	case "start":
+		return fmt.Sprintf("▶ 开始阶段 %s", evt.PhaseID)
+	case "complete":
+		p := eventPayload(evt)
+		summary := truncateRunes(p["summary"], 80)
+		switch p["result"] {
+		case "pass":
+			return fmt.Sprintf("✅ gate %s 通过: %s", evt.PhaseID, summary)
+		case "fail":
+			gateFails[evt.PhaseID]++
+			return fmt.Sprintf("❌ gate %s 未通过（第 %d 次）: %s", evt.PhaseID, gateFails[evt.PhaseID], summary)
+		}
+		return fmt.Sprintf("✔ 完成阶段 %s: %s", evt.PhaseID, summary)
+	case "fail":
+		return fmt.Sprintf("⛔ 阶段 %s 失败: %s", evt.PhaseID, truncateRunes(evt.Payload, 120))
+	case "spawn":
+		var subs []json.RawMessage
+		_ = json.Unmarshal([]byte(evt.Payload), &subs)
+		return fmt.Sprintf("🔀 阶段 %s 生成 %d 个子任务", evt.PhaseID, len(subs))
+	case "start_sub":
+		return fmt.Sprintf("▷ 开始子任务 %s/%s", evt.PhaseID, evt.SubID)
+	case "complete_sub":
+		p := eventPayload(evt)
+		mark := "✔"
+		if p["result"] == "fail" {
+			mark = "✗"
+		}
+		return fmt.Sprintf("%s 子任务 %s/%s: %s", mark, evt.PhaseID, evt.SubID, truncateRunes(p["summary"], 80))
+	case "impact":
+		return fmt.Sprintf("🎯 影响分析 %s", evt.Payload)
+	case "ack_risk":
+		return fmt.Sprintf("⚠️ 确认风险超支: %s", truncateRunes(evt.Payload, 80))
+	case "ack_budget":
+		return fmt.Sprintf("💰 追加执行预算: %s", truncateRunes(evt.Payload, 80))
+	case "takeover":
+		return fmt.Sprintf("🔐 接管任务链（原会话 %s）", evt.Payload)
+	case "imported":
+		return "📥 从导出文件导入"
+	case "finish":
+		return "🏁 任务链完成"
+	}
+	desc := "· " + evt.EventType
+	if evt.PhaseID != "" {
+		desc += " " + evt.PhaseID
+	}
+	if evt.Payload != "" {
+		desc += ": " + truncateRunes(evt.Payload, 80)
+	}
+	return desc
+}
+
+// chainHistoryStats 事件统计：总数、gate 失败次数、参与会话、时间跨度
+func chainHistoryStats(events []core.TaskChainEvent) string {
+	fails := 0
+	holders := make(map[string]bool)
+	for _, evt := range events {
+		if evt.EventType == "complete" && eventPayload(evt)["result"] == "fail" {
+			fails++
+		}
+		if evt.Holder != "" {
+			holders[evt.Holder] = true
+		}
+	}
+	s := fmt.Sprintf("共 %d 条事件，gate 未通过 %d 次，%d 个会话参与", len(events), fails, len(holders))
+	first, err1 := time.Parse(time.RFC3339, events[0].CreatedAt)
+	last, err2 := time.Parse(time.RFC3339, events[len(events)-1].CreatedAt)
+	if err1 == nil && err2 == nil {
+		s += fmt.Sprintf("，跨度 %s", last.Sub(first).Round(time.Second))
+	}
+	return s + "\n"
+}
@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestReplayChainEvents(t *testing.T) {
+	events := []core.TaskChainEvent{
+		{EventType: "init", Payload: "修复登录", Holder: "a:1", CreatedAt: "2024-05-01T10:00:00Z"},
+		{EventType: "start", PhaseID: "verify", Holder: "a:1", CreatedAt: "2024-05-01T10:01:00Z"},
+		{EventType: "complete", PhaseID: "verify", Payload: `{"result":"fail","summary":"测试失败"}`, Holder: "a:1", CreatedAt: "2024-05-01T10:05:00Z"},
+		{EventType: "takeover", Payload: "a:1", Holder: "b:2", CreatedAt: "2024-05-01T10:30:00Z"},
+		{EventType: "complete", PhaseID: "verify", Payload: `{"result":"fail","summary":"仍失败"}`, Holder: "b:2", CreatedAt: "2024-05-01T10:40:00Z"},
+	}
+	lines := replayChainEvents(events)
+	if len(lines) != len(events) {
+		t.Fatalf("lines = %d, want %d", len(lines), len(events))
+	}
+	if !strings.Contains(lines[0], "[a:1]") || strings.Contains(lines[1], "[a:1]") {
+		t.Errorf("holder should only be shown when it changes: %q / %q", lines[0], lines[1])
+	}
+	if !strings.Contains(lines[3], "[b:2]") || !strings.Contains(lines[3], "接管") {
+		t.Errorf("takeover line = %q", lines[3])
+	}
+	if !strings.Contains(lines[4], "第 2 次") {
+		t.Errorf("second gate failure should be numbered: %q", lines[4])
+	}
+	if stats := chainHistoryStats(events); !strings.Contains(stats, "gate 未通过 2 次") || !strings.Contains(stats, "2 个会话") {
+		t.Errorf("stats = %q", stats)
+	}
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 任务链计时与 metrics 报告 ==========
+// 阶段/子任务在 start 与 complete 时记录时间戳，随 phases_json 持久化。
+// metrics 模式据此给出总耗时、各阶段用时、重试开销与阶段间空闲，定位 agent 工作流卡在哪里。
+
+func (p *Phase) markStarted(now time.Time) {
+	if p.StartedAt.IsZero() {
+		p.StartedAt = now
+	}
+	p.AttemptAt = now
+	p.Attempts++
+}
+
+func (p *Phase) markCompleted(now time.Time) {
+	p.CompletedAt = now
+	if p.AttemptAt.IsZero() {
+		return
+	}
+	p.LastSec = int64(now.Sub(p.AttemptAt).Seconds())
+	p.SpentSec += p.LastSec
+	p.AttemptAt = time.Time{}
+}
+
+// spent 阶段累计用时，执行中的阶段计入本次已进行的时间
+func (p *Phase) spent(now time.Time) time.Duration {
+	d := time.Duration(p.SpentSec) * time.Second
+	if p.Status == PhaseActive && !p.AttemptAt.IsZero() {
+		d += now.Sub(p.AttemptAt)
+	}
+	return d
+}
+
+// retryOverhead 被重做的执行所花的时间（累计用时减去最近一次）
+func (p *Phase) retryOverhead() time.Duration {
+	if p.Attempts <= 1 {
+		return 0
+	}
+	return time.Duration(p.SpentSec-p.LastSec) * time.Second
+}
+
+func (s *SubTask) duration(now time.Time) time.Duration {
+	switch {
+	case s.StartedAt.IsZero():
+		return 0
+	case s.CompletedAt.IsZero():
+		if s.Status == SubTaskActive {
+			return now.Sub(s.StartedAt)
+		}
+		return 0
+	}
+	return s.CompletedAt.Sub(s.StartedAt)
+}
+
+// chainWallClock 首个阶段开始到最近完成（运行中则到现在）的时长
+func chainWallClock(chain *TaskChainV3, now time.Time) time.Duration {
+	var first, last time.Time
+	for _, p := range chain.Phases {
+		if !p.StartedAt.IsZero() && (first.IsZero() || p.StartedAt.Before(first)) {
+			first = p.StartedAt
+		}
+		if p.CompletedAt.After(last) {
+			last = p.CompletedAt
+		}
+	}
+	if first.IsZero() {
+		return 0
+	}
+	if chain.Status == "running" || last.IsZero() {
+		last = now
+	}
+	return last.Sub(first)
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
+
+// chainMetricsV3 metrics 模式：总耗时、各阶段用时与占比、重试开销、最慢子任务
+func chainMetricsV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	if args.TaskID == "" {
+		return mcp.NewToolResultError("metrics 模式需要 task_id 参数"), nil
+	}
+	chain, err := getOrLoadV3Chain(ctx, sm, args.TaskID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(renderChainMetrics(chain, time.Now())), nil
+}
+
+func renderChainMetrics(chain *TaskChainV3, now time.Time) string {
+	wall := chainWallClock(chain, now)
+	var active, overhead time.Duration
+	for i := range chain.Phases {
+		active += chain.Phases[i].spent(now)
+		overhead += chain.Phases[i].retryOverhead()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### ⏱ 任务链 %s 耗时统计 [%s]\n\n", chain.TaskID, chain.Status))
+	if wall == 0 {
+		sb.WriteString("_尚无计时数据（阶段开始后记录；旧版任务链没有时间戳）_\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("- 总耗时 (wall-clock): %s\n", formatDuration(wall)))
+	sb.WriteString(fmt.Sprintf("- 阶段执行累计: %s\n", formatDuration(active)))
+	if idle := wall - active; idle > time.Second {
+		sb.WriteString(fmt.Sprintf("- 阶段间空闲: %s (%.0f%%)\n", formatDuration(idle), pct(idle, wall)))
+	}
+	if overhead > 0 {
+		sb.WriteString(fmt.Sprintf("- 重试开销: %s (%.0f%%)\n", formatDuration(overhead), pct(overhead, wall)))
+	}
+
+	sb.WriteString("\n| 阶段 | 类型 | 状态 | 执行次数 | 用时 | 占比 | 重试开销 |\n|---|---|---|---|---|---|---|\n")
+	for i := range chain.Phases {
+		p := &chain.Phases[i]
+		d := p.spent(now)
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %s | %.0f%% | %s |\n",
+			p.ID, p.Type, p.Status, p.Attempts, formatDuration(d), pct(d, active), formatDuration(p.retryOverhead())))
+	}
+
+	type subTiming struct {
+		id string
+		d  time.Duration
+	}
+	var subs []subTiming
+	for _, p := range chain.Phases {
+		for _, s := range p.SubTasks {
+			if d := s.duration(now); d > 0 {
+				subs = append(subs, subTiming{p.ID + "/" + s.ID, d})
+			}
+		}
+	}
+	if len(subs) > 0 {
+		sort.Slice(subs, func(i, j int) bool { return subs[i].d > subs[j].d })
+		if len(subs) > 5 {
+			subs = subs[:5]
+		}
+		sb.WriteString("\n**最慢子任务**:\n")
+		for _, s := range subs {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", s.id, formatDuration(s.d)))
+		}
+	}
+	return sb.String()
+}
+
+func pct(part, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChainMetricsRetryOverhead(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	impl := Phase{ID: "implement", Type: PhaseExecute, Status: PhasePassed}
+	gate := Phase{ID: "verify", Type: PhaseGate, Status: PhasePassed}
+
+	impl.markStarted(t0)
+	impl.markCompleted(t0.Add(10 * time.Minute))
+	gate.markStarted(t0.Add(10 * time.Minute))
+	gate.markCompleted(t0.Add(15 * time.Minute)) // fail，回退重做
+	impl.markStarted(t0.Add(20 * time.Minute))
+	impl.markCompleted(t0.Add(25 * time.Minute))
+	gate.markStarted(t0.Add(25 * time.Minute))
+	gate.markCompleted(t0.Add(27 * time.Minute))
+
+	if impl.Attempts != 2 || impl.SpentSec != 15*60 || impl.retryOverhead() != 10*time.Minute {
+		t.Errorf("implement attempts=%d spent=%d overhead=%s", impl.Attempts, impl.SpentSec, impl.retryOverhead())
+	}
+	if !impl.StartedAt.Equal(t0) {
+		t.Errorf("StartedAt should keep the first start, got %s", impl.StartedAt)
+	}
+
+	chain := &TaskChainV3{TaskID: "T", Status: "finished", Phases: []Phase{impl, gate}}
+	if wall := chainWallClock(chain, t0.Add(time.Hour)); wall != 27*time.Minute {
+		t.Errorf("wall clock = %s, want 27m", wall)
+	}
+	out := renderChainMetrics(chain, t0.Add(time.Hour))
+	for _, want := range []string{"重试开销: 15m0s", "阶段间空闲: 5m0s", "| implement | execute | passed | 2 | 15m0s |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 任务链所有权（多会话互斥） ==========
+//
+// 多个客户端连接同一项目库时，同一条任务链只允许一个会话推进。start/complete/spawn/complete_sub
+// 会声明并续租所有权；租约未过期的链被其他会话持有时拒绝操作，需显式 takeover=true 接管。
+
+func chainLease(sm *SessionManager) time.Duration {
+	return time.Duration(core.LoadProjectSettings(sm.ProjectRoot).TaskChain.LeaseMinutes) * time.Minute
+}
+
+// ensureChainOwner 声明（续租）任务链所有权；被其他会话持有时返回拒绝结果，接管时记录 takeover 事件
+func ensureChainOwner(ctx context.Context, sm *SessionManager, taskID string, takeover bool) *mcp.CallToolResult {
+	if sm.Memory == nil || taskID == "" {
+		return nil
+	}
+	self := sessionHolder()
+	prev, err := sm.Memory.ClaimTaskChain(ctx, taskID, self, chainLease(sm), takeover)
+	var owned *core.TaskChainOwnedError
+	if errors.As(err, &owned) {
+		return mcp.NewToolResultError(fmt.Sprintf(
+			"任务链 %s 由会话 %s 持有，租约到期 %s。\n"+
+				"为避免两个会话同时修改同一条链，已拒绝本次操作。确认对方已停止后加 takeover=true 接管，"+
+				"或调用 task_chain(mode=\"claim\", task_id=\"%s\", takeover=true)。",
+			taskID, owned.Owner, formatLockExpiry(owned.LeaseUntil), taskID))
+	}
+	if err != nil {
+		// 链尚未持久化或库不可用时不阻塞操作
+		return nil
+	}
+	if takeover && prev != "" && prev != self {
+		_, _ = sm.Memory.AppendTaskChainEvent(ctx, &core.TaskChainEvent{
+			TaskID:    taskID,
+			EventType: "takeover",
+			Payload:   prev,
+			Holder:    self,
+		})
+	}
+	return nil
+}
+
+// claimChainV3 claim / heartbeat 模式：声明或续租任务链所有权
+// heartbeat 只为已持有的链续租，不会抢占过期的链
+func claimChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	if args.TaskID == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("%s 模式需要 task_id 参数", args.Mode)), nil
+	}
+	if sm.Memory == nil {
+		return mcp.NewToolResultError("记忆层尚未初始化"), nil
+	}
+	rec, err := sm.Memory.LoadTaskChain(ctx, args.TaskID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("加载任务链失败: %v", err)), nil
+	}
+	if rec == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("任务链 %s 不存在", args.TaskID)), nil
+	}
+	self := sessionHolder()
+	if args.Mode == "heartbeat" && rec.Owner != self {
+		return mcp.NewToolResultError(fmt.Sprintf("当前会话未持有任务链 %s（持有者: %s），请先 task_chain(mode=\"claim\")",
+			args.TaskID, fallback(rec.Owner, "无"))), nil
+	}
+	if res := ensureChainOwner(ctx, sm, args.TaskID, args.Takeover); res != nil {
+		return res, nil
+	}
+	until := time.Now().Add(chainLease(sm)).Format("01-02 15:04")
+	if args.Mode == "heartbeat" {
+		return mcp.NewToolResultText(fmt.Sprintf("💓 已续租任务链 %s，租约到期 %s", args.TaskID, until)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("🔐 会话 %s 已持有任务链 %s，租约到期 %s\n"+
+		"start/complete 会自动续租；长时间无操作时可用 task_chain(mode=\"heartbeat\") 保持所有权。", self, args.TaskID, until)), nil
+}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ========== loop 阶段的子任务依赖与并行调度 ==========
+//
+// 默认 loop 内子任务严格串行：完成一个才开始下一个。spawn 时可为子任务声明 depends_on，
+// 或传 parallel=true 让互不依赖的子任务同时进入 active；complete_sub 只校验声明的依赖，
+// 不再要求按顺序完成。依赖的子任务失败时，下游子任务直接标记为失败（未执行）。
+
+// unmetDeps 返回子任务尚未通过的依赖
+func unmetDeps(p *Phase, sub *SubTask) []string {
+	var blocking []string
+	for _, dep := range sub.DependsOn {
+		d := findSubTask(p, dep)
+		if d == nil || d.Status != SubTaskPassed {
+			blocking = append(blocking, dep)
+		}
+	}
+	return blocking
+}
+
+// ReadySubTasks 返回 loop 阶段中依赖已满足、可以开始的 pending 子任务
+func (tc *TaskChainV3) ReadySubTasks(phaseID string) []*SubTask {
+	p := tc.findPhase(phaseID)
+	if p == nil {
+		return nil
+	}
+	var ready []*SubTask
+	for i := range p.SubTasks {
+		if p.SubTasks[i].Status == SubTaskPending && len(unmetDeps(p, &p.SubTasks[i])) == 0 {
+			ready = append(ready, &p.SubTasks[i])
+		}
+	}
+	return ready
+}
+
+// skipDependents 依赖链上有子任务失败时，把无法再满足依赖的 pending 子任务标记为失败
+func skipDependents(p *Phase) {
+	for changed := true; changed; {
+		changed = false
+		for i := range p.SubTasks {
+			sub := &p.SubTasks[i]
+			if sub.Status != SubTaskPending {
+				continue
+			}
+			for _, dep := range sub.DependsOn {
+				if d := findSubTask(p, dep); d != nil && d.Status == SubTaskFailed {
+					sub.Status = SubTaskFailed
+					sub.Summary = fmt.Sprintf("依赖 %s 未通过，未执行", dep)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// validateSubTaskDeps 校验新子任务的 id 唯一、依赖存在且不成环
+func validateSubTaskDeps(existing, added []SubTask) error {
+	all := make(map[string]*SubTask, len(existing)+len(added))
+	for i := range existing {
+		all[existing[i].ID] = &existing[i]
+	}
+	for i := range added {
+		if _, dup := all[added[i].ID]; dup {
+			return fmt.Errorf("sub_task id '%s' 重复", added[i].ID)
+		}
+		all[added[i].ID] = &added[i]
+	}
+	for _, s := range added {
+		for _, dep := range s.DependsOn {
+			if dep == s.ID {
+				return fmt.Errorf("sub_task '%s' 不能依赖自身", s.ID)
+			}
+			if _, ok := all[dep]; !ok {
+				return fmt.Errorf("sub_task '%s' 依赖不存在的子任务 '%s'", s.ID, dep)
+			}
+		}
+	}
+
+	// DFS 检测环：0 未访问 / 1 访问中 / 2 已完成
+	state := make(map[string]int, len(all))
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case 1:
+			return fmt.Errorf("sub_task 依赖成环: %s → %s", strings.Join(path, " → "), id)
+		case 2:
+			return nil
+		}
+		state[id] = 1
+		path = append(path, id)
+		for _, dep := range all[id].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = 2
+		return nil
+	}
+	for _, s := range added {
+		if err := visit(s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDependsOn 兼容数组与逗号分隔字符串两种写法
+func parseDependsOn(v interface{}) []string {
+	var raw []string
+	switch deps := v.(type) {
+	case []interface{}:
+		for _, d := range deps {
+			raw = append(raw, fmt.Sprintf("%v", d))
+		}
+	case []string:
+		raw = deps
+	case string:
+		raw = strings.Split(deps, ",")
+	}
+	var out []string
+	for _, d := range raw {
+		if d = strings.TrimSpace(d); d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// startReadySubTasks 开始所有依赖已满足的 pending 子任务并逐个落库
+func startReadySubTasks(ctx context.Context, sm *SessionManager, chain *TaskChainV3, phaseID string) []*SubTask {
+	ready := chain.ReadySubTasks(phaseID)
+	for _, sub := range ready {
+		if err := chain.StartSubTask(phaseID, sub.ID); err != nil {
+			continue
+		}
+		markGitBaseline(sm, chain, phaseID, sub.ID)
+		_ = persistV3Chain(ctx, sm, chain, "start_sub", phaseID, sub.ID, "")
+	}
+	return ready
+}
+
+func renderStartedSubTasks(taskID, phaseID string, started []*SubTask) string {
+	if len(started) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n→ 可并行执行 %d 个子任务:\n", len(started)))
+	for _, s := range started {
+		sb.WriteString(fmt.Sprintf("  • %s「%s」\n", s.ID, s.Name))
+		if s.Verify != "" {
+			sb.WriteString(fmt.Sprintf("    验证命令: %s\n", s.Verify))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\n各自完成后调用（顺序不限）:\n  task_chain(mode=\"complete_sub\", task_id=\"%s\", phase_id=\"%s\", sub_id=\"<id>\", result=\"pass|fail\", summary=\"...\")\n",
+		taskID, phaseID))
+	return sb.String()
+}
+
+func renderDependsOn(s SubTask) string {
+	if len(s.DependsOn) == 0 {
+		return ""
+	}
+	return " ← " + strings.Join(s.DependsOn, ", ")
+}
+
+func hasSubTaskDeps(subs []SubTask) bool {
+	for _, s := range subs {
+		if len(s.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package tools
+
+import "testing"
+
+func TestParallelSubTasks_DependenciesOnly(t *testing.T) {
+	tc := &TaskChainV3{TaskID: "t", Phases: []Phase{{ID: "impl", Type: PhaseLoop, Status: PhaseActive}}}
+	subs := []SubTask{
+		{ID: "a", Name: "A"},
+		{ID: "b", Name: "B"},
+		{ID: "c", Name: "C", DependsOn: []string{"a", "b"}},
+		{ID: "d", Name: "D", DependsOn: []string{"c"}},
+	}
+	if err := tc.SpawnSubTasks("impl", subs, true); err != nil {
+		t.Fatal(err)
+	}
+	if ready := tc.ReadySubTasks("impl"); len(ready) != 2 {
+		t.Fatalf("ready = %d, want 2 (a, b)", len(ready))
+	}
+	if _, err := tc.CompleteSubTask("impl", "c", "pass", "x"); err == nil {
+		t.Fatal("c should be blocked by a, b")
+	}
+	// 并行 loop 中完成顺序不限
+	if _, err := tc.CompleteSubTask("impl", "b", "pass", "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.CompleteSubTask("impl", "a", "fail", "broken"); err != nil {
+		t.Fatal(err)
+	}
+	p := tc.findPhase("impl")
+	if c, d := findSubTask(p, "c"), findSubTask(p, "d"); c.Status != SubTaskFailed || d.Status != SubTaskFailed {
+		t.Errorf("dependents of failed sub should be skipped: c=%s d=%s", c.Status, d.Status)
+	}
+	if p.Status != PhasePassed {
+		t.Errorf("loop should be done, got %s", p.Status)
+	}
+}
+
+func TestSpawnSubTasks_RejectsCycle(t *testing.T) {
+	tc := &TaskChainV3{TaskID: "t", Phases: []Phase{{ID: "impl", Type: PhaseLoop, Status: PhaseActive}}}
+	subs := []SubTask{
+		{ID: "a", DependsOn: []string{"b"}},
+		{ID: "b", DependsOn: []string{"a"}},
+	}
+	if err := tc.SpawnSubTasks("impl", subs, false); err == nil {
+		t.Fatal("expected cycle error")
+	}
+}
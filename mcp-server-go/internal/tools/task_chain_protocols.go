@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-server-go/internal/core"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ========== 自定义协议 (.mcp-config/protocols.yaml) ==========
+//
+// 内置协议（linear/develop/debug/refactor）之外，团队可在项目级配置中声明自己的工作流：
+//
+//	protocols:
+//	  - name: review
+//	    description: 代码评审协议
+//	    phases:
+//	      - {id: read, name: 通读变更, type: execute}
+//	      - {id: comment, name: 逐条评审, type: loop}
+//	      - {id: approve_gate, name: 是否可合入？, type: gate, on_pass: done, on_fail: comment, max_retries: 2, verify: go test ./...}
+//	      - {id: done, name: 收尾, type: execute}
+//
+// 文件在每次 init / protocol 调用时重新读取，修改后无需重启。
+
+// builtinProtocols 内置协议名，自定义协议不得同名
+var builtinProtocols = []string{"linear", "develop", "debug", "refactor"}
+
+// ProtocolPhaseDef 自定义协议中的阶段定义
+type ProtocolPhaseDef struct {
+	ID         string `yaml:"id"`
+	Name       string `yaml:"name"`
+	Type       string `yaml:"type"` // execute / gate / loop，默认 execute
+	Input      string `yaml:"input"`
+	OnPass     string `yaml:"on_pass"`
+	OnFail     string `yaml:"on_fail"`
+	MaxRetries int    `yaml:"max_retries"`
+	Verify     string `yaml:"verify"` // gate 验证命令，配合 auto_verify 使用
+}
+
+// ProtocolDef 自定义协议
+type ProtocolDef struct {
+	Name        string             `yaml:"name"`
+	Description string             `yaml:"description"`
+	Phases      []ProtocolPhaseDef `yaml:"phases"`
+}
+
+type protocolsFile struct {
+	Protocols []ProtocolDef `yaml:"protocols"`
+}
+
+func protocolsPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".mcp-config", "protocols.yaml")
+}
+
+// loadCustomProtocols 读取并校验项目自定义协议；文件不存在时返回 nil
+func loadCustomProtocols(projectRoot string) ([]ProtocolDef, error) {
+	if projectRoot == "" || !core.FeatureEnabled(projectRoot, "custom_protocols") {
+		return nil, nil
+	}
+	data, err := os.ReadFile(protocolsPath(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f protocolsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析 protocols.yaml 失败: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range f.Protocols {
+		p := &f.Protocols[i]
+		p.Name = strings.TrimSpace(p.Name)
+		if p.Name == "" {
+			return nil, fmt.Errorf("protocols.yaml 第 %d 个协议缺少 name", i+1)
+		}
+		for _, b := range builtinProtocols {
+			if p.Name == b || p.Name == "custom" {
+				return nil, fmt.Errorf("protocols.yaml: 协议名 %q 与内置协议冲突", p.Name)
+			}
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("protocols.yaml: 协议 %q 重复定义", p.Name)
+		}
+		seen[p.Name] = true
+		if err := validateProtocolPhases(p); err != nil {
+			return nil, fmt.Errorf("protocols.yaml: 协议 %q %v", p.Name, err)
+		}
+	}
+	return f.Protocols, nil
+}
+
+func validateProtocolPhases(p *ProtocolDef) error {
+	if len(p.Phases) == 0 {
+		return fmt.Errorf("没有定义 phases")
+	}
+	ids := make(map[string]bool)
+	for i := range p.Phases {
+		ph := &p.Phases[i]
+		ph.ID = strings.TrimSpace(ph.ID)
+		if ph.ID == "" {
+			return fmt.Errorf("第 %d 个阶段缺少 id", i+1)
+		}
+		if ids[ph.ID] {
+			return fmt.Errorf("阶段 id %q 重复", ph.ID)
+		}
+		ids[ph.ID] = true
+		switch PhaseType(ph.Type) {
+		case "":
+			ph.Type = string(PhaseExecute)
+		case PhaseExecute, PhaseGate, PhaseLoop:
+		default:
+			return fmt.Errorf("阶段 %q 的 type 无效: %s（可用: execute, gate, loop）", ph.ID, ph.Type)
+		}
+		if ph.MaxRetries < 0 {
+			return fmt.Errorf("阶段 %q 的 max_retries 不能为负", ph.ID)
+		}
+	}
+	for _, ph := range p.Phases {
+		for _, target := range []string{ph.OnPass, ph.OnFail} {
+			if target != "" && !ids[target] {
+				return fmt.Errorf("阶段 %q 跳转到不存在的阶段 %q", ph.ID, target)
+			}
+		}
+	}
+	return nil
+}
+
+// phases 把协议定义展开为待执行的 Phase 列表；description 作为首阶段输入
+func (p ProtocolDef) phases(description string) []Phase {
+	out := make([]Phase, 0, len(p.Phases))
+	for i, d := range p.Phases {
+		ph := Phase{
+			ID:         d.ID,
+			Name:       fallback(d.Name, d.ID),
+			Type:       PhaseType(d.Type),
+			Status:     PhasePending,
+			Input:      d.Input,
+			OnPass:     d.OnPass,
+			OnFail:     d.OnFail,
+			MaxRetries: d.MaxRetries,
+			Verify:     d.Verify,
+		}
+		if i == 0 && ph.Input == "" {
+			ph.Input = description
+		}
+		out = append(out, ph)
+	}
+	return out
+}
+
+// flow 协议流程的单行描述，与内置协议的展示格式一致
+func (p ProtocolDef) flow() string {
+	parts := make([]string, 0, len(p.Phases))
+	for _, d := range p.Phases {
+		if PhaseType(d.Type) == PhaseLoop {
+			parts = append(parts, d.ID+"(loop)")
+		} else {
+			parts = append(parts, d.ID)
+		}
+	}
+	return strings.Join(parts, " → ")
+}
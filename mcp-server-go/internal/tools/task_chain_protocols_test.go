@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProtocols(t *testing.T, root, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, ".mcp-config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(protocolsPath(root), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildPhasesFromCustomProtocol(t *testing.T) {
+	root := t.TempDir()
+	writeProtocols(t, root, `
+protocols:
+  - name: review
+    description: 代码评审
+    phases:
+      - {id: read, name: 通读变更}
+      - {id: comment, type: loop}
+      - {id: approve_gate, type: gate, on_pass: done, on_fail: comment, max_retries: 2}
+      - {id: done}
+`)
+	phases, err := buildPhasesFromProtocol(root, "review", "评审 PR #12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) != 4 || phases[0].Input != "评审 PR #12" || phases[0].Type != PhaseExecute {
+		t.Fatalf("unexpected phases: %+v", phases)
+	}
+	if g := phases[2]; g.Type != PhaseGate || g.OnFail != "comment" || g.MaxRetries != 2 {
+		t.Errorf("unexpected gate: %+v", g)
+	}
+	if !strings.Contains(renderProtocolList(root), "read → comment(loop) → approve_gate → done") {
+		t.Errorf("protocol list missing custom flow")
+	}
+}
+
+func TestLoadCustomProtocols_InvalidJump(t *testing.T) {
+	root := t.TempDir()
+	writeProtocols(t, root, `
+protocols:
+  - name: broken
+    phases:
+      - {id: a, type: gate, on_fail: missing}
+`)
+	if _, err := buildPhasesFromProtocol(root, "broken", ""); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected invalid jump error, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ========== re-init 结构化差异 ==========
+
+// phaseDiff 新旧阶段列表的结构化差异
+type phaseDiff struct {
+	Added   []Phase  // 新计划中新增的阶段
+	Removed []Phase  // 旧链中被移除的阶段
+	Changed []string // 同 ID 但类型/名称变化的阶段描述
+	Lost    []string // re-init 后将丢失的进度（已通过/进行中的阶段与子任务）
+}
+
+// IsEmpty 新旧计划结构完全一致且无进度丢失
+func (d phaseDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.Lost) == 0
+}
+
+// diffChainPhases 对比现有链与新计划。新计划的阶段全部从 pending 开始，
+// 因此旧链中所有已推进的阶段/子任务都会计入丢失进度
+func diffChainPhases(oldPhases, newPhases []Phase) phaseDiff {
+	var d phaseDiff
+
+	newByID := make(map[string]Phase, len(newPhases))
+	for _, p := range newPhases {
+		newByID[p.ID] = p
+	}
+	oldByID := make(map[string]bool, len(oldPhases))
+
+	for _, old := range oldPhases {
+		oldByID[old.ID] = true
+		next, kept := newByID[old.ID]
+		if !kept {
+			d.Removed = append(d.Removed, old)
+		} else if next.Type != old.Type || next.Name != old.Name {
+			d.Changed = append(d.Changed, fmt.Sprintf("%s: %s「%s」→ %s「%s」", old.ID, old.Type, old.Name, next.Type, next.Name))
+		}
+
+		switch old.Status {
+		case PhasePassed, PhaseActive, PhaseFailed:
+			lost := fmt.Sprintf("%s「%s」(%s)", old.ID, old.Name, old.Status)
+			if old.Summary != "" {
+				lost += " — " + truncateRunes(old.Summary, 60)
+			}
+			d.Lost = append(d.Lost, lost)
+		}
+		passed := 0
+		for _, sub := range old.SubTasks {
+			if sub.Status == SubTaskPassed {
+				passed++
+			}
+		}
+		if passed > 0 {
+			d.Lost = append(d.Lost, fmt.Sprintf("%s 中 %d/%d 个已通过的子任务", old.ID, passed, len(old.SubTasks)))
+		}
+	}
+
+	for _, p := range newPhases {
+		if !oldByID[p.ID] {
+			d.Added = append(d.Added, p)
+		}
+	}
+	return d
+}
+
+// samePlanStructure 新旧计划的阶段与子任务定义是否一致；状态、summary、计时等运行时字段不参与比较
+func samePlanStructure(oldPhases, newPhases []Phase) bool {
+	if len(oldPhases) != len(newPhases) {
+		return false
+	}
+	for i, old := range oldPhases {
+		next := newPhases[i]
+		if old.ID != next.ID || old.Name != next.Name || old.Type != next.Type || old.Input != next.Input ||
+			old.OnPass != next.OnPass || old.OnFail != next.OnFail || old.MaxRetries != next.MaxRetries ||
+			old.Verify != next.Verify || old.Parallel != next.Parallel || len(old.SubTasks) != len(next.SubTasks) {
+			return false
+		}
+		for j, sub := range old.SubTasks {
+			ns := next.SubTasks[j]
+			if sub.ID != ns.ID || sub.Name != ns.Name || sub.Verify != ns.Verify ||
+				strings.Join(sub.DependsOn, ",") != strings.Join(ns.DependsOn, ",") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// renderPhaseDiff 渲染 re-init 差异，供 agent 确认
+func renderPhaseDiff(taskID string, oldChain *TaskChainV3, newProtocol string, d phaseDiff) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("任务链 %s 已存在（协议 %s，当前阶段 %s）。re-init 需确认以下变更：\n\n",
+		taskID, oldChain.Protocol, fallback(oldChain.CurrentPhase, "-")))
+	if oldChain.Protocol != newProtocol {
+		sb.WriteString(fmt.Sprintf("协议: %s → %s\n", oldChain.Protocol, newProtocol))
+	}
+
+	if d.IsEmpty() {
+		sb.WriteString("阶段结构无变化，且没有已推进的进度。\n")
+	}
+	if len(d.Added) > 0 {
+		sb.WriteString(fmt.Sprintf("\n+ 新增阶段 (%d):\n", len(d.Added)))
+		for _, p := range d.Added {
+			sb.WriteString(fmt.Sprintf("  + %s「%s」[%s]\n", p.ID, p.Name, p.Type))
+		}
+	}
+	if len(d.Removed) > 0 {
+		sb.WriteString(fmt.Sprintf("\n- 移除阶段 (%d):\n", len(d.Removed)))
+		for _, p := range d.Removed {
+			sb.WriteString(fmt.Sprintf("  - %s「%s」[%s]\n", p.ID, p.Name, p.Type))
+		}
+	}
+	if len(d.Changed) > 0 {
+		sb.WriteString(fmt.Sprintf("\n~ 变更阶段 (%d):\n", len(d.Changed)))
+		for _, c := range d.Changed {
+			sb.WriteString("  ~ " + c + "\n")
+		}
+	}
+	if len(d.Lost) > 0 {
+		sb.WriteString(fmt.Sprintf("\n⚠ 将丢失的进度 (%d):\n", len(d.Lost)))
+		for _, l := range d.Lost {
+			sb.WriteString("  ! " + l + "\n")
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n确认无误后使用相同参数并加上 confirm_reinit=true 重新调用：\n  task_chain(mode=\"init\", task_id=\"%s\", ..., confirm_reinit=true)\n", taskID))
+	return sb.String()
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func resultText(res *mcp.CallToolResult) string {
+	if res == nil || len(res.Content) == 0 {
+		return ""
+	}
+	text, _ := res.Content[0].(mcp.TextContent)
+	return text.Text
+}
+
+func TestInitTaskChainV3_ReinitDiffGuard(t *testing.T) {
+	ctx := context.Background()
+	plan := []interface{}{
+		map[string]interface{}{"id": "impl", "type": "execute"},
+		map[string]interface{}{"id": "check", "type": "gate", "verify": "go test ./..."},
+	}
+	// 通过 init 本身建立现有链：首阶段已自动进入 active
+	existing := func() *SessionManager {
+		sm := &SessionManager{ProjectRoot: t.TempDir()}
+		if res, _ := initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: plan}); res.IsError {
+			t.Fatalf("init: %s", resultText(res))
+		}
+		if sm.TaskChainsV3["T"].Phases[0].Status != PhaseActive {
+			t.Fatal("init should start the first phase")
+		}
+		return sm
+	}
+
+	t.Run("same plan accepted", func(t *testing.T) {
+		sm := existing()
+		old := sm.TaskChainsV3["T"]
+		old.Phases[0].Summary = "进行中"
+		res, _ := initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: plan})
+		if res.IsError || !strings.Contains(resultText(res), "保留现有进度") {
+			t.Fatalf("identical plan should be accepted without confirm_reinit: %s", resultText(res))
+		}
+		if sm.TaskChainsV3["T"] != old || old.ReinitCount != 0 || old.Phases[0].Summary != "进行中" {
+			t.Fatal("identical plan must keep the existing chain and its progress")
+		}
+
+		// 结构一致时同样不计入 re-init 次数，不会触发自审升级
+		old.ReinitCount = 1
+		if res, _ := initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: plan}); res.IsError {
+			t.Fatalf("identical plan should not escalate: %s", resultText(res))
+		}
+	})
+
+	t.Run("different plan refused", func(t *testing.T) {
+		sm := existing()
+		old := sm.TaskChainsV3["T"]
+		changed := append([]interface{}{}, plan...)
+		changed[1] = map[string]interface{}{"id": "review", "type": "gate"}
+		res, _ := initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: changed})
+		text := resultText(res)
+		if sm.TaskChainsV3["T"] != old {
+			t.Fatal("re-init with a different plan must not replace the chain before confirmation")
+		}
+		for _, want := range []string{"+ review", "- check", "! impl", "confirm_reinit=true"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("diff should contain %q:\n%s", want, text)
+			}
+		}
+
+		// 仅修改 gate 的验证命令也属于计划变更
+		verifyChanged := append([]interface{}{}, plan...)
+		verifyChanged[1] = map[string]interface{}{"id": "check", "type": "gate", "verify": "make test"}
+		if initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: verifyChanged}); sm.TaskChainsV3["T"] != old {
+			t.Fatal("changed verify command should require confirmation")
+		}
+	})
+
+	t.Run("confirm_reinit overrides", func(t *testing.T) {
+		sm := existing()
+		old := sm.TaskChainsV3["T"]
+		changed := []interface{}{map[string]interface{}{"id": "review", "type": "gate"}}
+		res, _ := initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: changed, ConfirmReinit: true})
+		chain := sm.TaskChainsV3["T"]
+		if res.IsError || chain == old || chain.findPhase("review") == nil || chain.ReinitCount != 1 {
+			t.Fatalf("confirm_reinit should apply the new plan: %s", resultText(res))
+		}
+
+		// 相同计划加 confirm_reinit 视为显式要求从头重置；第二次 re-init 自审升级
+		res, _ = initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: plan, ConfirmReinit: true})
+		if !res.IsError || !strings.Contains(resultText(res), "自审升级") {
+			t.Fatalf("second re-init should be refused even with confirm_reinit: %s", resultText(res))
+		}
+	})
+
+	t.Run("protocol change refused", func(t *testing.T) {
+		sm := existing()
+		old := sm.TaskChainsV3["T"]
+		old.Protocol = "linear"
+		if res, _ := initTaskChainV3(ctx, sm, TaskChainArgs{TaskID: "T", Phases: plan}); sm.TaskChainsV3["T"] != old {
+			t.Fatalf("protocol change should require confirmation: %s", resultText(res))
+		}
+	})
+}
+
+func TestCheckChainLiveness(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := core.NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+	sm := &SessionManager{ProjectRoot: root, Memory: mem}
+
+	if res := checkChainLiveness(ctx, sm, "T", false); res != nil {
+		t.Fatalf("chain without events should not be refused: %s", resultText(res))
+	}
+	chain := &TaskChainV3{TaskID: "T", Status: "running", Phases: []Phase{{ID: "impl", Type: PhaseExecute, Status: PhaseActive}}}
+	if err := persistV3Chain(ctx, sm, chain, "start", "impl", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if res := checkChainLiveness(ctx, sm, "T", false); res != nil {
+		t.Fatalf("own recent event should not be refused: %s", resultText(res))
+	}
+
+	if _, err := mem.AppendTaskChainEvent(ctx, &core.TaskChainEvent{TaskID: "T", EventType: "complete", PhaseID: "impl", Holder: "other-host:1"}); err != nil {
+		t.Fatal(err)
+	}
+	res := checkChainLiveness(ctx, sm, "T", false)
+	if res == nil || !res.IsError || !strings.Contains(resultText(res), "other-host:1") || !strings.Contains(resultText(res), "takeover=true") {
+		t.Fatalf("recent event from another session should refuse resume: %s", resultText(res))
+	}
+
+	if res := checkChainLiveness(ctx, sm, "T", true); res != nil {
+		t.Fatalf("takeover should be allowed: %s", resultText(res))
+	}
+	last, err := mem.LatestTaskChainEvent(ctx, "T")
+	if err != nil || last == nil || last.EventType != "takeover" || last.Payload != "other-host:1" || last.Holder != sessionHolder() {
+		t.Fatalf("takeover event not recorded: %+v, %v", last, err)
+	}
+	if res := checkChainLiveness(ctx, sm, "T", false); res != nil {
+		t.Fatalf("after takeover this session owns the chain: %s", resultText(res))
+	}
+}
+
+func TestEnforceSummaryLimit(t *testing.T) {
+	newSession := func(overflow string) *SessionManager {
+		root := t.TempDir()
+		settings := core.DefaultProjectSettings()
+		settings.TaskChain.MaxSummaryChars = 10
+		settings.TaskChain.SummaryOverflow = overflow
+		if err := core.SaveProjectSettings(root, settings); err != nil {
+			t.Fatal(err)
+		}
+		return &SessionManager{ProjectRoot: root}
+	}
+	long := strings.Repeat("输出", 20)
+
+	sm := newSession("")
+	if stored, notice, res := enforceSummaryLimit(sm, "T", "impl", "", "短结论"); res != nil || stored != "短结论" || notice != "" {
+		t.Fatalf("summary within limit should pass through: %q %q", stored, notice)
+	}
+
+	stored, notice, res := enforceSummaryLimit(sm, "T/1", "impl", "s1", long)
+	if res != nil {
+		t.Fatalf("truncate should not fail: %s", resultText(res))
+	}
+	if !strings.HasPrefix(stored, strings.Repeat("输出", 5)) || notice == "" {
+		t.Fatalf("summary should keep the first 10 runes and report the truncation: %q %q", stored, notice)
+	}
+	dir := filepath.ToSlash(core.DataPath(sm.ProjectRoot, "chain_attachments", "T_1"))
+	i := strings.Index(stored, dir)
+	if i < 0 || !strings.Contains(stored[i:], "/impl-s1-") {
+		t.Fatalf("summary should reference the attachment under %s: %q", dir, stored)
+	}
+	data, err := os.ReadFile(filepath.FromSlash(strings.TrimSuffix(stored[i:], "]")))
+	if err != nil || string(data) != long {
+		t.Fatalf("attachment should hold the full summary: %v", err)
+	}
+
+	sm = newSession("reject")
+	if stored, _, res := enforceSummaryLimit(sm, "T", "impl", "", long); res == nil || !res.IsError || stored != "" {
+		t.Fatalf("reject policy should refuse overlong summary, got %q", stored)
+	}
+	if _, err := os.Stat(core.DataPath(sm.ProjectRoot, "chain_attachments")); !os.IsNotExist(err) {
+		t.Error("reject policy should not write attachments")
+	}
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== 任务链恢复令牌 ==========
+//
+// 令牌布局（base64url 无填充）：
+//   [0]      版本号
+//   [1:5]    项目根路径 FNV-32a 哈希（大端）
+//   [5:n]    最近事件 ID (uvarint)
+//   [n:]     task_id (UTF-8)
+// 客户端丢失全部上下文后，仅凭令牌即可 resume，无需记住 task_id。
+
+const resumeTokenVersion = 1
+
+// resumeToken 解码后的恢复令牌
+type resumeToken struct {
+	ProjectHash uint32
+	EventID     int64
+	TaskID      string
+}
+
+func projectHash(projectRoot string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(filepath.ToSlash(filepath.Clean(projectRoot)))))
+	return h.Sum32()
+}
+
+func encodeResumeToken(projectRoot, taskID string, eventID int64) string {
+	buf := make([]byte, 5, 5+binary.MaxVarintLen64+len(taskID))
+	buf[0] = resumeTokenVersion
+	binary.BigEndian.PutUint32(buf[1:5], projectHash(projectRoot))
+	buf = binary.AppendUvarint(buf, uint64(eventID))
+	buf = append(buf, taskID...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func decodeResumeToken(token string) (resumeToken, error) {
+	var t resumeToken
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil || len(raw) < 6 || raw[0] != resumeTokenVersion {
+		return t, fmt.Errorf("无效的 resume_token")
+	}
+	t.ProjectHash = binary.BigEndian.Uint32(raw[1:5])
+	eventID, n := binary.Uvarint(raw[5:])
+	if n <= 0 || 5+n >= len(raw) {
+		return t, fmt.Errorf("无效的 resume_token")
+	}
+	t.EventID = int64(eventID)
+	t.TaskID = string(raw[5+n:])
+	return t, nil
+}
+
+// resolveResumeToken 校验令牌归属当前项目，返回 task_id 与令牌签发后的进展提示
+func resolveResumeToken(ctx context.Context, sm *SessionManager, token string) (string, string, error) {
+	t, err := decodeResumeToken(token)
+	if err != nil {
+		return "", "", err
+	}
+	if t.ProjectHash != projectHash(sm.ProjectRoot) {
+		return "", "", fmt.Errorf("resume_token 属于其他项目，请先 initialize_project 到签发令牌的项目")
+	}
+	note := ""
+	if sm.Memory != nil {
+		if last, err := sm.Memory.LatestTaskChainEvent(ctx, t.TaskID); err == nil && last != nil && last.ID > t.EventID {
+			note = fmt.Sprintf("⚠ 令牌签发后任务链已有新事件（最新 %s #%d），以下为当前最新状态。\n", last.EventType, last.ID)
+		}
+	}
+	return t.TaskID, note, nil
+}
+
+// appendResumeToken 在阶段完成结果后附加恢复令牌
+func appendResumeToken(ctx context.Context, sm *SessionManager, result *mcp.CallToolResult, taskID string) *mcp.CallToolResult {
+	if result == nil || result.IsError || sm.Memory == nil {
+		return result
+	}
+	last, err := sm.Memory.LatestTaskChainEvent(ctx, taskID)
+	if err != nil || last == nil {
+		return result
+	}
+	token := encodeResumeToken(sm.ProjectRoot, taskID, last.ID)
+	result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf(
+		"\n🔑 resume_token: %s\n  上下文丢失时调用 task_chain(mode=\"resume\", resume_token=\"%s\") 即可恢复。\n", token, token)))
+	return result
+}
@@ -0,0 +1,23 @@
+package tools
+
+import "testing"
+
+func TestResumeTokenRoundTrip(t *testing.T) {
+	token := encodeResumeToken("/work/proj", "TASK_任务-42", 1234)
+	got, err := decodeResumeToken(token)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if got.TaskID != "TASK_任务-42" || got.EventID != 1234 || got.ProjectHash != projectHash("/work/proj") {
+		t.Fatalf("unexpected token payload: %+v", got)
+	}
+	if got.ProjectHash == projectHash("/work/other") {
+		t.Fatalf("project hash should differ between projects")
+	}
+
+	for _, bad := range []string{"", "not-base64!!", encodeResumeToken("/p", "", 1)} {
+		if _, err := decodeResumeToken(bad); err == nil {
+			t.Errorf("expected error for token %q", bad)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// ========== 任务链风险预算 ==========
+
+// RiskBudget 任务链声明的风险预算，由链内 code_impact 调用累计
+type RiskBudget struct {
+	MaxAffected  int            `json:"max_affected,omitempty"`  // 累计影响节点上限 (0=不限)
+	MaxHighRisk  int            `json:"max_high_risk,omitempty"` // 触及高风险符号数上限 (0=不限)
+	Affected     map[string]int `json:"affected,omitempty"`      // 符号 -> 影响节点数（同一符号只计一次，取最大值）
+	HighRisk     []string       `json:"high_risk,omitempty"`     // 触及的高风险符号
+	Acknowledged bool           `json:"acknowledged,omitempty"`  // 超支后用户已确认
+	AckNote      string         `json:"ack_note,omitempty"`
+}
+
+// UsedAffected 累计影响节点数
+func (b *RiskBudget) UsedAffected() int {
+	total := 0
+	for _, n := range b.Affected {
+		total += n
+	}
+	return total
+}
+
+// Exceeded 是否超出任一预算
+func (b *RiskBudget) Exceeded() bool {
+	if b == nil {
+		return false
+	}
+	return (b.MaxAffected > 0 && b.UsedAffected() > b.MaxAffected) ||
+		(b.MaxHighRisk > 0 && len(b.HighRisk) > b.MaxHighRisk)
+}
+
+// Record 记录一次影响分析结果
+func (b *RiskBudget) Record(symbol string, affected int, highRisk bool) {
+	if b.Affected == nil {
+		b.Affected = make(map[string]int)
+	}
+	if prev, ok := b.Affected[symbol]; !ok || affected > prev {
+		b.Affected[symbol] = affected
+	}
+	if highRisk {
+		for _, s := range b.HighRisk {
+			if s == symbol {
+				return
+			}
+		}
+		b.HighRisk = append(b.HighRisk, symbol)
+	}
+}
+
+// Summary 预算使用情况单行描述
+func (b *RiskBudget) Summary() string {
+	limit := func(n int) string {
+		if n <= 0 {
+			return "∞"
+		}
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("影响节点 %d/%s，高风险符号 %d/%s", b.UsedAffected(), limit(b.MaxAffected), len(b.HighRisk), limit(b.MaxHighRisk))
+}
+
+// recordChainImpact 将 code_impact 结果计入任务链风险预算，返回需附加到输出的提示
+func recordChainImpact(ctx context.Context, sm *SessionManager, taskID, symbol string, result *services.ImpactResult) string {
+	chain, err := getOrLoadV3Chain(ctx, sm, taskID)
+	if err != nil {
+		return fmt.Sprintf("\n⚠ 未能计入风险预算: %v\n", err)
+	}
+	if chain.RiskBudget == nil {
+		// 无预算时仍记录事件，供 request_review 汇总链内影响分析
+		_ = persistV3Chain(ctx, sm, chain, "impact", chain.CurrentPhase, "", fmt.Sprintf("%s affected=%d risk=%s", symbol, result.AffectedNodes, result.RiskLevel))
+		return ""
+	}
+
+	wasExceeded := chain.RiskBudget.Exceeded()
+	chain.RiskBudget.Record(symbol, result.AffectedNodes, strings.EqualFold(result.RiskLevel, "high"))
+	_ = persistV3Chain(ctx, sm, chain, "impact", chain.CurrentPhase, "", fmt.Sprintf("%s affected=%d risk=%s", symbol, result.AffectedNodes, result.RiskLevel))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n**风险预算** (%s): %s\n", taskID, chain.RiskBudget.Summary()))
+	if chain.RiskBudget.Exceeded() && !chain.RiskBudget.Acknowledged {
+		if !wasExceeded {
+			sb.WriteString("\n⚠ 风险预算已超出：改动范围可能正在蔓延。\n")
+		}
+		sb.WriteString(fmt.Sprintf("在用户明确确认前，该任务链的 complete/complete_sub 将被阻止。\n"+
+			"  → 向用户说明影响范围，获得同意后调用 task_chain(mode=\"ack_risk\", task_id=\"%s\", summary=\"用户确认说明\")\n", taskID))
+	}
+	return sb.String()
+}
+
+// checkRiskBudgetGate 预算超支且未确认时阻止阶段推进
+func checkRiskBudgetGate(chain *TaskChainV3) error {
+	if chain.RiskBudget.Exceeded() && !chain.RiskBudget.Acknowledged {
+		return fmt.Errorf("任务链 %s 风险预算已超出 (%s)，需用户确认后调用 task_chain(mode=\"ack_risk\", task_id=\"%s\", summary=\"...\") 才能继续",
+			chain.TaskID, chain.RiskBudget.Summary(), chain.TaskID)
+	}
+	return nil
+}
+
+// renderRiskBudget 渲染预算详情（init 结果与 ack 回执使用）
+func renderRiskBudget(b *RiskBudget) string {
+	if b == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("风险预算: %s\n", b.Summary()))
+	if len(b.Affected) > 0 {
+		symbols := make([]string, 0, len(b.Affected))
+		for s := range b.Affected {
+			symbols = append(symbols, s)
+		}
+		sort.Strings(symbols)
+		for _, s := range symbols {
+			sb.WriteString(fmt.Sprintf("  - %s: %d 个影响节点\n", s, b.Affected[s]))
+		}
+	}
+	return sb.String()
+}
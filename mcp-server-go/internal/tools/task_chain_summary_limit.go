@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/core"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ========== summary 长度护栏 ==========
+// 防止把整段命令输出塞进 summary：超过 task_chain.max_summary_chars 时拒绝或截断，
+// 截断时全文另存为附件，任务链状态保持精简，resume 时不会拖入大段噪声。
+
+// enforceSummaryLimit 返回实际入库的 summary 与提示；reject 策略下返回错误结果
+func enforceSummaryLimit(sm *SessionManager, taskID, phaseID, subID, summary string) (string, string, *mcp.CallToolResult) {
+	settings := core.LoadProjectSettings(sm.ProjectRoot).TaskChain
+	limit := settings.MaxSummaryChars
+	length := len([]rune(summary))
+	if limit <= 0 || length <= limit {
+		return summary, "", nil
+	}
+
+	if settings.SummaryOverflow == "reject" {
+		return "", "", mcp.NewToolResultError(fmt.Sprintf(
+			"summary 过长（%d 字，上限 %d）。请只写结论：做了什么、改了哪些文件、验证结果；"+
+				"原始命令输出不要粘贴进 summary，必要时用 memo 记录要点。", length, limit))
+	}
+
+	name := phaseID
+	if subID != "" {
+		name += "-" + subID
+	}
+	dir := core.DataPath(sm.ProjectRoot, "chain_attachments", safeFileName(taskID))
+	attachment := filepath.Join(dir, fmt.Sprintf("%s-%s.md", safeFileName(name), time.Now().Format("20060102-150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", mcp.NewToolResultError(fmt.Sprintf("summary 过长且附件目录创建失败: %v", err))
+	}
+	if err := os.WriteFile(attachment, []byte(summary), 0644); err != nil {
+		return "", "", mcp.NewToolResultError(fmt.Sprintf("summary 过长且附件写入失败: %v", err))
+	}
+
+	head := truncateRunes(summary, limit)
+	stored := fmt.Sprintf("%s\n[summary 已截断，全文 %d 字见附件: %s]", head, length, filepath.ToSlash(attachment))
+	notice := fmt.Sprintf("✂️ summary 超过 %d 字，已截断入库，全文保存到 %s\n   下次请只写结论，不要粘贴完整命令输出。\n\n", limit, filepath.ToSlash(attachment))
+	return stored, notice, nil
+}
+
+// safeFileName 将 ID 转为可用作文件名的形式
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -3,6 +3,8 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // ========== 协议状态机数据结构 ==========
@@ -51,9 +53,21 @@ type Phase struct {
 	OnFail     string `json:"on_fail,omitempty"`
 	MaxRetries int    `json:"max_retries,omitempty"`
 	RetryCount int    `json:"retry_count,omitempty"`
+	Verify     string `json:"verify,omitempty"` // 验证命令，complete(auto_verify=true) 时执行并据退出码判定 pass/fail
 
 	// Loop 专用
 	SubTasks []SubTask `json:"sub_tasks,omitempty"`
+	Parallel bool      `json:"parallel,omitempty"` // 依赖已满足的子任务同时进入 active
+
+	GitBaseline *GitBaseline `json:"git_baseline,omitempty"` // 阶段开始时的 git 状态，用于 summary 核对
+
+	// 计时：gate 失败回退后阶段会重新开始，Attempts/SpentSec 累计每一次执行
+	StartedAt   time.Time `json:"started_at,omitzero"`   // 首次开始
+	AttemptAt   time.Time `json:"attempt_at,omitzero"`   // 本次执行开始
+	CompletedAt time.Time `json:"completed_at,omitzero"` // 最近一次完成
+	Attempts    int       `json:"attempts,omitempty"`
+	SpentSec    int64     `json:"spent_sec,omitempty"` // 各次执行累计用时（秒）
+	LastSec     int64     `json:"last_sec,omitempty"`  // 最近一次执行用时（秒）
 }
 
 // SubTask 子任务
@@ -63,6 +77,13 @@ type SubTask struct {
 	Verify  string        `json:"verify,omitempty"`
 	Status  SubTaskStatus `json:"status"`
 	Summary string        `json:"summary,omitempty"`
+
+	DependsOn []string `json:"depends_on,omitempty"` // 同一 loop 内必须先通过的子任务
+
+	GitBaseline *GitBaseline `json:"git_baseline,omitempty"`
+
+	StartedAt   time.Time `json:"started_at,omitzero"`
+	CompletedAt time.Time `json:"completed_at,omitzero"`
 }
 
 // TaskChainV3 协议状态机任务链
@@ -74,6 +95,12 @@ type TaskChainV3 struct {
 	Phases       []Phase `json:"phases"`
 	CurrentPhase string  `json:"current_phase"`
 	ReinitCount  int     `json:"reinit_count,omitempty"` // 重新初始化次数，用于自审升级判断
+
+	WorkingDir string            `json:"working_dir,omitempty"` // 验证命令执行目录（相对项目根目录）
+	Env        map[string]string `json:"env,omitempty"`         // 验证命令附加环境变量
+
+	RiskBudget *RiskBudget `json:"risk_budget,omitempty"` // 风险预算（由链内 code_impact 累计）
+	Budget     *StepBudget `json:"budget,omitempty"`      // 执行预算（由 complete/complete_sub 累计）
 }
 
 // ========== 状态流转引擎 ==========
@@ -122,6 +149,7 @@ func (tc *TaskChainV3) StartPhase(phaseID string) error {
 		return errPhaseWrongStatus(phaseID, p.Status, PhasePending)
 	}
 	p.Status = PhaseActive
+	p.markStarted(time.Now())
 	tc.CurrentPhase = phaseID
 	return nil
 }
@@ -141,6 +169,7 @@ func (tc *TaskChainV3) CompleteExecute(phaseID, summary string) (nextPhaseID str
 
 	p.Status = PhasePassed
 	p.Summary = summary
+	p.markCompleted(time.Now())
 
 	// 返回下一个阶段
 	next := tc.nextPhaseAfter(phaseID)
@@ -164,6 +193,7 @@ func (tc *TaskChainV3) CompleteGate(phaseID, result, summary string) (nextPhaseI
 	}
 
 	p.Summary = summary
+	p.markCompleted(time.Now())
 
 	if result == "pass" {
 		p.Status = PhasePassed
@@ -207,8 +237,8 @@ func (tc *TaskChainV3) CompleteGate(phaseID, result, summary string) (nextPhaseI
 	return "", retryInfo, nil
 }
 
-// SpawnSubTasks 在 loop 阶段生成子任务
-func (tc *TaskChainV3) SpawnSubTasks(phaseID string, subs []SubTask) error {
+// SpawnSubTasks 在 loop 阶段生成子任务；parallel 为 true 时该 loop 改为并行调度
+func (tc *TaskChainV3) SpawnSubTasks(phaseID string, subs []SubTask, parallel bool) error {
 	p := tc.findPhase(phaseID)
 	if p == nil {
 		return errPhaseNotFound(phaseID)
@@ -225,7 +255,13 @@ func (tc *TaskChainV3) SpawnSubTasks(phaseID string, subs []SubTask) error {
 			subs[i].Status = SubTaskPending
 		}
 	}
+	if err := validateSubTaskDeps(p.SubTasks, subs); err != nil {
+		return err
+	}
 	p.SubTasks = append(p.SubTasks, subs...)
+	if parallel {
+		p.Parallel = true
+	}
 	return nil
 }
 
@@ -242,7 +278,11 @@ func (tc *TaskChainV3) StartSubTask(phaseID, subID string) error {
 	if sub.Status != SubTaskPending {
 		return errSubTaskWrongStatus(subID, sub.Status, SubTaskPending)
 	}
+	if blocking := unmetDeps(p, sub); len(blocking) > 0 {
+		return errSubTaskBlocked(subID, blocking)
+	}
 	sub.Status = SubTaskActive
+	sub.StartedAt = time.Now()
 	return nil
 }
 
@@ -256,15 +296,22 @@ func (tc *TaskChainV3) CompleteSubTask(phaseID, subID, result, summary string) (
 	if sub == nil {
 		return false, errSubTaskNotFound(phaseID, subID)
 	}
-	if sub.Status != SubTaskActive {
+	// 并行 loop 中只校验声明的依赖：依赖已通过的 pending 子任务可直接完成
+	if sub.Status == SubTaskPending && p.Parallel {
+		if blocking := unmetDeps(p, sub); len(blocking) > 0 {
+			return false, errSubTaskBlocked(subID, blocking)
+		}
+	} else if sub.Status != SubTaskActive {
 		return false, errSubTaskWrongStatus(subID, sub.Status, SubTaskActive)
 	}
 
 	sub.Summary = summary
+	sub.CompletedAt = time.Now()
 	if result == "pass" {
 		sub.Status = SubTaskPassed
 	} else {
 		sub.Status = SubTaskFailed
+		skipDependents(p)
 	}
 
 	// 检查是否全部完成
@@ -280,19 +327,20 @@ func (tc *TaskChainV3) CompleteSubTask(phaseID, subID, result, summary string) (
 		p.Status = PhasePassed
 		// 汇总 summary
 		p.Summary = summary
+		p.markCompleted(time.Now())
 	}
 
 	return allDone, nil
 }
 
-// NextPendingSubTask 获取 loop 阶段下一个待执行的子任务
+// NextPendingSubTask 获取 loop 阶段下一个依赖已满足的待执行子任务
 func (tc *TaskChainV3) NextPendingSubTask(phaseID string) *SubTask {
 	p := tc.findPhase(phaseID)
 	if p == nil {
 		return nil
 	}
 	for i := range p.SubTasks {
-		if p.SubTasks[i].Status == SubTaskPending {
+		if p.SubTasks[i].Status == SubTaskPending && len(unmetDeps(p, &p.SubTasks[i])) == 0 {
 			return &p.SubTasks[i]
 		}
 	}
@@ -354,6 +402,10 @@ func errSubTaskWrongStatus(subID string, current, expected SubTaskStatus) error
 	return fmt.Errorf("sub_task '%s' status is '%s', expected '%s'", subID, current, expected)
 }
 
+func errSubTaskBlocked(subID string, blocking []string) error {
+	return fmt.Errorf("sub_task '%s' is blocked by unfinished dependencies: %s", subID, strings.Join(blocking, ", "))
+}
+
 // ========== 辅助函数 ==========
 
 func findSubTask(p *Phase, subID string) *SubTask {
@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"mcp-server-go/internal/core"
 
@@ -65,6 +69,19 @@ func persistV3Chain(ctx context.Context, sm *SessionManager, chain *TaskChainV3,
 		PhasesJSON:   phasesJSON,
 		CurrentPhase: chain.CurrentPhase,
 		ReinitCount:  chain.ReinitCount,
+		WorkingDir:   chain.WorkingDir,
+	}
+	if len(chain.Env) > 0 {
+		envJSON, _ := json.Marshal(chain.Env)
+		rec.EnvJSON = string(envJSON)
+	}
+	if chain.RiskBudget != nil {
+		budgetJSON, _ := json.Marshal(chain.RiskBudget)
+		rec.RiskBudget = string(budgetJSON)
+	}
+	if chain.Budget != nil {
+		budgetJSON, _ := json.Marshal(chain.Budget)
+		rec.Budget = string(budgetJSON)
 	}
 	if err := sm.Memory.SaveTaskChain(ctx, rec); err != nil {
 		return err
@@ -77,10 +94,13 @@ func persistV3Chain(ctx context.Context, sm *SessionManager, chain *TaskChainV3,
 			SubID:     subID,
 			EventType: eventType,
 			Payload:   payload,
+			Holder:    sessionHolder(),
 		}
 		if _, err := sm.Memory.AppendTaskChainEvent(ctx, evt); err != nil {
 			return err
 		}
+		recordExperimentOutcome(ctx, sm, chain, eventType)
+		notifyChainWebhooks(sm, chain, eventType, phaseID, payload)
 	}
 	return nil
 }
@@ -119,6 +139,18 @@ func getOrLoadV3Chain(ctx context.Context, sm *SessionManager, taskID string) (*
 		Phases:       phases,
 		CurrentPhase: rec.CurrentPhase,
 		ReinitCount:  rec.ReinitCount,
+		WorkingDir:   rec.WorkingDir,
+	}
+	if rec.EnvJSON != "" {
+		_ = json.Unmarshal([]byte(rec.EnvJSON), &chain.Env)
+	}
+	if rec.RiskBudget != "" {
+		chain.RiskBudget = &RiskBudget{}
+		_ = json.Unmarshal([]byte(rec.RiskBudget), chain.RiskBudget)
+	}
+	if rec.Budget != "" {
+		chain.Budget = &StepBudget{}
+		_ = json.Unmarshal([]byte(rec.Budget), chain.Budget)
 	}
 	sm.TaskChainsV3[taskID] = chain
 	return chain, nil
@@ -165,6 +197,9 @@ func parsePhasesFromArgs(phaseMaps []map[string]interface{}) ([]Phase, error) {
 				p.MaxRetries = int(n)
 			}
 		}
+		if v, ok := pm["verify"]; ok {
+			p.Verify = fmt.Sprintf("%v", v)
+		}
 
 		phases = append(phases, p)
 	}
@@ -195,6 +230,9 @@ func parseSubTasksFromArgs(subMaps []map[string]interface{}) ([]SubTask, error)
 		if v, ok := sm["verify"]; ok {
 			st.Verify = fmt.Sprintf("%v", v)
 		}
+		if v, ok := sm["depends_on"]; ok {
+			st.DependsOn = parseDependsOn(v)
+		}
 		subs = append(subs, st)
 	}
 	return subs, nil
@@ -233,15 +271,26 @@ func initTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 		if protocol == "" {
 			protocol = "linear"
 		}
-		phases, err = buildPhasesFromProtocol(protocol, args.Description)
+		phases, err = buildPhasesFromProtocol(sm.ProjectRoot, protocol, args.Description)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 	}
 
-	// 检测是否为 re-init（任务链已存在）
+	// 检测是否为 re-init（任务链已存在于内存或 DB）
 	reinitCount := 0
-	if existing, ok := sm.TaskChainsV3[args.TaskID]; ok {
+	if existing, loadErr := getOrLoadV3Chain(ctx, sm, args.TaskID); loadErr == nil && existing != nil {
+		// re-init 必须给出新计划，并先审阅与现有链的结构差异
+		if args.Phases == nil && strings.TrimSpace(args.Protocol) == "" {
+			return mcp.NewToolResultError(fmt.Sprintf(
+				"任务 '%s' 已存在，re-init 需要显式提供新的 phases 或 protocol，以便对比变更。", args.TaskID)), nil
+		}
+		// 计划结构与现有链一致：不重置，保留已有进度
+		if !args.ConfirmReinit && existing.Protocol == protocol && samePlanStructure(existing.Phases, phases) {
+			return mcp.NewToolResultText(fmt.Sprintf(
+				"任务链 %s 的计划与现有链一致，未 re-init，保留现有进度（当前阶段 %s）。\n如确需从头重置，使用相同参数并加上 confirm_reinit=true。\n",
+				args.TaskID, fallback(existing.CurrentPhase, "-"))), nil
+		}
 		reinitCount = existing.ReinitCount + 1
 		if reinitCount > 1 {
 			return mcp.NewToolResultError(fmt.Sprintf(
@@ -249,6 +298,18 @@ func initTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 				args.TaskID, existing.ReinitCount,
 			)), nil
 		}
+		if !args.ConfirmReinit {
+			diff := diffChainPhases(existing.Phases, phases)
+			return mcp.NewToolResultText(renderPhaseDiff(args.TaskID, existing, protocol, diff)), nil
+		}
+	}
+
+	workingDir, err := normalizeChainWorkingDir(sm.ProjectRoot, args.WorkingDir)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := core.CheckCommandEnv(args.Env); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	chain := &TaskChainV3{
@@ -258,6 +319,14 @@ func initTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 		Status:      "running",
 		Phases:      phases,
 		ReinitCount: reinitCount,
+		WorkingDir:  workingDir,
+		Env:         args.Env,
+	}
+	if args.MaxAffectedNodes > 0 || args.MaxHighRisk > 0 {
+		chain.RiskBudget = &RiskBudget{MaxAffected: args.MaxAffectedNodes, MaxHighRisk: args.MaxHighRisk}
+	}
+	if args.MaxSteps > 0 || args.MaxRetriesTotal > 0 || args.MaxTokens > 0 {
+		chain.Budget = &StepBudget{MaxSteps: args.MaxSteps, MaxRetriesTotal: args.MaxRetriesTotal, MaxTokens: args.MaxTokens}
 	}
 
 	sm.TaskChainsV3[args.TaskID] = chain
@@ -266,6 +335,9 @@ func initTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 	if err := persistV3Chain(ctx, sm, chain, "init", "", "", args.Description); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("持久化失败: %v", err)), nil
 	}
+	if exp := strings.TrimSpace(args.Experiment); exp != "" && sm.Memory != nil {
+		_ = sm.Memory.StartExperimentRun(ctx, chain.TaskID, exp, chain.Protocol)
+	}
 
 	// 自动开始第一个阶段
 	if len(phases) > 0 {
@@ -273,10 +345,20 @@ func initTaskChainV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 		if err := chain.StartPhase(firstPhase); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("启动首阶段失败: %v", err)), nil
 		}
+		markGitBaseline(sm, chain, firstPhase, "")
 		_ = persistV3Chain(ctx, sm, chain, "start", firstPhase, "", "")
 	}
 
-	return mcp.NewToolResultText(renderV3InitResult(chain)), nil
+	result := renderV3InitResult(chain)
+	if len(args.Files) > 0 && sm.Memory != nil {
+		claimed, conflicts, err := sm.Memory.ClaimFiles(ctx, chain.TaskID, sessionHolder(), args.Files, 0)
+		if err == nil {
+			result += fmt.Sprintf("\n🔒 已声明 %d 个文件软锁\n", len(claimed))
+			result += renderFileLockConflicts(conflicts)
+		}
+	}
+
+	return mcp.NewToolResultText(result), nil
 }
 
 // startPhaseV3 开始协议阶段
@@ -296,6 +378,7 @@ func startPhaseV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (
 	if err := chain.StartPhase(args.PhaseID); err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	markGitBaseline(sm, chain, args.PhaseID, "")
 
 	_ = persistV3Chain(ctx, sm, chain, "start", args.PhaseID, "", "")
 
@@ -327,7 +410,7 @@ func completePhaseV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 	if args.PhaseID == "" {
 		return mcp.NewToolResultError("协议 complete 模式需要 phase_id 参数"), nil
 	}
-	if args.Summary == "" {
+	if args.Summary == "" && !args.AutoVerify {
 		return mcp.NewToolResultError("complete 模式必须提供 summary"), nil
 	}
 
@@ -335,13 +418,45 @@ func completePhaseV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if err := checkRiskBudgetGate(chain); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkStepBudgetGate(chain); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	p := chain.findPhase(args.PhaseID)
 	if p == nil {
 		return mcp.NewToolResultError(fmt.Sprintf("phase '%s' not found", args.PhaseID)), nil
 	}
 
+	var verifyReport string
+	if args.AutoVerify {
+		if p.Type != PhaseGate {
+			return mcp.NewToolResultError("auto_verify 仅适用于 gate 阶段与子任务 (complete_sub)"), nil
+		}
+		if p.Status != PhaseActive {
+			return mcp.NewToolResultError(errPhaseWrongStatus(p.ID, p.Status, PhaseActive).Error()), nil
+		}
+		run, err := runAutoVerify(ctx, sm, chain, p.ID, p.Verify)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		verifyReport = run.render(args.Result)
+		args.Result = run.Result
+		args.Summary = run.mergeSummary(args.Summary)
+	}
+
+	fullSummary := args.Summary
+	summary, summaryNotice, rejected := enforceSummaryLimit(sm, args.TaskID, args.PhaseID, "", args.Summary)
+	if rejected != nil {
+		return rejected, nil
+	}
+	args.Summary = summary
+
 	var sb strings.Builder
+	sb.WriteString(summaryNotice)
+	sb.WriteString(verifyReport)
 
 	switch p.Type {
 	case PhaseGate:
@@ -351,7 +466,11 @@ func completePhaseV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 		nextID, retryInfo, err := chain.CompleteGate(args.PhaseID, args.Result, args.Summary)
 		if err != nil {
 			_ = persistV3Chain(ctx, sm, chain, "fail", args.PhaseID, "", err.Error())
-			return mcp.NewToolResultError(err.Error()), nil
+			msg := err.Error()
+			if chain.Status == "failed" {
+				msg += gateRollbackHint(ctx, sm, args.TaskID)
+			}
+			return mcp.NewToolResultError(msg), nil
 		}
 
 		payload, _ := json.Marshal(map[string]string{"result": args.Result, "summary": args.Summary})
@@ -382,6 +501,9 @@ func completePhaseV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 
 		sb.WriteString(fmt.Sprintf("【Phase '%s' 完成】%s\n", args.PhaseID, p.Name))
 		sb.WriteString(fmt.Sprintf("Summary: %s\n\n", args.Summary))
+		if diffVerifyEnabled(sm, args.VerifyDiff) {
+			sb.WriteString(renderDiffVerification(sm.ProjectRoot, p.GitBaseline, fullSummary))
+		}
 		if nextID != "" {
 			sb.WriteString(renderV3NextPhaseHint(chain, args.TaskID, nextID))
 		} else if chain.IsFinished() {
@@ -395,6 +517,7 @@ func completePhaseV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 		// loop 阶段的 complete 由子任务全部完成后自动触发，这里处理手动 complete
 		p.Status = PhasePassed
 		p.Summary = args.Summary
+		p.markCompleted(time.Now())
 		payload, _ := json.Marshal(map[string]string{"summary": args.Summary})
 		_ = persistV3Chain(ctx, sm, chain, "complete", args.PhaseID, "", string(payload))
 
@@ -442,18 +565,36 @@ func spawnSubTasksV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("解析 sub_tasks 失败: %v", err)), nil
 	}
+	if args.Parallel || hasSubTaskDeps(subs) {
+		if res := requireFeature(sm, "parallel_subtasks"); res != nil {
+			return res, nil
+		}
+	}
 
-	if err := chain.SpawnSubTasks(args.PhaseID, subs); err != nil {
+	if err := chain.SpawnSubTasks(args.PhaseID, subs, args.Parallel); err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	payload, _ := json.Marshal(subs)
 	_ = persistV3Chain(ctx, sm, chain, "spawn", args.PhaseID, "", string(payload))
 
+	// 并行 loop：依赖已满足的子任务全部开始
+	if p := chain.findPhase(args.PhaseID); p != nil && p.Parallel {
+		started := startReadySubTasks(ctx, sm, chain, args.PhaseID)
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("已创建 %d 个子任务（并行）:\n", len(subs)))
+		for _, s := range subs {
+			sb.WriteString(fmt.Sprintf("  • %s: %s [%s]%s\n", s.ID, s.Name, findSubTask(p, s.ID).Status, renderDependsOn(s)))
+		}
+		sb.WriteString(renderStartedSubTasks(args.TaskID, args.PhaseID, started))
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+
 	// 自动开始第一个子任务
 	firstSub := chain.NextPendingSubTask(args.PhaseID)
 	if firstSub != nil {
 		_ = chain.StartSubTask(args.PhaseID, firstSub.ID)
+		markGitBaseline(sm, chain, args.PhaseID, firstSub.ID)
 		_ = persistV3Chain(ctx, sm, chain, "start_sub", args.PhaseID, firstSub.ID, "")
 	}
 
@@ -464,7 +605,7 @@ func spawnSubTasksV3(ctx context.Context, sm *SessionManager, args TaskChainArgs
 		if firstSub != nil && s.ID == firstSub.ID {
 			status = "active"
 		}
-		sb.WriteString(fmt.Sprintf("  • %s: %s [%s]\n", s.ID, s.Name, status))
+		sb.WriteString(fmt.Sprintf("  • %s: %s [%s]%s\n", s.ID, s.Name, status, renderDependsOn(s)))
 	}
 	if firstSub != nil {
 		sb.WriteString(fmt.Sprintf("\n→ 开始执行: %s「%s」\n", firstSub.ID, firstSub.Name))
@@ -489,7 +630,7 @@ func completeSubTaskV3(ctx context.Context, sm *SessionManager, args TaskChainAr
 	if args.SubID == "" {
 		return mcp.NewToolResultError("complete_sub 模式需要 sub_id 参数"), nil
 	}
-	if args.Summary == "" {
+	if args.Summary == "" && !args.AutoVerify {
 		return mcp.NewToolResultError("complete_sub 模式必须提供 summary"), nil
 	}
 
@@ -502,6 +643,41 @@ func completeSubTaskV3(ctx context.Context, sm *SessionManager, args TaskChainAr
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if err := checkRiskBudgetGate(chain); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := checkStepBudgetGate(chain); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var verifyReport string
+	if args.AutoVerify {
+		p := chain.findPhase(args.PhaseID)
+		if p == nil {
+			return mcp.NewToolResultError(errPhaseNotFound(args.PhaseID).Error()), nil
+		}
+		sub := findSubTask(p, args.SubID)
+		if sub == nil {
+			return mcp.NewToolResultError(errSubTaskNotFound(args.PhaseID, args.SubID).Error()), nil
+		}
+		if sub.Status == SubTaskPassed || sub.Status == SubTaskFailed {
+			return mcp.NewToolResultError(errSubTaskWrongStatus(sub.ID, sub.Status, SubTaskActive).Error()), nil
+		}
+		run, err := runAutoVerify(ctx, sm, chain, args.PhaseID+"/"+sub.ID, sub.Verify)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		verifyReport = run.render(args.Result)
+		result = run.Result
+		args.Summary = run.mergeSummary(args.Summary)
+	}
+
+	fullSummary := args.Summary
+	summary, summaryNotice, rejected := enforceSummaryLimit(sm, args.TaskID, args.PhaseID, args.SubID, args.Summary)
+	if rejected != nil {
+		return rejected, nil
+	}
+	args.Summary = summary
 
 	allDone, err := chain.CompleteSubTask(args.PhaseID, args.SubID, result, args.Summary)
 	if err != nil {
@@ -512,8 +688,19 @@ func completeSubTaskV3(ctx context.Context, sm *SessionManager, args TaskChainAr
 	_ = persistV3Chain(ctx, sm, chain, "complete_sub", args.PhaseID, args.SubID, string(payload))
 
 	var sb strings.Builder
+	sb.WriteString(summaryNotice)
+	sb.WriteString(verifyReport)
 	sb.WriteString(fmt.Sprintf("【子任务 %s 完成】结果: %s\n", args.SubID, result))
 	sb.WriteString(fmt.Sprintf("Summary: %s\n\n", args.Summary))
+	if diffVerifyEnabled(sm, args.VerifyDiff) {
+		var base *GitBaseline
+		if p := chain.findPhase(args.PhaseID); p != nil {
+			if sub := findSubTask(p, args.SubID); sub != nil {
+				base = sub.GitBaseline
+			}
+		}
+		sb.WriteString(renderDiffVerification(sm.ProjectRoot, base, fullSummary))
+	}
 
 	if allDone {
 		sb.WriteString(fmt.Sprintf("✅ Loop '%s' 所有子任务已完成\n", args.PhaseID))
@@ -525,11 +712,25 @@ func completeSubTaskV3(ctx context.Context, sm *SessionManager, args TaskChainAr
 			_ = persistV3Chain(ctx, sm, chain, "finish", "", "", "")
 			sb.WriteString("✅ 所有阶段已完成。\n")
 		}
+	} else if p := chain.findPhase(args.PhaseID); p != nil && p.Parallel {
+		// 并行 loop：开始因本次完成而解锁的子任务，并列出仍在进行中的子任务
+		started := startReadySubTasks(ctx, sm, chain, args.PhaseID)
+		sb.WriteString(renderStartedSubTasks(args.TaskID, args.PhaseID, started))
+		var active []string
+		for _, s := range p.SubTasks {
+			if s.Status == SubTaskActive {
+				active = append(active, s.ID)
+			}
+		}
+		if len(active) > 0 {
+			sb.WriteString(fmt.Sprintf("进行中: %s\n", strings.Join(active, ", ")))
+		}
 	} else {
 		// 自动开始下一个子任务
 		nextSub := chain.NextPendingSubTask(args.PhaseID)
 		if nextSub != nil {
 			_ = chain.StartSubTask(args.PhaseID, nextSub.ID)
+			markGitBaseline(sm, chain, args.PhaseID, nextSub.ID)
 			_ = persistV3Chain(ctx, sm, chain, "start_sub", args.PhaseID, nextSub.ID, "")
 			sb.WriteString(fmt.Sprintf("→ 下一个子任务: %s「%s」\n", nextSub.ID, nextSub.Name))
 			if nextSub.Verify != "" {
@@ -557,6 +758,65 @@ func resumeTaskChainV3(ctx context.Context, sm *SessionManager, taskID string) (
 	return mcp.NewToolResultText(renderV3StatusJSON(chain)), nil
 }
 
+// checkChainLiveness resume 前检查是否有其他会话仍在驱动该任务链
+// 最近事件由其他会话写入且在存活窗口内时，未声明 takeover 则拒绝恢复；声明 takeover 则记录接管事件
+func checkChainLiveness(ctx context.Context, sm *SessionManager, taskID string, takeover bool) *mcp.CallToolResult {
+	if sm.Memory == nil || taskID == "" {
+		return nil
+	}
+	last, err := sm.Memory.LatestTaskChainEvent(ctx, taskID)
+	if err != nil || last == nil || last.Holder == "" {
+		return nil
+	}
+	self := sessionHolder()
+	if last.Holder == self {
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339, last.CreatedAt)
+	if err != nil {
+		return nil
+	}
+	window := time.Duration(core.LoadProjectSettings(sm.ProjectRoot).TaskChain.LivenessMinutes) * time.Minute
+	idle := time.Since(at)
+	if idle >= window {
+		return nil
+	}
+
+	if takeover {
+		_, _ = sm.Memory.AppendTaskChainEvent(ctx, &core.TaskChainEvent{
+			TaskID:    taskID,
+			EventType: "takeover",
+			Payload:   last.Holder,
+			Holder:    self,
+		})
+		return nil
+	}
+
+	return mcp.NewToolResultError(fmt.Sprintf(
+		"任务链 %s 可能仍由其他会话驱动：%s 在 %s 前写入了事件 %s（存活窗口 %d 分钟）。\n"+
+			"为避免重复执行同一阶段，已拒绝恢复。确认对方已断开后使用 task_chain(mode=\"resume\", task_id=\"%s\", takeover=true) 接管。",
+		taskID, last.Holder, idle.Round(time.Second), last.EventType, int(window.Minutes()), taskID))
+}
+
+// ackRiskBudgetV3 记录用户对风险预算超支的确认
+func ackRiskBudgetV3(ctx context.Context, sm *SessionManager, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	chain, err := getOrLoadV3Chain(ctx, sm, args.TaskID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if chain.RiskBudget == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("任务链 %s 未声明风险预算", args.TaskID)), nil
+	}
+	if strings.TrimSpace(args.Summary) == "" {
+		return mcp.NewToolResultError("ack_risk 模式需要 summary（记录用户的确认内容）"), nil
+	}
+
+	chain.RiskBudget.Acknowledged = true
+	chain.RiskBudget.AckNote = args.Summary
+	_ = persistV3Chain(ctx, sm, chain, "ack_risk", chain.CurrentPhase, "", args.Summary)
+	return mcp.NewToolResultText(fmt.Sprintf("已记录风险确认: %s\n%s", args.Summary, renderRiskBudget(chain.RiskBudget))), nil
+}
+
 // finishChainV3 完成协议任务链
 func finishChainV3(ctx context.Context, sm *SessionManager, taskID string) (*mcp.CallToolResult, error) {
 	chain, err := getOrLoadV3Chain(ctx, sm, taskID)
@@ -569,13 +829,66 @@ func finishChainV3(ctx context.Context, sm *SessionManager, taskID string) (*mcp
 	return nil, nil // 由调用方统一输出
 }
 
+// normalizeChainWorkingDir 校验任务链工作目录，必须位于项目根目录内
+func normalizeChainWorkingDir(projectRoot, dir string) (string, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" || dir == "." {
+		return "", nil
+	}
+	if projectRoot == "" {
+		return filepath.ToSlash(filepath.Clean(dir)), nil
+	}
+	abs := dir
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(projectRoot, dir)
+	}
+	rel, err := filepath.Rel(projectRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("working_dir 必须位于项目目录内: %s", dir)
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("working_dir 不存在或不是目录: %s", dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// renderChainEnvironment 渲染任务链的执行环境（工作目录 + 环境变量）
+func renderChainEnvironment(chain *TaskChainV3) string {
+	if chain.WorkingDir == "" && len(chain.Env) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	if chain.WorkingDir != "" {
+		sb.WriteString(fmt.Sprintf("工作目录: %s\n", chain.WorkingDir))
+	}
+	if len(chain.Env) > 0 {
+		keys := make([]string, 0, len(chain.Env))
+		for k := range chain.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var pairs []string
+		for _, k := range keys {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", k, chain.Env[k]))
+		}
+		sb.WriteString(fmt.Sprintf("环境变量: %s\n", strings.Join(pairs, " ")))
+	}
+	return sb.String()
+}
+
 // ========== 渲染辅助 ==========
 
 func renderV3InitResult(chain *TaskChainV3) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("协议任务链已初始化: %s\n", chain.TaskID))
 	sb.WriteString(fmt.Sprintf("协议: %s\n", chain.Protocol))
-	sb.WriteString(fmt.Sprintf("阶段数: %d\n\n", len(chain.Phases)))
+	sb.WriteString(fmt.Sprintf("阶段数: %d\n", len(chain.Phases)))
+	sb.WriteString(renderChainEnvironment(chain))
+	sb.WriteString(renderRiskBudget(chain.RiskBudget))
+	if chain.Budget != nil {
+		sb.WriteString(fmt.Sprintf("执行预算: %s\n", chain.Budget.Summary()))
+	}
+	sb.WriteString("\n")
 
 	for _, p := range chain.Phases {
 		marker := "○"
@@ -629,10 +942,12 @@ func renderV3NextPhaseHint(chain *TaskChainV3, taskID, nextID string) string {
 
 func renderV3StatusJSON(chain *TaskChainV3) string {
 	type subTaskView struct {
-		ID      string `json:"id"`
-		Name    string `json:"name"`
-		Status  string `json:"status"`
-		Summary string `json:"summary,omitempty"`
+		ID        string   `json:"id"`
+		Name      string   `json:"name"`
+		Status    string   `json:"status"`
+		Summary   string   `json:"summary,omitempty"`
+		DependsOn []string `json:"depends_on,omitempty"`
+		Duration  string   `json:"duration,omitempty"`
 	}
 	type phaseView struct {
 		ID         string        `json:"id"`
@@ -644,14 +959,22 @@ func renderV3StatusJSON(chain *TaskChainV3) string {
 		SubTotal   int           `json:"sub_total,omitempty"`
 		SubDone    int           `json:"sub_done,omitempty"`
 		SubTasks   []subTaskView `json:"sub_tasks,omitempty"`
+		Parallel   bool          `json:"parallel,omitempty"`
+		Attempts   int           `json:"attempts,omitempty"`
+		Duration   string        `json:"duration,omitempty"`
 	}
 	type statusView struct {
-		TaskID       string      `json:"task_id"`
-		Description  string      `json:"description"`
-		Protocol     string      `json:"protocol"`
-		Status       string      `json:"status"`
-		CurrentPhase string      `json:"current_phase"`
-		Phases       []phaseView `json:"phases"`
+		TaskID       string            `json:"task_id"`
+		Description  string            `json:"description"`
+		Protocol     string            `json:"protocol"`
+		Status       string            `json:"status"`
+		CurrentPhase string            `json:"current_phase"`
+		WorkingDir   string            `json:"working_dir,omitempty"`
+		Env          map[string]string `json:"env,omitempty"`
+		RiskBudget   *RiskBudget       `json:"risk_budget,omitempty"`
+		Budget       *StepBudget       `json:"budget,omitempty"`
+		Elapsed      string            `json:"elapsed,omitempty"`
+		Phases       []phaseView       `json:"phases"`
 	}
 
 	sv := statusView{
@@ -660,6 +983,14 @@ func renderV3StatusJSON(chain *TaskChainV3) string {
 		Protocol:     chain.Protocol,
 		Status:       chain.Status,
 		CurrentPhase: chain.CurrentPhase,
+		WorkingDir:   chain.WorkingDir,
+		Env:          chain.Env,
+		RiskBudget:   chain.RiskBudget,
+		Budget:       chain.Budget,
+	}
+	now := time.Now()
+	if wall := chainWallClock(chain, now); wall > 0 {
+		sv.Elapsed = formatDuration(wall)
 	}
 
 	for _, p := range chain.Phases {
@@ -675,6 +1006,12 @@ func renderV3StatusJSON(chain *TaskChainV3) string {
 		if p.Type == PhaseGate && p.RetryCount > 0 {
 			pv.RetryCount = p.RetryCount
 		}
+		if d := p.spent(now); d > 0 {
+			pv.Duration = formatDuration(d)
+		}
+		if p.Attempts > 1 {
+			pv.Attempts = p.Attempts
+		}
 		if p.Type == PhaseLoop && len(p.SubTasks) > 0 {
 			pv.SubTotal = len(p.SubTasks)
 			var stViews []subTaskView
@@ -683,16 +1020,21 @@ func renderV3StatusJSON(chain *TaskChainV3) string {
 					pv.SubDone++
 				}
 				stv := subTaskView{
-					ID:     s.ID,
-					Name:   s.Name,
-					Status: string(s.Status),
+					ID:        s.ID,
+					Name:      s.Name,
+					Status:    string(s.Status),
+					DependsOn: s.DependsOn,
 				}
 				if s.Summary != "" {
 					stv.Summary = s.Summary
 				}
+				if d := s.duration(now); d > 0 {
+					stv.Duration = formatDuration(d)
+				}
 				stViews = append(stViews, stv)
 			}
 			pv.SubTasks = stViews
+			pv.Parallel = p.Parallel
 		}
 		sv.Phases = append(sv.Phases, pv)
 	}
@@ -701,11 +1043,12 @@ func renderV3StatusJSON(chain *TaskChainV3) string {
 	return string(data)
 }
 
-// buildPhasesFromProtocol 根据协议名称生成 Phase 列表（Phase 4 会扩展完整协议）
-func buildPhasesFromProtocol(protocol, description string) ([]Phase, error) {
+// buildPhasesFromProtocol 根据协议名称生成 Phase 列表；内置协议之外查找 .mcp-config/protocols.yaml
+func buildPhasesFromProtocol(projectRoot, protocol, description string) ([]Phase, error) {
 	switch protocol {
 	case "linear":
 		// linear 协议：单个 execute 阶段
+		// 与其他协议一样是 TaskChainV3，经 SaveTaskChain 持久化，resume/status 可从 DB 恢复
 		return []Phase{
 			{ID: "main", Name: "执行", Type: PhaseExecute, Status: PhasePending, Input: description},
 		}, nil
@@ -738,7 +1081,18 @@ func buildPhasesFromProtocol(protocol, description string) ([]Phase, error) {
 		}, nil
 
 	default:
-		return nil, fmt.Errorf("未知协议: %s（可用: linear, develop, debug, refactor）", protocol)
+		custom, err := loadCustomProtocols(projectRoot)
+		if err != nil {
+			return nil, err
+		}
+		names := append([]string{}, builtinProtocols...)
+		for _, p := range custom {
+			if p.Name == protocol {
+				return p.phases(description), nil
+			}
+			names = append(names, p.Name)
+		}
+		return nil, fmt.Errorf("未知协议: %s（可用: %s）", protocol, strings.Join(names, ", "))
 	}
 }
 
@@ -758,8 +1112,8 @@ func isV3TaskInDB(ctx context.Context, sm *SessionManager, taskID string) bool {
 	return err == nil && rec != nil
 }
 
-// renderProtocolList 列出可用协议
-func renderProtocolList() string {
+// renderProtocolList 列出可用协议（内置 + 项目自定义）
+func renderProtocolList(projectRoot string) string {
 	protocols := []struct {
 		Name string
 		Desc string
@@ -776,6 +1130,15 @@ func renderProtocolList() string {
 	for _, p := range protocols {
 		sb.WriteString(fmt.Sprintf("  %s - %s\n    %s\n\n", p.Name, p.Desc, p.Flow))
 	}
+	custom, err := loadCustomProtocols(projectRoot)
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("⚠️ 自定义协议加载失败: %v\n\n", err))
+	} else if len(custom) > 0 {
+		sb.WriteString("自定义协议 (.mcp-config/protocols.yaml):\n\n")
+		for _, p := range custom {
+			sb.WriteString(fmt.Sprintf("  %s - %s\n    %s\n\n", p.Name, fallback(p.Description, "（无描述）"), p.flow()))
+		}
+	}
 	sb.WriteString("使用方式:\n")
 	sb.WriteString("  task_chain(mode=\"init\", task_id=\"...\", protocol=\"develop\", description=\"...\")\n")
 	sb.WriteString("\n协议选择:\n")
@@ -783,5 +1146,7 @@ func renderProtocolList() string {
 	sb.WriteString("  - protocol=\"develop\"：跨模块开发，需要拆解子任务并逐个验证\n")
 	sb.WriteString("  - protocol=\"debug\"：问题复现→定位→修复→验证，可能需要多轮重试\n")
 	sb.WriteString("  - protocol=\"refactor\"：大范围重构，需要基线验证和逐步替换\n")
+	sb.WriteString("\n自定义协议: 在 .mcp-config/protocols.yaml 的 protocols 列表中声明 name/description/phases\n")
+	sb.WriteString("  （阶段字段: id, name, type=execute|gate|loop, input, on_pass, on_fail, max_retries, verify）\n")
 	return sb.String()
 }
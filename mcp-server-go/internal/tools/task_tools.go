@@ -6,6 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"mcp-server-go/internal/core"
+	"mcp-server-go/internal/services"
+
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -17,35 +20,58 @@ type HookCreateArgs struct {
 	TaskID         string `json:"task_id" jsonschema:"description=关联的任务 ID"`
 	Tag            string `json:"tag" jsonschema:"description=可选标签"`
 	ExpiresInHours int    `json:"expires_in_hours" jsonschema:"default=0,description=过期时间(小时), 0表示不过期"`
+	Quiet          bool   `json:"quiet" jsonschema:"description=精简输出 (去除横幅/emoji/提示)"`
 }
 
 // HookListArgs 列出 Hook 参数
 type HookListArgs struct {
-	Status string `json:"status" jsonschema:"default=open,enum=open,enum=closed,description=状态筛选"`
+	Status string `json:"status" jsonschema:"default=open,enum=open,enum=closed,enum=expired,description=状态筛选"`
+	Quiet  bool   `json:"quiet" jsonschema:"description=精简输出 (去除横幅/emoji/提示)"`
 }
 
 // HookReleaseArgs 释放 Hook 参数
 type HookReleaseArgs struct {
 	HookID        string `json:"hook_id" jsonschema:"required,description=Hook 编号 (如 #001)"`
 	ResultSummary string `json:"result_summary" jsonschema:"description=完成总结"`
+	Quiet         bool   `json:"quiet" jsonschema:"description=精简输出 (去除横幅/emoji/提示)"`
 }
 
 // TaskChainArgs 任务链参数
 type TaskChainArgs struct {
-	Mode        string                   `json:"mode" jsonschema:"required,enum=init,enum=resume,enum=start,enum=complete,enum=spawn,enum=complete_sub,enum=finish,enum=status,enum=protocol,description=操作模式"`
-	TaskID      string                   `json:"task_id" jsonschema:"required,description=任务ID"`
+	Mode        string                   `json:"mode" jsonschema:"required,enum=init,enum=resume,enum=start,enum=complete,enum=spawn,enum=complete_sub,enum=finish,enum=status,enum=protocol,enum=ack_risk,enum=experiments,enum=export,enum=import,enum=claim,enum=heartbeat,enum=history,enum=metrics,enum=ack_budget,description=操作模式"`
+	TaskID      string                   `json:"task_id" jsonschema:"description=任务ID (resume 模式可改用 resume_token)"`
 	Description string                   `json:"description" jsonschema:"description=任务描述 (init模式)"`
-	Protocol    string                   `json:"protocol" jsonschema:"description=协议名称 (init模式，如 develop/debug/refactor，不传则默认 linear)"`
+	Protocol    string                   `json:"protocol" jsonschema:"description=协议名称 (init模式，如 develop/debug/refactor 或 protocols.yaml 中的自定义协议，不传则默认 linear)"`
 	PhaseID     string                   `json:"phase_id" jsonschema:"description=阶段ID (start/complete/spawn/complete_sub模式)"`
 	Result      string                   `json:"result" jsonschema:"description=gate结果 pass/fail (complete gate模式) 或子任务结果 (complete_sub模式)"`
 	Summary     string                   `json:"summary" jsonschema:"description=步骤/阶段/子任务总结 (complete/complete_sub模式)"`
 	SubID       string                   `json:"sub_id" jsonschema:"description=子任务ID (complete_sub模式)"`
-	SubTasks    interface{}              `json:"sub_tasks" jsonschema:"description=子任务列表 (spawn模式)"`
+	SubTasks    interface{}              `json:"sub_tasks" jsonschema:"description=子任务列表 (spawn模式)，每项 {id, name, verify, depends_on}"`
 	Phases      interface{}              `json:"phases" jsonschema:"description=手动定义阶段列表 (init模式)"`
+	Files       []string                 `json:"files" jsonschema:"description=本任务将编辑的文件 (init模式，自动声明文件软锁)"`
+	WorkingDir  string                   `json:"working_dir" jsonschema:"description=验证命令执行目录，相对项目根目录 (init模式)"`
+	Env         map[string]string        `json:"env" jsonschema:"description=验证命令附加环境变量 (init模式)"`
+	Quiet       bool                     `json:"quiet" jsonschema:"description=精简输出 (去除横幅/emoji/提示，适合批量编排)"`
+	Takeover    bool                     `json:"takeover" jsonschema:"description=强制接管仍被其他会话驱动或持有的任务链 (resume/start/complete/spawn/complete_sub/claim模式)"`
+	ConfirmReinit bool                   `json:"confirm_reinit" jsonschema:"description=确认 re-init 差异后提交 (init模式，任务链已存在时)"`
+	MaxAffectedNodes int                 `json:"max_affected_nodes" jsonschema:"description=风险预算：链内 code_impact 累计影响节点上限 (init模式)"`
+	MaxHighRisk      int                 `json:"max_high_risk" jsonschema:"description=风险预算：可触及的高风险符号数上限 (init模式)"`
+	ResumeToken      string              `json:"resume_token" jsonschema:"description=阶段完成时签发的恢复令牌，可代替 task_id (resume模式)"`
+	Experiment       string              `json:"experiment" jsonschema:"description=对照实验名：记录该链的协议/耗时/重试/re-init (init模式)；experiments模式下按实验名过滤"`
+	VerifyDiff       bool                `json:"verify_diff" jsonschema:"description=核对 summary 声称修改的文件与 git 实际变更 (complete/complete_sub模式)"`
+	File             string              `json:"file" jsonschema:"description=导出/导入的 JSON 文件路径 (export 模式可选，import 模式必填)"`
+	Format           string              `json:"format" jsonschema:"enum=json,enum=mermaid,description=导出格式：json 完整历史（默认）/ mermaid 状态机图 (export模式)"`
+	AutoVerify       bool                `json:"auto_verify" jsonschema:"description=执行 gate/子任务的 verify 命令，按退出码决定 pass/fail (complete gate/complete_sub模式)"`
+	Parallel         bool                `json:"parallel" jsonschema:"description=依赖已满足的子任务同时开始，complete_sub 只校验 depends_on (spawn模式)"`
+	Limit            int                 `json:"limit" jsonschema:"description=只显示最近 N 条事件 (history模式，默认全部)"`
+	MaxSteps         int                 `json:"max_steps" jsonschema:"description=执行预算：complete/complete_sub 调用次数上限 (init/ack_budget模式)"`
+	MaxRetriesTotal  int                 `json:"max_retries_total" jsonschema:"description=执行预算：全链 gate 失败次数上限 (init/ack_budget模式)"`
+	MaxTokens        int                 `json:"max_tokens" jsonschema:"description=执行预算：估算 token 上限 (init/ack_budget模式)"`
+	TokensUsed       int                 `json:"tokens_used" jsonschema:"description=本步骤实际消耗的 token，计入执行预算；不传则按 summary 与输出长度估算 (complete/complete_sub模式)"`
 }
 
 // RegisterTaskTools 注册任务管理工具
-func RegisterTaskTools(s *server.MCPServer, sm *SessionManager) {
+func RegisterTaskTools(s *server.MCPServer, sm *SessionManager, ai *services.ASTIndexer) {
 	// Hook 系列
 	s.AddTool(mcp.NewTool("manager_create_hook",
 		mcp.WithDescription(`manager_create_hook - 创建并挂起待办事项 (钩子)
@@ -85,18 +111,22 @@ func RegisterTaskTools(s *server.MCPServer, sm *SessionManager) {
 		mcp.WithDescription(`manager_list_hooks - 查看待办钩子列表
 
 用途：
-  列出当前项目中所有处于挂起或已闭合状态的任务钩子。
+  列出当前项目中所有处于挂起、已过期或已闭合状态的任务钩子。
 
 参数：
   status (默认: open)
-    筛选钩子状态 (open: 待办 / closed: 已完成)。
+    筛选钩子状态 (open: 待办 / expired: 已过期未处理 / closed: 已完成)。
 
 说明：
   - 用于检索因阻塞而暂停的任务进度。
+  - 设置了 expires_in_hours 的钩子到期后自动转为 expired，需确认后用 manager_release_hook 闭合。
+  - 24 小时内即将过期的 open 钩子会标记 ⏳。
 
 示例：
   manager_list_hooks(status="open")
     -> 列出所有打开的待办项
+  manager_list_hooks(status="expired")
+    -> 列出已过期、尚未处理的待办项
 
 触发词：
   "mpm 待办列表", "mpm listhooks"`),
@@ -128,6 +158,106 @@ func RegisterTaskTools(s *server.MCPServer, sm *SessionManager) {
 		mcp.WithInputSchema[HookReleaseArgs](),
 	), wrapReleaseHook(sm))
 
+	s.AddTool(mcp.NewTool("manager_import_todos",
+		mcp.WithDescription(`manager_import_todos - 将 TODO/FIXME/HACK 注释导入为钩子
+
+用途：
+  用 ripgrep 扫描项目注释中的 TODO/FIXME/HACK，与已有钩子去重后批量创建钩子，
+  把散落在代码里的技术债变成可追踪、可召回的待办项。
+
+参数：
+  scope (可选)
+    仅扫描该子目录（相对项目根）。
+
+  create (默认: false)
+    false 仅预览候选；true 批量创建钩子。
+
+  limit (默认: 50)
+    单次最多导入条数。
+
+说明：
+  - 钩子标签为 file:line；优先级 FIXME=high、HACK=medium、TODO=low。
+  - 去重基于"标记 + 注释内容 + 文件"，代码行移动后不会重复导入；已关闭的钩子同样视为已导入。
+
+示例：
+  manager_import_todos(scope="internal", create=true)
+    -> 导入 internal 目录下的注释债务
+
+触发词：
+  "mpm 导入todo", "mpm todos"`),
+		mcp.WithInputSchema[ImportTodosArgs](),
+	), wrapImportTodos(sm))
+
+	s.AddTool(mcp.NewTool("claim_files",
+		mcp.WithDescription(`claim_files - 文件软锁 (多会话协作提醒)
+
+用途：
+  声明当前任务正在编辑的文件。其他会话/任务触碰同一文件时会收到带持有者 task_id 的警告。
+  软锁不阻止写入，到期自动失效。
+
+参数：
+  mode (默认: claim)
+    claim: 声明锁 / release: 释放锁 / list: 列出所有生效锁
+  
+  task_id (claim/release 必填)
+    持有锁的任务 ID。
+  
+  files (claim 必填)
+    文件路径列表（相对项目根目录）。
+  
+  ttl_minutes (默认: 30)
+    锁有效期（分钟）。
+
+说明：
+  - task_chain init 传入 files 时会自动声明，finish 时自动释放。
+  - memo 记录的 path 被其他任务锁定时会附带警告。
+
+示例：
+  claim_files(task_id="AUTH_FIX", files=["core/auth.go"])
+    -> 声明 auth.go 正在被 AUTH_FIX 编辑
+
+触发词：
+  "mpm 锁定文件", "mpm claim"`),
+		mcp.WithInputSchema[ClaimFilesArgs](),
+	), wrapClaimFiles(sm))
+
+	s.AddTool(mcp.NewTool("checkpoint",
+		mcp.WithDescription(`checkpoint - 编辑检查点与回滚
+
+用途：
+  高风险修改前把选定文件快照到 .mcp-data/checkpoints，改坏了可一键恢复。
+
+参数：
+  mode (默认: create)
+    create: 创建检查点 / rollback: 回滚 / list: 列出检查点 / show: 查看检查点详情
+  
+  files (create 必填)
+    文件或目录列表（相对项目根目录）。目录会展开为其中的文件；尚不存在的文件回滚时会被删除。
+  
+  name (可选)
+    检查点名称。
+  
+  task_id (可选)
+    关联任务。rollback 未传 id 时回滚该任务最近的检查点；list 时按任务过滤。
+  
+  id (rollback/show 必填，除非 rollback 传了 task_id)
+    检查点 ID。
+
+说明：
+  - rollback 前会自动把当前状态保存为 pre-rollback 检查点，回滚可撤销。
+  - task_chain 的 gate 达到最大重试次数时，会提示回滚到该任务最近的检查点。
+
+示例：
+  checkpoint(task_id="AUTH_FIX", name="重构前", files=["core/auth.go", "core/session/"])
+    -> 快照 auth.go 与 session 目录
+  checkpoint(mode="rollback", task_id="AUTH_FIX")
+    -> 恢复到 AUTH_FIX 最近的检查点
+
+触发词：
+  "mpm 检查点", "mpm 回滚", "mpm checkpoint"`),
+		mcp.WithInputSchema[CheckpointArgs](),
+	), wrapCheckpoint(sm))
+
 	// Task Chain - 状态机任务链
 	s.AddTool(mcp.NewTool("task_chain",
 		mcp.WithDescription(`task_chain - 任务链执行器 (协议状态机模式)
@@ -137,24 +267,77 @@ func RegisterTaskTools(s *server.MCPServer, sm *SessionManager) {
 
 参数：
   mode (必填):
-    - init: 初始化协议任务链（需要 task_id + description，可选 protocol 或 phases）
+    - init: 初始化协议任务链（需要 task_id + description，可选 protocol 或 phases，可选 files 声明文件软锁）
+      可选 working_dir/env：子模块有独立构建环境时，声明验证命令的执行目录与环境变量
+      任务链已存在时为 re-init：须提供新的 phases 或 protocol，首次调用只返回阶段差异与将丢失的进度，
+      确认后加 confirm_reinit=true 再次调用才会生效
+      可选 max_affected_nodes/max_high_risk：声明风险预算，code_impact(task_id=...) 会累计，超支后需 ack_risk 才能继续
+      可选 max_steps/max_retries_total/max_tokens：声明执行预算，每次 complete/complete_sub 累计（tokens_used 未传时按字数估算），
+      用量过半/超 80% 逐级警告，用尽后阻止继续推进，需总结进度并询问用户
     - start: 开始一个阶段（需要 task_id + phase_id）
     - complete: 完成一个阶段（需要 task_id + phase_id + summary，gate 需加 result）
     - spawn: 在 loop 阶段生成子任务（需要 task_id + phase_id + sub_tasks）
+      子任务可声明 depends_on；parallel=true 时互不依赖的子任务同时开始，完成顺序不限（需启用 parallel_subtasks 功能）
     - complete_sub: 完成子任务（需要 task_id + phase_id + sub_id + summary，可选 result）
+      complete/complete_sub 可加 verify_diff=true（或 settings.json 中 task_chain.verify_summary）：
+      核对 summary 提到的文件与阶段开始后 git 实际变更，列出"声称修改但无变更"与"有变更未提及"
+      gate/子任务声明了 verify 命令时可加 auto_verify=true：经命令白名单执行该命令，按退出码判定 pass/fail
+      （在任务链 working_dir/env 下运行，summary 可省略，结果与输出摘要自动附加）
     - status: 查看任务状态（自动识别协议并从 DB 加载进度）
-    - resume: 恢复/续传任务
+    - resume: 恢复/续传任务（若其他会话近期仍在推进该链会拒绝，需加 takeover=true 接管）
+      每次 complete 会附带 resume_token，丢失上下文时只传 resume_token 即可恢复，无需 task_id
+    - claim: 声明任务链所有权（被其他会话持有且租约未过期时需加 takeover=true）
+    - heartbeat: 为当前会话持有的任务链续租（长时间无 start/complete 时使用）
+    - history: 按时间回放任务链事件（谁/何时/哪个阶段/gate 结果/重试），可选 limit 只看最近 N 条
+      resume 输出会附带最近几条事件
+    - metrics: 耗时统计（总耗时、各阶段用时与占比、重试开销、阶段间空闲、最慢子任务）
+      status/resume 中各阶段与子任务也会显示 duration
+      init/start/complete/spawn/complete_sub 会自动声明并续租所有权（租约见 settings.json task_chain.lease_minutes，默认 30 分钟），
+      其他会话持有时拒绝操作，需加 takeover=true 接管；finish 时释放
     - finish: 彻底完成并关闭任务链
-    - protocol: 列出可用协议
+    - protocol: 列出可用协议（含 .mcp-config/protocols.yaml 中的自定义协议）
+    - ack_risk: 风险预算超支后记录用户确认（需要 task_id + summary）
+    - ack_budget: 执行预算用尽后记录用户确认并追加预算（需要 task_id + summary，可传新的 max_* 上限，否则按原上限一半追加）
+    - experiments: 协议对照实验汇总（init 时传 experiment 的链，按实验+协议对比完成率/耗时/重试/re-init）
+    - export: 导出完整任务链（阶段、子任务、事件、summary 附件）为 JSON（需要 task_id，可选 file）
+      format="mermaid" 时改为渲染状态机图（阶段、gate pass/fail 跳转、loop 进度、当前位置），保存为 .mmd
+    - import: 从 export 生成的 JSON 恢复任务链（需要 file，可选 task_id 以新 ID 导入），用于跨机器迁移或附在问题报告中
 
 说明：
+  - quiet=true（或 settings.json 中 output.quiet）时去除横幅/emoji/提示，仅保留数据行。
+  - summary 超过 task_chain.max_summary_chars（默认 2000 字）时截断入库、全文另存附件；
+    task_chain.summary_overflow="reject" 时直接拒绝。summary 只写结论，不要粘贴完整命令输出。
   - 默认使用 linear 协议（线性执行）。
   - 大工程推荐使用 develop 协议，利用 loop 阶段拆解子任务。
 
 触发词：
   "mpm 任务链", "mpm 续传", "mpm chain"`),
 		mcp.WithInputSchema[TaskChainArgs](),
-	), wrapTaskChain(sm))
+	), wrapTaskChain(sm, ai))
+
+	s.AddTool(mcp.NewTool("request_review",
+		mcp.WithDescription(`request_review - 评审请求打包
+
+用途：
+  任务链完成（或需要中途评审）时，把代理做出的改动整理成一份评审文档交给人工审阅：
+  - 变更统计：任务链 git 基线以来的逐文件增删行数
+  - 阶段摘要：各阶段/子任务的 summary
+  - 影响分析：链内 code_impact(task_id=...) 的记录与风险预算
+  - 未解决风险：门控失败/重试、跳过的阶段、预算超支、摘要与实际改动不一致、未关闭钩子
+  - 建议重点审阅：变更文件中的高复杂度符号
+
+参数：
+  task_id (必填)
+  output (可选)
+    输出文件路径，默认数据目录下 reviews/<task_id>.md
+  post_pr (可选)
+    PR 编号或 URL，非空时执行 gh pr comment 发布评论。
+    需在 .mcp-config/settings.json 的 commands.allow 中加入 "gh pr comment"。
+
+触发词：
+  "mpm 评审", "mpm review", "提交评审"`),
+		mcp.WithInputSchema[RequestReviewArgs](),
+	), wrapRequestReview(sm, ai))
 }
 
 func wrapCreateHook(sm *SessionManager) server.ToolHandlerFunc {
@@ -173,7 +356,8 @@ func wrapCreateHook(sm *SessionManager) server.ToolHandlerFunc {
 			return mcp.NewToolResultError(fmt.Sprintf("创建 Hook 失败: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("📌 Hook 已创建 (ID: %s)\n\n**描述**: %s\n**优先级**: %s\n\n> 使用 `manager_release_hook(hook_id=\"%s\")` 释放此 Hook。", id, args.Description, args.Priority, id)), nil
+		result := mcp.NewToolResultText(fmt.Sprintf("📌 Hook 已创建 (ID: %s)\n\n**描述**: %s\n**优先级**: %s\n\n> 使用 `manager_release_hook(hook_id=\"%s\")` 释放此 Hook。", id, args.Description, args.Priority, id))
+		return applyQuiet(result, resolveQuiet(sm, args.Quiet)), nil
 	}
 }
 
@@ -190,6 +374,9 @@ func wrapListHooks(sm *SessionManager) server.ToolHandlerFunc {
 			return mcp.NewToolResultError("记忆层尚未初始化"), nil
 		}
 
+		// 先把已到期的 open 钩子转为 expired，避免两次后台清扫之间的视图滞后
+		_, _ = sm.Memory.ExpireHooks(ctx)
+
 		hooks, err := sm.Memory.ListHooks(ctx, args.Status)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("查询 Hook 失败: %v", err)), nil
@@ -205,7 +392,9 @@ func wrapListHooks(sm *SessionManager) server.ToolHandlerFunc {
 			expiration := ""
 			if h.ExpiresAt.Valid {
 				if time.Now().After(h.ExpiresAt.Time) {
-					expiration = " (EXPIRED)"
+					expiration = fmt.Sprintf(" (EXPIRED %s)", h.ExpiresAt.Time.Format("01-02 15:04"))
+				} else if time.Until(h.ExpiresAt.Time) <= core.HookExpiringSoonWindow {
+					expiration = fmt.Sprintf(" ⏳ (Exp: %s)", h.ExpiresAt.Time.Format("01-02 15:04"))
 				} else {
 					expiration = fmt.Sprintf(" (Exp: %s)", h.ExpiresAt.Time.Format("01-02 15:04"))
 				}
@@ -224,7 +413,7 @@ func wrapListHooks(sm *SessionManager) server.ToolHandlerFunc {
 			sb.WriteString(fmt.Sprintf("- **%s** (ID: %s) [%s]%s %s%s\n", displayID, h.HookID, h.Priority, taskDraft, h.Description, expiration))
 		}
 
-		return mcp.NewToolResultText(sb.String()), nil
+		return applyQuiet(mcp.NewToolResultText(sb.String()), resolveQuiet(sm, args.Quiet)), nil
 	}
 }
 
@@ -244,38 +433,110 @@ func wrapReleaseHook(sm *SessionManager) server.ToolHandlerFunc {
 			return mcp.NewToolResultError(fmt.Sprintf("释放 Hook 失败: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("✅ Hook %s 已释放。\n\n**结果摘要**: %s", args.HookID, args.ResultSummary)), nil
+		result := mcp.NewToolResultText(fmt.Sprintf("✅ Hook %s 已释放。\n\n**结果摘要**: %s", args.HookID, args.ResultSummary))
+		return applyQuiet(result, resolveQuiet(sm, args.Quiet)), nil
 	}
 }
 
-func wrapTaskChain(sm *SessionManager) server.ToolHandlerFunc {
+func wrapTaskChain(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var args TaskChainArgs
 		if err := request.BindArguments(&args); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
 		}
 
-		switch args.Mode {
-		case "init":
-			return initTaskChainV3(ctx, sm, args)
-		case "spawn":
-			return spawnSubTasksV3(ctx, sm, args)
-		case "complete_sub":
-			return completeSubTaskV3(ctx, sm, args)
-		case "protocol":
-			return mcp.NewToolResultText(renderProtocolList()), nil
-		case "start":
-			return startPhaseV3(ctx, sm, args)
-		case "complete":
-			return completePhaseV3(ctx, sm, args)
-		case "status", "resume":
-			return resumeTaskChainV3(ctx, sm, args.TaskID)
-		case "finish":
-			_, _ = finishChainV3(ctx, sm, args.TaskID)
-			return mcp.NewToolResultText(fmt.Sprintf("\n══════════════════════════════════════════════════════════════\n                    【任务链完成】%s\n══════════════════════════════════════════════════════════════\n\n任务已标记为完成。\n\n下一步建议：\n  → 调用 memo 工具记录最终结果\n  → 向用户汇报任务完成\n", args.TaskID)), nil
-		default:
-			return mcp.NewToolResultError(fmt.Sprintf("未知模式: %s", args.Mode)), nil
+		res, err := dispatchTaskChain(ctx, sm, ai, args)
+		return applyQuiet(res, resolveQuiet(sm, args.Quiet)), err
+	}
+}
+
+// dispatchTaskChain 按 mode 分发任务链操作
+func dispatchTaskChain(ctx context.Context, sm *SessionManager, ai *services.ASTIndexer, args TaskChainArgs) (*mcp.CallToolResult, error) {
+	switch args.Mode {
+	case "init", "start", "complete", "spawn", "complete_sub":
+		// 已有链被其他会话持有时拒绝修改（新链尚未持久化，此处直接放行）
+		if res := ensureChainOwner(ctx, sm, args.TaskID, args.Takeover); res != nil {
+			return res, nil
+		}
+	}
+
+	switch args.Mode {
+	case "init":
+		res, err := initTaskChainV3(ctx, sm, args)
+		if res != nil && !res.IsError {
+			_ = ensureChainOwner(ctx, sm, args.TaskID, false)
+		}
+		return res, err
+	case "claim", "heartbeat":
+		return claimChainV3(ctx, sm, args)
+	case "history":
+		return chainHistoryV3(ctx, sm, args)
+	case "metrics":
+		return chainMetricsV3(ctx, sm, args)
+	case "spawn":
+		return spawnSubTasksV3(ctx, sm, args)
+	case "complete_sub":
+		res, err := completeSubTaskV3(ctx, sm, args)
+		res = recordStepBudget(ctx, sm, args, res)
+		return appendComplexityAlerts(res, sm, ai, args.Summary), err
+	case "protocol":
+		return mcp.NewToolResultText(renderProtocolList(sm.ProjectRoot)), nil
+	case "ack_risk":
+		return ackRiskBudgetV3(ctx, sm, args)
+	case "ack_budget":
+		return ackBudgetV3(ctx, sm, args)
+	case "experiments":
+		return experimentStatsV3(ctx, sm, strings.TrimSpace(args.Experiment))
+	case "export":
+		if strings.EqualFold(strings.TrimSpace(args.Format), "mermaid") {
+			return exportChainDiagramV3(ctx, sm, args)
+		}
+		return exportTaskChainV3(ctx, sm, args)
+	case "import":
+		return importTaskChainV3(ctx, sm, args)
+	case "start":
+		return startPhaseV3(ctx, sm, args)
+	case "complete":
+		res, err := completePhaseV3(ctx, sm, args)
+		res = recordStepBudget(ctx, sm, args, res)
+		res = appendComplexityAlerts(res, sm, ai, args.Summary)
+		return appendResumeToken(ctx, sm, res, args.TaskID), err
+	case "status", "resume":
+		note := ""
+		if args.Mode == "resume" {
+			if strings.TrimSpace(args.ResumeToken) != "" {
+				taskID, tokenNote, err := resolveResumeToken(ctx, sm, args.ResumeToken)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				args.TaskID, note = taskID, tokenNote
+			}
+			if res := checkChainLiveness(ctx, sm, args.TaskID, args.Takeover); res != nil {
+				return res, nil
+			}
+			if args.Takeover && sm.Memory != nil {
+				_, _ = sm.Memory.ClaimTaskChain(ctx, args.TaskID, sessionHolder(), chainLease(sm), true)
+			}
+		}
+		res, err := resumeTaskChainV3(ctx, sm, args.TaskID)
+		if note != "" && res != nil && !res.IsError {
+			res.Content = append([]mcp.Content{mcp.NewTextContent(note)}, res.Content...)
+		}
+		if args.Mode == "resume" && res != nil && !res.IsError {
+			if history := recentChainHistory(ctx, sm, args.TaskID); history != "" {
+				res.Content = append(res.Content, mcp.NewTextContent(history))
+			}
+		}
+		return res, err
+	case "finish":
+		_, _ = finishChainV3(ctx, sm, args.TaskID)
+		if sm.Memory != nil {
+			_, _ = sm.Memory.ReleaseFileLocks(ctx, args.TaskID, nil)
+			_ = sm.Memory.ReleaseTaskChain(ctx, args.TaskID, sessionHolder())
 		}
+		return mcp.NewToolResultText(fmt.Sprintf("\n══════════════════════════════════════════════════════════════\n                    【任务链完成】%s\n══════════════════════════════════════════════════════════════\n\n任务已标记为完成。\n\n下一步建议：\n  → 调用 memo 工具记录最终结果\n  → 向用户汇报任务完成\n", args.TaskID)), nil
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("未知模式: %s", args.Mode)), nil
 	}
 }
 
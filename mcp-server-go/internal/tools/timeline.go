@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ============================================================================
+// open_timeline：Go 原生生成 project_timeline.html（不再依赖 Python）
+// ============================================================================
+
+const timelineFileName = "project_timeline.html"
+
+var timelineTmpl = template.Must(template.New("timeline").Parse(timelineHTMLTemplate))
+
+// timelineItem 页面脚本消费的条目（字段名与页面 JS 一致）
+type timelineItem struct {
+	ID         int64  `json:"id,omitempty"`
+	Category   string `json:"category"`
+	Entity     string `json:"entity"`
+	Act        string `json:"act,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Content    string `json:"content"`
+	Timestamp  string `json:"timestamp"`
+	GitCommit  string `json:"git_commit,omitempty"`
+	GitDirty   string `json:"git_dirty,omitempty"`
+	Annotation string `json:"annotation,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Source     string `json:"source,omitempty"`
+}
+
+// 项目活动来源在页面上的分类名
+var timelineSourceCategories = map[string]string{
+	core.TimelineSourceTaskChain: "任务链",
+	core.TimelineSourceHook:      "钩子",
+	core.TimelineSourceFact:      "事实",
+}
+
+var timelineHookActs = map[string]string{"created": "创建", "released": "释放"}
+
+// collectTimelineItems memo、人工标注与项目活动（任务链事件/钩子/事实），页面按时间排序
+func collectTimelineItems(ctx context.Context, mem *core.MemoryLayer) ([]timelineItem, error) {
+	memos, err := mem.TimelineMemos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 页面通过 innerHTML 拼接条目，文本字段先做 HTML 转义
+	esc := template.HTMLEscapeString
+	items := make([]timelineItem, 0, len(memos))
+	for _, m := range memos {
+		items = append(items, timelineItem{
+			ID: m.ID, Category: m.Category, Entity: esc(m.Entity), Act: esc(m.Act), Path: esc(m.Path),
+			Content: esc(m.Content), Timestamp: m.Timestamp.UTC().Format(time.RFC3339),
+			GitCommit: m.GitCommit, GitDirty: esc(m.GitDirty), Source: "memo",
+		})
+	}
+
+	annotations, err := mem.ListTimelineAnnotations(ctx, "", core.TimeRange{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(annotations) - 1; i >= 0; i-- { // 倒序 → 升序
+		a := annotations[i]
+		items = append(items, timelineItem{
+			Category: "标注", Annotation: fallback(a.Kind, "note"), Entity: esc(a.Title), Content: esc(a.Content),
+			Author: esc(a.Author), Timestamp: a.At.Format(time.RFC3339), Source: "annotation",
+		})
+	}
+
+	events, err := mem.TimelineActivity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		act := e.Kind
+		if e.Source == core.TimelineSourceHook {
+			act = fallback(timelineHookActs[e.Kind], e.Kind)
+		}
+		items = append(items, timelineItem{
+			Category: timelineSourceCategories[e.Source], Entity: esc(e.Title), Act: esc(act), Content: esc(e.Content),
+			Timestamp: e.At.UTC().Format(time.RFC3339), Source: e.Source,
+		})
+	}
+	return items, nil
+}
+
+// renderTimelineHTML 渲染时间线页面
+func renderTimelineHTML(projectName string, items []timelineItem) ([]byte, error) {
+	if items == nil {
+		items = []timelineItem{}
+	}
+	var buf bytes.Buffer
+	err := timelineTmpl.Execute(&buf, struct {
+		ProjectName string
+		Items       []timelineItem
+	}{fallback(projectName, "Project"), items})
+	return buf.Bytes(), err
+}
+
+// generateTimeline 生成 <project_root>/project_timeline.html，返回文件路径与条目数
+func generateTimeline(ctx context.Context, sm *SessionManager) (string, int, error) {
+	items, err := collectTimelineItems(ctx, sm.Memory)
+	if err != nil {
+		return "", 0, fmt.Errorf("读取时间线数据失败: %w", err)
+	}
+	page, err := renderTimelineHTML(filepath.Base(sm.ProjectRoot), items)
+	if err != nil {
+		return "", 0, fmt.Errorf("渲染时间线失败: %w", err)
+	}
+	htmlPath := filepath.Join(sm.ProjectRoot, timelineFileName)
+	if err := os.WriteFile(htmlPath, page, 0644); err != nil {
+		return "", 0, err
+	}
+	return htmlPath, len(items), nil
+}
+
+// generateTimelineWithPython 旧版兜底：执行项目中的 visualize_history.py
+func generateTimelineWithPython(root string) (string, error) {
+	scriptPath := filepath.Join(root, "scripts", "visualize_history.py")
+	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+		scriptPath = filepath.Join(root, "visualize_history.py")
+		if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+			return "", fmt.Errorf("找不到生成脚本 visualize_history.py (checked scripts/ and root)")
+		}
+	}
+	cmd := exec.Command("python", scriptPath)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), core.DataDirEnv+"="+core.DataDir(root))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("%v\nOutput: %s", err, output)
+	}
+	htmlPath := filepath.Join(root, timelineFileName)
+	if _, err := os.Stat(htmlPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("脚本执行成功但未生成 %s", timelineFileName)
+	}
+	return htmlPath, nil
+}
+
+// openInBrowser 用系统默认方式打开页面（Windows 优先 Edge 应用窗口）
+func openInBrowser(target string) error {
+	switch runtime.GOOS {
+	case "windows":
+		if err := exec.Command("cmd", "/c", "start", "msedge", "--app="+target).Start(); err == nil {
+			return nil
+		}
+		return exec.Command("cmd", "/c", "start", target).Start()
+	case "darwin":
+		return exec.Command("open", target).Start()
+	default:
+		return exec.Command("xdg-open", target).Start()
+	}
+}
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// open_timeline(live=true)：本机 HTTP 服务 + SSE 推送，时间线随记忆写入自动刷新
+// ============================================================================
+
+// liveTimelinePoll 轮询数据指纹的间隔（测试可调小）
+var liveTimelinePoll = 2 * time.Second
+
+// liveTimelineHeartbeat SSE 心跳间隔，防止代理/浏览器断开空闲连接
+const liveTimelineHeartbeat = 15 * time.Second
+
+// liveTimelineScript 注入到页面末尾：收到 update 事件即重新加载，断线由 EventSource 自动重连
+const liveTimelineScript = `<div id="mpm-live-badge" style="position:fixed;right:16px;bottom:16px;z-index:50;padding:4px 10px;border-radius:9999px;font:600 12px sans-serif;background:#16a34a;color:#fff;box-shadow:0 1px 4px rgba(0,0,0,.2)">● LIVE</div>
+<script>
+(function () {
+    const badge = document.getElementById('mpm-live-badge');
+    const es = new EventSource('/events');
+    es.addEventListener('update', () => location.reload());
+    es.onopen = () => { badge.style.background = '#16a34a'; badge.textContent = '● LIVE'; };
+    es.onerror = () => { badge.style.background = '#94a3b8'; badge.textContent = '○ 重连中'; };
+})();
+</script>
+</body>`
+
+// liveTimelineServer 单个实时时间线服务（每个 MCP 进程至多一个）
+type liveTimelineServer struct {
+	sm      *SessionManager
+	srv     *http.Server
+	url     string
+	ctx     context.Context // 关闭服务时取消：停止轮询并结束所有 SSE 连接
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+}
+
+var liveTimeline struct {
+	mu sync.Mutex
+	s  *liveTimelineServer
+}
+
+// startLiveTimeline 启动（或复用已运行的）实时时间线服务，返回访问地址。port 为 0 时由系统分配
+func startLiveTimeline(sm *SessionManager, port int) (string, bool, error) {
+	liveTimeline.mu.Lock()
+	defer liveTimeline.mu.Unlock()
+	if liveTimeline.s != nil {
+		return liveTimeline.s.url, true, nil
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return "", false, fmt.Errorf("监听端口失败: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ls := &liveTimelineServer{
+		sm:      sm,
+		url:     fmt.Sprintf("http://%s/", ln.Addr().String()),
+		ctx:     ctx,
+		cancel:  cancel,
+		clients: make(map[chan string]struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", ls.handlePage)
+	mux.HandleFunc("/events", ls.handleEvents)
+	ls.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := ls.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "[Timeline][WARN] 实时时间线服务退出: %v\n", err)
+		}
+	}()
+	go ls.watch()
+
+	liveTimeline.s = ls
+	return ls.url, false, nil
+}
+
+// stopLiveTimeline 关闭实时时间线服务（未运行时无操作）
+func stopLiveTimeline() {
+	liveTimeline.mu.Lock()
+	ls := liveTimeline.s
+	liveTimeline.s = nil
+	liveTimeline.mu.Unlock()
+	if ls == nil {
+		return
+	}
+	ls.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ls.srv.Shutdown(ctx)
+}
+
+func (ls *liveTimelineServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	root, mem := ls.sm.Binding()
+	if mem == nil {
+		http.Error(w, "项目未初始化，请先调用 initialize_project", http.StatusServiceUnavailable)
+		return
+	}
+	items, err := collectTimelineItems(r.Context(), mem)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("读取时间线数据失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	page, err := renderTimelineHTML(filepath.Base(root), items)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("渲染时间线失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if i := bytes.LastIndex(page, []byte("</body>")); i >= 0 {
+		page = append(page[:i:i], append([]byte(liveTimelineScript), page[i+len("</body>"):]...)...)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(page)
+}
+
+func (ls *liveTimelineServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan string, 1)
+	ls.mu.Lock()
+	ls.clients[ch] = struct{}{}
+	ls.mu.Unlock()
+	defer func() {
+		ls.mu.Lock()
+		delete(ls.clients, ch)
+		ls.mu.Unlock()
+	}()
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(liveTimelineHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ls.ctx.Done():
+			return
+		case sig := <-ch:
+			fmt.Fprintf(w, "event: update\ndata: %s\n\n", sig)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// watch 轮询时间线数据指纹，变化时通知所有已连接的页面
+func (ls *liveTimelineServer) watch() {
+	ticker := time.NewTicker(liveTimelinePoll)
+	defer ticker.Stop()
+	last := ""
+	for {
+		select {
+		case <-ls.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, mem := ls.sm.Binding()
+		if mem == nil {
+			continue
+		}
+		sig, err := mem.TimelineSignature(ls.ctx)
+		if err != nil {
+			continue
+		}
+		if last != "" && sig != last {
+			ls.broadcast(sig)
+		}
+		last = sig
+	}
+}
+
+func (ls *liveTimelineServer) broadcast(sig string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for ch := range ls.clients {
+		select {
+		case ch <- sig:
+		default: // 上一次通知尚未发出，页面反正会整体刷新
+		}
+	}
+}
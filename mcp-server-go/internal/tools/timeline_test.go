@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestGenerateTimeline_NativeHTML(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mem, err := core.NewMemoryLayer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+	if _, err := mem.AddMemos(ctx, []core.Memo{{Category: "修改", Entity: "auth", Act: "修复", Path: "auth.go", Content: "修复 </script><b>登录</b>"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.AddTimelineAnnotation(ctx, core.TimelineAnnotation{Kind: "release", Title: "v1.0 发布", Author: "alice", At: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	path, n, err := generateTimeline(ctx, &SessionManager{Memory: mem, ProjectRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("items = %d, want memo + annotation", n)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(raw)
+	if strings.Count(page, "</script>") != strings.Count(timelineHTMLTemplate, "</script>") {
+		t.Error("memo content must not be able to close the <script> block")
+	}
+	for _, want := range []string{"const rawData = [", `"category":"修改"`, `"annotation":"release"`, "v1.0 发布"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("timeline page missing %q", want)
+		}
+	}
+	if strings.Contains(page, "{{") {
+		t.Error("template placeholders left unrendered")
+	}
+}
+
+func TestCollectTimelineItems_Activity(t *testing.T) {
+	ctx := context.Background()
+	mem, err := core.NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+	hookID, err := mem.CreateHook(ctx, "补充登录测试", "high", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.ReleaseHook(ctx, hookID, "测试已补齐"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.SaveFact(ctx, "铁律", "禁止直接修改生成文件"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SaveTaskChain(ctx, &core.TaskChainRecord{TaskID: "T1", Description: "登录重构", Status: "running"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.AppendTaskChainEvent(ctx, &core.TaskChainEvent{TaskID: "T1", PhaseID: "p1", EventType: "phase_complete"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := collectTimelineItems(ctx, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, it := range items {
+		got[it.Source+"/"+it.Category+"/"+it.Act] = true
+	}
+	for _, want := range []string{"hook/钩子/创建", "hook/钩子/释放", "fact/事实/铁律", "task_chain/任务链/phase_complete @p1"} {
+		if !got[want] {
+			t.Errorf("missing timeline item %q, got %v", want, got)
+		}
+	}
+}
+
+func TestLiveTimeline_PushesUpdates(t *testing.T) {
+	ctx := context.Background()
+	mem, err := core.NewMemoryLayer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mem.FlushDevLog)
+	old := liveTimelinePoll
+	liveTimelinePoll = 20 * time.Millisecond
+	defer func() { liveTimelinePoll = old }()
+
+	url, _, err := startLiveTimeline(&SessionManager{Memory: mem, ProjectRoot: t.TempDir()}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopLiveTimeline()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), "new EventSource('/events')") {
+		t.Fatal("live page must subscribe to /events")
+	}
+
+	events, err := http.Get(url + "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+	time.Sleep(100 * time.Millisecond) // 等待首次指纹
+	if _, err := mem.AddMemos(ctx, []core.Memo{{Category: "开发", Entity: "live", Act: "新增", Path: "live.go", Content: "实时推送"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan bool, 1)
+	go func() {
+		sc := bufio.NewScanner(events.Body)
+		for sc.Scan() {
+			if sc.Text() == "event: update" {
+				got <- true
+				return
+			}
+		}
+		got <- false
+	}()
+	select {
+	case ok := <-got:
+		if !ok {
+			t.Fatal("event stream closed before update")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update pushed after memo write")
+	}
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ========== TODO/FIXME/HACK 注释导入为钩子 ==========
+
+// ImportTodosArgs 注释导入参数
+type ImportTodosArgs struct {
+	Scope  string `json:"scope" jsonschema:"description=仅扫描该子目录（相对项目根）"`
+	Create bool   `json:"create" jsonschema:"description=true 时批量创建钩子；默认仅预览"`
+	Limit  int    `json:"limit" jsonschema:"default=50,description=单次最多导入条数"`
+	Quiet  bool   `json:"quiet" jsonschema:"description=精简输出 (去除横幅/emoji/提示)"`
+}
+
+// todoCommentPattern 注释标记后的 TODO/FIXME/HACK，兼容 ripgrep 与 Go 正则语法
+const todoCommentPattern = `(//|#|/\*|\*|--|<!--)\s*(TODO|FIXME|HACK)\b`
+
+var todoCommentRe = regexp.MustCompile(todoCommentPattern + `\s*(\([^)]*\))?\s*[:：]?\s*(.*)`)
+
+// TodoItem 扫描到的注释债务
+type TodoItem struct {
+	Marker string
+	Text   string
+	File   string
+	Line   int
+}
+
+// Description 钩子描述，同时作为去重键（不含行号，代码行移动后不会重复导入）
+func (t TodoItem) Description() string {
+	return fmt.Sprintf("[%s] %s (%s)", t.Marker, t.Text, t.File)
+}
+
+func todoPriority(marker string) string {
+	switch marker {
+	case "FIXME":
+		return "high"
+	case "HACK":
+		return "medium"
+	}
+	return "low"
+}
+
+// scanTodoComments 用 ripgrep 扫描注释中的 TODO/FIXME/HACK
+func scanTodoComments(ctx context.Context, projectRoot, scope string) ([]TodoItem, error) {
+	root := projectRoot
+	if s := strings.Trim(filepath.ToSlash(strings.TrimSpace(scope)), "/"); s != "" && s != "." {
+		root = filepath.Join(projectRoot, filepath.FromSlash(s))
+	}
+	matches, err := services.NewRipgrepEngine().Search(ctx, services.SearchOptions{
+		Query:         todoCommentPattern,
+		RootPath:      root,
+		IsRegex:       true,
+		CaseSensitive: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []TodoItem
+	for _, m := range matches {
+		sub := todoCommentRe.FindStringSubmatch(m.Content)
+		if sub == nil {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sub[4]), "*/"))
+		text = strings.TrimSpace(strings.TrimSuffix(text, "-->"))
+		if text == "" {
+			continue
+		}
+		rel := m.FilePath
+		if r, err := filepath.Rel(projectRoot, m.FilePath); err == nil {
+			rel = r
+		}
+		items = append(items, TodoItem{
+			Marker: sub[2],
+			Text:   truncateRunes(text, 160),
+			File:   filepath.ToSlash(rel),
+			Line:   m.LineNumber,
+		})
+	}
+	return items, nil
+}
+
+func wrapImportTodos(sm *SessionManager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args ImportTodosArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+		if sm.Memory == nil || sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("记忆层尚未初始化"), nil
+		}
+		if args.Limit <= 0 {
+			args.Limit = 50
+		}
+
+		items, err := scanTodoComments(ctx, sm.ProjectRoot, args.Scope)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("扫描失败: %v", err)), nil
+		}
+
+		existing := make(map[string]bool)
+		for _, status := range []string{"open", "closed", "expired"} {
+			hooks, err := sm.Memory.ListHooks(ctx, status)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("查询 Hook 失败: %v", err)), nil
+			}
+			for _, h := range hooks {
+				existing[h.Description] = true
+			}
+		}
+
+		var fresh []TodoItem
+		duplicates := 0
+		for _, it := range items {
+			desc := it.Description()
+			if existing[desc] {
+				duplicates++
+				continue
+			}
+			existing[desc] = true // 同一注释在多处重复出现时只导入一次
+			fresh = append(fresh, it)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("### 🧾 注释债务扫描\n\n扫描到 %d 条 TODO/FIXME/HACK，已有钩子 %d 条，新增候选 %d 条。\n\n", len(items), duplicates, len(fresh)))
+		if len(fresh) == 0 {
+			return applyQuiet(mcp.NewToolResultText(sb.String()), resolveQuiet(sm, args.Quiet)), nil
+		}
+
+		batch := fresh
+		if len(batch) > args.Limit {
+			batch = batch[:args.Limit]
+		}
+		created := 0
+		for _, it := range batch {
+			tag := fmt.Sprintf("%s:%d", it.File, it.Line)
+			if !args.Create {
+				sb.WriteString(fmt.Sprintf("- [%s] %s `%s`\n", todoPriority(it.Marker), it.Description(), tag))
+				continue
+			}
+			id, err := sm.Memory.CreateHook(ctx, it.Description(), todoPriority(it.Marker), tag, "", 0)
+			if err != nil {
+				sb.WriteString(fmt.Sprintf("- ❌ %s: %v\n", tag, err))
+				continue
+			}
+			created++
+			sb.WriteString(fmt.Sprintf("- 📌 %s %s `%s`\n", id, it.Description(), tag))
+		}
+		if len(fresh) > len(batch) {
+			sb.WriteString(fmt.Sprintf("- ... 另有 %d 条，提高 limit 或再次调用继续导入\n", len(fresh)-len(batch)))
+		}
+
+		if args.Create {
+			sb.WriteString(fmt.Sprintf("\n✅ 已创建 %d 个钩子。\n", created))
+		} else {
+			sb.WriteString("\n> 以上为预览，确认后使用 `manager_import_todos(create=true)` 批量创建钩子。\n")
+		}
+		return applyQuiet(mcp.NewToolResultText(sb.String()), resolveQuiet(sm, args.Quiet)), nil
+	}
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mcp-server-go/internal/services"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// WarmUpArgs 会话预热参数
+type WarmUpArgs struct {
+	Scope    string `json:"scope" jsonschema:"description=预热范围 (目录，留空=整个项目)"`
+	Keywords string `json:"keywords" jsonschema:"description=记忆预取关键词 (留空则从 scope 推断)"`
+}
+
+// warmUpStep 单个预热步骤的结果
+type warmUpStep struct {
+	Name    string
+	OK      bool
+	Detail  string
+	Elapsed time.Duration
+}
+
+func wrapWarmUp(sm *SessionManager, ai *services.ASTIndexer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args WarmUpArgs
+		if err := request.BindArguments(&args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("参数错误: %v", err)), nil
+		}
+
+		if sm.ProjectRoot == "" {
+			return mcp.NewToolResultError("项目未初始化，请先执行 initialize_project"), nil
+		}
+
+		scope := strings.TrimSpace(args.Scope)
+		var steps []warmUpStep
+		const totalSteps = 4
+		run := func(name string, fn func() (string, error)) {
+			reportProgress(ctx, request, len(steps), totalSteps, name)
+			started := time.Now()
+			detail, err := fn()
+			step := warmUpStep{Name: name, OK: err == nil, Detail: detail, Elapsed: time.Since(started)}
+			if err != nil {
+				step.Detail = err.Error()
+			}
+			steps = append(steps, step)
+		}
+
+		// 1. 索引（有 scope 时只补录该范围）
+		run("AST 索引", func() (string, error) {
+			var (
+				result *services.IndexResult
+				err    error
+			)
+			if scope != "" {
+				result, err = ai.IndexScope(sm.ProjectRoot, scope)
+			} else {
+				result, err = ai.EnsureFreshIndex(sm.ProjectRoot)
+			}
+			if err != nil {
+				return "", err
+			}
+			if result.Status == "cached" {
+				return "索引仍然新鲜，跳过重建", nil
+			}
+			return fmt.Sprintf("%d 个文件 (strategy=%s)", result.TotalFiles, fallback(result.Strategy, "default")), nil
+		})
+
+		// 2. 结构地图缓存（索引未变化前 project_map(level=structure) 直接复用）
+		run("结构地图缓存", func() (string, error) {
+			structure, _, err := ai.CachedStructure(sm.ProjectRoot, scope, true)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d 个目录 / %d 个文件，已缓存供 project_map(level=structure) 复用", len(structure.Structure), structure.TotalFiles), nil
+		})
+
+		// 3. 反向调用索引（校验 calls 表可用并统计解析率）
+		run("反向调用索引", func() (string, error) {
+			g, err := ai.LoadCallGraph(sm.ProjectRoot)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d 个符号 / %d 条已解析调用边 / %d 条未解析调用", len(g.Symbols), g.EdgeCount(), len(g.Unresolved)), nil
+		})
+
+		// 4. 记忆预取
+		var prefetch strings.Builder
+		run("记忆预取", func() (string, error) {
+			if sm.Memory == nil {
+				return "", fmt.Errorf("记忆层尚未初始化")
+			}
+			keywords := strings.TrimSpace(args.Keywords)
+			if keywords == "" && scope != "" {
+				keywords = filepath.Base(filepath.Clean(scope))
+			}
+			facts, err := sm.Memory.QueryFacts(ctx, keywords, 10)
+			if err != nil {
+				return "", err
+			}
+			memos, err := sm.Memory.SearchMemos(ctx, keywords, "", 10)
+			if err != nil {
+				return "", err
+			}
+			for _, f := range facts {
+				prefetch.WriteString(fmt.Sprintf(formatFact, f.Type, f.Summarize, f.ID, f.CreatedAt.Format("2006-01-02")))
+			}
+			for _, m := range memos {
+				prefetch.WriteString(fmt.Sprintf(formatMemo, m.ID, m.Timestamp.Format("2006-01-02 15:04"), m.Category, m.Act, truncateRunes(m.Content, 120)))
+			}
+			return fmt.Sprintf("%d 条 facts / %d 条 memos (keywords=%q)", len(facts), len(memos), keywords), nil
+		})
+
+		var sb strings.Builder
+		sb.WriteString("### 🔥 会话预热报告\n\n")
+		if scope != "" {
+			sb.WriteString(fmt.Sprintf("**🔎 Scope**: `%s`\n\n", scope))
+		}
+		var total time.Duration
+		for _, st := range steps {
+			mark := "✅"
+			if !st.OK {
+				mark = "❌"
+			}
+			total += st.Elapsed
+			sb.WriteString(fmt.Sprintf("- %s **%s** (%dms): %s\n", mark, st.Name, st.Elapsed.Milliseconds(), st.Detail))
+		}
+		sb.WriteString(fmt.Sprintf("\n**总耗时**: %dms\n", total.Milliseconds()))
+		if prefetch.Len() > 0 {
+			sb.WriteString("\n#### 📌 预取记忆\n\n")
+			sb.WriteString(prefetch.String())
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+// ========== 任务链生命周期 webhook ==========
+
+const (
+	WebhookChainInit   = "chain_init"
+	WebhookChainFinish = "chain_finish"
+	WebhookChainFail   = "chain_fail"
+	WebhookGateFail    = "gate_fail"
+
+	webhookMaxAttempts = 4
+)
+
+var (
+	webhookClient = &http.Client{Timeout: 5 * time.Second}
+	// webhookBackoff 首次重试的等待时间，之后每次翻倍
+	webhookBackoff = time.Second
+)
+
+// WebhookPayload 推送给 webhook 的 JSON 载荷
+type WebhookPayload struct {
+	Event     string `json:"event"`
+	TaskID    string `json:"task_id"`
+	Protocol  string `json:"protocol"`
+	Status    string `json:"status"`
+	PhaseID   string `json:"phase_id,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	Project   string `json:"project"`
+	Holder    string `json:"holder"`
+	Timestamp string `json:"timestamp"`
+}
+
+// webhookEventFor 将任务链事件映射为 webhook 事件，无需通知时返回 ""
+func webhookEventFor(chain *TaskChainV3, eventType, payload string) string {
+	switch eventType {
+	case "init":
+		return WebhookChainInit
+	case "finish":
+		return WebhookChainFinish
+	case "fail":
+		if chain.Status == "failed" {
+			return WebhookChainFail
+		}
+	case "complete":
+		var body struct {
+			Result string `json:"result"`
+		}
+		if json.Unmarshal([]byte(payload), &body) == nil && body.Result == "fail" {
+			return WebhookGateFail
+		}
+	}
+	return ""
+}
+
+// notifyChainWebhooks 异步推送任务链事件到 settings.json 中订阅的 webhook
+func notifyChainWebhooks(sm *SessionManager, chain *TaskChainV3, eventType, phaseID, payload string) {
+	event := webhookEventFor(chain, eventType, payload)
+	if event == "" || sm.ProjectRoot == "" {
+		return
+	}
+	hooks := core.LoadProjectSettings(sm.ProjectRoot).Webhooks
+	if len(hooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		TaskID:    chain.TaskID,
+		Protocol:  chain.Protocol,
+		Status:    chain.Status,
+		PhaseID:   phaseID,
+		Detail:    truncateRunes(payload, 500),
+		Project:   sm.ProjectRoot,
+		Holder:    sessionHolder(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return
+	}
+
+	for _, hook := range hooks {
+		if hook.URL == "" || !webhookSubscribed(hook, event) {
+			continue
+		}
+		go func(hook core.WebhookSettings) {
+			if err := deliverWebhook(hook, event, body); err != nil {
+				fmt.Fprintf(os.Stderr, "[Webhook][WARN] %s -> %s 推送失败: %v\n", event, hook.URL, err)
+			}
+		}(hook)
+	}
+}
+
+func webhookSubscribed(hook core.WebhookSettings, event string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, e := range hook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhookBody 计算 HMAC-SHA256 签名 (sha256=<hex>)
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook 发送请求，网络错误或 5xx/429 时按指数退避重试
+func deliverWebhook(hook core.WebhookSettings, event string, body []byte) error {
+	backoff := webhookBackoff
+	var lastErr error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-MPM-Event", event)
+		if hook.Secret != "" {
+			req.Header.Set("X-MPM-Signature", signWebhookBody(hook.Secret, body))
+		}
+
+		resp, err := webhookClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+			if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+				return lastErr // 客户端错误重试无意义
+			}
+		} else {
+			lastErr = err
+		}
+
+		if attempt < webhookMaxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("重试 %d 次后仍失败: %w", webhookMaxAttempts, lastErr)
+}
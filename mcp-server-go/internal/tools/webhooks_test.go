@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mcp-server-go/internal/core"
+)
+
+func TestDeliverWebhook_SignsAndRetries(t *testing.T) {
+	old := webhookBackoff
+	webhookBackoff = time.Millisecond
+	defer func() { webhookBackoff = old }()
+
+	body := []byte(`{"event":"gate_fail"}`)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get("X-MPM-Signature"); sig != signWebhookBody("s3cret", got) {
+			t.Errorf("bad signature %q", sig)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := deliverWebhook(core.WebhookSettings{URL: srv.URL, Secret: "s3cret"}, "gate_fail", body); err != nil {
+		t.Fatalf("deliverWebhook failed: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
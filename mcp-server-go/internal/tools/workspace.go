@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"mcp-server-go/internal/services"
+)
+
+// setupWorkspace 处理 initialize_project 的 discover / sub_projects 参数，返回附加到初始化结果的说明
+// 重新探测时保留已有选择；sub_projects=["*"] 清除选择
+func setupWorkspace(projectRoot string, discover bool, selection []string) (string, error) {
+	ws, err := services.LoadWorkspace(projectRoot)
+	if err != nil {
+		return "", err
+	}
+	if !discover && len(selection) == 0 {
+		if dirs := ws.SelectedDirs(); len(dirs) > 0 {
+			return fmt.Sprintf("\n索引范围: 子项目 %s（initialize_project sub_projects=[\"*\"] 恢复整个项目）", strings.Join(dirs, ", ")), nil
+		}
+		return "", nil
+	}
+
+	if discover || ws == nil {
+		found := services.DiscoverSubProjects(projectRoot)
+		if ws != nil {
+			selected := make(map[string]bool)
+			for _, sp := range ws.SubProjects {
+				selected[sp.Dir] = sp.Selected
+			}
+			for i := range found {
+				found[i].Selected = selected[found[i].Dir]
+			}
+		}
+		ws = &services.Workspace{SubProjects: found}
+	}
+	if len(ws.SubProjects) == 0 {
+		if len(selection) > 0 && !(len(selection) == 1 && selection[0] == "*") {
+			return "", fmt.Errorf("未探测到子项目（go.mod / package.json / Cargo.toml），无法按 sub_projects 选择")
+		}
+		return "\n\n📦 未探测到嵌套子项目，按单一项目索引。", services.SaveWorkspace(projectRoot, ws)
+	}
+	if len(selection) == 1 && selection[0] == "*" {
+		_ = ws.Select(nil)
+	} else if len(selection) > 0 {
+		if err := ws.Select(selection); err != nil {
+			return "", err
+		}
+	}
+	if err := services.SaveWorkspace(projectRoot, ws); err != nil {
+		return "", fmt.Errorf("保存工作区失败: %v", err)
+	}
+	return renderWorkspace(ws), nil
+}
+
+func renderWorkspace(ws *services.Workspace) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n\n📦 工作区子项目 (%d):\n", len(ws.SubProjects)))
+	for _, sp := range ws.SubProjects {
+		mark := "  "
+		if sp.Selected {
+			mark = "✅"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s — %s [%s]\n", mark, sp.Name, sp.Dir, strings.Join(sp.Markers, ", ")))
+	}
+	if dirs := ws.SelectedDirs(); len(dirs) > 0 {
+		sb.WriteString(fmt.Sprintf("索引范围: 仅选中的 %d 个子项目（sub_projects=[\"*\"] 恢复整个项目）\n", len(dirs)))
+	} else {
+		sb.WriteString("索引范围: 整个项目（传 sub_projects=[名称...] 只索引部分子项目）\n")
+	}
+	sb.WriteString("project_map / code_search 可用 sub_project=<名称> 限定范围。")
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+// Package client 提供 MPM 工具集的类型化 Go 客户端，
+// 供 CI 机器人、脚本等程序直接驱动 MPM，无需手写 JSON-RPC 载荷。
+//
+// 工具封装 (tools_gen.go) 由 cmd/gen-client 根据 internal/tools 的注册代码生成：
+//
+//	c, err := client.NewStdio(ctx, "mpm-go", []string{"MPM_PROJECT_ROOT=/path/to/project"})
+//	if err != nil { ... }
+//	defer c.Close()
+//	res, err := c.TaskChain(ctx, client.TaskChainRequest{Mode: "status", TaskID: "T1"})
+package client
+
+//go:generate go run ../../cmd/gen-client -src ../../internal/tools -out tools_gen.go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ClientName 握手时上报的客户端名称
+const ClientName = "mpm-go-client"
+
+// ToolResult 工具调用结果
+type ToolResult struct {
+	Text    string // 所有文本内容按顺序拼接
+	IsError bool   // 工具返回了业务错误 (mcp.NewToolResultError)
+	Raw     *mcp.CallToolResult
+}
+
+// DecodeJSON 将结果文本解析为 JSON（适用于返回 JSON 的模式，如 task_chain status）
+func (r *ToolResult) DecodeJSON(v interface{}) error {
+	return json.Unmarshal([]byte(strings.TrimSpace(r.Text)), v)
+}
+
+// Err 业务错误转为 error，便于调用方统一处理
+func (r *ToolResult) Err() error {
+	if r.IsError {
+		return fmt.Errorf("工具返回错误: %s", r.Text)
+	}
+	return nil
+}
+
+// Client MPM 客户端
+type Client struct {
+	mcp mcpclient.MCPClient
+}
+
+// New 基于已有的 MCP 客户端创建（需已完成 Initialize）
+func New(c mcpclient.MCPClient) *Client {
+	return &Client{mcp: c}
+}
+
+// NewStdio 启动 MPM 服务进程并完成握手
+func NewStdio(ctx context.Context, command string, env []string, args ...string) (*Client, error) {
+	c, err := mcpclient.NewStdioMCPClient(command, env, args...)
+	if err != nil {
+		return nil, fmt.Errorf("启动 MPM 服务失败: %w", err)
+	}
+
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: ClientName, Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("MCP 握手失败: %w", err)
+	}
+	return &Client{mcp: c}, nil
+}
+
+// Close 关闭连接（stdio 模式下会结束服务进程）
+func (c *Client) Close() error {
+	return c.mcp.Close()
+}
+
+// Call 以任意参数调用工具；生成的类型化方法均经由此处
+func (c *Client) Call(ctx context.Context, tool string, args interface{}) (*ToolResult, error) {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = tool
+	if args != nil {
+		req.Params.Arguments = args
+	}
+
+	res, err := c.mcp.CallTool(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("调用 %s 失败: %w", tool, err)
+	}
+
+	var texts []string
+	for _, content := range res.Content {
+		if tc, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, tc.Text)
+		}
+	}
+	return &ToolResult{
+		Text:    strings.Join(texts, "\n"),
+		IsError: res.IsError,
+		Raw:     res,
+	}, nil
+}
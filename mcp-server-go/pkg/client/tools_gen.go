@@ -0,0 +1,626 @@
+// Code generated by cmd/gen-client; DO NOT EDIT.
+
+package client
+
+import "context"
+
+// AnnotateRequest annotate 的请求参数
+type AnnotateRequest struct {
+	Action  string `json:"action,omitempty"`  // 操作类型
+	Kind    string `json:"kind,omitempty"`    // 标注类型 (默认 note)
+	Title   string `json:"title,omitempty"`   // 标注标题 (add 必填)，如 v1.2 发布、支付故障
+	Content string `json:"content,omitempty"` // 补充说明
+	At      string `json:"at,omitempty"`      // 标注时间 (默认当前时间)，支持 2024-05-01 15:04 或 3d 等相对时长
+	Author  string `json:"author,omitempty"`  // 标注人
+	ID      int64  `json:"id,omitempty"`      // remove 时的标注 ID
+	Since   string `json:"since,omitempty"`   // list 时间下限
+	Until   string `json:"until,omitempty"`   // list 时间上限
+	Limit   int    `json:"limit,omitempty"`   // list 返回条数
+}
+
+// Annotate 调用 annotate - 时间线人工标注
+func (c *Client) Annotate(ctx context.Context, req AnnotateRequest) (*ToolResult, error) {
+	return c.Call(ctx, "annotate", req)
+}
+
+// ApiSurfaceRequest api_surface 的请求参数
+type ApiSurfaceRequest struct {
+	Scope           string `json:"scope,omitempty"`            // 只提取该目录下的公开符号 (留空=整个项目)
+	Action          string `json:"action,omitempty"`           // show 列出 / save 保存基线 / diff 与基线对比
+	Baseline        string `json:"baseline,omitempty"`         // 基线名 (save 默认按时间命名；diff 默认最近一次保存的基线)
+	IncludeVendored bool   `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件 (默认排除)
+}
+
+// ApiSurface 调用 api_surface - 公开 API 清单与破坏性变更检查
+func (c *Client) ApiSurface(ctx context.Context, req ApiSurfaceRequest) (*ToolResult, error) {
+	return c.Call(ctx, "api_surface", req)
+}
+
+// CheckpointRequest checkpoint 的请求参数
+type CheckpointRequest struct {
+	Mode   string   `json:"mode,omitempty"`    // 操作模式
+	Files  []string `json:"files,omitempty"`   // 要快照的文件或目录 (create 模式必填，相对项目根目录)
+	Name   string   `json:"name,omitempty"`    // 检查点名称，如 '重构前'
+	TaskID string   `json:"task_id,omitempty"` // 关联的任务ID (list 时按任务过滤；rollback 未传 id 时回滚该任务最近的检查点)
+	ID     int64    `json:"id,omitempty"`      // 检查点ID (rollback/show 模式)
+}
+
+// Checkpoint 调用 checkpoint - 编辑检查点与回滚
+func (c *Client) Checkpoint(ctx context.Context, req CheckpointRequest) (*ToolResult, error) {
+	return c.Call(ctx, "checkpoint", req)
+}
+
+// ClaimFilesRequest claim_files 的请求参数
+type ClaimFilesRequest struct {
+	Mode       string   `json:"mode,omitempty"`        // 操作模式
+	TaskID     string   `json:"task_id,omitempty"`     // 持有锁的任务ID (claim/release 模式必填)
+	Files      []string `json:"files,omitempty"`       // 文件路径列表 (release 模式不传则释放该任务全部锁)
+	TTLMinutes int      `json:"ttl_minutes,omitempty"` // 锁有效期(分钟)，到期自动失效
+}
+
+// ClaimFiles 调用 claim_files - 文件软锁 (多会话协作提醒)
+func (c *Client) ClaimFiles(ctx context.Context, req ClaimFilesRequest) (*ToolResult, error) {
+	return c.Call(ctx, "claim_files", req)
+}
+
+// CodeImpactRequest code_impact 的请求参数
+type CodeImpactRequest struct {
+	SymbolName      string `json:"symbol_name,omitempty"`      // 要分析的符号名 (函数名或类名)
+	Direction       string `json:"direction,omitempty"`        // 分析方向
+	IncludeVendored bool   `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件中的调用者
+	TaskID          string `json:"task_id,omitempty"`          // 所属任务链ID，结果计入该链的风险预算
+	MaxDepth        int    `json:"max_depth,omitempty"`        // 传播深度上限 (1=只看直接调用者，0=不限)
+	ShowPaths       bool   `json:"show_paths,omitempty"`       // 输出完整调用链 (如 handler → service → repo → target)
+}
+
+// CodeImpact 调用 code_impact - 代码修改影响分析
+func (c *Client) CodeImpact(ctx context.Context, req CodeImpactRequest) (*ToolResult, error) {
+	return c.Call(ctx, "code_impact", req)
+}
+
+// CodeSearchRequest code_search 的请求参数
+type CodeSearchRequest struct {
+	Query         string   `json:"query,omitempty"`          // 搜索关键词
+	Scope         string   `json:"scope,omitempty"`          // 限定范围
+	SubProject    string   `json:"sub_project,omitempty"`    // 限定到工作区子项目 (按名称)；同时给 scope 时 scope 相对子项目目录
+	SearchType    string   `json:"search_type,omitempty"`    // 符号类型过滤；content 为全文/正则搜索
+	ShowRelated   bool     `json:"show_related,omitempty"`   // 附带关联记忆：提及该符号的 memos/facts/未完成钩子/任务链
+	Regex         bool     `json:"regex,omitempty"`          // query 按正则解析（隐含 search_type=content）
+	CaseSensitive bool     `json:"case_sensitive,omitempty"` // 区分大小写 (content 模式)
+	Include       []string `json:"include,omitempty"`        // 只搜索匹配这些 glob 的文件，如 *.go、internal/**
+	Exclude       []string `json:"exclude,omitempty"`        // 排除匹配这些 glob 的文件或目录，如 *_test.go
+	Languages     []string `json:"languages,omitempty"`      // 语言过滤，如 go、python、typescript
+	ContextLines  int      `json:"context_lines,omitempty"`  // 匹配行前后的上下文行数 (content 模式，最多 10)
+	Limit         int      `json:"limit,omitempty"`          // content 模式最多返回的匹配数，默认 50
+}
+
+// CodeSearch 调用 code_search - 代码符号定位 (比 grep 更懂代码)
+func (c *Client) CodeSearch(ctx context.Context, req CodeSearchRequest) (*ToolResult, error) {
+	return c.Call(ctx, "code_search", req)
+}
+
+// DeadCodeRequest dead_code 的请求参数
+type DeadCodeRequest struct {
+	Scope           string `json:"scope,omitempty"`            // 只检查该目录/文件 (留空=整个项目)
+	MinConfidence   string `json:"min_confidence,omitempty"`   // 最低置信度
+	Limit           int    `json:"limit,omitempty"`            // 最多列出的候选数
+	IncludeVendored bool   `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件 (默认排除)
+}
+
+// DeadCode 调用 dead_code - 死代码 / 无引用符号检测
+func (c *Client) DeadCode(ctx context.Context, req DeadCodeRequest) (*ToolResult, error) {
+	return c.Call(ctx, "dead_code", req)
+}
+
+// DependencyGraphRequest dependency_graph 的请求参数
+type DependencyGraphRequest struct {
+	Scope           string `json:"scope,omitempty"`            // 只保留至少一端位于该目录/文件下的依赖 (留空=整个项目)
+	Level           string `json:"level,omitempty"`            // 聚合粒度：module=目录，file=文件
+	Format          string `json:"format,omitempty"`           // 输出格式
+	MaxEdges        int    `json:"max_edges,omitempty"`        // 最多保留的边数 (按调用数降序)
+	Output          string `json:"output,omitempty"`           // 同时写入文件 (相对项目根目录)，留空则只返回文本
+	IncludeVendored bool   `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件 (默认排除)
+}
+
+// DependencyGraph 调用 dependency_graph - 模块/文件级依赖图导出 (Mermaid / DOT)
+func (c *Client) DependencyGraph(ctx context.Context, req DependencyGraphRequest) (*ToolResult, error) {
+	return c.Call(ctx, "dependency_graph", req)
+}
+
+// DepsAuditRequest deps_audit 的请求参数
+type DepsAuditRequest struct {
+	Dir      string `json:"dir,omitempty"`       // 只审计该目录下的清单 (相对项目根，留空=全部)
+	SkipMemo bool   `json:"skip_memo,omitempty"` // 不自动记录审计摘要备忘
+	Limit    int    `json:"limit,omitempty"`     // 每个清单最多列出的漏洞数
+}
+
+// DepsAudit 调用 deps_audit - 依赖漏洞审计
+func (c *Client) DepsAudit(ctx context.Context, req DepsAuditRequest) (*ToolResult, error) {
+	return c.Call(ctx, "deps_audit", req)
+}
+
+// ExportMemoryRequest export_memory 的请求参数
+type ExportMemoryRequest struct {
+	Mode string `json:"mode,omitempty"` // export (导出为 Markdown) / import (从导出目录回灌数据库)
+	Dir  string `json:"dir,omitempty"`  // 导出目录（相对项目根，默认 docs/memory）
+}
+
+// ExportMemory 调用 export_memory - 把项目记忆导出为 Markdown 知识库 / 从知识库导入
+func (c *Client) ExportMemory(ctx context.Context, req ExportMemoryRequest) (*ToolResult, error) {
+	return c.Call(ctx, "export_memory", req)
+}
+
+// FeaturesRequest features 的请求参数
+type FeaturesRequest struct {
+	Action string `json:"action,omitempty"` // 操作类型
+	Name   string `json:"name,omitempty"`   // 功能名 (enable/disable 必填)
+}
+
+// Features 调用 features - 项目级实验性功能开关
+func (c *Client) Features(ctx context.Context, req FeaturesRequest) (*ToolResult, error) {
+	return c.Call(ctx, "features", req)
+}
+
+// FlowTraceRequest flow_trace 的请求参数
+type FlowTraceRequest struct {
+	SymbolName  string   `json:"symbol_name,omitempty"`  // 入口符号名（函数/类，与 file_path 二选一；若同时提供则优先 symbol_name）
+	SymbolNames []string `json:"symbol_names,omitempty"` // 多个入口符号名（最多 5 个），对比共同下游依赖与分叉点
+	FilePath    string   `json:"file_path,omitempty"`    // 目标文件路径（与 symbol_name 二选一）
+	Scope       string   `json:"scope,omitempty"`        // 限定范围（目录，超大仓库建议必填）
+	Direction   string   `json:"direction,omitempty"`    // 追踪方向
+	Mode        string   `json:"mode,omitempty"`         // 输出层级（brief/standard/deep）
+	MaxNodes    int      `json:"max_nodes,omitempty"`    // 输出节点上限
+	Render      string   `json:"render,omitempty"`       // 输出形式：text 文本摘要 / mermaid_sequence 时序图（保存为 .mmd）
+	MaxChains   int      `json:"max_chains,omitempty"`   // mermaid_sequence 每个方向最多绘制的调用链数
+}
+
+// FlowTrace 调用 flow_trace - 业务流程追踪（文件/函数）
+func (c *Client) FlowTrace(ctx context.Context, req FlowTraceRequest) (*ToolResult, error) {
+	return c.Call(ctx, "flow_trace", req)
+}
+
+// GlossaryRequest glossary 的请求参数
+type GlossaryRequest struct {
+	Action     string   `json:"action,omitempty"`     // 操作类型
+	Term       string   `json:"term,omitempty"`       // 术语名 (add/remove 必填)
+	Definition string   `json:"definition,omitempty"` // 术语定义
+	Aliases    []string `json:"aliases,omitempty"`    // 别名/同义词 (如中英文对照)
+	Symbols    []string `json:"symbols,omitempty"`    // 对应的代码符号
+	Paths      []string `json:"paths,omitempty"`      // 对应的文件或目录 (相对项目根)
+	Query      string   `json:"query,omitempty"`      // search 时的检索关键词
+}
+
+// Glossary 调用 glossary - 项目术语表
+func (c *Client) Glossary(ctx context.Context, req GlossaryRequest) (*ToolResult, error) {
+	return c.Call(ctx, "glossary", req)
+}
+
+// GuardrailCheckRequest guardrail_check 的请求参数
+type GuardrailCheckRequest struct {
+	TaskID string   `json:"task_id,omitempty"` // manager_analyze 返回的 task_id (留空则只检查文件锁)
+	Files  []string `json:"files,omitempty"`   // 计划修改的文件 (相对项目根目录)
+	Intent string   `json:"intent,omitempty"`  // 本次修改意图，写入审计日志
+}
+
+// GuardrailCheck 调用 guardrail_check - 修改前的约束检查
+func (c *Client) GuardrailCheck(ctx context.Context, req GuardrailCheckRequest) (*ToolResult, error) {
+	return c.Call(ctx, "guardrail_check", req)
+}
+
+// IndexReportRequest index_report 的请求参数
+type IndexReportRequest struct {
+	Format          string  `json:"format,omitempty"`           // 输出格式
+	Output          string  `json:"output,omitempty"`           // 输出文件路径 (默认数据目录下 index_report.<format>)
+	Threshold       float64 `json:"threshold,omitempty"`        // 高风险分数阈值 (默认读取项目配置)
+	Limit           int     `json:"limit,omitempty"`            // 每类条目上限
+	IncludeVendored bool    `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件 (默认排除)
+}
+
+// IndexReport 调用 index_report - 索引审查报告导出 (SARIF/JSON)
+func (c *Client) IndexReport(ctx context.Context, req IndexReportRequest) (*ToolResult, error) {
+	return c.Call(ctx, "index_report", req)
+}
+
+// IndexStatusRequest index_status 的请求参数
+type IndexStatusRequest struct {
+	ProjectRoot string `json:"project_root,omitempty"` // 可选项目根路径，留空时使用当前会话项目
+	Mode        string `json:"mode,omitempty"`         // status 查看后台任务状态；index_estimate 不建索引，快速统计候选文件并预测耗时；rollback 回滚到上一版索引
+	Scope       string `json:"scope,omitempty"`        // index_estimate 模式下仅统计该子目录（相对路径）
+}
+
+// IndexStatus 调用 index_status - 查看 AST 索引后台任务状态
+func (c *Client) IndexStatus(ctx context.Context, req IndexStatusRequest) (*ToolResult, error) {
+	return c.Call(ctx, "index_status", req)
+}
+
+// InitializeProjectRequest initialize_project 的请求参数
+type InitializeProjectRequest struct {
+	ProjectRoot    string   `json:"project_root,omitempty"`     // 项目根路径 (绝对路径)
+	ForceFullIndex bool     `json:"force_full_index,omitempty"` // 强制全量索引（禁用大仓库bootstrap策略，默认false）
+	RenderTarget   string   `json:"render_target,omitempty"`    // 本会话的输出渲染目标：markdown 或 plain（纯文本，适配不支持 Markdown 的终端客户端）
+	Discover       bool     `json:"discover,omitempty"`         // 探测嵌套子项目 (go.mod / package.json / Cargo.toml 边界)，适用于 monorepo
+	SubProjects    []string `json:"sub_projects,omitempty"`     // 只索引这些子项目 (名称或目录)；["*"] 清除选择、索引整个项目
+}
+
+// InitializeProject 调用 initialize_project - 初始化项目环境与数据库
+func (c *Client) InitializeProject(ctx context.Context, req InitializeProjectRequest) (*ToolResult, error) {
+	return c.Call(ctx, "initialize_project", req)
+}
+
+// KnownFactsRequest known_facts 的请求参数
+type KnownFactsRequest struct {
+	Type       string  `json:"type,omitempty"`       // 事实类型 (如：铁律、避坑)
+	Summarize  string  `json:"summarize,omitempty"`  // 事实描述
+	Force      bool    `json:"force,omitempty"`      // 存在近似重复事实时仍强制保存
+	Scope      string  `json:"scope,omitempty"`      // 作用域 (path:internal/core / module:tools / symbol:SaveFact)，为空表示全局
+	Priority   string  `json:"priority,omitempty"`   // 优先级
+	TTL        string  `json:"ttl,omitempty"`        // 有效期 (如 30d、2w、12h)，到期后不再被检索；为空表示永久
+	Supersedes []int64 `json:"supersedes,omitempty"` // 被新事实取代的旧事实 ID，旧事实不再被检索
+}
+
+// KnownFacts 调用 known_facts - 原子级经验事实存档
+func (c *Client) KnownFacts(ctx context.Context, req KnownFactsRequest) (*ToolResult, error) {
+	return c.Call(ctx, "known_facts", req)
+}
+
+// ManagerAnalyzeRequest manager_analyze 的请求参数
+type ManagerAnalyzeRequest struct {
+	TaskDescription string   `json:"task_description,omitempty"` // 用户的原始指令/任务详情
+	Intent          string   `json:"intent,omitempty"`           // LLM 自行判断的意向 (DEBUG/DEVELOP/REFACTOR/DESIGN/RESEARCH/ONBOARD)
+	Symbols         []string `json:"symbols,omitempty"`          // 提取的代码符号
+	ReadOnly        bool     `json:"read_only,omitempty"`        // 是否为只读分析模式
+	Scope           string   `json:"scope,omitempty"`            // 任务范围描述
+	Step            int      `json:"step,omitempty"`             // 执行步骤 (1=分析, 2=生成策略, 3=增量更新)，默认为1
+	TaskID          string   `json:"task_id,omitempty"`          // 步骤2/3时必填，步骤1返回的 task_id
+	MaxAnchors      int      `json:"max_anchors,omitempty"`      // 单次最多解析的符号数 (默认读取 settings.json analyze.max_anchors，缺省 10)
+	ResolveMore     bool     `json:"resolve_more,omitempty"`     // 配合 task_id 使用：继续解析上次因数量上限被推迟的符号
+	Quiet           bool     `json:"quiet,omitempty"`            // 精简输出 (去除 emoji 并压缩 JSON)
+}
+
+// ManagerAnalyze 调用 manager_analyze - 任务情报聚合与战术简报（两步自迭代 + 增量更新）
+func (c *Client) ManagerAnalyze(ctx context.Context, req ManagerAnalyzeRequest) (*ToolResult, error) {
+	return c.Call(ctx, "manager_analyze", req)
+}
+
+// ManagerCreateHookRequest manager_create_hook 的请求参数
+type ManagerCreateHookRequest struct {
+	Description    string `json:"description,omitempty"`      // 待办事项描述
+	Priority       string `json:"priority,omitempty"`         // 优先级
+	TaskID         string `json:"task_id,omitempty"`          // 关联的任务 ID
+	Tag            string `json:"tag,omitempty"`              // 可选标签
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"` // 过期时间(小时), 0表示不过期
+	Quiet          bool   `json:"quiet,omitempty"`            // 精简输出 (去除横幅/emoji/提示)
+}
+
+// ManagerCreateHook 调用 manager_create_hook - 创建并挂起待办事项 (钩子)
+func (c *Client) ManagerCreateHook(ctx context.Context, req ManagerCreateHookRequest) (*ToolResult, error) {
+	return c.Call(ctx, "manager_create_hook", req)
+}
+
+// ManagerImportTodosRequest manager_import_todos 的请求参数
+type ManagerImportTodosRequest struct {
+	Scope  string `json:"scope,omitempty"`  // 仅扫描该子目录（相对项目根）
+	Create bool   `json:"create,omitempty"` // true 时批量创建钩子；默认仅预览
+	Limit  int    `json:"limit,omitempty"`  // 单次最多导入条数
+	Quiet  bool   `json:"quiet,omitempty"`  // 精简输出 (去除横幅/emoji/提示)
+}
+
+// ManagerImportTodos 调用 manager_import_todos - 将 TODO/FIXME/HACK 注释导入为钩子
+func (c *Client) ManagerImportTodos(ctx context.Context, req ManagerImportTodosRequest) (*ToolResult, error) {
+	return c.Call(ctx, "manager_import_todos", req)
+}
+
+// ManagerListHooksRequest manager_list_hooks 的请求参数
+type ManagerListHooksRequest struct {
+	Status string `json:"status,omitempty"` // 状态筛选
+	Quiet  bool   `json:"quiet,omitempty"`  // 精简输出 (去除横幅/emoji/提示)
+}
+
+// ManagerListHooks 调用 manager_list_hooks - 查看待办钩子列表
+func (c *Client) ManagerListHooks(ctx context.Context, req ManagerListHooksRequest) (*ToolResult, error) {
+	return c.Call(ctx, "manager_list_hooks", req)
+}
+
+// ManagerReleaseHookRequest manager_release_hook 的请求参数
+type ManagerReleaseHookRequest struct {
+	HookID        string `json:"hook_id,omitempty"`        // Hook 编号 (如 #001)
+	ResultSummary string `json:"result_summary,omitempty"` // 完成总结
+	Quiet         bool   `json:"quiet,omitempty"`          // 精简输出 (去除横幅/emoji/提示)
+}
+
+// ManagerReleaseHook 调用 manager_release_hook - 释放并闭合待办钩子
+func (c *Client) ManagerReleaseHook(ctx context.Context, req ManagerReleaseHookRequest) (*ToolResult, error) {
+	return c.Call(ctx, "manager_release_hook", req)
+}
+
+// MemoItem 嵌套参数类型
+type MemoItem struct {
+	Category string `json:"category,omitempty"` // 分类 (如：修改、开发、决策)，必须使用用户对话语言
+	Entity   string `json:"entity,omitempty"`   // 改动的实体，必须使用用户对话语言
+	Act      string `json:"act,omitempty"`      // 具体的行动，必须使用用户对话语言
+	Path     string `json:"path,omitempty"`     // 文件路径
+	Content  string `json:"content,omitempty"`  // 详细内容，必须使用用户对话语言
+	Key      string `json:"key,omitempty"`      // 兼容字段：键
+	Value    string `json:"value,omitempty"`    // 兼容字段：值
+}
+
+// MemoRequest memo 的请求参数
+type MemoRequest struct {
+	Items     []MemoItem `json:"items,omitempty"`      // 录入事项列表
+	Lang      string     `json:"lang,omitempty"`       // 当前用户对话的语言 (zh=中文, en=英文)
+	WithDirty bool       `json:"with_dirty,omitempty"` // 同时记录未提交改动的文件列表 (默认只记录 git HEAD)
+	TaskID    string     `json:"task_id,omitempty"`    // 当前任务链 ID，文件锁提醒会跳过该任务自己持有的锁 (省略时取本会话唯一运行中的任务链)
+}
+
+// Memo 调用 memo - 项目的"黑匣子" (如果不记，等于没做)
+func (c *Client) Memo(ctx context.Context, req MemoRequest) (*ToolResult, error) {
+	return c.Call(ctx, "memo", req)
+}
+
+// MemoManageRequest memo_manage 的请求参数
+type MemoManageRequest struct {
+	Action    string  `json:"action,omitempty"`    // 操作
+	ID        int64   `json:"id,omitempty"`        // memo ID (memo 返回的 IDs / system_recall 中的 [ID])，compact 以外必填
+	Category  string  `json:"category,omitempty"`  // update: 新分类
+	Entity    string  `json:"entity,omitempty"`    // update: 新实体
+	Act       string  `json:"act,omitempty"`       // update: 新行为
+	Path      string  `json:"path,omitempty"`      // update: 新文件路径
+	Content   string  `json:"content,omitempty"`   // update: 新内容
+	Reason    string  `json:"reason,omitempty"`    // 修改原因 (写入归档墓碑条目，update/delete 必填)
+	Threshold float64 `json:"threshold,omitempty"` // compact: 内容相似度阈值 (0~1，默认 0.75)
+	Apply     bool    `json:"apply,omitempty"`     // compact: true 执行合并，默认仅预览
+}
+
+// MemoManage 调用 memo_manage - 修订 / 撤回已录入的 memo
+func (c *Client) MemoManage(ctx context.Context, req MemoManageRequest) (*ToolResult, error) {
+	return c.Call(ctx, "memo_manage", req)
+}
+
+// MemoryAdminRequest memory_admin 的请求参数
+type MemoryAdminRequest struct {
+	Mode string `json:"mode,omitempty"` // backup/list/restore/vacuum/integrity_check
+	Name string `json:"name,omitempty"` // 要恢复的整包备份目录名 (restore 必填，见 list 输出)
+}
+
+// MemoryAdmin 调用 memory_admin - 记忆库运维（整包备份 / 恢复 / VACUUM / 完整性检查）
+func (c *Client) MemoryAdmin(ctx context.Context, req MemoryAdminRequest) (*ToolResult, error) {
+	return c.Call(ctx, "memory_admin", req)
+}
+
+// OpenTimelineRequest open_timeline 的请求参数
+type OpenTimelineRequest struct {
+	Live bool `json:"live,omitempty"` // 启动本机 HTTP 服务实时展示时间线，记忆写入后页面自动刷新
+	Port int  `json:"port,omitempty"` // live 模式监听端口（仅 127.0.0.1），0 表示自动分配
+	Stop bool `json:"stop,omitempty"` // 关闭正在运行的实时时间线服务
+}
+
+// OpenTimeline 调用 open_timeline - 项目演进可视化界面
+func (c *Client) OpenTimeline(ctx context.Context, req OpenTimelineRequest) (*ToolResult, error) {
+	return c.Call(ctx, "open_timeline", req)
+}
+
+// PersonaRequest persona 的请求参数
+type PersonaRequest struct {
+	Mode           string   `json:"mode,omitempty"`            // 操作模式
+	Name           string   `json:"name,omitempty"`            // 人格名称 (activate/update/delete 必填)
+	NewName        string   `json:"new_name,omitempty"`        // 新名称 (update 可选)
+	DisplayName    string   `json:"display_name,omitempty"`    // 显示名称
+	Avatar         string   `json:"avatar,omitempty"`          // 头像或图标
+	HardDirective  string   `json:"hard_directive,omitempty"`  // 核心指令
+	Aliases        []string `json:"aliases,omitempty"`         // 别名列表
+	StyleMust      []string `json:"style_must,omitempty"`      // 必须遵守风格
+	StyleSignature []string `json:"style_signature,omitempty"` // 标志性表达
+	StyleTaboo     []string `json:"style_taboo,omitempty"`     // 禁用表达
+	Triggers       []string `json:"triggers,omitempty"`        // 触发词
+}
+
+// Persona 调用 persona - AI 人格管理工具
+func (c *Client) Persona(ctx context.Context, req PersonaRequest) (*ToolResult, error) {
+	return c.Call(ctx, "persona", req)
+}
+
+// ProjectMapRequest project_map 的请求参数
+type ProjectMapRequest struct {
+	Scope           string `json:"scope,omitempty"`            // 限定范围 (目录或文件路径，留空=整个项目)
+	SubProject      string `json:"sub_project,omitempty"`      // 限定到工作区子项目 (initialize_project discover=true 探测出的名称)；同时给 scope 时 scope 相对子项目目录
+	Level           string `json:"level,omitempty"`            // 视图层级
+	CorePaths       string `json:"core_paths,omitempty"`       // 核心目录列表 (JSON 数组字符串)
+	IncludeVendored bool   `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件 (默认排除)
+	SkipComplexity  bool   `json:"skip_complexity,omitempty"`  // 跳过复杂度热力图计算 (超大仓库提速)
+	From            string `json:"from,omitempty"`             // diff 视图的起点快照 ID (留空=上一个快照)
+	To              string `json:"to,omitempty"`               // diff 视图的终点快照 ID (留空=当前代码)
+}
+
+// ProjectMap 调用 project_map - 你的项目导航仪 (当不知道代码在哪时)
+func (c *Client) ProjectMap(ctx context.Context, req ProjectMapRequest) (*ToolResult, error) {
+	return c.Call(ctx, "project_map", req)
+}
+
+// ProjectStatsRequest project_stats 的请求参数
+type ProjectStatsRequest struct {
+	Scope string `json:"scope,omitempty"` // 仅统计该子目录（相对项目根）
+	Top   int    `json:"top,omitempty"`   // 最大文件/目录列表的条数
+	Quiet bool   `json:"quiet,omitempty"` // 精简输出 (去除横幅/emoji/提示)
+}
+
+// ProjectStats 调用 project_stats - 项目规模统计
+func (c *Client) ProjectStats(ctx context.Context, req ProjectStatsRequest) (*ToolResult, error) {
+	return c.Call(ctx, "project_stats", req)
+}
+
+// RenamePlanRequest rename_plan 的请求参数
+type RenamePlanRequest struct {
+	OldName string `json:"old_name,omitempty"` // 现有符号名
+	NewName string `json:"new_name,omitempty"` // 新符号名
+	Scope   string `json:"scope,omitempty"`    // 只在该目录下查找引用 (留空=整个项目)
+}
+
+// RenamePlan 调用 rename_plan - 符号重命名影响清单与分步计划
+func (c *Client) RenamePlan(ctx context.Context, req RenamePlanRequest) (*ToolResult, error) {
+	return c.Call(ctx, "rename_plan", req)
+}
+
+// ReportRequest report 的请求参数
+type ReportRequest struct {
+	Since   string `json:"since,omitempty"`    // 起始时间 (ISO 日期如 2024-05-01，或相对时长如 1d/7d/2w)
+	Until   string `json:"until,omitempty"`    // 截止时间 (格式同 since，纯日期包含当天)，默认现在
+	GroupBy string `json:"group_by,omitempty"` // memo 分组方式：day 按日期（默认）、category 按类型、entity 按实体
+	Save    bool   `json:"save,omitempty"`     // 同时写入数据目录 reports/report-<起>-<止>.md
+}
+
+// Report 调用 report - 周报 / 日报生成
+func (c *Client) Report(ctx context.Context, req ReportRequest) (*ToolResult, error) {
+	return c.Call(ctx, "report", req)
+}
+
+// RequestReviewRequest request_review 的请求参数
+type RequestReviewRequest struct {
+	TaskID string `json:"task_id,omitempty"` // 任务链 ID
+	Output string `json:"output,omitempty"`  // 输出文件路径 (默认数据目录下 reviews/<task_id>.md)
+	PostPR string `json:"post_pr,omitempty"` // PR 编号或 URL；非空时通过 gh pr comment 发布评论
+}
+
+// RequestReview 调用 request_review - 评审请求打包
+func (c *Client) RequestReview(ctx context.Context, req RequestReviewRequest) (*ToolResult, error) {
+	return c.Call(ctx, "request_review", req)
+}
+
+// RestoreBackupRequest restore_backup 的请求参数
+type RestoreBackupRequest struct {
+	Action string `json:"action,omitempty"` // 操作类型
+	Name   string `json:"name,omitempty"`   // 要恢复的备份文件名 (restore 必填，见 list 输出)
+}
+
+// RestoreBackup 调用 restore_backup - 记忆库整库备份与恢复
+func (c *Client) RestoreBackup(ctx context.Context, req RestoreBackupRequest) (*ToolResult, error) {
+	return c.Call(ctx, "restore_backup", req)
+}
+
+// SecurityScanRequest security_scan 的请求参数
+type SecurityScanRequest struct {
+	Scope           string `json:"scope,omitempty"`            // 只扫描该目录/文件 (留空=整个项目)
+	MinSeverity     string `json:"min_severity,omitempty"`     // 最低严重程度
+	SkipHooks       bool   `json:"skip_hooks,omitempty"`       // 不为 high 级命中自动创建钩子
+	Limit           int    `json:"limit,omitempty"`            // 最多列出的命中数
+	IncludeVendored bool   `json:"include_vendored,omitempty"` // 包含 vendored/generated 文件 (默认排除)
+}
+
+// SecurityScan 调用 security_scan - 凭据泄露与危险模式扫描
+func (c *Client) SecurityScan(ctx context.Context, req SecurityScanRequest) (*ToolResult, error) {
+	return c.Call(ctx, "security_scan", req)
+}
+
+// ServerStatsRequest server_stats 的请求参数
+type ServerStatsRequest struct {
+	Format      string `json:"format,omitempty"`       // 输出格式：table (默认，Markdown 表格) / prometheus (文本暴露格式)
+	Top         int    `json:"top,omitempty"`          // 表格只列出总耗时最高的前 N 个工具 (默认 20)
+	Audit       int    `json:"audit,omitempty"`        // 附带最近 N 条命令执行审计 (auto_verify / embedding / intent 等经 CommandRunner 的命令)，0 = 不显示
+	AuditSource string `json:"audit_source,omitempty"` // 审计来源前缀过滤 (如 auto_verify、embedding、intent)
+}
+
+// ServerStats 调用 server_stats - 服务性能统计
+func (c *Client) ServerStats(ctx context.Context, req ServerStatsRequest) (*ToolResult, error) {
+	return c.Call(ctx, "server_stats", req)
+}
+
+// SkillList 调用 skill_list - 列出可用技能库 (领域知识)
+func (c *Client) SkillList(ctx context.Context) (*ToolResult, error) {
+	return c.Call(ctx, "skill_list", nil)
+}
+
+// SkillLoadRequest skill_load 的请求参数
+type SkillLoadRequest struct {
+	Name     string `json:"name,omitempty"`     // Skill 名称 (文件夹名或元数据中的 name)
+	Level    string `json:"level,omitempty"`    // 加载级别
+	Resource string `json:"resource,omitempty"` // 可选。指定要加载的子资源路径 (如 references/guide.md)
+	Refresh  bool   `json:"refresh,omitempty"`  // 是否强制刷新缓存
+}
+
+// SkillLoad 调用 skill_load - 加载并阅读技能文档 (专家指导)
+func (c *Client) SkillLoad(ctx context.Context, req SkillLoadRequest) (*ToolResult, error) {
+	return c.Call(ctx, "skill_load", req)
+}
+
+// SuggestFactsRequest suggest_facts 的请求参数
+type SuggestFactsRequest struct {
+	Action         string `json:"action,omitempty"`          // 操作类型
+	ID             int64  `json:"id,omitempty"`              // accept/dismiss 的候选 ID
+	Type           string `json:"type,omitempty"`            // accept 时覆盖事实类型 (默认沿用候选类型)
+	Summarize      string `json:"summarize,omitempty"`       // accept 时覆盖事实描述 (默认沿用候选原句)
+	MinOccurrences int    `json:"min_occurrences,omitempty"` // 至少在多少条不同 memo 中出现
+}
+
+// SuggestFacts 调用 suggest_facts - 从 memo 中挖掘候选事实
+func (c *Client) SuggestFacts(ctx context.Context, req SuggestFactsRequest) (*ToolResult, error) {
+	return c.Call(ctx, "suggest_facts", req)
+}
+
+// SystemMetrics 调用 system_metrics - 查看服务运行指标
+func (c *Client) SystemMetrics(ctx context.Context) (*ToolResult, error) {
+	return c.Call(ctx, "system_metrics", nil)
+}
+
+// SystemRecallRequest system_recall 的请求参数
+type SystemRecallRequest struct {
+	Keywords string `json:"keywords,omitempty"` // 检索关键词
+	Category string `json:"category,omitempty"` // 过滤类型 (开发/重构/避坑等)
+	Limit    int    `json:"limit,omitempty"`    // 返回条数
+	Since    string `json:"since,omitempty"`    // 起始时间 (ISO 日期如 2024-05-01，或相对时长如 3d/2w/12h)
+	Until    string `json:"until,omitempty"`    // 截止时间 (格式同 since，纯日期包含当天)
+	Semantic bool   `json:"semantic,omitempty"` // 关键词 + 向量语义混合检索 (需配置 settings.json embedding)
+}
+
+// SystemRecall 调用 system_recall - 你的记忆回溯器 (少走弯路)
+func (c *Client) SystemRecall(ctx context.Context, req SystemRecallRequest) (*ToolResult, error) {
+	return c.Call(ctx, "system_recall", req)
+}
+
+// TaskChainRequest task_chain 的请求参数
+type TaskChainRequest struct {
+	Mode             string            `json:"mode,omitempty"`               // 操作模式
+	TaskID           string            `json:"task_id,omitempty"`            // 任务ID (resume 模式可改用 resume_token)
+	Description      string            `json:"description,omitempty"`        // 任务描述 (init模式)
+	Protocol         string            `json:"protocol,omitempty"`           // 协议名称 (init模式，如 develop/debug/refactor 或 protocols.yaml 中的自定义协议，不传则默认 linear)
+	PhaseID          string            `json:"phase_id,omitempty"`           // 阶段ID (start/complete/spawn/complete_sub模式)
+	Result           string            `json:"result,omitempty"`             // gate结果 pass/fail (complete gate模式) 或子任务结果 (complete_sub模式)
+	Summary          string            `json:"summary,omitempty"`            // 步骤/阶段/子任务总结 (complete/complete_sub模式)
+	SubID            string            `json:"sub_id,omitempty"`             // 子任务ID (complete_sub模式)
+	SubTasks         interface{}       `json:"sub_tasks,omitempty"`          // 子任务列表 (spawn模式)，每项 {id, name, verify, depends_on}
+	Phases           interface{}       `json:"phases,omitempty"`             // 手动定义阶段列表 (init模式)
+	Files            []string          `json:"files,omitempty"`              // 本任务将编辑的文件 (init模式，自动声明文件软锁)
+	WorkingDir       string            `json:"working_dir,omitempty"`        // 验证命令执行目录，相对项目根目录 (init模式)
+	Env              map[string]string `json:"env,omitempty"`                // 验证命令附加环境变量 (init模式)
+	Quiet            bool              `json:"quiet,omitempty"`              // 精简输出 (去除横幅/emoji/提示，适合批量编排)
+	Takeover         bool              `json:"takeover,omitempty"`           // 强制接管仍被其他会话驱动或持有的任务链 (resume/start/complete/spawn/complete_sub/claim模式)
+	ConfirmReinit    bool              `json:"confirm_reinit,omitempty"`     // 确认 re-init 差异后提交 (init模式，任务链已存在时)
+	MaxAffectedNodes int               `json:"max_affected_nodes,omitempty"` // 风险预算：链内 code_impact 累计影响节点上限 (init模式)
+	MaxHighRisk      int               `json:"max_high_risk,omitempty"`      // 风险预算：可触及的高风险符号数上限 (init模式)
+	ResumeToken      string            `json:"resume_token,omitempty"`       // 阶段完成时签发的恢复令牌，可代替 task_id (resume模式)
+	Experiment       string            `json:"experiment,omitempty"`         // 对照实验名：记录该链的协议/耗时/重试/re-init (init模式)；experiments模式下按实验名过滤
+	VerifyDiff       bool              `json:"verify_diff,omitempty"`        // 核对 summary 声称修改的文件与 git 实际变更 (complete/complete_sub模式)
+	File             string            `json:"file,omitempty"`               // 导出/导入的 JSON 文件路径 (export 模式可选，import 模式必填)
+	Format           string            `json:"format,omitempty"`             // 导出格式：json 完整历史（默认）/ mermaid 状态机图 (export模式)
+	AutoVerify       bool              `json:"auto_verify,omitempty"`        // 执行 gate/子任务的 verify 命令，按退出码决定 pass/fail (complete gate/complete_sub模式)
+	Parallel         bool              `json:"parallel,omitempty"`           // 依赖已满足的子任务同时开始，complete_sub 只校验 depends_on (spawn模式)
+	Limit            int               `json:"limit,omitempty"`              // 只显示最近 N 条事件 (history模式，默认全部)
+	MaxSteps         int               `json:"max_steps,omitempty"`          // 执行预算：complete/complete_sub 调用次数上限 (init/ack_budget模式)
+	MaxRetriesTotal  int               `json:"max_retries_total,omitempty"`  // 执行预算：全链 gate 失败次数上限 (init/ack_budget模式)
+	MaxTokens        int               `json:"max_tokens,omitempty"`         // 执行预算：估算 token 上限 (init/ack_budget模式)
+	TokensUsed       int               `json:"tokens_used,omitempty"`        // 本步骤实际消耗的 token，计入执行预算；不传则按 summary 与输出长度估算 (complete/complete_sub模式)
+}
+
+// TaskChain 调用 task_chain - 任务链执行器 (协议状态机模式)
+func (c *Client) TaskChain(ctx context.Context, req TaskChainRequest) (*ToolResult, error) {
+	return c.Call(ctx, "task_chain", req)
+}
+
+// WarmUpRequest warm_up 的请求参数
+type WarmUpRequest struct {
+	Scope    string `json:"scope,omitempty"`    // 预热范围 (目录，留空=整个项目)
+	Keywords string `json:"keywords,omitempty"` // 记忆预取关键词 (留空则从 scope 推断)
+}
+
+// WarmUp 调用 warm_up - 会话预热 (重度任务开始前一键准备)
+func (c *Client) WarmUp(ctx context.Context, req WarmUpRequest) (*ToolResult, error) {
+	return c.Call(ctx, "warm_up", req)
+}